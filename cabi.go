@@ -0,0 +1,216 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"go/format"
+	"io"
+	"regexp"
+	"text/template"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+// cIdent matches a C identifier.
+var cIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// generateCABI writes a Go file, of the package of the grammar's prelude,
+// wrapping the ParseAt function of the named rule
+// in C functions exported with cgo,
+// so that a parser generated with the Config
+// can be embedded in a non-Go host
+// as a C shared library or archive:
+//
+//	char *<cname>_parse(const char *text, size_t n);
+//	void <cname>_free(char *result);
+//
+// If cname is the empty string, it is the package name.
+//
+// The parse function parses the rule at the beginning of the n bytes of text,
+// and returns a NUL-terminated JSON object, to be freed with the free function.
+// On success, it is the byte offset of the end of the match
+// and the result of the rule's actions, encoded by encoding/json:
+//
+//	{"end": 5, "value": ...}
+//
+// On failure, it is the error; for a syntax error also its location,
+// and the peg.ErrorCode and rule of the failure, if known:
+//
+//	{"error": {"message": "want ...; got ...", "loc": {"byte": 2, "line": 1, "column": 3}, "code": "expected-literal", "rule": "Semi"}}
+//
+// A panic of an action is returned as an error too,
+// instead of crashing the host.
+//
+// If rule is the empty string, it is the first rule.
+// The rule must be defined in the checked grammar and have no parameters,
+// and the parser must be generated with actions.
+func generateCABI(c grammar.Config, w io.Writer, gr *grammar.Grammar, rule, cname string) error {
+	r, err := replRoot(gr, rule)
+	if err != nil {
+		return err
+	}
+	pkg, err := preludePackage(gr)
+	if err != nil {
+		return err
+	}
+	if cname == "" {
+		cname = pkg
+	}
+	if !cIdent.MatchString(cname) {
+		return errors.New("bad C name " + cname + ": want a C identifier")
+	}
+	data := map[string]string{
+		"Package":   pkg,
+		"Prefix":    c.Prefix,
+		"PegImport": c.PegImportPath(),
+		"Rule":      r.Name.String(),
+		"Root":      r.Name.Ident(),
+		"CName":     cname,
+	}
+	var b bytes.Buffer
+	if err := template.Must(template.New("cabi").Parse(cabiTemplate)).Execute(&b, data); err != nil {
+		return err
+	}
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+var cabiTemplate = `// Code generated by peggy cabi. DO NOT EDIT.
+
+package {{.Package}}
+
+// #include <stdlib.h>
+import "C"
+
+import (
+	"encoding/json"
+	"fmt"
+	"unsafe"
+
+	peg "{{.PegImport}}"
+)
+
+// {{.CName}}_parse parses the {{.Rule}} rule
+// at the beginning of the n bytes of text,
+// returning a JSON object of the result or the error,
+// which must be freed with {{.CName}}_free.
+//
+//export {{.CName}}_parse
+func {{.CName}}_parse(text *C.char, n C.size_t) *C.char {
+	var s string
+	if n > 0 {
+		s = string(unsafe.Slice((*byte)(unsafe.Pointer(text)), n))
+	}
+	return C.CString(string({{.Prefix}}jsonParse(s)))
+}
+
+// {{.CName}}_free frees a result of {{.CName}}_parse.
+//
+//export {{.CName}}_free
+func {{.CName}}_free(result *C.char) {
+	C.free(unsafe.Pointer(result))
+}
+` + jsonParseFuncs
+
+// cabiMain checks the grammar files, or standard input if none,
+// and writes the C ABI wrapper of the rule
+// to the -o file or standard output.
+func cabiMain(rule, cname string, args []string) error {
+	cfg, err := flagConfig()
+	if err != nil {
+		return err
+	}
+	g, err := parseFiles(cfg, args)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Check(g); err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if err := generateCABI(cfg, &b, g, rule, cname); err != nil {
+		return err
+	}
+	return writeOutput(*out, b.Bytes())
+}
+
+// jsonParseFuncs is the template of the functions
+// returning the JSON result of parsing a text with the Root rule,
+// shared by the C ABI wrapper and the WebAssembly harness.
+// They import encoding/json, fmt, and peg.
+var jsonParseFuncs = `
+// {{.Prefix}}jsonResult is the JSON result of parsing a text.
+type {{.Prefix}}jsonResult struct {
+	End   int             ` + "`" + `json:"end"` + "`" + `
+	Value json.RawMessage ` + "`" + `json:"value,omitempty"` + "`" + `
+	Error *{{.Prefix}}jsonError ` + "`" + `json:"error,omitempty"` + "`" + `
+}
+
+// {{.Prefix}}jsonError is an error of parsing a text.
+type {{.Prefix}}jsonError struct {
+	Message string         ` + "`" + `json:"message"` + "`" + `
+	Loc     *{{.Prefix}}jsonLoc ` + "`" + `json:"loc,omitempty"` + "`" + `
+	Code    string         ` + "`" + `json:"code,omitempty"` + "`" + `
+	Rule    string         ` + "`" + `json:"rule,omitempty"` + "`" + `
+}
+
+// {{.Prefix}}jsonLoc is the location of a syntax error.
+type {{.Prefix}}jsonLoc struct {
+	Byte   int ` + "`" + `json:"byte"` + "`" + `
+	Line   int ` + "`" + `json:"line"` + "`" + `
+	Column int ` + "`" + `json:"column"` + "`" + `
+}
+
+// {{.Prefix}}jsonParse returns the JSON result of parsing the text
+// with the root rule.
+func {{.Prefix}}jsonParse(text string) (result []byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = {{.Prefix}}jsonErrorResult(fmt.Errorf("panic: %v", r))
+		}
+	}()
+	parser, err := {{.Prefix}}NewParser(text)
+	if err != nil {
+		return {{.Prefix}}jsonErrorResult(err)
+	}
+	end, v, err := {{.Prefix}}{{.Root}}ParseAt(parser, 0)
+	if err != nil {
+		return {{.Prefix}}jsonErrorResult(err)
+	}
+	value, err := json.Marshal(v)
+	if err != nil {
+		return {{.Prefix}}jsonErrorResult(err)
+	}
+	result, err = json.Marshal({{.Prefix}}jsonResult{End: end, Value: value})
+	if err != nil {
+		return {{.Prefix}}jsonErrorResult(err)
+	}
+	return result
+}
+
+// {{.Prefix}}jsonErrorResult returns the JSON result of the error.
+func {{.Prefix}}jsonErrorResult(err error) []byte {
+	e := &{{.Prefix}}jsonError{Message: err.Error()}
+	if pe, ok := err.(peg.Error); ok {
+		e.Message = pe.Message
+		e.Loc = &{{.Prefix}}jsonLoc{Byte: pe.Loc.Byte, Line: pe.Loc.Line, Column: pe.Loc.Column}
+		e.Code = string(pe.Code)
+		e.Rule = pe.Rule
+	}
+	result, err := json.Marshal({{.Prefix}}jsonResult{End: -1, Error: e})
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+`
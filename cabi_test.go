@@ -0,0 +1,177 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+func TestGenerateCABIErrors(t *testing.T) {
+	const src = `
+		Sum <- x:Num "+" y:Num { return int(x + y) }
+		Num <- n:[0-9] { return int(n[0] - '0') }
+		Deep @param(depth int) <- "d"`
+	tests := []struct {
+		rule, cname, err string
+	}{
+		{rule: "Nope", err: "rule Nope undefined"},
+		{rule: "Deep", err: ":4.3,4.32: rule Deep has parameters, so it cannot be the root"},
+		{rule: "Sum", cname: "my-lang", err: "bad C name my-lang: want a C identifier"},
+	}
+	for _, test := range tests {
+		g, err := grammar.Parse(strings.NewReader(src), "")
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %s", src, err)
+		}
+		if err := grammar.Check(g); err != nil {
+			t.Fatalf("Check(%q) failed: %s", src, err)
+		}
+		var b strings.Builder
+		err = generateCABI(grammar.Config{Prefix: "_"}, &b, g, test.rule, test.cname)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("generateCABI(_, _, _, %q, %q)=%v, want %q", test.rule, test.cname, err, test.err)
+		}
+	}
+}
+
+// TestCABIMainKeepsOutput tests that a failure
+// leaves the -o file as it was.
+func TestCABIMainKeepsOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peggy_cabi_main_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "e.peggy")
+	if err := ioutil.WriteFile(file, []byte("{\npackage main\n}\nA <- \"a\"\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "out.go")
+	if err := ioutil.WriteFile(output, []byte("old"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	// This test cannot be run in parallel.
+	*out = output
+	defer func() { *out = "" }()
+	if err := cabiMain("Nope", "", []string{file}); err == nil {
+		t.Errorf("cabiMain(\"Nope\", \"\", _)=nil, want an error")
+	}
+	if data, err := ioutil.ReadFile(output); err != nil || string(data) != "old" {
+		t.Errorf("after failing, -o file contains %q, %v, want %q", data, err, "old")
+	}
+}
+
+func TestGenerateCABI(t *testing.T) {
+	if _, err := exec.LookPath("cc"); err != nil {
+		t.Skip("no C compiler")
+	}
+	// main calls the exported C functions as cgo does,
+	// writing the result for the text on standard input.
+	const cabiPrelude = `{
+package main
+
+// #include <stdlib.h>
+import "C"
+
+import (
+	"io/ioutil"
+	"os"
+	"unsafe"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	text := C.CString(string(data))
+	defer C.free(unsafe.Pointer(text))
+	result := calc_parse(text, C.size_t(len(data)))
+	defer calc_free(result)
+	os.Stdout.WriteString(C.GoString(result))
+}
+}
+`
+	const src = `
+		Sum <- x:Num "+" y:Num { return int(x + y) }
+		Num "number" <- n:[0-9] {
+			if n == "0" {
+				panic("zero")
+			}
+			return int(n[0] - '0')
+		}`
+	dir, err := ioutil.TempDir("", "peggy_cabi_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	g, err := grammar.Parse(strings.NewReader(cabiPrelude+src), "")
+	if err != nil {
+		t.Fatalf("Parse failed: %s", err)
+	}
+	if err := grammar.Check(g); err != nil {
+		t.Fatalf("Check failed: %s", err)
+	}
+	cfg := grammar.Config{Prefix: "_"}
+	var parser, wrapper strings.Builder
+	if err := cfg.Generate(&parser, "", g); err != nil {
+		t.Fatalf("Generate failed: %s", err)
+	}
+	if err := generateCABI(cfg, &wrapper, g, "Sum", "calc"); err != nil {
+		t.Fatalf("generateCABI failed: %s", err)
+	}
+	files := []string{filepath.Join(dir, "calc.go"), filepath.Join(dir, "calc_cabi.go")}
+	for i, src := range []string{parser.String(), wrapper.String()} {
+		if err := ioutil.WriteFile(files[i], []byte(src), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	binary := filepath.Join(dir, "calc")
+	cmd := exec.Command("go", append([]string{"build", "-o", binary}, files...)...)
+	cmd.Env = append(os.Environ(), "CGO_ENABLED=1")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %s\n%s", err, out)
+	}
+
+	tests := []struct {
+		text, want string
+	}{
+		{text: "1+2", want: `{"end":3,"value":3}`},
+		{text: "1+2+3", want: `{"end":3,"value":3}`},
+		{
+			text: "1-",
+			want: `{"end":-1,"error":{"message":"want \"+\"; got '-'","loc":{"byte":1,"line":1,"column":2},"code":"expected-literal","rule":"Sum"}}`,
+		},
+		{
+			text: "",
+			want: `{"end":-1,"error":{"message":"want number; got EOF","loc":{"byte":0,"line":1,"column":1},"code":"named-rule","rule":"Num"}}`,
+		},
+		{text: "0+1", want: `{"end":-1,"error":{"message":"panic: zero"}}`},
+	}
+	for _, test := range tests {
+		cmd := exec.Command(binary)
+		cmd.Stdin = strings.NewReader(test.text)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Errorf("parse(%q) failed: %s", test.text, err)
+			continue
+		}
+		if got := string(out); got != test.want {
+			t.Errorf("parse(%q)=%s, want %s", test.text, got, test.want)
+		}
+	}
+}
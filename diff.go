@@ -0,0 +1,234 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+// A semver is the part of a semantic version
+// that a change to a grammar requires incrementing.
+type semver int
+
+const (
+	// patch changes don't change the generated API
+	// or the rules of the grammar.
+	patch semver = iota
+
+	// minor changes add to the generated API
+	// or change the language accepted by existing rules.
+	minor
+
+	// major changes remove from or change the generated API,
+	// breaking Go code that uses the generated parser.
+	major
+)
+
+func (v semver) String() string {
+	switch v {
+	case major:
+		return "major"
+	case minor:
+		return "minor"
+	default:
+		return "patch"
+	}
+}
+
+// A grammarChange is a semantic difference between two grammars.
+type grammarChange struct {
+	// Desc describes the change.
+	Desc string
+	// Semver is the version increment required by the change.
+	Semver semver
+}
+
+// diffGrammars returns the semantic differences
+// between two checked grammars:
+// added and removed rules, changed rule headers and result types,
+// and changed expressions, reporting additions, removals,
+// and reordering of the branches of a rule's top-level choice.
+func diffGrammars(old, new *grammar.Grammar) []grammarChange {
+	var changes []grammarChange
+	add := func(v semver, format string, args ...interface{}) {
+		changes = append(changes, grammarChange{Desc: fmt.Sprintf(format, args...), Semver: v})
+	}
+	oldRules := make(map[string]*grammar.Rule)
+	for _, r := range old.CheckedRules {
+		oldRules[r.Name.String()] = r
+	}
+	newRules := make(map[string]*grammar.Rule)
+	for _, r := range new.CheckedRules {
+		newRules[r.Name.String()] = r
+	}
+	for _, r := range old.CheckedRules {
+		if newRules[r.Name.String()] == nil {
+			add(major, "removed rule %s", r.Name)
+		}
+	}
+	for _, r := range new.CheckedRules {
+		if oldRules[r.Name.String()] == nil {
+			add(minor, "added rule %s", r.Name)
+		}
+	}
+	for _, n := range new.CheckedRules {
+		o := oldRules[n.Name.String()]
+		if o == nil {
+			continue
+		}
+		if o.Type() != n.Type() {
+			add(major, "rule %s: type changed from %s to %s", n.Name, o.Type(), n.Type())
+		}
+		if textString(o.Params) != textString(n.Params) {
+			add(major, "rule %s: parameters changed from (%s) to (%s)",
+				n.Name, textString(o.Params), textString(n.Params))
+		}
+		if textString(o.ErrorName) != textString(n.ErrorName) {
+			add(minor, "rule %s: error name changed from %q to %q",
+				n.Name, textString(o.ErrorName), textString(n.ErrorName))
+		}
+		if a, b := annotationString(o), annotationString(n); a != b {
+			add(patch, "rule %s: annotations changed from %q to %q", n.Name, a, b)
+		}
+		diffExprs(n.Name.String(), o.Expr, n.Expr, add)
+	}
+	return changes
+}
+
+// diffExprs adds the changes between the old and new expressions of a rule.
+func diffExprs(rule string, old, new grammar.Expr, add func(semver, string, ...interface{})) {
+	oc, ok0 := old.(*grammar.Choice)
+	nc, ok1 := new.(*grammar.Choice)
+	if !ok0 || !ok1 {
+		switch {
+		case old.String() != new.String():
+			add(minor, "rule %s: expression changed from %s to %s", rule, old, new)
+		case codeString(old) != codeString(new):
+			add(patch, "rule %s: action or predicate code changed", rule)
+		}
+		return
+	}
+	var oldBranches, newBranches []string
+	oldCount := make(map[string]int)
+	newCount := make(map[string]int)
+	for _, e := range oc.Exprs {
+		oldBranches = append(oldBranches, e.String())
+		oldCount[e.String()]++
+	}
+	for _, e := range nc.Exprs {
+		newBranches = append(newBranches, e.String())
+		newCount[e.String()]++
+	}
+	var oldKept, newKept []string
+	for _, b := range oldBranches {
+		if newCount[b] > 0 {
+			newCount[b]--
+			oldKept = append(oldKept, b)
+			continue
+		}
+		add(minor, "rule %s: removed choice branch %s", rule, b)
+	}
+	for _, b := range newBranches {
+		if oldCount[b] > 0 {
+			oldCount[b]--
+			newKept = append(newKept, b)
+			continue
+		}
+		add(minor, "rule %s: added choice branch %s", rule, b)
+	}
+	if strings.Join(oldKept, "\x00") != strings.Join(newKept, "\x00") {
+		add(minor, "rule %s: choice branches reordered from %s to %s",
+			rule, strings.Join(oldKept, " / "), strings.Join(newKept, " / "))
+	}
+	if old.String() == new.String() && codeString(old) != codeString(new) {
+		add(patch, "rule %s: action or predicate code changed", rule)
+	}
+}
+
+// textString returns the string of the Text, or "" if it is nil.
+func textString(t grammar.Text) string {
+	if t == nil {
+		return ""
+	}
+	return t.String()
+}
+
+// annotationString returns the string of the annotations of the rule
+// other than its parameters.
+func annotationString(r *grammar.Rule) string {
+	a := *r
+	a.Params, a.ResultType = nil, nil
+	return strings.TrimSpace(a.HeaderString())
+}
+
+// codeString returns the Go code of the actions and code predicates
+// of the expression.
+func codeString(expr grammar.Expr) string {
+	var s strings.Builder
+	expr.Walk(func(e grammar.Expr) bool {
+		switch e := e.(type) {
+		case *grammar.Action:
+			s.WriteString(e.Code.String() + "\x00")
+		case *grammar.PredCode:
+			s.WriteString(e.Code.String() + "\x00")
+		}
+		return true
+	})
+	return s.String()
+}
+
+// diffMain writes the semantic differences
+// between the grammars in the old and new files to w,
+// one per line, followed by the semantic version increment they require.
+func diffMain(w io.Writer, oldFile, newFile string) error {
+	old, err := checkFile(oldFile)
+	if err != nil {
+		return err
+	}
+	new, err := checkFile(newFile)
+	if err != nil {
+		return err
+	}
+	v := patch
+	for _, c := range diffGrammars(old, new) {
+		if c.Semver > v {
+			v = c.Semver
+		}
+		if _, err := fmt.Fprintln(w, c.Desc); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "semver: %s\n", v)
+	return err
+}
+
+// checkFile returns the parsed and checked grammar of a file.
+func checkFile(file string) (*grammar.Grammar, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cfg, err := flagConfig()
+	if err != nil {
+		return nil, err
+	}
+	g, err := cfg.Parse(bufio.NewReader(f), file)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Check(g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+func TestDiffGrammars(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want []grammarChange
+	}{
+		{
+			name: "no change",
+			old:  `A <- "a" / "b"`,
+			new: `A <-
+				"a" / "b" # comment`,
+			want: nil,
+		},
+		{
+			name: "added and removed rules",
+			old: `A <- B
+				B <- "b"`,
+			new: `A <- C
+				C <- "c"`,
+			want: []grammarChange{
+				{Desc: "removed rule B", Semver: major},
+				{Desc: "added rule C", Semver: minor},
+				{Desc: "rule A: expression changed from B to C", Semver: minor},
+			},
+		},
+		{
+			name: "reordered branches",
+			old:  `A <- "a" / "b" / "c"`,
+			new:  `A <- "c" / "a" / "b"`,
+			want: []grammarChange{
+				{Desc: `rule A: choice branches reordered from "a" / "b" / "c" to "c" / "a" / "b"`, Semver: minor},
+			},
+		},
+		{
+			name: "added and removed branches",
+			old:  `A <- "a" / "b" / "c"`,
+			new:  `A <- "a" / "c" / "d"`,
+			want: []grammarChange{
+				{Desc: `rule A: removed choice branch "b"`, Semver: minor},
+				{Desc: `rule A: added choice branch "d"`, Semver: minor},
+			},
+		},
+		{
+			name: "changed type",
+			old:  `A <- "a" { return 1 }`,
+			new:  `A <- "a" { return "" }`,
+			want: []grammarChange{
+				{Desc: "rule A: type changed from int to string", Semver: major},
+				{Desc: "rule A: action or predicate code changed", Semver: patch},
+			},
+		},
+		{
+			name: "changed code",
+			old:  `A <- "a" &{ true } / "b"`,
+			new:  `A <- "a" &{ false } / "b"`,
+			want: []grammarChange{
+				{Desc: "rule A: action or predicate code changed", Semver: patch},
+			},
+		},
+		{
+			name: "changed annotations and error name",
+			old:  `A <- "a"`,
+			new:  `A "an a" @token <- "a"`,
+			want: []grammarChange{
+				{Desc: `rule A: error name changed from "" to "an a"`, Semver: minor},
+				{Desc: `rule A: annotations changed from "" to "@token"`, Semver: patch},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			old := checkString(t, test.old)
+			new := checkString(t, test.new)
+			if got := diffGrammars(old, new); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("diffGrammars(%q, %q)=%v, want %v", test.old, test.new, got, test.want)
+			}
+		})
+	}
+}
+
+func checkString(t *testing.T, in string) *grammar.Grammar {
+	t.Helper()
+	g, err := grammar.Parse(strings.NewReader(in), "test.file")
+	if err != nil {
+		t.Fatalf("Parse(%q, _)=_, %v, want _,nil", in, err)
+	}
+	if err := grammar.Check(g); err != nil {
+		t.Fatalf("Check(%q)=%v, want nil", in, err)
+	}
+	return g
+}
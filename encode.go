@@ -0,0 +1,39 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import "errors"
+
+// encodeMain checks the grammar files, or standard input if none,
+// writing the encoded Grammar in the format, json or gob,
+// to the -o file or standard output.
+func encodeMain(format string, args []string) error {
+	cfg, err := flagConfig()
+	if err != nil {
+		return err
+	}
+	g, err := parseFiles(cfg, args)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Check(g); err != nil {
+		return err
+	}
+	var data []byte
+	switch format {
+	case "json":
+		data, err = g.MarshalJSON()
+	case "gob":
+		data, err = g.GobEncode()
+	default:
+		return errors.New("bad format " + format + ": want json or gob")
+	}
+	if err != nil {
+		return err
+	}
+	return writeOutput(*out, data)
+}
@@ -1,6 +1,7 @@
 // Calc is an example calculator program.
 // You can build it from calc.peggy with
-// 	peggy -o calc.go calc.peggy
+//
+//	peggy -o calc.go calc.peggy
 package main
 
 import (
@@ -73,14 +74,31 @@ const (
 )
 
 type _Parser struct {
-	text     string
-	deltaPos [][_N]int32
-	deltaErr [][_N]int32
-	node     map[_key]*peg.Node
-	fail     map[_key]*peg.Fail
-	act      map[_key]interface{}
-	lastFail int
-	data     interface{}
+	text           string
+	deltaPos       [][_N]int32
+	deltaErr       [][_N]int32
+	node           map[_key]*peg.Node
+	fail           map[_key]*peg.Fail
+	actExpr        map[int](*big.Float)
+	actSum         map[int](big.Float)
+	actSumTail     map[int]tail
+	actAddOp       map[int]op
+	actProduct     map[int](big.Float)
+	actProductTail map[int]tail
+	actMulOp       map[int]op
+	actValue       map[int](big.Float)
+	actNum         map[int](big.Float)
+	act_           map[int]string
+	actEOF         map[int]string
+	lastFail       int
+	// Checks records the failed Expect
+	// and the diagnostics of the actions.
+	peg.Checks
+	// failBudget is the number of rule nodes
+	// that the Fail pass may yet build, if boundFails.
+	failBudget int
+	boundFails bool
+	data       interface{}
 }
 
 type _key struct {
@@ -92,6 +110,10 @@ type tooBigError struct{}
 
 func (tooBigError) Error() string { return "input is too big" }
 
+// _NewParser returns a new Parser for the text.
+// Only the memo table used by every pass is allocated here;
+// the tables of the Node, Fail, and Action passes
+// are allocated when the pass is first run.
 func _NewParser(text string) (*_Parser, error) {
 	n := len(text) + 1
 	if n < 0 {
@@ -101,13 +123,301 @@ func _NewParser(text string) (*_Parser, error) {
 		text:     text,
 		deltaPos: make([][_N]int32, n),
 		deltaErr: make([][_N]int32, n),
-		node:     make(map[_key]*peg.Node),
-		fail:     make(map[_key]*peg.Fail),
-		act:      make(map[_key]interface{}),
 	}
 	return p, nil
 }
 
+// _Diagnostics returns the diagnostics recorded by Diag,
+// in the order that they were recorded, without duplicates,
+// each a peg.Error located at the start of its text
+// with End the location of the end of its text.
+func _Diagnostics(parser *_Parser) []peg.Error {
+	return parser.Diagnostics(peg.Locator{}, parser.text)
+}
+
+// _ExprMatches returns whether the Expr rule matches all of text.
+// It runs only the Accepts pass.
+func _ExprMatches(text string) bool {
+	parser, err := _NewParser(text)
+	if err != nil {
+		return false
+	}
+	pos, _ := _ExprAccepts(parser, 0)
+	return pos == len(text)
+}
+
+// _ExprParseBounded parses the Expr rule
+// at the beginning of text, like _ExprParseAt,
+// but on failure, the Fail pass builds at most max rule nodes,
+// replacing the rest by leaves wanting the rule's name,
+// and the peg.Error lists at most max of the sorted Wants.
+// So the cost of a failure is bounded
+// even for adversarial inputs.
+func _ExprParseBounded(text string, max int) (end int, v *big.Float, err error) {
+	parser, err := _NewParser(text)
+	if err != nil {
+		return -1, v, err
+	}
+	dp, de := _ExprAccepts(parser, 0)
+	if dp < 0 {
+		if max < 1 {
+			max = 1
+		}
+		parser.lastFail = de
+		parser.failBudget, parser.boundFails = max, true
+		_, fail := _ExprFail(parser, 0, de)
+		opts := peg.ErrorOptions{Sort: true, MaxWants: max}
+		return -1, v, opts.SimpleError(text, fail)
+	}
+	end, p := _ExprAction(parser, 0)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, 0, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _ExprParseAt parses the Expr rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _ExprParseAt(parser *_Parser, start int) (end int, v *big.Float, err error) {
+	dp, de := _ExprAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _ExprFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _ExprAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _SumParseAt parses the Sum rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _SumParseAt(parser *_Parser, start int) (end int, v big.Float, err error) {
+	dp, de := _SumAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _SumFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _SumAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _SumTailParseAt parses the SumTail rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _SumTailParseAt(parser *_Parser, start int) (end int, v tail, err error) {
+	dp, de := _SumTailAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _SumTailFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _SumTailAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _AddOpParseAt parses the AddOp rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _AddOpParseAt(parser *_Parser, start int) (end int, v op, err error) {
+	dp, de := _AddOpAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _AddOpFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _AddOpAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _ProductParseAt parses the Product rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _ProductParseAt(parser *_Parser, start int) (end int, v big.Float, err error) {
+	dp, de := _ProductAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _ProductFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _ProductAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _ProductTailParseAt parses the ProductTail rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _ProductTailParseAt(parser *_Parser, start int) (end int, v tail, err error) {
+	dp, de := _ProductTailAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _ProductTailFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _ProductTailAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _MulOpParseAt parses the MulOp rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _MulOpParseAt(parser *_Parser, start int) (end int, v op, err error) {
+	dp, de := _MulOpAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _MulOpFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _MulOpAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _ValueParseAt parses the Value rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _ValueParseAt(parser *_Parser, start int) (end int, v big.Float, err error) {
+	dp, de := _ValueAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _ValueFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _ValueAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _NumParseAt parses the Num rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _NumParseAt(parser *_Parser, start int) (end int, v big.Float, err error) {
+	dp, de := _NumAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _NumFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _NumAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// __ParseAt parses the _ rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func __ParseAt(parser *_Parser, start int) (end int, v string, err error) {
+	dp, de := __Accepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := __Fail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := __Action(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _EOFParseAt parses the EOF rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _EOFParseAt(parser *_Parser, start int) (end int, v string, err error) {
+	dp, de := _EOFAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _EOFFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _EOFAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
 func _max(a, b int) int {
 	if a > b {
 		return a
@@ -115,37 +425,51 @@ func _max(a, b int) int {
 	return b
 }
 
+// _getMemo returns the memo table entries for the rule at the start position.
+// Each entry is 0 if the rule has not been memoized at the position.
+func _getMemo(parser *_Parser, rule, start int) (dp, de int32) {
+	return parser.deltaPos[start][rule], parser.deltaErr[start][rule]
+}
+
+// _setMemo sets the memo table entries for the rule at the start position.
+func _setMemo(parser *_Parser, rule, start int, dp, de int32) {
+	parser.deltaPos[start][rule] = dp
+	parser.deltaErr[start][rule] = de
+}
+
 func _memoize(parser *_Parser, rule, start, pos, perr int) (int, int) {
 	parser.lastFail = perr
 	derr := perr - start
-	parser.deltaErr[start][rule] = int32(derr + 1)
 	if pos >= 0 {
 		dpos := pos - start
-		parser.deltaPos[start][rule] = int32(dpos + 1)
+		_setMemo(parser, rule, start, int32(dpos+1), int32(derr+1))
 		return dpos, derr
 	}
-	parser.deltaPos[start][rule] = -1
+	_setMemo(parser, rule, start, -1, int32(derr+1))
 	return -1, derr
 }
 
 func _memo(parser *_Parser, rule, start int) (int, int, bool) {
-	dp := parser.deltaPos[start][rule]
+	dp, de := _getMemo(parser, rule, start)
 	if dp == 0 {
 		return 0, 0, false
 	}
 	if dp > 0 {
 		dp--
 	}
-	de := parser.deltaErr[start][rule] - 1
-	return int(dp), int(de), true
+	return int(dp), int(de - 1), true
 }
 
-func _failMemo(parser *_Parser, rule, start, errPos int) (int, *peg.Fail) {
+// _failMemo returns the memoized result of the Fail pass
+// for the rule at the start position,
+// or start and nil if the rule's Fail node must be built.
+// If the Fail pass is bounded and its budget is spent,
+// the node is not built, but replaced by a leaf wanting want at errPos.
+func _failMemo(parser *_Parser, rule, start, errPos int, want string) (int, *peg.Fail) {
 	if start > parser.lastFail {
 		return -1, &peg.Fail{}
 	}
-	dp := parser.deltaPos[start][rule]
-	de := parser.deltaErr[start][rule]
+	dp, de := _getMemo(parser, rule, start)
 	if start+int(de-1) < errPos {
 		if dp > 0 {
 			return start + int(dp-1), &peg.Fail{}
@@ -159,6 +483,16 @@ func _failMemo(parser *_Parser, rule, start, errPos int) (int, *peg.Fail) {
 	if dp > 0 && f != nil {
 		return start + int(dp-1), f
 	}
+	if parser.boundFails {
+		if parser.failBudget == 0 {
+			f := &peg.Fail{Pos: errPos, Want: want, Code: peg.NamedRule}
+			if dp > 0 {
+				return start + int(dp-1), f
+			}
+			return -1, f
+		}
+		parser.failBudget--
+	}
 	return start, nil
 }
 
@@ -216,8 +550,6 @@ func _leaf(parser *_Parser, start, end int) *peg.Node {
 func use(interface{}) {}
 
 func _ExprAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
-	var labels [1]string
-	use(labels)
 	if dp, de, ok := _memo(parser, _Expr, start); ok {
 		return dp, de
 	}
@@ -226,12 +558,10 @@ func _ExprAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
 	// s:Sum EOF
 	// s:Sum
 	{
-		pos1 := pos
 		// Sum
 		if !_accept(parser, _SumAccepts, &pos, &perr) {
 			goto fail
 		}
-		labels[0] = parser.text[pos1:pos]
 	}
 	// EOF
 	if !_accept(parser, _EOFAccepts, &pos, &perr) {
@@ -243,13 +573,14 @@ fail:
 }
 
 func _ExprNode(parser *_Parser, start int) (int, *peg.Node) {
-	var labels [1]string
-	use(labels)
-	dp := parser.deltaPos[start][_Expr]
+	dp, _ := _getMemo(parser, _Expr, start)
 	if dp < 0 {
 		return -1, nil
 	}
 	key := _key{start: start, rule: _Expr}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
 	node := parser.node[key]
 	if node != nil {
 		return start + int(dp-1), node
@@ -260,12 +591,10 @@ func _ExprNode(parser *_Parser, start int) (int, *peg.Node) {
 	// s:Sum EOF
 	// s:Sum
 	{
-		pos1 := pos
 		// Sum
 		if !_node(parser, _SumNode, node, &pos) {
 			goto fail
 		}
-		labels[0] = parser.text[pos1:pos]
 	}
 	// EOF
 	if !_node(parser, _EOFNode, node, &pos) {
@@ -279,9 +608,7 @@ fail:
 }
 
 func _ExprFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
-	var labels [1]string
-	use(labels)
-	pos, failure := _failMemo(parser, _Expr, start, errPos)
+	pos, failure := _failMemo(parser, _Expr, start, errPos, "Expr")
 	if failure != nil {
 		return pos, failure
 	}
@@ -290,16 +617,17 @@ func _ExprFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 		Pos:  int(start),
 	}
 	key := _key{start: start, rule: _Expr}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
 	// action
 	// s:Sum EOF
 	// s:Sum
 	{
-		pos1 := pos
 		// Sum
 		if !_fail(parser, _SumFail, errPos, failure, &pos) {
 			goto fail
 		}
-		labels[0] = parser.text[pos1:pos]
 	}
 	// EOF
 	if !_fail(parser, _EOFFail, errPos, failure, &pos) {
@@ -313,17 +641,12 @@ fail:
 }
 
 func _ExprAction(parser *_Parser, start int) (int, *(*big.Float)) {
-	var labels [1]string
-	use(labels)
 	var label0 (big.Float)
-	dp := parser.deltaPos[start][_Expr]
+	dp, _ := _getMemo(parser, _Expr, start)
 	if dp < 0 {
 		return -1, nil
 	}
-	key := _key{start: start, rule: _Expr}
-	n := parser.act[key]
-	if n != nil {
-		n := n.((*big.Float))
+	if n, ok := parser.actExpr[start]; ok {
 		return start + int(dp-1), &n
 	}
 	var node (*big.Float)
@@ -334,7 +657,6 @@ func _ExprAction(parser *_Parser, start int) (int, *(*big.Float)) {
 		// s:Sum EOF
 		// s:Sum
 		{
-			pos2 := pos
 			// Sum
 			if p, n := _SumAction(parser, pos); n == nil {
 				goto fail
@@ -342,7 +664,6 @@ func _ExprAction(parser *_Parser, start int) (int, *(*big.Float)) {
 				label0 = *n
 				pos = p
 			}
-			labels[0] = parser.text[pos2:pos]
 		}
 		// EOF
 		if p, n := _EOFAction(parser, pos); n == nil {
@@ -356,15 +677,16 @@ func _ExprAction(parser *_Parser, start int) (int, *(*big.Float)) {
 		}(
 			start0, pos, label0)
 	}
-	parser.act[key] = node
+	if parser.actExpr == nil {
+		parser.actExpr = make(map[int](*big.Float))
+	}
+	parser.actExpr[start] = node
 	return pos, &node
 fail:
 	return -1, nil
 }
 
 func _SumAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
-	var labels [2]string
-	use(labels)
 	if dp, de, ok := _memo(parser, _Sum, start); ok {
 		return dp, de
 	}
@@ -373,16 +695,13 @@ func _SumAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
 	// l:Product tail:SumTail*
 	// l:Product
 	{
-		pos1 := pos
 		// Product
 		if !_accept(parser, _ProductAccepts, &pos, &perr) {
 			goto fail
 		}
-		labels[0] = parser.text[pos1:pos]
 	}
 	// tail:SumTail*
 	{
-		pos2 := pos
 		// SumTail*
 		for {
 			pos4 := pos
@@ -395,7 +714,6 @@ func _SumAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
 			pos = pos4
 			break
 		}
-		labels[1] = parser.text[pos2:pos]
 	}
 	return _memoize(parser, _Sum, start, pos, perr)
 fail:
@@ -403,13 +721,14 @@ fail:
 }
 
 func _SumNode(parser *_Parser, start int) (int, *peg.Node) {
-	var labels [2]string
-	use(labels)
-	dp := parser.deltaPos[start][_Sum]
+	dp, _ := _getMemo(parser, _Sum, start)
 	if dp < 0 {
 		return -1, nil
 	}
 	key := _key{start: start, rule: _Sum}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
 	node := parser.node[key]
 	if node != nil {
 		return start + int(dp-1), node
@@ -420,16 +739,13 @@ func _SumNode(parser *_Parser, start int) (int, *peg.Node) {
 	// l:Product tail:SumTail*
 	// l:Product
 	{
-		pos1 := pos
 		// Product
 		if !_node(parser, _ProductNode, node, &pos) {
 			goto fail
 		}
-		labels[0] = parser.text[pos1:pos]
 	}
 	// tail:SumTail*
 	{
-		pos2 := pos
 		// SumTail*
 		for {
 			nkids3 := len(node.Kids)
@@ -444,7 +760,6 @@ func _SumNode(parser *_Parser, start int) (int, *peg.Node) {
 			pos = pos4
 			break
 		}
-		labels[1] = parser.text[pos2:pos]
 	}
 	node.Text = parser.text[start:pos]
 	parser.node[key] = node
@@ -454,9 +769,7 @@ fail:
 }
 
 func _SumFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
-	var labels [2]string
-	use(labels)
-	pos, failure := _failMemo(parser, _Sum, start, errPos)
+	pos, failure := _failMemo(parser, _Sum, start, errPos, "Sum")
 	if failure != nil {
 		return pos, failure
 	}
@@ -465,20 +778,20 @@ func _SumFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 		Pos:  int(start),
 	}
 	key := _key{start: start, rule: _Sum}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
 	// action
 	// l:Product tail:SumTail*
 	// l:Product
 	{
-		pos1 := pos
 		// Product
 		if !_fail(parser, _ProductFail, errPos, failure, &pos) {
 			goto fail
 		}
-		labels[0] = parser.text[pos1:pos]
 	}
 	// tail:SumTail*
 	{
-		pos2 := pos
 		// SumTail*
 		for {
 			pos4 := pos
@@ -491,7 +804,6 @@ func _SumFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 			pos = pos4
 			break
 		}
-		labels[1] = parser.text[pos2:pos]
 	}
 	parser.fail[key] = failure
 	return pos, failure
@@ -501,18 +813,13 @@ fail:
 }
 
 func _SumAction(parser *_Parser, start int) (int, *(big.Float)) {
-	var labels [2]string
-	use(labels)
 	var label0 (big.Float)
 	var label1 []tail
-	dp := parser.deltaPos[start][_Sum]
+	dp, _ := _getMemo(parser, _Sum, start)
 	if dp < 0 {
 		return -1, nil
 	}
-	key := _key{start: start, rule: _Sum}
-	n := parser.act[key]
-	if n != nil {
-		n := n.((big.Float))
+	if n, ok := parser.actSum[start]; ok {
 		return start + int(dp-1), &n
 	}
 	var node (big.Float)
@@ -523,7 +830,6 @@ func _SumAction(parser *_Parser, start int) (int, *(big.Float)) {
 		// l:Product tail:SumTail*
 		// l:Product
 		{
-			pos2 := pos
 			// Product
 			if p, n := _ProductAction(parser, pos); n == nil {
 				goto fail
@@ -531,11 +837,9 @@ func _SumAction(parser *_Parser, start int) (int, *(big.Float)) {
 				label0 = *n
 				pos = p
 			}
-			labels[0] = parser.text[pos2:pos]
 		}
 		// tail:SumTail*
 		{
-			pos3 := pos
 			// SumTail*
 			for {
 				pos5 := pos
@@ -553,7 +857,6 @@ func _SumAction(parser *_Parser, start int) (int, *(big.Float)) {
 				pos = pos5
 				break
 			}
-			labels[1] = parser.text[pos3:pos]
 		}
 		node = func(
 			start, end int, l big.Float, tail []tail) big.Float {
@@ -561,15 +864,16 @@ func _SumAction(parser *_Parser, start int) (int, *(big.Float)) {
 		}(
 			start0, pos, label0, label1)
 	}
-	parser.act[key] = node
+	if parser.actSum == nil {
+		parser.actSum = make(map[int](big.Float))
+	}
+	parser.actSum[start] = node
 	return pos, &node
 fail:
 	return -1, nil
 }
 
 func _SumTailAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
-	var labels [2]string
-	use(labels)
 	if dp, de, ok := _memo(parser, _SumTail, start); ok {
 		return dp, de
 	}
@@ -578,21 +882,17 @@ func _SumTailAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
 	// op:AddOp r:Product
 	// op:AddOp
 	{
-		pos1 := pos
 		// AddOp
 		if !_accept(parser, _AddOpAccepts, &pos, &perr) {
 			goto fail
 		}
-		labels[0] = parser.text[pos1:pos]
 	}
 	// r:Product
 	{
-		pos2 := pos
 		// Product
 		if !_accept(parser, _ProductAccepts, &pos, &perr) {
 			goto fail
 		}
-		labels[1] = parser.text[pos2:pos]
 	}
 	return _memoize(parser, _SumTail, start, pos, perr)
 fail:
@@ -600,13 +900,14 @@ fail:
 }
 
 func _SumTailNode(parser *_Parser, start int) (int, *peg.Node) {
-	var labels [2]string
-	use(labels)
-	dp := parser.deltaPos[start][_SumTail]
+	dp, _ := _getMemo(parser, _SumTail, start)
 	if dp < 0 {
 		return -1, nil
 	}
 	key := _key{start: start, rule: _SumTail}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
 	node := parser.node[key]
 	if node != nil {
 		return start + int(dp-1), node
@@ -617,21 +918,17 @@ func _SumTailNode(parser *_Parser, start int) (int, *peg.Node) {
 	// op:AddOp r:Product
 	// op:AddOp
 	{
-		pos1 := pos
 		// AddOp
 		if !_node(parser, _AddOpNode, node, &pos) {
 			goto fail
 		}
-		labels[0] = parser.text[pos1:pos]
 	}
 	// r:Product
 	{
-		pos2 := pos
 		// Product
 		if !_node(parser, _ProductNode, node, &pos) {
 			goto fail
 		}
-		labels[1] = parser.text[pos2:pos]
 	}
 	node.Text = parser.text[start:pos]
 	parser.node[key] = node
@@ -641,9 +938,7 @@ fail:
 }
 
 func _SumTailFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
-	var labels [2]string
-	use(labels)
-	pos, failure := _failMemo(parser, _SumTail, start, errPos)
+	pos, failure := _failMemo(parser, _SumTail, start, errPos, "SumTail")
 	if failure != nil {
 		return pos, failure
 	}
@@ -652,25 +947,24 @@ func _SumTailFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 		Pos:  int(start),
 	}
 	key := _key{start: start, rule: _SumTail}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
 	// action
 	// op:AddOp r:Product
 	// op:AddOp
 	{
-		pos1 := pos
 		// AddOp
 		if !_fail(parser, _AddOpFail, errPos, failure, &pos) {
 			goto fail
 		}
-		labels[0] = parser.text[pos1:pos]
 	}
 	// r:Product
 	{
-		pos2 := pos
 		// Product
 		if !_fail(parser, _ProductFail, errPos, failure, &pos) {
 			goto fail
 		}
-		labels[1] = parser.text[pos2:pos]
 	}
 	parser.fail[key] = failure
 	return pos, failure
@@ -680,18 +974,13 @@ fail:
 }
 
 func _SumTailAction(parser *_Parser, start int) (int, *tail) {
-	var labels [2]string
-	use(labels)
 	var label0 op
 	var label1 (big.Float)
-	dp := parser.deltaPos[start][_SumTail]
+	dp, _ := _getMemo(parser, _SumTail, start)
 	if dp < 0 {
 		return -1, nil
 	}
-	key := _key{start: start, rule: _SumTail}
-	n := parser.act[key]
-	if n != nil {
-		n := n.(tail)
+	if n, ok := parser.actSumTail[start]; ok {
 		return start + int(dp-1), &n
 	}
 	var node tail
@@ -702,7 +991,6 @@ func _SumTailAction(parser *_Parser, start int) (int, *tail) {
 		// op:AddOp r:Product
 		// op:AddOp
 		{
-			pos2 := pos
 			// AddOp
 			if p, n := _AddOpAction(parser, pos); n == nil {
 				goto fail
@@ -710,11 +998,9 @@ func _SumTailAction(parser *_Parser, start int) (int, *tail) {
 				label0 = *n
 				pos = p
 			}
-			labels[0] = parser.text[pos2:pos]
 		}
 		// r:Product
 		{
-			pos3 := pos
 			// Product
 			if p, n := _ProductAction(parser, pos); n == nil {
 				goto fail
@@ -722,7 +1008,6 @@ func _SumTailAction(parser *_Parser, start int) (int, *tail) {
 				label1 = *n
 				pos = p
 			}
-			labels[1] = parser.text[pos3:pos]
 		}
 		node = func(
 			start, end int, op op, r big.Float) tail {
@@ -730,7 +1015,10 @@ func _SumTailAction(parser *_Parser, start int) (int, *tail) {
 		}(
 			start0, pos, label0, label1)
 	}
-	parser.act[key] = node
+	if parser.actSumTail == nil {
+		parser.actSumTail = make(map[int]tail)
+	}
+	parser.actSumTail[start] = node
 	return pos, &node
 fail:
 	return -1, nil
@@ -747,11 +1035,9 @@ func _AddOpAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
 		// action
 		// _ "+"
 		// _
-		if !_accept(parser, __Accepts, &pos, &perr) {
-			goto fail4
-		}
+		_accept(parser, __Accepts, &pos, &perr)
 		// "+"
-		if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "+" {
+		if pos >= len(parser.text) || parser.text[pos] != "+"[0] {
 			perr = _max(perr, pos)
 			goto fail4
 		}
@@ -762,11 +1048,9 @@ func _AddOpAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
 		// action
 		// _ "-"
 		// _
-		if !_accept(parser, __Accepts, &pos, &perr) {
-			goto fail6
-		}
+		_accept(parser, __Accepts, &pos, &perr)
 		// "-"
-		if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "-" {
+		if pos >= len(parser.text) || parser.text[pos] != "-"[0] {
 			perr = _max(perr, pos)
 			goto fail6
 		}
@@ -784,11 +1068,14 @@ fail:
 }
 
 func _AddOpNode(parser *_Parser, start int) (int, *peg.Node) {
-	dp := parser.deltaPos[start][_AddOp]
+	dp, _ := _getMemo(parser, _AddOp, start)
 	if dp < 0 {
 		return -1, nil
 	}
 	key := _key{start: start, rule: _AddOp}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
 	node := parser.node[key]
 	if node != nil {
 		return start + int(dp-1), node
@@ -802,11 +1089,9 @@ func _AddOpNode(parser *_Parser, start int) (int, *peg.Node) {
 		// action
 		// _ "+"
 		// _
-		if !_node(parser, __Node, node, &pos) {
-			goto fail4
-		}
+		_node(parser, __Node, node, &pos)
 		// "+"
-		if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "+" {
+		if pos >= len(parser.text) || parser.text[pos] != "+"[0] {
 			goto fail4
 		}
 		node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
@@ -818,11 +1103,9 @@ func _AddOpNode(parser *_Parser, start int) (int, *peg.Node) {
 		// action
 		// _ "-"
 		// _
-		if !_node(parser, __Node, node, &pos) {
-			goto fail6
-		}
+		_node(parser, __Node, node, &pos)
 		// "-"
-		if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "-" {
+		if pos >= len(parser.text) || parser.text[pos] != "-"[0] {
 			goto fail6
 		}
 		node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
@@ -842,7 +1125,7 @@ fail:
 }
 
 func _AddOpFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
-	pos, failure := _failMemo(parser, _AddOp, start, errPos)
+	pos, failure := _failMemo(parser, _AddOp, start, errPos, "operator")
 	if failure != nil {
 		return pos, failure
 	}
@@ -851,21 +1134,23 @@ func _AddOpFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 		Pos:  int(start),
 	}
 	key := _key{start: start, rule: _AddOp}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
 	// _ "+" {…}/_ "-" {…}
 	{
 		pos3 := pos
 		// action
 		// _ "+"
 		// _
-		if !_fail(parser, __Fail, errPos, failure, &pos) {
-			goto fail4
-		}
+		_fail(parser, __Fail, errPos, failure, &pos)
 		// "+"
-		if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "+" {
+		if pos >= len(parser.text) || parser.text[pos] != "+"[0] {
 			if pos >= errPos {
 				failure.Kids = append(failure.Kids, &peg.Fail{
 					Pos:  int(pos),
 					Want: "\"+\"",
+					Code: peg.ExpectedLiteral,
 				})
 			}
 			goto fail4
@@ -877,15 +1162,14 @@ func _AddOpFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 		// action
 		// _ "-"
 		// _
-		if !_fail(parser, __Fail, errPos, failure, &pos) {
-			goto fail6
-		}
+		_fail(parser, __Fail, errPos, failure, &pos)
 		// "-"
-		if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "-" {
+		if pos >= len(parser.text) || parser.text[pos] != "-"[0] {
 			if pos >= errPos {
 				failure.Kids = append(failure.Kids, &peg.Fail{
 					Pos:  int(pos),
 					Want: "\"-\"",
+					Code: peg.ExpectedLiteral,
 				})
 			}
 			goto fail6
@@ -903,19 +1187,17 @@ func _AddOpFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 fail:
 	failure.Kids = nil
 	failure.Want = "operator"
+	failure.Code = peg.NamedRule
 	parser.fail[key] = failure
 	return -1, failure
 }
 
 func _AddOpAction(parser *_Parser, start int) (int, *op) {
-	dp := parser.deltaPos[start][_AddOp]
+	dp, _ := _getMemo(parser, _AddOp, start)
 	if dp < 0 {
 		return -1, nil
 	}
-	key := _key{start: start, rule: _AddOp}
-	n := parser.act[key]
-	if n != nil {
-		n := n.(op)
+	if n, ok := parser.actAddOp[start]; ok {
 		return start + int(dp-1), &n
 	}
 	var node op
@@ -929,13 +1211,12 @@ func _AddOpAction(parser *_Parser, start int) (int, *op) {
 			start5 := pos
 			// _ "+"
 			// _
-			if p, n := __Action(parser, pos); n == nil {
-				goto fail4
-			} else {
+			{
+				p, _ := __Action(parser, pos)
 				pos = p
 			}
 			// "+"
-			if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "+" {
+			if pos >= len(parser.text) || parser.text[pos] != "+"[0] {
 				goto fail4
 			}
 			pos++
@@ -954,13 +1235,12 @@ func _AddOpAction(parser *_Parser, start int) (int, *op) {
 			start8 := pos
 			// _ "-"
 			// _
-			if p, n := __Action(parser, pos); n == nil {
-				goto fail7
-			} else {
+			{
+				p, _ := __Action(parser, pos)
 				pos = p
 			}
 			// "-"
-			if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "-" {
+			if pos >= len(parser.text) || parser.text[pos] != "-"[0] {
 				goto fail7
 			}
 			pos++
@@ -977,15 +1257,16 @@ func _AddOpAction(parser *_Parser, start int) (int, *op) {
 		goto fail
 	ok0:
 	}
-	parser.act[key] = node
+	if parser.actAddOp == nil {
+		parser.actAddOp = make(map[int]op)
+	}
+	parser.actAddOp[start] = node
 	return pos, &node
 fail:
 	return -1, nil
 }
 
 func _ProductAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
-	var labels [2]string
-	use(labels)
 	if dp, de, ok := _memo(parser, _Product, start); ok {
 		return dp, de
 	}
@@ -994,16 +1275,13 @@ func _ProductAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
 	// l:Value tail:ProductTail*
 	// l:Value
 	{
-		pos1 := pos
 		// Value
 		if !_accept(parser, _ValueAccepts, &pos, &perr) {
 			goto fail
 		}
-		labels[0] = parser.text[pos1:pos]
 	}
 	// tail:ProductTail*
 	{
-		pos2 := pos
 		// ProductTail*
 		for {
 			pos4 := pos
@@ -1016,7 +1294,6 @@ func _ProductAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
 			pos = pos4
 			break
 		}
-		labels[1] = parser.text[pos2:pos]
 	}
 	return _memoize(parser, _Product, start, pos, perr)
 fail:
@@ -1024,13 +1301,14 @@ fail:
 }
 
 func _ProductNode(parser *_Parser, start int) (int, *peg.Node) {
-	var labels [2]string
-	use(labels)
-	dp := parser.deltaPos[start][_Product]
+	dp, _ := _getMemo(parser, _Product, start)
 	if dp < 0 {
 		return -1, nil
 	}
 	key := _key{start: start, rule: _Product}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
 	node := parser.node[key]
 	if node != nil {
 		return start + int(dp-1), node
@@ -1041,16 +1319,13 @@ func _ProductNode(parser *_Parser, start int) (int, *peg.Node) {
 	// l:Value tail:ProductTail*
 	// l:Value
 	{
-		pos1 := pos
 		// Value
 		if !_node(parser, _ValueNode, node, &pos) {
 			goto fail
 		}
-		labels[0] = parser.text[pos1:pos]
 	}
 	// tail:ProductTail*
 	{
-		pos2 := pos
 		// ProductTail*
 		for {
 			nkids3 := len(node.Kids)
@@ -1065,7 +1340,6 @@ func _ProductNode(parser *_Parser, start int) (int, *peg.Node) {
 			pos = pos4
 			break
 		}
-		labels[1] = parser.text[pos2:pos]
 	}
 	node.Text = parser.text[start:pos]
 	parser.node[key] = node
@@ -1075,9 +1349,7 @@ fail:
 }
 
 func _ProductFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
-	var labels [2]string
-	use(labels)
-	pos, failure := _failMemo(parser, _Product, start, errPos)
+	pos, failure := _failMemo(parser, _Product, start, errPos, "Product")
 	if failure != nil {
 		return pos, failure
 	}
@@ -1086,20 +1358,20 @@ func _ProductFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 		Pos:  int(start),
 	}
 	key := _key{start: start, rule: _Product}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
 	// action
 	// l:Value tail:ProductTail*
 	// l:Value
 	{
-		pos1 := pos
 		// Value
 		if !_fail(parser, _ValueFail, errPos, failure, &pos) {
 			goto fail
 		}
-		labels[0] = parser.text[pos1:pos]
 	}
 	// tail:ProductTail*
 	{
-		pos2 := pos
 		// ProductTail*
 		for {
 			pos4 := pos
@@ -1112,7 +1384,6 @@ func _ProductFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 			pos = pos4
 			break
 		}
-		labels[1] = parser.text[pos2:pos]
 	}
 	parser.fail[key] = failure
 	return pos, failure
@@ -1122,18 +1393,13 @@ fail:
 }
 
 func _ProductAction(parser *_Parser, start int) (int, *(big.Float)) {
-	var labels [2]string
-	use(labels)
 	var label0 (big.Float)
 	var label1 []tail
-	dp := parser.deltaPos[start][_Product]
+	dp, _ := _getMemo(parser, _Product, start)
 	if dp < 0 {
 		return -1, nil
 	}
-	key := _key{start: start, rule: _Product}
-	n := parser.act[key]
-	if n != nil {
-		n := n.((big.Float))
+	if n, ok := parser.actProduct[start]; ok {
 		return start + int(dp-1), &n
 	}
 	var node (big.Float)
@@ -1144,7 +1410,6 @@ func _ProductAction(parser *_Parser, start int) (int, *(big.Float)) {
 		// l:Value tail:ProductTail*
 		// l:Value
 		{
-			pos2 := pos
 			// Value
 			if p, n := _ValueAction(parser, pos); n == nil {
 				goto fail
@@ -1152,11 +1417,9 @@ func _ProductAction(parser *_Parser, start int) (int, *(big.Float)) {
 				label0 = *n
 				pos = p
 			}
-			labels[0] = parser.text[pos2:pos]
 		}
 		// tail:ProductTail*
 		{
-			pos3 := pos
 			// ProductTail*
 			for {
 				pos5 := pos
@@ -1174,7 +1437,6 @@ func _ProductAction(parser *_Parser, start int) (int, *(big.Float)) {
 				pos = pos5
 				break
 			}
-			labels[1] = parser.text[pos3:pos]
 		}
 		node = func(
 			start, end int, l big.Float, tail []tail) big.Float {
@@ -1182,15 +1444,16 @@ func _ProductAction(parser *_Parser, start int) (int, *(big.Float)) {
 		}(
 			start0, pos, label0, label1)
 	}
-	parser.act[key] = node
+	if parser.actProduct == nil {
+		parser.actProduct = make(map[int](big.Float))
+	}
+	parser.actProduct[start] = node
 	return pos, &node
 fail:
 	return -1, nil
 }
 
 func _ProductTailAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
-	var labels [2]string
-	use(labels)
 	if dp, de, ok := _memo(parser, _ProductTail, start); ok {
 		return dp, de
 	}
@@ -1199,21 +1462,17 @@ func _ProductTailAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
 	// op:MulOp r:Value
 	// op:MulOp
 	{
-		pos1 := pos
 		// MulOp
 		if !_accept(parser, _MulOpAccepts, &pos, &perr) {
 			goto fail
 		}
-		labels[0] = parser.text[pos1:pos]
 	}
 	// r:Value
 	{
-		pos2 := pos
 		// Value
 		if !_accept(parser, _ValueAccepts, &pos, &perr) {
 			goto fail
 		}
-		labels[1] = parser.text[pos2:pos]
 	}
 	return _memoize(parser, _ProductTail, start, pos, perr)
 fail:
@@ -1221,13 +1480,14 @@ fail:
 }
 
 func _ProductTailNode(parser *_Parser, start int) (int, *peg.Node) {
-	var labels [2]string
-	use(labels)
-	dp := parser.deltaPos[start][_ProductTail]
+	dp, _ := _getMemo(parser, _ProductTail, start)
 	if dp < 0 {
 		return -1, nil
 	}
 	key := _key{start: start, rule: _ProductTail}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
 	node := parser.node[key]
 	if node != nil {
 		return start + int(dp-1), node
@@ -1238,21 +1498,17 @@ func _ProductTailNode(parser *_Parser, start int) (int, *peg.Node) {
 	// op:MulOp r:Value
 	// op:MulOp
 	{
-		pos1 := pos
 		// MulOp
 		if !_node(parser, _MulOpNode, node, &pos) {
 			goto fail
 		}
-		labels[0] = parser.text[pos1:pos]
 	}
 	// r:Value
 	{
-		pos2 := pos
 		// Value
 		if !_node(parser, _ValueNode, node, &pos) {
 			goto fail
 		}
-		labels[1] = parser.text[pos2:pos]
 	}
 	node.Text = parser.text[start:pos]
 	parser.node[key] = node
@@ -1262,9 +1518,7 @@ fail:
 }
 
 func _ProductTailFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
-	var labels [2]string
-	use(labels)
-	pos, failure := _failMemo(parser, _ProductTail, start, errPos)
+	pos, failure := _failMemo(parser, _ProductTail, start, errPos, "ProductTail")
 	if failure != nil {
 		return pos, failure
 	}
@@ -1273,25 +1527,24 @@ func _ProductTailFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 		Pos:  int(start),
 	}
 	key := _key{start: start, rule: _ProductTail}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
 	// action
 	// op:MulOp r:Value
 	// op:MulOp
 	{
-		pos1 := pos
 		// MulOp
 		if !_fail(parser, _MulOpFail, errPos, failure, &pos) {
 			goto fail
 		}
-		labels[0] = parser.text[pos1:pos]
 	}
 	// r:Value
 	{
-		pos2 := pos
 		// Value
 		if !_fail(parser, _ValueFail, errPos, failure, &pos) {
 			goto fail
 		}
-		labels[1] = parser.text[pos2:pos]
 	}
 	parser.fail[key] = failure
 	return pos, failure
@@ -1301,18 +1554,13 @@ fail:
 }
 
 func _ProductTailAction(parser *_Parser, start int) (int, *tail) {
-	var labels [2]string
-	use(labels)
 	var label0 op
 	var label1 (big.Float)
-	dp := parser.deltaPos[start][_ProductTail]
+	dp, _ := _getMemo(parser, _ProductTail, start)
 	if dp < 0 {
 		return -1, nil
 	}
-	key := _key{start: start, rule: _ProductTail}
-	n := parser.act[key]
-	if n != nil {
-		n := n.(tail)
+	if n, ok := parser.actProductTail[start]; ok {
 		return start + int(dp-1), &n
 	}
 	var node tail
@@ -1323,7 +1571,6 @@ func _ProductTailAction(parser *_Parser, start int) (int, *tail) {
 		// op:MulOp r:Value
 		// op:MulOp
 		{
-			pos2 := pos
 			// MulOp
 			if p, n := _MulOpAction(parser, pos); n == nil {
 				goto fail
@@ -1331,11 +1578,9 @@ func _ProductTailAction(parser *_Parser, start int) (int, *tail) {
 				label0 = *n
 				pos = p
 			}
-			labels[0] = parser.text[pos2:pos]
 		}
 		// r:Value
 		{
-			pos3 := pos
 			// Value
 			if p, n := _ValueAction(parser, pos); n == nil {
 				goto fail
@@ -1343,7 +1588,6 @@ func _ProductTailAction(parser *_Parser, start int) (int, *tail) {
 				label1 = *n
 				pos = p
 			}
-			labels[1] = parser.text[pos3:pos]
 		}
 		node = func(
 			start, end int, op op, r big.Float) tail {
@@ -1351,7 +1595,10 @@ func _ProductTailAction(parser *_Parser, start int) (int, *tail) {
 		}(
 			start0, pos, label0, label1)
 	}
-	parser.act[key] = node
+	if parser.actProductTail == nil {
+		parser.actProductTail = make(map[int]tail)
+	}
+	parser.actProductTail[start] = node
 	return pos, &node
 fail:
 	return -1, nil
@@ -1368,11 +1615,9 @@ func _MulOpAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
 		// action
 		// _ "*"
 		// _
-		if !_accept(parser, __Accepts, &pos, &perr) {
-			goto fail4
-		}
+		_accept(parser, __Accepts, &pos, &perr)
 		// "*"
-		if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "*" {
+		if pos >= len(parser.text) || parser.text[pos] != "*"[0] {
 			perr = _max(perr, pos)
 			goto fail4
 		}
@@ -1383,11 +1628,9 @@ func _MulOpAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
 		// action
 		// _ "/"
 		// _
-		if !_accept(parser, __Accepts, &pos, &perr) {
-			goto fail6
-		}
+		_accept(parser, __Accepts, &pos, &perr)
 		// "/"
-		if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "/" {
+		if pos >= len(parser.text) || parser.text[pos] != "/"[0] {
 			perr = _max(perr, pos)
 			goto fail6
 		}
@@ -1405,11 +1648,14 @@ fail:
 }
 
 func _MulOpNode(parser *_Parser, start int) (int, *peg.Node) {
-	dp := parser.deltaPos[start][_MulOp]
+	dp, _ := _getMemo(parser, _MulOp, start)
 	if dp < 0 {
 		return -1, nil
 	}
 	key := _key{start: start, rule: _MulOp}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
 	node := parser.node[key]
 	if node != nil {
 		return start + int(dp-1), node
@@ -1423,11 +1669,9 @@ func _MulOpNode(parser *_Parser, start int) (int, *peg.Node) {
 		// action
 		// _ "*"
 		// _
-		if !_node(parser, __Node, node, &pos) {
-			goto fail4
-		}
+		_node(parser, __Node, node, &pos)
 		// "*"
-		if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "*" {
+		if pos >= len(parser.text) || parser.text[pos] != "*"[0] {
 			goto fail4
 		}
 		node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
@@ -1439,11 +1683,9 @@ func _MulOpNode(parser *_Parser, start int) (int, *peg.Node) {
 		// action
 		// _ "/"
 		// _
-		if !_node(parser, __Node, node, &pos) {
-			goto fail6
-		}
+		_node(parser, __Node, node, &pos)
 		// "/"
-		if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "/" {
+		if pos >= len(parser.text) || parser.text[pos] != "/"[0] {
 			goto fail6
 		}
 		node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
@@ -1463,7 +1705,7 @@ fail:
 }
 
 func _MulOpFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
-	pos, failure := _failMemo(parser, _MulOp, start, errPos)
+	pos, failure := _failMemo(parser, _MulOp, start, errPos, "operator")
 	if failure != nil {
 		return pos, failure
 	}
@@ -1472,21 +1714,23 @@ func _MulOpFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 		Pos:  int(start),
 	}
 	key := _key{start: start, rule: _MulOp}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
 	// _ "*" {…}/_ "/" {…}
 	{
 		pos3 := pos
 		// action
 		// _ "*"
 		// _
-		if !_fail(parser, __Fail, errPos, failure, &pos) {
-			goto fail4
-		}
+		_fail(parser, __Fail, errPos, failure, &pos)
 		// "*"
-		if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "*" {
+		if pos >= len(parser.text) || parser.text[pos] != "*"[0] {
 			if pos >= errPos {
 				failure.Kids = append(failure.Kids, &peg.Fail{
 					Pos:  int(pos),
 					Want: "\"*\"",
+					Code: peg.ExpectedLiteral,
 				})
 			}
 			goto fail4
@@ -1498,15 +1742,14 @@ func _MulOpFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 		// action
 		// _ "/"
 		// _
-		if !_fail(parser, __Fail, errPos, failure, &pos) {
-			goto fail6
-		}
+		_fail(parser, __Fail, errPos, failure, &pos)
 		// "/"
-		if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "/" {
+		if pos >= len(parser.text) || parser.text[pos] != "/"[0] {
 			if pos >= errPos {
 				failure.Kids = append(failure.Kids, &peg.Fail{
 					Pos:  int(pos),
 					Want: "\"/\"",
+					Code: peg.ExpectedLiteral,
 				})
 			}
 			goto fail6
@@ -1524,19 +1767,17 @@ func _MulOpFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 fail:
 	failure.Kids = nil
 	failure.Want = "operator"
+	failure.Code = peg.NamedRule
 	parser.fail[key] = failure
 	return -1, failure
 }
 
 func _MulOpAction(parser *_Parser, start int) (int, *op) {
-	dp := parser.deltaPos[start][_MulOp]
+	dp, _ := _getMemo(parser, _MulOp, start)
 	if dp < 0 {
 		return -1, nil
 	}
-	key := _key{start: start, rule: _MulOp}
-	n := parser.act[key]
-	if n != nil {
-		n := n.(op)
+	if n, ok := parser.actMulOp[start]; ok {
 		return start + int(dp-1), &n
 	}
 	var node op
@@ -1550,13 +1791,12 @@ func _MulOpAction(parser *_Parser, start int) (int, *op) {
 			start5 := pos
 			// _ "*"
 			// _
-			if p, n := __Action(parser, pos); n == nil {
-				goto fail4
-			} else {
+			{
+				p, _ := __Action(parser, pos)
 				pos = p
 			}
 			// "*"
-			if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "*" {
+			if pos >= len(parser.text) || parser.text[pos] != "*"[0] {
 				goto fail4
 			}
 			pos++
@@ -1575,13 +1815,12 @@ func _MulOpAction(parser *_Parser, start int) (int, *op) {
 			start8 := pos
 			// _ "/"
 			// _
-			if p, n := __Action(parser, pos); n == nil {
-				goto fail7
-			} else {
+			{
+				p, _ := __Action(parser, pos)
 				pos = p
 			}
 			// "/"
-			if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "/" {
+			if pos >= len(parser.text) || parser.text[pos] != "/"[0] {
 				goto fail7
 			}
 			pos++
@@ -1598,15 +1837,16 @@ func _MulOpAction(parser *_Parser, start int) (int, *op) {
 		goto fail
 	ok0:
 	}
-	parser.act[key] = node
+	if parser.actMulOp == nil {
+		parser.actMulOp = make(map[int]op)
+	}
+	parser.actMulOp[start] = node
 	return pos, &node
 fail:
 	return -1, nil
 }
 
 func _ValueAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
-	var labels [1]string
-	use(labels)
 	if dp, de, ok := _memo(parser, _Value, start); ok {
 		return dp, de
 	}
@@ -1624,30 +1864,24 @@ func _ValueAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
 		// action
 		// _ "(" e:Sum _ ")"
 		// _
-		if !_accept(parser, __Accepts, &pos, &perr) {
-			goto fail5
-		}
+		_accept(parser, __Accepts, &pos, &perr)
 		// "("
-		if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "(" {
+		if pos >= len(parser.text) || parser.text[pos] != "("[0] {
 			perr = _max(perr, pos)
 			goto fail5
 		}
 		pos++
 		// e:Sum
 		{
-			pos7 := pos
 			// Sum
 			if !_accept(parser, _SumAccepts, &pos, &perr) {
 				goto fail5
 			}
-			labels[0] = parser.text[pos7:pos]
 		}
 		// _
-		if !_accept(parser, __Accepts, &pos, &perr) {
-			goto fail5
-		}
+		_accept(parser, __Accepts, &pos, &perr)
 		// ")"
-		if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != ")" {
+		if pos >= len(parser.text) || parser.text[pos] != ")"[0] {
 			perr = _max(perr, pos)
 			goto fail5
 		}
@@ -1664,13 +1898,14 @@ fail:
 }
 
 func _ValueNode(parser *_Parser, start int) (int, *peg.Node) {
-	var labels [1]string
-	use(labels)
-	dp := parser.deltaPos[start][_Value]
+	dp, _ := _getMemo(parser, _Value, start)
 	if dp < 0 {
 		return -1, nil
 	}
 	key := _key{start: start, rule: _Value}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
 	node := parser.node[key]
 	if node != nil {
 		return start + int(dp-1), node
@@ -1692,30 +1927,24 @@ func _ValueNode(parser *_Parser, start int) (int, *peg.Node) {
 		// action
 		// _ "(" e:Sum _ ")"
 		// _
-		if !_node(parser, __Node, node, &pos) {
-			goto fail5
-		}
+		_node(parser, __Node, node, &pos)
 		// "("
-		if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "(" {
+		if pos >= len(parser.text) || parser.text[pos] != "("[0] {
 			goto fail5
 		}
 		node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
 		pos++
 		// e:Sum
 		{
-			pos7 := pos
 			// Sum
 			if !_node(parser, _SumNode, node, &pos) {
 				goto fail5
 			}
-			labels[0] = parser.text[pos7:pos]
 		}
 		// _
-		if !_node(parser, __Node, node, &pos) {
-			goto fail5
-		}
+		_node(parser, __Node, node, &pos)
 		// ")"
-		if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != ")" {
+		if pos >= len(parser.text) || parser.text[pos] != ")"[0] {
 			goto fail5
 		}
 		node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
@@ -1735,9 +1964,7 @@ fail:
 }
 
 func _ValueFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
-	var labels [1]string
-	use(labels)
-	pos, failure := _failMemo(parser, _Value, start, errPos)
+	pos, failure := _failMemo(parser, _Value, start, errPos, "Value")
 	if failure != nil {
 		return pos, failure
 	}
@@ -1746,6 +1973,9 @@ func _ValueFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 		Pos:  int(start),
 	}
 	key := _key{start: start, rule: _Value}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
 	// Num/_ "(" e:Sum _ ")" {…}
 	{
 		pos3 := pos
@@ -1759,15 +1989,14 @@ func _ValueFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 		// action
 		// _ "(" e:Sum _ ")"
 		// _
-		if !_fail(parser, __Fail, errPos, failure, &pos) {
-			goto fail5
-		}
+		_fail(parser, __Fail, errPos, failure, &pos)
 		// "("
-		if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "(" {
+		if pos >= len(parser.text) || parser.text[pos] != "("[0] {
 			if pos >= errPos {
 				failure.Kids = append(failure.Kids, &peg.Fail{
 					Pos:  int(pos),
 					Want: "\"(\"",
+					Code: peg.ExpectedLiteral,
 				})
 			}
 			goto fail5
@@ -1775,23 +2004,20 @@ func _ValueFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 		pos++
 		// e:Sum
 		{
-			pos7 := pos
 			// Sum
 			if !_fail(parser, _SumFail, errPos, failure, &pos) {
 				goto fail5
 			}
-			labels[0] = parser.text[pos7:pos]
 		}
 		// _
-		if !_fail(parser, __Fail, errPos, failure, &pos) {
-			goto fail5
-		}
+		_fail(parser, __Fail, errPos, failure, &pos)
 		// ")"
-		if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != ")" {
+		if pos >= len(parser.text) || parser.text[pos] != ")"[0] {
 			if pos >= errPos {
 				failure.Kids = append(failure.Kids, &peg.Fail{
 					Pos:  int(pos),
 					Want: "\")\"",
+					Code: peg.ExpectedLiteral,
 				})
 			}
 			goto fail5
@@ -1811,17 +2037,12 @@ fail:
 }
 
 func _ValueAction(parser *_Parser, start int) (int, *(big.Float)) {
-	var labels [1]string
-	use(labels)
 	var label0 (big.Float)
-	dp := parser.deltaPos[start][_Value]
+	dp, _ := _getMemo(parser, _Value, start)
 	if dp < 0 {
 		return -1, nil
 	}
-	key := _key{start: start, rule: _Value}
-	n := parser.act[key]
-	if n != nil {
-		n := n.((big.Float))
+	if n, ok := parser.actValue[start]; ok {
 		return start + int(dp-1), &n
 	}
 	var node (big.Float)
@@ -1846,19 +2067,17 @@ func _ValueAction(parser *_Parser, start int) (int, *(big.Float)) {
 			start6 := pos
 			// _ "(" e:Sum _ ")"
 			// _
-			if p, n := __Action(parser, pos); n == nil {
-				goto fail5
-			} else {
+			{
+				p, _ := __Action(parser, pos)
 				pos = p
 			}
 			// "("
-			if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "(" {
+			if pos >= len(parser.text) || parser.text[pos] != "("[0] {
 				goto fail5
 			}
 			pos++
 			// e:Sum
 			{
-				pos8 := pos
 				// Sum
 				if p, n := _SumAction(parser, pos); n == nil {
 					goto fail5
@@ -1866,16 +2085,14 @@ func _ValueAction(parser *_Parser, start int) (int, *(big.Float)) {
 					label0 = *n
 					pos = p
 				}
-				labels[0] = parser.text[pos8:pos]
 			}
 			// _
-			if p, n := __Action(parser, pos); n == nil {
-				goto fail5
-			} else {
+			{
+				p, _ := __Action(parser, pos)
 				pos = p
 			}
 			// ")"
-			if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != ")" {
+			if pos >= len(parser.text) || parser.text[pos] != ")"[0] {
 				goto fail5
 			}
 			pos++
@@ -1892,15 +2109,16 @@ func _ValueAction(parser *_Parser, start int) (int, *(big.Float)) {
 		goto fail
 	ok0:
 	}
-	parser.act[key] = node
+	if parser.actValue == nil {
+		parser.actValue = make(map[int](big.Float))
+	}
+	parser.actValue[start] = node
 	return pos, &node
 fail:
 	return -1, nil
 }
 
 func _NumAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
-	var labels [1]string
-	use(labels)
 	if dp, de, ok := _memo(parser, _Num, start); ok {
 		return dp, de
 	}
@@ -1908,12 +2126,9 @@ func _NumAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
 	// action
 	// _ n:([0-9]+ ("." [0-9]+)?)
 	// _
-	if !_accept(parser, __Accepts, &pos, &perr) {
-		goto fail
-	}
+	_accept(parser, __Accepts, &pos, &perr)
 	// n:([0-9]+ ("." [0-9]+)?)
 	{
-		pos1 := pos
 		// ([0-9]+ ("." [0-9]+)?)
 		// [0-9]+ ("." [0-9]+)?
 		// [0-9]+
@@ -1944,7 +2159,7 @@ func _NumAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
 			// ("." [0-9]+)
 			// "." [0-9]+
 			// "."
-			if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "." {
+			if pos >= len(parser.text) || parser.text[pos] != "."[0] {
 				perr = _max(perr, pos)
 				goto fail9
 			}
@@ -1976,7 +2191,6 @@ func _NumAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
 			pos = pos8
 		ok15:
 		}
-		labels[0] = parser.text[pos1:pos]
 	}
 	perr = start
 	return _memoize(parser, _Num, start, pos, perr)
@@ -1985,13 +2199,14 @@ fail:
 }
 
 func _NumNode(parser *_Parser, start int) (int, *peg.Node) {
-	var labels [1]string
-	use(labels)
-	dp := parser.deltaPos[start][_Num]
+	dp, _ := _getMemo(parser, _Num, start)
 	if dp < 0 {
 		return -1, nil
 	}
 	key := _key{start: start, rule: _Num}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
 	node := parser.node[key]
 	if node != nil {
 		return start + int(dp-1), node
@@ -2001,12 +2216,9 @@ func _NumNode(parser *_Parser, start int) (int, *peg.Node) {
 	// action
 	// _ n:([0-9]+ ("." [0-9]+)?)
 	// _
-	if !_node(parser, __Node, node, &pos) {
-		goto fail
-	}
+	_node(parser, __Node, node, &pos)
 	// n:([0-9]+ ("." [0-9]+)?)
 	{
-		pos1 := pos
 		// ([0-9]+ ("." [0-9]+)?)
 		{
 			nkids2 := len(node.Kids)
@@ -2046,7 +2258,7 @@ func _NumNode(parser *_Parser, start int) (int, *peg.Node) {
 					pos013 := pos
 					// "." [0-9]+
 					// "."
-					if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "." {
+					if pos >= len(parser.text) || parser.text[pos] != "."[0] {
 						goto fail11
 					}
 					node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
@@ -2087,7 +2299,6 @@ func _NumNode(parser *_Parser, start int) (int, *peg.Node) {
 			sub := _sub(parser, pos03, pos, node.Kids[nkids2:])
 			node.Kids = append(node.Kids[:nkids2], sub)
 		}
-		labels[0] = parser.text[pos1:pos]
 	}
 	node.Text = parser.text[start:pos]
 	parser.node[key] = node
@@ -2097,9 +2308,7 @@ fail:
 }
 
 func _NumFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
-	var labels [1]string
-	use(labels)
-	pos, failure := _failMemo(parser, _Num, start, errPos)
+	pos, failure := _failMemo(parser, _Num, start, errPos, "number")
 	if failure != nil {
 		return pos, failure
 	}
@@ -2108,15 +2317,15 @@ func _NumFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 		Pos:  int(start),
 	}
 	key := _key{start: start, rule: _Num}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
 	// action
 	// _ n:([0-9]+ ("." [0-9]+)?)
 	// _
-	if !_fail(parser, __Fail, errPos, failure, &pos) {
-		goto fail
-	}
+	_fail(parser, __Fail, errPos, failure, &pos)
 	// n:([0-9]+ ("." [0-9]+)?)
 	{
-		pos1 := pos
 		// ([0-9]+ ("." [0-9]+)?)
 		// [0-9]+ ("." [0-9]+)?
 		// [0-9]+
@@ -2126,6 +2335,7 @@ func _NumFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 				failure.Kids = append(failure.Kids, &peg.Fail{
 					Pos:  int(pos),
 					Want: "[0-9]",
+					Code: peg.ExpectedClass,
 				})
 			}
 			goto fail
@@ -2140,6 +2350,7 @@ func _NumFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 					failure.Kids = append(failure.Kids, &peg.Fail{
 						Pos:  int(pos),
 						Want: "[0-9]",
+						Code: peg.ExpectedClass,
 					})
 				}
 				goto fail6
@@ -2157,11 +2368,12 @@ func _NumFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 			// ("." [0-9]+)
 			// "." [0-9]+
 			// "."
-			if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "." {
+			if pos >= len(parser.text) || parser.text[pos] != "."[0] {
 				if pos >= errPos {
 					failure.Kids = append(failure.Kids, &peg.Fail{
 						Pos:  int(pos),
 						Want: "\".\"",
+						Code: peg.ExpectedLiteral,
 					})
 				}
 				goto fail9
@@ -2174,6 +2386,7 @@ func _NumFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 					failure.Kids = append(failure.Kids, &peg.Fail{
 						Pos:  int(pos),
 						Want: "[0-9]",
+						Code: peg.ExpectedClass,
 					})
 				}
 				goto fail9
@@ -2188,6 +2401,7 @@ func _NumFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 						failure.Kids = append(failure.Kids, &peg.Fail{
 							Pos:  int(pos),
 							Want: "[0-9]",
+							Code: peg.ExpectedClass,
 						})
 					}
 					goto fail14
@@ -2204,7 +2418,6 @@ func _NumFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 			pos = pos8
 		ok15:
 		}
-		labels[0] = parser.text[pos1:pos]
 	}
 	failure.Kids = nil
 	parser.fail[key] = failure
@@ -2212,22 +2425,18 @@ func _NumFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 fail:
 	failure.Kids = nil
 	failure.Want = "number"
+	failure.Code = peg.NamedRule
 	parser.fail[key] = failure
 	return -1, failure
 }
 
 func _NumAction(parser *_Parser, start int) (int, *(big.Float)) {
-	var labels [1]string
-	use(labels)
 	var label0 string
-	dp := parser.deltaPos[start][_Num]
+	dp, _ := _getMemo(parser, _Num, start)
 	if dp < 0 {
 		return -1, nil
 	}
-	key := _key{start: start, rule: _Num}
-	n := parser.act[key]
-	if n != nil {
-		n := n.((big.Float))
+	if n, ok := parser.actNum[start]; ok {
 		return start + int(dp-1), &n
 	}
 	var node (big.Float)
@@ -2237,100 +2446,75 @@ func _NumAction(parser *_Parser, start int) (int, *(big.Float)) {
 		start0 := pos
 		// _ n:([0-9]+ ("." [0-9]+)?)
 		// _
-		if p, n := __Action(parser, pos); n == nil {
-			goto fail
-		} else {
+		{
+			p, _ := __Action(parser, pos)
 			pos = p
 		}
 		// n:([0-9]+ ("." [0-9]+)?)
 		{
-			pos2 := pos
 			// ([0-9]+ ("." [0-9]+)?)
 			// [0-9]+ ("." [0-9]+)?
 			{
-				var node3 string
+				pos4 := pos
+				// [0-9]+ ("." [0-9]+)?
 				// [0-9]+
-				{
-					var node6 string
-					// [0-9]
-					if r, w := _next(parser, pos); r < '0' || r > '9' {
-						goto fail
-					} else {
-						node6 = parser.text[pos : pos+w]
-						pos += w
-					}
-					node3 += node6
+				// [0-9]
+				if r, w := _next(parser, pos); r < '0' || r > '9' {
+					goto fail
+				} else {
+					pos += w
 				}
 				for {
-					pos5 := pos
-					var node6 string
+					pos7 := pos
 					// [0-9]
 					if r, w := _next(parser, pos); r < '0' || r > '9' {
-						goto fail7
+						goto fail9
 					} else {
-						node6 = parser.text[pos : pos+w]
 						pos += w
 					}
-					node3 += node6
 					continue
-				fail7:
-					pos = pos5
+				fail9:
+					pos = pos7
 					break
 				}
-				label0, node3 = label0+node3, ""
 				// ("." [0-9]+)?
 				{
-					pos9 := pos
+					pos11 := pos
 					// ("." [0-9]+)
 					// "." [0-9]+
-					{
-						var node11 string
-						// "."
-						if len(parser.text[pos:]) < 1 || parser.text[pos:pos+1] != "." {
-							goto fail10
-						}
-						node11 = parser.text[pos : pos+1]
-						pos++
-						node3, node11 = node3+node11, ""
-						// [0-9]+
-						{
-							var node14 string
-							// [0-9]
-							if r, w := _next(parser, pos); r < '0' || r > '9' {
-								goto fail10
-							} else {
-								node14 = parser.text[pos : pos+w]
-								pos += w
-							}
-							node11 += node14
-						}
-						for {
-							pos13 := pos
-							var node14 string
-							// [0-9]
-							if r, w := _next(parser, pos); r < '0' || r > '9' {
-								goto fail15
-							} else {
-								node14 = parser.text[pos : pos+w]
-								pos += w
-							}
-							node11 += node14
-							continue
-						fail15:
-							pos = pos13
-							break
+					// "."
+					if pos >= len(parser.text) || parser.text[pos] != "."[0] {
+						goto fail12
+					}
+					pos++
+					// [0-9]+
+					// [0-9]
+					if r, w := _next(parser, pos); r < '0' || r > '9' {
+						goto fail12
+					} else {
+						pos += w
+					}
+					for {
+						pos15 := pos
+						// [0-9]
+						if r, w := _next(parser, pos); r < '0' || r > '9' {
+							goto fail17
+						} else {
+							pos += w
 						}
-						node3, node11 = node3+node11, ""
+						continue
+					fail17:
+						pos = pos15
+						break
 					}
-					goto ok16
-				fail10:
-					node3 = ""
-					pos = pos9
-				ok16:
+					goto ok18
+				fail12:
+					pos = pos11
+				ok18:
 				}
-				label0, node3 = label0+node3, ""
+				label0 = parser.text[pos4:pos]
 			}
-			labels[0] = parser.text[pos2:pos]
+
 		}
 		node = func(
 			start, end int, n string) big.Float {
@@ -2340,7 +2524,10 @@ func _NumAction(parser *_Parser, start int) (int, *(big.Float)) {
 		}(
 			start0, pos, label0)
 	}
-	parser.act[key] = node
+	if parser.actNum == nil {
+		parser.actNum = make(map[int](big.Float))
+	}
+	parser.actNum[start] = node
 	return pos, &node
 fail:
 	return -1, nil
@@ -2387,11 +2574,14 @@ func __Accepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
 func __Node(parser *_Parser, start int) (int, *peg.Node) {
 	var labels [1]string
 	use(labels)
-	dp := parser.deltaPos[start][__]
+	dp, _ := _getMemo(parser, __, start)
 	if dp < 0 {
 		return -1, nil
 	}
 	key := _key{start: start, rule: __}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
 	node := parser.node[key]
 	if node != nil {
 		return start + int(dp-1), node
@@ -2440,7 +2630,15 @@ func __Node(parser *_Parser, start int) (int, *peg.Node) {
 func __Fail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 	var labels [1]string
 	use(labels)
-	pos, failure := _failMemo(parser, __, start, errPos)
+	pos, failure := _failMemo(parser, __, start, errPos, "space")
+	if failure != nil && pos < 0 {
+		// The rule never fails, so its callers do not check,
+		// and it accepts even where the Fail pass is cut short.
+		pos = start
+		if dp, _ := _getMemo(parser, __, start); dp > 0 {
+			pos += int(dp - 1)
+		}
+	}
 	if failure != nil {
 		return pos, failure
 	}
@@ -2449,6 +2647,9 @@ func __Fail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 		Pos:  int(start),
 	}
 	key := _key{start: start, rule: __}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
 	// (s:. &{…})*
 	for {
 		pos1 := pos
@@ -2463,6 +2664,7 @@ func __Fail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 					failure.Kids = append(failure.Kids, &peg.Fail{
 						Pos:  int(pos),
 						Want: ".",
+						Code: peg.ExpectedAny,
 					})
 				}
 				goto fail3
@@ -2477,6 +2679,7 @@ func __Fail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 				failure.Kids = append(failure.Kids, &peg.Fail{
 					Pos:  int(pos),
 					Want: "&{" + " isSpace(s) " + "}",
+					Code: peg.PredicateFailed,
 				})
 			}
 			goto fail3
@@ -2495,14 +2698,11 @@ func __Action(parser *_Parser, start int) (int, *string) {
 	var labels [1]string
 	use(labels)
 	var label0 string
-	dp := parser.deltaPos[start][__]
+	dp, _ := _getMemo(parser, __, start)
 	if dp < 0 {
 		return -1, nil
 	}
-	key := _key{start: start, rule: __}
-	n := parser.act[key]
-	if n != nil {
-		n := n.(string)
+	if n, ok := parser.act_[start]; ok {
 		return start + int(dp-1), &n
 	}
 	var node string
@@ -2542,7 +2742,10 @@ func __Action(parser *_Parser, start int) (int, *string) {
 		pos = pos1
 		break
 	}
-	parser.act[key] = node
+	if parser.act_ == nil {
+		parser.act_ = make(map[int]string)
+	}
+	parser.act_[start] = node
 	return pos, &node
 }
 
@@ -2576,11 +2779,14 @@ fail:
 }
 
 func _EOFNode(parser *_Parser, start int) (int, *peg.Node) {
-	dp := parser.deltaPos[start][_EOF]
+	dp, _ := _getMemo(parser, _EOF, start)
 	if dp < 0 {
 		return -1, nil
 	}
 	key := _key{start: start, rule: _EOF}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
 	node := parser.node[key]
 	if node != nil {
 		return start + int(dp-1), node
@@ -2613,7 +2819,7 @@ fail:
 }
 
 func _EOFFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
-	pos, failure := _failMemo(parser, _EOF, start, errPos)
+	pos, failure := _failMemo(parser, _EOF, start, errPos, "end of file")
 	if failure != nil {
 		return pos, failure
 	}
@@ -2622,6 +2828,9 @@ func _EOFFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 		Pos:  int(start),
 	}
 	key := _key{start: start, rule: _EOF}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
 	// !.
 	{
 		pos1 := pos
@@ -2632,6 +2841,7 @@ func _EOFFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 				failure.Kids = append(failure.Kids, &peg.Fail{
 					Pos:  int(pos),
 					Want: ".",
+					Code: peg.ExpectedAny,
 				})
 			}
 			goto ok0
@@ -2644,6 +2854,7 @@ func _EOFFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 			failure.Kids = append(failure.Kids, &peg.Fail{
 				Pos:  int(pos),
 				Want: "!.",
+				Code: peg.PredicateFailed,
 			})
 		}
 		goto fail
@@ -2657,19 +2868,17 @@ func _EOFFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 fail:
 	failure.Kids = nil
 	failure.Want = "end of file"
+	failure.Code = peg.NamedRule
 	parser.fail[key] = failure
 	return -1, failure
 }
 
 func _EOFAction(parser *_Parser, start int) (int, *string) {
-	dp := parser.deltaPos[start][_EOF]
+	dp, _ := _getMemo(parser, _EOF, start)
 	if dp < 0 {
 		return -1, nil
 	}
-	key := _key{start: start, rule: _EOF}
-	n := parser.act[key]
-	if n != nil {
-		n := n.(string)
+	if n, ok := parser.actEOF[start]; ok {
 		return start + int(dp-1), &n
 	}
 	var node string
@@ -2689,7 +2898,10 @@ func _EOFAction(parser *_Parser, start int) (int, *string) {
 		pos = pos1
 		node = ""
 	}
-	parser.act[key] = node
+	if parser.actEOF == nil {
+		parser.actEOF = make(map[int]string)
+	}
+	parser.actEOF[start] = node
 	return pos, &node
 fail:
 	return -1, nil
@@ -0,0 +1,5655 @@
+// JSON is an example parser of newline-delimited JSON, RFC 8259,
+// writing each value to standard output compacted, one per line.
+// You can build it from json.peggy with
+//
+//	peggy -o json.go json.peggy
+//
+// Each line is parsed by a new parser, so memory is bounded by the longest line,
+// and the values slice the input instead of copying it,
+// except for strings with escapes.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+func main() {
+	in := bufio.NewScanner(os.Stdin)
+	in.Buffer(nil, 64<<20)
+	out := bufio.NewWriter(os.Stdout)
+	status := 0
+	for line := 1; in.Scan(); line++ {
+		v, err := parse(in.Text())
+		if err != nil {
+			out.Flush()
+			e := err.(peg.Error)
+			fmt.Fprintf(os.Stderr, "%d.%d: %s\n", line, e.Loc.Column, e.Message)
+			status = 1
+			continue
+		}
+		out.Write(v.AppendTo(nil))
+		out.WriteByte('\n')
+	}
+	if err := in.Err(); err != nil {
+		out.Flush()
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := out.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(status)
+}
+
+// parse returns the Value of the JSON text or a peg.Error.
+// The cost of building the error is bounded,
+// so adversarial input cannot make it expensive.
+func parse(text string) (Value, error) {
+	_, v, err := _DocumentParseBounded(text, 32)
+	return v, err
+}
+
+// A Kind is the kind of a JSON value.
+type Kind int
+
+const (
+	Null Kind = iota
+	Bool
+	Number
+	String
+	Array
+	Object
+)
+
+// A Value is a JSON value.
+type Value struct {
+	Kind Kind
+
+	// Text is the literal of a Null, Bool, or Number,
+	// or the unescaped text of a String.
+	Text string
+
+	// Elems are the elements of an Array
+	// or the values of the members of an Object.
+	Elems []Value
+
+	// Key is the key of the value of an Object member,
+	// and the empty string otherwise.
+	Key string
+}
+
+// AppendTo appends the compact JSON encoding of the Value to b.
+func (v Value) AppendTo(b []byte) []byte {
+	switch v.Kind {
+	case String:
+		return appendQuote(b, v.Text)
+	case Array:
+		b = append(b, '[')
+		for i, e := range v.Elems {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = e.AppendTo(b)
+		}
+		return append(b, ']')
+	case Object:
+		b = append(b, '{')
+		for i, e := range v.Elems {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendQuote(b, e.Key)
+			b = append(b, ':')
+			b = e.AppendTo(b)
+		}
+		return append(b, '}')
+	}
+	return append(b, v.Text...)
+}
+
+// appendQuote appends the JSON string literal of s to b,
+// escaping only what JSON requires.
+func appendQuote(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b = append(b, '\\', c)
+		case c == '\n':
+			b = append(b, '\\', 'n')
+		case c == '\r':
+			b = append(b, '\\', 'r')
+		case c == '\t':
+			b = append(b, '\\', 't')
+		case c < 0x20:
+			b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+		default:
+			b = append(b, c)
+		}
+	}
+	return append(b, '"')
+}
+
+// unescape returns the text of a string literal, without its quotes,
+// with its escapes replaced.
+// Text without escapes is returned as is, sharing its memory.
+func unescape(text string) string {
+	if strings.IndexByte(text, '\\') < 0 {
+		return text
+	}
+	var b strings.Builder
+	b.Grow(len(text))
+	for len(text) > 0 {
+		i := strings.IndexByte(text, '\\')
+		if i < 0 {
+			b.WriteString(text)
+			break
+		}
+		b.WriteString(text[:i])
+		c := text[i+1]
+		text = text[i+2:]
+		switch c {
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			r := hex4(text)
+			text = text[4:]
+			if utf16.IsSurrogate(r) && len(text) >= 6 && text[:2] == `\u` {
+				if d := utf16.DecodeRune(r, hex4(text[2:])); d != utf8.RuneError {
+					r = d
+					text = text[6:]
+				}
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// hex4 returns the rune of the 4 hex digits beginning text.
+func hex4(text string) rune {
+	n, _ := strconv.ParseUint(text[:4], 16, 32)
+	return rune(n)
+}
+
+const (
+	_Document                    int = 0
+	_Value                       int = 1
+	_Object                      int = 2
+	_Member                      int = 3
+	_Array                       int = 4
+	_String                      int = 5
+	_Hex                         int = 6
+	_Number                      int = 7
+	_Int                         int = 8
+	_Frac                        int = 9
+	_Exp                         int = 10
+	_Literal                     int = 11
+	__                           int = 12
+	_Delimited___7b__Member___7d int = 13
+	_Delimited___5b__Value___5d  int = 14
+
+	_N int = 15
+)
+
+type _Parser struct {
+	text                           string
+	deltaPos                       [][_N]int32
+	deltaErr                       [][_N]int32
+	node                           map[_key]*peg.Node
+	fail                           map[_key]*peg.Fail
+	actDocument                    map[int]Value
+	actValue                       map[int]Value
+	actObject                      map[int]Value
+	actMember                      map[int]Value
+	actArray                       map[int]Value
+	actString                      map[int]Value
+	actHex                         map[int]string
+	actNumber                      map[int]Value
+	actInt                         map[int]string
+	actFrac                        map[int]string
+	actExp                         map[int]string
+	actLiteral                     map[int]Value
+	act_                           map[int]string
+	actDelimited___7b__Member___7d map[int][]Value
+	actDelimited___5b__Value___5d  map[int][]Value
+	lastFail                       int
+	// Checks records the failed Expect
+	// and the diagnostics of the actions.
+	peg.Checks
+	// failBudget is the number of rule nodes
+	// that the Fail pass may yet build, if boundFails.
+	failBudget int
+	boundFails bool
+	depth      int
+	// depthFails is the number of rule invocations
+	// that failed by exceeding a maximum depth.
+	depthFails int
+	data       interface{}
+}
+
+type _key struct {
+	start int
+	rule  int
+}
+
+type tooBigError struct{}
+
+func (tooBigError) Error() string { return "input is too big" }
+
+// _NewParser returns a new Parser for the text.
+// Only the memo table used by every pass is allocated here;
+// the tables of the Node, Fail, and Action passes
+// are allocated when the pass is first run.
+func _NewParser(text string) (*_Parser, error) {
+	n := len(text) + 1
+	if n < 0 {
+		return nil, tooBigError{}
+	}
+	p := &_Parser{
+		text:     text,
+		deltaPos: make([][_N]int32, n),
+		deltaErr: make([][_N]int32, n),
+	}
+	return p, nil
+}
+
+// _Diagnostics returns the diagnostics recorded by Diag,
+// in the order that they were recorded, without duplicates,
+// each a peg.Error located at the start of its text
+// with End the location of the end of its text.
+func _Diagnostics(parser *_Parser) []peg.Error {
+	return parser.Diagnostics(peg.Locator{}, parser.text)
+}
+
+// _DocumentMatches returns whether the Document rule matches all of text.
+// It runs only the Accepts pass.
+func _DocumentMatches(text string) bool {
+	parser, err := _NewParser(text)
+	if err != nil {
+		return false
+	}
+	pos, _ := _DocumentAccepts(parser, 0)
+	return pos == len(text)
+}
+
+// _DocumentParseBounded parses the Document rule
+// at the beginning of text, like _DocumentParseAt,
+// but on failure, the Fail pass builds at most max rule nodes,
+// replacing the rest by leaves wanting the rule's name,
+// and the peg.Error lists at most max of the sorted Wants.
+// So the cost of a failure is bounded
+// even for adversarial inputs.
+func _DocumentParseBounded(text string, max int) (end int, v Value, err error) {
+	parser, err := _NewParser(text)
+	if err != nil {
+		return -1, v, err
+	}
+	dp, de := _DocumentAccepts(parser, 0)
+	if dp < 0 {
+		if max < 1 {
+			max = 1
+		}
+		parser.lastFail = de
+		parser.failBudget, parser.boundFails = max, true
+		_, fail := _DocumentFail(parser, 0, de)
+		opts := peg.ErrorOptions{Sort: true, MaxWants: max}
+		return -1, v, opts.SimpleError(text, fail)
+	}
+	end, p := _DocumentAction(parser, 0)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, 0, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _DocumentParseAt parses the Document rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _DocumentParseAt(parser *_Parser, start int) (end int, v Value, err error) {
+	dp, de := _DocumentAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _DocumentFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _DocumentAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _ValueParseAt parses the Value rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _ValueParseAt(parser *_Parser, start int) (end int, v Value, err error) {
+	dp, de := _ValueAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _ValueFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _ValueAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _ObjectParseAt parses the Object rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _ObjectParseAt(parser *_Parser, start int) (end int, v Value, err error) {
+	dp, de := _ObjectAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _ObjectFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _ObjectAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _MemberParseAt parses the Member rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _MemberParseAt(parser *_Parser, start int) (end int, v Value, err error) {
+	dp, de := _MemberAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _MemberFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _MemberAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _ArrayParseAt parses the Array rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _ArrayParseAt(parser *_Parser, start int) (end int, v Value, err error) {
+	dp, de := _ArrayAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _ArrayFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _ArrayAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _StringParseAt parses the String rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _StringParseAt(parser *_Parser, start int) (end int, v Value, err error) {
+	dp, de := _StringAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _StringFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _StringAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _HexParseAt parses the Hex rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _HexParseAt(parser *_Parser, start int) (end int, v string, err error) {
+	dp, de := _HexAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _HexFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _HexAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _NumberParseAt parses the Number rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _NumberParseAt(parser *_Parser, start int) (end int, v Value, err error) {
+	dp, de := _NumberAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _NumberFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _NumberAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _IntParseAt parses the Int rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _IntParseAt(parser *_Parser, start int) (end int, v string, err error) {
+	dp, de := _IntAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _IntFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _IntAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _FracParseAt parses the Frac rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _FracParseAt(parser *_Parser, start int) (end int, v string, err error) {
+	dp, de := _FracAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _FracFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _FracAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _ExpParseAt parses the Exp rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _ExpParseAt(parser *_Parser, start int) (end int, v string, err error) {
+	dp, de := _ExpAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _ExpFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _ExpAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _LiteralParseAt parses the Literal rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _LiteralParseAt(parser *_Parser, start int) (end int, v Value, err error) {
+	dp, de := _LiteralAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _LiteralFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _LiteralAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// __ParseAt parses the _ rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func __ParseAt(parser *_Parser, start int) (end int, v string, err error) {
+	dp, de := __Accepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := __Fail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := __Action(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _Delimited___7b__Member___7dParseAt parses the Delimited<"{", Member, "}"> rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _Delimited___7b__Member___7dParseAt(parser *_Parser, start int) (end int, v []Value, err error) {
+	dp, de := _Delimited___7b__Member___7dAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _Delimited___7b__Member___7dFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _Delimited___7b__Member___7dAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _Delimited___5b__Value___5dParseAt parses the Delimited<"[", Value, "]"> rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _Delimited___5b__Value___5dParseAt(parser *_Parser, start int) (end int, v []Value, err error) {
+	dp, de := _Delimited___5b__Value___5dAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _Delimited___5b__Value___5dFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _Delimited___5b__Value___5dAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+func _max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// _getMemo returns the memo table entries for the rule at the start position.
+// Each entry is 0 if the rule has not been memoized at the position.
+func _getMemo(parser *_Parser, rule, start int) (dp, de int32) {
+	return parser.deltaPos[start][rule], parser.deltaErr[start][rule]
+}
+
+// _setMemo sets the memo table entries for the rule at the start position.
+func _setMemo(parser *_Parser, rule, start int, dp, de int32) {
+	parser.deltaPos[start][rule] = dp
+	parser.deltaErr[start][rule] = de
+}
+
+// _memoize memoizes the rule at the start position,
+// unless the number of depth failures
+// changed from depthFails while parsing it.
+func _memoize(parser *_Parser, rule, start, pos, perr int, depthFails int) (int, int) {
+	parser.lastFail = perr
+	derr := perr - start
+	if parser.depthFails != depthFails {
+		// The result depends on a maximum depth, not just the position.
+		if pos >= 0 {
+			return pos - start, derr
+		}
+		return -1, derr
+	}
+	if pos >= 0 {
+		dpos := pos - start
+		_setMemo(parser, rule, start, int32(dpos+1), int32(derr+1))
+		return dpos, derr
+	}
+	_setMemo(parser, rule, start, -1, int32(derr+1))
+	return -1, derr
+}
+
+// _ensureMemo returns the memo table entries for the rule at the start position,
+// first running the Accepts pass if the rule was not memoized,
+// which happens if its result depends on a maximum depth.
+func _ensureMemo(parser *_Parser, rule, start int) (dp, de int32) {
+	if dp, de = _getMemo(parser, rule, start); dp != 0 {
+		return dp, de
+	}
+	lastFail := parser.lastFail
+	var dpos, derr int
+	switch rule {
+	case _Document:
+		dpos, derr = _DocumentAccepts(parser, start)
+	case _Value:
+		dpos, derr = _ValueAccepts(parser, start)
+	case _Object:
+		dpos, derr = _ObjectAccepts(parser, start)
+	case _Member:
+		dpos, derr = _MemberAccepts(parser, start)
+	case _Array:
+		dpos, derr = _ArrayAccepts(parser, start)
+	case _String:
+		dpos, derr = _StringAccepts(parser, start)
+	case _Hex:
+		dpos, derr = _HexAccepts(parser, start)
+	case _Number:
+		dpos, derr = _NumberAccepts(parser, start)
+	case _Int:
+		dpos, derr = _IntAccepts(parser, start)
+	case _Frac:
+		dpos, derr = _FracAccepts(parser, start)
+	case _Exp:
+		dpos, derr = _ExpAccepts(parser, start)
+	case _Literal:
+		dpos, derr = _LiteralAccepts(parser, start)
+	case __:
+		dpos, derr = __Accepts(parser, start)
+	case _Delimited___7b__Member___7d:
+		dpos, derr = _Delimited___7b__Member___7dAccepts(parser, start)
+	case _Delimited___5b__Value___5d:
+		dpos, derr = _Delimited___5b__Value___5dAccepts(parser, start)
+	}
+	parser.lastFail = lastFail
+	if dpos < 0 {
+		return -1, int32(derr + 1)
+	}
+	return int32(dpos + 1), int32(derr + 1)
+}
+
+// _memoized returns whether the rule is memoized at the start position,
+// which it is not if its result depends on a maximum depth.
+// The results of the other passes are only reused if it is.
+func _memoized(parser *_Parser, rule, start int) bool {
+	dp, _ := _getMemo(parser, rule, start)
+	return dp != 0
+}
+
+func _memo(parser *_Parser, rule, start int) (int, int, bool) {
+	dp, de := _getMemo(parser, rule, start)
+	if dp == 0 {
+		return 0, 0, false
+	}
+	if dp > 0 {
+		dp--
+	}
+	return int(dp), int(de - 1), true
+}
+
+// _failMemo returns the memoized result of the Fail pass
+// for the rule at the start position,
+// or start and nil if the rule's Fail node must be built.
+// If the Fail pass is bounded and its budget is spent,
+// the node is not built, but replaced by a leaf wanting want at errPos.
+func _failMemo(parser *_Parser, rule, start, errPos int, want string) (int, *peg.Fail) {
+	if start > parser.lastFail {
+		return -1, &peg.Fail{}
+	}
+	dp, de := _ensureMemo(parser, rule, start)
+	if start+int(de-1) < errPos {
+		if dp > 0 {
+			return start + int(dp-1), &peg.Fail{}
+		}
+		return -1, &peg.Fail{}
+	}
+	f := parser.fail[_key{start: start, rule: rule}]
+	if dp < 0 && f != nil {
+		return -1, f
+	}
+	if dp > 0 && f != nil {
+		return start + int(dp-1), f
+	}
+	if parser.boundFails {
+		if parser.failBudget == 0 {
+			f := &peg.Fail{Pos: errPos, Want: want, Code: peg.NamedRule}
+			if dp > 0 {
+				return start + int(dp-1), f
+			}
+			return -1, f
+		}
+		parser.failBudget--
+	}
+	return start, nil
+}
+
+func _accept(parser *_Parser, f func(*_Parser, int) (int, int), pos, perr *int) bool {
+	dp, de := f(parser, *pos)
+	*perr = _max(*perr, *pos+de)
+	if dp < 0 {
+		return false
+	}
+	*pos += dp
+	return true
+}
+
+func _node(parser *_Parser, f func(*_Parser, int) (int, *peg.Node), node *peg.Node, pos *int) bool {
+	p, kid := f(parser, *pos)
+	if kid == nil {
+		return false
+	}
+	node.Kids = append(node.Kids, kid)
+	*pos = p
+	return true
+}
+
+func _fail(parser *_Parser, f func(*_Parser, int, int) (int, *peg.Fail), errPos int, node *peg.Fail, pos *int) bool {
+	p, kid := f(parser, *pos, errPos)
+	if kid.Want != "" || len(kid.Kids) > 0 {
+		node.Kids = append(node.Kids, kid)
+	}
+	if p < 0 {
+		return false
+	}
+	*pos = p
+	return true
+}
+
+func _next(parser *_Parser, pos int) (rune, int) {
+	r, w := peg.DecodeRuneInString(parser.text[pos:])
+	return r, w
+}
+
+func _sub(parser *_Parser, start, end int, kids []*peg.Node) *peg.Node {
+	node := &peg.Node{
+		Text: parser.text[start:end],
+		Kids: make([]*peg.Node, len(kids)),
+	}
+	copy(node.Kids, kids)
+	return node
+}
+
+func _leaf(parser *_Parser, start, end int) *peg.Node {
+	return &peg.Node{Text: parser.text[start:end]}
+}
+
+// A no-op function to mark a variable as used.
+func use(interface{}) {}
+
+func _DocumentAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
+	if dp, de, ok := _memo(parser, _Document, start); ok {
+		return dp, de
+	}
+	depthFails := parser.depthFails
+	if parser.depth >= 500 {
+		// Whether the depth is exceeded depends on the invocation,
+		// not just the position, so neither the failure
+		// nor the results depending on it are memoized.
+		parser.depthFails++
+		parser.lastFail = start
+		return -1, 0
+	}
+	parser.depth++
+	pos, perr := start, -1
+	// action
+	// _ v:Value _ !. !> "end of line"
+	// _
+	if !_accept(parser, __Accepts, &pos, &perr) {
+		goto fail
+	}
+	// v:Value
+	{
+		// Value
+		if !_accept(parser, _ValueAccepts, &pos, &perr) {
+			goto fail
+		}
+	}
+	// _
+	if !_accept(parser, __Accepts, &pos, &perr) {
+		goto fail
+	}
+	// !. !> "end of line"
+	{
+		pos2, perr3 := pos, perr
+		// !.
+		{
+			pos8 := pos
+			perr10 := perr
+			// .
+			if r, w := _next(parser, pos); w == 0 || r == '\uFFFD' {
+				perr = _max(perr, pos)
+				goto ok7
+			} else {
+				pos += w
+			}
+			pos = pos8
+			perr = _max(perr10, pos)
+			goto fail5
+		ok7:
+			pos = pos8
+			perr = perr10
+		}
+		perr = _max(perr3, pos2)
+		goto ok6
+	fail5:
+		perr = _max(perr3, pos2)
+		goto fail
+	ok6:
+	}
+	parser.depth--
+	return _memoize(parser, _Document, start, pos, perr, depthFails)
+fail:
+	parser.depth--
+	return _memoize(parser, _Document, start, -1, perr, depthFails)
+}
+
+func _DocumentNode(parser *_Parser, start int) (int, *peg.Node) {
+	dp, _ := _ensureMemo(parser, _Document, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	key := _key{start: start, rule: _Document}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
+	node := parser.node[key]
+	if node != nil {
+		return start + int(dp-1), node
+	}
+	pos := start
+	node = &peg.Node{Name: "Document"}
+	parser.depth++
+	// action
+	// _ v:Value _ !. !> "end of line"
+	// _
+	if !_node(parser, __Node, node, &pos) {
+		goto fail
+	}
+	// v:Value
+	{
+		// Value
+		if !_node(parser, _ValueNode, node, &pos) {
+			goto fail
+		}
+	}
+	// _
+	if !_node(parser, __Node, node, &pos) {
+		goto fail
+	}
+	// !. !> "end of line"
+	// !.
+	{
+		pos3 := pos
+		nkids4 := len(node.Kids)
+		// .
+		if r, w := _next(parser, pos); w == 0 || r == '\uFFFD' {
+			goto ok2
+		} else {
+			node.Kids = append(node.Kids, _leaf(parser, pos, pos+w))
+			pos += w
+		}
+		pos = pos3
+		node.Kids = node.Kids[:nkids4]
+		goto fail
+	ok2:
+		pos = pos3
+		node.Kids = node.Kids[:nkids4]
+	}
+	parser.depth--
+	node.Text = parser.text[start:pos]
+	if _memoized(parser, _Document, start) {
+		parser.node[key] = node
+	}
+	return pos, node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _DocumentFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
+	pos, failure := _failMemo(parser, _Document, start, errPos, "Document")
+	if failure != nil {
+		return pos, failure
+	}
+	failure = &peg.Fail{
+		Name: "Document",
+		Pos:  int(start),
+	}
+	key := _key{start: start, rule: _Document}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
+	if parser.depth >= 500 {
+		failure.Want = peg.MaxDepthExceeded
+		failure.Code = peg.DepthExceeded
+		return -1, failure
+	}
+	parser.depth++
+	// action
+	// _ v:Value _ !. !> "end of line"
+	// _
+	if !_fail(parser, __Fail, errPos, failure, &pos) {
+		goto fail
+	}
+	// v:Value
+	{
+		// Value
+		if !_fail(parser, _ValueFail, errPos, failure, &pos) {
+			goto fail
+		}
+	}
+	// _
+	if !_fail(parser, __Fail, errPos, failure, &pos) {
+		goto fail
+	}
+	// !. !> "end of line"
+	{
+		pos2 := pos
+		nkids4 := len(failure.Kids)
+		// !.
+		{
+			pos8 := pos
+			nkids9 := len(failure.Kids)
+			// .
+			if r, w := _next(parser, pos); w == 0 || r == '\uFFFD' {
+				if pos >= errPos {
+					failure.Kids = append(failure.Kids, &peg.Fail{
+						Pos:  int(pos),
+						Want: ".",
+						Code: peg.ExpectedAny,
+					})
+				}
+				goto ok7
+			} else {
+				pos += w
+			}
+			pos = pos8
+			failure.Kids = failure.Kids[:nkids9]
+			if pos >= errPos {
+				failure.Kids = append(failure.Kids, &peg.Fail{
+					Pos:  int(pos),
+					Want: "!.",
+					Code: peg.PredicateFailed,
+				})
+			}
+			goto fail5
+		ok7:
+			pos = pos8
+			failure.Kids = failure.Kids[:nkids9]
+		}
+		failure.Kids = failure.Kids[:nkids4]
+		goto ok6
+	fail5:
+		failure.Kids = failure.Kids[:nkids4]
+		if pos2 >= errPos {
+			failure.Kids = append(failure.Kids, &peg.Fail{
+				Pos:  int(pos2),
+				Want: "end of line",
+				Code: peg.NamedRule,
+			})
+		}
+		goto fail
+	ok6:
+	}
+	parser.depth--
+	if _memoized(parser, _Document, start) {
+		parser.fail[key] = failure
+	}
+	return pos, failure
+fail:
+	parser.depth--
+	if _memoized(parser, _Document, start) {
+		parser.fail[key] = failure
+	}
+	return -1, failure
+}
+
+func _DocumentAction(parser *_Parser, start int) (int, *Value) {
+	var label0 Value
+	dp, _ := _ensureMemo(parser, _Document, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	if n, ok := parser.actDocument[start]; ok {
+		return start + int(dp-1), &n
+	}
+	var node Value
+	pos := start
+	parser.depth++
+	// action
+	{
+		start0 := pos
+		// _ v:Value _ !. !> "end of line"
+		// _
+		if p, n := __Action(parser, pos); n == nil {
+			goto fail
+		} else {
+			pos = p
+		}
+		// v:Value
+		{
+			// Value
+			if p, n := _ValueAction(parser, pos); n == nil {
+				goto fail
+			} else {
+				label0 = *n
+				pos = p
+			}
+		}
+		// _
+		if p, n := __Action(parser, pos); n == nil {
+			goto fail
+		} else {
+			pos = p
+		}
+		// !. !> "end of line"
+		// !.
+		{
+			pos4 := pos
+			// .
+			if r, w := _next(parser, pos); w == 0 || r == '\uFFFD' {
+				goto ok3
+			} else {
+				pos += w
+			}
+			pos = pos4
+			goto fail
+		ok3:
+			pos = pos4
+		}
+		node = func(
+			start, end int, v Value) Value {
+			return Value(v)
+		}(
+			start0, pos, label0)
+	}
+	parser.depth--
+	if _memoized(parser, _Document, start) {
+		if parser.actDocument == nil {
+			parser.actDocument = make(map[int]Value)
+		}
+		parser.actDocument[start] = node
+	}
+	return pos, &node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _ValueAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
+	if dp, de, ok := _memo(parser, _Value, start); ok {
+		return dp, de
+	}
+	depthFails := parser.depthFails
+	if parser.depth >= 500 {
+		// Whether the depth is exceeded depends on the invocation,
+		// not just the position, so neither the failure
+		// nor the results depending on it are memoized.
+		parser.depthFails++
+		parser.lastFail = start
+		return -1, 0
+	}
+	parser.depth++
+	pos, perr := start, -1
+	// action
+	// &[{["\-0-9tfn] !> "a value" v:(Object/Array/String/Number/Literal)
+	// &[{["\-0-9tfn] !> "a value"
+	{
+		pos1, perr2 := pos, perr
+		// &[{["\-0-9tfn]
+		{
+			pos7 := pos
+			perr9 := perr
+			// [{["\-0-9tfn]
+			if r, w := _next(parser, pos); r < 0x80 && (uint64(0x3ff200400000000)>>uint(r)|uint64(0x810404008000000)>>uint(r-64))&1 == 0 ||
+				r >= 0x80 {
+				perr = _max(perr, pos)
+				goto fail10
+			} else {
+				pos += w
+			}
+			goto ok6
+		fail10:
+			pos = pos7
+			perr = _max(perr9, pos)
+			goto fail4
+		ok6:
+			pos = pos7
+			perr = perr9
+		}
+		perr = _max(perr2, pos1)
+		goto ok5
+	fail4:
+		perr = _max(perr2, pos1)
+		goto fail
+	ok5:
+	}
+	// v:(Object/Array/String/Number/Literal)
+	{
+		// (Object/Array/String/Number/Literal)
+		// Object/Array/String/Number/Literal
+		{
+			pos15 := pos
+			// Object
+			if !_accept(parser, _ObjectAccepts, &pos, &perr) {
+				goto fail16
+			}
+			goto ok12
+		fail16:
+			pos = pos15
+			// Array
+			if !_accept(parser, _ArrayAccepts, &pos, &perr) {
+				goto fail17
+			}
+			goto ok12
+		fail17:
+			pos = pos15
+			// String
+			if !_accept(parser, _StringAccepts, &pos, &perr) {
+				goto fail18
+			}
+			goto ok12
+		fail18:
+			pos = pos15
+			// Number
+			if !_accept(parser, _NumberAccepts, &pos, &perr) {
+				goto fail19
+			}
+			goto ok12
+		fail19:
+			pos = pos15
+			// Literal
+			if !_accept(parser, _LiteralAccepts, &pos, &perr) {
+				goto fail20
+			}
+			goto ok12
+		fail20:
+			pos = pos15
+			goto fail
+		ok12:
+		}
+	}
+	parser.depth--
+	return _memoize(parser, _Value, start, pos, perr, depthFails)
+fail:
+	parser.depth--
+	return _memoize(parser, _Value, start, -1, perr, depthFails)
+}
+
+func _ValueNode(parser *_Parser, start int) (int, *peg.Node) {
+	dp, _ := _ensureMemo(parser, _Value, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	key := _key{start: start, rule: _Value}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
+	node := parser.node[key]
+	if node != nil {
+		return start + int(dp-1), node
+	}
+	pos := start
+	node = &peg.Node{Name: "Value"}
+	parser.depth++
+	// action
+	// &[{["\-0-9tfn] !> "a value" v:(Object/Array/String/Number/Literal)
+	// &[{["\-0-9tfn] !> "a value"
+	// &[{["\-0-9tfn]
+	{
+		pos2 := pos
+		nkids3 := len(node.Kids)
+		// [{["\-0-9tfn]
+		if r, w := _next(parser, pos); r < 0x80 && (uint64(0x3ff200400000000)>>uint(r)|uint64(0x810404008000000)>>uint(r-64))&1 == 0 ||
+			r >= 0x80 {
+			goto fail5
+		} else {
+			node.Kids = append(node.Kids, _leaf(parser, pos, pos+w))
+			pos += w
+		}
+		goto ok1
+	fail5:
+		pos = pos2
+		goto fail
+	ok1:
+		pos = pos2
+		node.Kids = node.Kids[:nkids3]
+	}
+	// v:(Object/Array/String/Number/Literal)
+	{
+		// (Object/Array/String/Number/Literal)
+		{
+			nkids7 := len(node.Kids)
+			pos08 := pos
+			// Object/Array/String/Number/Literal
+			{
+				pos12 := pos
+				nkids10 := len(node.Kids)
+				// Object
+				if !_node(parser, _ObjectNode, node, &pos) {
+					goto fail13
+				}
+				goto ok9
+			fail13:
+				node.Kids = node.Kids[:nkids10]
+				pos = pos12
+				// Array
+				if !_node(parser, _ArrayNode, node, &pos) {
+					goto fail14
+				}
+				goto ok9
+			fail14:
+				node.Kids = node.Kids[:nkids10]
+				pos = pos12
+				// String
+				if !_node(parser, _StringNode, node, &pos) {
+					goto fail15
+				}
+				goto ok9
+			fail15:
+				node.Kids = node.Kids[:nkids10]
+				pos = pos12
+				// Number
+				if !_node(parser, _NumberNode, node, &pos) {
+					goto fail16
+				}
+				goto ok9
+			fail16:
+				node.Kids = node.Kids[:nkids10]
+				pos = pos12
+				// Literal
+				if !_node(parser, _LiteralNode, node, &pos) {
+					goto fail17
+				}
+				goto ok9
+			fail17:
+				node.Kids = node.Kids[:nkids10]
+				pos = pos12
+				goto fail
+			ok9:
+			}
+			sub := _sub(parser, pos08, pos, node.Kids[nkids7:])
+			node.Kids = append(node.Kids[:nkids7], sub)
+		}
+	}
+	parser.depth--
+	node.Text = parser.text[start:pos]
+	if _memoized(parser, _Value, start) {
+		parser.node[key] = node
+	}
+	return pos, node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _ValueFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
+	pos, failure := _failMemo(parser, _Value, start, errPos, "Value")
+	if failure != nil {
+		return pos, failure
+	}
+	failure = &peg.Fail{
+		Name: "Value",
+		Pos:  int(start),
+	}
+	key := _key{start: start, rule: _Value}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
+	if parser.depth >= 500 {
+		failure.Want = peg.MaxDepthExceeded
+		failure.Code = peg.DepthExceeded
+		return -1, failure
+	}
+	parser.depth++
+	// action
+	// &[{["\-0-9tfn] !> "a value" v:(Object/Array/String/Number/Literal)
+	// &[{["\-0-9tfn] !> "a value"
+	{
+		pos1 := pos
+		nkids3 := len(failure.Kids)
+		// &[{["\-0-9tfn]
+		{
+			pos7 := pos
+			nkids8 := len(failure.Kids)
+			// [{["\-0-9tfn]
+			if r, w := _next(parser, pos); r < 0x80 && (uint64(0x3ff200400000000)>>uint(r)|uint64(0x810404008000000)>>uint(r-64))&1 == 0 ||
+				r >= 0x80 {
+				if pos >= errPos {
+					failure.Kids = append(failure.Kids, &peg.Fail{
+						Pos:  int(pos),
+						Want: "[{[\"\\-0-9tfn]",
+						Code: peg.ExpectedClass,
+					})
+				}
+				goto fail10
+			} else {
+				pos += w
+			}
+			goto ok6
+		fail10:
+			pos = pos7
+			failure.Kids = failure.Kids[:nkids8]
+			if pos >= errPos {
+				failure.Kids = append(failure.Kids, &peg.Fail{
+					Pos:  int(pos),
+					Want: "&[{[\"\\-0-9tfn]",
+					Code: peg.PredicateFailed,
+				})
+			}
+			goto fail4
+		ok6:
+			pos = pos7
+			failure.Kids = failure.Kids[:nkids8]
+		}
+		failure.Kids = failure.Kids[:nkids3]
+		goto ok5
+	fail4:
+		failure.Kids = failure.Kids[:nkids3]
+		if pos1 >= errPos {
+			failure.Kids = append(failure.Kids, &peg.Fail{
+				Pos:  int(pos1),
+				Want: "a value",
+				Code: peg.NamedRule,
+			})
+		}
+		goto fail
+	ok5:
+	}
+	// v:(Object/Array/String/Number/Literal)
+	{
+		// (Object/Array/String/Number/Literal)
+		// Object/Array/String/Number/Literal
+		{
+			pos15 := pos
+			// Object
+			if !_fail(parser, _ObjectFail, errPos, failure, &pos) {
+				goto fail16
+			}
+			goto ok12
+		fail16:
+			pos = pos15
+			// Array
+			if !_fail(parser, _ArrayFail, errPos, failure, &pos) {
+				goto fail17
+			}
+			goto ok12
+		fail17:
+			pos = pos15
+			// String
+			if !_fail(parser, _StringFail, errPos, failure, &pos) {
+				goto fail18
+			}
+			goto ok12
+		fail18:
+			pos = pos15
+			// Number
+			if !_fail(parser, _NumberFail, errPos, failure, &pos) {
+				goto fail19
+			}
+			goto ok12
+		fail19:
+			pos = pos15
+			// Literal
+			if !_fail(parser, _LiteralFail, errPos, failure, &pos) {
+				goto fail20
+			}
+			goto ok12
+		fail20:
+			pos = pos15
+			goto fail
+		ok12:
+		}
+	}
+	parser.depth--
+	if _memoized(parser, _Value, start) {
+		parser.fail[key] = failure
+	}
+	return pos, failure
+fail:
+	parser.depth--
+	if _memoized(parser, _Value, start) {
+		parser.fail[key] = failure
+	}
+	return -1, failure
+}
+
+func _ValueAction(parser *_Parser, start int) (int, *Value) {
+	var label0 Value
+	dp, _ := _ensureMemo(parser, _Value, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	if n, ok := parser.actValue[start]; ok {
+		return start + int(dp-1), &n
+	}
+	var node Value
+	pos := start
+	parser.depth++
+	// action
+	{
+		start0 := pos
+		// &[{["\-0-9tfn] !> "a value" v:(Object/Array/String/Number/Literal)
+		// &[{["\-0-9tfn] !> "a value"
+		// &[{["\-0-9tfn]
+		{
+			pos3 := pos
+			// [{["\-0-9tfn]
+			if r, w := _next(parser, pos); r < 0x80 && (uint64(0x3ff200400000000)>>uint(r)|uint64(0x810404008000000)>>uint(r-64))&1 == 0 ||
+				r >= 0x80 {
+				goto fail6
+			} else {
+				pos += w
+			}
+			goto ok2
+		fail6:
+			pos = pos3
+			goto fail
+		ok2:
+			pos = pos3
+		}
+		// v:(Object/Array/String/Number/Literal)
+		{
+			// (Object/Array/String/Number/Literal)
+			// Object/Array/String/Number/Literal
+			{
+				pos11 := pos
+				var node10 Value
+				// Object
+				if p, n := _ObjectAction(parser, pos); n == nil {
+					goto fail12
+				} else {
+					label0 = *n
+					pos = p
+				}
+				goto ok8
+			fail12:
+				label0 = node10
+				pos = pos11
+				// Array
+				if p, n := _ArrayAction(parser, pos); n == nil {
+					goto fail13
+				} else {
+					label0 = *n
+					pos = p
+				}
+				goto ok8
+			fail13:
+				label0 = node10
+				pos = pos11
+				// String
+				if p, n := _StringAction(parser, pos); n == nil {
+					goto fail14
+				} else {
+					label0 = *n
+					pos = p
+				}
+				goto ok8
+			fail14:
+				label0 = node10
+				pos = pos11
+				// Number
+				if p, n := _NumberAction(parser, pos); n == nil {
+					goto fail15
+				} else {
+					label0 = *n
+					pos = p
+				}
+				goto ok8
+			fail15:
+				label0 = node10
+				pos = pos11
+				// Literal
+				if p, n := _LiteralAction(parser, pos); n == nil {
+					goto fail16
+				} else {
+					label0 = *n
+					pos = p
+				}
+				goto ok8
+			fail16:
+				label0 = node10
+				pos = pos11
+				goto fail
+			ok8:
+			}
+		}
+		node = func(
+			start, end int, v Value) Value {
+			return Value(v)
+		}(
+			start0, pos, label0)
+	}
+	parser.depth--
+	if _memoized(parser, _Value, start) {
+		if parser.actValue == nil {
+			parser.actValue = make(map[int]Value)
+		}
+		parser.actValue[start] = node
+	}
+	return pos, &node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _ObjectAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
+	if dp, de, ok := _memo(parser, _Object, start); ok {
+		return dp, de
+	}
+	depthFails := parser.depthFails
+	if parser.depth >= 500 {
+		// Whether the depth is exceeded depends on the invocation,
+		// not just the position, so neither the failure
+		// nor the results depending on it are memoized.
+		parser.depthFails++
+		parser.lastFail = start
+		return -1, 0
+	}
+	parser.depth++
+	pos, perr := start, -1
+	// action
+	// ms:Delimited<"{", Member, "}">
+	{
+		// Delimited<"{", Member, "}">
+		if !_accept(parser, _Delimited___7b__Member___7dAccepts, &pos, &perr) {
+			goto fail
+		}
+	}
+	parser.depth--
+	return _memoize(parser, _Object, start, pos, perr, depthFails)
+fail:
+	parser.depth--
+	return _memoize(parser, _Object, start, -1, perr, depthFails)
+}
+
+func _ObjectNode(parser *_Parser, start int) (int, *peg.Node) {
+	dp, _ := _ensureMemo(parser, _Object, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	key := _key{start: start, rule: _Object}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
+	node := parser.node[key]
+	if node != nil {
+		return start + int(dp-1), node
+	}
+	pos := start
+	node = &peg.Node{Name: "Object"}
+	parser.depth++
+	// action
+	// ms:Delimited<"{", Member, "}">
+	{
+		// Delimited<"{", Member, "}">
+		if !_node(parser, _Delimited___7b__Member___7dNode, node, &pos) {
+			goto fail
+		}
+	}
+	parser.depth--
+	node.Text = parser.text[start:pos]
+	if _memoized(parser, _Object, start) {
+		parser.node[key] = node
+	}
+	return pos, node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _ObjectFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
+	pos, failure := _failMemo(parser, _Object, start, errPos, "Object")
+	if failure != nil {
+		return pos, failure
+	}
+	failure = &peg.Fail{
+		Name: "Object",
+		Pos:  int(start),
+	}
+	key := _key{start: start, rule: _Object}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
+	if parser.depth >= 500 {
+		failure.Want = peg.MaxDepthExceeded
+		failure.Code = peg.DepthExceeded
+		return -1, failure
+	}
+	parser.depth++
+	// action
+	// ms:Delimited<"{", Member, "}">
+	{
+		// Delimited<"{", Member, "}">
+		if !_fail(parser, _Delimited___7b__Member___7dFail, errPos, failure, &pos) {
+			goto fail
+		}
+	}
+	parser.depth--
+	if _memoized(parser, _Object, start) {
+		parser.fail[key] = failure
+	}
+	return pos, failure
+fail:
+	parser.depth--
+	if _memoized(parser, _Object, start) {
+		parser.fail[key] = failure
+	}
+	return -1, failure
+}
+
+func _ObjectAction(parser *_Parser, start int) (int, *Value) {
+	var label0 []Value
+	dp, _ := _ensureMemo(parser, _Object, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	if n, ok := parser.actObject[start]; ok {
+		return start + int(dp-1), &n
+	}
+	var node Value
+	pos := start
+	parser.depth++
+	// action
+	{
+		start0 := pos
+		// ms:Delimited<"{", Member, "}">
+		{
+			// Delimited<"{", Member, "}">
+			if p, n := _Delimited___7b__Member___7dAction(parser, pos); n == nil {
+				goto fail
+			} else {
+				label0 = *n
+				pos = p
+			}
+		}
+		node = func(
+			start, end int, ms []Value) Value {
+			return Value{Kind: Object, Elems: ms}
+		}(
+			start0, pos, label0)
+	}
+	parser.depth--
+	if _memoized(parser, _Object, start) {
+		if parser.actObject == nil {
+			parser.actObject = make(map[int]Value)
+		}
+		parser.actObject[start] = node
+	}
+	return pos, &node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _MemberAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
+	if dp, de, ok := _memo(parser, _Member, start); ok {
+		return dp, de
+	}
+	depthFails := parser.depthFails
+	if parser.depth >= 500 {
+		// Whether the depth is exceeded depends on the invocation,
+		// not just the position, so neither the failure
+		// nor the results depending on it are memoized.
+		parser.depthFails++
+		parser.lastFail = start
+		return -1, 0
+	}
+	parser.depth++
+	pos, perr := start, -1
+	// action
+	// k:String _ ":" _ v:Value
+	// k:String
+	{
+		// String
+		if !_accept(parser, _StringAccepts, &pos, &perr) {
+			goto fail
+		}
+	}
+	// _
+	if !_accept(parser, __Accepts, &pos, &perr) {
+		goto fail
+	}
+	// ":"
+	if pos >= len(parser.text) || parser.text[pos] != ":"[0] {
+		perr = _max(perr, pos)
+		goto fail
+	}
+	pos++
+	// _
+	if !_accept(parser, __Accepts, &pos, &perr) {
+		goto fail
+	}
+	// v:Value
+	{
+		// Value
+		if !_accept(parser, _ValueAccepts, &pos, &perr) {
+			goto fail
+		}
+	}
+	parser.depth--
+	return _memoize(parser, _Member, start, pos, perr, depthFails)
+fail:
+	parser.depth--
+	return _memoize(parser, _Member, start, -1, perr, depthFails)
+}
+
+func _MemberNode(parser *_Parser, start int) (int, *peg.Node) {
+	dp, _ := _ensureMemo(parser, _Member, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	key := _key{start: start, rule: _Member}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
+	node := parser.node[key]
+	if node != nil {
+		return start + int(dp-1), node
+	}
+	pos := start
+	node = &peg.Node{Name: "Member"}
+	parser.depth++
+	// action
+	// k:String _ ":" _ v:Value
+	// k:String
+	{
+		// String
+		if !_node(parser, _StringNode, node, &pos) {
+			goto fail
+		}
+	}
+	// _
+	if !_node(parser, __Node, node, &pos) {
+		goto fail
+	}
+	// ":"
+	if pos >= len(parser.text) || parser.text[pos] != ":"[0] {
+		goto fail
+	}
+	node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
+	pos++
+	// _
+	if !_node(parser, __Node, node, &pos) {
+		goto fail
+	}
+	// v:Value
+	{
+		// Value
+		if !_node(parser, _ValueNode, node, &pos) {
+			goto fail
+		}
+	}
+	parser.depth--
+	node.Text = parser.text[start:pos]
+	if _memoized(parser, _Member, start) {
+		parser.node[key] = node
+	}
+	return pos, node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _MemberFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
+	pos, failure := _failMemo(parser, _Member, start, errPos, "Member")
+	if failure != nil {
+		return pos, failure
+	}
+	failure = &peg.Fail{
+		Name: "Member",
+		Pos:  int(start),
+	}
+	key := _key{start: start, rule: _Member}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
+	if parser.depth >= 500 {
+		failure.Want = peg.MaxDepthExceeded
+		failure.Code = peg.DepthExceeded
+		return -1, failure
+	}
+	parser.depth++
+	// action
+	// k:String _ ":" _ v:Value
+	// k:String
+	{
+		// String
+		if !_fail(parser, _StringFail, errPos, failure, &pos) {
+			goto fail
+		}
+	}
+	// _
+	if !_fail(parser, __Fail, errPos, failure, &pos) {
+		goto fail
+	}
+	// ":"
+	if pos >= len(parser.text) || parser.text[pos] != ":"[0] {
+		if pos >= errPos {
+			failure.Kids = append(failure.Kids, &peg.Fail{
+				Pos:  int(pos),
+				Want: "\":\"",
+				Code: peg.ExpectedLiteral,
+			})
+		}
+		goto fail
+	}
+	pos++
+	// _
+	if !_fail(parser, __Fail, errPos, failure, &pos) {
+		goto fail
+	}
+	// v:Value
+	{
+		// Value
+		if !_fail(parser, _ValueFail, errPos, failure, &pos) {
+			goto fail
+		}
+	}
+	parser.depth--
+	if _memoized(parser, _Member, start) {
+		parser.fail[key] = failure
+	}
+	return pos, failure
+fail:
+	parser.depth--
+	if _memoized(parser, _Member, start) {
+		parser.fail[key] = failure
+	}
+	return -1, failure
+}
+
+func _MemberAction(parser *_Parser, start int) (int, *Value) {
+	var label0 Value
+	var label1 Value
+	dp, _ := _ensureMemo(parser, _Member, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	if n, ok := parser.actMember[start]; ok {
+		return start + int(dp-1), &n
+	}
+	var node Value
+	pos := start
+	parser.depth++
+	// action
+	{
+		start0 := pos
+		// k:String _ ":" _ v:Value
+		// k:String
+		{
+			// String
+			if p, n := _StringAction(parser, pos); n == nil {
+				goto fail
+			} else {
+				label0 = *n
+				pos = p
+			}
+		}
+		// _
+		if p, n := __Action(parser, pos); n == nil {
+			goto fail
+		} else {
+			pos = p
+		}
+		// ":"
+		if pos >= len(parser.text) || parser.text[pos] != ":"[0] {
+			goto fail
+		}
+		pos++
+		// _
+		if p, n := __Action(parser, pos); n == nil {
+			goto fail
+		} else {
+			pos = p
+		}
+		// v:Value
+		{
+			// Value
+			if p, n := _ValueAction(parser, pos); n == nil {
+				goto fail
+			} else {
+				label1 = *n
+				pos = p
+			}
+		}
+		node = func(
+			start, end int, k Value, v Value) Value {
+			v.Key = k.Text
+			return Value(v)
+		}(
+			start0, pos, label0, label1)
+	}
+	parser.depth--
+	if _memoized(parser, _Member, start) {
+		if parser.actMember == nil {
+			parser.actMember = make(map[int]Value)
+		}
+		parser.actMember[start] = node
+	}
+	return pos, &node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _ArrayAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
+	if dp, de, ok := _memo(parser, _Array, start); ok {
+		return dp, de
+	}
+	depthFails := parser.depthFails
+	if parser.depth >= 500 {
+		// Whether the depth is exceeded depends on the invocation,
+		// not just the position, so neither the failure
+		// nor the results depending on it are memoized.
+		parser.depthFails++
+		parser.lastFail = start
+		return -1, 0
+	}
+	parser.depth++
+	pos, perr := start, -1
+	// action
+	// es:Delimited<"[", Value, "]">
+	{
+		// Delimited<"[", Value, "]">
+		if !_accept(parser, _Delimited___5b__Value___5dAccepts, &pos, &perr) {
+			goto fail
+		}
+	}
+	parser.depth--
+	return _memoize(parser, _Array, start, pos, perr, depthFails)
+fail:
+	parser.depth--
+	return _memoize(parser, _Array, start, -1, perr, depthFails)
+}
+
+func _ArrayNode(parser *_Parser, start int) (int, *peg.Node) {
+	dp, _ := _ensureMemo(parser, _Array, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	key := _key{start: start, rule: _Array}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
+	node := parser.node[key]
+	if node != nil {
+		return start + int(dp-1), node
+	}
+	pos := start
+	node = &peg.Node{Name: "Array"}
+	parser.depth++
+	// action
+	// es:Delimited<"[", Value, "]">
+	{
+		// Delimited<"[", Value, "]">
+		if !_node(parser, _Delimited___5b__Value___5dNode, node, &pos) {
+			goto fail
+		}
+	}
+	parser.depth--
+	node.Text = parser.text[start:pos]
+	if _memoized(parser, _Array, start) {
+		parser.node[key] = node
+	}
+	return pos, node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _ArrayFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
+	pos, failure := _failMemo(parser, _Array, start, errPos, "Array")
+	if failure != nil {
+		return pos, failure
+	}
+	failure = &peg.Fail{
+		Name: "Array",
+		Pos:  int(start),
+	}
+	key := _key{start: start, rule: _Array}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
+	if parser.depth >= 500 {
+		failure.Want = peg.MaxDepthExceeded
+		failure.Code = peg.DepthExceeded
+		return -1, failure
+	}
+	parser.depth++
+	// action
+	// es:Delimited<"[", Value, "]">
+	{
+		// Delimited<"[", Value, "]">
+		if !_fail(parser, _Delimited___5b__Value___5dFail, errPos, failure, &pos) {
+			goto fail
+		}
+	}
+	parser.depth--
+	if _memoized(parser, _Array, start) {
+		parser.fail[key] = failure
+	}
+	return pos, failure
+fail:
+	parser.depth--
+	if _memoized(parser, _Array, start) {
+		parser.fail[key] = failure
+	}
+	return -1, failure
+}
+
+func _ArrayAction(parser *_Parser, start int) (int, *Value) {
+	var label0 []Value
+	dp, _ := _ensureMemo(parser, _Array, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	if n, ok := parser.actArray[start]; ok {
+		return start + int(dp-1), &n
+	}
+	var node Value
+	pos := start
+	parser.depth++
+	// action
+	{
+		start0 := pos
+		// es:Delimited<"[", Value, "]">
+		{
+			// Delimited<"[", Value, "]">
+			if p, n := _Delimited___5b__Value___5dAction(parser, pos); n == nil {
+				goto fail
+			} else {
+				label0 = *n
+				pos = p
+			}
+		}
+		node = func(
+			start, end int, es []Value) Value {
+			return Value{Kind: Array, Elems: es}
+		}(
+			start0, pos, label0)
+	}
+	parser.depth--
+	if _memoized(parser, _Array, start) {
+		if parser.actArray == nil {
+			parser.actArray = make(map[int]Value)
+		}
+		parser.actArray[start] = node
+	}
+	return pos, &node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _StringAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
+	if dp, de, ok := _memo(parser, _String, start); ok {
+		return dp, de
+	}
+	depthFails := parser.depthFails
+	if parser.depth >= 500 {
+		// Whether the depth is exceeded depends on the invocation,
+		// not just the position, so neither the failure
+		// nor the results depending on it are memoized.
+		parser.depthFails++
+		parser.lastFail = start
+		return -1, 0
+	}
+	parser.depth++
+	pos, perr := start, -1
+	// action
+	// "\"" s:$([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")* "\""
+	// "\""
+	if pos >= len(parser.text) || parser.text[pos] != "\""[0] {
+		perr = _max(perr, pos)
+		goto fail
+	}
+	pos++
+	// s:$([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")*
+	{
+		// $([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")*
+		// ([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")*
+		for {
+			pos3 := pos
+			// ([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")
+			// [^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits"
+			{
+				pos9 := pos
+				// [^"\\\x00-\x1f]
+				if r, w := _next(parser, pos); r < 0x80 && (uint64(0xfffffffb00000000)>>uint(r)|uint64(0xffffffffefffffff)>>uint(r-64))&1 == 0 ||
+					r >= 0x80 && (w == 0 || r == '\uFFFD') {
+					perr = _max(perr, pos)
+					goto fail10
+				} else {
+					pos += w
+				}
+				goto ok6
+			fail10:
+				pos = pos9
+				// "\\" ["\\/bfnrt]
+				// "\\"
+				if pos >= len(parser.text) || parser.text[pos] != "\\"[0] {
+					perr = _max(perr, pos)
+					goto fail11
+				}
+				pos++
+				// ["\\/bfnrt]
+				if r, w := _next(parser, pos); r < 0x80 && (uint64(0x800400000000)>>uint(r)|uint64(0x14404410000000)>>uint(r-64))&1 == 0 ||
+					r >= 0x80 {
+					perr = _max(perr, pos)
+					goto fail11
+				} else {
+					pos += w
+				}
+				goto ok6
+			fail11:
+				pos = pos9
+				// "\\u" (Hex Hex Hex Hex) !> "4 hex digits"
+				// "\\u"
+				if len(parser.text)-pos < 2 || parser.text[pos:pos+2] != "\\u" {
+					perr = _max(perr, pos)
+					goto fail13
+				}
+				pos += 2
+				// (Hex Hex Hex Hex) !> "4 hex digits"
+				{
+					pos15, perr16 := pos, perr
+					// (Hex Hex Hex Hex)
+					// Hex Hex Hex Hex
+					// Hex
+					if !_accept(parser, _HexAccepts, &pos, &perr) {
+						goto fail18
+					}
+					// Hex
+					if !_accept(parser, _HexAccepts, &pos, &perr) {
+						goto fail18
+					}
+					// Hex
+					if !_accept(parser, _HexAccepts, &pos, &perr) {
+						goto fail18
+					}
+					// Hex
+					if !_accept(parser, _HexAccepts, &pos, &perr) {
+						goto fail18
+					}
+					perr = _max(perr16, pos15)
+					goto ok19
+				fail18:
+					perr = _max(perr16, pos15)
+					goto fail13
+				ok19:
+				}
+				goto ok6
+			fail13:
+				pos = pos9
+				goto fail5
+			ok6:
+			}
+			continue
+		fail5:
+			pos = pos3
+			break
+		}
+	}
+	// "\""
+	if pos >= len(parser.text) || parser.text[pos] != "\""[0] {
+		perr = _max(perr, pos)
+		goto fail
+	}
+	pos++
+	parser.depth--
+	return _memoize(parser, _String, start, pos, perr, depthFails)
+fail:
+	parser.depth--
+	return _memoize(parser, _String, start, -1, perr, depthFails)
+}
+
+func _StringNode(parser *_Parser, start int) (int, *peg.Node) {
+	dp, _ := _ensureMemo(parser, _String, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	key := _key{start: start, rule: _String}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
+	node := parser.node[key]
+	if node != nil {
+		return start + int(dp-1), node
+	}
+	pos := start
+	node = &peg.Node{Name: "String"}
+	parser.depth++
+	// action
+	// "\"" s:$([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")* "\""
+	// "\""
+	if pos >= len(parser.text) || parser.text[pos] != "\""[0] {
+		goto fail
+	}
+	node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
+	pos++
+	// s:$([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")*
+	{
+		// $([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")*
+		// ([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")*
+		for {
+			nkids2 := len(node.Kids)
+			pos3 := pos
+			// ([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")
+			{
+				nkids6 := len(node.Kids)
+				pos07 := pos
+				// [^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits"
+				{
+					pos11 := pos
+					nkids9 := len(node.Kids)
+					// [^"\\\x00-\x1f]
+					if r, w := _next(parser, pos); r < 0x80 && (uint64(0xfffffffb00000000)>>uint(r)|uint64(0xffffffffefffffff)>>uint(r-64))&1 == 0 ||
+						r >= 0x80 && (w == 0 || r == '\uFFFD') {
+						goto fail12
+					} else {
+						node.Kids = append(node.Kids, _leaf(parser, pos, pos+w))
+						pos += w
+					}
+					goto ok8
+				fail12:
+					node.Kids = node.Kids[:nkids9]
+					pos = pos11
+					// "\\" ["\\/bfnrt]
+					// "\\"
+					if pos >= len(parser.text) || parser.text[pos] != "\\"[0] {
+						goto fail13
+					}
+					node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
+					pos++
+					// ["\\/bfnrt]
+					if r, w := _next(parser, pos); r < 0x80 && (uint64(0x800400000000)>>uint(r)|uint64(0x14404410000000)>>uint(r-64))&1 == 0 ||
+						r >= 0x80 {
+						goto fail13
+					} else {
+						node.Kids = append(node.Kids, _leaf(parser, pos, pos+w))
+						pos += w
+					}
+					goto ok8
+				fail13:
+					node.Kids = node.Kids[:nkids9]
+					pos = pos11
+					// "\\u" (Hex Hex Hex Hex) !> "4 hex digits"
+					// "\\u"
+					if len(parser.text)-pos < 2 || parser.text[pos:pos+2] != "\\u" {
+						goto fail15
+					}
+					node.Kids = append(node.Kids, _leaf(parser, pos, pos+2))
+					pos += 2
+					// (Hex Hex Hex Hex) !> "4 hex digits"
+					// (Hex Hex Hex Hex)
+					{
+						nkids17 := len(node.Kids)
+						pos018 := pos
+						// Hex Hex Hex Hex
+						// Hex
+						if !_node(parser, _HexNode, node, &pos) {
+							goto fail15
+						}
+						// Hex
+						if !_node(parser, _HexNode, node, &pos) {
+							goto fail15
+						}
+						// Hex
+						if !_node(parser, _HexNode, node, &pos) {
+							goto fail15
+						}
+						// Hex
+						if !_node(parser, _HexNode, node, &pos) {
+							goto fail15
+						}
+						sub := _sub(parser, pos018, pos, node.Kids[nkids17:])
+						node.Kids = append(node.Kids[:nkids17], sub)
+					}
+					goto ok8
+				fail15:
+					node.Kids = node.Kids[:nkids9]
+					pos = pos11
+					goto fail5
+				ok8:
+				}
+				sub := _sub(parser, pos07, pos, node.Kids[nkids6:])
+				node.Kids = append(node.Kids[:nkids6], sub)
+			}
+			continue
+		fail5:
+			node.Kids = node.Kids[:nkids2]
+			pos = pos3
+			break
+		}
+	}
+	// "\""
+	if pos >= len(parser.text) || parser.text[pos] != "\""[0] {
+		goto fail
+	}
+	node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
+	pos++
+	parser.depth--
+	node.Text = parser.text[start:pos]
+	if _memoized(parser, _String, start) {
+		parser.node[key] = node
+	}
+	return pos, node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _StringFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
+	pos, failure := _failMemo(parser, _String, start, errPos, "String")
+	if failure != nil {
+		return pos, failure
+	}
+	failure = &peg.Fail{
+		Name: "String",
+		Pos:  int(start),
+	}
+	key := _key{start: start, rule: _String}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
+	if parser.depth >= 500 {
+		failure.Want = peg.MaxDepthExceeded
+		failure.Code = peg.DepthExceeded
+		return -1, failure
+	}
+	parser.depth++
+	// action
+	// "\"" s:$([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")* "\""
+	// "\""
+	if pos >= len(parser.text) || parser.text[pos] != "\""[0] {
+		if pos >= errPos {
+			failure.Kids = append(failure.Kids, &peg.Fail{
+				Pos:  int(pos),
+				Want: "\"\\\"\"",
+				Code: peg.ExpectedLiteral,
+			})
+		}
+		goto fail
+	}
+	pos++
+	// s:$([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")*
+	{
+		// $([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")*
+		// ([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")*
+		for {
+			pos3 := pos
+			// ([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")
+			// [^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits"
+			{
+				pos9 := pos
+				// [^"\\\x00-\x1f]
+				if r, w := _next(parser, pos); r < 0x80 && (uint64(0xfffffffb00000000)>>uint(r)|uint64(0xffffffffefffffff)>>uint(r-64))&1 == 0 ||
+					r >= 0x80 && (w == 0 || r == '\uFFFD') {
+					if pos >= errPos {
+						failure.Kids = append(failure.Kids, &peg.Fail{
+							Pos:  int(pos),
+							Want: "[^\"\\\\\\x00-\\x1f]",
+							Code: peg.ExpectedClass,
+						})
+					}
+					goto fail10
+				} else {
+					pos += w
+				}
+				goto ok6
+			fail10:
+				pos = pos9
+				// "\\" ["\\/bfnrt]
+				// "\\"
+				if pos >= len(parser.text) || parser.text[pos] != "\\"[0] {
+					if pos >= errPos {
+						failure.Kids = append(failure.Kids, &peg.Fail{
+							Pos:  int(pos),
+							Want: "\"\\\\\"",
+							Code: peg.ExpectedLiteral,
+						})
+					}
+					goto fail11
+				}
+				pos++
+				// ["\\/bfnrt]
+				if r, w := _next(parser, pos); r < 0x80 && (uint64(0x800400000000)>>uint(r)|uint64(0x14404410000000)>>uint(r-64))&1 == 0 ||
+					r >= 0x80 {
+					if pos >= errPos {
+						failure.Kids = append(failure.Kids, &peg.Fail{
+							Pos:  int(pos),
+							Want: "[\"\\\\/bfnrt]",
+							Code: peg.ExpectedClass,
+						})
+					}
+					goto fail11
+				} else {
+					pos += w
+				}
+				goto ok6
+			fail11:
+				pos = pos9
+				// "\\u" (Hex Hex Hex Hex) !> "4 hex digits"
+				// "\\u"
+				if len(parser.text)-pos < 2 || parser.text[pos:pos+2] != "\\u" {
+					if pos >= errPos {
+						failure.Kids = append(failure.Kids, &peg.Fail{
+							Pos:  int(pos),
+							Want: "\"\\\\u\"",
+							Code: peg.ExpectedLiteral,
+						})
+					}
+					goto fail13
+				}
+				pos += 2
+				// (Hex Hex Hex Hex) !> "4 hex digits"
+				{
+					pos15 := pos
+					nkids17 := len(failure.Kids)
+					// (Hex Hex Hex Hex)
+					// Hex Hex Hex Hex
+					// Hex
+					if !_fail(parser, _HexFail, errPos, failure, &pos) {
+						goto fail18
+					}
+					// Hex
+					if !_fail(parser, _HexFail, errPos, failure, &pos) {
+						goto fail18
+					}
+					// Hex
+					if !_fail(parser, _HexFail, errPos, failure, &pos) {
+						goto fail18
+					}
+					// Hex
+					if !_fail(parser, _HexFail, errPos, failure, &pos) {
+						goto fail18
+					}
+					failure.Kids = failure.Kids[:nkids17]
+					goto ok19
+				fail18:
+					failure.Kids = failure.Kids[:nkids17]
+					if pos15 >= errPos {
+						failure.Kids = append(failure.Kids, &peg.Fail{
+							Pos:  int(pos15),
+							Want: "4 hex digits",
+							Code: peg.NamedRule,
+						})
+					}
+					goto fail13
+				ok19:
+				}
+				goto ok6
+			fail13:
+				pos = pos9
+				goto fail5
+			ok6:
+			}
+			continue
+		fail5:
+			pos = pos3
+			break
+		}
+	}
+	// "\""
+	if pos >= len(parser.text) || parser.text[pos] != "\""[0] {
+		if pos >= errPos {
+			failure.Kids = append(failure.Kids, &peg.Fail{
+				Pos:  int(pos),
+				Want: "\"\\\"\"",
+				Code: peg.ExpectedLiteral,
+			})
+		}
+		goto fail
+	}
+	pos++
+	parser.depth--
+	if _memoized(parser, _String, start) {
+		parser.fail[key] = failure
+	}
+	return pos, failure
+fail:
+	parser.depth--
+	if _memoized(parser, _String, start) {
+		parser.fail[key] = failure
+	}
+	return -1, failure
+}
+
+func _StringAction(parser *_Parser, start int) (int, *Value) {
+	var label0 string
+	dp, _ := _ensureMemo(parser, _String, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	if n, ok := parser.actString[start]; ok {
+		return start + int(dp-1), &n
+	}
+	var node Value
+	pos := start
+	parser.depth++
+	// action
+	{
+		start0 := pos
+		// "\"" s:$([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")* "\""
+		// "\""
+		if pos >= len(parser.text) || parser.text[pos] != "\""[0] {
+			goto fail
+		}
+		pos++
+		// s:$([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")*
+		{
+			// $([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")*
+			{
+				pos3 := pos
+				// ([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")*
+				for {
+					pos5 := pos
+					// ([^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits")
+					// [^"\\\x00-\x1f]/"\\" ["\\/bfnrt]/"\\u" (Hex Hex Hex Hex) !> "4 hex digits"
+					{
+						pos11 := pos
+						// [^"\\\x00-\x1f]
+						if r, w := _next(parser, pos); r < 0x80 && (uint64(0xfffffffb00000000)>>uint(r)|uint64(0xffffffffefffffff)>>uint(r-64))&1 == 0 ||
+							r >= 0x80 && (w == 0 || r == '\uFFFD') {
+							goto fail12
+						} else {
+							pos += w
+						}
+						goto ok8
+					fail12:
+						pos = pos11
+						// "\\" ["\\/bfnrt]
+						// "\\"
+						if pos >= len(parser.text) || parser.text[pos] != "\\"[0] {
+							goto fail13
+						}
+						pos++
+						// ["\\/bfnrt]
+						if r, w := _next(parser, pos); r < 0x80 && (uint64(0x800400000000)>>uint(r)|uint64(0x14404410000000)>>uint(r-64))&1 == 0 ||
+							r >= 0x80 {
+							goto fail13
+						} else {
+							pos += w
+						}
+						goto ok8
+					fail13:
+						pos = pos11
+						// "\\u" (Hex Hex Hex Hex) !> "4 hex digits"
+						// "\\u"
+						if len(parser.text)-pos < 2 || parser.text[pos:pos+2] != "\\u" {
+							goto fail15
+						}
+						pos += 2
+						// (Hex Hex Hex Hex) !> "4 hex digits"
+						// (Hex Hex Hex Hex)
+						// Hex Hex Hex Hex
+						// Hex
+						if p, n := _HexAction(parser, pos); n == nil {
+							goto fail15
+						} else {
+							pos = p
+						}
+						// Hex
+						if p, n := _HexAction(parser, pos); n == nil {
+							goto fail15
+						} else {
+							pos = p
+						}
+						// Hex
+						if p, n := _HexAction(parser, pos); n == nil {
+							goto fail15
+						} else {
+							pos = p
+						}
+						// Hex
+						if p, n := _HexAction(parser, pos); n == nil {
+							goto fail15
+						} else {
+							pos = p
+						}
+						goto ok8
+					fail15:
+						pos = pos11
+						goto fail7
+					ok8:
+					}
+					continue
+				fail7:
+					pos = pos5
+					break
+				}
+				label0 = parser.text[pos3:pos]
+			}
+		}
+		// "\""
+		if pos >= len(parser.text) || parser.text[pos] != "\""[0] {
+			goto fail
+		}
+		pos++
+		node = func(
+			start, end int, s string) Value {
+			return Value{Kind: String, Text: unescape(s)}
+		}(
+			start0, pos, label0)
+	}
+	parser.depth--
+	if _memoized(parser, _String, start) {
+		if parser.actString == nil {
+			parser.actString = make(map[int]Value)
+		}
+		parser.actString[start] = node
+	}
+	return pos, &node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _HexAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
+	if dp, de, ok := _memo(parser, _Hex, start); ok {
+		return dp, de
+	}
+	depthFails := parser.depthFails
+	if parser.depth >= 500 {
+		// Whether the depth is exceeded depends on the invocation,
+		// not just the position, so neither the failure
+		// nor the results depending on it are memoized.
+		parser.depthFails++
+		parser.lastFail = start
+		return -1, 0
+	}
+	parser.depth++
+	pos, perr := start, -1
+	// [0-9a-fA-F]
+	if r, w := _next(parser, pos); r < 0x80 && (uint64(0x3ff000000000000)>>uint(r)|uint64(0x7e0000007e)>>uint(r-64))&1 == 0 ||
+		r >= 0x80 {
+		perr = _max(perr, pos)
+		goto fail
+	} else {
+		pos += w
+	}
+	parser.depth--
+	return _memoize(parser, _Hex, start, pos, perr, depthFails)
+fail:
+	parser.depth--
+	return _memoize(parser, _Hex, start, -1, perr, depthFails)
+}
+
+func _HexNode(parser *_Parser, start int) (int, *peg.Node) {
+	dp, _ := _ensureMemo(parser, _Hex, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	key := _key{start: start, rule: _Hex}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
+	node := parser.node[key]
+	if node != nil {
+		return start + int(dp-1), node
+	}
+	pos := start
+	node = &peg.Node{Name: "Hex"}
+	parser.depth++
+	// [0-9a-fA-F]
+	if r, w := _next(parser, pos); r < 0x80 && (uint64(0x3ff000000000000)>>uint(r)|uint64(0x7e0000007e)>>uint(r-64))&1 == 0 ||
+		r >= 0x80 {
+		goto fail
+	} else {
+		node.Kids = append(node.Kids, _leaf(parser, pos, pos+w))
+		pos += w
+	}
+	parser.depth--
+	node.Text = parser.text[start:pos]
+	if _memoized(parser, _Hex, start) {
+		parser.node[key] = node
+	}
+	return pos, node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _HexFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
+	pos, failure := _failMemo(parser, _Hex, start, errPos, "Hex")
+	if failure != nil {
+		return pos, failure
+	}
+	failure = &peg.Fail{
+		Name: "Hex",
+		Pos:  int(start),
+	}
+	key := _key{start: start, rule: _Hex}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
+	if parser.depth >= 500 {
+		failure.Want = peg.MaxDepthExceeded
+		failure.Code = peg.DepthExceeded
+		return -1, failure
+	}
+	parser.depth++
+	// [0-9a-fA-F]
+	if r, w := _next(parser, pos); r < 0x80 && (uint64(0x3ff000000000000)>>uint(r)|uint64(0x7e0000007e)>>uint(r-64))&1 == 0 ||
+		r >= 0x80 {
+		if pos >= errPos {
+			failure.Kids = append(failure.Kids, &peg.Fail{
+				Pos:  int(pos),
+				Want: "[0-9a-fA-F]",
+				Code: peg.ExpectedClass,
+			})
+		}
+		goto fail
+	} else {
+		pos += w
+	}
+	parser.depth--
+	if _memoized(parser, _Hex, start) {
+		parser.fail[key] = failure
+	}
+	return pos, failure
+fail:
+	parser.depth--
+	if _memoized(parser, _Hex, start) {
+		parser.fail[key] = failure
+	}
+	return -1, failure
+}
+
+func _HexAction(parser *_Parser, start int) (int, *string) {
+	dp, _ := _ensureMemo(parser, _Hex, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	if n, ok := parser.actHex[start]; ok {
+		return start + int(dp-1), &n
+	}
+	var node string
+	pos := start
+	parser.depth++
+	// [0-9a-fA-F]
+	if r, w := _next(parser, pos); r < 0x80 && (uint64(0x3ff000000000000)>>uint(r)|uint64(0x7e0000007e)>>uint(r-64))&1 == 0 ||
+		r >= 0x80 {
+		goto fail
+	} else {
+		node = parser.text[pos : pos+w]
+		pos += w
+	}
+	parser.depth--
+	if _memoized(parser, _Hex, start) {
+		if parser.actHex == nil {
+			parser.actHex = make(map[int]string)
+		}
+		parser.actHex[start] = node
+	}
+	return pos, &node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _NumberAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
+	if dp, de, ok := _memo(parser, _Number, start); ok {
+		return dp, de
+	}
+	depthFails := parser.depthFails
+	if parser.depth >= 500 {
+		// Whether the depth is exceeded depends on the invocation,
+		// not just the position, so neither the failure
+		// nor the results depending on it are memoized.
+		parser.depthFails++
+		parser.lastFail = start
+		return -1, 0
+	}
+	parser.depth++
+	pos, perr := start, -1
+	// action
+	// n:$("-"? Int Frac? Exp?)
+	{
+		// $("-"? Int Frac? Exp?)
+		// ("-"? Int Frac? Exp?)
+		// "-"? Int Frac? Exp?
+		// "-"?
+		{
+			pos3 := pos
+			// "-"
+			if pos >= len(parser.text) || parser.text[pos] != "-"[0] {
+				perr = _max(perr, pos)
+				goto fail4
+			}
+			pos++
+			goto ok5
+		fail4:
+			pos = pos3
+		ok5:
+		}
+		// Int
+		if !_accept(parser, _IntAccepts, &pos, &perr) {
+			goto fail
+		}
+		// Frac?
+		{
+			pos7 := pos
+			// Frac
+			if !_accept(parser, _FracAccepts, &pos, &perr) {
+				goto fail8
+			}
+			goto ok9
+		fail8:
+			pos = pos7
+		ok9:
+		}
+		// Exp?
+		{
+			pos11 := pos
+			// Exp
+			if !_accept(parser, _ExpAccepts, &pos, &perr) {
+				goto fail12
+			}
+			goto ok13
+		fail12:
+			pos = pos11
+		ok13:
+		}
+	}
+	parser.depth--
+	return _memoize(parser, _Number, start, pos, perr, depthFails)
+fail:
+	parser.depth--
+	return _memoize(parser, _Number, start, -1, perr, depthFails)
+}
+
+func _NumberNode(parser *_Parser, start int) (int, *peg.Node) {
+	dp, _ := _ensureMemo(parser, _Number, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	key := _key{start: start, rule: _Number}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
+	node := parser.node[key]
+	if node != nil {
+		return start + int(dp-1), node
+	}
+	pos := start
+	node = &peg.Node{Name: "Number"}
+	parser.depth++
+	// action
+	// n:$("-"? Int Frac? Exp?)
+	{
+		// $("-"? Int Frac? Exp?)
+		// ("-"? Int Frac? Exp?)
+		{
+			nkids1 := len(node.Kids)
+			pos02 := pos
+			// "-"? Int Frac? Exp?
+			// "-"?
+			{
+				nkids4 := len(node.Kids)
+				pos5 := pos
+				// "-"
+				if pos >= len(parser.text) || parser.text[pos] != "-"[0] {
+					goto fail6
+				}
+				node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
+				pos++
+				goto ok7
+			fail6:
+				node.Kids = node.Kids[:nkids4]
+				pos = pos5
+			ok7:
+			}
+			// Int
+			if !_node(parser, _IntNode, node, &pos) {
+				goto fail
+			}
+			// Frac?
+			{
+				nkids8 := len(node.Kids)
+				pos9 := pos
+				// Frac
+				if !_node(parser, _FracNode, node, &pos) {
+					goto fail10
+				}
+				goto ok11
+			fail10:
+				node.Kids = node.Kids[:nkids8]
+				pos = pos9
+			ok11:
+			}
+			// Exp?
+			{
+				nkids12 := len(node.Kids)
+				pos13 := pos
+				// Exp
+				if !_node(parser, _ExpNode, node, &pos) {
+					goto fail14
+				}
+				goto ok15
+			fail14:
+				node.Kids = node.Kids[:nkids12]
+				pos = pos13
+			ok15:
+			}
+			sub := _sub(parser, pos02, pos, node.Kids[nkids1:])
+			node.Kids = append(node.Kids[:nkids1], sub)
+		}
+	}
+	parser.depth--
+	node.Text = parser.text[start:pos]
+	if _memoized(parser, _Number, start) {
+		parser.node[key] = node
+	}
+	return pos, node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _NumberFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
+	pos, failure := _failMemo(parser, _Number, start, errPos, "Number")
+	if failure != nil {
+		return pos, failure
+	}
+	failure = &peg.Fail{
+		Name: "Number",
+		Pos:  int(start),
+	}
+	key := _key{start: start, rule: _Number}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
+	if parser.depth >= 500 {
+		failure.Want = peg.MaxDepthExceeded
+		failure.Code = peg.DepthExceeded
+		return -1, failure
+	}
+	parser.depth++
+	// action
+	// n:$("-"? Int Frac? Exp?)
+	{
+		// $("-"? Int Frac? Exp?)
+		// ("-"? Int Frac? Exp?)
+		// "-"? Int Frac? Exp?
+		// "-"?
+		{
+			pos3 := pos
+			// "-"
+			if pos >= len(parser.text) || parser.text[pos] != "-"[0] {
+				if pos >= errPos {
+					failure.Kids = append(failure.Kids, &peg.Fail{
+						Pos:  int(pos),
+						Want: "\"-\"",
+						Code: peg.ExpectedLiteral,
+					})
+				}
+				goto fail4
+			}
+			pos++
+			goto ok5
+		fail4:
+			pos = pos3
+		ok5:
+		}
+		// Int
+		if !_fail(parser, _IntFail, errPos, failure, &pos) {
+			goto fail
+		}
+		// Frac?
+		{
+			pos7 := pos
+			// Frac
+			if !_fail(parser, _FracFail, errPos, failure, &pos) {
+				goto fail8
+			}
+			goto ok9
+		fail8:
+			pos = pos7
+		ok9:
+		}
+		// Exp?
+		{
+			pos11 := pos
+			// Exp
+			if !_fail(parser, _ExpFail, errPos, failure, &pos) {
+				goto fail12
+			}
+			goto ok13
+		fail12:
+			pos = pos11
+		ok13:
+		}
+	}
+	parser.depth--
+	if _memoized(parser, _Number, start) {
+		parser.fail[key] = failure
+	}
+	return pos, failure
+fail:
+	parser.depth--
+	if _memoized(parser, _Number, start) {
+		parser.fail[key] = failure
+	}
+	return -1, failure
+}
+
+func _NumberAction(parser *_Parser, start int) (int, *Value) {
+	var label0 string
+	dp, _ := _ensureMemo(parser, _Number, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	if n, ok := parser.actNumber[start]; ok {
+		return start + int(dp-1), &n
+	}
+	var node Value
+	pos := start
+	parser.depth++
+	// action
+	{
+		start0 := pos
+		// n:$("-"? Int Frac? Exp?)
+		{
+			// $("-"? Int Frac? Exp?)
+			{
+				pos2 := pos
+				// ("-"? Int Frac? Exp?)
+				// "-"? Int Frac? Exp?
+				// "-"?
+				{
+					pos5 := pos
+					// "-"
+					if pos >= len(parser.text) || parser.text[pos] != "-"[0] {
+						goto fail6
+					}
+					pos++
+					goto ok7
+				fail6:
+					pos = pos5
+				ok7:
+				}
+				// Int
+				if p, n := _IntAction(parser, pos); n == nil {
+					goto fail
+				} else {
+					pos = p
+				}
+				// Frac?
+				{
+					pos9 := pos
+					// Frac
+					if p, n := _FracAction(parser, pos); n == nil {
+						goto fail10
+					} else {
+						pos = p
+					}
+					goto ok11
+				fail10:
+					pos = pos9
+				ok11:
+				}
+				// Exp?
+				{
+					pos13 := pos
+					// Exp
+					if p, n := _ExpAction(parser, pos); n == nil {
+						goto fail14
+					} else {
+						pos = p
+					}
+					goto ok15
+				fail14:
+					pos = pos13
+				ok15:
+				}
+				label0 = parser.text[pos2:pos]
+			}
+		}
+		node = func(
+			start, end int, n string) Value {
+			return Value{Kind: Number, Text: n}
+		}(
+			start0, pos, label0)
+	}
+	parser.depth--
+	if _memoized(parser, _Number, start) {
+		if parser.actNumber == nil {
+			parser.actNumber = make(map[int]Value)
+		}
+		parser.actNumber[start] = node
+	}
+	return pos, &node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _IntAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
+	if dp, de, ok := _memo(parser, _Int, start); ok {
+		return dp, de
+	}
+	depthFails := parser.depthFails
+	if parser.depth >= 500 {
+		// Whether the depth is exceeded depends on the invocation,
+		// not just the position, so neither the failure
+		// nor the results depending on it are memoized.
+		parser.depthFails++
+		parser.lastFail = start
+		return -1, 0
+	}
+	parser.depth++
+	pos, perr := start, -1
+	// "0"/[1-9] [0-9]*
+	{
+		pos3 := pos
+		// "0"
+		if pos >= len(parser.text) || parser.text[pos] != "0"[0] {
+			perr = _max(perr, pos)
+			goto fail4
+		}
+		pos++
+		goto ok0
+	fail4:
+		pos = pos3
+		// [1-9] [0-9]*
+		// [1-9]
+		if r, w := _next(parser, pos); r < '1' || r > '9' {
+			perr = _max(perr, pos)
+			goto fail5
+		} else {
+			pos += w
+		}
+		// [0-9]*
+		for {
+			pos8 := pos
+			// [0-9]
+			if r, w := _next(parser, pos); r < '0' || r > '9' {
+				perr = _max(perr, pos)
+				goto fail10
+			} else {
+				pos += w
+			}
+			continue
+		fail10:
+			pos = pos8
+			break
+		}
+		goto ok0
+	fail5:
+		pos = pos3
+		goto fail
+	ok0:
+	}
+	parser.depth--
+	return _memoize(parser, _Int, start, pos, perr, depthFails)
+fail:
+	parser.depth--
+	return _memoize(parser, _Int, start, -1, perr, depthFails)
+}
+
+func _IntNode(parser *_Parser, start int) (int, *peg.Node) {
+	dp, _ := _ensureMemo(parser, _Int, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	key := _key{start: start, rule: _Int}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
+	node := parser.node[key]
+	if node != nil {
+		return start + int(dp-1), node
+	}
+	pos := start
+	node = &peg.Node{Name: "Int"}
+	parser.depth++
+	// "0"/[1-9] [0-9]*
+	{
+		pos3 := pos
+		nkids1 := len(node.Kids)
+		// "0"
+		if pos >= len(parser.text) || parser.text[pos] != "0"[0] {
+			goto fail4
+		}
+		node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
+		pos++
+		goto ok0
+	fail4:
+		node.Kids = node.Kids[:nkids1]
+		pos = pos3
+		// [1-9] [0-9]*
+		// [1-9]
+		if r, w := _next(parser, pos); r < '1' || r > '9' {
+			goto fail5
+		} else {
+			node.Kids = append(node.Kids, _leaf(parser, pos, pos+w))
+			pos += w
+		}
+		// [0-9]*
+		for {
+			nkids7 := len(node.Kids)
+			pos8 := pos
+			// [0-9]
+			if r, w := _next(parser, pos); r < '0' || r > '9' {
+				goto fail10
+			} else {
+				node.Kids = append(node.Kids, _leaf(parser, pos, pos+w))
+				pos += w
+			}
+			continue
+		fail10:
+			node.Kids = node.Kids[:nkids7]
+			pos = pos8
+			break
+		}
+		goto ok0
+	fail5:
+		node.Kids = node.Kids[:nkids1]
+		pos = pos3
+		goto fail
+	ok0:
+	}
+	parser.depth--
+	node.Text = parser.text[start:pos]
+	if _memoized(parser, _Int, start) {
+		parser.node[key] = node
+	}
+	return pos, node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _IntFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
+	pos, failure := _failMemo(parser, _Int, start, errPos, "Int")
+	if failure != nil {
+		return pos, failure
+	}
+	failure = &peg.Fail{
+		Name: "Int",
+		Pos:  int(start),
+	}
+	key := _key{start: start, rule: _Int}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
+	if parser.depth >= 500 {
+		failure.Want = peg.MaxDepthExceeded
+		failure.Code = peg.DepthExceeded
+		return -1, failure
+	}
+	parser.depth++
+	// "0"/[1-9] [0-9]*
+	{
+		pos3 := pos
+		// "0"
+		if pos >= len(parser.text) || parser.text[pos] != "0"[0] {
+			if pos >= errPos {
+				failure.Kids = append(failure.Kids, &peg.Fail{
+					Pos:  int(pos),
+					Want: "\"0\"",
+					Code: peg.ExpectedLiteral,
+				})
+			}
+			goto fail4
+		}
+		pos++
+		goto ok0
+	fail4:
+		pos = pos3
+		// [1-9] [0-9]*
+		// [1-9]
+		if r, w := _next(parser, pos); r < '1' || r > '9' {
+			if pos >= errPos {
+				failure.Kids = append(failure.Kids, &peg.Fail{
+					Pos:  int(pos),
+					Want: "[1-9]",
+					Code: peg.ExpectedClass,
+				})
+			}
+			goto fail5
+		} else {
+			pos += w
+		}
+		// [0-9]*
+		for {
+			pos8 := pos
+			// [0-9]
+			if r, w := _next(parser, pos); r < '0' || r > '9' {
+				if pos >= errPos {
+					failure.Kids = append(failure.Kids, &peg.Fail{
+						Pos:  int(pos),
+						Want: "[0-9]",
+						Code: peg.ExpectedClass,
+					})
+				}
+				goto fail10
+			} else {
+				pos += w
+			}
+			continue
+		fail10:
+			pos = pos8
+			break
+		}
+		goto ok0
+	fail5:
+		pos = pos3
+		goto fail
+	ok0:
+	}
+	parser.depth--
+	if _memoized(parser, _Int, start) {
+		parser.fail[key] = failure
+	}
+	return pos, failure
+fail:
+	parser.depth--
+	if _memoized(parser, _Int, start) {
+		parser.fail[key] = failure
+	}
+	return -1, failure
+}
+
+func _IntAction(parser *_Parser, start int) (int, *string) {
+	dp, _ := _ensureMemo(parser, _Int, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	if n, ok := parser.actInt[start]; ok {
+		return start + int(dp-1), &n
+	}
+	var node string
+	pos := start
+	parser.depth++
+	// "0"/[1-9] [0-9]*
+	{
+		pos3 := pos
+		var node2 string
+		// "0"
+		if pos >= len(parser.text) || parser.text[pos] != "0"[0] {
+			goto fail4
+		}
+		node = parser.text[pos : pos+1]
+		pos++
+		goto ok0
+	fail4:
+		node = node2
+		pos = pos3
+		// [1-9] [0-9]*
+		{
+			pos7 := pos
+			// [1-9] [0-9]*
+			// [1-9]
+			if r, w := _next(parser, pos); r < '1' || r > '9' {
+				goto fail5
+			} else {
+				pos += w
+			}
+			// [0-9]*
+			for {
+				pos10 := pos
+				// [0-9]
+				if r, w := _next(parser, pos); r < '0' || r > '9' {
+					goto fail12
+				} else {
+					pos += w
+				}
+				continue
+			fail12:
+				pos = pos10
+				break
+			}
+			node = parser.text[pos7:pos]
+		}
+
+		goto ok0
+	fail5:
+		node = node2
+		pos = pos3
+		goto fail
+	ok0:
+	}
+	parser.depth--
+	if _memoized(parser, _Int, start) {
+		if parser.actInt == nil {
+			parser.actInt = make(map[int]string)
+		}
+		parser.actInt[start] = node
+	}
+	return pos, &node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _FracAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
+	if dp, de, ok := _memo(parser, _Frac, start); ok {
+		return dp, de
+	}
+	depthFails := parser.depthFails
+	if parser.depth >= 500 {
+		// Whether the depth is exceeded depends on the invocation,
+		// not just the position, so neither the failure
+		// nor the results depending on it are memoized.
+		parser.depthFails++
+		parser.lastFail = start
+		return -1, 0
+	}
+	parser.depth++
+	pos, perr := start, -1
+	// "." [0-9]+ !> "fraction digits"
+	// "."
+	if pos >= len(parser.text) || parser.text[pos] != "."[0] {
+		perr = _max(perr, pos)
+		goto fail
+	}
+	pos++
+	// [0-9]+ !> "fraction digits"
+	{
+		pos1, perr2 := pos, perr
+		// [0-9]+
+		// [0-9]
+		if r, w := _next(parser, pos); r < '0' || r > '9' {
+			perr = _max(perr, pos)
+			goto fail4
+		} else {
+			pos += w
+		}
+		for {
+			pos7 := pos
+			// [0-9]
+			if r, w := _next(parser, pos); r < '0' || r > '9' {
+				perr = _max(perr, pos)
+				goto fail9
+			} else {
+				pos += w
+			}
+			continue
+		fail9:
+			pos = pos7
+			break
+		}
+		perr = _max(perr2, pos1)
+		goto ok5
+	fail4:
+		perr = _max(perr2, pos1)
+		goto fail
+	ok5:
+	}
+	parser.depth--
+	return _memoize(parser, _Frac, start, pos, perr, depthFails)
+fail:
+	parser.depth--
+	return _memoize(parser, _Frac, start, -1, perr, depthFails)
+}
+
+func _FracNode(parser *_Parser, start int) (int, *peg.Node) {
+	dp, _ := _ensureMemo(parser, _Frac, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	key := _key{start: start, rule: _Frac}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
+	node := parser.node[key]
+	if node != nil {
+		return start + int(dp-1), node
+	}
+	pos := start
+	node = &peg.Node{Name: "Frac"}
+	parser.depth++
+	// "." [0-9]+ !> "fraction digits"
+	// "."
+	if pos >= len(parser.text) || parser.text[pos] != "."[0] {
+		goto fail
+	}
+	node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
+	pos++
+	// [0-9]+ !> "fraction digits"
+	// [0-9]+
+	// [0-9]
+	if r, w := _next(parser, pos); r < '0' || r > '9' {
+		goto fail
+	} else {
+		node.Kids = append(node.Kids, _leaf(parser, pos, pos+w))
+		pos += w
+	}
+	for {
+		nkids1 := len(node.Kids)
+		pos2 := pos
+		// [0-9]
+		if r, w := _next(parser, pos); r < '0' || r > '9' {
+			goto fail4
+		} else {
+			node.Kids = append(node.Kids, _leaf(parser, pos, pos+w))
+			pos += w
+		}
+		continue
+	fail4:
+		node.Kids = node.Kids[:nkids1]
+		pos = pos2
+		break
+	}
+	parser.depth--
+	node.Text = parser.text[start:pos]
+	if _memoized(parser, _Frac, start) {
+		parser.node[key] = node
+	}
+	return pos, node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _FracFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
+	pos, failure := _failMemo(parser, _Frac, start, errPos, "Frac")
+	if failure != nil {
+		return pos, failure
+	}
+	failure = &peg.Fail{
+		Name: "Frac",
+		Pos:  int(start),
+	}
+	key := _key{start: start, rule: _Frac}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
+	if parser.depth >= 500 {
+		failure.Want = peg.MaxDepthExceeded
+		failure.Code = peg.DepthExceeded
+		return -1, failure
+	}
+	parser.depth++
+	// "." [0-9]+ !> "fraction digits"
+	// "."
+	if pos >= len(parser.text) || parser.text[pos] != "."[0] {
+		if pos >= errPos {
+			failure.Kids = append(failure.Kids, &peg.Fail{
+				Pos:  int(pos),
+				Want: "\".\"",
+				Code: peg.ExpectedLiteral,
+			})
+		}
+		goto fail
+	}
+	pos++
+	// [0-9]+ !> "fraction digits"
+	{
+		pos1 := pos
+		nkids3 := len(failure.Kids)
+		// [0-9]+
+		// [0-9]
+		if r, w := _next(parser, pos); r < '0' || r > '9' {
+			if pos >= errPos {
+				failure.Kids = append(failure.Kids, &peg.Fail{
+					Pos:  int(pos),
+					Want: "[0-9]",
+					Code: peg.ExpectedClass,
+				})
+			}
+			goto fail4
+		} else {
+			pos += w
+		}
+		for {
+			pos7 := pos
+			// [0-9]
+			if r, w := _next(parser, pos); r < '0' || r > '9' {
+				if pos >= errPos {
+					failure.Kids = append(failure.Kids, &peg.Fail{
+						Pos:  int(pos),
+						Want: "[0-9]",
+						Code: peg.ExpectedClass,
+					})
+				}
+				goto fail9
+			} else {
+				pos += w
+			}
+			continue
+		fail9:
+			pos = pos7
+			break
+		}
+		failure.Kids = failure.Kids[:nkids3]
+		goto ok5
+	fail4:
+		failure.Kids = failure.Kids[:nkids3]
+		if pos1 >= errPos {
+			failure.Kids = append(failure.Kids, &peg.Fail{
+				Pos:  int(pos1),
+				Want: "fraction digits",
+				Code: peg.NamedRule,
+			})
+		}
+		goto fail
+	ok5:
+	}
+	parser.depth--
+	if _memoized(parser, _Frac, start) {
+		parser.fail[key] = failure
+	}
+	return pos, failure
+fail:
+	parser.depth--
+	if _memoized(parser, _Frac, start) {
+		parser.fail[key] = failure
+	}
+	return -1, failure
+}
+
+func _FracAction(parser *_Parser, start int) (int, *string) {
+	dp, _ := _ensureMemo(parser, _Frac, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	if n, ok := parser.actFrac[start]; ok {
+		return start + int(dp-1), &n
+	}
+	var node string
+	pos := start
+	parser.depth++
+	// "." [0-9]+ !> "fraction digits"
+	{
+		pos1 := pos
+		// "." [0-9]+ !> "fraction digits"
+		// "."
+		if pos >= len(parser.text) || parser.text[pos] != "."[0] {
+			goto fail
+		}
+		pos++
+		// [0-9]+ !> "fraction digits"
+		// [0-9]+
+		// [0-9]
+		if r, w := _next(parser, pos); r < '0' || r > '9' {
+			goto fail
+		} else {
+			pos += w
+		}
+		for {
+			pos4 := pos
+			// [0-9]
+			if r, w := _next(parser, pos); r < '0' || r > '9' {
+				goto fail6
+			} else {
+				pos += w
+			}
+			continue
+		fail6:
+			pos = pos4
+			break
+		}
+		node = parser.text[pos1:pos]
+	}
+
+	parser.depth--
+	if _memoized(parser, _Frac, start) {
+		if parser.actFrac == nil {
+			parser.actFrac = make(map[int]string)
+		}
+		parser.actFrac[start] = node
+	}
+	return pos, &node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _ExpAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
+	if dp, de, ok := _memo(parser, _Exp, start); ok {
+		return dp, de
+	}
+	depthFails := parser.depthFails
+	if parser.depth >= 500 {
+		// Whether the depth is exceeded depends on the invocation,
+		// not just the position, so neither the failure
+		// nor the results depending on it are memoized.
+		parser.depthFails++
+		parser.lastFail = start
+		return -1, 0
+	}
+	parser.depth++
+	pos, perr := start, -1
+	// [eE] [+\-]? [0-9]+ !> "exponent digits"
+	// [eE]
+	if r, w := _next(parser, pos); r < 0x80 && (uint64(0x0)>>uint(r)|uint64(0x2000000020)>>uint(r-64))&1 == 0 ||
+		r >= 0x80 {
+		perr = _max(perr, pos)
+		goto fail
+	} else {
+		pos += w
+	}
+	// [+\-]?
+	{
+		pos2 := pos
+		// [+\-]
+		if r, w := _next(parser, pos); r < 0x80 && (uint64(0x280000000000)>>uint(r)|uint64(0x0)>>uint(r-64))&1 == 0 ||
+			r >= 0x80 {
+			perr = _max(perr, pos)
+			goto fail3
+		} else {
+			pos += w
+		}
+		goto ok4
+	fail3:
+		pos = pos2
+	ok4:
+	}
+	// [0-9]+ !> "exponent digits"
+	{
+		pos5, perr6 := pos, perr
+		// [0-9]+
+		// [0-9]
+		if r, w := _next(parser, pos); r < '0' || r > '9' {
+			perr = _max(perr, pos)
+			goto fail8
+		} else {
+			pos += w
+		}
+		for {
+			pos11 := pos
+			// [0-9]
+			if r, w := _next(parser, pos); r < '0' || r > '9' {
+				perr = _max(perr, pos)
+				goto fail13
+			} else {
+				pos += w
+			}
+			continue
+		fail13:
+			pos = pos11
+			break
+		}
+		perr = _max(perr6, pos5)
+		goto ok9
+	fail8:
+		perr = _max(perr6, pos5)
+		goto fail
+	ok9:
+	}
+	parser.depth--
+	return _memoize(parser, _Exp, start, pos, perr, depthFails)
+fail:
+	parser.depth--
+	return _memoize(parser, _Exp, start, -1, perr, depthFails)
+}
+
+func _ExpNode(parser *_Parser, start int) (int, *peg.Node) {
+	dp, _ := _ensureMemo(parser, _Exp, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	key := _key{start: start, rule: _Exp}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
+	node := parser.node[key]
+	if node != nil {
+		return start + int(dp-1), node
+	}
+	pos := start
+	node = &peg.Node{Name: "Exp"}
+	parser.depth++
+	// [eE] [+\-]? [0-9]+ !> "exponent digits"
+	// [eE]
+	if r, w := _next(parser, pos); r < 0x80 && (uint64(0x0)>>uint(r)|uint64(0x2000000020)>>uint(r-64))&1 == 0 ||
+		r >= 0x80 {
+		goto fail
+	} else {
+		node.Kids = append(node.Kids, _leaf(parser, pos, pos+w))
+		pos += w
+	}
+	// [+\-]?
+	{
+		nkids1 := len(node.Kids)
+		pos2 := pos
+		// [+\-]
+		if r, w := _next(parser, pos); r < 0x80 && (uint64(0x280000000000)>>uint(r)|uint64(0x0)>>uint(r-64))&1 == 0 ||
+			r >= 0x80 {
+			goto fail3
+		} else {
+			node.Kids = append(node.Kids, _leaf(parser, pos, pos+w))
+			pos += w
+		}
+		goto ok4
+	fail3:
+		node.Kids = node.Kids[:nkids1]
+		pos = pos2
+	ok4:
+	}
+	// [0-9]+ !> "exponent digits"
+	// [0-9]+
+	// [0-9]
+	if r, w := _next(parser, pos); r < '0' || r > '9' {
+		goto fail
+	} else {
+		node.Kids = append(node.Kids, _leaf(parser, pos, pos+w))
+		pos += w
+	}
+	for {
+		nkids5 := len(node.Kids)
+		pos6 := pos
+		// [0-9]
+		if r, w := _next(parser, pos); r < '0' || r > '9' {
+			goto fail8
+		} else {
+			node.Kids = append(node.Kids, _leaf(parser, pos, pos+w))
+			pos += w
+		}
+		continue
+	fail8:
+		node.Kids = node.Kids[:nkids5]
+		pos = pos6
+		break
+	}
+	parser.depth--
+	node.Text = parser.text[start:pos]
+	if _memoized(parser, _Exp, start) {
+		parser.node[key] = node
+	}
+	return pos, node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _ExpFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
+	pos, failure := _failMemo(parser, _Exp, start, errPos, "Exp")
+	if failure != nil {
+		return pos, failure
+	}
+	failure = &peg.Fail{
+		Name: "Exp",
+		Pos:  int(start),
+	}
+	key := _key{start: start, rule: _Exp}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
+	if parser.depth >= 500 {
+		failure.Want = peg.MaxDepthExceeded
+		failure.Code = peg.DepthExceeded
+		return -1, failure
+	}
+	parser.depth++
+	// [eE] [+\-]? [0-9]+ !> "exponent digits"
+	// [eE]
+	if r, w := _next(parser, pos); r < 0x80 && (uint64(0x0)>>uint(r)|uint64(0x2000000020)>>uint(r-64))&1 == 0 ||
+		r >= 0x80 {
+		if pos >= errPos {
+			failure.Kids = append(failure.Kids, &peg.Fail{
+				Pos:  int(pos),
+				Want: "[eE]",
+				Code: peg.ExpectedClass,
+			})
+		}
+		goto fail
+	} else {
+		pos += w
+	}
+	// [+\-]?
+	{
+		pos2 := pos
+		// [+\-]
+		if r, w := _next(parser, pos); r < 0x80 && (uint64(0x280000000000)>>uint(r)|uint64(0x0)>>uint(r-64))&1 == 0 ||
+			r >= 0x80 {
+			if pos >= errPos {
+				failure.Kids = append(failure.Kids, &peg.Fail{
+					Pos:  int(pos),
+					Want: "[+\\-]",
+					Code: peg.ExpectedClass,
+				})
+			}
+			goto fail3
+		} else {
+			pos += w
+		}
+		goto ok4
+	fail3:
+		pos = pos2
+	ok4:
+	}
+	// [0-9]+ !> "exponent digits"
+	{
+		pos5 := pos
+		nkids7 := len(failure.Kids)
+		// [0-9]+
+		// [0-9]
+		if r, w := _next(parser, pos); r < '0' || r > '9' {
+			if pos >= errPos {
+				failure.Kids = append(failure.Kids, &peg.Fail{
+					Pos:  int(pos),
+					Want: "[0-9]",
+					Code: peg.ExpectedClass,
+				})
+			}
+			goto fail8
+		} else {
+			pos += w
+		}
+		for {
+			pos11 := pos
+			// [0-9]
+			if r, w := _next(parser, pos); r < '0' || r > '9' {
+				if pos >= errPos {
+					failure.Kids = append(failure.Kids, &peg.Fail{
+						Pos:  int(pos),
+						Want: "[0-9]",
+						Code: peg.ExpectedClass,
+					})
+				}
+				goto fail13
+			} else {
+				pos += w
+			}
+			continue
+		fail13:
+			pos = pos11
+			break
+		}
+		failure.Kids = failure.Kids[:nkids7]
+		goto ok9
+	fail8:
+		failure.Kids = failure.Kids[:nkids7]
+		if pos5 >= errPos {
+			failure.Kids = append(failure.Kids, &peg.Fail{
+				Pos:  int(pos5),
+				Want: "exponent digits",
+				Code: peg.NamedRule,
+			})
+		}
+		goto fail
+	ok9:
+	}
+	parser.depth--
+	if _memoized(parser, _Exp, start) {
+		parser.fail[key] = failure
+	}
+	return pos, failure
+fail:
+	parser.depth--
+	if _memoized(parser, _Exp, start) {
+		parser.fail[key] = failure
+	}
+	return -1, failure
+}
+
+func _ExpAction(parser *_Parser, start int) (int, *string) {
+	dp, _ := _ensureMemo(parser, _Exp, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	if n, ok := parser.actExp[start]; ok {
+		return start + int(dp-1), &n
+	}
+	var node string
+	pos := start
+	parser.depth++
+	// [eE] [+\-]? [0-9]+ !> "exponent digits"
+	{
+		pos1 := pos
+		// [eE] [+\-]? [0-9]+ !> "exponent digits"
+		// [eE]
+		if r, w := _next(parser, pos); r < 0x80 && (uint64(0x0)>>uint(r)|uint64(0x2000000020)>>uint(r-64))&1 == 0 ||
+			r >= 0x80 {
+			goto fail
+		} else {
+			pos += w
+		}
+		// [+\-]?
+		{
+			pos4 := pos
+			// [+\-]
+			if r, w := _next(parser, pos); r < 0x80 && (uint64(0x280000000000)>>uint(r)|uint64(0x0)>>uint(r-64))&1 == 0 ||
+				r >= 0x80 {
+				goto fail5
+			} else {
+				pos += w
+			}
+			goto ok6
+		fail5:
+			pos = pos4
+		ok6:
+		}
+		// [0-9]+ !> "exponent digits"
+		// [0-9]+
+		// [0-9]
+		if r, w := _next(parser, pos); r < '0' || r > '9' {
+			goto fail
+		} else {
+			pos += w
+		}
+		for {
+			pos8 := pos
+			// [0-9]
+			if r, w := _next(parser, pos); r < '0' || r > '9' {
+				goto fail10
+			} else {
+				pos += w
+			}
+			continue
+		fail10:
+			pos = pos8
+			break
+		}
+		node = parser.text[pos1:pos]
+	}
+
+	parser.depth--
+	if _memoized(parser, _Exp, start) {
+		if parser.actExp == nil {
+			parser.actExp = make(map[int]string)
+		}
+		parser.actExp[start] = node
+	}
+	return pos, &node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _LiteralAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
+	if dp, de, ok := _memo(parser, _Literal, start); ok {
+		return dp, de
+	}
+	depthFails := parser.depthFails
+	if parser.depth >= 500 {
+		// Whether the depth is exceeded depends on the invocation,
+		// not just the position, so neither the failure
+		// nor the results depending on it are memoized.
+		parser.depthFails++
+		parser.lastFail = start
+		return -1, 0
+	}
+	parser.depth++
+	pos, perr := start, -1
+	// t:("true"/"false") {…}/"null" {…}
+	{
+		pos3 := pos
+		// action
+		// t:("true"/"false")
+		{
+			// ("true"/"false")
+			// "true"/"false"
+			{
+				if pos < len(parser.text) {
+					switch parser.text[pos] {
+					case 't':
+						if len(parser.text)-pos >= 4 && parser.text[pos:pos+4] == "true" {
+							pos += 4
+							goto ok6
+						}
+					case 'f':
+						if len(parser.text)-pos >= 5 && parser.text[pos:pos+5] == "false" {
+							perr = _max(perr, pos)
+							pos += 5
+							goto ok6
+						}
+					}
+				}
+				perr = _max(perr, pos)
+				goto fail4
+			ok6:
+			}
+		}
+		goto ok0
+	fail4:
+		pos = pos3
+		// action
+		// "null"
+		if len(parser.text)-pos < 4 || parser.text[pos:pos+4] != "null" {
+			perr = _max(perr, pos)
+			goto fail7
+		}
+		pos += 4
+		goto ok0
+	fail7:
+		pos = pos3
+		goto fail
+	ok0:
+	}
+	perr = start
+	parser.depth--
+	return _memoize(parser, _Literal, start, pos, perr, depthFails)
+fail:
+	parser.depth--
+	return _memoize(parser, _Literal, start, -1, perr, depthFails)
+}
+
+func _LiteralNode(parser *_Parser, start int) (int, *peg.Node) {
+	dp, _ := _ensureMemo(parser, _Literal, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	key := _key{start: start, rule: _Literal}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
+	node := parser.node[key]
+	if node != nil {
+		return start + int(dp-1), node
+	}
+	pos := start
+	node = &peg.Node{Name: "Literal"}
+	parser.depth++
+	// t:("true"/"false") {…}/"null" {…}
+	{
+		pos3 := pos
+		nkids1 := len(node.Kids)
+		// action
+		// t:("true"/"false")
+		{
+			// ("true"/"false")
+			{
+				nkids6 := len(node.Kids)
+				pos07 := pos
+				// "true"/"false"
+				{
+					if pos < len(parser.text) {
+						switch parser.text[pos] {
+						case 't':
+							if len(parser.text)-pos >= 4 && parser.text[pos:pos+4] == "true" {
+								node.Kids = append(node.Kids, _leaf(parser, pos, pos+4))
+								pos += 4
+								goto ok8
+							}
+						case 'f':
+							if len(parser.text)-pos >= 5 && parser.text[pos:pos+5] == "false" {
+								node.Kids = append(node.Kids, _leaf(parser, pos, pos+5))
+								pos += 5
+								goto ok8
+							}
+						}
+					}
+					goto fail4
+				ok8:
+				}
+				sub := _sub(parser, pos07, pos, node.Kids[nkids6:])
+				node.Kids = append(node.Kids[:nkids6], sub)
+			}
+		}
+		goto ok0
+	fail4:
+		node.Kids = node.Kids[:nkids1]
+		pos = pos3
+		// action
+		// "null"
+		if len(parser.text)-pos < 4 || parser.text[pos:pos+4] != "null" {
+			goto fail9
+		}
+		node.Kids = append(node.Kids, _leaf(parser, pos, pos+4))
+		pos += 4
+		goto ok0
+	fail9:
+		node.Kids = node.Kids[:nkids1]
+		pos = pos3
+		goto fail
+	ok0:
+	}
+	parser.depth--
+	node.Text = parser.text[start:pos]
+	if _memoized(parser, _Literal, start) {
+		parser.node[key] = node
+	}
+	return pos, node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _LiteralFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
+	pos, failure := _failMemo(parser, _Literal, start, errPos, "true, false, or null")
+	if failure != nil {
+		return pos, failure
+	}
+	failure = &peg.Fail{
+		Name: "Literal",
+		Pos:  int(start),
+	}
+	key := _key{start: start, rule: _Literal}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
+	if parser.depth >= 500 {
+		failure.Want = peg.MaxDepthExceeded
+		failure.Code = peg.DepthExceeded
+		return -1, failure
+	}
+	parser.depth++
+	// t:("true"/"false") {…}/"null" {…}
+	{
+		pos3 := pos
+		// action
+		// t:("true"/"false")
+		{
+			// ("true"/"false")
+			// "true"/"false"
+			{
+				pos9 := pos
+				// "true"
+				if len(parser.text)-pos < 4 || parser.text[pos:pos+4] != "true" {
+					if pos >= errPos {
+						failure.Kids = append(failure.Kids, &peg.Fail{
+							Pos:  int(pos),
+							Want: "\"true\"",
+							Code: peg.ExpectedLiteral,
+						})
+					}
+					goto fail10
+				}
+				pos += 4
+				goto ok6
+			fail10:
+				pos = pos9
+				// "false"
+				if len(parser.text)-pos < 5 || parser.text[pos:pos+5] != "false" {
+					if pos >= errPos {
+						failure.Kids = append(failure.Kids, &peg.Fail{
+							Pos:  int(pos),
+							Want: "\"false\"",
+							Code: peg.ExpectedLiteral,
+						})
+					}
+					goto fail11
+				}
+				pos += 5
+				goto ok6
+			fail11:
+				pos = pos9
+				goto fail4
+			ok6:
+			}
+		}
+		goto ok0
+	fail4:
+		pos = pos3
+		// action
+		// "null"
+		if len(parser.text)-pos < 4 || parser.text[pos:pos+4] != "null" {
+			if pos >= errPos {
+				failure.Kids = append(failure.Kids, &peg.Fail{
+					Pos:  int(pos),
+					Want: "\"null\"",
+					Code: peg.ExpectedLiteral,
+				})
+			}
+			goto fail12
+		}
+		pos += 4
+		goto ok0
+	fail12:
+		pos = pos3
+		goto fail
+	ok0:
+	}
+	parser.depth--
+	failure.Kids = nil
+	if _memoized(parser, _Literal, start) {
+		parser.fail[key] = failure
+	}
+	return pos, failure
+fail:
+	parser.depth--
+	failure.Kids = nil
+	failure.Want = "true, false, or null"
+	failure.Code = peg.NamedRule
+	if _memoized(parser, _Literal, start) {
+		parser.fail[key] = failure
+	}
+	return -1, failure
+}
+
+func _LiteralAction(parser *_Parser, start int) (int, *Value) {
+	var label0 string
+	dp, _ := _ensureMemo(parser, _Literal, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	if n, ok := parser.actLiteral[start]; ok {
+		return start + int(dp-1), &n
+	}
+	var node Value
+	pos := start
+	parser.depth++
+	// t:("true"/"false") {…}/"null" {…}
+	{
+		pos3 := pos
+		var node2 Value
+		// action
+		{
+			start5 := pos
+			// t:("true"/"false")
+			{
+				// ("true"/"false")
+				// "true"/"false"
+				{
+					if pos < len(parser.text) {
+						switch parser.text[pos] {
+						case 't':
+							if len(parser.text)-pos >= 4 && parser.text[pos:pos+4] == "true" {
+								label0 = parser.text[pos : pos+4]
+								pos += 4
+								goto ok7
+							}
+						case 'f':
+							if len(parser.text)-pos >= 5 && parser.text[pos:pos+5] == "false" {
+								label0 = parser.text[pos : pos+5]
+								pos += 5
+								goto ok7
+							}
+						}
+					}
+					goto fail4
+				ok7:
+				}
+			}
+			node = func(
+				start, end int, t string) Value {
+				return Value{Kind: Bool, Text: t}
+			}(
+				start5, pos, label0)
+		}
+		goto ok0
+	fail4:
+		node = node2
+		pos = pos3
+		// action
+		{
+			start9 := pos
+			// "null"
+			if len(parser.text)-pos < 4 || parser.text[pos:pos+4] != "null" {
+				goto fail8
+			}
+			pos += 4
+			node = func(
+				start, end int) Value {
+				return Value{Kind: Null, Text: "null"}
+			}(
+				start9, pos)
+		}
+		goto ok0
+	fail8:
+		node = node2
+		pos = pos3
+		goto fail
+	ok0:
+	}
+	parser.depth--
+	if _memoized(parser, _Literal, start) {
+		if parser.actLiteral == nil {
+			parser.actLiteral = make(map[int]Value)
+		}
+		parser.actLiteral[start] = node
+	}
+	return pos, &node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func __Accepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
+	if dp, de, ok := _memo(parser, __, start); ok {
+		return dp, de
+	}
+	depthFails := parser.depthFails
+	if parser.depth >= 500 {
+		// Whether the depth is exceeded depends on the invocation,
+		// not just the position, so neither the failure
+		// nor the results depending on it are memoized.
+		parser.depthFails++
+		parser.lastFail = start
+		return -1, 0
+	}
+	parser.depth++
+	pos, perr := start, -1
+	// [ \t\r\n]*
+	for {
+		pos1 := pos
+		// [ \t\r\n]
+		if r, w := _next(parser, pos); r < 0x80 && (uint64(0x100002600)>>uint(r)|uint64(0x0)>>uint(r-64))&1 == 0 ||
+			r >= 0x80 {
+			perr = _max(perr, pos)
+			goto fail3
+		} else {
+			pos += w
+		}
+		continue
+	fail3:
+		pos = pos1
+		break
+	}
+	perr = start
+	parser.depth--
+	return _memoize(parser, __, start, pos, perr, depthFails)
+}
+
+func __Node(parser *_Parser, start int) (int, *peg.Node) {
+	dp, _ := _ensureMemo(parser, __, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	key := _key{start: start, rule: __}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
+	node := parser.node[key]
+	if node != nil {
+		return start + int(dp-1), node
+	}
+	pos := start
+	node = &peg.Node{Name: "_"}
+	parser.depth++
+	// [ \t\r\n]*
+	for {
+		nkids0 := len(node.Kids)
+		pos1 := pos
+		// [ \t\r\n]
+		if r, w := _next(parser, pos); r < 0x80 && (uint64(0x100002600)>>uint(r)|uint64(0x0)>>uint(r-64))&1 == 0 ||
+			r >= 0x80 {
+			goto fail3
+		} else {
+			node.Kids = append(node.Kids, _leaf(parser, pos, pos+w))
+			pos += w
+		}
+		continue
+	fail3:
+		node.Kids = node.Kids[:nkids0]
+		pos = pos1
+		break
+	}
+	parser.depth--
+	node.Text = parser.text[start:pos]
+	if _memoized(parser, __, start) {
+		parser.node[key] = node
+	}
+	return pos, node
+}
+
+func __Fail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
+	pos, failure := _failMemo(parser, __, start, errPos, "whitespace")
+	if failure != nil {
+		return pos, failure
+	}
+	failure = &peg.Fail{
+		Name: "_",
+		Pos:  int(start),
+	}
+	key := _key{start: start, rule: __}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
+	if parser.depth >= 500 {
+		failure.Want = peg.MaxDepthExceeded
+		failure.Code = peg.DepthExceeded
+		return -1, failure
+	}
+	parser.depth++
+	// [ \t\r\n]*
+	for {
+		pos1 := pos
+		// [ \t\r\n]
+		if r, w := _next(parser, pos); r < 0x80 && (uint64(0x100002600)>>uint(r)|uint64(0x0)>>uint(r-64))&1 == 0 ||
+			r >= 0x80 {
+			if pos >= errPos {
+				failure.Kids = append(failure.Kids, &peg.Fail{
+					Pos:  int(pos),
+					Want: "[ \\t\\r\\n]",
+					Code: peg.ExpectedClass,
+				})
+			}
+			goto fail3
+		} else {
+			pos += w
+		}
+		continue
+	fail3:
+		pos = pos1
+		break
+	}
+	parser.depth--
+	failure.Kids = nil
+	if _memoized(parser, __, start) {
+		parser.fail[key] = failure
+	}
+	return pos, failure
+}
+
+func __Action(parser *_Parser, start int) (int, *string) {
+	dp, _ := _ensureMemo(parser, __, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	if n, ok := parser.act_[start]; ok {
+		return start + int(dp-1), &n
+	}
+	var node string
+	pos := start
+	parser.depth++
+	// [ \t\r\n]*
+	{
+		pos0 := pos
+		// [ \t\r\n]*
+		for {
+			pos2 := pos
+			// [ \t\r\n]
+			if r, w := _next(parser, pos); r < 0x80 && (uint64(0x100002600)>>uint(r)|uint64(0x0)>>uint(r-64))&1 == 0 ||
+				r >= 0x80 {
+				goto fail4
+			} else {
+				pos += w
+			}
+			continue
+		fail4:
+			pos = pos2
+			break
+		}
+		node = parser.text[pos0:pos]
+	}
+
+	parser.depth--
+	if _memoized(parser, __, start) {
+		if parser.act_ == nil {
+			parser.act_ = make(map[int]string)
+		}
+		parser.act_[start] = node
+	}
+	return pos, &node
+}
+
+func _Delimited___7b__Member___7dAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
+	if dp, de, ok := _memo(parser, _Delimited___7b__Member___7d, start); ok {
+		return dp, de
+	}
+	depthFails := parser.depthFails
+	if parser.depth >= 500 {
+		// Whether the depth is exceeded depends on the invocation,
+		// not just the position, so neither the failure
+		// nor the results depending on it are memoized.
+		parser.depthFails++
+		parser.lastFail = start
+		return -1, 0
+	}
+	parser.depth++
+	pos, perr := start, -1
+	// action
+	// "{" _ es:(e:Member es:(_ "," _ x:Member {…})* _ {…}/() {…}) "}"
+	// "{"
+	if pos >= len(parser.text) || parser.text[pos] != "{"[0] {
+		perr = _max(perr, pos)
+		goto fail
+	}
+	pos++
+	// _
+	if !_accept(parser, __Accepts, &pos, &perr) {
+		goto fail
+	}
+	// es:(e:Member es:(_ "," _ x:Member {…})* _ {…}/() {…})
+	{
+		// (e:Member es:(_ "," _ x:Member {…})* _ {…}/() {…})
+		// e:Member es:(_ "," _ x:Member {…})* _ {…}/() {…}
+		{
+			pos5 := pos
+			// action
+			// e:Member es:(_ "," _ x:Member {…})* _
+			// e:Member
+			{
+				// Member
+				if !_accept(parser, _MemberAccepts, &pos, &perr) {
+					goto fail6
+				}
+			}
+			// es:(_ "," _ x:Member {…})*
+			{
+				// (_ "," _ x:Member {…})*
+				for {
+					pos11 := pos
+					// (_ "," _ x:Member {…})
+					// action
+					// _ "," _ x:Member
+					// _
+					if !_accept(parser, __Accepts, &pos, &perr) {
+						goto fail13
+					}
+					// ","
+					if pos >= len(parser.text) || parser.text[pos] != ","[0] {
+						perr = _max(perr, pos)
+						goto fail13
+					}
+					pos++
+					// _
+					if !_accept(parser, __Accepts, &pos, &perr) {
+						goto fail13
+					}
+					// x:Member
+					{
+						// Member
+						if !_accept(parser, _MemberAccepts, &pos, &perr) {
+							goto fail13
+						}
+					}
+					continue
+				fail13:
+					pos = pos11
+					break
+				}
+			}
+			// _
+			if !_accept(parser, __Accepts, &pos, &perr) {
+				goto fail6
+			}
+			goto ok2
+		fail6:
+			pos = pos5
+			// action
+			// ()
+		ok2:
+		}
+	}
+	// "}"
+	if pos >= len(parser.text) || parser.text[pos] != "}"[0] {
+		perr = _max(perr, pos)
+		goto fail
+	}
+	pos++
+	parser.depth--
+	return _memoize(parser, _Delimited___7b__Member___7d, start, pos, perr, depthFails)
+fail:
+	parser.depth--
+	return _memoize(parser, _Delimited___7b__Member___7d, start, -1, perr, depthFails)
+}
+
+func _Delimited___7b__Member___7dNode(parser *_Parser, start int) (int, *peg.Node) {
+	dp, _ := _ensureMemo(parser, _Delimited___7b__Member___7d, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	key := _key{start: start, rule: _Delimited___7b__Member___7d}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
+	node := parser.node[key]
+	if node != nil {
+		return start + int(dp-1), node
+	}
+	pos := start
+	node = &peg.Node{Name: "Delimited<\"{\", Member, \"}\">"}
+	parser.depth++
+	// action
+	// "{" _ es:(e:Member es:(_ "," _ x:Member {…})* _ {…}/() {…}) "}"
+	// "{"
+	if pos >= len(parser.text) || parser.text[pos] != "{"[0] {
+		goto fail
+	}
+	node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
+	pos++
+	// _
+	if !_node(parser, __Node, node, &pos) {
+		goto fail
+	}
+	// es:(e:Member es:(_ "," _ x:Member {…})* _ {…}/() {…})
+	{
+		// (e:Member es:(_ "," _ x:Member {…})* _ {…}/() {…})
+		{
+			nkids2 := len(node.Kids)
+			pos03 := pos
+			// e:Member es:(_ "," _ x:Member {…})* _ {…}/() {…}
+			{
+				pos7 := pos
+				nkids5 := len(node.Kids)
+				// action
+				// e:Member es:(_ "," _ x:Member {…})* _
+				// e:Member
+				{
+					// Member
+					if !_node(parser, _MemberNode, node, &pos) {
+						goto fail8
+					}
+				}
+				// es:(_ "," _ x:Member {…})*
+				{
+					// (_ "," _ x:Member {…})*
+					for {
+						nkids12 := len(node.Kids)
+						pos13 := pos
+						// (_ "," _ x:Member {…})
+						{
+							nkids16 := len(node.Kids)
+							pos017 := pos
+							// action
+							// _ "," _ x:Member
+							// _
+							if !_node(parser, __Node, node, &pos) {
+								goto fail15
+							}
+							// ","
+							if pos >= len(parser.text) || parser.text[pos] != ","[0] {
+								goto fail15
+							}
+							node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
+							pos++
+							// _
+							if !_node(parser, __Node, node, &pos) {
+								goto fail15
+							}
+							// x:Member
+							{
+								// Member
+								if !_node(parser, _MemberNode, node, &pos) {
+									goto fail15
+								}
+							}
+							sub := _sub(parser, pos017, pos, node.Kids[nkids16:])
+							node.Kids = append(node.Kids[:nkids16], sub)
+						}
+						continue
+					fail15:
+						node.Kids = node.Kids[:nkids12]
+						pos = pos13
+						break
+					}
+				}
+				// _
+				if !_node(parser, __Node, node, &pos) {
+					goto fail8
+				}
+				goto ok4
+			fail8:
+				node.Kids = node.Kids[:nkids5]
+				pos = pos7
+				// action
+				// ()
+			ok4:
+			}
+			sub := _sub(parser, pos03, pos, node.Kids[nkids2:])
+			node.Kids = append(node.Kids[:nkids2], sub)
+		}
+	}
+	// "}"
+	if pos >= len(parser.text) || parser.text[pos] != "}"[0] {
+		goto fail
+	}
+	node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
+	pos++
+	parser.depth--
+	node.Text = parser.text[start:pos]
+	if _memoized(parser, _Delimited___7b__Member___7d, start) {
+		parser.node[key] = node
+	}
+	return pos, node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _Delimited___7b__Member___7dFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
+	pos, failure := _failMemo(parser, _Delimited___7b__Member___7d, start, errPos, "Delimited<\"{\", Member, \"}\">")
+	if failure != nil {
+		return pos, failure
+	}
+	failure = &peg.Fail{
+		Name: "Delimited<\"{\", Member, \"}\">",
+		Pos:  int(start),
+	}
+	key := _key{start: start, rule: _Delimited___7b__Member___7d}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
+	if parser.depth >= 500 {
+		failure.Want = peg.MaxDepthExceeded
+		failure.Code = peg.DepthExceeded
+		return -1, failure
+	}
+	parser.depth++
+	// action
+	// "{" _ es:(e:Member es:(_ "," _ x:Member {…})* _ {…}/() {…}) "}"
+	// "{"
+	if pos >= len(parser.text) || parser.text[pos] != "{"[0] {
+		if pos >= errPos {
+			failure.Kids = append(failure.Kids, &peg.Fail{
+				Pos:  int(pos),
+				Want: "\"{\"",
+				Code: peg.ExpectedLiteral,
+			})
+		}
+		goto fail
+	}
+	pos++
+	// _
+	if !_fail(parser, __Fail, errPos, failure, &pos) {
+		goto fail
+	}
+	// es:(e:Member es:(_ "," _ x:Member {…})* _ {…}/() {…})
+	{
+		// (e:Member es:(_ "," _ x:Member {…})* _ {…}/() {…})
+		// e:Member es:(_ "," _ x:Member {…})* _ {…}/() {…}
+		{
+			pos5 := pos
+			// action
+			// e:Member es:(_ "," _ x:Member {…})* _
+			// e:Member
+			{
+				// Member
+				if !_fail(parser, _MemberFail, errPos, failure, &pos) {
+					goto fail6
+				}
+			}
+			// es:(_ "," _ x:Member {…})*
+			{
+				// (_ "," _ x:Member {…})*
+				for {
+					pos11 := pos
+					// (_ "," _ x:Member {…})
+					// action
+					// _ "," _ x:Member
+					// _
+					if !_fail(parser, __Fail, errPos, failure, &pos) {
+						goto fail13
+					}
+					// ","
+					if pos >= len(parser.text) || parser.text[pos] != ","[0] {
+						if pos >= errPos {
+							failure.Kids = append(failure.Kids, &peg.Fail{
+								Pos:  int(pos),
+								Want: "\",\"",
+								Code: peg.ExpectedLiteral,
+							})
+						}
+						goto fail13
+					}
+					pos++
+					// _
+					if !_fail(parser, __Fail, errPos, failure, &pos) {
+						goto fail13
+					}
+					// x:Member
+					{
+						// Member
+						if !_fail(parser, _MemberFail, errPos, failure, &pos) {
+							goto fail13
+						}
+					}
+					continue
+				fail13:
+					pos = pos11
+					break
+				}
+			}
+			// _
+			if !_fail(parser, __Fail, errPos, failure, &pos) {
+				goto fail6
+			}
+			goto ok2
+		fail6:
+			pos = pos5
+			// action
+			// ()
+		ok2:
+		}
+	}
+	// "}"
+	if pos >= len(parser.text) || parser.text[pos] != "}"[0] {
+		if pos >= errPos {
+			failure.Kids = append(failure.Kids, &peg.Fail{
+				Pos:  int(pos),
+				Want: "\"}\"",
+				Code: peg.ExpectedLiteral,
+			})
+		}
+		goto fail
+	}
+	pos++
+	parser.depth--
+	if _memoized(parser, _Delimited___7b__Member___7d, start) {
+		parser.fail[key] = failure
+	}
+	return pos, failure
+fail:
+	parser.depth--
+	if _memoized(parser, _Delimited___7b__Member___7d, start) {
+		parser.fail[key] = failure
+	}
+	return -1, failure
+}
+
+func _Delimited___7b__Member___7dAction(parser *_Parser, start int) (int, *[]Value) {
+	var label0 Value
+	var label1 Value
+	var label2 []Value
+	var label3 []Value
+	dp, _ := _ensureMemo(parser, _Delimited___7b__Member___7d, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	if n, ok := parser.actDelimited___7b__Member___7d[start]; ok {
+		return start + int(dp-1), &n
+	}
+	var node []Value
+	pos := start
+	parser.depth++
+	// action
+	{
+		start0 := pos
+		// "{" _ es:(e:Member es:(_ "," _ x:Member {…})* _ {…}/() {…}) "}"
+		// "{"
+		if pos >= len(parser.text) || parser.text[pos] != "{"[0] {
+			goto fail
+		}
+		pos++
+		// _
+		if p, n := __Action(parser, pos); n == nil {
+			goto fail
+		} else {
+			pos = p
+		}
+		// es:(e:Member es:(_ "," _ x:Member {…})* _ {…}/() {…})
+		{
+			// (e:Member es:(_ "," _ x:Member {…})* _ {…}/() {…})
+			// e:Member es:(_ "," _ x:Member {…})* _ {…}/() {…}
+			{
+				pos6 := pos
+				var node5 []Value
+				// action
+				{
+					start8 := pos
+					// e:Member es:(_ "," _ x:Member {…})* _
+					// e:Member
+					{
+						// Member
+						if p, n := _MemberAction(parser, pos); n == nil {
+							goto fail7
+						} else {
+							label0 = *n
+							pos = p
+						}
+					}
+					// es:(_ "," _ x:Member {…})*
+					{
+						// (_ "," _ x:Member {…})*
+						for {
+							pos13 := pos
+							var node14 Value
+							// (_ "," _ x:Member {…})
+							// action
+							{
+								start16 := pos
+								// _ "," _ x:Member
+								// _
+								if p, n := __Action(parser, pos); n == nil {
+									goto fail15
+								} else {
+									pos = p
+								}
+								// ","
+								if pos >= len(parser.text) || parser.text[pos] != ","[0] {
+									goto fail15
+								}
+								pos++
+								// _
+								if p, n := __Action(parser, pos); n == nil {
+									goto fail15
+								} else {
+									pos = p
+								}
+								// x:Member
+								{
+									// Member
+									if p, n := _MemberAction(parser, pos); n == nil {
+										goto fail15
+									} else {
+										label1 = *n
+										pos = p
+									}
+								}
+								node14 = func(
+									start, end int, e Value, x Value) Value {
+									return Value(x)
+								}(
+									start16, pos, label0, label1)
+							}
+							label2 = append(label2, node14)
+							continue
+						fail15:
+							pos = pos13
+							break
+						}
+					}
+					// _
+					if p, n := __Action(parser, pos); n == nil {
+						goto fail7
+					} else {
+						pos = p
+					}
+					label3 = func(
+						start, end int, e Value, es []Value, x Value) []Value {
+						return []Value(append([]Value{e}, es...))
+					}(
+						start8, pos, label0, label2, label1)
+				}
+				goto ok3
+			fail7:
+				label3 = node5
+				pos = pos6
+				// action
+				{
+					start20 := pos
+					// ()
+					label3 = func(
+						start, end int) []Value {
+						return []Value(nil)
+					}(
+						start20, pos)
+				}
+			ok3:
+			}
+		}
+		// "}"
+		if pos >= len(parser.text) || parser.text[pos] != "}"[0] {
+			goto fail
+		}
+		pos++
+		node = func(
+			start, end int, es []Value) []Value {
+			return []Value(es)
+		}(
+			start0, pos, label3)
+	}
+	parser.depth--
+	if _memoized(parser, _Delimited___7b__Member___7d, start) {
+		if parser.actDelimited___7b__Member___7d == nil {
+			parser.actDelimited___7b__Member___7d = make(map[int][]Value)
+		}
+		parser.actDelimited___7b__Member___7d[start] = node
+	}
+	return pos, &node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _Delimited___5b__Value___5dAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
+	if dp, de, ok := _memo(parser, _Delimited___5b__Value___5d, start); ok {
+		return dp, de
+	}
+	depthFails := parser.depthFails
+	if parser.depth >= 500 {
+		// Whether the depth is exceeded depends on the invocation,
+		// not just the position, so neither the failure
+		// nor the results depending on it are memoized.
+		parser.depthFails++
+		parser.lastFail = start
+		return -1, 0
+	}
+	parser.depth++
+	pos, perr := start, -1
+	// action
+	// "[" _ es:(e:Value es:(_ "," _ x:Value {…})* _ {…}/() {…}) "]"
+	// "["
+	if pos >= len(parser.text) || parser.text[pos] != "["[0] {
+		perr = _max(perr, pos)
+		goto fail
+	}
+	pos++
+	// _
+	if !_accept(parser, __Accepts, &pos, &perr) {
+		goto fail
+	}
+	// es:(e:Value es:(_ "," _ x:Value {…})* _ {…}/() {…})
+	{
+		// (e:Value es:(_ "," _ x:Value {…})* _ {…}/() {…})
+		// e:Value es:(_ "," _ x:Value {…})* _ {…}/() {…}
+		{
+			pos5 := pos
+			// action
+			// e:Value es:(_ "," _ x:Value {…})* _
+			// e:Value
+			{
+				// Value
+				if !_accept(parser, _ValueAccepts, &pos, &perr) {
+					goto fail6
+				}
+			}
+			// es:(_ "," _ x:Value {…})*
+			{
+				// (_ "," _ x:Value {…})*
+				for {
+					pos11 := pos
+					// (_ "," _ x:Value {…})
+					// action
+					// _ "," _ x:Value
+					// _
+					if !_accept(parser, __Accepts, &pos, &perr) {
+						goto fail13
+					}
+					// ","
+					if pos >= len(parser.text) || parser.text[pos] != ","[0] {
+						perr = _max(perr, pos)
+						goto fail13
+					}
+					pos++
+					// _
+					if !_accept(parser, __Accepts, &pos, &perr) {
+						goto fail13
+					}
+					// x:Value
+					{
+						// Value
+						if !_accept(parser, _ValueAccepts, &pos, &perr) {
+							goto fail13
+						}
+					}
+					continue
+				fail13:
+					pos = pos11
+					break
+				}
+			}
+			// _
+			if !_accept(parser, __Accepts, &pos, &perr) {
+				goto fail6
+			}
+			goto ok2
+		fail6:
+			pos = pos5
+			// action
+			// ()
+		ok2:
+		}
+	}
+	// "]"
+	if pos >= len(parser.text) || parser.text[pos] != "]"[0] {
+		perr = _max(perr, pos)
+		goto fail
+	}
+	pos++
+	parser.depth--
+	return _memoize(parser, _Delimited___5b__Value___5d, start, pos, perr, depthFails)
+fail:
+	parser.depth--
+	return _memoize(parser, _Delimited___5b__Value___5d, start, -1, perr, depthFails)
+}
+
+func _Delimited___5b__Value___5dNode(parser *_Parser, start int) (int, *peg.Node) {
+	dp, _ := _ensureMemo(parser, _Delimited___5b__Value___5d, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	key := _key{start: start, rule: _Delimited___5b__Value___5d}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
+	node := parser.node[key]
+	if node != nil {
+		return start + int(dp-1), node
+	}
+	pos := start
+	node = &peg.Node{Name: "Delimited<\"[\", Value, \"]\">"}
+	parser.depth++
+	// action
+	// "[" _ es:(e:Value es:(_ "," _ x:Value {…})* _ {…}/() {…}) "]"
+	// "["
+	if pos >= len(parser.text) || parser.text[pos] != "["[0] {
+		goto fail
+	}
+	node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
+	pos++
+	// _
+	if !_node(parser, __Node, node, &pos) {
+		goto fail
+	}
+	// es:(e:Value es:(_ "," _ x:Value {…})* _ {…}/() {…})
+	{
+		// (e:Value es:(_ "," _ x:Value {…})* _ {…}/() {…})
+		{
+			nkids2 := len(node.Kids)
+			pos03 := pos
+			// e:Value es:(_ "," _ x:Value {…})* _ {…}/() {…}
+			{
+				pos7 := pos
+				nkids5 := len(node.Kids)
+				// action
+				// e:Value es:(_ "," _ x:Value {…})* _
+				// e:Value
+				{
+					// Value
+					if !_node(parser, _ValueNode, node, &pos) {
+						goto fail8
+					}
+				}
+				// es:(_ "," _ x:Value {…})*
+				{
+					// (_ "," _ x:Value {…})*
+					for {
+						nkids12 := len(node.Kids)
+						pos13 := pos
+						// (_ "," _ x:Value {…})
+						{
+							nkids16 := len(node.Kids)
+							pos017 := pos
+							// action
+							// _ "," _ x:Value
+							// _
+							if !_node(parser, __Node, node, &pos) {
+								goto fail15
+							}
+							// ","
+							if pos >= len(parser.text) || parser.text[pos] != ","[0] {
+								goto fail15
+							}
+							node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
+							pos++
+							// _
+							if !_node(parser, __Node, node, &pos) {
+								goto fail15
+							}
+							// x:Value
+							{
+								// Value
+								if !_node(parser, _ValueNode, node, &pos) {
+									goto fail15
+								}
+							}
+							sub := _sub(parser, pos017, pos, node.Kids[nkids16:])
+							node.Kids = append(node.Kids[:nkids16], sub)
+						}
+						continue
+					fail15:
+						node.Kids = node.Kids[:nkids12]
+						pos = pos13
+						break
+					}
+				}
+				// _
+				if !_node(parser, __Node, node, &pos) {
+					goto fail8
+				}
+				goto ok4
+			fail8:
+				node.Kids = node.Kids[:nkids5]
+				pos = pos7
+				// action
+				// ()
+			ok4:
+			}
+			sub := _sub(parser, pos03, pos, node.Kids[nkids2:])
+			node.Kids = append(node.Kids[:nkids2], sub)
+		}
+	}
+	// "]"
+	if pos >= len(parser.text) || parser.text[pos] != "]"[0] {
+		goto fail
+	}
+	node.Kids = append(node.Kids, _leaf(parser, pos, pos+1))
+	pos++
+	parser.depth--
+	node.Text = parser.text[start:pos]
+	if _memoized(parser, _Delimited___5b__Value___5d, start) {
+		parser.node[key] = node
+	}
+	return pos, node
+fail:
+	parser.depth--
+	return -1, nil
+}
+
+func _Delimited___5b__Value___5dFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
+	pos, failure := _failMemo(parser, _Delimited___5b__Value___5d, start, errPos, "Delimited<\"[\", Value, \"]\">")
+	if failure != nil {
+		return pos, failure
+	}
+	failure = &peg.Fail{
+		Name: "Delimited<\"[\", Value, \"]\">",
+		Pos:  int(start),
+	}
+	key := _key{start: start, rule: _Delimited___5b__Value___5d}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
+	if parser.depth >= 500 {
+		failure.Want = peg.MaxDepthExceeded
+		failure.Code = peg.DepthExceeded
+		return -1, failure
+	}
+	parser.depth++
+	// action
+	// "[" _ es:(e:Value es:(_ "," _ x:Value {…})* _ {…}/() {…}) "]"
+	// "["
+	if pos >= len(parser.text) || parser.text[pos] != "["[0] {
+		if pos >= errPos {
+			failure.Kids = append(failure.Kids, &peg.Fail{
+				Pos:  int(pos),
+				Want: "\"[\"",
+				Code: peg.ExpectedLiteral,
+			})
+		}
+		goto fail
+	}
+	pos++
+	// _
+	if !_fail(parser, __Fail, errPos, failure, &pos) {
+		goto fail
+	}
+	// es:(e:Value es:(_ "," _ x:Value {…})* _ {…}/() {…})
+	{
+		// (e:Value es:(_ "," _ x:Value {…})* _ {…}/() {…})
+		// e:Value es:(_ "," _ x:Value {…})* _ {…}/() {…}
+		{
+			pos5 := pos
+			// action
+			// e:Value es:(_ "," _ x:Value {…})* _
+			// e:Value
+			{
+				// Value
+				if !_fail(parser, _ValueFail, errPos, failure, &pos) {
+					goto fail6
+				}
+			}
+			// es:(_ "," _ x:Value {…})*
+			{
+				// (_ "," _ x:Value {…})*
+				for {
+					pos11 := pos
+					// (_ "," _ x:Value {…})
+					// action
+					// _ "," _ x:Value
+					// _
+					if !_fail(parser, __Fail, errPos, failure, &pos) {
+						goto fail13
+					}
+					// ","
+					if pos >= len(parser.text) || parser.text[pos] != ","[0] {
+						if pos >= errPos {
+							failure.Kids = append(failure.Kids, &peg.Fail{
+								Pos:  int(pos),
+								Want: "\",\"",
+								Code: peg.ExpectedLiteral,
+							})
+						}
+						goto fail13
+					}
+					pos++
+					// _
+					if !_fail(parser, __Fail, errPos, failure, &pos) {
+						goto fail13
+					}
+					// x:Value
+					{
+						// Value
+						if !_fail(parser, _ValueFail, errPos, failure, &pos) {
+							goto fail13
+						}
+					}
+					continue
+				fail13:
+					pos = pos11
+					break
+				}
+			}
+			// _
+			if !_fail(parser, __Fail, errPos, failure, &pos) {
+				goto fail6
+			}
+			goto ok2
+		fail6:
+			pos = pos5
+			// action
+			// ()
+		ok2:
+		}
+	}
+	// "]"
+	if pos >= len(parser.text) || parser.text[pos] != "]"[0] {
+		if pos >= errPos {
+			failure.Kids = append(failure.Kids, &peg.Fail{
+				Pos:  int(pos),
+				Want: "\"]\"",
+				Code: peg.ExpectedLiteral,
+			})
+		}
+		goto fail
+	}
+	pos++
+	parser.depth--
+	if _memoized(parser, _Delimited___5b__Value___5d, start) {
+		parser.fail[key] = failure
+	}
+	return pos, failure
+fail:
+	parser.depth--
+	if _memoized(parser, _Delimited___5b__Value___5d, start) {
+		parser.fail[key] = failure
+	}
+	return -1, failure
+}
+
+func _Delimited___5b__Value___5dAction(parser *_Parser, start int) (int, *[]Value) {
+	var label0 Value
+	var label1 Value
+	var label2 []Value
+	var label3 []Value
+	dp, _ := _ensureMemo(parser, _Delimited___5b__Value___5d, start)
+	if dp < 0 {
+		return -1, nil
+	}
+	if n, ok := parser.actDelimited___5b__Value___5d[start]; ok {
+		return start + int(dp-1), &n
+	}
+	var node []Value
+	pos := start
+	parser.depth++
+	// action
+	{
+		start0 := pos
+		// "[" _ es:(e:Value es:(_ "," _ x:Value {…})* _ {…}/() {…}) "]"
+		// "["
+		if pos >= len(parser.text) || parser.text[pos] != "["[0] {
+			goto fail
+		}
+		pos++
+		// _
+		if p, n := __Action(parser, pos); n == nil {
+			goto fail
+		} else {
+			pos = p
+		}
+		// es:(e:Value es:(_ "," _ x:Value {…})* _ {…}/() {…})
+		{
+			// (e:Value es:(_ "," _ x:Value {…})* _ {…}/() {…})
+			// e:Value es:(_ "," _ x:Value {…})* _ {…}/() {…}
+			{
+				pos6 := pos
+				var node5 []Value
+				// action
+				{
+					start8 := pos
+					// e:Value es:(_ "," _ x:Value {…})* _
+					// e:Value
+					{
+						// Value
+						if p, n := _ValueAction(parser, pos); n == nil {
+							goto fail7
+						} else {
+							label0 = *n
+							pos = p
+						}
+					}
+					// es:(_ "," _ x:Value {…})*
+					{
+						// (_ "," _ x:Value {…})*
+						for {
+							pos13 := pos
+							var node14 Value
+							// (_ "," _ x:Value {…})
+							// action
+							{
+								start16 := pos
+								// _ "," _ x:Value
+								// _
+								if p, n := __Action(parser, pos); n == nil {
+									goto fail15
+								} else {
+									pos = p
+								}
+								// ","
+								if pos >= len(parser.text) || parser.text[pos] != ","[0] {
+									goto fail15
+								}
+								pos++
+								// _
+								if p, n := __Action(parser, pos); n == nil {
+									goto fail15
+								} else {
+									pos = p
+								}
+								// x:Value
+								{
+									// Value
+									if p, n := _ValueAction(parser, pos); n == nil {
+										goto fail15
+									} else {
+										label1 = *n
+										pos = p
+									}
+								}
+								node14 = func(
+									start, end int, e Value, x Value) Value {
+									return Value(x)
+								}(
+									start16, pos, label0, label1)
+							}
+							label2 = append(label2, node14)
+							continue
+						fail15:
+							pos = pos13
+							break
+						}
+					}
+					// _
+					if p, n := __Action(parser, pos); n == nil {
+						goto fail7
+					} else {
+						pos = p
+					}
+					label3 = func(
+						start, end int, e Value, es []Value, x Value) []Value {
+						return []Value(append([]Value{e}, es...))
+					}(
+						start8, pos, label0, label2, label1)
+				}
+				goto ok3
+			fail7:
+				label3 = node5
+				pos = pos6
+				// action
+				{
+					start20 := pos
+					// ()
+					label3 = func(
+						start, end int) []Value {
+						return []Value(nil)
+					}(
+						start20, pos)
+				}
+			ok3:
+			}
+		}
+		// "]"
+		if pos >= len(parser.text) || parser.text[pos] != "]"[0] {
+			goto fail
+		}
+		pos++
+		node = func(
+			start, end int, es []Value) []Value {
+			return []Value(es)
+		}(
+			start0, pos, label3)
+	}
+	parser.depth--
+	if _memoized(parser, _Delimited___5b__Value___5d, start) {
+		if parser.actDelimited___5b__Value___5d == nil {
+			parser.actDelimited___5b__Value___5d = make(map[int][]Value)
+		}
+		parser.actDelimited___5b__Value___5d[start] = node
+	}
+	return pos, &node
+fail:
+	parser.depth--
+	return -1, nil
+}
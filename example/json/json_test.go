@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text string
+		want string // the compact output or the error
+	}{
+		{text: `null`, want: `null`},
+		{text: ` true `, want: `true`},
+		{text: `false`, want: `false`},
+		{text: `-0.5e+10`, want: `-0.5e+10`},
+		{text: `""`, want: `""`},
+		{text: `"a\"b\\c\/d\né😀"`, want: `"a\"b\\c/d\né😀"`},
+		{text: `"\u0001"`, want: `"\u0001"`},
+		{text: `[ ]`, want: `[]`},
+		{text: `{ }`, want: `{}`},
+		{text: `[1, [2, [3]], {"a": {"b": []}}]`, want: `[1,[2,[3]],{"a":{"b":[]}}]`},
+		{text: "{\"k\" :\t\"v\" ,\r\n \"n\": null}", want: `{"k":"v","n":null}`},
+
+		{text: ``, want: `1.1: want a value; got EOF`},
+		{text: `[1,]`, want: `1.4: want a value; got ']'`},
+		{text: `[1 2]`, want: `1.4: want "," or "]"; got '2]'`},
+		{text: `{"a" 1}`, want: `1.6: want ":"; got '1}'`},
+		{text: `{1: 2}`, want: `1.2: want "\"" or "}"; got '1: 2}'`},
+		{text: `"\uzz"`, want: `1.4: want 4 hex digits; got 'zz"'`},
+		{text: `"\x"`, want: `1.3: want ["\\/bfnrt]; got 'x"'`},
+		{text: `1.`, want: `1.3: want fraction digits; got EOF`},
+		{text: `1e`, want: `1.3: want [+\-] or exponent digits; got EOF`},
+		{text: `01`, want: `1.2: want ".", [eE], or end of line; got '1'`},
+		{text: `nul`, want: `1.1: want "-", "0", "[", "\"", "{", [1-9], or true, false, or null; got 'nul'`},
+		{text: `[] []`, want: `1.4: want end of line; got '[]'`},
+		{text: `"abc`, want: `1.5: want "\"", "\\", "\\u", or [^"\\\x00-\x1f]; got EOF`},
+	}
+	for _, test := range tests {
+		var got string
+		if v, err := parse(test.text); err != nil {
+			e := err.(peg.Error)
+			got = fmt.Sprintf("%d.%d: %s", e.Loc.Line, e.Loc.Column, e.Message)
+		} else {
+			got = string(v.AppendTo(nil))
+		}
+		if got != test.want {
+			t.Errorf("parse(%q)=%q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+// TestEvents checks that each value of the benchmark input
+// means the same after a round trip through the parser.
+func TestEvents(t *testing.T) {
+	lines := readLines(t, "testdata/events.ndjson")
+	for i, line := range lines {
+		v, err := parse(line)
+		if err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		if got := string(v.AppendTo(nil)); !sameJSON(t, got, line) {
+			t.Fatalf("line %d: got %s, want %s", i+1, got, line)
+		}
+	}
+}
+
+// TestStress parses random values, and random mutations of them,
+// checking that the parser agrees with encoding/json.
+func TestStress(t *testing.T) {
+	n := 2000
+	if testing.Short() {
+		n = 200
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < n; i++ {
+		text := randText(rnd, randValue(rnd, 5))
+		v, err := parse(text)
+		if err != nil {
+			t.Fatalf("parse(%q) failed: %v", text, err)
+		}
+		if got := string(v.AppendTo(nil)); !sameJSON(t, got, text) {
+			t.Fatalf("parse(%q)=%s", text, got)
+		}
+
+		mut := mutate(rnd, text)
+		_, err = parse(mut)
+		if valid := json.Valid([]byte(mut)); valid != (err == nil) {
+			t.Fatalf("parse(%q) error=%v, but json.Valid=%v", mut, err, valid)
+		}
+		if err == nil {
+			continue
+		}
+		// The error is located within the text,
+		// and is the same as that of a new parse.
+		e := err.(peg.Error)
+		if e.Loc.Byte < 0 || e.Loc.Byte > len(mut) {
+			t.Fatalf("parse(%q) error at byte %d", mut, e.Loc.Byte)
+		}
+		if _, err2 := parse(mut); err2.Error() != err.Error() {
+			t.Fatalf("parse(%q) error=%v, then %v", mut, err, err2)
+		}
+	}
+}
+
+func TestNesting(t *testing.T) {
+	deep := func(n int) string {
+		return strings.Repeat(`[{"a":`, n) + "1" + strings.Repeat(`}]`, n)
+	}
+	if _, err := parse(deep(50)); err != nil {
+		t.Errorf("parse(50 deep) failed: %v", err)
+	}
+	for _, n := range []int{200, 10000} {
+		if _, err := parse(deep(n)); err == nil {
+			t.Errorf("parse(%d deep) succeeded, want an error", n)
+		}
+	}
+}
+
+// TestStringsShared checks that strings without escapes
+// are sliced from the input, not copied.
+func TestStringsShared(t *testing.T) {
+	allocs := func(s string) float64 {
+		text := `["` + s + `", "` + s + `"]`
+		return testing.AllocsPerRun(10, func() {
+			if _, err := parse(text); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+	short, long := allocs("x"), allocs(strings.Repeat("x", 4096))
+	if long != short {
+		t.Errorf("parsing long strings made %v allocations, short %v", long, short)
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	lines := readLines(b, "testdata/events.ndjson")
+	b.SetBytes(int64(size(lines)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			if _, err := parse(line); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkUnmarshal is encoding/json on the BenchmarkParse input,
+// for comparison.
+func BenchmarkUnmarshal(b *testing.B) {
+	lines := readLines(b, "testdata/events.ndjson")
+	b.SetBytes(int64(size(lines)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			var v interface{}
+			if err := json.Unmarshal([]byte(line), &v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkErrors parses each line of the BenchmarkParse input
+// missing its last byte, so each parse builds an error.
+func BenchmarkErrors(b *testing.B) {
+	lines := readLines(b, "testdata/events.ndjson")
+	for i, line := range lines {
+		lines[i] = line[:len(line)-1]
+	}
+	b.SetBytes(int64(size(lines)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			if _, err := parse(line); err == nil {
+				b.Fatalf("parse(%q) succeeded", line)
+			}
+		}
+	}
+}
+
+func BenchmarkNesting(b *testing.B) {
+	text := strings.Repeat(`[{"a":`, 50) + "1" + strings.Repeat(`}]`, 50)
+	b.SetBytes(int64(len(text)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := parse(text); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func readLines(tb testing.TB, path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+	var lines []string
+	in := bufio.NewScanner(f)
+	in.Buffer(nil, 64<<20)
+	for in.Scan() {
+		lines = append(lines, in.Text())
+	}
+	if err := in.Err(); err != nil {
+		tb.Fatal(err)
+	}
+	return lines
+}
+
+func size(lines []string) int {
+	var n int
+	for _, line := range lines {
+		n += len(line)
+	}
+	return n
+}
+
+// sameJSON returns whether the JSON texts decode to the same value,
+// comparing numbers by their text.
+func sameJSON(t *testing.T, a, b string) bool {
+	t.Helper()
+	decode := func(s string) interface{} {
+		d := json.NewDecoder(strings.NewReader(s))
+		d.UseNumber()
+		var v interface{}
+		if err := d.Decode(&v); err != nil {
+			t.Fatalf("decode(%q) failed: %v", s, err)
+		}
+		return v
+	}
+	return reflect.DeepEqual(decode(a), decode(b))
+}
+
+// randValue returns a random value
+// of the kinds decoded by encoding/json, nested at most depth deep.
+func randValue(rnd *rand.Rand, depth int) interface{} {
+	n := 6
+	if depth == 0 {
+		n = 4
+	}
+	switch rnd.Intn(n) {
+	case 0:
+		return nil
+	case 1:
+		return rnd.Intn(2) == 0
+	case 2:
+		switch rnd.Intn(3) {
+		case 0:
+			return json.Number(strconv.Itoa(rnd.Intn(2000) - 1000))
+		case 1:
+			return json.Number(strconv.FormatFloat(rnd.NormFloat64()*1e3, 'f', -1, 64))
+		default:
+			return json.Number(strconv.FormatFloat(rnd.ExpFloat64()*1e-20, 'e', -1, 64))
+		}
+	case 3:
+		return randString(rnd)
+	case 4:
+		a := make([]interface{}, rnd.Intn(4))
+		for i := range a {
+			a[i] = randValue(rnd, depth-1)
+		}
+		return a
+	default:
+		m := make(map[string]interface{})
+		for i := rnd.Intn(4); i > 0; i-- {
+			m[randString(rnd)] = randValue(rnd, depth-1)
+		}
+		return m
+	}
+}
+
+func randString(rnd *rand.Rand) string {
+	const chars = "ab \"\\/\n\t\x01é世😀"
+	rs := []rune(chars)
+	var s strings.Builder
+	for i := rnd.Intn(8); i > 0; i-- {
+		s.WriteRune(rs[rnd.Intn(len(rs))])
+	}
+	return s.String()
+}
+
+// randText returns the JSON text of v,
+// either compact or indented.
+func randText(rnd *rand.Rand, v interface{}) string {
+	var b bytes.Buffer
+	e := json.NewEncoder(&b)
+	e.SetEscapeHTML(rnd.Intn(2) == 0)
+	if rnd.Intn(2) == 0 {
+		e.SetIndent(" ", "\t")
+	}
+	if err := e.Encode(v); err != nil {
+		panic(err)
+	}
+	return b.String()
+}
+
+// mutate returns text with a random ASCII edit:
+// a byte inserted, or a rune deleted or replaced by a byte,
+// or the text truncated.
+func mutate(rnd *rand.Rand, text string) string {
+	const bytes = "{}[],:\"\\ 0-.eE+tfnu1a"
+	c := string(bytes[rnd.Intn(len(bytes))])
+	// Edit whole runes, keeping the text valid UTF-8.
+	i := rnd.Intn(len(text) + 1)
+	for i < len(text) && !utf8.RuneStart(text[i]) {
+		i++
+	}
+	_, w := utf8.DecodeRuneInString(text[i:])
+	switch rnd.Intn(4) {
+	case 0:
+		return text[:i] + c + text[i:]
+	case 1:
+		return text[:i] + text[i+w:]
+	case 2:
+		return text[:i] + c + text[i+w:]
+	default:
+		return text[:i]
+	}
+}
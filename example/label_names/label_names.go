@@ -1,5 +1,6 @@
 // Test labels with the same name but in different choice branches.
-// 	peggy -o label_names.go label_names.peggy
+//
+//	peggy -o label_names.go label_names.peggy
 package main
 
 import (
@@ -45,9 +46,16 @@ type _Parser struct {
 	deltaErr [][_N]int32
 	node     map[_key]*peg.Node
 	fail     map[_key]*peg.Fail
-	act      map[_key]interface{}
+	actExpr  map[int]string
 	lastFail int
-	data     interface{}
+	// Checks records the failed Expect
+	// and the diagnostics of the actions.
+	peg.Checks
+	// failBudget is the number of rule nodes
+	// that the Fail pass may yet build, if boundFails.
+	failBudget int
+	boundFails bool
+	data       interface{}
 }
 
 type _key struct {
@@ -59,6 +67,10 @@ type tooBigError struct{}
 
 func (tooBigError) Error() string { return "input is too big" }
 
+// _NewParser returns a new Parser for the text.
+// Only the memo table used by every pass is allocated here;
+// the tables of the Node, Fail, and Action passes
+// are allocated when the pass is first run.
 func _NewParser(text string) (*_Parser, error) {
 	n := len(text) + 1
 	if n < 0 {
@@ -68,13 +80,81 @@ func _NewParser(text string) (*_Parser, error) {
 		text:     text,
 		deltaPos: make([][_N]int32, n),
 		deltaErr: make([][_N]int32, n),
-		node:     make(map[_key]*peg.Node),
-		fail:     make(map[_key]*peg.Fail),
-		act:      make(map[_key]interface{}),
 	}
 	return p, nil
 }
 
+// _Diagnostics returns the diagnostics recorded by Diag,
+// in the order that they were recorded, without duplicates,
+// each a peg.Error located at the start of its text
+// with End the location of the end of its text.
+func _Diagnostics(parser *_Parser) []peg.Error {
+	return parser.Diagnostics(peg.Locator{}, parser.text)
+}
+
+// _ExprMatches returns whether the Expr rule matches all of text.
+// It runs only the Accepts pass.
+func _ExprMatches(text string) bool {
+	parser, err := _NewParser(text)
+	if err != nil {
+		return false
+	}
+	pos, _ := _ExprAccepts(parser, 0)
+	return pos == len(text)
+}
+
+// _ExprParseBounded parses the Expr rule
+// at the beginning of text, like _ExprParseAt,
+// but on failure, the Fail pass builds at most max rule nodes,
+// replacing the rest by leaves wanting the rule's name,
+// and the peg.Error lists at most max of the sorted Wants.
+// So the cost of a failure is bounded
+// even for adversarial inputs.
+func _ExprParseBounded(text string, max int) (end int, v string, err error) {
+	parser, err := _NewParser(text)
+	if err != nil {
+		return -1, v, err
+	}
+	dp, de := _ExprAccepts(parser, 0)
+	if dp < 0 {
+		if max < 1 {
+			max = 1
+		}
+		parser.lastFail = de
+		parser.failBudget, parser.boundFails = max, true
+		_, fail := _ExprFail(parser, 0, de)
+		opts := peg.ErrorOptions{Sort: true, MaxWants: max}
+		return -1, v, opts.SimpleError(text, fail)
+	}
+	end, p := _ExprAction(parser, 0)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, 0, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
+// _ExprParseAt parses the Expr rule
+// beginning at byte offset start of the parser's text,
+// which must be between 0 and the length of the text,
+// without requiring the rule to match the rest of the text.
+// It returns the byte offset of the end of the match and the rule's result,
+// or a peg.Error if the rule does not match at start.
+// The parser may be used for multiple calls,
+// sharing its memo tables.
+func _ExprParseAt(parser *_Parser, start int) (end int, v string, err error) {
+	dp, de := _ExprAccepts(parser, start)
+	if dp < 0 {
+		parser.lastFail = start + de
+		_, fail := _ExprFail(parser, start, start+de)
+		return -1, v, peg.SimpleError(parser.text, fail)
+	}
+	end, p := _ExprAction(parser, start)
+	if err := parser.ExpectError(peg.Locator{}, parser.text, start, end); err != nil {
+		return -1, v, err
+	}
+	return end, *p, nil
+}
+
 func _max(a, b int) int {
 	if a > b {
 		return a
@@ -82,37 +162,51 @@ func _max(a, b int) int {
 	return b
 }
 
+// _getMemo returns the memo table entries for the rule at the start position.
+// Each entry is 0 if the rule has not been memoized at the position.
+func _getMemo(parser *_Parser, rule, start int) (dp, de int32) {
+	return parser.deltaPos[start][rule], parser.deltaErr[start][rule]
+}
+
+// _setMemo sets the memo table entries for the rule at the start position.
+func _setMemo(parser *_Parser, rule, start int, dp, de int32) {
+	parser.deltaPos[start][rule] = dp
+	parser.deltaErr[start][rule] = de
+}
+
 func _memoize(parser *_Parser, rule, start, pos, perr int) (int, int) {
 	parser.lastFail = perr
 	derr := perr - start
-	parser.deltaErr[start][rule] = int32(derr + 1)
 	if pos >= 0 {
 		dpos := pos - start
-		parser.deltaPos[start][rule] = int32(dpos + 1)
+		_setMemo(parser, rule, start, int32(dpos+1), int32(derr+1))
 		return dpos, derr
 	}
-	parser.deltaPos[start][rule] = -1
+	_setMemo(parser, rule, start, -1, int32(derr+1))
 	return -1, derr
 }
 
 func _memo(parser *_Parser, rule, start int) (int, int, bool) {
-	dp := parser.deltaPos[start][rule]
+	dp, de := _getMemo(parser, rule, start)
 	if dp == 0 {
 		return 0, 0, false
 	}
 	if dp > 0 {
 		dp--
 	}
-	de := parser.deltaErr[start][rule] - 1
-	return int(dp), int(de), true
+	return int(dp), int(de - 1), true
 }
 
-func _failMemo(parser *_Parser, rule, start, errPos int) (int, *peg.Fail) {
+// _failMemo returns the memoized result of the Fail pass
+// for the rule at the start position,
+// or start and nil if the rule's Fail node must be built.
+// If the Fail pass is bounded and its budget is spent,
+// the node is not built, but replaced by a leaf wanting want at errPos.
+func _failMemo(parser *_Parser, rule, start, errPos int, want string) (int, *peg.Fail) {
 	if start > parser.lastFail {
 		return -1, &peg.Fail{}
 	}
-	dp := parser.deltaPos[start][rule]
-	de := parser.deltaErr[start][rule]
+	dp, de := _getMemo(parser, rule, start)
 	if start+int(de-1) < errPos {
 		if dp > 0 {
 			return start + int(dp-1), &peg.Fail{}
@@ -126,6 +220,16 @@ func _failMemo(parser *_Parser, rule, start, errPos int) (int, *peg.Fail) {
 	if dp > 0 && f != nil {
 		return start + int(dp-1), f
 	}
+	if parser.boundFails {
+		if parser.failBudget == 0 {
+			f := &peg.Fail{Pos: errPos, Want: want, Code: peg.NamedRule}
+			if dp > 0 {
+				return start + int(dp-1), f
+			}
+			return -1, f
+		}
+		parser.failBudget--
+	}
 	return start, nil
 }
 
@@ -183,19 +287,16 @@ func _leaf(parser *_Parser, start, end int) *peg.Node {
 func use(interface{}) {}
 
 func _ExprAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
-	var labels [2]string
-	use(labels)
 	if dp, de, ok := _memo(parser, _Expr, start); ok {
 		return dp, de
 	}
 	pos, perr := start, -1
-	// letter:[a] {…}/letter:[b] {…}
+	// letter:[a] {…}!memo/letter:[b] {…}!memo
 	{
 		pos3 := pos
 		// action
 		// letter:[a]
 		{
-			pos5 := pos
 			// [a]
 			if r, w := _next(parser, pos); r != 'a' {
 				perr = _max(perr, pos)
@@ -203,7 +304,6 @@ func _ExprAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
 			} else {
 				pos += w
 			}
-			labels[0] = parser.text[pos5:pos]
 		}
 		goto ok0
 	fail4:
@@ -211,7 +311,6 @@ func _ExprAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
 		// action
 		// letter:[b]
 		{
-			pos7 := pos
 			// [b]
 			if r, w := _next(parser, pos); r != 'b' {
 				perr = _max(perr, pos)
@@ -219,7 +318,6 @@ func _ExprAccepts(parser *_Parser, start int) (deltaPos, deltaErr int) {
 			} else {
 				pos += w
 			}
-			labels[1] = parser.text[pos7:pos]
 		}
 		goto ok0
 	fail6:
@@ -233,27 +331,27 @@ fail:
 }
 
 func _ExprNode(parser *_Parser, start int) (int, *peg.Node) {
-	var labels [2]string
-	use(labels)
-	dp := parser.deltaPos[start][_Expr]
+	dp, _ := _getMemo(parser, _Expr, start)
 	if dp < 0 {
 		return -1, nil
 	}
 	key := _key{start: start, rule: _Expr}
+	if parser.node == nil {
+		parser.node = make(map[_key]*peg.Node)
+	}
 	node := parser.node[key]
 	if node != nil {
 		return start + int(dp-1), node
 	}
 	pos := start
 	node = &peg.Node{Name: "Expr"}
-	// letter:[a] {…}/letter:[b] {…}
+	// letter:[a] {…}!memo/letter:[b] {…}!memo
 	{
 		pos3 := pos
 		nkids1 := len(node.Kids)
 		// action
 		// letter:[a]
 		{
-			pos5 := pos
 			// [a]
 			if r, w := _next(parser, pos); r != 'a' {
 				goto fail4
@@ -261,7 +359,6 @@ func _ExprNode(parser *_Parser, start int) (int, *peg.Node) {
 				node.Kids = append(node.Kids, _leaf(parser, pos, pos+w))
 				pos += w
 			}
-			labels[0] = parser.text[pos5:pos]
 		}
 		goto ok0
 	fail4:
@@ -270,7 +367,6 @@ func _ExprNode(parser *_Parser, start int) (int, *peg.Node) {
 		// action
 		// letter:[b]
 		{
-			pos7 := pos
 			// [b]
 			if r, w := _next(parser, pos); r != 'b' {
 				goto fail6
@@ -278,7 +374,6 @@ func _ExprNode(parser *_Parser, start int) (int, *peg.Node) {
 				node.Kids = append(node.Kids, _leaf(parser, pos, pos+w))
 				pos += w
 			}
-			labels[1] = parser.text[pos7:pos]
 		}
 		goto ok0
 	fail6:
@@ -295,9 +390,7 @@ fail:
 }
 
 func _ExprFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
-	var labels [2]string
-	use(labels)
-	pos, failure := _failMemo(parser, _Expr, start, errPos)
+	pos, failure := _failMemo(parser, _Expr, start, errPos, "Expr")
 	if failure != nil {
 		return pos, failure
 	}
@@ -306,26 +399,28 @@ func _ExprFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 		Pos:  int(start),
 	}
 	key := _key{start: start, rule: _Expr}
-	// letter:[a] {…}/letter:[b] {…}
+	if parser.fail == nil {
+		parser.fail = make(map[_key]*peg.Fail)
+	}
+	// letter:[a] {…}!memo/letter:[b] {…}!memo
 	{
 		pos3 := pos
 		// action
 		// letter:[a]
 		{
-			pos5 := pos
 			// [a]
 			if r, w := _next(parser, pos); r != 'a' {
 				if pos >= errPos {
 					failure.Kids = append(failure.Kids, &peg.Fail{
 						Pos:  int(pos),
 						Want: "[a]",
+						Code: peg.ExpectedClass,
 					})
 				}
 				goto fail4
 			} else {
 				pos += w
 			}
-			labels[0] = parser.text[pos5:pos]
 		}
 		goto ok0
 	fail4:
@@ -333,20 +428,19 @@ func _ExprFail(parser *_Parser, start, errPos int) (int, *peg.Fail) {
 		// action
 		// letter:[b]
 		{
-			pos7 := pos
 			// [b]
 			if r, w := _next(parser, pos); r != 'b' {
 				if pos >= errPos {
 					failure.Kids = append(failure.Kids, &peg.Fail{
 						Pos:  int(pos),
 						Want: "[b]",
+						Code: peg.ExpectedClass,
 					})
 				}
 				goto fail6
 			} else {
 				pos += w
 			}
-			labels[1] = parser.text[pos7:pos]
 		}
 		goto ok0
 	fail6:
@@ -362,32 +456,42 @@ fail:
 }
 
 func _ExprAction(parser *_Parser, start int) (int, *string) {
-	var labels [2]string
-	use(labels)
 	var label0 string
 	var label1 string
-	dp := parser.deltaPos[start][_Expr]
+	dp, _ := _getMemo(parser, _Expr, start)
 	if dp < 0 {
 		return -1, nil
 	}
-	key := _key{start: start, rule: _Expr}
-	n := parser.act[key]
-	if n != nil {
-		n := n.(string)
+	if n, ok := parser.actExpr[start]; ok {
 		return start + int(dp-1), &n
 	}
 	var node string
 	pos := start
-	// letter:[a] {…}/letter:[b] {…}
+	// letter:[a] {…}!memo/letter:[b] {…}!memo
 	{
 		pos3 := pos
 		var node2 string
+		// dry run
+		{
+			pos, perr := pos, -1
+			// action
+			// letter:[a]
+			{
+				// [a]
+				if r, w := _next(parser, pos); r != 'a' {
+					perr = _max(perr, pos)
+					goto fail4
+				} else {
+					pos += w
+				}
+			}
+			use(perr)
+		}
 		// action
 		{
-			start5 := pos
+			start6 := pos
 			// letter:[a]
 			{
-				pos6 := pos
 				// [a]
 				if r, w := _next(parser, pos); r != 'a' {
 					goto fail4
@@ -395,49 +499,65 @@ func _ExprAction(parser *_Parser, start int) (int, *string) {
 					label0 = parser.text[pos : pos+w]
 					pos += w
 				}
-				labels[0] = parser.text[pos6:pos]
 			}
 			node = func(
 				start, end int, letter string) string {
 				fmt.Printf("a=[%s]\n", letter)
 				return string(letter)
 			}(
-				start5, pos, label0)
+				start6, pos, label0)
 		}
 		goto ok0
 	fail4:
 		node = node2
 		pos = pos3
+		// dry run
+		{
+			pos, perr := pos, -1
+			// action
+			// letter:[b]
+			{
+				// [b]
+				if r, w := _next(parser, pos); r != 'b' {
+					perr = _max(perr, pos)
+					goto fail8
+				} else {
+					pos += w
+				}
+			}
+			use(perr)
+		}
 		// action
 		{
-			start8 := pos
+			start10 := pos
 			// letter:[b]
 			{
-				pos9 := pos
 				// [b]
 				if r, w := _next(parser, pos); r != 'b' {
-					goto fail7
+					goto fail8
 				} else {
 					label1 = parser.text[pos : pos+w]
 					pos += w
 				}
-				labels[1] = parser.text[pos9:pos]
 			}
 			node = func(
 				start, end int, letter string) string {
 				fmt.Printf("b=[%s]\n", letter)
 				return string(letter)
 			}(
-				start8, pos, label1)
+				start10, pos, label1)
 		}
 		goto ok0
-	fail7:
+	fail8:
 		node = node2
 		pos = pos3
 		goto fail
 	ok0:
 	}
-	parser.act[key] = node
+	if parser.actExpr == nil {
+		parser.actExpr = make(map[int]string)
+	}
+	parser.actExpr[start] = node
 	return pos, &node
 fail:
 	return -1, nil
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestExamples tests that each checked-in example parser
+// is what peggy currently generates from its grammar,
+// so changes to the generator regenerate the examples.
+func TestExamples(t *testing.T) {
+	grammars, err := filepath.Glob(filepath.Join("example", "*", "*.peggy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(grammars) == 0 {
+		t.Fatal("no example grammars")
+	}
+	dir, err := ioutil.TempDir("", "peggy_examples")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	peggy := filepath.Join(dir, "peggy")
+	cmd := exec.Command("go", "build", "-o", peggy, ".")
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("failed to build peggy: %v", err)
+	}
+	for _, grammar := range grammars {
+		example := strings.TrimSuffix(grammar, ".peggy") + ".go"
+		// Each example is its own package, so it is generated to its own directory.
+		exampleDir := filepath.Join(dir, filepath.Base(filepath.Dir(example)))
+		if err := os.Mkdir(exampleDir, 0777); err != nil {
+			t.Fatal(err)
+		}
+		// peggy is run in the example's directory,
+		// as the generated file's comment says to regenerate it.
+		got := filepath.Join(exampleDir, filepath.Base(example))
+		cmd := exec.Command(peggy, "-o", got, filepath.Base(grammar))
+		cmd.Dir = filepath.Dir(grammar)
+		if msg, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("peggy %s: %v\n%s", grammar, err, msg)
+			continue
+		}
+		gotSrc, err := ioutil.ReadFile(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantSrc, err := ioutil.ReadFile(example)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(gotSrc) != string(wantSrc) {
+			t.Errorf("%s is stale; regenerate it in its directory with peggy -o %s %s",
+				example, filepath.Base(example), filepath.Base(grammar))
+		}
+	}
+}
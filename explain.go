@@ -0,0 +1,97 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/eaburns/peggy/grammar"
+	"github.com/eaburns/peggy/peg"
+)
+
+// explain generates the parser for a grammar file
+// along with a harness that parses an input from in
+// with the root rule and runs the Fail pass,
+// and writes to w an explanation of the failure, from ExplainFail.
+// If the input parses, the explanation says so.
+// If root is the empty string, the first rule of the grammar is used.
+func explain(w io.Writer, in io.Reader, file, root string) error {
+	input, err := ioutil.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	cfg, err := flagConfig()
+	if err != nil {
+		return err
+	}
+	var fails bytes.Buffer
+	if err := runHarness(&fails, bytes.NewReader(input), file, root, "explain", explainHarness, cfg); err != nil {
+		return err
+	}
+	if fails.Len() == 0 {
+		_, err := io.WriteString(w, "the input parsed without error\n")
+		return err
+	}
+	fail, err := peg.ReadFail(bufio.NewReader(&fails))
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	g, err := cfg.Parse(bufio.NewReader(f), file)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Check(g); err != nil {
+		return err
+	}
+	return grammar.ExplainFail(w, "<stdin>", string(input), g, fail)
+}
+
+var explainHarness = `package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	peg "{{.PegImport}}"
+)
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	input := string(data)
+	p, err := {{.Prefix}}NewParser(input)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	pos, perr := {{.Prefix}}{{.Root}}Accepts(p, 0)
+	if pos >= 0 {
+		if pos < len(input) {
+			fmt.Fprintf(os.Stderr, "parsed only %d of %d bytes\n", pos, len(input))
+		}
+		return
+	}
+	_, fail := {{.Prefix}}{{.Root}}Fail(p, 0, perr)
+	if err := peg.WriteFail(os.Stdout, fail); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`
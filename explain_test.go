@@ -0,0 +1,71 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peggy_explain_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "g.peggy")
+	const grammar = `{
+package calc
+
+import "github.com/eaburns/peggy/peg"
+
+func main() {}
+}
+Top <- List !.
+List <- "[" (Elem ("," Elem)*)? "]"
+Elem <- Num / List
+Num <- [0-9]+
+`
+	if err := ioutil.WriteFile(file, []byte(grammar), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	var got strings.Builder
+	if err := explain(&got, strings.NewReader("[1,[2,x]]"), file, ""); err != nil {
+		t.Fatalf("explain(_, \"[1,[2,x]]\", _, \"\")=%v, want nil", err)
+	}
+	want := `<stdin>:1.7: want [0-9] or "["; got 'x]]'
+
+The furthest the parse reached is 1.7.
+Rules tried there: Elem, List, Num.
+
+The rules leading to the failure, each with where it began:
+Top at 1.1 (` + file + `:8.1)
+  List at 1.1 (` + file + `:9.1)
+    Elem at 1.4 (` + file + `:10.1)
+      List at 1.4 (` + file + `:9.1)
+        Elem at 1.7 (` + file + `:10.1)
+          Num at 1.7 (` + file + `:11.1)
+            wanted [0-9]: the rune is not in the character class
+          List at 1.7 (` + file + `:9.1)
+            wanted "[": the literal did not match the input
+`
+	if got.String() != want {
+		t.Errorf("explain(_, \"[1,[2,x]]\", _, \"\") wrote\n%s\nwant\n%s", got.String(), want)
+	}
+
+	got.Reset()
+	if err := explain(&got, strings.NewReader("[1]"), file, ""); err != nil {
+		t.Fatalf("explain(_, \"[1]\", _, \"\")=%v, want nil", err)
+	}
+	if got.String() != "the input parsed without error\n" {
+		t.Errorf("explain(_, \"[1]\", _, \"\") wrote %q, want success", got.String())
+	}
+}
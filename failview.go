@@ -0,0 +1,35 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+// failview pretty-prints each peg.Fail tree
+// of a log written with peg.WriteFail.
+func failview(w io.Writer, r io.Reader) error {
+	in := bufio.NewReader(r)
+	for {
+		f, err := peg.ReadFail(in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := peg.PrettyWrite(w, f); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+func TestFailview(t *testing.T) {
+	fails := []*peg.Fail{
+		{Name: "A", Kids: []*peg.Fail{{Pos: 1, Want: `"a"`}}},
+		{Name: "B", Pos: 2, Want: "b"},
+	}
+	var log bytes.Buffer
+	var want strings.Builder
+	for _, f := range fails {
+		if err := peg.WriteFail(&log, f); err != nil {
+			t.Fatalf("peg.WriteFail(_, %s)=%v, want nil", peg.Pretty(f), err)
+		}
+		want.WriteString(peg.Pretty(f) + "\n")
+	}
+	var got strings.Builder
+	if err := failview(&got, &log); err != nil {
+		t.Fatalf("failview(_, _)=%v, want nil", err)
+	}
+	if got.String() != want.String() {
+		t.Errorf("failview(_, _) wrote\n%s\nwant\n%s", got.String(), want.String())
+	}
+}
+
+func TestFailviewTruncated(t *testing.T) {
+	var log bytes.Buffer
+	if err := peg.WriteFail(&log, &peg.Fail{Name: "A"}); err != nil {
+		t.Fatalf("peg.WriteFail(_, _)=%v, want nil", err)
+	}
+	log.Truncate(log.Len() - 1)
+	var got strings.Builder
+	if err := failview(&got, &log); err == nil {
+		t.Errorf("failview(_, _)=nil, want error")
+	}
+}
@@ -1,5 +1,5 @@
 module github.com/eaburns/peggy
 
-go 1.13
+go 1.22
 
 require github.com/eaburns/pretty v1.0.0
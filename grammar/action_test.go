@@ -1,4 +1,4 @@
-package main
+package grammar
 
 import (
 	"encoding/json"
@@ -30,6 +30,18 @@ var actionTests = []actionTest{
 			{"abc☺XYZ", "abc☺XYZ"},
 		},
 	},
+	{
+		name: "sliced and concatenated strings",
+		grammar: `
+			A <- Text ";" Mixed
+			Text <- ("a" Id)+ ", "? $[x]* !"q" (&"" ^ / "z")
+			Id <- [0-9] ("." [0-9])?
+			Mixed <- ("m" ("-" { return string("+") }))* Text?`,
+		cases: []actionTestCase{
+			{"a1a2.3, xx;m-m-a4", "a1a2.3, xx;m+m+a4"},
+			{"a1;", "a1;"},
+		},
+	},
 	{
 		name:    "char class",
 		grammar: `A <- [a-zA-Z0-9☺]`,
@@ -163,6 +175,39 @@ var actionTests = []actionTest{
 			{"abcxyz", "abcxyz"},
 		},
 	},
+	{
+		name:    "capture",
+		grammar: `A <- $[0-9]+`,
+		cases: []actionTestCase{
+			{"1", "1"},
+			{"123", "123"},
+		},
+	},
+	{
+		name: "capture non-string",
+		grammar: `
+			A <- x:$( Num Num ) "xyz" { return string(x) }
+			Num <- [0-9] { return 5 }`,
+		cases: []actionTestCase{
+			{"12xyz", "12"},
+		},
+	},
+	{
+		name:    "longest choice",
+		grammar: `A <- "a" { return "one" } | "aa" { return "two" } | "a" "a" { return "tie" }`,
+		cases: []actionTestCase{
+			{"a", "one"},
+			{"aa", "two"},
+		},
+	},
+	{
+		name:    "diff",
+		grammar: `A <- [0-9]+ - "0" [0-9]*`,
+		cases: []actionTestCase{
+			{"1", "1"},
+			{"120", "120"},
+		},
+	},
 	{
 		name:    "subexpr",
 		grammar: `A <- ("a" "b" "c")`,
@@ -200,6 +245,111 @@ var actionTests = []actionTest{
 			}},
 		},
 	},
+	{
+		name: "labeled predicates",
+		grammar: `
+			A <- p:&"a" q:!"b" r:(&{ true }) s:. (t:&"c" { return string(t) })? {
+				return string("[" + p + q + r + "]" + s)
+			}`,
+		cases: []actionTestCase{
+			{"a", "[]a"},
+		},
+	},
+	{
+		name: "memoized action in failed branch",
+		grammar: `
+			A <- (("e" { n, _ := parser.data.(int); parser.data = n+1; return "" }) "x" / "e" "y") {
+				n, _ := parser.data.(int)
+				return int(n)
+			}`,
+		cases: []actionTestCase{
+			{"ex", 1.0},
+			{"ey", 1.0},
+		},
+	},
+	{
+		name: "declared result type",
+		grammar: `
+			A -> []int <- n:Num ns:("," m:Num { return int(m) })* {
+				return []int(append([]int{n}, ns...))
+			}
+			Num -> int <- "1" { return int(1) } / "2" { return int(2) }`,
+		cases: []actionTestCase{
+			{"1", []interface{}{1.0}},
+			{"2,1,2", []interface{}{2.0, 1.0, 2.0}},
+		},
+	},
+	{
+		name: "declared result type of uninferable actions",
+		grammar: `
+			A -> int <- "(" e:A ")" { return e } / n:[0-9] { return int(n[0] - '0') }`,
+		cases: []actionTestCase{
+			{"7", 7.0},
+			{"((7))", 7.0},
+		},
+	},
+	{
+		name: "memoized zero-valued rule result",
+		grammar: `
+			A <- (B "x" / B "y") {
+				n, _ := parser.data.(int)
+				return int(n)
+			}
+			B <- "e" { n, _ := parser.data.(int); parser.data = n+1; return int(0) }`,
+		cases: []actionTestCase{
+			{"ex", 1.0},
+			{"ey", 1.0},
+		},
+	},
+	{
+		name: "!memo action in failed branch",
+		grammar: `
+			A <- (("e" { n, _ := parser.data.(int); parser.data = n+1; return "" }!memo) "x" / "e" "y") {
+				n, _ := parser.data.(int)
+				return int(n)
+			}`,
+		cases: []actionTestCase{
+			{"ex", 1.0},
+			{"ey", 0.0},
+		},
+	},
+	{
+		name: "!memo action in failed repetition",
+		grammar: `
+			A <- (E "x")* E "y" {
+				n, _ := parser.data.(int)
+				return int(n)
+			}
+			E <- "e" { n, _ := parser.data.(int); parser.data = n+1; return "" }!memo`,
+		cases: []actionTestCase{
+			{"ey", 1.0},
+			{"exey", 2.0},
+			{"exexey", 3.0},
+		},
+	},
+	{
+		name: "!memo action beneath a predicate",
+		grammar: `
+			A <- &E !(E "x") "e" {
+				n, _ := parser.data.(int)
+				return int(n)
+			}
+			E <- "e" { n, _ := parser.data.(int); parser.data = n+1; return "" }!memo`,
+		cases: []actionTestCase{
+			{"e", 0.0},
+		},
+	},
+	{
+		name: "rule parameters",
+		grammar: `
+			A <- Expr(0)
+			Expr @param(depth int) <- &{ depth < 2 } "(" e:Expr(depth+1) ")" { return int(e) } / "x" { return int(depth) }`,
+		cases: []actionTestCase{
+			{"x", 0.0},
+			{"(x)", 1.0},
+			{"((x))", 2.0},
+		},
+	},
 	{
 		name: "start and end",
 		grammar: `
@@ -283,7 +433,7 @@ func TestActionGen(t *testing.T) {
 		test := test
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
-			source := generateTest(actionPrelude, test.grammar)
+			source := generateTest(Config{Prefix: "_"}, actionPrelude, test.grammar)
 			binary := build(source)
 			defer rm(binary)
 			go rm(source)
@@ -0,0 +1,151 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+// astActions gives the rule a generated AST struct type, if it qualifies,
+// wrapping each branch of the rule's expression in an Action
+// that returns a pointer to the struct with its fields
+// set to the values of the branch's labels.
+//
+// A rule qualifies if it has labels, no actions, and no declared result type.
+// The struct type is named <prefix><rule ident>AST,
+// and has Begin and End fields with the byte offsets of the match,
+// and a field for each label in scope at the end of a branch.
+func (r *Rule) astActions(prefix string) {
+	if r.AST != nil || r.ResultType != nil || hasAction(r.Expr) {
+		return
+	}
+	branches := astBranches(&r.Expr)
+	seen := make(map[string]bool)
+	var fields []*LabelExpr
+	for _, b := range branches {
+		for _, l := range scopeLabels(*b) {
+			if n := l.Label.String(); !seen[n] {
+				seen[n] = true
+				fields = append(fields, l)
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+	r.AST = fields
+	typ := prefix + r.Name.Ident() + "AST"
+	for _, b := range branches {
+		code := "return &" + typ + "{Begin: start, End: end"
+		for _, l := range scopeLabels(*b) {
+			n := l.Label.String()
+			code += ", " + n + ": " + n
+		}
+		code += "}"
+		end := (*b).End()
+		*b = &Action{
+			Expr:       *b,
+			Code:       text{str: code, begin: end, end: end},
+			ReturnType: "*" + typ,
+		}
+	}
+}
+
+// checkAST returns an error for any field of the rule's AST struct
+// that conflicts with the position fields
+// or that is the name of labels of different types.
+func (r *Rule) checkAST(errs *Errors) {
+	if r.AST == nil {
+		return
+	}
+	types := make(map[string]string)
+	for _, l := range r.AST {
+		n := l.Label.String()
+		if n == "Begin" || n == "End" {
+			errs.add(l.Label, "label %s conflicts with the AST %s field", n, n)
+		}
+		types[n] = l.Type()
+	}
+	for _, b := range astBranches(&r.Expr) {
+		for _, l := range scopeLabels(*b) {
+			n := l.Label.String()
+			if got, want := l.Type(), types[n]; got != want {
+				errs.add(l, "type mismatch: got %s, expected %s", got, want)
+			}
+		}
+	}
+}
+
+// astBranches returns pointers to the expressions
+// whose value is the result of *e,
+// following the branches of Choices and LongestChoices
+// and the contents of SubExprs.
+func astBranches(e *Expr) []*Expr {
+	var branches []*Expr
+	var walk func(*Expr)
+	walk = func(p *Expr) {
+		switch e := (*p).(type) {
+		case *Action:
+			walk(&e.Expr)
+		case *Choice:
+			for i := range e.Exprs {
+				walk(&e.Exprs[i])
+			}
+		case *LongestChoice:
+			for i := range e.Exprs {
+				walk(&e.Exprs[i])
+			}
+		case *SubExpr:
+			walk(&e.Expr)
+		default:
+			branches = append(branches, p)
+		}
+	}
+	walk(e)
+	return branches
+}
+
+// scopeLabels returns the labels of e that are in scope after e,
+// in the order that they appear.
+// Labels within a Choice are only in scope within its branch.
+func scopeLabels(e Expr) []*LabelExpr {
+	switch e := e.(type) {
+	case *Action:
+		return scopeLabels(e.Expr)
+	case *Sequence:
+		var labels []*LabelExpr
+		for _, sub := range e.Exprs {
+			labels = append(labels, scopeLabels(sub)...)
+		}
+		return labels
+	case *LabelExpr:
+		return append([]*LabelExpr{e}, scopeLabels(e.Expr)...)
+	case *WantExpr:
+		return scopeLabels(e.Expr)
+	case *PredExpr:
+		return scopeLabels(e.Expr)
+	case *CaptureExpr:
+		return scopeLabels(e.Expr)
+	case *DiffExpr:
+		return append(scopeLabels(e.Expr), scopeLabels(e.Sub)...)
+	case *RepExpr:
+		return scopeLabels(e.Expr)
+	case *OptExpr:
+		return scopeLabels(e.Expr)
+	case *SubExpr:
+		return scopeLabels(e.Expr)
+	}
+	return nil
+}
+
+// hasAction returns whether e contains an Action.
+func hasAction(e Expr) bool {
+	var found bool
+	e.Walk(func(e Expr) bool {
+		if _, ok := e.(*Action); ok {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
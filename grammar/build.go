@@ -0,0 +1,369 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"unicode"
+)
+
+// The New functions construct a Grammar programmatically,
+// for tools that synthesize grammars,
+// instead of printing Peggy source and parsing it.
+// They validate their arguments,
+// and parenthesize subexpressions in SubExprs as needed,
+// so built Exprs have the structure of parsed ones.
+// A built Grammar is then checked and generated
+// like a parsed one, with Check and Config.Generate.
+//
+// Built Texts and Exprs are located at BuiltLoc.
+
+// BuiltLoc is the location of Texts and Exprs
+// constructed by the New functions.
+var BuiltLoc = Loc{File: "<built>"}
+
+// NewText returns a Text of the string, located at BuiltLoc.
+func NewText(s string) Text {
+	return text{str: s, begin: BuiltLoc, end: BuiltLoc}
+}
+
+// NewGrammar returns a Grammar of the rules.
+// The prelude, if non-empty, is Go source code
+// that begins the generated file, including its package clause.
+func NewGrammar(prelude string, rules ...Rule) (*Grammar, error) {
+	g := &Grammar{Rules: rules}
+	if prelude != "" {
+		if err := ParseGoFile(BuiltLoc, prelude); err != nil {
+			return nil, err
+		}
+		g.Prelude = NewText(prelude)
+	}
+	return g, nil
+}
+
+// NewRule returns a Rule with the name and expression.
+func NewRule(name string, expr Expr) (Rule, error) {
+	if err := checkIdent("rule name", name); err != nil {
+		return Rule{}, err
+	}
+	if expr == nil {
+		return Rule{}, errors.New("rule " + name + " has no expression")
+	}
+	return Rule{Name: Name{Name: NewText(name)}, Expr: expr}, nil
+}
+
+// NewChoice returns an ordered choice of the expressions,
+// or the expression itself if there is only one.
+func NewChoice(exprs ...Expr) (Expr, error) {
+	if err := checkExprs("choice", exprs); err != nil {
+		return nil, err
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return &Choice{Exprs: parenAll(exprs, precAction)}, nil
+}
+
+// NewLongestChoice returns a longest-match choice of the expressions,
+// or the expression itself if there is only one.
+func NewLongestChoice(exprs ...Expr) (Expr, error) {
+	if err := checkExprs("longest choice", exprs); err != nil {
+		return nil, err
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return &LongestChoice{Exprs: parenAll(exprs, precAction)}, nil
+}
+
+// NewAction returns an action expression
+// running the Go function body code when the expression matches.
+// The code must return a value of an inferable type.
+func NewAction(expr Expr, code string) (*Action, error) {
+	if expr == nil {
+		return nil, errors.New("action has no expression")
+	}
+	typ, err := parseGoBody(BuiltLoc, code)
+	if err != nil {
+		return nil, err
+	}
+	return &Action{Expr: paren(expr, precDiff), Code: NewText(code), ReturnType: typ}, nil
+}
+
+// NewDiffExpr returns an expression matching expr,
+// but not if sub matches the same text.
+func NewDiffExpr(expr, sub Expr) (*DiffExpr, error) {
+	if expr == nil || sub == nil {
+		return nil, errors.New("difference is missing an expression")
+	}
+	return &DiffExpr{Expr: paren(expr, precDiff), Sub: paren(sub, precSeq), Loc: BuiltLoc}, nil
+}
+
+// NewSequence returns a sequence of the expressions,
+// or the expression itself if there is only one.
+func NewSequence(exprs ...Expr) (Expr, error) {
+	if err := checkExprs("sequence", exprs); err != nil {
+		return nil, err
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return &Sequence{Exprs: parenAll(exprs, precWant)}, nil
+}
+
+// NewLabelExpr returns the expression labeled for use by actions and code predicates.
+func NewLabelExpr(label string, expr Expr) (*LabelExpr, error) {
+	if err := checkIdent("label", label); err != nil {
+		return nil, err
+	}
+	if expr == nil {
+		return nil, errors.New("label " + label + " has no expression")
+	}
+	return &LabelExpr{Label: NewText(label), Expr: paren(expr, precPred)}, nil
+}
+
+// NewWantExpr returns the expression with a description, !>,
+// wanted by the Fail tree in place of the fails beneath it.
+func NewWantExpr(want string, expr Expr) (*WantExpr, error) {
+	if want == "" {
+		return nil, errors.New("!> description must not be empty")
+	}
+	if expr == nil {
+		return nil, errors.New("!> " + strconv.Quote(want) + " has no expression")
+	}
+	return &WantExpr{Expr: paren(expr, precLabel), Want: NewText(want), Loc: BuiltLoc}, nil
+}
+
+// NewPredExpr returns a predicate, & or, if neg, !, of the expression.
+func NewPredExpr(neg bool, expr Expr) (*PredExpr, error) {
+	if expr == nil {
+		return nil, errors.New("predicate has no expression")
+	}
+	return &PredExpr{Neg: neg, Expr: paren(expr, precPred), Loc: BuiltLoc}, nil
+}
+
+// NewCaptureExpr returns a capture, $, of the expression.
+func NewCaptureExpr(expr Expr) (*CaptureExpr, error) {
+	if expr == nil {
+		return nil, errors.New("capture has no expression")
+	}
+	return &CaptureExpr{Expr: paren(expr, precPred), Loc: BuiltLoc}, nil
+}
+
+// NewRepExpr returns a repetition of the expression
+// with the operator op: *, +, or ?.
+func NewRepExpr(op rune, expr Expr) (Expr, error) {
+	if expr == nil {
+		return nil, fmt.Errorf("repetition %c has no expression", op)
+	}
+	expr = paren(expr, precRep)
+	switch op {
+	case '*', '+':
+		return &RepExpr{Op: op, Expr: expr, Loc: BuiltLoc}, nil
+	case '?':
+		return &OptExpr{Expr: expr, Loc: BuiltLoc}, nil
+	default:
+		return nil, fmt.Errorf("bad repetition operator %q: want *, +, or ?", op)
+	}
+}
+
+// NewIdent returns an identifier referring to the named rule.
+func NewIdent(name string) (*Ident, error) {
+	if err := checkIdent("rule name", name); err != nil {
+		return nil, err
+	}
+	return &Ident{Name: Name{Name: NewText(name)}}, nil
+}
+
+// NewPredCode returns a code predicate, & or, if neg, !,
+// of the Go boolean expression code.
+func NewPredCode(neg bool, code string) (*PredCode, error) {
+	if err := parseGoExpr(BuiltLoc, code); err != nil {
+		return nil, err
+	}
+	return &PredCode{Code: NewText(code), Neg: neg, Loc: BuiltLoc}, nil
+}
+
+// NewDelegateExpr returns an expression delegating the region
+// delimited by the open and close markers to the parser fun,
+// a Go expression of type func(string) (T, error).
+func NewDelegateExpr(fun, open, close string) (*DelegateExpr, error) {
+	args := fun + ", " + strconv.Quote(open) + ", " + strconv.Quote(close)
+	fun, open, close, err := parseDelegateArgs(BuiltLoc, args)
+	if err != nil {
+		return nil, err
+	}
+	return &DelegateExpr{
+		Func:  fun,
+		Open:  open,
+		Close: close,
+		Args:  NewText(args),
+		Loc:   BuiltLoc,
+	}, nil
+}
+
+// NewNativeExpr returns an expression matching with the Go function fun,
+// a Go expression of type func(string, int) (int, *peg.Fail).
+func NewNativeExpr(fun string) (*NativeExpr, error) {
+	if n, err := parseGoArgs(BuiltLoc, fun); err != nil {
+		return nil, err
+	} else if n != 1 {
+		return nil, Err(BuiltLoc, "%%native wants a function")
+	}
+	return &NativeExpr{Func: fun, Args: NewText(fun), Loc: BuiltLoc}, nil
+}
+
+// NewBalancedExpr returns an expression matching a block
+// delimited by the balanced open and close delimiters,
+// skipping any escape, if it is non-empty, and the rune following it.
+func NewBalancedExpr(open, close, escape string) (*BlockExpr, error) {
+	return newBlockExpr("balanced", open, close, escape)
+}
+
+// NewUntilExpr returns an expression matching the text
+// up to and including the first close delimiter,
+// skipping any escape, if it is non-empty, and the rune following it.
+func NewUntilExpr(close, escape string) (*BlockExpr, error) {
+	return newBlockExpr("until", "", close, escape)
+}
+
+func newBlockExpr(kind, open, close, escape string) (*BlockExpr, error) {
+	args := strconv.Quote(close)
+	if kind == "balanced" {
+		args = strconv.Quote(open) + ", " + args
+	}
+	if escape != "" {
+		args += ", " + strconv.Quote(escape)
+	}
+	open, close, escape, err := parseBlockArgs(BuiltLoc, kind, args)
+	if err != nil {
+		return nil, err
+	}
+	return &BlockExpr{
+		Kind:   kind,
+		Open:   open,
+		Close:  close,
+		Escape: escape,
+		Args:   NewText(args),
+		Loc:    BuiltLoc,
+	}, nil
+}
+
+// NewLiteral returns a literal matching the string.
+func NewLiteral(s string) *Literal {
+	return &Literal{Text: NewText(s)}
+}
+
+// NewCharClass returns a character class
+// matching a rune in one of the spans, or, if neg, in none of them.
+// Each span is the first and last rune of a range.
+func NewCharClass(neg bool, spans ...[2]rune) (*CharClass, error) {
+	if len(spans) == 0 {
+		return nil, errors.New("character class has no spans")
+	}
+	for _, sp := range spans {
+		if sp[0] > sp[1] {
+			return nil, fmt.Errorf("bad character class span %q-%q", sp[0], sp[1])
+		}
+	}
+	return &CharClass{
+		Spans: append([][2]rune{}, spans...),
+		Neg:   neg,
+		Open:  BuiltLoc,
+		Close: BuiltLoc,
+	}, nil
+}
+
+// NewAny returns an expression matching any rune.
+func NewAny() *Any { return &Any{Loc: BuiltLoc} }
+
+// NewCut returns a cut, committing the choice branch containing it.
+func NewCut() *Cut { return &Cut{Loc: BuiltLoc} }
+
+// NewEmpty returns an expression matching the empty string.
+func NewEmpty() *Empty { return &Empty{Open: BuiltLoc, Close: BuiltLoc} }
+
+// The precedence of each kind of expression, lowest first.
+// An operand of lower precedence than an operator's
+// is parenthesized in a SubExpr.
+const (
+	precChoice = iota
+	precAction
+	precDiff
+	precSeq
+	precWant
+	precLabel
+	precPred
+	precRep
+	precOperand
+)
+
+func precedence(e Expr) int {
+	switch e.(type) {
+	case *Choice, *LongestChoice:
+		return precChoice
+	case *Action:
+		return precAction
+	case *DiffExpr:
+		return precDiff
+	case *Sequence:
+		return precSeq
+	case *WantExpr:
+		return precWant
+	case *LabelExpr:
+		return precLabel
+	case *PredExpr, *CaptureExpr:
+		return precPred
+	case *RepExpr, *OptExpr:
+		return precRep
+	default:
+		return precOperand
+	}
+}
+
+// paren returns the expression,
+// parenthesized if its precedence is lower than prec.
+func paren(e Expr, prec int) Expr {
+	if precedence(e) >= prec {
+		return e
+	}
+	return &SubExpr{Expr: e, Open: BuiltLoc, Close: BuiltLoc}
+}
+
+func parenAll(exprs []Expr, prec int) []Expr {
+	ps := make([]Expr, len(exprs))
+	for i, e := range exprs {
+		ps[i] = paren(e, prec)
+	}
+	return ps
+}
+
+func checkExprs(what string, exprs []Expr) error {
+	if len(exprs) == 0 {
+		return errors.New(what + " has no expressions")
+	}
+	for _, e := range exprs {
+		if e == nil {
+			return errors.New(what + " has a nil expression")
+		}
+	}
+	return nil
+}
+
+func checkIdent(what, s string) error {
+	for i, r := range s {
+		if !isIdentRune(r) || i == 0 && !unicode.IsLetter(r) && r != '_' {
+			return fmt.Errorf("bad %s %q", what, s)
+		}
+	}
+	if s == "" {
+		return errors.New("empty " + what)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// must panics if err is non-nil, and otherwise returns e.
+func must(e Expr, err error) Expr {
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+func TestBuild(t *testing.T) {
+	num := must(NewRepExpr('+', must(NewCharClass(false, [2]rune{'0', '9'}))))
+	list := must(NewSequence(
+		NewLiteral("["),
+		must(NewRepExpr('?', must(NewSequence(
+			must(NewIdent("Elem")),
+			must(NewRepExpr('*', must(NewSequence(NewLiteral(","), must(NewIdent("Elem")))))),
+		)))),
+		NewLiteral("]"),
+	))
+	elem := must(NewChoice(
+		must(NewAction(must(NewLabelExpr("n", num)), "return string(n)")),
+		must(NewAction(must(NewIdent("List")), "return \"list\"")),
+	))
+	word := must(NewDiffExpr(
+		must(NewRepExpr('+', must(NewCharClass(false, [2]rune{'a', 'z'})))),
+		must(NewChoice(NewLiteral("if"), NewLiteral("else"))),
+	))
+	top := must(NewSequence(
+		must(NewChoice(must(NewIdent("List")), must(NewIdent("Word")))),
+		must(NewPredExpr(true, NewAny())),
+	))
+	var rules []Rule
+	for _, r := range []struct {
+		name string
+		expr Expr
+	}{
+		{"Top", top},
+		{"List", list},
+		{"Elem", elem},
+		{"Word", word},
+	} {
+		rule, err := NewRule(r.name, r.expr)
+		if err != nil {
+			t.Fatalf("NewRule(%q, _)=_, %v, want _, nil", r.name, err)
+		}
+		rules = append(rules, rule)
+	}
+	g, err := NewGrammar("package main\n", rules...)
+	if err != nil {
+		t.Fatalf("NewGrammar(_)=_, %v, want _, nil", err)
+	}
+
+	var src strings.Builder
+	for i := range g.Rules {
+		src.WriteString(g.Rules[i].String() + "\n")
+	}
+	const want = `Top <- (List/Word) !.
+List <- "[" (Elem ("," Elem)*)? "]"
+Elem <- n:[0-9]+ {…}/List {…}
+Word <- [a-z]+ - ("if"/"else")
+`
+	if src.String() != want {
+		t.Errorf("built rules:\n%s\nwant\n%s", src.String(), want)
+	}
+	// String elides action code, so only rules without actions parse.
+	for i := range g.Rules {
+		r := g.Rules[i].String()
+		if strings.Contains(r, "…") {
+			continue
+		}
+		parsed, err := Parse(strings.NewReader(r), "built.peggy")
+		if err != nil {
+			t.Errorf("Parse(%q)=_, %v, want _, nil", r, err)
+			continue
+		}
+		if got := parsed.Rules[0].String(); got != r {
+			t.Errorf("Parse(%q)=%q", r, got)
+		}
+	}
+
+	if err := Check(g); err != nil {
+		t.Fatalf("Check(built)=%v, want nil", err)
+	}
+	if err := Generate(ioutil.Discard, "built.peggy", g); err != nil {
+		t.Fatalf("Generate(built)=%v, want nil", err)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "bad rule name", err: func() error { _, err := NewRule("1x", NewAny()); return err }()},
+		{name: "nil rule expr", err: func() error { _, err := NewRule("A", nil); return err }()},
+		{name: "bad ident", err: func() error { _, err := NewIdent("a-b"); return err }()},
+		{name: "empty label", err: func() error { _, err := NewLabelExpr("", NewAny()); return err }()},
+		{name: "empty choice", err: func() error { _, err := NewChoice(); return err }()},
+		{name: "nil in sequence", err: func() error { _, err := NewSequence(NewAny(), nil); return err }()},
+		{name: "bad rep op", err: func() error { _, err := NewRepExpr('!', NewAny()); return err }()},
+		{name: "bad span", err: func() error { _, err := NewCharClass(false, [2]rune{'z', 'a'}); return err }()},
+		{name: "bad action", err: func() error { _, err := NewAction(NewAny(), "return"); return err }()},
+		{name: "bad pred code", err: func() error { _, err := NewPredCode(false, "x ==="); return err }()},
+		{name: "bad delegate", err: func() error { _, err := NewDelegateExpr("f(", "<", ">"); return err }()},
+		{name: "empty delegate marker", err: func() error { _, err := NewDelegateExpr("f", "", ">"); return err }()},
+		{name: "bad native", err: func() error { _, err := NewNativeExpr("f("); return err }()},
+		{name: "empty native", err: func() error { _, err := NewNativeExpr(""); return err }()},
+		{name: "empty balanced delimiter", err: func() error { _, err := NewBalancedExpr("", ")", ""); return err }()},
+		{name: "empty until delimiter", err: func() error { _, err := NewUntilExpr("", `\\`); return err }()},
+		{name: "bad prelude", err: func() error { _, err := NewGrammar("func"); return err }()},
+	}
+	for _, test := range tests {
+		if test.err == nil {
+			t.Errorf("%s: got nil error", test.name)
+		}
+	}
+}
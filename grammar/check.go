@@ -0,0 +1,1050 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"sort"
+	"strings"
+)
+
+// Check does semantic analysis of the rules,
+// using a default Config:
+//
+//	Config{Prefix: "_"}
+func Check(grammar *Grammar) error {
+	return Config{Prefix: "_"}.Check(grammar)
+}
+
+// Check does semantic analysis of the rules with the Config's options,
+// setting bookkeeping needed to later generate the parser,
+// returning any errors encountered in order of their begin location.
+func (c Config) Check(grammar *Grammar) error {
+	var errs Errors
+	rules := expandTemplates(grammar.Rules, &errs)
+	ruleMap := make(map[string]*Rule, len(rules))
+	for i, r := range rules {
+		r.N = i
+		name := r.Name.String()
+		if other := ruleMap[name]; other != nil {
+			errs.add(r, "rule %s redefined", name)
+		}
+		ruleMap[name] = r
+	}
+
+	for _, r := range rules {
+		if r.NoCase {
+			r.Expr = foldCase(r.Expr)
+		}
+	}
+
+	if c.AST {
+		for _, r := range rules {
+			r.astActions(c.Prefix)
+		}
+	}
+
+	if c.OptOK || c.Tuples {
+		// Rule types are computed by checkLeft,
+		// so the expressions are marked before it.
+		for _, r := range rules {
+			r.Expr.Walk(func(e Expr) bool {
+				switch e := e.(type) {
+				case *OptExpr:
+					e.OK = c.OptOK
+				case *Sequence:
+					e.Tuples = c.Tuples
+				}
+				return true
+			})
+		}
+	}
+
+	grammar.LeftRecursion = nil
+	p := path{cycles: &grammar.LeftRecursion}
+	for _, r := range rules {
+		r.checkLeft(ruleMap, p, &errs)
+	}
+	var warns *Errors
+	if c.WarnUnused {
+		warns = &Errors{}
+	}
+	for _, r := range rules {
+		check(r, ruleMap, c, &errs, warns)
+		r.checkAST(&errs)
+		if c.Sandbox {
+			checkSandbox(r, &errs)
+		}
+		if c.Strict {
+			checkStrict(r, &errs)
+		}
+	}
+	checkPrelude(grammar, rules, c.Prefix, &errs)
+	grammar.Warnings = nil
+	if warns != nil {
+		grammar.Warnings = uniqueWarnings(warns)
+	}
+	checkEffects(rules)
+	checkTextual(rules)
+	checkInfallible(grammar, rules)
+	if err := errs.ret(); err != nil {
+		return err
+	}
+	grammar.CheckedRules = rules
+	return nil
+}
+
+// foldCase returns the expression with its literals and character classes,
+// not including those of referenced rules, made case-insensitive:
+// each literal is rewritten to a sequence of literals and character classes,
+// and each character class has the other case of its letters added.
+func foldCase(expr Expr) Expr {
+	switch e := expr.(type) {
+	case *Choice:
+		for i, sub := range e.Exprs {
+			e.Exprs[i] = foldCase(sub)
+		}
+	case *LongestChoice:
+		for i, sub := range e.Exprs {
+			e.Exprs[i] = foldCase(sub)
+		}
+	case *Sequence:
+		for i, sub := range e.Exprs {
+			e.Exprs[i] = foldCase(sub)
+		}
+	case *Action:
+		e.Expr = foldCase(e.Expr)
+	case *LabelExpr:
+		e.Expr = foldCase(e.Expr)
+	case *WantExpr:
+		e.Expr = foldCase(e.Expr)
+	case *PredExpr:
+		e.Expr = foldCase(e.Expr)
+	case *CaptureExpr:
+		e.Expr = foldCase(e.Expr)
+	case *DiffExpr:
+		e.Expr = foldCase(e.Expr)
+		e.Sub = foldCase(e.Sub)
+	case *RepExpr:
+		e.Expr = foldCase(e.Expr)
+	case *OptExpr:
+		e.Expr = foldCase(e.Expr)
+	case *SubExpr:
+		e.Expr = foldCase(e.Expr)
+	case *Literal:
+		folded := foldLiteral(e.Text)
+		switch f := folded.(type) {
+		case *SubExpr:
+			f.Source = e.String()
+		case *CharClass:
+			f.Source = e.String()
+			f.Close = e.Text.End()
+		}
+		return folded
+	case *CharClass:
+		folded := *e
+		folded.Spans = append([][2]rune{}, e.Spans...)
+		foldCharClass(&folded)
+		return &folded
+	}
+	return expr
+}
+
+func expandTemplates(ruleDefs []Rule, errs *Errors) []*Rule {
+	var expanded, todo []*Rule
+	tmplNames := make(map[string]*Rule)
+	for i := range ruleDefs {
+		r := &ruleDefs[i]
+		if len(r.Name.Args) > 0 {
+			seenParams := make(map[string]bool)
+			for _, param := range r.Name.Args {
+				n := param.String()
+				if seenParams[n] {
+					errs.add(param, "parameter %s redefined", n)
+				}
+				seenParams[n] = true
+			}
+			tmplNames[r.Name.Name.String()] = r
+		} else {
+			expanded = append(expanded, r)
+			todo = append(todo, r)
+		}
+	}
+
+	seen := make(map[string]bool)
+	// coded are the templates whose %code is already
+	// in an expansion, since it must only be emitted once.
+	coded := make(map[*Rule]bool)
+	for i := 0; i < len(todo); i++ {
+		for _, invok := range invokedTemplates(todo[i]) {
+			if seen[invok.Name.String()] {
+				continue
+			}
+			seen[invok.Name.String()] = true
+			tmpl := tmplNames[invok.Name.Name.String()]
+			if tmpl == nil {
+				continue // undefined template, error reported elsewhere
+			}
+			exp := expand1(tmpl, invok, errs)
+			if exp == nil {
+				continue // error expanding, error reported elsewhere
+			}
+			if coded[tmpl] {
+				exp.Code = nil
+			}
+			coded[tmpl] = true
+			todo = append(todo, exp)
+			expanded = append(expanded, exp)
+		}
+	}
+	return expanded
+}
+
+func expand1(tmpl *Rule, invok *Ident, errs *Errors) *Rule {
+	if len(invok.Args) != len(tmpl.Args) {
+		var params []string
+		for _, p := range tmpl.Args {
+			params = append(params, p.String())
+		}
+		err := Err(invok, "template %s argument count mismatch: got %d, expected %d (%s)",
+			tmpl.Name, len(invok.Args), len(tmpl.Args), strings.Join(params, ", "))
+		err = err.note(tmpl.Name, "template %s defined here", tmpl.Name)
+		errs.Errs = append(errs.Errs, err)
+		return nil
+	}
+	copy := *tmpl
+	sub := make(map[string]string, len(tmpl.Args))
+	for i, arg := range invok.Args {
+		sub[tmpl.Args[i].String()] = arg.String()
+	}
+	copy.Args = invok.Args
+	copy.Expr = tmpl.Expr.substitute(sub)
+	return &copy
+}
+
+func invokedTemplates(r *Rule) []*Ident {
+	var tmpls []*Ident
+	r.Expr.Walk(func(e Expr) bool {
+		if id, ok := e.(*Ident); ok {
+			if len(id.Args) > 0 {
+				tmpls = append(tmpls, id)
+			}
+		}
+		return true
+	})
+	return tmpls
+}
+
+type path struct {
+	stack  []*Rule
+	seen   map[*Rule]bool
+	cycles *[][]*Rule
+}
+
+func (p *path) push(r *Rule) bool {
+	if p.seen == nil {
+		p.seen = make(map[*Rule]bool)
+	}
+	if p.seen[r] {
+		return false
+	}
+	p.stack = append(p.stack, r)
+	p.seen[r] = true
+	return true
+}
+
+func (p *path) pop() {
+	p.stack = p.stack[:len(p.stack)]
+}
+
+func (p *path) cycle(r *Rule) []*Rule {
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		if p.stack[i] == r {
+			return append(append([]*Rule{}, p.stack[i:]...), r)
+		}
+	}
+	panic("no cycle")
+}
+
+func cycleString(rules []*Rule) string {
+	var s string
+	for _, r := range rules {
+		if s != "" {
+			s += ", "
+		}
+		s += r.Name.String()
+	}
+	return s
+}
+
+func (r *Rule) checkLeft(rules map[string]*Rule, p path, errs *Errors) {
+	if r.typ != nil {
+		return
+	}
+	if !p.push(r) {
+		cycle := p.cycle(r)
+		errs.add(cycle[0], "left-recursion: %s", cycleString(cycle))
+		*p.cycles = append(*p.cycles, cycle)
+		for _, r := range cycle {
+			r.typ = new(string)
+		}
+		return
+	}
+	r.Expr.checkLeft(rules, p, errs)
+	t := r.Expr.Type()
+	if r.ResultType != nil {
+		t = r.ResultType.String()
+	}
+	r.typ = &t
+	r.epsilon = r.Expr.Epsilon()
+	p.pop()
+}
+
+func (e *Choice) checkLeft(rules map[string]*Rule, p path, errs *Errors) {
+	for _, sub := range e.Exprs {
+		sub.checkLeft(rules, p, errs)
+	}
+}
+
+func (e *LongestChoice) checkLeft(rules map[string]*Rule, p path, errs *Errors) {
+	for _, sub := range e.Exprs {
+		sub.checkLeft(rules, p, errs)
+	}
+}
+
+func (e *Action) checkLeft(rules map[string]*Rule, p path, errs *Errors) {
+	e.Expr.checkLeft(rules, p, errs)
+}
+
+func (e *Sequence) checkLeft(rules map[string]*Rule, p path, errs *Errors) {
+	for _, sub := range e.Exprs {
+		sub.checkLeft(rules, p, errs)
+		if !sub.Epsilon() {
+			break
+		}
+	}
+}
+
+func (e *LabelExpr) checkLeft(rules map[string]*Rule, p path, errs *Errors) {
+	e.Expr.checkLeft(rules, p, errs)
+}
+
+func (e *WantExpr) checkLeft(rules map[string]*Rule, p path, errs *Errors) {
+	e.Expr.checkLeft(rules, p, errs)
+}
+
+func (e *PredExpr) checkLeft(rules map[string]*Rule, p path, errs *Errors) {
+	e.Expr.checkLeft(rules, p, errs)
+}
+
+func (e *CaptureExpr) checkLeft(rules map[string]*Rule, p path, errs *Errors) {
+	e.Expr.checkLeft(rules, p, errs)
+}
+
+// The subtracted expression begins at the same position as the Expr,
+// so both are checked for left-recursion.
+func (e *DiffExpr) checkLeft(rules map[string]*Rule, p path, errs *Errors) {
+	e.Expr.checkLeft(rules, p, errs)
+	e.Sub.checkLeft(rules, p, errs)
+}
+
+func (e *RepExpr) checkLeft(rules map[string]*Rule, p path, errs *Errors) {
+	e.Expr.checkLeft(rules, p, errs)
+}
+
+func (e *OptExpr) checkLeft(rules map[string]*Rule, p path, errs *Errors) {
+	e.Expr.checkLeft(rules, p, errs)
+}
+
+func (e *Ident) checkLeft(rules map[string]*Rule, p path, errs *Errors) {
+	if e.rule = rules[e.Name.String()]; e.rule != nil {
+		e.rule.checkLeft(rules, p, errs)
+	}
+}
+
+func (e *SubExpr) checkLeft(rules map[string]*Rule, p path, errs *Errors) {
+	e.Expr.checkLeft(rules, p, errs)
+}
+
+func (e *PredCode) checkLeft(rules map[string]*Rule, p path, errs *Errors) {}
+
+func (e *DelegateExpr) checkLeft(rules map[string]*Rule, p path, errs *Errors) {}
+
+func (e *NativeExpr) checkLeft(rules map[string]*Rule, p path, errs *Errors) {}
+
+func (e *BlockExpr) checkLeft(rules map[string]*Rule, p path, errs *Errors) {}
+
+func (e *Literal) checkLeft(rules map[string]*Rule, p path, errs *Errors) {}
+
+func (e *CharClass) checkLeft(rules map[string]*Rule, p path, errs *Errors) {}
+
+func (e *Any) checkLeft(rules map[string]*Rule, p path, errs *Errors) {}
+
+func (e *Cut) checkLeft(rules map[string]*Rule, p path, errs *Errors) {}
+
+func (e *Empty) checkLeft(rules map[string]*Rule, p path, errs *Errors) {}
+
+type ctx struct {
+	rules     map[string]*Rule
+	allLabels *[]*LabelExpr
+	curLabels map[string]*LabelExpr
+
+	// declared are the Choices whose branches are checked
+	// against the rule's declared result type,
+	// instead of against the type of their first branch.
+	declared map[Expr]bool
+
+	// warns, if non-nil, collects warnings of unused values.
+	warns *Errors
+
+	// actions indicates to check the types of the values of actions.
+	actions bool
+
+	// pure indicates to reject !memo actions.
+	pure bool
+
+	// cutErr is the error of a cut in the current context,
+	// or the empty string if a cut commits a choice branch.
+	cutErr string
+}
+
+// reservedParams are identifiers defined by the generated rule functions,
+// which would be shadowed by a rule parameter of the same name.
+var reservedParams = map[string]bool{
+	"parser":  true,
+	"start":   true,
+	"end":     true,
+	"pos":     true,
+	"perr":    true,
+	"errPos":  true,
+	"node":    true,
+	"failure": true,
+	"key":     true,
+	"labels":  true,
+	"dp":      true,
+}
+
+func check(rule *Rule, rules map[string]*Rule, c Config, errs, warns *Errors) {
+	if rule.Params != nil {
+		loc := rule.Params.Begin()
+		loc.Col++ // skip the open (.
+		names, _ := parseGoParams(loc, rule.Params.String())
+		for _, n := range names {
+			if reservedParams[n] {
+				errs.add(rule.Params, "parameter %s is a reserved identifier", n)
+			}
+		}
+	}
+	if rule.Recovers() && rule.Params != nil {
+		errs.add(rule.Name, "rule %s has parameters, so it cannot recover", rule.Name)
+	}
+	if rule.ErrorMessage() {
+		switch verbs, ok := formatVerbs(rule.ErrorName.String()); {
+		case !ok:
+			errs.add(rule.ErrorName, "error message has a %% not beginning %%%%, %%s, %%q, or %%v")
+		case verbs > 1:
+			errs.add(rule.ErrorName, "error message has %d format verbs, want 1", verbs)
+		}
+	}
+	ctx := ctx{
+		rules:     rules,
+		allLabels: &rule.Labels,
+		curLabels: make(map[string]*LabelExpr),
+		declared:  make(map[Expr]bool),
+		warns:     warns,
+		actions:   !c.NoActions,
+		pure:      c.Pure,
+		cutErr:    "^ must be in a / choice branch",
+	}
+	var results []Expr
+	if rule.ResultType != nil {
+		results = resultExprs(rule.Expr, ctx.declared)
+		// The value of a delegate is whatever its parser returns,
+		// so one whose value is the result has the declared type.
+		for _, e := range results {
+			if d, ok := e.(*DelegateExpr); ok {
+				d.typ = rule.ResultType.String()
+			}
+		}
+	}
+	rule.Expr.check(ctx, true, errs)
+	if ctx.actions {
+		t := rule.ResultType
+		for _, e := range results {
+			if got := e.Type(); got != t.String() && got != "" {
+				errs.add(e, "type mismatch: got %s, expected %s", got, t)
+			}
+		}
+	}
+	sort.Slice(rule.Labels, func(i, j int) bool {
+		return rule.Labels[i].N < rule.Labels[j].N
+	})
+	if warns != nil && ctx.actions {
+		checkUnusedLabels(rule, warns)
+	}
+}
+
+// checkUnusedLabels warns of each label of the rule
+// not referenced by the code of any action or code predicate
+// in which it is in scope.
+func checkUnusedLabels(rule *Rule, warns *Errors) {
+	used := make(map[*LabelExpr]bool)
+	see := func(code Text, labels []*LabelExpr) {
+		idents := goIdents(code.String())
+		for _, l := range labels {
+			used[l] = used[l] || idents[l.Label.String()]
+		}
+	}
+	rule.Expr.Walk(func(e Expr) bool {
+		switch e := e.(type) {
+		case *Action:
+			see(e.Code, e.Labels)
+		case *PredCode:
+			see(e.Code, e.Labels)
+		}
+		return true
+	})
+	for _, l := range rule.Labels {
+		if !used[l] {
+			warns.add(l.Label, "label %s is unused", l.Label)
+		}
+	}
+}
+
+// sandboxReserved are the identifiers defined by the generated rule functions
+// that action and code predicate code may not refer to with -sandbox.
+// Code may refer to start and end, the labels in scope,
+// and the rule's parameters, which carry any state into the rule.
+var sandboxReserved = map[string]bool{
+	"parser":     true,
+	"pos":        true,
+	"perr":       true,
+	"errPos":     true,
+	"node":       true,
+	"failure":    true,
+	"key":        true,
+	"labels":     true,
+	"dp":         true,
+	"de":         true,
+	"deltaPos":   true,
+	"deltaErr":   true,
+	"outer":      true,
+	"depthFails": true,
+	"slow":       true,
+	"use":        true,
+}
+
+// sandboxNumbered are the stems of the numbered identifiers
+// defined by the generated rule functions, such as pos3 or label0.
+var sandboxNumbered = map[string]bool{
+	"begin": true,
+	"best":  true,
+	"end":   true,
+	"label": true,
+	"nkids": true,
+	"node":  true,
+	"ok":    true,
+	"perr":  true,
+	"pos":   true,
+	"start": true,
+	"win":   true,
+}
+
+// checkSandbox reports each action and code predicate of the rule
+// whose code refers to an identifier of the generated rule functions
+// other than a label in scope.
+func checkSandbox(rule *Rule, errs *Errors) {
+	see := func(kind string, code Text, body bool, labels []*LabelExpr) {
+		for _, id := range goFreeIdents(code.String(), body) {
+			reserved := sandboxReserved[id]
+			if stem := strings.TrimRight(id, "0123456789"); stem != id && sandboxNumbered[stem] {
+				reserved = true
+			}
+			if !reserved || isLabel(id, labels) {
+				continue
+			}
+			errs.add(code, "%s refers to %s, an identifier of the generated parser", kind, id)
+		}
+	}
+	rule.Expr.Walk(func(e Expr) bool {
+		switch e := e.(type) {
+		case *Action:
+			see("action", e.Code, true, e.Labels)
+		case *PredCode:
+			see("code predicate", e.Code, false, e.Labels)
+		}
+		return true
+	})
+}
+
+// checkStrict reports suspicious uses of expressions
+// that can match the empty string:
+// non-final branches of ordered choices that never fail,
+// making the branches after them unreachable,
+// predicates of them, ? of them, and repetitions of them,
+// which make no progress once they match the empty string,
+// so they repeat forever.
+func checkStrict(rule *Rule, errs *Errors) {
+	rule.Expr.Walk(func(e Expr) bool {
+		switch e := e.(type) {
+		case *Choice:
+			for i, b := range e.Exprs[:len(e.Exprs)-1] {
+				if b.Epsilon() && neverFails(b, nil) {
+					errs.add(b, "choice branch %d matches the empty string and never fails, so the branches after it are unreachable", i+1)
+				}
+			}
+		case *PredExpr:
+			if !e.Expr.Epsilon() {
+				break
+			}
+			op, result := "&", "succeeds"
+			if e.Neg {
+				op, result = "!", "fails"
+			}
+			if neverFails(e.Expr, nil) {
+				errs.add(e, "%s%s always %s, since %s matches the empty string and never fails", op, e.Expr, result, e.Expr)
+			} else {
+				errs.add(e, "%s%s tests only whether %s fails, since it can match the empty string", op, e.Expr, e.Expr)
+			}
+		case *OptExpr:
+			if !e.Expr.Epsilon() {
+				break
+			}
+			if neverFails(e.Expr, nil) {
+				errs.add(e, "%s? is redundant, since %s matches the empty string and never fails", e.Expr, e.Expr)
+			} else {
+				errs.add(e, "%s? only keeps %s from failing, since it can already match the empty string", e.Expr, e.Expr)
+			}
+		case *RepExpr:
+			if !e.Expr.Epsilon() {
+				break
+			}
+			if neverFails(e.Expr, nil) {
+				errs.add(e, "%s%c loops forever, since %s matches the empty string and never fails", e.Expr, e.Op, e.Expr)
+			} else {
+				errs.add(e, "%s%c can loop forever, since %s can match the empty string", e.Expr, e.Op, e.Expr)
+			}
+		}
+		return true
+	})
+}
+
+// neverFails returns whether the expression always matches.
+// Unlike CanFail, it follows identifiers to their rules,
+// assuming rules already being followed can fail.
+func neverFails(e Expr, following map[*Rule]bool) bool {
+	switch e := e.(type) {
+	case *Choice:
+		for _, sub := range e.Exprs {
+			if neverFails(sub, following) {
+				return true
+			}
+			if commits(sub) {
+				return false
+			}
+		}
+		return false
+	case *LongestChoice:
+		for _, sub := range e.Exprs {
+			if neverFails(sub, following) {
+				return true
+			}
+		}
+		return false
+	case *Sequence:
+		for _, sub := range e.Exprs {
+			if !neverFails(sub, following) {
+				return false
+			}
+		}
+		return true
+	case *Action:
+		return neverFails(e.Expr, following)
+	case *LabelExpr:
+		return neverFails(e.Expr, following)
+	case *WantExpr:
+		return neverFails(e.Expr, following)
+	case *CaptureExpr:
+		return neverFails(e.Expr, following)
+	case *SubExpr:
+		return neverFails(e.Expr, following)
+	case *PredExpr:
+		return !e.Neg && neverFails(e.Expr, following)
+	case *RepExpr:
+		return e.Op == '*' || neverFails(e.Expr, following)
+	case *OptExpr:
+		return true
+	case *Ident:
+		if e.rule == nil || following[e.rule] {
+			return false
+		}
+		if following == nil {
+			following = make(map[*Rule]bool)
+		}
+		following[e.rule] = true
+		defer delete(following, e.rule)
+		return neverFails(e.rule.Expr, following)
+	default:
+		return !e.CanFail()
+	}
+}
+
+func isLabel(id string, labels []*LabelExpr) bool {
+	for _, l := range labels {
+		if l.Label.String() == id {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueWarnings returns the warnings sorted by location,
+// without the duplicates from multiple expansions of a template.
+func uniqueWarnings(warns *Errors) []Error {
+	warns.ret()
+	var uniq []Error
+	seen := make(map[string]bool)
+	for _, w := range warns.Errs {
+		if !seen[w.Error()] {
+			seen[w.Error()] = true
+			uniq = append(uniq, w)
+		}
+	}
+	return uniq
+}
+
+// resultExprs returns the expressions whose value is the result of e,
+// following the branches of Choices and LongestChoices
+// and the contents of SubExprs,
+// and adding the followed choices to declared.
+func resultExprs(e Expr, declared map[Expr]bool) []Expr {
+	switch e := e.(type) {
+	case *Choice:
+		declared[e] = true
+		var results []Expr
+		for _, sub := range e.Exprs {
+			results = append(results, resultExprs(sub, declared)...)
+		}
+		return results
+	case *LongestChoice:
+		declared[e] = true
+		var results []Expr
+		for _, sub := range e.Exprs {
+			results = append(results, resultExprs(sub, declared)...)
+		}
+		return results
+	case *SubExpr:
+		return resultExprs(e.Expr, declared)
+	}
+	return []Expr{e}
+}
+
+func (e *Choice) check(ctx ctx, valueUsed bool, errs *Errors) {
+	ctx.cutErr = ""
+	checkChoice(e, e.Exprs, ctx, valueUsed, errs)
+}
+
+func (e *LongestChoice) check(ctx ctx, valueUsed bool, errs *Errors) {
+	ctx.cutErr = "^ must be in a / choice branch, not a | choice branch"
+	checkChoice(e, e.Exprs, ctx, valueUsed, errs)
+}
+
+// checkChoice checks the branches of a Choice or LongestChoice, e.
+// Labels defined in a branch are only in scope within the branch,
+// and all branches must have the same type.
+func checkChoice(e Expr, exprs []Expr, ctx ctx, valueUsed bool, errs *Errors) {
+	for _, sub := range exprs {
+		subCtx := ctx
+		subCtx.curLabels = make(map[string]*LabelExpr)
+		for n, l := range ctx.curLabels {
+			subCtx.curLabels[n] = l
+		}
+		sub.check(subCtx, valueUsed, errs)
+	}
+	if ctx.declared[e] {
+		return
+	}
+	t := exprs[0].Type()
+	for _, sub := range exprs {
+		if got := sub.Type(); ctx.actions && valueUsed && got != t && got != "" && t != "" {
+			errs.add(sub, "type mismatch: got %s, expected %s", got, t)
+		}
+	}
+}
+
+func (e *Action) check(ctx ctx, valueUsed bool, errs *Errors) {
+	e.Expr.check(ctx, false, errs)
+	// A !memo action may be run only for its effects.
+	if ctx.warns != nil && ctx.actions && !valueUsed && !e.NoMemo {
+		ctx.warns.add(e, "action value is discarded")
+	}
+	if e.NoMemo && ctx.pure {
+		errs.add(e, "!memo action not allowed with pure actions")
+	}
+	for _, l := range ctx.curLabels {
+		e.Labels = append(e.Labels, l)
+	}
+	sort.Slice(e.Labels, func(i, j int) bool {
+		return e.Labels[i].Label.String() < e.Labels[j].Label.String()
+	})
+}
+
+// BUG: figure out what to do about sequence types.
+func (e *Sequence) check(ctx ctx, valueUsed bool, errs *Errors) {
+	for _, sub := range e.Exprs {
+		sub.check(ctx, valueUsed, errs)
+	}
+	if e.IsTuple() {
+		return
+	}
+	t := e.Exprs[0].Type()
+	for _, sub := range e.Exprs {
+		if got := sub.Type(); ctx.actions && valueUsed && got != t && got != "" && t != "" {
+			errs.add(sub, "type mismatch: got %s, expected %s", got, t)
+		}
+	}
+}
+
+func (e *LabelExpr) check(ctx ctx, valueUsed bool, errs *Errors) {
+	e.Expr.check(ctx, true, errs)
+	if _, ok := ctx.curLabels[e.Label.String()]; ok {
+		errs.add(e.Label, "label %s redefined", e.Label.String())
+	}
+	if ctx.warns != nil && isPredicate(e.Expr) {
+		ctx.warns.add(e.Label, "label %s of a predicate is always empty", e.Label)
+	}
+	e.N = len(*ctx.allLabels)
+	*ctx.allLabels = append(*ctx.allLabels, e)
+	ctx.curLabels[e.Label.String()] = e
+}
+
+// isPredicate returns whether the expression,
+// ignoring parentheses, is a predicate or code predicate.
+// A predicate consumes no input,
+// so the value of its label is always the empty string.
+func isPredicate(e Expr) bool {
+	for {
+		switch s := e.(type) {
+		case *SubExpr:
+			e = s.Expr
+		case *PredExpr, *PredCode:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+func (e *WantExpr) check(ctx ctx, valueUsed bool, errs *Errors) {
+	e.Expr.check(ctx, valueUsed, errs)
+	if e.Want.String() == "" {
+		errs.add(e.Want, "!> description must not be empty")
+	}
+}
+
+func (e *PredExpr) check(ctx ctx, valueUsed bool, errs *Errors) {
+	ctx.cutErr = "^ must not be within a predicate"
+	e.Expr.check(ctx, false, errs)
+}
+
+func (e *CaptureExpr) check(ctx ctx, valueUsed bool, errs *Errors) {
+	e.Expr.check(ctx, false, errs)
+}
+
+func (e *DiffExpr) check(ctx ctx, valueUsed bool, errs *Errors) {
+	e.Expr.check(ctx, valueUsed, errs)
+	ctx.cutErr = "^ must not be within the right side of -"
+	e.Sub.check(ctx, false, errs)
+}
+
+func (e *RepExpr) check(ctx ctx, valueUsed bool, errs *Errors) {
+	ctx.cutErr = "^ must not be within a repetition"
+	e.Expr.check(ctx, valueUsed, errs)
+}
+
+func (e *OptExpr) check(ctx ctx, valueUsed bool, errs *Errors) {
+	ctx.cutErr = "^ must not be within an optional expression"
+	e.Expr.check(ctx, valueUsed, errs)
+}
+
+func (e *SubExpr) check(ctx ctx, valueUsed bool, errs *Errors) {
+	e.Expr.check(ctx, valueUsed, errs)
+}
+
+func (e *Ident) check(ctx ctx, _ bool, errs *Errors) {
+	r, ok := ctx.rules[e.Name.String()]
+	if !ok {
+		errs.add(e, "rule %s undefined", e.Name.String())
+		return
+	}
+	e.rule = r
+	switch {
+	case r.Params == nil && e.CallArgs != nil:
+		errs.add(e, "rule %s has no parameters", e.Name.String())
+	case r.Params != nil && e.CallArgs == nil:
+		errs.add(e, "rule %s requires arguments", e.Name.String())
+	case r.Params != nil:
+		loc := r.Params.Begin()
+		loc.Col++ // skip the open (.
+		params, _ := parseGoParams(loc, r.Params.String())
+		loc = e.CallArgs.Begin()
+		loc.Col++ // skip the open (.
+		n, _ := parseGoArgs(loc, e.CallArgs.String())
+		if n != len(params) {
+			errs.add(e, "rule %s argument count mismatch: got %d, expected %d",
+				e.Name.String(), n, len(params))
+		}
+	}
+}
+
+func (e *PredCode) check(ctx ctx, _ bool, _ *Errors) {
+	for _, l := range ctx.curLabels {
+		e.Labels = append(e.Labels, l)
+	}
+	sort.Slice(e.Labels, func(i, j int) bool {
+		return e.Labels[i].Label.String() < e.Labels[j].Label.String()
+	})
+}
+
+func (e *DelegateExpr) check(ctx, bool, *Errors) {}
+
+func (e *NativeExpr) check(ctx, bool, *Errors) {}
+
+func (e *BlockExpr) check(ctx, bool, *Errors) {}
+
+func (e *Literal) check(ctx, bool, *Errors) {}
+
+func (e *CharClass) check(ctx, bool, *Errors) {}
+
+func (e *Any) check(ctx, bool, *Errors) {}
+
+func (e *Cut) check(ctx ctx, _ bool, errs *Errors) {
+	if ctx.cutErr != "" {
+		errs.add(e, "%s", ctx.cutErr)
+	}
+}
+
+func (e *Empty) check(ctx, bool, *Errors) {}
+
+// checkEffects sets the effects field of each rule.
+// Effects are propagated through identifiers
+// until reaching a fixed point.
+func checkEffects(rules []*Rule) {
+	for changed := true; changed; {
+		changed = false
+		for _, r := range rules {
+			if !r.effects && hasEffects(r.Expr) {
+				r.effects = true
+				changed = true
+			}
+		}
+	}
+}
+
+// checkInfallible sets the infallible field of each rule.
+// Rules are assumed fallible until found otherwise,
+// which is propagated through identifiers
+// until reaching a fixed point,
+// so rules that never fail only if the others of their cycle never fail
+// are conservatively fallible.
+// Rules with a maximum depth fail when it is exceeded.
+func checkInfallible(grammar *Grammar, rules []*Rule) {
+	for _, r := range rules {
+		r.infallible = false
+	}
+	for changed := grammar.MaxDepth == 0; changed; {
+		changed = false
+		for _, r := range rules {
+			if !r.infallible && r.MaxDepth == 0 && !r.Expr.CanFail() {
+				r.infallible = true
+				changed = true
+			}
+		}
+	}
+}
+
+// checkTextual sets the textual field of each rule.
+// Rules are assumed textual until found otherwise,
+// which is propagated through identifiers
+// until reaching a fixed point.
+func checkTextual(rules []*Rule) {
+	for _, r := range rules {
+		r.textual = !r.Recovers()
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, r := range rules {
+			if r.textual && !isTextual(r.Expr) {
+				r.textual = false
+				changed = true
+			}
+		}
+	}
+}
+
+// isTextual returns whether the value of the expression in the action pass
+// is always the text that it matched,
+// so that it can be sliced from the input instead of concatenated.
+// It is only valid after the rule textual fields are set by checkTextual.
+func isTextual(expr Expr) bool {
+	switch e := expr.(type) {
+	case *Choice:
+		return allTextual(e.Exprs)
+	case *LongestChoice:
+		return allTextual(e.Exprs)
+	case *Sequence:
+		return allTextual(e.Exprs)
+	case *LabelExpr:
+		// The label's variable is set by the value of its expression,
+		// which is unused if the value is sliced from the input.
+		return isPredicate(e.Expr)
+	case *WantExpr:
+		return isTextual(e.Expr)
+	case *DiffExpr:
+		return isTextual(e.Expr)
+	case *RepExpr:
+		return isTextual(e.Expr)
+	case *OptExpr:
+		return isTextual(e.Expr)
+	case *SubExpr:
+		return isTextual(e.Expr)
+	case *Ident:
+		return e.rule != nil && e.rule.textual
+	case *Action, *DelegateExpr:
+		return false
+	default:
+		// Predicates and cuts match the empty string,
+		// and their value is the empty string.
+		// The value of the remaining expressions is the text they matched.
+		return true
+	}
+}
+
+func allTextual(exprs []Expr) bool {
+	for _, e := range exprs {
+		if !isTextual(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasEffects returns whether the expression contains a !memo action,
+// either directly or through a referenced rule.
+// It is only valid after the rule effects are set by checkEffects.
+func hasEffects(expr Expr) bool {
+	var effects bool
+	expr.Walk(func(e Expr) bool {
+		switch e := e.(type) {
+		case *Action:
+			effects = effects || e.NoMemo
+		case *Ident:
+			effects = effects || (e.rule != nil && e.rule.effects)
+		}
+		return !effects
+	})
+	return effects
+}
@@ -0,0 +1,998 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+type checkTest struct {
+	name        string
+	in          string
+	err         string
+	skipActions bool
+	// cfg is the Config of Check, or nil for the default.
+	cfg *Config
+}
+
+func (test checkTest) Run(t *testing.T) {
+	in := strings.NewReader(test.in)
+	g, err := Parse(in, "test.file")
+	if err != nil {
+		t.Errorf("Parse(%q, _)=_, %v, want _,nil", test.in, err)
+		return
+	}
+	if test.cfg != nil {
+		err = test.cfg.Check(g)
+	} else {
+		err = Check(g)
+	}
+	if test.err == "" {
+		if err != nil {
+			t.Errorf("Check(%q)=%v, want nil", test.in, err)
+		}
+		return
+	}
+	re := regexp.MustCompile(test.err)
+	if err == nil || !re.MatchString(err.Error()) {
+		var e string
+		if err != nil {
+			e = err.Error()
+		}
+		t.Errorf("Check(%q)=%v, but expected to match %q",
+			test.in, e, test.err)
+		return
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []checkTest{
+		{
+			name: "empty OK",
+			in:   "",
+			err:  "",
+		},
+		{
+			name: "various OK",
+			in: `A <- (G/B C)*
+B <- &{pred}*
+C <- !{pred}* { return string(act) }
+D <- .* !B
+E <- C*
+F <- "cde"*
+G <- [fgh]*`,
+			err: "",
+		},
+		{
+			name: "redefined rule",
+			in:   "A <- [x]\nA <- [y]",
+			err:  "^test.file:2.1,2.9: rule A redefined",
+		},
+		{
+			name: "undefined rule",
+			in:   "A <- B",
+			err:  "^test.file:1.6,1.7: rule B undefined",
+		},
+		{
+			name: "undefined rule after CRLF",
+			in:   "A <- B\r\nB <- C",
+			err:  "^test.file:2.6,2.7: rule C undefined",
+		},
+		{
+			name: "undefined rule after CR",
+			in:   "A <- B\rB <- C",
+			err:  "^test.file:2.6,2.7: rule C undefined",
+		},
+		{
+			name: "redefined label",
+			in:   "A <- a:[a] a:[a]",
+			err:  "^test.file:1.12,1.13: label a redefined",
+		},
+		{
+			name: "non-redefined label with same name in different branch",
+			in:   "A <- a:[a] / (a:[a] / a:[a]) / a:[a]",
+			err:  "",
+		},
+		{
+			name: "redefined label in same choice branch",
+			in:   "A <- a:[a] / a:[a] a:[a]",
+			err:  "^test.file:1.20,1.21: label a redefined",
+		},
+		{
+			name: "choice first error",
+			in:   "A <- Undefined / A",
+			err:  ".+",
+		},
+		{
+			name: "choice second error",
+			in:   "A <- B / Undefined\nB <- [x]",
+			err:  ".+",
+		},
+		{
+			name: "seq first error",
+			in:   "A <- Undefined A",
+			err:  ".+",
+		},
+		{
+			name: "sequence second error",
+			in:   "A <- B Undefined\nB <- [x]",
+			err:  ".+",
+		},
+		{
+			name: "template parameter OK",
+			in: `A<x> <- x
+				B <- A<C>
+				C <- "c"`,
+			err: "",
+		},
+		{
+			name: "template parameter redef",
+			in: `A<x, x> <- x
+				B <- A<C, C>
+				C <- "c"`,
+			err: "^test.file:1.6,1.7: parameter x redefined$",
+		},
+		{
+			name: "literal template parameter redef",
+			in: `A<"x", 'x'> <- "x"
+				B <- A<"a", "b">`,
+			err: `^test.file:1.8,1.11: parameter "x" redefined$`,
+		},
+		{
+			name: "literal template arguments OK",
+			in: `A<"x", y> <- "x" y
+				B <- A<"a", "b"> A<C, "b">
+				C <- "c"`,
+			err: "",
+		},
+		{
+			name: "template arg count mismatch",
+			in: `A<x> <- x
+				B <- A<C, C>
+				C <- "c"`,
+			err: "^test.file:2.10,2.16: template A<x> argument count mismatch: got 2, expected 1 \\(x\\)\n" +
+				"\ttest.file:1.1,1.4: template A<x> defined here\n",
+		},
+		{
+			name: "multi-parameter template arg count mismatch",
+			in: `A<x, y> <- x y
+				B <- A<C>
+				C <- "c"`,
+			err: "^test.file:2.10,2.13: template A<x, y> argument count mismatch: got 1, expected 2 \\(x, y\\)\n" +
+				"\ttest.file:1.1,1.7: template A<x, y> defined here\n",
+		},
+		{
+			name: "multiple errors",
+			in:   "A <- U1 U2\nA <- u:[x] u:[x]",
+			err: "test.file:1.6,1.8: rule U1 undefined\n" +
+				"test.file:1.9,1.11: rule U2 undefined\n" +
+				"test.file:2.1,2.17: rule A redefined\n" +
+				"test.file:2.12,2.13: label u redefined",
+		},
+		{
+			name: "right recursion is OK",
+			in: `A <- "b" B
+				B <- A`,
+		},
+		{
+			name: "direct left-recursion",
+			in:   `A <- A`,
+			err:  "^test.file:1.1,1.7: left-recursion: A, A$",
+		},
+		{
+			name: "indirect left-recursion",
+			in: `A <- C0
+				C0 <- C1
+				C1 <- C2
+				C2 <- C0`,
+			err: "^test.file:2.5,2.13: left-recursion: C0, C1, C2, C0$",
+		},
+		{
+			name: "choice left-recursion",
+			in: `A <- B / C / D
+				B <- "b"
+				C <- "c"
+				D <- A`,
+			err: "^test.file:1.1,1.15: left-recursion: A, D, A$",
+		},
+		{
+			name: "longest choice left-recursion",
+			in:   `A <- "a" | A`,
+			err:  "^test.file:1.1,1.13: left-recursion: A, A$",
+		},
+		{
+			name: "diff left-recursion",
+			in:   `A <- "a" - A`,
+			err:  "^test.file:1.1,1.13: left-recursion: A, A$",
+		},
+		{
+			name: "sequence left-recursion",
+			in: `A <- !B C D E
+				B <- "b"
+				C <- !"c"
+				D <- C # non-consuming through C
+				E <- A`,
+			err: "^test.file:1.1,1.14: left-recursion: A, E, A$",
+		},
+		{
+			name: "various expr left-recursion",
+			in: `Choice <- "a" / Sequence
+				Sequence <- SubExpr "b"
+				SubExpr <- ( PredExpr )
+				PredExpr <- &RepExpr
+				RepExpr <- OptExpr+
+				OptExpr <- Action?
+				Action <- Choice { return "" }`,
+			err: "^test.file:1.1,1.25: left-recursion: Choice, Sequence, SubExpr, PredExpr, RepExpr, OptExpr, Action, Choice$",
+		},
+		{
+			name: "templates calling templates",
+			in: `A <- B<X>
+				B<X> <- C<X>
+				C<X> <- "a" D<X> C<X>?
+				D<X> <- X
+				X <- "x"`,
+			err: "", // this should work fine.
+		},
+		{
+			name: "template left-recursion",
+			in: `A <- C0
+				C0 <- C1
+				C1 <- C2<C0>
+				C2<X> <- X`,
+			err: "^test.file:2.5,2.13: left-recursion: C0, C1, C2<C0>, C0$",
+		},
+		{
+			name: "multiple left-recursion errors",
+			in: `A <- A
+				B <- C
+				C <- B`,
+			err: "^test.file:1.1,1.7: left-recursion: A, A\n" +
+				"test.file:2.5,2.11: left-recursion: B, C, B$",
+		},
+		{
+			name: "right-recursion is OK",
+			in: `A <- B C A?
+				B <- "b" B / C
+				C <- "c"`,
+			err: "",
+		},
+
+		{
+			name: "choice type mismatch",
+			in:   `A <- "a" / "b" { return 5 }`,
+			err:  "^test.file:1.12,1.28: type mismatch: got int, expected string",
+		},
+		{
+			name: "sequence type mismatch",
+			in:   `A <- "a" ( "b" { return 5 } )`,
+			err:  "^test.file:1.10,1.29: type mismatch: got int, expected string",
+		},
+		{
+			name: "unused choice, no mismatch",
+			in:   `A <- ( "a" / "b" { return 5 } ) { return 6 }`,
+			err:  "",
+		},
+		{
+			name: "unused sequence, no mismatch",
+			in:   `A <- "a" ( "b" { return 5 } ) { return 6 }`,
+			err:  "",
+		},
+		{
+			name: "&-pred subexpression is unused",
+			in:   `A <- "a" !( "b" { return 5 } )`,
+			err:  "",
+		},
+		{
+			name: "!-pred subexpression is unused",
+			in:   `A <- "a" !( "b" { return 5 } )`,
+			err:  "",
+		},
+		{
+			name: "rule parameters OK",
+			in: `A <- B(1, "x")
+				B @param(n int, s string) <- C(n+1)
+				C @param(n int) <- "c"`,
+			err: "",
+		},
+		{
+			name: "missing arguments",
+			in: `A <- B
+				B @param(n int) <- "b"`,
+			err: "^test.file:1.6,1.7: rule B requires arguments",
+		},
+		{
+			name: "argument count mismatch",
+			in: `A <- B(1, 2)
+				B @param(n int) <- "b"`,
+			err: "^test.file:1.6,1.13: rule B argument count mismatch: got 2, expected 1",
+		},
+		{
+			name: "reserved parameter",
+			in:   `A @param(pos int) <- "a"`,
+			err:  "^test.file:1.9,1.18: parameter pos is a reserved identifier",
+		},
+		{
+			name: "cut in a choice branch",
+			in:   `A <- "a" ^ "b" / "c" ("d" / "e" ^ "f")* / $("g" ^ "h") / "i"`,
+		},
+		{
+			name: "cut not in a choice branch",
+			in:   `A <- "a" ^ "b"`,
+			err:  "^test.file:1.10,1.11: \\^ must be in a / choice branch$",
+		},
+		{
+			name: "cut in a repetition",
+			in:   `A <- ("a" ^ "b")* / "c"`,
+			err:  "^test.file:1.11,1.12: \\^ must not be within a repetition$",
+		},
+		{
+			name: "cut in an optional expression",
+			in:   `A <- ("a" ^ "b")? / "c"`,
+			err:  "^test.file:1.11,1.12: \\^ must not be within an optional expression$",
+		},
+		{
+			name: "cut in a predicate",
+			in:   `A <- !("a" ^ "b") "c" / "d"`,
+			err:  "^test.file:1.12,1.13: \\^ must not be within a predicate$",
+		},
+		{
+			name: "cut in the right side of -",
+			in:   `A <- [a-z]+ - ("a" ^ "b") / "d"`,
+			err:  "^test.file:1.20,1.21: \\^ must not be within the right side of -$",
+		},
+		{
+			name: "cut in a longest choice branch",
+			in:   `A <- "a" ^ "b" | "c"`,
+			err:  "^test.file:1.10,1.11: \\^ must be in a / choice branch, not a | choice branch$",
+		},
+		{
+			name: "recovering rule with parameters",
+			in:   `A @param(x int) @recoverpast(";") <- "a" ";"`,
+			err:  "^test.file:1.1,1.2: rule A has parameters, so it cannot recover",
+		},
+		{
+			name: "multiple type errors",
+			in: `A <- B ( "c" { return 0 } )
+				B <- "b" / ( "c" { return 0 } )`,
+			err: "^test.file:1.8,1.27: type mismatch: got int, expected string\n" +
+				"test.file:2.16,2.35: type mismatch: got int, expected string$",
+		},
+		{
+			name: "declared result type OK",
+			in: `A -> int <- "a" { return 1 } / ( "b" { return 2 } / B )
+				B -> int <- "c" { return 3 }`,
+			err: "",
+		},
+		{
+			name: "declared result type of actions",
+			in:   `A -> float64 <- "a" { return 1 } / "b" { return x }`,
+			err:  "",
+		},
+		{
+			name: "declared result type mismatch",
+			in:   `A -> int <- "a" / "b" { return 2 }`,
+			err:  "^test.file:1.13,1.16: type mismatch: got string, expected int$",
+		},
+		{
+			name: "declared result type mismatch in subexpression",
+			in:   `A -> int <- "a" { return 1 } / ( "b" { return 2 } / "c" )`,
+			err:  "^test.file:1.53,1.56: type mismatch: got string, expected int$",
+		},
+		{
+			name: "declared result type mismatch without choice",
+			in:   `A -> []string <- "a"`,
+			err:  "^test.file:1.18,1.21: type mismatch: got string, expected \\[\\]string$",
+		},
+		{
+			name: "declared result type of referenced rule",
+			in: `A <- B { return 1 } / B
+				B -> float64 <- "b" { return 1.0 }`,
+			err: "^test.file:1.23,1.24: type mismatch: got float64, expected int$",
+		},
+		{
+			name: "empty !> description",
+			in:   `A <- "a" !> ""`,
+			err:  `^test.file:1.13,1.15: !> description must not be empty$`,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			test.Run(t)
+		})
+	}
+}
+
+// TestCheckCallWithoutParameters tests calling a rule without parameters,
+// which cannot be parsed, since a ( following its name
+// begins a subexpression, but can be built.
+func TestCheckCallWithoutParameters(t *testing.T) {
+	call, err := NewIdent("B")
+	if err != nil {
+		t.Fatalf("NewIdent(\"B\")=_, %v, want _,nil", err)
+	}
+	call.CallArgs = NewText("1")
+	a, err := NewRule("A", call)
+	if err != nil {
+		t.Fatalf("NewRule(\"A\", _)=_, %v, want _,nil", err)
+	}
+	b, err := NewRule("B", NewLiteral("b"))
+	if err != nil {
+		t.Fatalf("NewRule(\"B\", _)=_, %v, want _,nil", err)
+	}
+	g, err := NewGrammar("", a, b)
+	if err != nil {
+		t.Fatalf("NewGrammar(\"\", A, B)=_, %v, want _,nil", err)
+	}
+	const want = "rule B has no parameters"
+	if err := Check(g); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Check(%q)=%v, want error containing %q", String(g.Rules), err, want)
+	}
+}
+
+func TestCheckErrorMessage(t *testing.T) {
+	tests := []checkTest{
+		{
+			name: "error name with a % OK",
+			in:   `A "100%" <- "%"`,
+		},
+		{
+			name: "error message OK",
+			in:   `A "want 100%% after %q" <- "%"`,
+		},
+		{
+			name: "bad %",
+			in:   `A "50% after %q" <- "%"`,
+			err:  `^test.file:1.3,1.17: error message has a % not beginning %%, %s, %q, or %v$`,
+		},
+		{
+			name: "multiple verbs",
+			in:   `A "%s after %q" <- "%"`,
+			err:  `^test.file:1.3,1.16: error message has 2 format verbs, want 1$`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, test.Run)
+	}
+}
+
+func TestCheckPrelude(t *testing.T) {
+	tests := []checkTest{
+		{
+			name: "prelude and code OK",
+			in: `{
+package main
+
+import (
+	"fmt"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func init() {}
+func init() {}
+
+type T int
+
+func (T) String() string { return fmt.Sprint(yaml.Marshal) }
+func main() {}
+}
+%code { type U int; func (U) String() string { return "" } }
+A <- "a"`,
+		},
+		{
+			name: "duplicate main",
+			in: `{
+package main
+
+func main() {}
+
+func main() {}
+}
+A <- "a"`,
+			err: "^test.file:6.6,6.10: main redeclared\n" +
+				"\ttest.file:4.6,4.10: main previously declared here$",
+		},
+		{
+			name: "duplicate method",
+			in: `{
+package main
+type T int
+func (T) M() {}
+func (*T) M() {}
+}
+A <- "a"`,
+			err: "^test.file:5.11,5.12: T.M redeclared",
+		},
+		{
+			name: "duplicate import",
+			in: `{
+package main
+import "fmt"
+import "example.com/fmt/v2"
+}
+A <- "a"`,
+			err: "^test.file:4.8,4.28: fmt redeclared",
+		},
+		{
+			name: "code redeclares prelude",
+			in: `{
+package main
+var x int
+}
+%code { var x string }
+A <- "a"`,
+			err: "^test.file:5.13,5.14: x redeclared\n" +
+				"\ttest.file:3.5,3.6: x previously declared here$",
+		},
+		{
+			name: "declared by the generated parser",
+			in: `{
+package main
+func use(interface{}) {}
+type _Parser struct{}
+}
+A <- "a"`,
+			err: "^test.file:3.6,3.9: use redeclared; it is declared by the generated parser\n" +
+				"test.file:4.6,4.13: _Parser redeclared; it is declared by the generated parser$",
+		},
+		{
+			name: "declared by the generated parser for a rule",
+			in: `{
+package main
+func _AAccepts() {}
+}
+A <- "a"`,
+			err: "^test.file:3.6,3.15: _AAccepts redeclared; it is declared by the generated parser\n" +
+				"\ttest.file:5.1,5.2: for rule A$",
+		},
+		{
+			name: "code declared by the generated parser for a template",
+			in: `A <- B<C>
+%code { func _B__CNode() {} }
+B<x> <- x
+C <- "c"`,
+			err: "^test.file:2.14,2.23: _B__CNode redeclared; it is declared by the generated parser\n" +
+				"\ttest.file:3.1,3.2: for rule B<C>$",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, test.Run)
+	}
+
+	// The parser rejects a prelude without a package clause,
+	// but a decoded or built Grammar may have one.
+	g, err := Parse(strings.NewReader("{\npackage main\n}\nA <- \"a\""), "test.file")
+	if err != nil {
+		t.Fatalf("Parse(...)=_, %v", err)
+	}
+	g.Prelude = text{str: "\nfunc main() {}\n", begin: Loc{File: "test.file", Line: 1, Col: 1}}
+	want := "test.file:2.1: prelude has no package clause"
+	if err := Check(g); err == nil || err.Error() != want {
+		t.Errorf("Check(prelude without a package clause)=%v, want %s", err, want)
+	}
+}
+
+func TestNoActions(t *testing.T) {
+	tests := []checkTest{
+		{
+			name: "choice type mismatch: no error",
+			in:   `A <- "a" / "b" { return 5 }`,
+		},
+		{
+			name: "sequence type mismatch: no error",
+			in:   `A <- "a" ( "b" { return 5 } )`,
+		},
+	}
+	for _, test := range tests {
+		test.cfg = &Config{Prefix: "_", NoActions: true}
+		t.Run(test.name, test.Run)
+	}
+}
+
+func TestTuples(t *testing.T) {
+	tests := []checkTest{
+		{
+			name: "sequence of different types is a tuple",
+			in:   `A <- "a" ( "b" { return 5 } )`,
+		},
+		{
+			name: "choice type mismatch is still an error",
+			in:   `A <- "a" / "b" { return 5 }`,
+			err:  "^test.file:1.12,1.28: type mismatch: got int, expected string",
+		},
+		{
+			name: "choice of tuple types",
+			in: `A <- "a" ( "b" { return 5 } ) / "c" B
+				B <- "d" { return 6 }`,
+		},
+	}
+	for _, test := range tests {
+		test.cfg = &Config{Prefix: "_", Tuples: true}
+		t.Run(test.name, test.Run)
+	}
+}
+
+func TestPureActions(t *testing.T) {
+	tests := []checkTest{
+		{
+			name: "memoized action OK",
+			in:   `A <- "a" { return 5 }`,
+		},
+		{
+			name: "!memo action",
+			in:   `A <- "a" { return 5 }!memo`,
+			err:  "^test.file:1.6,1.22: !memo action not allowed with pure actions",
+		},
+	}
+	for _, test := range tests {
+		test.cfg = &Config{Prefix: "_", Pure: true}
+		t.Run(test.name, test.Run)
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	tests := []checkTest{
+		{
+			name: "labels, start, and end OK",
+			in:   `A <- x:"a" &{ x != "" } { return string(x + string(rune(end-start))) }`,
+		},
+		{
+			name: "parameters OK",
+			in:   `A @param(depth int) <- &{ depth < 10 } "a" { return int(depth) }`,
+		},
+		{
+			name: "label shadowing a reserved identifier OK",
+			in:   `A <- pos:"a" { return string(pos) }`,
+		},
+		{
+			name: "locals and fields OK",
+			in:   `A <- "a" { pos := start; s := struct{ parser int }{parser: pos}; return int(s.parser) }`,
+		},
+		{
+			name: "action refers to parser",
+			in:   `A <- "a" { return len(parser.text) }`,
+			err:  "^test.file:1.10,1.37: action refers to parser, an identifier of the generated parser",
+		},
+		{
+			name: "action assigns pos",
+			in:   `A <- "a" { pos = 0; return 1 }`,
+			err:  "action refers to pos, an identifier of the generated parser",
+		},
+		{
+			name: "action refers to numbered identifier",
+			in:   `A <- "a" { return string(label0) }`,
+			err:  "action refers to label0, an identifier of the generated parser",
+		},
+		{
+			name: "code predicate refers to labels",
+			in:   `A <- x:"a" &{ labels[0] != "" }`,
+			err:  "code predicate refers to labels, an identifier of the generated parser",
+		},
+	}
+	for _, test := range tests {
+		test.cfg = &Config{Prefix: "_", Sandbox: true}
+		t.Run(test.name, test.Run)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	tests := []checkTest{
+		{
+			name: "epsilon final branch OK",
+			in:   `A <- "a" / "b"*`,
+		},
+		{
+			name: "epsilon branch that can fail OK",
+			in:   `A <- !"a" / "b"`,
+		},
+		{
+			name: "longest choice OK",
+			in:   `A <- "a"* | "b"`,
+		},
+		{
+			name: "predicates and ? of non-epsilon OK",
+			in:   `A <- &"a" !"b" "c"? "d"+?`,
+		},
+		{
+			name: "unreachable branches",
+			in:   `A <- "a" / "b"* / "c"`,
+			err:  `^test.file:1.12,1.15: choice branch 2 matches the empty string and never fails, so the branches after it are unreachable$`,
+		},
+		{
+			name: "unreachable branches through rules",
+			in: `A <- B / "c"
+				B <- C "x"?
+				C <- "y"?`,
+			err: `^test.file:1.6,1.7: choice branch 1 matches the empty string and never fails`,
+		},
+		{
+			name: "and predicate always succeeds",
+			in:   `A <- &"a"* "b"`,
+			err:  `^test.file:1.6,1.10: &"a"\* always succeeds, since "a"\* matches the empty string and never fails$`,
+		},
+		{
+			name: "not predicate always fails",
+			in:   `A <- !("a"?) "b"`,
+			err:  `!\("a"\?\) always fails, since \("a"\?\) matches the empty string and never fails`,
+		},
+		{
+			name: "predicate of a predicate",
+			in:   `A <- &!"a" .`,
+			err:  `^test.file:1.6,1.11: &!"a" tests only whether !"a" fails, since it can match the empty string$`,
+		},
+		{
+			name: "redundant ?",
+			in:   `A <- "a"*? "b"`,
+			err:  `^test.file:1.6,1.10: "a"\*\? is redundant, since "a"\* matches the empty string and never fails$`,
+		},
+		{
+			name: "empty branch",
+			in:   `A <- () / "x"`,
+			err:  `^test.file:1.6,1.8: choice branch 1 matches the empty string and never fails, so the branches after it are unreachable$`,
+		},
+		{
+			name: "repetition of an expression that never fails",
+			in:   `A <- ("a" / ())*`,
+			err:  `^test.file:1.6,1.16: \("a"/\(\)\)\* loops forever, since \("a"/\(\)\) matches the empty string and never fails$`,
+		},
+		{
+			name: "repetition of an expression that can fail",
+			in:   `A <- (&"a")+`,
+			err:  `^test.file:1.6,1.12: \(&"a"\)\+ can loop forever, since \(&"a"\) can match the empty string$`,
+		},
+		{
+			name: "? of an expression that can fail",
+			in:   `A <- (!"a")? "b"`,
+			err:  `\(!"a"\)\? only keeps \(!"a"\) from failing, since it can already match the empty string`,
+		},
+	}
+	for _, test := range tests {
+		test.cfg = &Config{Prefix: "_", Strict: true}
+		t.Run(test.name, test.Run)
+	}
+}
+
+func TestCheckUnused(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{
+			name: "used labels",
+			in:   `A <- x:"a" &{ x != "" } y:"b" { return string(x + y) }`,
+		},
+		{
+			name: "unused label",
+			in:   `A <- x:"a" y:"b" { return string(y) }`,
+			want: []string{"test.file:1.6,1.7: label x is unused"},
+		},
+		{
+			name: "label without action",
+			in:   `A <- x:"a"`,
+			want: []string{"test.file:1.6,1.7: label x is unused"},
+		},
+		{
+			name: "label out of scope of action",
+			in:   `A <- x:"a" / "b" { return string(x) }`,
+			want: []string{"test.file:1.6,1.7: label x is unused"},
+		},
+		{
+			name: "label only in a comment",
+			in:   `A <- x:"a" { return "" /* x */ }`,
+			want: []string{"test.file:1.6,1.7: label x is unused"},
+		},
+		{
+			name: "label used by code predicate",
+			in:   `A <- x:"a" !{ x == "b" }`,
+		},
+		{
+			name: "labeled predicates",
+			in:   `A <- x:&"a" y:(!{ true }) "a" { return string(x + y) }`,
+			want: []string{
+				"test.file:1.6,1.7: label x of a predicate is always empty",
+				"test.file:1.13,1.14: label y of a predicate is always empty",
+			},
+		},
+		{
+			name: "action under predicate",
+			in:   `A <- &("a" { return 5 }) "a"`,
+			want: []string{"test.file:1.8,1.24: action value is discarded"},
+		},
+		{
+			name: "action under capture",
+			in:   `A <- $("a" { return 5 })`,
+			want: []string{"test.file:1.8,1.24: action value is discarded"},
+		},
+		{
+			name: "action under action",
+			in:   `A <- ("a" { return 5 }) { return 6 }`,
+			want: []string{"test.file:1.7,1.23: action value is discarded"},
+		},
+		{
+			name: "labeled action under action",
+			in:   `A <- x:("a" { return 5 }) { return int(x) }`,
+		},
+		{
+			name: "!memo action under action",
+			in:   `A <- ("a" { return 5 }!memo) { return 6 }`,
+		},
+		{
+			name: "template expanded twice",
+			in:   "A <- B<C> B<D>\nB<X> <- x:X\nC <- \"c\"\nD <- \"d\"",
+			want: []string{"test.file:2.9,2.10: label x is unused"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g, err := Parse(strings.NewReader(test.in), "test.file")
+			if err != nil {
+				t.Fatalf("Parse(%q, _)=_, %v, want _,nil", test.in, err)
+			}
+			if err := (Config{Prefix: "_", WarnUnused: true}).Check(g); err != nil {
+				t.Fatalf("Check(%q)=%v, want nil", test.in, err)
+			}
+			var got []string
+			for _, w := range g.Warnings {
+				got = append(got, w.Error())
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Check(%q) warnings=%q, want %q", test.in, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckAST(t *testing.T) {
+	tests := []checkTest{
+		{
+			name: "labels of the same type",
+			in:   `A <- x:"a" y:"b" / x:"c"`,
+		},
+		{
+			name: "labels of different branches",
+			in: `A <- x:"a" / y:B
+				B <- "b"`,
+		},
+		{
+			name: "rule with an action: no AST",
+			in: `A <- x:B { return string(x.y) }
+				B <- y:"b"`,
+		},
+		{
+			name: "label type mismatch",
+			in: `A <- x:"a" / x:B
+				B <- "b" { return 5 }`,
+			err: "^test.file:1.14,1.17: type mismatch: got int, expected string$",
+		},
+		{
+			name: "Begin label",
+			in:   `A <- Begin:"a"`,
+			err:  "^test.file:1.6,1.11: label Begin conflicts with the AST Begin field$",
+		},
+		{
+			name: "End label",
+			in:   `A <- "a" / End:"b"`,
+			err:  "^test.file:1.12,1.15: label End conflicts with the AST End field$",
+		},
+	}
+	for _, test := range tests {
+		test.cfg = &Config{Prefix: "_", AST: true}
+		t.Run(test.name, test.Run)
+	}
+
+	const in = `A <- b:B c:C<B>? / d:D
+B <- ( x:"x" / y:"y" ) z:"z"
+C<X> <- x:X
+D <- "d" { return 5 }
+E <- "e"`
+	g, err := Parse(strings.NewReader(in), "test.file")
+	if err != nil {
+		t.Fatalf("Parse(%q)=_, %v, want _,nil", in, err)
+	}
+	if err := (Config{Prefix: "_", AST: true}).Check(g); err != nil {
+		t.Fatalf("Check(%q)=%v, want nil", in, err)
+	}
+	for i, test := range []struct {
+		name   string
+		typ    string
+		fields []string
+	}{
+		{name: "A", typ: "*_AAST", fields: []string{"b", "c", "d"}},
+		{name: "B", typ: "*_BAST", fields: []string{"z"}},
+		{name: "D", typ: "int"},
+		{name: "E", typ: "string"},
+		{name: "C<B>", typ: "*_C__BAST", fields: []string{"x"}},
+	} {
+		r := g.CheckedRules[i]
+		if r.Name.String() != test.name {
+			t.Fatalf("CheckedRules[%d].Name=%s, want %s", i, r.Name, test.name)
+		}
+		if r.Type() != test.typ {
+			t.Errorf("%s.Type()=%s, want %s", test.name, r.Type(), test.typ)
+		}
+		var fields []string
+		for _, l := range r.AST {
+			fields = append(fields, l.Label.String())
+		}
+		if !reflect.DeepEqual(fields, test.fields) {
+			t.Errorf("%s.AST=%v, want %v", test.name, fields, test.fields)
+		}
+	}
+}
+
+func TestCheckAnalysis(t *testing.T) {
+	const in = `A <- B C { return 5 }
+B <- "b"?
+C <- D / "c"
+D <- "d" ( E F )*
+E <- "e"
+F <- C`
+	g, err := Parse(strings.NewReader(in), "test.file")
+	if err != nil {
+		t.Fatalf("Parse(%q)=_, %v, want _,nil", in, err)
+	}
+	if err := Check(g); err != nil {
+		t.Fatalf("Check(%q)=%v, want nil", in, err)
+	}
+	tests := []struct {
+		name    string
+		typ     string
+		epsilon bool
+	}{
+		{name: "A", typ: "int", epsilon: false},
+		{name: "B", typ: "string", epsilon: true},
+		{name: "C", typ: "string", epsilon: false},
+		{name: "D", typ: "string", epsilon: false},
+	}
+	for i, test := range tests {
+		r := g.CheckedRules[i]
+		if r.Name.String() != test.name {
+			t.Fatalf("CheckedRules[%d].Name=%s, want %s", i, r.Name, test.name)
+		}
+		if r.Type() != test.typ {
+			t.Errorf("%s.Type()=%s, want %s", test.name, r.Type(), test.typ)
+		}
+		if r.Epsilon() != test.epsilon {
+			t.Errorf("%s.Epsilon()=%v, want %v", test.name, r.Epsilon(), test.epsilon)
+		}
+	}
+	id := g.CheckedRules[0].Expr.(*Action).Expr.(*Sequence).Exprs[0].(*Ident)
+	if id.Rule() != g.CheckedRules[1] {
+		t.Errorf("A's B.Rule()=%v, want %v", id.Rule(), g.CheckedRules[1])
+	}
+	if len(g.LeftRecursion) != 0 {
+		t.Errorf("LeftRecursion=%v, want none", g.LeftRecursion)
+	}
+}
+
+func TestCheckLeftRecursionCycles(t *testing.T) {
+	const in = `A <- B
+B <- C / "b"
+C <- B
+D <- D`
+	g, err := Parse(strings.NewReader(in), "test.file")
+	if err != nil {
+		t.Fatalf("Parse(%q)=_, %v, want _,nil", in, err)
+	}
+	if err := Check(g); err == nil {
+		t.Fatalf("Check(%q)=nil, want left-recursion errors", in)
+	}
+	var got []string
+	for _, cycle := range g.LeftRecursion {
+		got = append(got, cycleString(cycle))
+	}
+	want := []string{"B, C, B", "D, D"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LeftRecursion=%q, want %q", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+// A coverPoint is a rule or choice branch
+// instrumented by the -cover flag.
+type coverPoint struct {
+	Loc    string
+	Rule   string
+	Branch int
+}
+
+func coverLoc(l Located) string {
+	b, e := l.Begin(), l.End()
+	return fmt.Sprintf("%s:%d.%d,%d.%d", b.File, b.Line, b.Col, e.Line, e.Col)
+}
+
+// coverPoints returns the coverage points of the grammar:
+// first each rule, indexed by rule constant,
+// then each choice branch, in order of the rules.
+// It also returns a map from each branch to its point index.
+func coverPoints(gr *Grammar) ([]coverPoint, map[Expr]int) {
+	var points []coverPoint
+	for _, r := range gr.CheckedRules {
+		points = append(points, coverPoint{Loc: coverLoc(r), Rule: r.Name.String()})
+	}
+	index := make(map[Expr]int)
+	for _, r := range gr.CheckedRules {
+		r.Expr.Walk(func(e Expr) bool {
+			if c, ok := e.(*Choice); ok {
+				for i, sub := range c.Exprs {
+					index[sub] = len(points)
+					points = append(points, coverPoint{
+						Loc:    coverLoc(sub),
+						Rule:   r.Name.String(),
+						Branch: i + 1,
+					})
+				}
+			}
+			return true
+		})
+	}
+	return points, index
+}
+
+// CoverReport merges the coverage profiles in the files
+// and writes a line for each rule and choice branch that never accepted,
+// followed by a summary of the rules and branches covered.
+func CoverReport(w io.Writer, files []string) error {
+	var points []peg.CoverPoint
+	counts := make(map[peg.CoverPoint]uint64)
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		c, err := peg.ReadProfile(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		for i, p := range c.Points {
+			if _, ok := counts[p]; !ok {
+				points = append(points, p)
+			}
+			counts[p] += c.Counts[i]
+		}
+	}
+	var rules, rulesHit, branches, branchesHit int
+	for _, p := range points {
+		hit := counts[p] > 0
+		if p.Branch == 0 {
+			rules++
+			if hit {
+				rulesHit++
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "%s: rule %s not covered\n", p.Loc, p.Rule); err != nil {
+				return err
+			}
+			continue
+		}
+		branches++
+		if hit {
+			branchesHit++
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s: branch %d of rule %s not covered\n", p.Loc, p.Branch, p.Rule); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "rules: %d/%d (%s), branches: %d/%d (%s)\n",
+		rulesHit, rules, percent(rulesHit, rules),
+		branchesHit, branches, percent(branchesHit, branches))
+	return err
+}
+
+func percent(n, d int) string {
+	if d == 0 {
+		return "100.0%"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(d))
+}
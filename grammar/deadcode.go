@@ -0,0 +1,318 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+)
+
+// removeDeadCode removes the unreachable statements
+// of the generated functions of the file:
+// the statements following a terminating statement, such as a return or goto,
+// up to the next label that is the target of a remaining branch.
+// Labels left without a branch to them are removed,
+// keeping their statements only if they are reachable otherwise.
+// Variables whose uses were all removed are assigned to _,
+// since Go rejects variables that are declared and never used.
+// The comments within removed statements are removed too,
+// and their lines are merged into the preceding lines,
+// so they are not printed as blank lines.
+//
+// Only the functions emitted by the templates are pruned,
+// not those of the prelude or %code blocks,
+// and function literals, such as those of actions, are left as is,
+// so the code of the grammar is never changed.
+func removeDeadCode(fset *token.FileSet, f *ast.File, generated map[*ast.FuncDecl]bool) {
+	var dead []span
+	for _, d := range f.Decls {
+		fun, ok := d.(*ast.FuncDecl)
+		if ok && fun.Body != nil && generated[fun] {
+			n := len(dead)
+			if dead = pruneFunc(fun.Body, dead); len(dead) > n {
+				useVars(fun.Body)
+			}
+		}
+	}
+	if len(dead) == 0 {
+		return
+	}
+	comments := f.Comments[:0]
+	for _, c := range f.Comments {
+		if !within(c, dead) {
+			comments = append(comments, c)
+		}
+	}
+	f.Comments = comments
+
+	// Merge the lines of later spans first,
+	// so the lines of earlier spans are unchanged.
+	sort.Slice(dead, func(i, j int) bool { return dead[i].pos > dead[j].pos })
+	file := fset.File(f.Pos())
+	for _, d := range dead {
+		first, last := file.Line(d.pos), file.Line(d.end)
+		if !d.stmt {
+			last-- // d.end begins the following statement.
+		}
+		for i := first; i <= last && first > 1; i++ {
+			file.MergeLine(first - 1)
+		}
+	}
+}
+
+// A span is the text of a removed statement or label.
+type span struct {
+	pos, end token.Pos
+	// stmt is whether the span is an entire statement;
+	// otherwise it is a label, and end begins its statement.
+	stmt bool
+}
+
+// pruneFunc removes the dead code of a function body,
+// appending the removed statements to dead,
+// until no more is removed,
+// since removing a branch can leave its label unused.
+func pruneFunc(body *ast.BlockStmt, dead []span) []span {
+	for {
+		p := pruner{targets: branchTargets(body), dead: dead}
+		body.List = p.prune(body.List)
+		dead = p.dead
+		if !p.changed {
+			return dead
+		}
+	}
+}
+
+// A pruner removes the dead code of a function body.
+type pruner struct {
+	// targets are the number of branches to each label.
+	targets map[string]int
+	// dead are the removed statements and labels.
+	dead []span
+	// changed is whether any statement or label was removed.
+	changed bool
+}
+
+// prune returns the list with its dead statements removed,
+// pruning the statement lists nested in the live statements.
+func (p *pruner) prune(list []ast.Stmt) []ast.Stmt {
+	var live []ast.Stmt
+	reachable := true
+	for _, s := range list {
+		if l, ok := s.(*ast.LabeledStmt); ok {
+			if p.targets[l.Label.Name] > 0 {
+				reachable = true
+			} else if reachable {
+				p.changed = true
+				s = l.Stmt
+				if e, ok := s.(*ast.EmptyStmt); ok && e.Implicit {
+					p.dead = append(p.dead, span{pos: l.Pos(), end: l.End(), stmt: true})
+					continue
+				}
+				p.dead = append(p.dead, span{pos: l.Pos(), end: s.Pos()})
+			}
+		}
+		if !reachable {
+			p.changed = true
+			p.dead = append(p.dead, span{pos: s.Pos(), end: s.End(), stmt: true})
+			continue
+		}
+		p.pruneNested(s)
+		live = append(live, s)
+		reachable = !terminates(s)
+	}
+	return live
+}
+
+// pruneNested prunes the statement lists nested in the statement,
+// but not those of function literals.
+func (p *pruner) pruneNested(s ast.Stmt) {
+	switch s := s.(type) {
+	case *ast.BlockStmt:
+		s.List = p.prune(s.List)
+	case *ast.LabeledStmt:
+		p.pruneNested(s.Stmt)
+	case *ast.IfStmt:
+		p.pruneNested(s.Body)
+		if s.Else != nil {
+			p.pruneNested(s.Else)
+		}
+	case *ast.ForStmt:
+		p.pruneNested(s.Body)
+	case *ast.RangeStmt:
+		p.pruneNested(s.Body)
+	case *ast.SwitchStmt:
+		p.pruneNested(s.Body)
+	case *ast.TypeSwitchStmt:
+		p.pruneNested(s.Body)
+	case *ast.SelectStmt:
+		p.pruneNested(s.Body)
+	case *ast.CaseClause:
+		s.Body = p.prune(s.Body)
+	case *ast.CommClause:
+		s.Body = p.prune(s.Body)
+	}
+}
+
+// useVars adds an assignment to _
+// following the declaration of each variable of the function body
+// that is not used, such as a rule's result
+// when the only statements using it are removed,
+// because it follows a predicate that always fails.
+// Assigning a variable does not use it;
+// a variable declared in a function literal is left as is.
+func useVars(body *ast.BlockStmt) {
+	used := make(map[*ast.Object]bool)
+	var use func(ast.Node) bool
+	use = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if n.Tok != token.ASSIGN && n.Tok != token.DEFINE {
+				break
+			}
+			for _, l := range n.Lhs {
+				if _, ok := l.(*ast.Ident); !ok {
+					ast.Inspect(l, use)
+				}
+			}
+			for _, r := range n.Rhs {
+				ast.Inspect(r, use)
+			}
+			return false
+		case *ast.ValueSpec:
+			if n.Type != nil {
+				ast.Inspect(n.Type, use)
+			}
+			for _, v := range n.Values {
+				ast.Inspect(v, use)
+			}
+			return false
+		case *ast.Ident:
+			if n.Obj != nil {
+				used[n.Obj] = true
+			}
+		}
+		return true
+	}
+	ast.Inspect(body, use)
+
+	var stmts func([]ast.Stmt) []ast.Stmt
+	stmts = func(list []ast.Stmt) []ast.Stmt {
+		var out []ast.Stmt
+		for _, s := range list {
+			out = append(out, s)
+			for _, id := range declared(s) {
+				if id.Name != "_" && id.Obj != nil && !used[id.Obj] {
+					out = append(out, &ast.AssignStmt{
+						Lhs: []ast.Expr{ast.NewIdent("_")},
+						Tok: token.ASSIGN,
+						Rhs: []ast.Expr{ast.NewIdent(id.Name)},
+					})
+				}
+			}
+		}
+		return out
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.BlockStmt:
+			n.List = stmts(n.List)
+		case *ast.CaseClause:
+			n.Body = stmts(n.Body)
+		case *ast.CommClause:
+			n.Body = stmts(n.Body)
+		}
+		return true
+	})
+}
+
+// declared returns the identifiers of the variables
+// declared by the statement.
+func declared(s ast.Stmt) []*ast.Ident {
+	switch s := s.(type) {
+	case *ast.DeclStmt:
+		d, ok := s.Decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.VAR {
+			return nil
+		}
+		var ids []*ast.Ident
+		for _, spec := range d.Specs {
+			ids = append(ids, spec.(*ast.ValueSpec).Names...)
+		}
+		return ids
+	case *ast.AssignStmt:
+		if s.Tok != token.DEFINE {
+			return nil
+		}
+		var ids []*ast.Ident
+		for _, l := range s.Lhs {
+			if id, ok := l.(*ast.Ident); ok && id.Obj != nil && id.Obj.Decl == s {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+	return nil
+}
+
+// terminates returns whether control never continues
+// to the statement following s.
+// It is conservative, recognizing only returns, branches,
+// calls to panic, and blocks ending in one of them.
+func terminates(s ast.Stmt) bool {
+	switch s := s.(type) {
+	case *ast.ReturnStmt:
+		return true
+	case *ast.BranchStmt:
+		return s.Tok != token.FALLTHROUGH
+	case *ast.ExprStmt:
+		call, ok := s.X.(*ast.CallExpr)
+		if !ok {
+			return false
+		}
+		id, ok := call.Fun.(*ast.Ident)
+		return ok && id.Name == "panic" && id.Obj == nil
+	case *ast.BlockStmt:
+		return len(s.List) > 0 && terminates(s.List[len(s.List)-1])
+	case *ast.LabeledStmt:
+		return terminates(s.Stmt)
+	}
+	return false
+}
+
+// branchTargets returns the number of branch statements
+// to each label of the function body,
+// not including those of nested function literals,
+// which have labels of their own.
+func branchTargets(body *ast.BlockStmt) map[string]int {
+	targets := make(map[string]int)
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.BranchStmt:
+			if n.Label != nil {
+				targets[n.Label.Name]++
+			}
+		}
+		return true
+	})
+	return targets
+}
+
+// within returns whether the node is within any of the spans.
+func within(n ast.Node, spans []span) bool {
+	for _, s := range spans {
+		if s.pos <= n.Pos() && n.End() <= s.end {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,280 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRemoveDeadCode(t *testing.T) {
+	tests := []struct {
+		name string
+		// code is code of the grammar, preceding in,
+		// the generated code.
+		code string
+		in   string
+		want string
+	}{
+		{
+			name: "no dead code",
+			in: `func _A(pos int) int {
+	if pos < 0 {
+		goto fail
+	}
+	return pos
+fail:
+	return -1
+}`,
+			want: `func _A(pos int) int {
+	if pos < 0 {
+		goto fail
+	}
+	return pos
+fail:
+	return -1
+}`,
+		},
+		{
+			name: "unreferenced label after return",
+			in: `func _A(pos int) int {
+	return pos
+fail:
+	// The rule failed.
+	return -1
+}`,
+			want: `func _A(pos int) int {
+	return pos
+}`,
+		},
+		{
+			name: "label referenced only by dead code",
+			in: `func _A(pos int) int {
+	goto ok
+	pos++
+	goto fail
+ok:
+	return pos
+fail:
+	return -1
+}`,
+			want: `func _A(pos int) int {
+	goto ok
+ok:
+	return pos
+}`,
+		},
+		{
+			name: "unreferenced label of reachable statement",
+			in: `func _A(pos int) int {
+	{
+		pos++
+		goto ok
+		pos--
+	ok:
+	}
+	if pos > 1 {
+		return pos
+		panic("unreachable")
+	}
+	return pos
+}`,
+			want: `func _A(pos int) int {
+	{
+		pos++
+		goto ok
+	ok:
+	}
+	if pos > 1 {
+		return pos
+	}
+	return pos
+}`,
+		},
+		{
+			name: "nested lists",
+			in: `func _A(pos int) int {
+	for i := 0; i < pos; i++ {
+		switch i {
+		case 1:
+			continue
+			pos++
+		default:
+			break
+			pos--
+		}
+	}
+	return pos
+}`,
+			want: `func _A(pos int) int {
+	for i := 0; i < pos; i++ {
+		switch i {
+		case 1:
+			continue
+		default:
+			break
+		}
+	}
+	return pos
+}`,
+		},
+		{
+			name: "variables used only by dead code",
+			in: `func _A(pos int) (int, *string) {
+	var node string
+	var label0 string
+	{
+		start0 := pos
+		label0 = "a"
+		goto fail
+		node = label0 + string(start0)
+	}
+	return pos, &node
+fail:
+	return -1, nil
+}`,
+			want: `func _A(pos int) (int, *string) {
+	var node string
+	_ = node
+	var label0 string
+	_ = label0
+	{
+		start0 := pos
+		_ = start0
+		label0 = "a"
+		goto fail
+	}
+fail:
+	return -1, nil
+}`,
+		},
+		{
+			name: "grammar code and function literals",
+			code: `func f() int {
+	return 1
+	return 2
+}
+
+`,
+			in: `func _A() int {
+	g := func() int {
+		return 1
+		return 2
+	}
+	return g()
+	return 3
+}`,
+			want: `func f() int {
+	return 1
+	return 2
+}
+
+func _A() int {
+	g := func() int {
+		return 1
+		return 2
+	}
+	return g()
+}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var b strings.Builder
+			code := "package p\n\n" + test.code
+			src := code + test.in + "\n"
+			if err := gofmt(&b, src, "_", [][2]int{{len(code), len(src)}}, nil); err != nil {
+				t.Fatalf("gofmt(_, %q, \"_\", all, nil)=%v, want nil", test.in, err)
+			}
+			want := "package p\n\n" + test.want + "\n"
+			if got := b.String(); got != want {
+				t.Errorf("gofmt(_, %q, \"_\", all, nil)=\n%s\nwant\n%s", test.in, got, want)
+			}
+		})
+	}
+}
+
+// TestRemoveDeadCodeKeepsGrammarCode tests that the dead code
+// of the prelude and %code blocks is not removed,
+// even with functions named like generated functions.
+func TestRemoveDeadCodeKeepsGrammarCode(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		grammar string
+	}{
+		{
+			prefix: "_",
+			grammar: `{
+package p
+
+func _helper() int {
+	return 1
+	println("unreachable")
+}
+}
+A <- "a"
+`,
+		},
+		{
+			prefix: "",
+			grammar: `{
+package main
+
+func main() {
+	goto end
+	println("unreachable")
+end:
+}
+}
+A <- "a"
+`,
+		},
+		{
+			prefix: "_",
+			grammar: `{
+package p
+}
+%code {
+func _ACode() int {
+	return 1
+	println("unreachable")
+}
+}
+A <- "a"
+`,
+		},
+	}
+	for _, test := range tests {
+		g, err := Parse(strings.NewReader(test.grammar), "test.peggy")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Check(g); err != nil {
+			t.Fatal(err)
+		}
+		var b strings.Builder
+		if err := (Config{Prefix: test.prefix}).Generate(&b, "test.peggy", g); err != nil {
+			t.Fatalf("Generate(%q)=%v, want nil", test.grammar, err)
+		}
+		if !strings.Contains(b.String(), `println("unreachable")`) {
+			t.Errorf("Generate(%q) with prefix %q removed grammar code:\n%s", test.grammar, test.prefix, b.String())
+		}
+	}
+}
+
+// TestGofmtParseError tests that gofmt returns the error
+// of source that does not parse, writing the source unformatted.
+func TestGofmtParseError(t *testing.T) {
+	const src = "package p\n\nfunc _A( {\n"
+	var b strings.Builder
+	if err := gofmt(&b, src, "_", [][2]int{{0, len(src)}}, nil); err == nil {
+		t.Errorf("gofmt(_, %q, \"_\", all, nil)=nil, want error", src)
+	}
+	if got := b.String(); got != src {
+		t.Errorf("gofmt(_, %q, \"_\", all, nil) wrote %q, want %q", src, got, src)
+	}
+}
@@ -0,0 +1,779 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// grammarVersion is the version of the Grammar JSON and gob encodings.
+// It must be incremented whenever the encoding changes,
+// for example when an Expr type or a field is added,
+// so that stale encodings are rejected
+// instead of decoding to an incomplete Grammar.
+const grammarVersion = 1
+
+// MarshalJSON implements json.Marshaler,
+// encoding the Grammar, including the results of the Check pass:
+// its CheckedRules, with expanded templates,
+// the types of rules and expressions, the rules referred to by identifiers,
+// the labels in scope of actions, its left-recursive cycles, and its warnings.
+// The encoding begins with a version number,
+// and UnmarshalJSON rejects encodings of other versions,
+// so that caches of encoded grammars made by other versions of Peggy
+// are detected as stale.
+func (g *Grammar) MarshalJSON() ([]byte, error) {
+	enc, err := encodeGrammar(g)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(enc)
+}
+
+// UnmarshalJSON implements json.Unmarshaler,
+// decoding a Grammar encoded by MarshalJSON into the receiver.
+// The decoded Grammar need not be checked again:
+// it has the same results of the Check pass as the encoded Grammar.
+// However, its Text fields are no longer the original types,
+// and Rules and CheckedRules of the decoded Grammar
+// share rules only where those of the encoded Grammar did.
+func (g *Grammar) UnmarshalJSON(data []byte) error {
+	var enc grammarEnc
+	if err := json.Unmarshal(data, &enc); err != nil {
+		return err
+	}
+	return enc.decode(g)
+}
+
+// GobEncode implements gob.GobEncoder,
+// encoding the Grammar as MarshalJSON, but in the gob format.
+func (g *Grammar) GobEncode() ([]byte, error) {
+	enc, err := encodeGrammar(g)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(enc); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// GobDecode implements gob.GobDecoder,
+// decoding a Grammar encoded by GobEncode into the receiver,
+// as UnmarshalJSON.
+func (g *Grammar) GobDecode(data []byte) error {
+	var enc grammarEnc
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&enc); err != nil {
+		return err
+	}
+	return enc.decode(g)
+}
+
+// grammarEnc is the encoding of a Grammar.
+//
+// Rules are the Grammar's Rules followed by
+// the expanded templates of its CheckedRules,
+// or, if Check failed, any expanded templates it refers to.
+// Rules are referred to by their index in Rules.
+type grammarEnc struct {
+	Version       int
+	Prelude       *textEnc            `json:",omitempty"`
+	Rules         []*ruleEnc          `json:",omitempty"`
+	NRules        int                 `json:",omitempty"`
+	MaxDepth      int                 `json:",omitempty"`
+	WarnSlow      time.Duration       `json:",omitempty"`
+	InvalidBytes  bool                `json:",omitempty"`
+	Newline       *textEnc            `json:",omitempty"`
+	Consts        map[string]*textEnc `json:",omitempty"`
+	CheckedRules  []int               `json:",omitempty"`
+	LeftRecursion [][]int             `json:",omitempty"`
+	Warnings      []*errorEnc         `json:",omitempty"`
+}
+
+// ruleEnc is the encoding of a Rule.
+// Labels are referred to by their index
+// in the pre-order walk of the rule's Expr.
+type ruleEnc struct {
+	Name         nameEnc
+	ErrorName    *textEnc          `json:",omitempty"`
+	Params       *textEnc          `json:",omitempty"`
+	MaxDepth     int               `json:",omitempty"`
+	WarnSlow     time.Duration     `json:",omitempty"`
+	ResultType   *textEnc          `json:",omitempty"`
+	Token        bool              `json:",omitempty"`
+	NoCase       bool              `json:",omitempty"`
+	RecoverUntil []string          `json:",omitempty"`
+	RecoverPast  []string          `json:",omitempty"`
+	Metadata     map[string]string `json:",omitempty"`
+	AST          []int             `json:",omitempty"`
+	Code         []*textEnc        `json:",omitempty"`
+	Expr         *exprEnc
+	N            int     `json:",omitempty"`
+	Type         *string `json:",omitempty"`
+	Epsilon      bool    `json:",omitempty"`
+	Effects      bool    `json:",omitempty"`
+	Textual      bool    `json:",omitempty"`
+	Infallible   bool    `json:",omitempty"`
+	Labels       []int   `json:",omitempty"`
+}
+
+type nameEnc struct {
+	Name *textEnc
+	Args []*textEnc `json:",omitempty"`
+}
+
+type textEnc struct {
+	Str        string
+	Begin, End Loc
+}
+
+type errorEnc struct {
+	Begin, End Loc
+	Msg        string
+	Notes      []*errorEnc `json:",omitempty"`
+}
+
+// exprEnc is the encoding of an Expr.
+// Kind is the kind of the Expr,
+// and the other fields are set according to its kind.
+type exprEnc struct {
+	Kind       string
+	Exprs      []*exprEnc `json:",omitempty"`
+	Expr       *exprEnc   `json:",omitempty"`
+	Sub        *exprEnc   `json:",omitempty"`
+	Text       *textEnc   `json:",omitempty"`
+	Name       *nameEnc   `json:",omitempty"`
+	Args       *textEnc   `json:",omitempty"`
+	Loc        *Loc       `json:",omitempty"`
+	Open       *Loc       `json:",omitempty"`
+	Close      *Loc       `json:",omitempty"`
+	Neg        bool       `json:",omitempty"`
+	OK         bool       `json:",omitempty"`
+	Tuples     bool       `json:",omitempty"`
+	Op         rune       `json:",omitempty"`
+	N          int        `json:",omitempty"`
+	ReturnType string     `json:",omitempty"`
+	NoMemo     bool       `json:",omitempty"`
+	NoCase     bool       `json:",omitempty"`
+	Source     string     `json:",omitempty"`
+	Spans      [][2]rune  `json:",omitempty"`
+	Func       string     `json:",omitempty"`
+	OpenMark   string     `json:",omitempty"`
+	CloseMark  string     `json:",omitempty"`
+	EscapeMark string     `json:",omitempty"`
+	Type       string     `json:",omitempty"`
+	Labels     []int      `json:",omitempty"`
+	// Rule is 1 plus the index of the rule
+	// referred to by an identifier, or 0 if it is unresolved.
+	Rule int `json:",omitempty"`
+}
+
+func encodeGrammar(g *Grammar) (*grammarEnc, error) {
+	enc := &grammarEnc{
+		Version:      grammarVersion,
+		Prelude:      encodeText(g.Prelude),
+		NRules:       len(g.Rules),
+		MaxDepth:     g.MaxDepth,
+		WarnSlow:     g.WarnSlow,
+		InvalidBytes: g.InvalidBytes,
+		Newline:      encodeText(g.Newline),
+	}
+	for k, v := range g.Consts {
+		if enc.Consts == nil {
+			enc.Consts = make(map[string]*textEnc)
+		}
+		enc.Consts[k] = encodeText(v)
+	}
+	rules := make([]*Rule, 0, len(g.Rules))
+	ruleIndex := make(map[*Rule]int)
+	for i := range g.Rules {
+		ruleIndex[&g.Rules[i]] = i
+		rules = append(rules, &g.Rules[i])
+	}
+	index := func(r *Rule) int {
+		if _, ok := ruleIndex[r]; !ok {
+			ruleIndex[r] = len(rules)
+			rules = append(rules, r)
+		}
+		return ruleIndex[r]
+	}
+	for _, r := range g.CheckedRules {
+		enc.CheckedRules = append(enc.CheckedRules, index(r))
+	}
+	// If Check failed, CheckedRules is empty,
+	// but LeftRecursion may have expanded templates.
+	for _, cycle := range g.LeftRecursion {
+		var c []int
+		for _, r := range cycle {
+			c = append(c, index(r))
+		}
+		enc.LeftRecursion = append(enc.LeftRecursion, c)
+	}
+	// If Check failed, identifiers may also refer to such rules.
+	for i := 0; i < len(rules); i++ {
+		rules[i].Expr.Walk(func(e Expr) bool {
+			if id, ok := e.(*Ident); ok && id.rule != nil {
+				index(id.rule)
+			}
+			return true
+		})
+	}
+	for _, r := range rules {
+		re, err := encodeRule(r, ruleIndex)
+		if err != nil {
+			return nil, err
+		}
+		enc.Rules = append(enc.Rules, re)
+	}
+	for _, w := range g.Warnings {
+		enc.Warnings = append(enc.Warnings, encodeError(w))
+	}
+	return enc, nil
+}
+
+func encodeRule(r *Rule, ruleIndex map[*Rule]int) (*ruleEnc, error) {
+	e := ruleEncoder{ruleIndex: ruleIndex, labels: make(map[*LabelExpr]int)}
+	r.Expr.Walk(func(expr Expr) bool {
+		if l, ok := expr.(*LabelExpr); ok {
+			e.labels[l] = len(e.labels)
+		}
+		return true
+	})
+	enc := &ruleEnc{
+		Name:         encodeName(r.Name),
+		ErrorName:    encodeText(r.ErrorName),
+		Params:       encodeText(r.Params),
+		MaxDepth:     r.MaxDepth,
+		WarnSlow:     r.WarnSlow,
+		ResultType:   encodeText(r.ResultType),
+		Token:        r.Token,
+		NoCase:       r.NoCase,
+		RecoverUntil: r.RecoverUntil,
+		RecoverPast:  r.RecoverPast,
+		Metadata:     r.Metadata,
+		AST:          e.labelIndices(r.AST),
+		N:            r.N,
+		Type:         r.typ,
+		Epsilon:      r.epsilon,
+		Effects:      r.effects,
+		Textual:      r.textual,
+		Infallible:   r.infallible,
+		Labels:       e.labelIndices(r.Labels),
+	}
+	for _, c := range r.Code {
+		enc.Code = append(enc.Code, encodeText(c))
+	}
+	enc.Expr = e.expr(r.Expr)
+	if e.err != nil {
+		return nil, e.err
+	}
+	return enc, nil
+}
+
+type ruleEncoder struct {
+	ruleIndex map[*Rule]int
+	labels    map[*LabelExpr]int
+	err       error
+}
+
+func (e *ruleEncoder) labelIndices(labels []*LabelExpr) []int {
+	var is []int
+	for _, l := range labels {
+		i, ok := e.labels[l]
+		if !ok {
+			e.err = fmt.Errorf("label %s is not in its rule", l.Label)
+			continue
+		}
+		is = append(is, i)
+	}
+	return is
+}
+
+func (e *ruleEncoder) exprs(exprs []Expr) []*exprEnc {
+	var encs []*exprEnc
+	for _, expr := range exprs {
+		encs = append(encs, e.expr(expr))
+	}
+	return encs
+}
+
+func (e *ruleEncoder) expr(expr Expr) *exprEnc {
+	switch expr := expr.(type) {
+	case *Choice:
+		return &exprEnc{Kind: "choice", Exprs: e.exprs(expr.Exprs)}
+	case *LongestChoice:
+		return &exprEnc{Kind: "longest", Exprs: e.exprs(expr.Exprs)}
+	case *Action:
+		return &exprEnc{
+			Kind:       "action",
+			Expr:       e.expr(expr.Expr),
+			Text:       encodeText(expr.Code),
+			ReturnType: expr.ReturnType,
+			NoMemo:     expr.NoMemo,
+			Labels:     e.labelIndices(expr.Labels),
+		}
+	case *Sequence:
+		return &exprEnc{Kind: "sequence", Exprs: e.exprs(expr.Exprs), Tuples: expr.Tuples}
+	case *LabelExpr:
+		return &exprEnc{Kind: "label", Text: encodeText(expr.Label), Expr: e.expr(expr.Expr), N: expr.N}
+	case *WantExpr:
+		return &exprEnc{Kind: "want", Text: encodeText(expr.Want), Expr: e.expr(expr.Expr), Loc: &expr.Loc}
+	case *PredExpr:
+		return &exprEnc{Kind: "pred", Expr: e.expr(expr.Expr), Neg: expr.Neg, Loc: &expr.Loc}
+	case *CaptureExpr:
+		return &exprEnc{Kind: "capture", Expr: e.expr(expr.Expr), Loc: &expr.Loc}
+	case *DiffExpr:
+		return &exprEnc{Kind: "diff", Expr: e.expr(expr.Expr), Sub: e.expr(expr.Sub), Loc: &expr.Loc}
+	case *RepExpr:
+		return &exprEnc{Kind: "rep", Op: expr.Op, Expr: e.expr(expr.Expr), Loc: &expr.Loc}
+	case *OptExpr:
+		return &exprEnc{Kind: "opt", Expr: e.expr(expr.Expr), Loc: &expr.Loc, OK: expr.OK}
+	case *Ident:
+		name := encodeName(expr.Name)
+		enc := &exprEnc{Kind: "ident", Name: &name, Args: encodeText(expr.CallArgs)}
+		if expr.rule != nil {
+			i, ok := e.ruleIndex[expr.rule]
+			if !ok {
+				e.err = fmt.Errorf("rule %s is not in the grammar", expr.rule.Name)
+			}
+			enc.Rule = i + 1
+		}
+		return enc
+	case *SubExpr:
+		return &exprEnc{
+			Kind:   "sub",
+			Expr:   e.expr(expr.Expr),
+			Open:   &expr.Open,
+			Close:  &expr.Close,
+			Source: expr.Source,
+		}
+	case *PredCode:
+		return &exprEnc{
+			Kind:   "predcode",
+			Text:   encodeText(expr.Code),
+			Neg:    expr.Neg,
+			Loc:    &expr.Loc,
+			Labels: e.labelIndices(expr.Labels),
+		}
+	case *DelegateExpr:
+		return &exprEnc{
+			Kind:      "delegate",
+			Func:      expr.Func,
+			OpenMark:  expr.Open,
+			CloseMark: expr.Close,
+			Args:      encodeText(expr.Args),
+			Loc:       &expr.Loc,
+			Type:      expr.typ,
+		}
+	case *NativeExpr:
+		return &exprEnc{Kind: "native", Func: expr.Func, Args: encodeText(expr.Args), Loc: &expr.Loc}
+	case *BlockExpr:
+		return &exprEnc{
+			Kind:       expr.Kind,
+			OpenMark:   expr.Open,
+			CloseMark:  expr.Close,
+			EscapeMark: expr.Escape,
+			Args:       encodeText(expr.Args),
+			Loc:        &expr.Loc,
+		}
+	case *Literal:
+		return &exprEnc{Kind: "literal", Text: encodeText(expr.Text)}
+	case *CharClass:
+		return &exprEnc{
+			Kind:   "charclass",
+			Spans:  expr.Spans,
+			Neg:    expr.Neg,
+			Open:   &expr.Open,
+			Close:  &expr.Close,
+			Source: expr.Source,
+			NoCase: expr.NoCase,
+		}
+	case *Any:
+		return &exprEnc{Kind: "any", Loc: &expr.Loc}
+	case *Cut:
+		return &exprEnc{Kind: "cut", Loc: &expr.Loc}
+	case *Empty:
+		return &exprEnc{Kind: "empty", Open: &expr.Open, Close: &expr.Close}
+	default:
+		e.err = fmt.Errorf("cannot encode expression type %T", expr)
+		return nil
+	}
+}
+
+func encodeText(t Text) *textEnc {
+	if t == nil {
+		return nil
+	}
+	return &textEnc{Str: t.String(), Begin: t.Begin(), End: t.End()}
+}
+
+func encodeName(n Name) nameEnc {
+	enc := nameEnc{Name: encodeText(n.Name)}
+	for _, a := range n.Args {
+		enc.Args = append(enc.Args, encodeText(a))
+	}
+	return enc
+}
+
+func encodeError(err Error) *errorEnc {
+	enc := &errorEnc{Begin: err.Begin(), End: err.End(), Msg: err.Msg}
+	for _, n := range err.Notes {
+		enc.Notes = append(enc.Notes, encodeError(n))
+	}
+	return enc
+}
+
+func (enc *grammarEnc) decode(g *Grammar) error {
+	if enc.Version != grammarVersion {
+		return fmt.Errorf("unsupported Grammar encoding version %d", enc.Version)
+	}
+	if enc.NRules < 0 || enc.NRules > len(enc.Rules) {
+		return fmt.Errorf("bad rule count %d", enc.NRules)
+	}
+	d := grammarDecoder{enc: enc, rules: make([]*Rule, len(enc.Rules))}
+	dec := Grammar{
+		Prelude:      decodeText(enc.Prelude),
+		Rules:        make([]Rule, enc.NRules),
+		MaxDepth:     enc.MaxDepth,
+		WarnSlow:     enc.WarnSlow,
+		InvalidBytes: enc.InvalidBytes,
+		Newline:      decodeText(enc.Newline),
+	}
+	// Allocate all rules before decoding any,
+	// since identifiers may refer to any rule.
+	for i := range d.rules {
+		if i < enc.NRules {
+			d.rules[i] = &dec.Rules[i]
+		} else {
+			d.rules[i] = new(Rule)
+		}
+	}
+	for i, re := range enc.Rules {
+		if err := d.rule(d.rules[i], re); err != nil {
+			return err
+		}
+	}
+	for k, v := range enc.Consts {
+		if dec.Consts == nil {
+			dec.Consts = make(map[string]Text)
+		}
+		dec.Consts[k] = decodeText(v)
+	}
+	for _, i := range enc.CheckedRules {
+		r, err := d.ruleAt(i)
+		if err != nil {
+			return err
+		}
+		dec.CheckedRules = append(dec.CheckedRules, r)
+	}
+	for _, c := range enc.LeftRecursion {
+		var cycle []*Rule
+		for _, i := range c {
+			r, err := d.ruleAt(i)
+			if err != nil {
+				return err
+			}
+			cycle = append(cycle, r)
+		}
+		dec.LeftRecursion = append(dec.LeftRecursion, cycle)
+	}
+	for _, w := range enc.Warnings {
+		dec.Warnings = append(dec.Warnings, decodeError(w))
+	}
+	*g = dec
+	return nil
+}
+
+type grammarDecoder struct {
+	enc   *grammarEnc
+	rules []*Rule
+
+	// labels are the labels of the rule being decoded,
+	// in the pre-order of its Expr.
+	labels []*LabelExpr
+	err    error
+}
+
+func (d *grammarDecoder) ruleAt(i int) (*Rule, error) {
+	if i < 0 || i >= len(d.rules) {
+		return nil, fmt.Errorf("bad rule index %d", i)
+	}
+	return d.rules[i], nil
+}
+
+func (d *grammarDecoder) rule(r *Rule, enc *ruleEnc) error {
+	if enc == nil || enc.Expr == nil {
+		return errors.New("missing rule expression")
+	}
+	d.labels = nil
+	*r = Rule{
+		Name:         d.name(enc.Name),
+		ErrorName:    decodeText(enc.ErrorName),
+		Params:       decodeText(enc.Params),
+		MaxDepth:     enc.MaxDepth,
+		WarnSlow:     enc.WarnSlow,
+		ResultType:   decodeText(enc.ResultType),
+		Token:        enc.Token,
+		NoCase:       enc.NoCase,
+		RecoverUntil: enc.RecoverUntil,
+		RecoverPast:  enc.RecoverPast,
+		Metadata:     enc.Metadata,
+		Expr:         d.expr(enc.Expr),
+		N:            enc.N,
+		typ:          enc.Type,
+		epsilon:      enc.Epsilon,
+		effects:      enc.Effects,
+		textual:      enc.Textual,
+		infallible:   enc.Infallible,
+	}
+	for _, c := range enc.Code {
+		r.Code = append(r.Code, decodeText(c))
+	}
+	// Labels are decoded after the Expr,
+	// since they refer to its label expressions.
+	r.AST = d.labelRefs(enc.AST)
+	r.Labels = d.labelRefs(enc.Labels)
+	d.resolveLabels(r.Expr, enc.Expr)
+	return d.err
+}
+
+// resolveLabels sets the Labels of the actions and code predicates
+// of an Expr decoded from the exprEnc.
+func (d *grammarDecoder) resolveLabels(expr Expr, enc *exprEnc) {
+	if d.err != nil {
+		return
+	}
+	switch expr := expr.(type) {
+	case *Choice:
+		d.resolveAll(expr.Exprs, enc.Exprs)
+	case *LongestChoice:
+		d.resolveAll(expr.Exprs, enc.Exprs)
+	case *Sequence:
+		d.resolveAll(expr.Exprs, enc.Exprs)
+	case *Action:
+		expr.Labels = d.labelRefs(enc.Labels)
+		d.resolveLabels(expr.Expr, enc.Expr)
+	case *PredCode:
+		expr.Labels = d.labelRefs(enc.Labels)
+	case *LabelExpr:
+		d.resolveLabels(expr.Expr, enc.Expr)
+	case *WantExpr:
+		d.resolveLabels(expr.Expr, enc.Expr)
+	case *PredExpr:
+		d.resolveLabels(expr.Expr, enc.Expr)
+	case *CaptureExpr:
+		d.resolveLabels(expr.Expr, enc.Expr)
+	case *DiffExpr:
+		d.resolveLabels(expr.Expr, enc.Expr)
+		d.resolveLabels(expr.Sub, enc.Sub)
+	case *RepExpr:
+		d.resolveLabels(expr.Expr, enc.Expr)
+	case *OptExpr:
+		d.resolveLabels(expr.Expr, enc.Expr)
+	case *SubExpr:
+		d.resolveLabels(expr.Expr, enc.Expr)
+	}
+}
+
+func (d *grammarDecoder) resolveAll(exprs []Expr, encs []*exprEnc) {
+	for i, expr := range exprs {
+		d.resolveLabels(expr, encs[i])
+	}
+}
+
+func (d *grammarDecoder) labelRefs(is []int) []*LabelExpr {
+	var labels []*LabelExpr
+	for _, i := range is {
+		if i < 0 || i >= len(d.labels) {
+			d.fail(fmt.Errorf("bad label index %d", i))
+			continue
+		}
+		labels = append(labels, d.labels[i])
+	}
+	return labels
+}
+
+func (d *grammarDecoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *grammarDecoder) name(enc nameEnc) Name {
+	if enc.Name == nil {
+		d.fail(errors.New("missing name"))
+	}
+	n := Name{Name: decodeText(enc.Name)}
+	for _, a := range enc.Args {
+		n.Args = append(n.Args, decodeText(a))
+	}
+	return n
+}
+
+func (d *grammarDecoder) exprs(encs []*exprEnc) []Expr {
+	if len(encs) == 0 {
+		d.fail(errors.New("missing subexpressions"))
+	}
+	var exprs []Expr
+	for _, enc := range encs {
+		exprs = append(exprs, d.expr(enc))
+	}
+	return exprs
+}
+
+// text returns the decoded Text, which must be non-nil.
+func (d *grammarDecoder) text(enc *textEnc) Text {
+	if enc == nil {
+		d.fail(errors.New("missing text"))
+		return text{}
+	}
+	return decodeText(enc)
+}
+
+// loc returns the Loc, which must be non-nil.
+func (d *grammarDecoder) loc(l *Loc) Loc {
+	if l == nil {
+		d.fail(errors.New("missing location"))
+		return Loc{}
+	}
+	return *l
+}
+
+// expr returns the Expr decoded from the exprEnc.
+// If the exprEnc is malformed, d.err is set,
+// and the returned Expr is a placeholder.
+func (d *grammarDecoder) expr(enc *exprEnc) Expr {
+	if enc == nil {
+		d.fail(errors.New("missing expression"))
+		return &Any{}
+	}
+	sub := func() Expr { return d.expr(enc.Expr) }
+	switch enc.Kind {
+	case "choice":
+		return &Choice{Exprs: d.exprs(enc.Exprs)}
+	case "longest":
+		return &LongestChoice{Exprs: d.exprs(enc.Exprs)}
+	case "action":
+		return &Action{
+			Expr:       sub(),
+			Code:       d.text(enc.Text),
+			ReturnType: enc.ReturnType,
+			NoMemo:     enc.NoMemo,
+		}
+	case "sequence":
+		return &Sequence{Exprs: d.exprs(enc.Exprs), Tuples: enc.Tuples}
+	case "label":
+		// Labels are numbered in pre-order,
+		// so the label is appended before its subexpression is decoded.
+		l := &LabelExpr{Label: d.text(enc.Text), N: enc.N}
+		d.labels = append(d.labels, l)
+		l.Expr = sub()
+		return l
+	case "want":
+		return &WantExpr{Expr: sub(), Want: d.text(enc.Text), Loc: d.loc(enc.Loc)}
+	case "pred":
+		return &PredExpr{Expr: sub(), Neg: enc.Neg, Loc: d.loc(enc.Loc)}
+	case "capture":
+		return &CaptureExpr{Expr: sub(), Loc: d.loc(enc.Loc)}
+	case "diff":
+		return &DiffExpr{Expr: sub(), Sub: d.expr(enc.Sub), Loc: d.loc(enc.Loc)}
+	case "rep":
+		if enc.Op != '*' && enc.Op != '+' {
+			d.fail(fmt.Errorf("bad repetition operator %q", enc.Op))
+		}
+		return &RepExpr{Op: enc.Op, Expr: sub(), Loc: d.loc(enc.Loc)}
+	case "opt":
+		return &OptExpr{Expr: sub(), Loc: d.loc(enc.Loc), OK: enc.OK}
+	case "ident":
+		if enc.Name == nil {
+			d.fail(errors.New("missing identifier name"))
+			return &Any{}
+		}
+		e := &Ident{Name: d.name(*enc.Name), CallArgs: decodeText(enc.Args)}
+		if enc.Rule > 0 {
+			r, err := d.ruleAt(enc.Rule - 1)
+			if err != nil {
+				d.fail(err)
+			}
+			e.rule = r
+		}
+		return e
+	case "sub":
+		return &SubExpr{
+			Expr:   sub(),
+			Open:   d.loc(enc.Open),
+			Close:  d.loc(enc.Close),
+			Source: enc.Source,
+		}
+	case "predcode":
+		return &PredCode{Code: d.text(enc.Text), Neg: enc.Neg, Loc: d.loc(enc.Loc)}
+	case "delegate":
+		return &DelegateExpr{
+			Func:  enc.Func,
+			Open:  enc.OpenMark,
+			Close: enc.CloseMark,
+			Args:  d.text(enc.Args),
+			Loc:   d.loc(enc.Loc),
+			typ:   enc.Type,
+		}
+	case "native":
+		return &NativeExpr{Func: enc.Func, Args: d.text(enc.Args), Loc: d.loc(enc.Loc)}
+	case "balanced", "until":
+		return &BlockExpr{
+			Kind:   enc.Kind,
+			Open:   enc.OpenMark,
+			Close:  enc.CloseMark,
+			Escape: enc.EscapeMark,
+			Args:   d.text(enc.Args),
+			Loc:    d.loc(enc.Loc),
+		}
+	case "literal":
+		return &Literal{Text: d.text(enc.Text)}
+	case "charclass":
+		return &CharClass{
+			Spans:  enc.Spans,
+			Neg:    enc.Neg,
+			Open:   d.loc(enc.Open),
+			Close:  d.loc(enc.Close),
+			Source: enc.Source,
+			NoCase: enc.NoCase,
+		}
+	case "any":
+		return &Any{Loc: d.loc(enc.Loc)}
+	case "cut":
+		return &Cut{Loc: d.loc(enc.Loc)}
+	case "empty":
+		return &Empty{Open: d.loc(enc.Open), Close: d.loc(enc.Close)}
+	default:
+		d.fail(fmt.Errorf("bad expression kind %q", enc.Kind))
+		return &Any{}
+	}
+}
+
+func decodeText(enc *textEnc) Text {
+	if enc == nil {
+		return nil
+	}
+	return text{str: enc.Str, begin: enc.Begin, end: enc.End}
+}
+
+func decodeError(enc *errorEnc) Error {
+	err := Error{
+		Located: text{begin: enc.Begin, end: enc.End},
+		Msg:     enc.Msg,
+	}
+	for _, n := range enc.Notes {
+		err.Notes = append(err.Notes, decodeError(n))
+	}
+	return err
+}
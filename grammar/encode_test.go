@@ -0,0 +1,160 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGrammarEncoding(t *testing.T) {
+	const grammar = `{
+package p
+
+func f(string) (int, error) { return 0, nil }
+}
+%maxdepth 10
+%warnslow 5ms
+%const ws = " \t"
+A <- x:B y:List<C> { return string(x + y) } / &{ true } "a" / $(. - "b")
+B "bee" @maxdepth(3) @doc("b") -> string <- "b" / [0-9]+ / .? / !"c" [\{ws}]
+List<X> <- X ("," X)*
+C @warnslow(1ms) <- c:[^c]+ !"d" &"e" !{ len(c) > 1 }
+D -> int <- %delegate(f, "<", ">")
+Nat <- %native(scan)
+R <- %balanced("{", "}", "\\") %until("*/")
+E <- "x" {return 1} | "y" {return 2}
+S @recoverpast(";") @recoveruntil("}") <- "s" ";"
+K <- "k" ^ "x" / "y"
+W @nocase <- "where" [a-c] "_"
+Y <- "y" / ()
+Digits <- [0-9]+ !> "digits"
+`
+	g, err := Parse(strings.NewReader(grammar), "test.peggy")
+	if err != nil {
+		t.Fatalf("Parse(_)=%v, want nil", err)
+	}
+	if err := Check(g); err != nil {
+		t.Fatalf("Check(_)=%v, want nil", err)
+	}
+	want := generateString(t, g)
+
+	codecs := []struct {
+		name   string
+		encode func(*Grammar) ([]byte, error)
+		decode func([]byte, *Grammar) error
+	}{
+		{
+			name:   "json",
+			encode: func(g *Grammar) ([]byte, error) { return json.Marshal(g) },
+			decode: func(data []byte, g *Grammar) error { return json.Unmarshal(data, g) },
+		},
+		{
+			name: "gob",
+			encode: func(g *Grammar) ([]byte, error) {
+				var b bytes.Buffer
+				err := gob.NewEncoder(&b).Encode(g)
+				return b.Bytes(), err
+			},
+			decode: func(data []byte, g *Grammar) error {
+				return gob.NewDecoder(bytes.NewReader(data)).Decode(g)
+			},
+		},
+	}
+	for _, codec := range codecs {
+		data, err := codec.encode(g)
+		if err != nil {
+			t.Errorf("%s encode failed: %v", codec.name, err)
+			continue
+		}
+		var got Grammar
+		if err := codec.decode(data, &got); err != nil {
+			t.Errorf("%s decode failed: %v", codec.name, err)
+			continue
+		}
+		if s, w := String(got.Rules), String(g.Rules); s != w {
+			t.Errorf("%s decoded rules\n%s\nwant\n%s", codec.name, s, w)
+		}
+		if len(got.CheckedRules) != len(g.CheckedRules) {
+			t.Errorf("%s decoded %d checked rules, want %d",
+				codec.name, len(got.CheckedRules), len(g.CheckedRules))
+			continue
+		}
+		for i, r := range got.CheckedRules {
+			for j := range g.Rules {
+				if g.CheckedRules[i] == &g.Rules[j] && r != &got.Rules[j] {
+					t.Errorf("%s decoded checked rule %s is not shared with Rules", codec.name, r.Name)
+				}
+			}
+			r.Expr.Walk(func(e Expr) bool {
+				if id, ok := e.(*Ident); ok && id.Rule() != got.CheckedRules[id.Rule().N] {
+					t.Errorf("%s decoded %s refers to a rule not in CheckedRules", codec.name, id)
+				}
+				return true
+			})
+		}
+		if s := generateString(t, &got); s != want {
+			t.Errorf("%s decoded grammar generated\n%s\nwant\n%s", codec.name, s, want)
+		}
+	}
+}
+
+func TestGrammarEncodingVersion(t *testing.T) {
+	g, err := Parse(strings.NewReader("A <- 'a'"), "")
+	if err != nil {
+		t.Fatalf("Parse(_)=%v, want nil", err)
+	}
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatalf("json.Marshal(_)=%v, want nil", err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("json.Unmarshal(_)=%v, want nil", err)
+	}
+	m["Version"] = grammarVersion + 1
+	if data, err = json.Marshal(m); err != nil {
+		t.Fatalf("json.Marshal(_)=%v, want nil", err)
+	}
+	var got Grammar
+	err = json.Unmarshal(data, &got)
+	if err == nil || !strings.Contains(err.Error(), "unsupported Grammar encoding version") {
+		t.Errorf("json.Unmarshal(stale)=%v, want unsupported Grammar encoding version", err)
+	}
+}
+
+func TestGrammarDecodeErrors(t *testing.T) {
+	tests := []string{
+		`{"Version": %d, "NRules": 2}`,
+		`{"Version": %d, "NRules": 1, "Rules": [{"Name": {}, "Expr": {"Kind": "any", "Loc": {}}}]}`,
+		`{"Version": %d, "NRules": 1, "Rules": [{"Name": {"Name": {}}, "Expr": {"Kind": "bogus"}}]}`,
+		`{"Version": %d, "NRules": 1, "Rules": [{"Name": {"Name": {}}, "Expr": {"Kind": "any"}}]}`,
+		`{"Version": %d, "NRules": 1, "Rules": [{"Name": {"Name": {}}, "Expr": {"Kind": "sequence"}}]}`,
+		`{"Version": %d, "NRules": 1, "Rules": [{"Name": {"Name": {}}, "Expr": {"Kind": "any", "Loc": {}}, "Labels": [0]}]}`,
+		`{"Version": %d, "NRules": 1, "Rules": [{"Name": {"Name": {}}, "Expr": {"Kind": "ident", "Name": {"Name": {}}, "Rule": 2}}]}`,
+		`{"Version": %d, "CheckedRules": [0]}`,
+	}
+	for _, test := range tests {
+		test = fmt.Sprintf(test, grammarVersion)
+		var g Grammar
+		if err := json.Unmarshal([]byte(test), &g); err == nil {
+			t.Errorf("json.Unmarshal(%s)=nil, want error", test)
+		}
+	}
+}
+
+func generateString(t *testing.T, g *Grammar) string {
+	var b strings.Builder
+	if err := (Config{Prefix: "_"}).Generate(&b, "test.peggy", g); err != nil {
+		t.Fatalf("Generate(_)=%v, want nil", err)
+	}
+	return b.String()
+}
@@ -4,7 +4,7 @@
 // license that can be found in the LICENSE file or at
 // https://developers.google.com/open-source/licenses/bsd.
 
-package main
+package grammar
 
 import (
 	"fmt"
@@ -53,21 +53,48 @@ func (err Errors) Error() string {
 type Error struct {
 	Located
 	Msg string
+
+	// Notes are additional messages tied to other elements
+	// of the input file that are related to the error,
+	// such as the definition of a misused template.
+	Notes []Error
+
+	// cause is the underlying error, if any,
+	// such as the error reading the input.
+	cause error
 }
 
+// Unwrap returns the underlying error of the Error, if any,
+// such as the error of the io.RuneScanner input of Parse.
+func (err Error) Unwrap() error { return err.cause }
+
+// Error returns the string representation of the Error,
+// followed by each of its Notes on a tab-indented line.
 func (err Error) Error() string {
 	b, e := err.Begin(), err.End()
 	l0, c0 := b.Line, b.Col
 	l1, c1 := e.Line, e.Col
+	var s string
 	switch {
 	case l0 == l1 && c0 == c1:
-		return fmt.Sprintf("%s:%d.%d: %s", b.File, l0, c0, err.Msg)
+		s = fmt.Sprintf("%s:%d.%d: %s", b.File, l0, c0, err.Msg)
 	default:
-		return fmt.Sprintf("%s:%d.%d,%d.%d: %s", b.File, l0, c0, l1, c1, err.Msg)
+		s = fmt.Sprintf("%s:%d.%d,%d.%d: %s", b.File, l0, c0, l1, c1, err.Msg)
+	}
+	for _, n := range err.Notes {
+		s += "\n\t" + n.Error()
 	}
+	return s
 }
 
 // Err returns an error containing the location and formatted message.
 func Err(loc Located, format string, args ...interface{}) Error {
 	return Error{Located: loc, Msg: fmt.Sprintf(format, args...)}
 }
+
+// note returns a copy of the Error with an added note
+// containing the location and formatted message.
+func (err Error) note(loc Located, format string, args ...interface{}) Error {
+	err.Notes = append(err.Notes[:len(err.Notes):len(err.Notes)], Err(loc, format, args...))
+	return err
+}
@@ -0,0 +1,169 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+// ExplainFail writes to w a narrative of the failed parse
+// of the text, named name, by the checked grammar,
+// from the Fail tree of the failure.
+//
+// It begins with the error message of peg.SimpleError.
+// It then lists the rules that were tried at the failure position,
+// the furthest position that the parse reached,
+// and the paths of rules, from the root, that lead to the failure,
+// along with what each expression that failed there wanted,
+// and why the expression failed.
+// Fails that did not reach the failure position are omitted.
+func ExplainFail(w io.Writer, name, text string, g *Grammar, fail *peg.Fail) error {
+	nl := g.pegNewline().Newline
+	rules := make(map[string]*Rule)
+	for _, r := range g.CheckedRules {
+		rules[r.Name.String()] = r
+	}
+	leaves := peg.LeafFails(fail)
+	if len(leaves) == 0 {
+		return nil
+	}
+	pos := leaves[0].Pos
+	x := &explainer{
+		w:      w,
+		rules:  rules,
+		locs:   nl.Locate(text, fail),
+		pos:    pos,
+		reach:  make(map[*peg.Fail]bool),
+		tried:  make(map[string]bool),
+		walked: make(map[*peg.Fail]bool),
+	}
+	x.reaches(fail)
+
+	err := nl.SimpleError(text, fail)
+	err.FilePath = name
+	x.printf("%s\n\n", err)
+	loc := x.locs[leaves[0]]
+	x.printf("The furthest the parse reached is %d.%d.\n", loc.Line, loc.Column)
+	var tried []string
+	for r := range x.tried {
+		tried = append(tried, r)
+	}
+	sort.Strings(tried)
+	if len(tried) == 0 {
+		x.printf("No rule began there; it failed within the rules below.\n")
+	} else {
+		x.printf("Rules tried there: %s.\n", strings.Join(tried, ", "))
+	}
+	x.printf("\nThe rules leading to the failure, each with where it began:\n")
+	x.explain("", fail)
+	return x.err
+}
+
+type explainer struct {
+	w     io.Writer
+	rules map[string]*Rule
+	locs  map[*peg.Fail]peg.Loc
+	// pos is the failure position.
+	pos int
+	// reach is whether each Fail leads to a leaf at pos.
+	reach map[*peg.Fail]bool
+	// tried are the names of rules that began at pos
+	// and lead to a leaf at pos.
+	tried map[string]bool
+	// walked are the Fails already explained,
+	// since a Fail may be shared by multiple parents.
+	walked map[*peg.Fail]bool
+	err    error
+}
+
+func (x *explainer) printf(format string, args ...interface{}) {
+	if x.err == nil {
+		_, x.err = fmt.Fprintf(x.w, format, args...)
+	}
+}
+
+// reaches returns whether f leads to a leaf Fail at the failure position.
+func (x *explainer) reaches(f *peg.Fail) bool {
+	if r, ok := x.reach[f]; ok {
+		return r
+	}
+	r := len(f.Kids) == 0 && f.Pos == x.pos
+	for _, k := range f.Kids {
+		// Visit every kid to record the tried rules.
+		r = x.reaches(k) || r
+	}
+	x.reach[f] = r
+	if r && f.Name != "" && f.Pos == x.pos {
+		x.tried[f.Name] = true
+	}
+	return r
+}
+
+func (x *explainer) explain(indent string, f *peg.Fail) {
+	if !x.reach[f] {
+		return
+	}
+	loc := x.locs[f]
+	if len(f.Kids) == 0 && f.Name == "" {
+		x.printf("%swanted %s: %s\n", indent, f.Want, failReason(f))
+		return
+	}
+	where := ""
+	if r, ok := x.rules[f.Name]; ok {
+		b := r.Name.Begin()
+		where = fmt.Sprintf(" (%s:%d.%d)", b.File, b.Line, b.Col)
+	}
+	if len(f.Kids) == 0 {
+		x.printf("%s%s at %d.%d%s wanted %s: %s\n", indent, f.Name, loc.Line, loc.Column, where, f.Want, failReason(f))
+		return
+	}
+	if x.walked[f] {
+		x.printf("%s%s at %d.%d%s, as above\n", indent, f.Name, loc.Line, loc.Column, where)
+		return
+	}
+	x.walked[f] = true
+	x.printf("%s%s at %d.%d%s\n", indent, f.Name, loc.Line, loc.Column, where)
+	for _, k := range f.Kids {
+		x.explain(indent+"  ", k)
+	}
+}
+
+// failReason returns why an expression failed, given its Fail.
+func failReason(f *peg.Fail) string {
+	switch f.Code {
+	case peg.DepthExceeded:
+		return "parsing the rule would exceed its maximum nesting depth"
+	case peg.ExpectedLiteral:
+		return "the literal did not match the input"
+	case peg.ExpectedAny:
+		return "there was no rune; the input ended"
+	case peg.ExpectedClass:
+		return "the rune is not in the character class"
+	case peg.PredicateFailed:
+		switch {
+		case strings.HasPrefix(f.Want, "!{"):
+			return "the code predicate was true"
+		case strings.HasPrefix(f.Want, "&{"):
+			return "the code predicate was false"
+		case strings.HasPrefix(f.Want, "!"):
+			return "the negative predicate's expression matched"
+		case strings.HasPrefix(f.Want, "&"):
+			return "the predicate's expression did not match"
+		default:
+			return "the subtracted expression matched the same text"
+		}
+	case peg.NamedRule:
+		return "the named rule failed; the fails within it are not reported"
+	default:
+		return "the expression failed"
+	}
+}
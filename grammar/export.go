@@ -0,0 +1,540 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// Export writes the checked grammar in another grammar dialect:
+// "abnf", RFC 5234 ABNF with the RFC 7405 %s strings,
+// "pegjs", a PEG.js grammar,
+// or "lpeg", a grammar of the LPeg re module.
+//
+// The conversion is best-effort.
+// Actions are Go code, so they are dropped,
+// and template rules are exported as their expansions.
+// An expression that cannot be converted exactly
+// is approximated or dropped,
+// and a comment preceding its rule describes the change.
+//
+// Code predicates are Go code too,
+// but dropping one would silently change the language,
+// so each is replaced by an expression that never matches,
+// and is returned in dropped for it to be rewritten by hand.
+func Export(w io.Writer, to string, gr *Grammar) (dropped Errors, err error) {
+	var x exporter
+	switch to {
+	case "abnf":
+		x = exporter{comment: ";", define: " = ", header: []string{
+			"ABNF alternatives are unordered, unlike the choices of the PEG this was exported from.",
+		}}
+	case "pegjs":
+		x = exporter{comment: "//", define: " = "}
+	case "lpeg":
+		x = exporter{comment: "--", define: " <- ", header: []string{
+			"LPeg matches bytes, but the PEG this was exported from matched runes.",
+		}}
+	default:
+		return Errors{}, errors.New("bad export dialect " + to + ": want abnf, pegjs, or lpeg")
+	}
+	x.dialect = to
+	for _, h := range x.header {
+		if _, err := fmt.Fprintf(w, "%s %s\n", x.comment, h); err != nil {
+			return x.dropped, err
+		}
+	}
+	for _, r := range gr.CheckedRules {
+		x.notes = x.notes[:0]
+		s := x.name(r) + x.errorName(r) + x.define + x.expr(r.Expr)
+		for _, n := range x.notes {
+			if _, err := fmt.Fprintf(w, "%s %s\n", x.comment, n); err != nil {
+				return x.dropped, err
+			}
+		}
+		if _, err := io.WriteString(w, s+"\n"); err != nil {
+			return x.dropped, err
+		}
+	}
+	return x.dropped, nil
+}
+
+// An exporter converts rules to another dialect.
+type exporter struct {
+	dialect string
+	// comment begins a line comment.
+	comment string
+	// define separates a rule name from its expression.
+	define string
+	// header are comments beginning the output,
+	// about differences that apply to the entire grammar.
+	header []string
+	// notes are the comments preceding the current rule,
+	// describing the expressions that could not be converted exactly.
+	notes []string
+	// dropped are the code predicates replaced by fail.
+	dropped Errors
+}
+
+func (x *exporter) note(format string, args ...interface{}) {
+	x.notes = append(x.notes, fmt.Sprintf(format, args...))
+}
+
+// name returns the rule's name in the dialect.
+// ABNF rule names use - instead of _.
+func (x *exporter) name(r *Rule) string {
+	name := r.Name.Ident()
+	if x.dialect != "abnf" {
+		return name
+	}
+	name = strings.Replace(name, "_", "-", -1)
+	if c := name[0]; c > unicode.MaxASCII || !unicode.IsLetter(rune(c)) {
+		name = "r" + name
+	}
+	return name
+}
+
+// errorName returns the rule's display name in the dialect, if any.
+// Only PEG.js has display names.
+func (x *exporter) errorName(r *Rule) string {
+	if r.ErrorName == nil || x.dialect != "pegjs" {
+		return ""
+	}
+	return " " + x.literal(r.ErrorName.String())
+}
+
+// empty returns an expression matching the empty string,
+// which replaces expressions that are dropped.
+func (x *exporter) empty() string {
+	if x.dialect == "lpeg" {
+		return "''"
+	}
+	return `""`
+}
+
+// fail returns an expression that never matches,
+// which replaces expressions whose result cannot be computed.
+// ABNF has no such expression, so it is a prose value,
+// which ABNF tools cannot match.
+func (x *exporter) fail() string {
+	switch x.dialect {
+	case "abnf":
+		return "<code predicate>"
+	case "lpeg":
+		return "!''"
+	}
+	return `!""`
+}
+
+func (x *exporter) expr(e Expr) string {
+	switch e := e.(type) {
+	case *Choice:
+		return x.choice(e.Exprs)
+	case *LongestChoice:
+		// ABNF alternatives have no order,
+		// so they are closer to a longest choice than an ordered choice.
+		if x.dialect != "abnf" {
+			x.note("approximated longest choice %s as an ordered choice", e)
+		}
+		return x.choice(e.Exprs)
+	case *Action:
+		return x.expr(e.Expr)
+	case *Sequence:
+		s := x.expr(e.Exprs[0])
+		for _, sub := range e.Exprs[1:] {
+			s += " " + x.expr(sub)
+		}
+		return s
+	case *LabelExpr:
+		if x.dialect == "pegjs" {
+			return e.Label.String() + ":" + x.expr(e.Expr)
+		}
+		return x.expr(e.Expr)
+	case *WantExpr:
+		return x.expr(e.Expr)
+	case *PredExpr:
+		if x.dialect == "abnf" {
+			x.note("dropped predicate %s", e)
+			return x.empty()
+		}
+		op := "&"
+		if e.Neg {
+			op = "!"
+		}
+		return op + x.expr(e.Expr)
+	case *CaptureExpr:
+		switch x.dialect {
+		case "pegjs":
+			return "$" + x.expr(e.Expr)
+		case "lpeg":
+			return "{ " + x.expr(e.Expr) + " }"
+		}
+		return x.expr(e.Expr)
+	case *DiffExpr:
+		if x.dialect == "abnf" {
+			x.note("dropped the subtrahend of difference %s", e)
+			return x.expr(e.Expr)
+		}
+		// !Sub Expr rejects more than the difference:
+		// input where Sub matches only a prefix of Expr's match.
+		x.note("approximated difference %s as !Sub Expr", e)
+		sub := x.expr(e.Sub)
+		switch e.Sub.(type) {
+		case *Sequence, *Choice, *LongestChoice, *DiffExpr, *Action:
+			sub = "(" + sub + ")"
+		}
+		return "!" + sub + " " + x.expr(e.Expr)
+	case *RepExpr:
+		if x.dialect == "abnf" {
+			if e.Op == '+' {
+				return "1*" + x.expr(e.Expr)
+			}
+			return "*" + x.expr(e.Expr)
+		}
+		return x.expr(e.Expr) + string(e.Op)
+	case *OptExpr:
+		if x.dialect == "abnf" {
+			if sub, ok := e.Expr.(*SubExpr); ok {
+				return "[" + x.expr(sub.Expr) + "]"
+			}
+			return "[" + x.expr(e.Expr) + "]"
+		}
+		return x.expr(e.Expr) + "?"
+	case *SubExpr:
+		return "(" + x.expr(e.Expr) + ")"
+	case *Ident:
+		return x.name(e.Rule())
+	case *PredCode:
+		x.note("replaced code predicate %s with an expression that never matches", e)
+		x.dropped.add(e, "replaced code predicate %s with an expression that never matches", e)
+		return x.fail()
+	case *Cut:
+		x.note("dropped cut %s", e)
+		return x.empty()
+	case *Empty:
+		return x.empty()
+	case *DelegateExpr:
+		if x.dialect == "abnf" {
+			x.note("dropped delegate %s", e)
+			return x.empty()
+		}
+		// The delimited region is matched, but not parsed.
+		x.note("approximated delegate %s as its delimited region", e)
+		cl := x.literal(e.Close)
+		return "(" + x.literal(e.Open) + " (!" + cl + " .)* " + cl + ")"
+	case *NativeExpr:
+		x.note("dropped native matcher %s", e)
+		return x.empty()
+	case *BlockExpr:
+		if x.dialect == "abnf" {
+			x.note("dropped block %s", e)
+			return x.empty()
+		}
+		// Escapes and nesting are not matched.
+		cl := x.literal(e.Close)
+		if e.Open != "" {
+			x.note("approximated block %s as its delimited region", e)
+			return "(" + x.literal(e.Open) + " (!" + cl + " .)* " + cl + ")"
+		}
+		if e.Escape != "" {
+			x.note("approximated block %s without its escape", e)
+		}
+		return "((!" + cl + " .)* " + cl + ")"
+	case *Literal:
+		return x.literal(e.Text.String())
+	case *CharClass:
+		return x.charClass(e)
+	case *Any:
+		if x.dialect == "abnf" {
+			return "%x0-10FFFF"
+		}
+		return "."
+	default:
+		panic(fmt.Sprintf("impossible type: %T", e))
+	}
+}
+
+func (x *exporter) choice(exprs []Expr) string {
+	s := x.expr(exprs[0])
+	for _, sub := range exprs[1:] {
+		s += " / " + x.expr(sub)
+	}
+	return s
+}
+
+// literal returns the dialect's literal matching the string.
+func (x *exporter) literal(s string) string {
+	switch x.dialect {
+	case "abnf":
+		return abnfLiteral(s)
+	case "lpeg":
+		return lpegLiteral(s)
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		if r == '"' {
+			b.WriteString(`\"`)
+		} else {
+			b.WriteString(pegjsEsc(r))
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// abnfLiteral returns an ABNF string, case-sensitive if it has letters,
+// or, if it has characters that cannot be quoted, a %x value.
+func abnfLiteral(s string) string {
+	quotable, letters := true, false
+	for _, r := range s {
+		quotable = quotable && r >= 0x20 && r <= 0x7E && r != '"'
+		letters = letters || unicode.IsLetter(r)
+	}
+	switch {
+	case !quotable:
+		var hex []string
+		for _, r := range s {
+			hex = append(hex, fmt.Sprintf("%X", r))
+		}
+		return "%x" + strings.Join(hex, ".")
+	case letters:
+		return `%s"` + s + `"`
+	default:
+		return `"` + s + `"`
+	}
+}
+
+// lpegLiteral returns an LPeg re pattern matching the string.
+// re strings have no escapes,
+// so a string containing both quotes is split,
+// and newlines are matched by %nl.
+func lpegLiteral(s string) string {
+	if s == "" {
+		return "''"
+	}
+	var parts []string
+	var cur []rune
+	quote := rune(0)
+	flush := func() {
+		if len(cur) > 0 {
+			q := string(quote)
+			if quote == 0 {
+				q = "'"
+			}
+			parts = append(parts, q+string(cur)+q)
+		}
+		cur, quote = cur[:0], 0
+	}
+	for _, r := range s {
+		switch {
+		case r == '\n':
+			flush()
+			parts = append(parts, "%nl")
+		case r == '\'' || r == '"':
+			other := '"'
+			if r == '"' {
+				other = '\''
+			}
+			if quote == r {
+				flush()
+			}
+			quote = other
+			cur = append(cur, r)
+		default:
+			cur = append(cur, r)
+		}
+	}
+	flush()
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+// charClass returns the dialect's expression matching the character class.
+func (x *exporter) charClass(e *CharClass) string {
+	switch x.dialect {
+	case "abnf":
+		spans := e.Spans
+		if e.Neg {
+			spans = complementSpans(spans)
+		}
+		if len(spans) == 0 {
+			x.note("dropped character class %s, which matches nothing", e)
+			return x.empty()
+		}
+		var alts []string
+		for _, sp := range spans {
+			alt := fmt.Sprintf("%%x%X", sp[0])
+			if sp[0] != sp[1] {
+				alt += fmt.Sprintf("-%X", sp[1])
+			}
+			alts = append(alts, alt)
+		}
+		if len(alts) == 1 {
+			return alts[0]
+		}
+		return "(" + strings.Join(alts, " / ") + ")"
+	case "lpeg":
+		return x.lpegCharClass(e)
+	}
+	s := "["
+	if e.Neg {
+		s += "^"
+	}
+	for _, sp := range e.Spans {
+		s += pegjsClassEsc(sp[0])
+		if sp[0] != sp[1] {
+			s += "-" + pegjsClassEsc(sp[1])
+		}
+	}
+	return s + "]"
+}
+
+// complementSpans returns the spans of the runes not in spans.
+func complementSpans(spans [][2]rune) [][2]rune {
+	var comp [][2]rune
+	next := rune(0)
+	for _, sp := range sortSpans(spans) {
+		if sp[0] > next {
+			comp = append(comp, [2]rune{next, sp[0] - 1})
+		}
+		if sp[1]+1 > next {
+			next = sp[1] + 1
+		}
+	}
+	if next <= unicode.MaxRune {
+		comp = append(comp, [2]rune{next, unicode.MaxRune})
+	}
+	return comp
+}
+
+func sortSpans(spans [][2]rune) [][2]rune {
+	sorted := append([][2]rune{}, spans...)
+	for i := 1; i < len(sorted); i++ {
+		for j := i; j > 0 && sorted[j][0] < sorted[j-1][0]; j-- {
+			sorted[j], sorted[j-1] = sorted[j-1], sorted[j]
+		}
+	}
+	return sorted
+}
+
+// lpegCharClass returns an LPeg re pattern matching the character class.
+// re classes match bytes, and have no escapes:
+// ] must be first, - last, and ^ not first.
+// Non-ASCII runes are matched by literals, outside of the class,
+// and non-ASCII ranges are dropped.
+func (x *exporter) lpegCharClass(e *CharClass) string {
+	var items, runes []string
+	var close, dash, caret bool
+	for _, sp := range e.Spans {
+		switch {
+		case sp[1] > unicode.MaxASCII && sp[0] == sp[1]:
+			runes = append(runes, lpegLiteral(string(sp[0])))
+			continue
+		case sp[1] > unicode.MaxASCII:
+			x.note("dropped non-ASCII range %q-%q of character class %s", sp[0], sp[1], e)
+			if sp[0] > unicode.MaxASCII {
+				continue
+			}
+			sp[1] = unicode.MaxASCII
+		}
+		switch {
+		case sp[0] == sp[1] && sp[0] == ']':
+			close = true
+		case sp[0] == sp[1] && sp[0] == '-':
+			dash = true
+		case sp[0] == sp[1] && sp[0] == '^':
+			caret = true
+		case sp[0] == sp[1] && sp[0] == '%':
+			items = append(items, "%-%")
+		case sp[0] == sp[1]:
+			items = append(items, string(sp[0]))
+		default:
+			items = append(items, string(sp[0])+"-"+string(sp[1]))
+		}
+	}
+	if close {
+		items = append([]string{"]"}, items...)
+	}
+	switch {
+	case caret && len(items) == 0 && !dash:
+		items = nil
+		runes = append(runes, "'^'")
+	case caret && len(items) == 0:
+		items = []string{"-", "^"}
+	case caret && dash:
+		items = append(items, "^", "-")
+	case caret:
+		items = append(items, "^")
+	case dash:
+		items = append(items, "-")
+	}
+	var class string
+	switch {
+	case len(items) > 0 && e.Neg:
+		class = "[^" + strings.Join(items, "") + "]"
+	case len(items) > 0:
+		class = "[" + strings.Join(items, "") + "]"
+	}
+	if len(runes) == 0 {
+		if class == "" {
+			// All spans were dropped; !'' matches nothing.
+			return "!''"
+		}
+		return class
+	}
+	if e.Neg {
+		if class == "" {
+			class = "."
+		}
+		return "(!(" + strings.Join(runes, " / ") + ") " + class + ")"
+	}
+	if class != "" {
+		runes = append([]string{class}, runes...)
+	}
+	if len(runes) == 1 {
+		return runes[0]
+	}
+	return "(" + strings.Join(runes, " / ") + ")"
+}
+
+// pegjsEsc returns the JavaScript string escape of the rune, if needed.
+func pegjsEsc(r rune) string {
+	switch r {
+	case '\\':
+		return `\\`
+	case '\n':
+		return `\n`
+	case '\r':
+		return `\r`
+	case '\t':
+		return `\t`
+	}
+	if unicode.IsGraphic(r) {
+		return string(r)
+	}
+	if r > 0xFFFF {
+		r1, r2 := utf16.EncodeRune(r)
+		return fmt.Sprintf(`\u%04X\u%04X`, r1, r2)
+	}
+	return fmt.Sprintf(`\u%04X`, r)
+}
+
+func pegjsClassEsc(r rune) string {
+	switch r {
+	case ']', '^', '-':
+		return `\` + string(r)
+	}
+	return pegjsEsc(r)
+}
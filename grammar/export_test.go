@@ -0,0 +1,154 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	const grammar = `A "a list" <- "[" (x:Num ("," Num)*)? "]" !. { return "" }
+Num <- $[0-9_]+ / Word - "if" / &{ true } "x" / "a\n'b\"" / [^a\]\-]
+Word <- [a-zé]+ | "_" List<Digit>
+Digit <- [0-9]
+List<E> <- E+`
+	tests := []struct {
+		name string
+		to   string
+		in   string
+		want string
+		// dropped are the messages of the dropped code predicates.
+		dropped []string
+		err     string
+	}{
+		{
+			name: "abnf",
+			to:   "abnf",
+			in:   grammar,
+			want: `; ABNF alternatives are unordered, unlike the choices of the PEG this was exported from.
+; dropped predicate !.
+A = "[" [Num *("," Num)] "]" ""
+; dropped the subtrahend of difference Word - "if"
+; replaced code predicate &{…} with an expression that never matches
+Num = 1*(%x30-39 / %x5F) / Word / <code predicate> %s"x" / %x61.A.27.62.22 / (%x0-2C / %x2E-5C / %x5E-60 / %x62-10FFFF)
+Word = 1*(%x61-7A / %xE9) / "_" List--Digit
+Digit = %x30-39
+List--Digit = 1*Digit
+`,
+			dropped: []string{"test.file:2.33,2.42: replaced code predicate &{…} with an expression that never matches"},
+		},
+		{
+			name: "pegjs",
+			to:   "pegjs",
+			in:   grammar,
+			want: `A "a list" = "[" (x:Num ("," Num)*)? "]" !.
+// approximated difference Word - "if" as !Sub Expr
+// replaced code predicate &{…} with an expression that never matches
+Num = $[0-9_]+ / !"if" Word / !"" "x" / "a\n'b\"" / [^a\]\-]
+// approximated longest choice [a-zé]+|"_" List<Digit> as an ordered choice
+Word = [a-zé]+ / "_" List__Digit
+Digit = [0-9]
+List__Digit = Digit+
+`,
+			dropped: []string{"test.file:2.33,2.42: replaced code predicate &{…} with an expression that never matches"},
+		},
+		{
+			name: "lpeg",
+			to:   "lpeg",
+			in:   grammar,
+			want: `-- LPeg matches bytes, but the PEG this was exported from matched runes.
+A <- '[' (Num (',' Num)*)? ']' !.
+-- approximated difference Word - "if" as !Sub Expr
+-- replaced code predicate &{…} with an expression that never matches
+Num <- { [0-9_]+ } / !'if' Word / !'' 'x' / ('a' %nl "'b" '"') / [^]a-]
+-- approximated longest choice [a-zé]+|"_" List<Digit> as an ordered choice
+Word <- ([a-z] / 'é')+ / '_' List__Digit
+Digit <- [0-9]
+List__Digit <- Digit+
+`,
+			dropped: []string{"test.file:2.33,2.42: replaced code predicate &{…} with an expression that never matches"},
+		},
+		{
+			name: "pegjs code predicate",
+			to:   "pegjs",
+			in:   `_ "space" <- ( s:. &{ isSpace(s) } )*`,
+			want: `// replaced code predicate &{…} with an expression that never matches
+_ "space" = (s:. !"")*
+`,
+			dropped: []string{"test.file:1.20,1.35: replaced code predicate &{…} with an expression that never matches"},
+		},
+		{
+			name: "lpeg character classes",
+			to:   "lpeg",
+			in:   `A <- [\^] [\^\-] [a\^] [^%] [é-ü]`,
+			want: `-- LPeg matches bytes, but the PEG this was exported from matched runes.
+-- dropped non-ASCII range 'é'-'ü' of character class [é-ü]
+A <- '^' [-^] [a^] [^%-%] !''
+`,
+		},
+		{
+			name: "pegjs delegate",
+			to:   "pegjs",
+			in:   `A <- "x" %delegate(sql.Parse, "<<", ">>")* { return "" }`,
+			want: `// approximated delegate %delegate(sql.Parse, "<<", ">>") as its delimited region
+A = "x" ("<<" (!">>" .)* ">>")*
+`,
+		},
+		{
+			name: "lpeg native",
+			to:   "lpeg",
+			in:   `A <- "x" %native(scan)`,
+			want: `-- LPeg matches bytes, but the PEG this was exported from matched runes.
+-- dropped native matcher %native(scan)
+A <- 'x' ''
+`,
+		},
+		{
+			name: "bad dialect",
+			to:   "yacc",
+			in:   `A <- "a"`,
+			err:  "^bad export dialect yacc: want abnf, pegjs, or lpeg",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			g, err := Parse(strings.NewReader(test.in), "test.file")
+			if err != nil {
+				t.Fatalf("Parse(%q)=_, %v, want _,nil", test.in, err)
+			}
+			if err := Check(g); err != nil {
+				t.Fatalf("Check(%q)=%v, want nil", test.in, err)
+			}
+			var b strings.Builder
+			dropped, err := Export(&b, test.to, g)
+			if test.err != "" {
+				if err == nil || !regexp.MustCompile(test.err).MatchString(err.Error()) {
+					t.Fatalf("Export(_, %s, %q)=%v, want matching %q", test.to, test.in, err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Export(_, %s, %q)=%v, want nil", test.to, test.in, err)
+			}
+			if b.String() != test.want {
+				t.Errorf("Export(_, %s, %q) wrote\n%s\nwant\n%s", test.to, test.in, b.String(), test.want)
+			}
+			var msgs []string
+			for _, e := range dropped.Errs {
+				msgs = append(msgs, e.Error())
+			}
+			if !reflect.DeepEqual(msgs, test.dropped) {
+				t.Errorf("Export(_, %s, %q) dropped %q, want %q", test.to, test.in, msgs, test.dropped)
+			}
+		})
+	}
+}
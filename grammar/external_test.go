@@ -0,0 +1,146 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+// TestExternalBuild tests building, checking, and generating a grammar
+// from outside of the grammar package, as a tool importing it does.
+func TestExternalBuild(t *testing.T) {
+	digit, err := grammar.NewCharClass(false, [2]rune{'0', '9'})
+	if err != nil {
+		t.Fatalf("NewCharClass(false, [0-9])=_, %v, want _, nil", err)
+	}
+	num, err := grammar.NewRepExpr('+', digit)
+	if err != nil {
+		t.Fatalf("NewRepExpr('+', _)=_, %v, want _, nil", err)
+	}
+	rule, err := grammar.NewRule("Num", num)
+	if err != nil {
+		t.Fatalf("NewRule(Num, _)=_, %v, want _, nil", err)
+	}
+	g, err := grammar.NewGrammar("package calc\n", rule)
+	if err != nil {
+		t.Fatalf("NewGrammar(_, _)=_, %v, want _, nil", err)
+	}
+	if err := grammar.Check(g); err != nil {
+		t.Fatalf("Check(_)=%v, want nil", err)
+	}
+	if got, want := grammar.String(g.Rules), "Num <- [0-9]+"; got != want {
+		t.Errorf("String(_)=%q, want %q", got, want)
+	}
+	var b strings.Builder
+	if err := (grammar.Config{Prefix: "_"}).Generate(&b, "num.peggy", g); err != nil {
+		t.Fatalf("Generate(_, _, _)=%v, want nil", err)
+	}
+	if !strings.Contains(b.String(), "func _NumAccepts(") {
+		t.Errorf("Generate(_, _, _) wrote no _NumAccepts function:\n%s", b.String())
+	}
+}
+
+// TestExternalAnalysis tests reading the results of the Check pass
+// from outside of the grammar package, as a linter importing it does.
+func TestExternalAnalysis(t *testing.T) {
+	g, err := grammar.Parse(strings.NewReader(`A <- x:"a" y:"b"? { return string(x + y) }`), "test.peggy")
+	if err != nil {
+		t.Fatalf("Parse(_, _)=_, %v, want _, nil", err)
+	}
+	if err := grammar.Check(g); err != nil {
+		t.Fatalf("Check(_)=%v, want nil", err)
+	}
+	a := g.CheckedRules[0]
+	if got, want := a.Type(), "string"; got != want {
+		t.Errorf("A.Type()=%q, want %q", got, want)
+	}
+	if a.Epsilon() {
+		t.Errorf("A.Epsilon()=true, want false")
+	}
+	var labels []string
+	for _, l := range a.Labels {
+		labels = append(labels, l.Label.String())
+	}
+	if got, want := strings.Join(labels, " "), "x y"; got != want {
+		t.Errorf("A.Labels=[%s], want [%s]", got, want)
+	}
+
+	g, err = grammar.Parse(strings.NewReader("A <- B \"a\"\nB <- C \"b\" / \"\"\nC <- B \"c\""), "test.peggy")
+	if err != nil {
+		t.Fatalf("Parse(_, _)=_, %v, want _, nil", err)
+	}
+	if err := grammar.Check(g); err == nil {
+		t.Fatalf("Check(_)=nil, want a left-recursion error")
+	}
+	var cycles []string
+	for _, cycle := range g.LeftRecursion {
+		var names []string
+		for _, r := range cycle {
+			names = append(names, r.Name.String())
+		}
+		cycles = append(cycles, strings.Join(names, ", "))
+	}
+	if got, want := strings.Join(cycles, "; "), "B, C, B"; got != want {
+		t.Errorf("LeftRecursion=[%s], want [%s]", got, want)
+	}
+}
+
+// TestExternalEncoding tests encoding a checked grammar
+// with encoding/json and encoding/gob and decoding it
+// from outside of the grammar package, as a cache importing it does.
+func TestExternalEncoding(t *testing.T) {
+	const src = "A <- x:B y:\"a\"? { return string(x + y) }\nB <- [0-9]+ { return string(\"b\") }"
+	g, err := grammar.Parse(strings.NewReader(src), "test.peggy")
+	if err != nil {
+		t.Fatalf("Parse(_, _)=_, %v, want _, nil", err)
+	}
+	if err := grammar.Check(g); err != nil {
+		t.Fatalf("Check(_)=%v, want nil", err)
+	}
+
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatalf("json.Marshal(_)=_, %v, want _, nil", err)
+	}
+	var fromJSON grammar.Grammar
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatalf("json.Unmarshal(_, _)=%v, want nil", err)
+	}
+
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(g); err != nil {
+		t.Fatalf("gob Encode(_)=%v, want nil", err)
+	}
+	var fromGob grammar.Grammar
+	if err := gob.NewDecoder(&b).Decode(&fromGob); err != nil {
+		t.Fatalf("gob Decode(_)=%v, want nil", err)
+	}
+
+	for _, test := range []struct {
+		name string
+		g    *grammar.Grammar
+	}{
+		{"json", &fromJSON},
+		{"gob", &fromGob},
+	} {
+		if got, want := grammar.String(test.g.Rules), grammar.String(g.Rules); got != want {
+			t.Errorf("%s: decoded rules %q, want %q", test.name, got, want)
+		}
+		if len(test.g.CheckedRules) != 2 {
+			t.Fatalf("%s: decoded %d CheckedRules, want 2", test.name, len(test.g.CheckedRules))
+		}
+		if got, want := test.g.CheckedRules[0].Type(), "string"; got != want {
+			t.Errorf("%s: decoded A.Type()=%q, want %q", test.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,3398 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"hash/fnv"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Generate generates a parser for the rules,
+// using a default Config:
+//
+//	Config{Prefix: "_"}
+func Generate(w io.Writer, file string, grammar *Grammar) error {
+	return Config{Prefix: "_"}.Generate(w, file, grammar)
+}
+
+// A Config specifies code generation options,
+// along with the options of parsing and checking the grammar,
+// used by its Parse and Check methods.
+type Config struct {
+	Prefix string
+
+	// NoActions indicates to not generate the Action functions,
+	// computing the values of the rules,
+	// and for Check to not check the types of the values.
+	NoActions bool
+
+	// NoParseTree indicates to not generate the Node functions,
+	// building the parse trees of the rules.
+	NoParseTree bool
+
+	// Memo is the layout of the generated parser's memo table.
+	Memo MemoLayout
+
+	// SharedMemo indicates to generate a parser
+	// that can share memo table entries across parses
+	// in a peg.MemoCache, with <Prefix>NewSharedParser.
+	SharedMemo bool
+
+	// Incremental indicates to precede the functions of each rule
+	// with a comment holding a hash of the rule and its dependencies,
+	// and to copy the functions of each rule whose hash is unchanged
+	// from Previous, instead of generating them.
+	Incremental bool
+
+	// Previous is the output of a previous Incremental Generate,
+	// or the empty string.
+	Previous string
+
+	// Cover indicates to instrument the generated parser
+	// to count the acceptances of each rule and choice branch
+	// in a peg.Coverage variable, <Prefix>Coverage.
+	Cover bool
+
+	// CacheSilentFails indicates that a rule first tried
+	// inside a silent expression, & or ! or the right side of -,
+	// memoizes no fail position,
+	// so its fails are not reported when the memoized result
+	// is used later in a non-silent context.
+	// This matches some other PEG parsers, and gives error reports
+	// that never point into text only examined by lookahead,
+	// at the cost of sometimes reporting an earlier, less precise position.
+	CacheSilentFails bool
+
+	// Profile indicates to instrument the generated parser
+	// to record the time of the Accepts pass of each rule,
+	// by the stack of rules being parsed,
+	// in a peg.Profile variable, <Prefix>Profile.
+	Profile bool
+
+	// Trace indicates to instrument the generated parser
+	// to record the rules entered, accepted, and failed
+	// by the Accepts pass, in order,
+	// in a peg.Trace variable, <Prefix>Trace.
+	Trace bool
+
+	// PegImport is the import path of the peg runtime package
+	// used by the generated parser, or the empty string
+	// for DefaultPegImport.
+	// Imports of DefaultPegImport in the grammar's prelude
+	// are changed to import PegImport as peg.
+	PegImport string
+
+	// PegParser indicates to generate ParseNode and ParseValue methods
+	// of the generated Parser, implementing peg.Parser.
+	PegParser bool
+
+	// Errors is the strategy selecting the failure reported
+	// by the generated parse functions when a parse fails.
+	Errors ErrorMode
+
+	// TreeNames is the naming of the expansions of template rules
+	// in the Node and Fail trees of the generated parser.
+	TreeNames TreeNaming
+
+	// Assert indicates to generate compile-time assertions
+	// that the rule constants are exactly 0 through <Prefix>N-1,
+	// in the order of the rules,
+	// and that the Action function of each rule without parameters
+	// has the rule's type,
+	// so generator regressions fail to compile in Peggy's own tests
+	// instead of in users' builds.
+	Assert bool
+
+	// RuleNames indicates to generate a table of the names of the rules,
+	// <Prefix>RuleNames, indexed by rule constant.
+	RuleNames bool
+
+	// Tags are the build tags enabling %if regions of the grammar
+	// parsed by Parse. Spaces around tags and empty tags are ignored.
+	Tags []string
+
+	// AST indicates for Check to add actions building AST struct types,
+	// named with the Prefix, to labeled rules without actions.
+	AST bool
+
+	// OptOK indicates for Check to give optional expressions of non-string types
+	// the type struct{ Value T; OK bool } instead of *T.
+	OptOK bool
+
+	// Tuples indicates for Check to give sequences of differently typed expressions
+	// the type struct{ V0 T0; V1 T1; ... } instead of a type mismatch.
+	Tuples bool
+
+	// WarnUnused indicates for Check to warn of labels unused
+	// by any action or code predicate, and of discarded action values.
+	WarnUnused bool
+
+	// Sandbox indicates for Check to reject action and code predicate code
+	// referring to identifiers of the generated parser, such as parser or pos.
+	Sandbox bool
+
+	// Strict indicates for Check to reject non-final choice branches
+	// that match the empty string and never fail,
+	// and predicates and ? of expressions that can match the empty string.
+	Strict bool
+
+	// Pure indicates for Check to reject !memo actions.
+	Pure bool
+
+	// labels maps each template expansion to the label
+	// naming it in the trees, when TreeNames is LabelNames.
+	labels map[*Rule]string
+
+	// cover maps each choice branch to its index
+	// in the Coverage points, when Cover is set.
+	cover map[Expr]int
+
+	// sourceMap indicates to precede the code of each expression
+	// with a marker comment holding its location,
+	// which GenerateSourceMap removes after recording.
+	sourceMap bool
+}
+
+// DefaultPegImport is the import path of the peg runtime package.
+const DefaultPegImport = "github.com/eaburns/peggy/peg"
+
+// PegImportPath returns the import path of the peg runtime package.
+func (c Config) PegImportPath() string {
+	if c.PegImport == "" {
+		return DefaultPegImport
+	}
+	return c.PegImport
+}
+
+// A MemoLayout is a data layout of a generated parser's memo table,
+// which holds, for each rule and position,
+// the result of the accepts pass.
+type MemoLayout int
+
+const (
+	// RowMajor lays out the memo table as a slice,
+	// indexed by position, of arrays indexed by rule.
+	// It is compact for grammars with few rules,
+	// but for wide grammars, each row is large and sparsely used.
+	RowMajor MemoLayout = iota
+
+	// ColumnMajor lays out the memo table as an array,
+	// indexed by rule, of slices indexed by position.
+	// Each rule's slice is only allocated if the rule is used.
+	ColumnMajor
+
+	// SparseMemo lays out the memo table as a map,
+	// keyed by rule and position.
+	// It uses memory only for the rules and positions that are used,
+	// but each access is more expensive.
+	SparseMemo
+)
+
+// An ErrorMode is a strategy selecting the failure
+// reported by the generated parse functions when a parse fails.
+type ErrorMode int
+
+const (
+	// FurthestErrors reports the failures at the furthest position
+	// examined by the parse.
+	FurthestErrors ErrorMode = iota
+
+	// CutErrors reports the first committed failure,
+	// the failure of a choice branch after a cut, ^,
+	// at the furthest position examined by the branch.
+	// If there is no committed failure,
+	// it reports the failures at the furthest position.
+	// The Accepts pass records the first committed failure
+	// in the generated parser, returned by <Prefix>Committed.
+	CutErrors
+
+	// MergedErrors reports the first committed failure, like CutErrors,
+	// and, if the furthest position is after it,
+	// also the failures at the furthest position.
+	MergedErrors
+)
+
+// A TreeNaming is a naming of the expansions of template rules
+// in the Node and Fail trees of a generated parser.
+type TreeNaming int
+
+const (
+	// InstanceNames names an expansion by the template
+	// and its arguments, for example, List<Expr>.
+	InstanceNames TreeNaming = iota
+
+	// TemplateNames names an expansion by the template alone,
+	// for example, List.
+	TemplateNames
+
+	// LabelNames names an expansion by the label
+	// of its invocations, if they are all labeled the same,
+	// for example, args for args:List<Expr>,
+	// and otherwise by the template alone.
+	LabelNames
+)
+
+// treeName returns the name of the rule in Node and Fail trees.
+func (c Config) treeName(r *Rule) string {
+	if len(r.Args) == 0 {
+		return r.Name.String()
+	}
+	switch c.TreeNames {
+	case TemplateNames:
+		return r.Name.Name.String()
+	case LabelNames:
+		if l, ok := c.labels[r]; ok {
+			return l
+		}
+		return r.Name.Name.String()
+	}
+	return r.Name.String()
+}
+
+// templateLabels returns the label of each template expansion
+// whose invocations are all labeled the same.
+func templateLabels(gr *Grammar) map[*Rule]string {
+	labels := make(map[*Rule]string)
+	mixed := make(map[*Rule]bool)
+	invokLabels := make(map[*Ident]string)
+	for _, r := range gr.CheckedRules {
+		r.Expr.Walk(func(e Expr) bool {
+			switch e := e.(type) {
+			case *LabelExpr:
+				if id, ok := e.Expr.(*Ident); ok {
+					invokLabels[id] = e.Label.String()
+				}
+			case *Ident:
+				if e.rule == nil || len(e.rule.Args) == 0 {
+					break
+				}
+				l, ok := invokLabels[e]
+				if prev, seen := labels[e.rule]; !ok || seen && prev != l {
+					mixed[e.rule] = true
+				}
+				labels[e.rule] = l
+			}
+			return true
+		})
+	}
+	for r := range mixed {
+		delete(labels, r)
+	}
+	return labels
+}
+
+// Generate generates a parser for the rules.
+func (c Config) Generate(w io.Writer, file string, gr *Grammar) error {
+	if c.SharedMemo && len(recoveringRules(gr)) > 0 {
+		return errors.New("a shared memo cannot be used with @recoveruntil or @recoverpast")
+	}
+	if err := c.CheckPegImportPath(); err != nil {
+		return err
+	}
+	var points []coverPoint
+	if c.Cover {
+		points, c.cover = coverPoints(gr)
+	}
+	if c.TreeNames == LabelNames {
+		c.labels = templateLabels(gr)
+	}
+	b := bytes.NewBuffer(nil)
+	if err := writePrelude(b, c, gr); err != nil {
+		return err
+	}
+	// generated are the byte ranges of b written by the templates,
+	// as opposed to the code of the grammar or of a previous output.
+	var generated [][2]int
+	start := b.Len()
+	if err := writeDecls(b, c, gr, points); err != nil {
+		return err
+	}
+	generated = append(generated, [2]int{start, b.Len()})
+	var prev map[string]string
+	// The marker lines of a source map are not in the previous output,
+	// so with a source map, every rule is generated.
+	if c.Incremental && !c.sourceMap {
+		prev = ruleSegments(c.Previous)
+	}
+	for _, r := range gr.CheckedRules {
+		if c.Incremental {
+			marker := ruleMarker + r.Name.Ident() + " " + ruleHash(c, gr, r) + "\n"
+			if seg, ok := prev[marker]; ok {
+				b.WriteString(seg)
+				continue
+			}
+			b.WriteString(marker)
+		}
+		for _, code := range r.Code {
+			if _, err := io.WriteString(b, code.String()+"\n"); err != nil {
+				return err
+			}
+		}
+		start := b.Len()
+		if err := writeRule(b, c, gr, r); err != nil {
+			return err
+		}
+		generated = append(generated, [2]int{start, b.Len()})
+	}
+	return gofmt(w, b.String(), c.Prefix, generated, gr)
+}
+
+// ruleMarker begins the comment preceding the functions of each rule
+// in Incremental output.
+// It is followed by the rule's identifier and hash.
+const ruleMarker = "// peggy:rule "
+
+// ruleSegments returns the code of each rule of Incremental output,
+// keyed by its marker line, which begins the code.
+// The code of a rule extends to the next marker or the end of the output.
+func ruleSegments(out string) map[string]string {
+	segs := make(map[string]string)
+	var marker string
+	var seg strings.Builder
+	for _, line := range strings.SplitAfter(out, "\n") {
+		if strings.HasPrefix(line, ruleMarker) {
+			if marker != "" {
+				segs[marker] = seg.String()
+			}
+			marker = line
+			seg.Reset()
+		}
+		if marker != "" {
+			seg.WriteString(line)
+		}
+	}
+	if marker != "" {
+		segs[marker] = seg.String()
+	}
+	return segs
+}
+
+// generatorVersion is included in the hash of each rule,
+// so Incremental output of an earlier generator is not reused.
+// It must be incremented by a change to the generator, other than to its templates,
+// that changes the generated code of a rule.
+const generatorVersion = 1
+
+// templatesHash is a hash of the templates of the generated code,
+// included in the hash of each rule,
+// so a change to the templates regenerates every rule.
+var templatesHash = func() uint64 {
+	h := fnv.New64a()
+	io.WriteString(h, declsTemplate)
+	io.WriteString(h, ruleTemplate)
+	for _, ts := range append(ruleTemplates, globalTemplates...) {
+		io.WriteString(h, ts[1])
+	}
+	var exprTemplates []string
+	for _, t := range templates {
+		exprTemplates = append(exprTemplates, t)
+	}
+	sort.Strings(exprTemplates)
+	for _, t := range exprTemplates {
+		io.WriteString(h, t)
+	}
+	return h.Sum64()
+}()
+
+// ruleHash returns a hash of everything that determines
+// the generated code of the rule:
+// the generator, the configuration, including that of Check,
+// the grammar's directives,
+// and the rule and the rules it references, transitively.
+// With Cover, the code also depends on the choices of preceding rules,
+// so each rule is hashed with all rules of the grammar.
+func ruleHash(c Config, gr *Grammar, r *Rule) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d %x\n", generatorVersion, templatesHash)
+	fmt.Fprintf(h, "%s %d %v %v %v %v %v %d %v %v\n", c.Prefix, c.Memo, c.SharedMemo, c.Cover,
+		c.CacheSilentFails, c.Profile, c.Trace, c.Errors, c.NoActions, c.NoParseTree)
+	fmt.Fprintf(h, "%v %q %v %d\n", c.PegParser, c.PegImportPath(), c.Assert, c.TreeNames)
+	fmt.Fprintf(h, "%v %v %v %v %v\n", c.AST, c.OptOK, c.Tuples, c.Sandbox, c.Pure)
+	fmt.Fprintf(h, "%d %v %s %d\n", gr.MaxDepth, gr.InvalidBytes, textString(gr.Newline), gr.WarnSlow)
+	seen := make(map[*Rule]bool)
+	var add func(*Rule)
+	add = func(r *Rule) {
+		if seen[r] {
+			return
+		}
+		seen[r] = true
+		fmt.Fprintf(h, "%s %q %q%s <- %s : %s\n", r.Name, c.treeName(r),
+			textString(r.ErrorName), r.HeaderString(), r.Expr.fullString(), r.Type())
+		for _, code := range r.Code {
+			io.WriteString(h, code.String()+"\n")
+		}
+		r.Expr.Walk(func(e Expr) bool {
+			if id, ok := e.(*Ident); ok && id.Rule() != nil {
+				add(id.Rule())
+			}
+			return true
+		})
+	}
+	add(r)
+	if c.Cover {
+		for _, r := range gr.CheckedRules {
+			add(r)
+		}
+	}
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// gofmt writes the generated source formatted,
+// with the dead code removed from the functions
+// declared within the generated byte ranges of the source.
+func gofmt(w io.Writer, s, prefix string, generated [][2]int, gr *Grammar) error {
+	fset := token.NewFileSet()
+	root, err := parser.ParseFile(fset, "", s, parser.ParseComments)
+	if err != nil {
+		io.WriteString(w, s)
+		return err
+	}
+	if gr != nil {
+		if err := checkCollisions(root, prefix, gr); err != nil {
+			return err
+		}
+	}
+	funcs := make(map[*ast.FuncDecl]bool)
+	file := fset.File(root.Pos())
+	for _, d := range root.Decls {
+		fun, ok := d.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		off := file.Offset(fun.Pos())
+		for _, g := range generated {
+			if g[0] <= off && off < g[1] {
+				funcs[fun] = true
+				break
+			}
+		}
+	}
+	removeDeadCode(fset, root, funcs)
+	if err := format.Node(w, fset, root); err != nil {
+		io.WriteString(w, s)
+		return err
+	}
+	return nil
+}
+
+// checkCollisions returns an error if a top-level identifier
+// of the generated file is declared more than once
+// because of the name of a rule, such as a rule named N,
+// whose constant collides with the <prefix>N rule count,
+// or rules A and AAction, whose functions and constants collide.
+// The error is attributed to the rule with the longest identifier
+// beginning the colliding name.
+// Collisions not attributed to a rule are left to the compiler.
+func checkCollisions(root *ast.File, prefix string, gr *Grammar) error {
+	seen := make(map[string]bool)
+	declare := func(id *ast.Ident) error {
+		if id.Name == "_" || id.Name == "init" {
+			return nil
+		}
+		if !seen[id.Name] {
+			seen[id.Name] = true
+			return nil
+		}
+		var rule *Rule
+		for _, r := range gr.CheckedRules {
+			ident := prefix + r.Name.Ident()
+			if strings.HasPrefix(id.Name, ident) &&
+				(rule == nil || len(ident) > len(prefix+rule.Name.Ident())) {
+				rule = r
+			}
+		}
+		if rule == nil {
+			return nil
+		}
+		return Err(rule, "rule %s: the generated identifier %s is declared more than once; rename the rule", rule.Name, id.Name)
+	}
+	for _, decl := range root.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv != nil {
+				continue
+			}
+			if err := declare(decl.Name); err != nil {
+				return err
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.ValueSpec:
+					for _, id := range spec.Names {
+						if err := declare(id); err != nil {
+							return err
+						}
+					}
+				case *ast.TypeSpec:
+					if err := declare(spec.Name); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func writePrelude(w io.Writer, c Config, gr *Grammar) error {
+	if gr.Prelude == nil {
+		return nil
+	}
+	_, err := io.WriteString(w, renamePegImport(gr.Prelude.String(), c.PegImportPath()))
+	return err
+}
+
+// CheckPegImportPath returns an error
+// if the PegImportPath is not a valid import path.
+func (c Config) CheckPegImportPath() error {
+	return checkImportPath(c.PegImportPath())
+}
+
+// checkImportPath returns an error if the path is not a valid import path:
+// a non-empty, slash-separated sequence of non-empty elements
+// of letters, digits, and the characters -._~+,
+// none of which begins with a dot.
+func checkImportPath(path string) error {
+	for _, elem := range strings.Split(path, "/") {
+		if elem == "" || elem[0] == '.' {
+			return errors.New("bad peg import path " + strconv.Quote(path))
+		}
+		for _, r := range elem {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-._~+", r) {
+				return errors.New("bad peg import path " + strconv.Quote(path))
+			}
+		}
+	}
+	return nil
+}
+
+// renamePegImport returns the prelude with each import of DefaultPegImport
+// changed to import path, named peg if the path's last element is not peg.
+// If the imports of the prelude do not parse,
+// the prelude is returned unchanged,
+// leaving the error to be reported with the generated code.
+func renamePegImport(prelude, path string) string {
+	if path == DefaultPegImport {
+		return prelude
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", prelude, parser.ImportsOnly)
+	if err != nil {
+		return prelude
+	}
+	var b strings.Builder
+	var prev int
+	for _, imp := range file.Imports {
+		if p, err := strconv.Unquote(imp.Path.Value); err != nil || p != DefaultPegImport {
+			continue
+		}
+		start := fset.Position(imp.Path.Pos()).Offset
+		b.WriteString(prelude[prev:start])
+		if imp.Name == nil && path[strings.LastIndex(path, "/")+1:] != "peg" {
+			b.WriteString("peg ")
+		}
+		b.WriteString(strconv.Quote(path))
+		prev = fset.Position(imp.Path.End()).Offset
+	}
+	b.WriteString(prelude[prev:])
+	return b.String()
+}
+
+func writeDecls(w io.Writer, c Config, gr *Grammar, points []coverPoint) error {
+	tmp, err := template.New("Decls").Funcs(map[string]interface{}{
+		"quote":    strconv.Quote,
+		"treeName": c.treeName,
+	}).Parse(declsTemplate)
+	if err != nil {
+		return err
+	}
+	var id string
+	if c.SharedMemo {
+		id = grammarID(gr)
+	}
+	var ruleDepth, metadata bool
+	slow := gr.WarnSlow > 0
+	for _, r := range gr.CheckedRules {
+		ruleDepth = ruleDepth || r.MaxDepth > 0
+		metadata = metadata || len(r.Metadata) > 0
+		slow = slow || r.WarnSlow > 0
+	}
+	return tmp.Execute(w, map[string]interface{}{
+		"Config":     c,
+		"Grammar":    gr,
+		"RuleDepth":  ruleDepth,
+		"Depths":     gr.hasMaxDepth(),
+		"RuleNames":  c.RuleNames,
+		"Metadata":   metadata,
+		"Slow":       slow,
+		"GenActions": !c.NoActions,
+		"ParseTree":  !c.NoParseTree,
+		"RowMajor":   c.Memo == RowMajor,
+		"Column":     c.Memo == ColumnMajor,
+		"Sparse":     c.Memo == SparseMemo,
+		"Roots":      rootRules(gr),
+		"Cover":      points,
+		"GrammarID":  id,
+		"Newline":    gr.pegNewline().Const,
+		"Recovering": recoveringRules(gr),
+		"Committed":  c.Errors != FurthestErrors,
+		"Merged":     c.Errors == MergedErrors,
+	})
+}
+
+// recoveringRules returns the rules that recover from failures.
+func recoveringRules(gr *Grammar) []*Rule {
+	var rs []*Rule
+	for _, r := range gr.CheckedRules {
+		if r.Recovers() {
+			rs = append(rs, r)
+		}
+	}
+	return rs
+}
+
+// hasMaxDepth returns whether the grammar limits the depth of any rule.
+func (gr *Grammar) hasMaxDepth() bool {
+	if gr.MaxDepth > 0 {
+		return true
+	}
+	for _, r := range gr.CheckedRules {
+		if r.MaxDepth > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// grammarID returns a string identifying the grammar's rules,
+// which keys the entries of a shared memo cache.
+func grammarID(gr *Grammar) string {
+	h := fnv.New64a()
+	for _, r := range gr.CheckedRules {
+		io.WriteString(h, r.Name.String()+" <- "+r.Expr.fullString()+"\n")
+	}
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// rootRules returns the rules without parameters
+// that are not referenced by any other rule.
+func rootRules(gr *Grammar) []*Rule {
+	referenced := make(map[*Rule]bool)
+	for _, r := range gr.CheckedRules {
+		r.Expr.Walk(func(e Expr) bool {
+			if id, ok := e.(*Ident); ok && id.Rule() != r {
+				referenced[id.Rule()] = true
+			}
+			return true
+		})
+	}
+	var roots []*Rule
+	for _, r := range gr.CheckedRules {
+		if r.Params == nil && !referenced[r] {
+			roots = append(roots, r)
+		}
+	}
+	return roots
+}
+
+// predLabels returns the labels of the rule, in order,
+// that are in scope of a code predicate,
+// which reads their text from the labels array.
+// The labels array has an entry for each, in order,
+// and no pass sets the text of the other labels.
+// The text of a label of a predicate is always empty,
+// so it is never read from the labels array.
+func predLabels(r *Rule) []*LabelExpr {
+	read := make(map[*LabelExpr]bool)
+	r.Expr.Walk(func(e Expr) bool {
+		if p, ok := e.(*PredCode); ok {
+			for _, l := range p.Labels {
+				read[l] = !isPredicate(l.Expr)
+			}
+		}
+		return true
+	})
+	var labels []*LabelExpr
+	for _, l := range r.Labels {
+		if read[l] {
+			labels = append(labels, l)
+		}
+	}
+	return labels
+}
+
+// labelSlot returns the index of the label's entry in the labels array
+// of the rule, or -1 if it has none.
+func labelSlot(r *Rule, l *LabelExpr) int {
+	for i, pl := range predLabels(r) {
+		if pl == l {
+			return i
+		}
+	}
+	return -1
+}
+
+func writeRule(w io.Writer, c Config, gr *Grammar, r *Rule) error {
+	funcs := map[string]interface{}{
+		"gen":        gen,
+		"quote":      strconv.Quote,
+		"quoteList":  quoteList,
+		"predicate":  isPredicate,
+		"predLabels": predLabels,
+		"makeAcceptState": func(r *Rule) state {
+			return state{
+				Config:      c,
+				Grammar:     gr,
+				Rule:        r,
+				n:           new(int),
+				AcceptsPass: true,
+			}
+		},
+		"makeNodeState": func(r *Rule) state {
+			return state{
+				Config:   c,
+				Grammar:  gr,
+				Rule:     r,
+				n:        new(int),
+				NodePass: true,
+			}
+		},
+		"makeFailState": func(r *Rule) state {
+			return state{
+				Config:   c,
+				Grammar:  gr,
+				Rule:     r,
+				n:        new(int),
+				FailPass: true,
+			}
+		},
+		"makeActionState": func(r *Rule) state {
+			return state{
+				Config:     c,
+				Grammar:    gr,
+				Rule:       r,
+				n:          new(int),
+				ActionPass: true,
+			}
+		},
+	}
+	data := map[string]interface{}{
+		"Config":       c,
+		"Grammar":      gr,
+		"Rule":         r,
+		"TreeName":     c.treeName(r),
+		"Depth":        gr.MaxDepth > 0 || r.MaxDepth > 0,
+		"Depths":       gr.hasMaxDepth(),
+		"GenActions":   !c.NoActions,
+		"GenParseTree": !c.NoParseTree,
+	}
+	tmp, err := template.New("rule").Parse(ruleTemplate)
+	if err != nil {
+		return err
+	}
+	for _, ts := range ruleTemplates {
+		name, text := ts[0], ts[1]
+		tmp, err = tmp.New(name).Funcs(funcs).Parse(text)
+		if err != nil {
+			return err
+		}
+	}
+	return tmp.ExecuteTemplate(w, "rule", data)
+}
+
+type state struct {
+	Config
+	Grammar *Grammar
+	Rule    *Rule
+	Expr    Expr
+	Fail    string
+	// Node is the ident into which to assign action-pass value, or "".
+	Node string
+	n    *int
+	// AcceptsPass indicates whether to generate the accepts pass.
+	AcceptsPass bool
+	// NodePass indicates whether to generate the node pass.
+	NodePass bool
+	// FailPass indicates whether to generate the error pass.
+	FailPass bool
+	// ActionPass indicates whether to generate the action pass.
+	ActionPass bool
+	// DryRun indicates that the accepts pass code
+	// is a dry run within the action pass.
+	DryRun bool
+	// Cut is the ident of the boolean set by a cut
+	// committing the branch of the enclosing choice, or "".
+	Cut string
+}
+
+// CoverIndex returns the index of the choice branch
+// in the Coverage points.
+func (s state) CoverIndex(e Expr) int { return s.cover[e] }
+
+// A literalCase is a case of the switch generated for a choice of literals,
+// matching the literals that begin with its byte.
+type literalCase struct {
+	// Byte is the Go expression of the first byte.
+	Byte string
+	// Literals are the literals beginning with Byte,
+	// in the order of their branches of the choice.
+	Literals []literalBranch
+}
+
+// A literalBranch is a literal branch of a choice.
+type literalBranch struct {
+	Text string
+	// First indicates whether the literal is the first branch.
+	First bool
+}
+
+// LiteralCases returns the cases of a switch on the next byte
+// that matches the choice,
+// or nil if the choice must be matched branch by branch,
+// because not all of its branches are literals,
+// or because the pass depends on each branch that fails.
+func (s state) LiteralCases(e *Choice) []literalCase {
+	if s.FailPass || s.Config.Cover || len(e.Exprs) < 2 {
+		return nil
+	}
+	var cases []literalCase
+	index := make(map[byte]int)
+	for i, sub := range e.Exprs {
+		lit, ok := sub.(*Literal)
+		if !ok || len(lit.Text.String()) == 0 {
+			return nil
+		}
+		text := lit.Text.String()
+		j, ok := index[text[0]]
+		if !ok {
+			j = len(cases)
+			index[text[0]] = j
+			b := fmt.Sprintf("%#x", text[0])
+			if text[0] < utf8.RuneSelf && strconv.IsPrint(rune(text[0])) {
+				b = strconv.QuoteRune(rune(text[0]))
+			}
+			cases = append(cases, literalCase{Byte: b})
+		}
+		cases[j].Literals = append(cases[j].Literals, literalBranch{Text: text, First: i == 0})
+	}
+	return cases
+}
+
+// MaxLen returns the length of the longest of the literals of the cases.
+func (s state) MaxLen(cases []literalCase) int {
+	var n int
+	for _, c := range cases {
+		for _, l := range c.Literals {
+			if len(l.Text) > n {
+				n = len(l.Text)
+			}
+		}
+	}
+	return n
+}
+
+// An asciiClass is a character class
+// tested by a bit mask for ASCII runes.
+type asciiClass struct {
+	// Lo and Hi are the Go expressions of the bit masks
+	// of the accepted runes less than 64 and less than 128.
+	Lo, Hi string
+	// Rest is the character class for the non-ASCII runes.
+	Rest *CharClass
+}
+
+// ASCIIClass returns the asciiClass of the character class,
+// or nil if testing its ASCII spans is no more costly
+// than testing the bit mask.
+func (s state) ASCIIClass(e *CharClass) *asciiClass {
+	rest := &CharClass{Neg: e.Neg, Open: e.Open, Close: e.Close}
+	var mask [2]uint64
+	var n int
+	for _, span := range e.Spans {
+		if span[0] < utf8.RuneSelf {
+			n++
+			for r := span[0]; r <= span[1] && r < utf8.RuneSelf; r++ {
+				mask[r/64] |= 1 << uint(r%64)
+			}
+		}
+		if span[1] >= utf8.RuneSelf {
+			if span[0] < utf8.RuneSelf {
+				span[0] = utf8.RuneSelf
+			}
+			rest.Spans = append(rest.Spans, span)
+		}
+	}
+	if n < 2 {
+		return nil
+	}
+	if e.Neg {
+		mask[0], mask[1] = ^mask[0], ^mask[1]
+	}
+	return &asciiClass{
+		Lo:   fmt.Sprintf("%#x", mask[0]),
+		Hi:   fmt.Sprintf("%#x", mask[1]),
+		Rest: rest,
+	}
+}
+
+// WithExpr returns the state with its Expr replaced.
+func (s state) WithExpr(e Expr) state {
+	s.Expr = e
+	return s
+}
+
+// WithCut returns the state with its Cut replaced.
+func (s state) WithCut(cut string) state {
+	s.Cut = cut
+	return s
+}
+
+// Committing returns whether any branch of the choice
+// commits to the branch with a cut, and can then fail,
+// either before the last branch,
+// or in an Accepts pass recording committed failures.
+func (s state) Committing(e *Choice) bool {
+	record := s.Errors != FurthestErrors && s.AcceptsPass && !s.DryRun
+	for i, sub := range e.Exprs {
+		if commits(sub) && sub.CanFail() && (i < len(e.Exprs)-1 || record) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s state) id(str string) string {
+	(*s.n)++
+	return str + strconv.Itoa(*s.n-1)
+}
+
+func gen(parentState state, expr Expr, node, fail string) (string, error) {
+	t := reflect.TypeOf(expr)
+	tmpString, ok := templates[reflect.TypeOf(expr)]
+	if !ok {
+		return "", errors.New("gen not found: " + t.String())
+	}
+	funcs := map[string]interface{}{
+		"quote":     strconv.Quote,
+		"quoteRune": strconv.QuoteRune,
+		"id":        parentState.id,
+		"gen":       gen,
+		"dryRun":    dryRun,
+		"measure":   measure,
+		"effects":   hasEffects,
+		"textual":   isTextual,
+		"commits":   commits,
+		"labelSlot": labelSlot,
+		"predicate": isPredicate,
+		"last":      func(i int, exprs []Expr) bool { return i == len(exprs)-1 },
+	}
+	tmp, err := template.New(t.String()).Funcs(funcs).Parse(tmpString)
+	if err != nil {
+		return "", err
+	}
+	if err := addGlobalTemplates(tmp); err != nil {
+		return "", err
+	}
+	b := bytes.NewBuffer(nil)
+	state := parentState
+	state.Expr = expr
+	state.Fail = fail
+	state.Node = node
+	if state.sourceMap {
+		b.WriteString(sourceMapMarker(expr))
+	}
+	err = tmp.Execute(b, state)
+	return b.String(), err
+}
+
+// dryRun returns the accepts pass code for an expression,
+// to be used in the action pass to test whether an expression accepts
+// before running it, so that its !memo actions are only run
+// if the expression is part of the successful parse.
+// On failure, it jumps to fail; on success, pos is unchanged.
+func dryRun(parentState state, expr Expr, fail string) (string, error) {
+	s := parentState
+	s.ActionPass = false
+	s.AcceptsPass = true
+	s.DryRun = true
+	code, err := gen(s, expr, "", fail)
+	if err != nil {
+		return "", err
+	}
+	return "// dry run\n{\npos, perr := pos, -1\n" + code + "use(perr)\n}\n", nil
+}
+
+// measure returns the accepts pass code for a branch of a LongestChoice,
+// to be used in the node and action passes to find the longest branch
+// without the effects of the pass.
+// If the branch accepts, and its end position is greater than best,
+// the code sets best to the end position and win to the branch number.
+func measure(parentState state, expr Expr, i int, best, win, fail string) (string, error) {
+	s := parentState
+	s.NodePass = false
+	s.ActionPass = false
+	s.AcceptsPass = true
+	s.DryRun = true
+	code, err := gen(s, expr, "", fail)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("// measure\n{\npos, perr := pos, -1\n%s"+
+		"if pos > %s {\n%s, %s = pos, %d\n}\nuse(perr)\n}\n",
+		code, best, best, win, i), nil
+}
+
+var globalTemplates = [][2]string{
+	{"charClassCondition", charClassCondition},
+	{"callTemplate", callTemplate},
+	{"literalChoice", literalChoice},
+	{"textValue", textValue},
+}
+
+func addGlobalTemplates(tmp *template.Template) error {
+	for _, p := range globalTemplates {
+		var err error
+		if tmp, err = tmp.New(p[0]).Parse(p[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A note on formatting in Expr templates
+//
+// gofmt properly fixes any horizontal spacing issues.
+// However, while it eliminates duplicate empty lines,
+// it does not eliminate empty lines.
+// For example, it will convert a sequence of 2 or more empty lines
+// into a single empty line, but it will not remove the empty line.
+// So it's important to handle newlines propertly
+// to maintain a nice, consistent formatting.
+//
+// There are two rules:
+// 	1) Templates must end with a newline, or the codegen will be invalid.
+// 	2) Templates should not begin with an newline, or the codegen will be ugly.
+
+var declsTemplate = `
+	{{$pre := $.Config.Prefix -}}
+
+	const (
+		{{range $r := $.Grammar.CheckedRules -}}
+			{{$pre}}{{$r.Name.Ident}} int = {{$r.N}}
+		{{end}}
+		{{$pre}}N int = {{len $.Grammar.CheckedRules}}
+	)
+
+	{{if $.Config.Assert -}}
+		// Compile-time assertions of the rule constants and types.
+		// An index is out of bounds if a constant is not its rule's number.
+		func _() {
+			var x [1]struct{}
+			{{range $r := $.Grammar.CheckedRules -}}
+				_ = x[{{$pre}}{{$r.Name.Ident}}-{{$r.N}}]
+			{{end -}}
+			_ = x[{{$pre}}N-{{len $.Grammar.CheckedRules}}]
+		}
+
+		{{if $.GenActions -}}
+			{{range $r := $.Grammar.CheckedRules -}}
+				{{if not $r.Params -}}
+					var _ func(*{{$pre}}Parser, int) (int, *{{$r.Type}}) = {{$pre}}{{$r.Name.Ident}}Action
+				{{end -}}
+			{{end}}
+		{{end -}}
+	{{end -}}
+
+	{{if $.RuleNames -}}
+		// {{$pre}}RuleNames are the names of the rules, indexed by rule constant.
+		var {{$pre}}RuleNames = [{{$pre}}N]string{
+			{{range $r := $.Grammar.CheckedRules -}}
+				{{$pre}}{{$r.Name.Ident}}: {{quote $r.Name.String}},
+			{{end -}}
+		}
+	{{end -}}
+
+	{{if $.Metadata -}}
+		// {{$pre}}RuleMetadata maps rule constants
+		// to the metadata annotations of the rule.
+		// Rules with no metadata annotations are not in the map.
+		var {{$pre}}RuleMetadata = map[int]map[string]string{
+			{{range $r := $.Grammar.CheckedRules -}}
+				{{if $r.Metadata -}}
+					{{$pre}}{{$r.Name.Ident}}: {
+						{{range $name, $v := $r.Metadata -}}
+							{{quote $name}}: {{quote $v}},
+						{{end -}}
+					},
+				{{end -}}
+			{{end -}}
+		}
+	{{end -}}
+
+	{{if $.Cover -}}
+		// {{$pre}}Coverage counts the acceptances
+		// of each rule and choice branch of the grammar.
+		// The first {{$pre}}N points are the rules, indexed by rule constant.
+		var {{$pre}}Coverage = &peg.Coverage{
+			Points: []peg.CoverPoint{
+				{{range $p := $.Cover -}}
+					{Loc: {{quote $p.Loc}}, Rule: {{quote $p.Rule}}
+						{{- if $p.Branch}}, Branch: {{$p.Branch}}{{end}}},
+				{{end -}}
+			},
+			Counts: make([]uint64, {{len $.Cover}}),
+		}
+
+	{{end -}}
+
+	{{if $.Config.Profile -}}
+		// {{$pre}}Profile records the time of the Accepts pass
+		// of each rule, by the stack of rules being parsed.
+		var {{$pre}}Profile = &peg.Profile{
+			Rules: []string{
+				{{range $r := $.Grammar.CheckedRules -}}
+					{{$pre}}{{$r.Name.Ident}}: {{quote $r.Name.String}},
+				{{end -}}
+			},
+		}
+
+	{{end -}}
+
+	{{if $.Config.Trace -}}
+		// {{$pre}}Trace records the rules entered, accepted, and failed
+		// by the Accepts pass of the most recently parsed text.
+		var {{$pre}}Trace = &peg.Trace{
+			Rules: []string{
+				{{range $r := $.Grammar.CheckedRules -}}
+					{{$pre}}{{$r.Name.Ident}}: {{quote $r.Name.String}},
+				{{end -}}
+			},
+		}
+
+	{{end -}}
+
+	{{range $r := $.Grammar.CheckedRules -}}
+		{{if $r.AST -}}
+			// {{$pre}}{{$r.Name.Ident}}AST is the abstract syntax tree of rule {{$r.Name}}.
+			type {{$pre}}{{$r.Name.Ident}}AST struct {
+				// Begin and End are the byte offsets
+				// of the start and end of the rule's match.
+				Begin, End int
+				{{range $l := $r.AST -}}
+					{{$l.Label}} {{$l.Type}}
+				{{end -}}
+			}
+
+		{{end -}}
+	{{end -}}
+
+	type {{$pre}}Parser struct {
+		text string
+		{{if $.RowMajor -}}
+			deltaPos [][{{$pre}}N]int32
+			deltaErr [][{{$pre}}N]int32
+		{{else if $.Column -}}
+			deltaPos [{{$pre}}N][]int32
+			deltaErr [{{$pre}}N][]int32
+		{{else if $.Sparse -}}
+			memo map[{{$pre}}key][2]int32
+		{{end -}}
+		node map[{{$pre}}key]*peg.Node
+		fail map[{{$pre}}key]*peg.Fail
+		{{if $.GenActions -}}
+			{{range $r := $.Grammar.CheckedRules -}}
+				{{if not $r.Params -}}
+					act{{$r.Name.Ident}} map[int]{{$r.Type}}
+				{{end -}}
+			{{end -}}
+		{{end -}}
+		lastFail int
+		{{if $.Committed -}}
+			// committed is the byte offset, plus 1,
+			// of the first committed failure, or 0 if none.
+			committed int
+		{{end -}}
+		{{if $.Recovering -}}
+			recoveries []{{$pre}}recovery
+		{{end -}}
+		{{if $.GenActions -}}
+			// Checks records the failed Expect
+			// and the diagnostics of the actions.
+			peg.Checks
+		{{end -}}
+		// failBudget is the number of rule nodes
+		// that the Fail pass may yet build, if boundFails.
+		failBudget int
+		boundFails bool
+		{{if $.Config.CacheSilentFails -}}
+			// silent is the depth of silent expressions
+			// enclosing the Accepts pass.
+			silent int
+		{{end -}}
+		{{if $.Config.SharedMemo -}}
+			shared *peg.MemoCache
+			prefix peg.PrefixHash
+			// extent is the number of bytes at the beginning of text
+			// examined by the rule being parsed,
+			// and extents are those of the memoized rules.
+			extent int
+			extents map[{{$pre}}key]int
+		{{end -}}
+		{{if $.Grammar.MaxDepth -}}
+			depth int
+		{{end -}}
+		{{if $.RuleDepth -}}
+			ruleDepth [{{$pre}}N]int
+		{{end -}}
+		{{if $.Depths -}}
+			// depthFails is the number of rule invocations
+			// that failed by exceeding a maximum depth.
+			depthFails int
+		{{end -}}
+		{{if $.Slow -}}
+			slow []peg.SlowParse
+		{{end -}}
+		data interface{}
+	}
+
+	{{if $.Grammar.Newline -}}
+		// {{$pre}}Newline specifies the line terminators of the input,
+		// for computing error locations, as set by %newline.
+		const {{$pre}}Newline = peg.{{$.Newline}}
+
+	{{end -}}
+
+	type {{$pre}}key struct {
+		start int
+		rule int
+	}
+
+	type tooBigError struct{}
+	func (tooBigError) Error() string { return "input is too big" }
+
+	// {{$pre}}NewParser returns a new Parser for the text.
+	// Only the memo table used by every pass is allocated here;
+	// the tables of the Node, Fail, and Action passes
+	// are allocated when the pass is first run.
+	func {{$pre}}NewParser(text string) (*{{$pre}}Parser, error) {
+		n := len(text)+1
+		if n < 0 {
+			return nil, tooBigError{}
+		}
+		p := &{{$pre}}Parser{
+			text: text,
+			{{if $.RowMajor -}}
+				deltaPos: make([][{{$pre}}N]int32, n),
+				deltaErr: make([][{{$pre}}N]int32, n),
+			{{else if $.Sparse -}}
+				memo: make(map[{{$pre}}key][2]int32),
+			{{end -}}
+		}
+		return p, nil
+	}
+
+	{{if $.Committed -}}
+		// {{$pre}}Committed returns the byte offset
+		// of the first committed failure found by the parser's Accepts pass:
+		// the furthest error of a choice branch that failed after a cut, ^.
+		// It returns -1 if there is none.
+		func {{$pre}}Committed(parser *{{$pre}}Parser) int {
+			return parser.committed - 1
+		}
+
+		// {{$pre}}failError returns the error of the failed parse
+		// of a rule at start with furthest error at errPos,
+		// reporting the first committed failure between them, if any.
+		func {{$pre}}failError(parser *{{$pre}}Parser, f func(*{{$pre}}Parser, int, int) (int, *peg.Fail), start, errPos int, opts peg.ErrorOptions) error {
+			if c := {{$pre}}Committed(parser); c >= start && c < errPos {
+				_, fail := f(parser, start, c)
+				return opts.{{if $.Merged}}MergedError{{else}}CommittedError{{end}}(parser.text, fail, c)
+			}
+			_, fail := f(parser, start, errPos)
+			return opts.SimpleError(parser.text, fail)
+		}
+
+	{{end -}}
+
+	{{if $.GenActions -}}
+		// {{$pre}}Diagnostics returns the diagnostics recorded by Diag,
+		// in the order that they were recorded, without duplicates,
+		// each a peg.Error located at the start of its text
+		// with End the location of the end of its text.
+		func {{$pre}}Diagnostics(parser *{{$pre}}Parser) []peg.Error {
+			return parser.Diagnostics(peg.Locator{ {{- if $.Grammar.Newline}}Newline: {{$pre}}Newline{{end -}} }, parser.text)
+		}
+
+	{{end -}}
+
+	{{if $.Recovering -}}
+		// {{$pre}}recovery is a recovery by the Accepts pass
+		// from the failure of the rule at start,
+		// skipping to end, with the furthest error at errPos.
+		type {{$pre}}recovery struct {
+			rule, start, end, errPos int
+		}
+
+		// {{$pre}}Recoveries returns the recoveries by the Accepts pass
+		// from failures of rules annotated with @recoveruntil or @recoverpast,
+		// in the order that they finished,
+		// each with the Fail tree of the rule's failure.
+		// Recoveries of rules whose result the parse later discarded,
+		// for example in a failed choice branch, are included.
+		func {{$pre}}Recoveries(parser *{{$pre}}Parser) []peg.Recovery {
+			var rs []peg.Recovery
+			for _, r := range parser.recoveries {
+				// Fail trees are memoized for a single error position.
+				parser.fail = nil
+				var fail *peg.Fail
+				var name string
+				switch r.rule {
+				{{range $r := $.Recovering -}}
+					case {{$pre}}{{$r.Name.Ident}}:
+						name = {{quote (treeName $r)}}
+						_, fail = {{$pre}}{{$r.Name.Ident}}Fail(parser, r.start, r.errPos)
+				{{end -}}
+				}
+				rs = append(rs, peg.Recovery{Name: name, Pos: r.start, End: r.end, Fail: fail})
+			}
+			parser.fail = nil
+			return rs
+		}
+
+	{{end -}}
+
+	{{if $.Slow -}}
+		// {{$pre}}SlowParses returns the parses by the parser
+		// of rules whose Accepts pass took longer than their time budget,
+		// set by %warnslow or @warnslow, in the order that they finished.
+		func {{$pre}}SlowParses(parser *{{$pre}}Parser) []peg.SlowParse {
+			return parser.slow
+		}
+
+	{{end -}}
+
+	{{if $.Config.SharedMemo -}}
+		// {{$pre}}grammarID identifies the grammar in a peg.MemoCache.
+		const {{$pre}}grammarID = {{quote $.GrammarID}}
+
+		// {{$pre}}NewSharedParser returns a new Parser for the text
+		// that shares memo table entries with other parsers using the cache.
+		func {{$pre}}NewSharedParser(text string, cache *peg.MemoCache) (*{{$pre}}Parser, error) {
+			p, err := {{$pre}}NewParser(text)
+			if err != nil {
+				return nil, err
+			}
+			p.shared = cache
+			p.prefix = cache.PrefixHash(text)
+			return p, nil
+		}
+
+	{{end -}}
+
+	{{range $r := $.Roots -}}
+		{{- $id := $r.Name.Ident -}}
+		// {{$pre}}{{$id}}Matches returns whether the {{$r.Name.String}} rule matches all of text.
+		// It runs only the Accepts pass.
+		func {{$pre}}{{$id}}Matches(text string) bool {
+			parser, err := {{$pre}}NewParser(text)
+			if err != nil {
+				return false
+			}
+			pos, _ := {{$pre}}{{$id}}Accepts(parser, 0)
+			return pos == len(text)
+		}
+
+		// {{$pre}}{{$id}}ParseBounded parses the {{$r.Name.String}} rule
+		// at the beginning of text, like {{$pre}}{{$id}}ParseAt,
+		// but on failure, the Fail pass builds at most max rule nodes,
+		// replacing the rest by leaves wanting the rule's name,
+		// and the peg.Error lists at most max of the sorted Wants.
+		// So the cost of a failure is bounded
+		// even for adversarial inputs.
+		func {{$pre}}{{$id}}ParseBounded(text string, max int) (end int,
+			{{- if $.GenActions}} v {{$r.Type}},{{end}} err error) {
+			parser, err := {{$pre}}NewParser(text)
+			if err != nil {
+				return -1, {{if $.GenActions}}v, {{end}}err
+			}
+			dp, de := {{$pre}}{{$id}}Accepts(parser, 0)
+			if dp < 0 {
+				if max < 1 {
+					max = 1
+				}
+				parser.lastFail = de
+				parser.failBudget, parser.boundFails = max, true
+				{{if not $.Committed -}}
+					_, fail := {{$pre}}{{$id}}Fail(parser, 0, de)
+				{{end -}}
+				opts := peg.ErrorOptions{Sort: true, MaxWants: max}
+				{{if $.Grammar.Newline -}}
+					opts.Locator.Newline = {{$pre}}Newline
+				{{end -}}
+				{{if $.Committed -}}
+					return -1, {{if $.GenActions}}v, {{end}}{{$pre}}failError(parser, {{$pre}}{{$id}}Fail, 0, de, opts)
+				{{else -}}
+					return -1, {{if $.GenActions}}v, {{end}}opts.SimpleError(text, fail)
+				{{end -}}
+			}
+			{{if $.GenActions -}}
+				end, p := {{$pre}}{{$id}}Action(parser, 0)
+				if err := parser.ExpectError(peg.Locator{ {{- if $.Grammar.Newline}}Newline: {{$pre}}Newline{{end -}} }, parser.text, 0, end); err != nil {
+					return -1, v, err
+				}
+				return end, *p, nil
+			{{else -}}
+				return dp, nil
+			{{end -}}
+		}
+
+	{{end -}}
+
+	{{range $r := $.Grammar.CheckedRules -}}
+		{{- if not $r.Params -}}
+			{{- $id := $r.Name.Ident -}}
+			// {{$pre}}{{$id}}ParseAt parses the {{$r.Name.String}} rule
+			// beginning at byte offset start of the parser's text,
+			// which must be between 0 and the length of the text,
+			// without requiring the rule to match the rest of the text.
+			{{if $.GenActions -}}
+				// It returns the byte offset of the end of the match and the rule's result,
+			{{else -}}
+				// It returns the byte offset of the end of the match,
+			{{end -}}
+			// or a peg.Error if the rule does not match at start.
+			// The parser may be used for multiple calls,
+			// sharing its memo tables.
+			func {{$pre}}{{$id}}ParseAt(parser *{{$pre}}Parser, start int) (end int,
+				{{- if $.GenActions}} v {{$r.Type}},{{end}} err error) {
+				dp, de := {{$pre}}{{$id}}Accepts(parser, start)
+				if dp < 0 {
+					parser.lastFail = start + de
+					{{if $.Committed -}}
+						return -1, {{if $.GenActions}}v, {{end -}}
+							{{$pre}}failError(parser, {{$pre}}{{$id}}Fail, start, start+de, peg.ErrorOptions{ {{- if $.Grammar.Newline}}Locator: peg.Locator{Newline: {{$pre}}Newline}{{end -}} })
+					{{else -}}
+						_, fail := {{$pre}}{{$id}}Fail(parser, start, start+de)
+						return -1, {{if $.GenActions}}v, {{end -}}
+							{{if $.Grammar.Newline}}{{$pre}}Newline{{else}}peg{{end}}.SimpleError(parser.text, fail)
+					{{end -}}
+				}
+				{{if $.GenActions -}}
+					end, p := {{$pre}}{{$id}}Action(parser, start)
+					if err := parser.ExpectError(peg.Locator{ {{- if $.Grammar.Newline}}Newline: {{$pre}}Newline{{end -}} }, parser.text, start, end); err != nil {
+						return -1, v, err
+					}
+					return end, *p, nil
+				{{else -}}
+					return start + dp, nil
+				{{end -}}
+			}
+
+		{{end -}}
+	{{end -}}
+
+	{{if $.Config.PegParser -}}
+		var _ peg.Parser = (*{{$pre}}Parser)(nil)
+
+		// ParseNode implements peg.Parser,
+		// returning the syntax tree of the named rule
+		// at the beginning of the parser's text.
+		func (parser *{{$pre}}Parser) ParseNode(rule string) (*peg.Node, error) {
+			switch rule {
+			{{range $r := $.Grammar.CheckedRules -}}
+				{{- $id := $r.Name.Ident -}}
+				case {{quote $r.Name.String}}:
+				{{if $r.Params -}}
+					return nil, peg.RuleError{Rule: rule, Reason: "has parameters"}
+				{{else if not $.ParseTree -}}
+					return nil, peg.RuleError{Rule: rule, Reason: "parse trees not generated"}
+				{{else -}}
+					dp, de := {{$pre}}{{$id}}Accepts(parser, 0)
+					if dp < 0 {
+						parser.lastFail = de
+						{{if $.Committed -}}
+							return nil, {{$pre}}failError(parser, {{$pre}}{{$id}}Fail, 0, de, peg.ErrorOptions{ {{- if $.Grammar.Newline}}Locator: peg.Locator{Newline: {{$pre}}Newline}{{end -}} })
+						{{else -}}
+							_, fail := {{$pre}}{{$id}}Fail(parser, 0, de)
+							return nil, {{if $.Grammar.Newline}}{{$pre}}Newline{{else}}peg{{end}}.SimpleError(parser.text, fail)
+						{{end -}}
+					}
+					_, node := {{$pre}}{{$id}}Node(parser, 0)
+					return node, nil
+				{{end -}}
+			{{end -}}
+			}
+			return nil, peg.RuleError{Rule: rule, Reason: "undefined"}
+		}
+
+		// ParseValue implements peg.Parser,
+		// returning the result of the actions of the named rule
+		// at the beginning of the parser's text.
+		func (parser *{{$pre}}Parser) ParseValue(rule string) (interface{}, error) {
+			switch rule {
+			{{range $r := $.Grammar.CheckedRules -}}
+				{{- $id := $r.Name.Ident -}}
+				case {{quote $r.Name.String}}:
+				{{if $r.Params -}}
+					return nil, peg.RuleError{Rule: rule, Reason: "has parameters"}
+				{{else if not $.GenActions -}}
+					return nil, peg.RuleError{Rule: rule, Reason: "actions not generated"}
+				{{else -}}
+					_, v, err := {{$pre}}{{$id}}ParseAt(parser, 0)
+					if err != nil {
+						return nil, err
+					}
+					return v, nil
+				{{end -}}
+			{{end -}}
+			}
+			return nil, peg.RuleError{Rule: rule, Reason: "undefined"}
+		}
+
+	{{end -}}
+
+	func {{$pre}}max(a, b int) int {
+		if a > b {
+			return a
+		}
+		return b
+	}
+
+	// {{$pre}}getMemo returns the memo table entries for the rule at the start position.
+	// Each entry is 0 if the rule has not been memoized at the position.
+	func {{$pre}}getMemo(parser *{{$pre}}Parser, rule, start int) (dp, de int32) {
+		{{if $.RowMajor -}}
+			return parser.deltaPos[start][rule], parser.deltaErr[start][rule]
+		{{else if $.Column -}}
+			if parser.deltaPos[rule] == nil {
+				return 0, 0
+			}
+			return parser.deltaPos[rule][start], parser.deltaErr[rule][start]
+		{{else if $.Sparse -}}
+			d := parser.memo[{{$pre}}key{start: start, rule: rule}]
+			return d[0], d[1]
+		{{end -}}
+	}
+
+	// {{$pre}}setMemo sets the memo table entries for the rule at the start position.
+	func {{$pre}}setMemo(parser *{{$pre}}Parser, rule, start int, dp, de int32) {
+		{{if $.RowMajor -}}
+			parser.deltaPos[start][rule] = dp
+			parser.deltaErr[start][rule] = de
+		{{else if $.Column -}}
+			if parser.deltaPos[rule] == nil {
+				parser.deltaPos[rule] = make([]int32, len(parser.text)+1)
+				parser.deltaErr[rule] = make([]int32, len(parser.text)+1)
+			}
+			parser.deltaPos[rule][start] = dp
+			parser.deltaErr[rule][start] = de
+		{{else if $.Sparse -}}
+			parser.memo[{{$pre}}key{start: start, rule: rule}] = [2]int32{dp, de}
+		{{end -}}
+	}
+
+	{{if $.Config.SharedMemo -}}
+		// {{$pre}}memoize memoizes the rule at the start position,
+		// locally and in the shared cache,
+		// and restores the extent of the calling rule, outer,
+		// extended by that of this rule.
+		{{if $.Depths -}}
+			// The rule is not memoized if the number of depth failures
+			// changed from depthFails while parsing it.
+		{{end -}}
+		func {{$pre}}memoize(parser *{{$pre}}Parser, rule, start, pos, perr, outer int
+			{{- if $.Depths}}, depthFails int{{end}}) (int, int) {
+			parser.lastFail = perr
+			derr := perr - start
+			{{if $.Config.CacheSilentFails -}}
+				if parser.silent > 0 {
+					derr = -start - 1
+				}
+			{{end -}}
+			dpos := int32(-1)
+			if pos >= 0 {
+				dpos = int32(pos - start + 1)
+			}
+			extent := parser.extent
+			parser.extent = {{$pre}}max(outer, extent)
+			{{if $.Depths -}}
+				if parser.depthFails != depthFails {
+					// The result depends on a maximum depth, not just the text.
+					if pos >= 0 {
+						return pos - start, derr
+					}
+					return -1, derr
+				}
+			{{end -}}
+			{{$pre}}setMemo(parser, rule, start, dpos, int32(derr+1))
+			if parser.extents == nil {
+				parser.extents = make(map[{{$pre}}key]int)
+			}
+			parser.extents[{{$pre}}key{start: start, rule: rule}] = extent
+			if parser.shared != nil && extent <= len(parser.text) {
+				parser.shared.Store({{$pre}}grammarID, rule, start, peg.MemoEntry{
+					DeltaPos: dpos,
+					DeltaErr: int32(derr+1),
+					Extent: extent,
+					Hash: parser.prefix[extent],
+				})
+			}
+			if pos >= 0 {
+				return pos - start, derr
+			}
+			return -1, derr
+		}
+
+		// {{$pre}}examine extends the extent of the rule being parsed
+		// to include the bytes before end,
+		// or beyond the end of the text if end is greater than its length.
+		func {{$pre}}examine(parser *{{$pre}}Parser, end int) {
+			if end > len(parser.text) {
+				end = len(parser.text)+1
+			}
+			if end > parser.extent {
+				parser.extent = end
+			}
+		}
+	{{else -}}
+		{{if $.Depths -}}
+			// {{$pre}}memoize memoizes the rule at the start position,
+			// unless the number of depth failures
+			// changed from depthFails while parsing it.
+		{{end -}}
+		func {{$pre}}memoize(parser *{{$pre}}Parser, rule, start, pos, perr int
+			{{- if $.Depths}}, depthFails int{{end}}) (int, int) {
+			parser.lastFail = perr
+			derr := perr - start
+			{{if $.Config.CacheSilentFails -}}
+				if parser.silent > 0 {
+					derr = -start - 1
+				}
+			{{end -}}
+			{{if $.Depths -}}
+				if parser.depthFails != depthFails {
+					// The result depends on a maximum depth, not just the position.
+					if pos >= 0 {
+						return pos - start, derr
+					}
+					return -1, derr
+				}
+			{{end -}}
+			if pos >= 0 {
+				dpos := pos - start
+				{{$pre}}setMemo(parser, rule, start, int32(dpos + 1), int32(derr+1))
+				return dpos, derr
+			}
+			{{$pre}}setMemo(parser, rule, start, -1, int32(derr+1))
+			return -1, derr
+		}
+	{{end}}
+
+	{{if (or $.Config.SharedMemo $.Depths) -}}
+		// {{$pre}}ensureMemo returns the memo table entries for the rule at the start position,
+		// first running the Accepts pass if the rule was not memoized,
+		{{if $.Config.SharedMemo -}}
+			// which happens beneath a rule whose entries came from the shared cache
+			{{- if $.Depths}},
+			// or if its result depends on a maximum depth{{end}}.
+		{{else -}}
+			// which happens if its result depends on a maximum depth.
+		{{end -}}
+		func {{$pre}}ensureMemo(parser *{{$pre}}Parser, rule, start int) (dp, de int32) {
+			if dp, de = {{$pre}}getMemo(parser, rule, start); dp != 0 {
+				return dp, de
+			}
+			lastFail{{if $.Config.SharedMemo}}, extent{{end}} := parser.lastFail{{if $.Config.SharedMemo}}, parser.extent{{end}}
+			var dpos, derr int
+			switch rule {
+			{{range $r := $.Grammar.CheckedRules -}}
+				{{if not $r.Params -}}
+					case {{$pre}}{{$r.Name.Ident}}:
+						dpos, derr = {{$pre}}{{$r.Name.Ident}}Accepts(parser, start)
+				{{end -}}
+			{{end -}}
+			}
+			parser.lastFail{{if $.Config.SharedMemo}}, parser.extent{{end}} = lastFail{{if $.Config.SharedMemo}}, extent{{end}}
+			if dpos < 0 {
+				return -1, int32(derr + 1)
+			}
+			return int32(dpos + 1), int32(derr + 1)
+		}
+	{{end -}}
+
+	{{if $.Depths -}}
+		// {{$pre}}memoized returns whether the rule is memoized at the start position,
+		// which it is not if its result depends on a maximum depth.
+		// The results of the other passes are only reused if it is.
+		func {{$pre}}memoized(parser *{{$pre}}Parser, rule, start int) bool {
+			dp, _ := {{$pre}}getMemo(parser, rule, start)
+			return dp != 0
+		}
+
+	{{end -}}
+
+	func {{$pre}}memo(parser *{{$pre}}Parser, rule, start int) (int, int, bool) {
+		dp, de := {{$pre}}getMemo(parser, rule, start)
+		{{if $.Config.SharedMemo -}}
+			key := {{$pre}}key{start: start, rule: rule}
+			if dp == 0 {
+				e, ok := parser.shared.Lookup({{$pre}}grammarID, rule, start, parser.prefix)
+				if !ok {
+					return 0, 0, false
+				}
+				dp, de = e.DeltaPos, e.DeltaErr
+				{{$pre}}setMemo(parser, rule, start, dp, de)
+				if parser.extents == nil {
+					parser.extents = make(map[{{$pre}}key]int)
+				}
+				parser.extents[key] = e.Extent
+				parser.lastFail = start + int(de-1)
+			}
+			parser.extent = {{$pre}}max(parser.extent, parser.extents[key])
+		{{else -}}
+			if dp == 0 {
+				return 0, 0, false
+			}
+		{{end -}}
+		if dp > 0 {
+			dp--
+		}
+		return int(dp), int(de - 1), true
+	}
+
+	// {{$pre}}failMemo returns the memoized result of the Fail pass
+	// for the rule at the start position,
+	// or start and nil if the rule's Fail node must be built.
+	// If the Fail pass is bounded and its budget is spent,
+	// the node is not built, but replaced by a leaf wanting want at errPos.
+	func {{$pre}}failMemo(parser *{{$pre}}Parser, rule, start, errPos int, want string) (int, *peg.Fail) {
+		if start > parser.lastFail {
+			return -1, &peg.Fail{}
+		}
+		dp, de := {{$pre}}{{if (or $.Config.SharedMemo $.Depths)}}ensureMemo{{else}}getMemo{{end}}(parser, rule, start)
+		if start+int(de-1) < errPos {
+			if dp > 0 {
+				return start + int(dp-1), &peg.Fail{}
+			}
+			return -1, &peg.Fail{}
+		}
+		f := parser.fail[_key{start: start, rule: rule}]
+		if dp < 0 && f != nil {
+			return -1, f
+		}
+		if dp > 0 && f != nil {
+			return start + int(dp-1), f
+		}
+		if parser.boundFails {
+			if parser.failBudget == 0 {
+				f := &peg.Fail{Pos: errPos, Want: want, Code: peg.NamedRule}
+				if dp > 0 {
+					return start + int(dp-1), f
+				}
+				return -1, f
+			}
+			parser.failBudget--
+		}
+		return start, nil
+	}
+
+	func {{$pre}}accept(parser *{{$pre}}Parser, f func(*{{$pre}}Parser, int) (int, int), pos, perr *int) bool {
+		dp, de := f(parser, *pos)
+		*perr = _max(*perr, *pos+de)
+		if dp < 0 {
+			return false
+		}
+		*pos += dp
+		return true
+	}
+
+	func {{$pre}}node(parser *{{$pre}}Parser, f func(*{{$pre}}Parser, int) (int, *peg.Node), node *peg.Node, pos *int) bool {
+		p, kid := f(parser, *pos)
+		if kid == nil {
+			return false
+		}
+		node.Kids = append(node.Kids, kid)
+		*pos = p
+		return true
+	}
+
+	func {{$pre}}fail(parser *{{$pre}}Parser, f func(*{{$pre}}Parser, int, int) (int, *peg.Fail), errPos int, node *peg.Fail, pos *int) bool {
+		p, kid := f(parser, *pos, errPos)
+		if kid.Want != "" || len(kid.Kids) > 0 {
+			node.Kids = append(node.Kids, kid)
+		}
+		if p < 0 {
+			return false
+		}
+		*pos = p
+		return true
+	}
+
+	func {{$pre}}next(parser *{{$pre}}Parser, pos int) (rune, int) {
+		r, w := peg.DecodeRuneInString(parser.text[pos:])
+		{{if $.Config.SharedMemo -}}
+			if r == '\uFFFD' {
+				// An invalid encoding may be the prefix of a valid one.
+				{{$pre}}examine(parser, pos+4)
+			} else {
+				{{$pre}}examine(parser, pos+{{$pre}}max(w, 1))
+			}
+		{{end -}}
+		return r, w
+	}
+
+	func {{$pre}}sub(parser *{{$pre}}Parser, start, end int, kids []*peg.Node) *peg.Node {
+		node := &peg.Node{
+			Text: parser.text[start:end],
+			Kids: make([]*peg.Node, len(kids)),
+		}
+		copy(node.Kids, kids)
+		return node
+	}
+
+	func {{$pre}}leaf(parser *{{$pre}}Parser, start, end int) *peg.Node {
+		return &peg.Node{Text: parser.text[start:end]}
+	}
+
+	// A no-op function to mark a variable as used.
+	func use(interface{}) {}
+`
+
+// templates contains a mapping from Expr types to their templates.
+// These templates parse the input text and compute
+// for each <rule, pos> pair encountered by the parse,
+// the position immediately following the text accepted by the rule,
+// or the position of the furthest error encountered by the rule.
+//
+// When generating the parse tree pass,
+// the templates also add peg.Nodes to the kids slice.
+//
+// Variables for use by the templates:
+//
+//	parser is the *Parser.
+//		parser.text is the input text.
+//	pos is the byte offset into parser.text of where to begin parsing.
+//		If the Expr fails to parse, pos must be set to the position of the error.
+//		If if the Expr succeeds to parse, pos must be set
+//		to the position just after the accepted text.
+//
+// On the accepts pass these variables are also defined:
+//
+//	perr is the position of the max error position found so far.
+//		It is only defined if Rule.Expr.CanFail.
+//		It is initialized to -1 at the beginning of the parse.
+//		It is updated by Choice nodes when branches fail,
+//		and by rules when their entire parse fails.
+//	ok is a scratch boolean variable.
+//		It may be either true or false before and after each Expr template.
+//		Each template that wants to use ok must set it before using it.
+//
+// On the node tree pass these variables are also defined:
+//
+//	node is the *peg.Node of the Rule being parsed.
+//
+// On the action tree pass these variables are also defined:
+//
+//	node is an interface{} containing the current action tree node value.
+//
+// On the fail tree pass these variables are also defined:
+//
+//	failure is the *peg.Fail of the Rule being parsed.
+//	errPos is the position before which Fail nodes are not generated.
+var templates = map[reflect.Type]string{
+	reflect.TypeOf(&Choice{}):        choiceTemplate,
+	reflect.TypeOf(&LongestChoice{}): longestChoiceTemplate,
+	reflect.TypeOf(&Action{}):        actionTemplate,
+	reflect.TypeOf(&Sequence{}):      sequenceTemplate,
+	reflect.TypeOf(&LabelExpr{}):     labelExprTemplate,
+	reflect.TypeOf(&WantExpr{}):      wantExprTemplate,
+	reflect.TypeOf(&PredExpr{}):      predExprTemplate,
+	reflect.TypeOf(&CaptureExpr{}):   captureExprTemplate,
+	reflect.TypeOf(&DiffExpr{}):      diffExprTemplate,
+	reflect.TypeOf(&RepExpr{}):       repExprTemplate,
+	reflect.TypeOf(&OptExpr{}):       optExprTemplate,
+	reflect.TypeOf(&SubExpr{}):       subExprTemplate,
+	reflect.TypeOf(&PredCode{}):      predCodeTemplate,
+	reflect.TypeOf(&DelegateExpr{}):  delegateExprTemplate,
+	reflect.TypeOf(&NativeExpr{}):    nativeExprTemplate,
+	reflect.TypeOf(&BlockExpr{}):     blockExprTemplate,
+	reflect.TypeOf(&Ident{}):         identTemplate,
+	reflect.TypeOf(&Literal{}):       literalTemplate,
+	reflect.TypeOf(&Any{}):           anyTemplate,
+	reflect.TypeOf(&Cut{}):           cutTemplate,
+	reflect.TypeOf(&Empty{}):         emptyTemplate,
+	reflect.TypeOf(&CharClass{}):     charClassTemplate,
+}
+
+// ruleTemplates are the named templates used by ruleTemplate.
+var ruleTemplates = [][2]string{
+	{"ruleAccepts", ruleAccepts},
+	{"ruleNode", ruleNode},
+	{"ruleFail", ruleFail},
+	{"stringLabels", stringLabels},
+	{"syncs", syncs},
+	{"depthCond", depthCond},
+	{"depthEnter", depthEnter},
+	{"depthExit", depthExit},
+	{"ruleAction", ruleAction},
+}
+
+var ruleTemplate = `
+	{{template "ruleAccepts" $}}
+	{{if $.GenParseTree -}}
+		{{template "ruleNode" $}}
+	{{end -}}
+	{{template "ruleFail" $}}
+	{{if $.GenActions -}}
+		{{template "ruleAction" $}}
+	{{end -}}
+`
+
+var stringLabels = `
+	{{- with predLabels $.Rule -}}
+		var labels [{{len .}}]string
+		use(labels)
+	{{- end -}}
+`
+
+// depthCond is the condition under which
+// invoking the rule would exceed a maximum nesting depth.
+var syncs = `
+	{{- if . -}}
+		[]string{ {{- quoteList .}}}
+	{{- else -}}
+		nil
+	{{- end -}}
+`
+
+var depthCond = `
+	{{- $id := $.Rule.Name.Ident -}}
+	{{- if $.Grammar.MaxDepth -}}
+		parser.depth >= {{$.Grammar.MaxDepth}}
+	{{- end -}}
+	{{- if and $.Grammar.MaxDepth $.Rule.MaxDepth}} || {{end -}}
+	{{- if $.Rule.MaxDepth -}}
+		parser.ruleDepth[{{$.Config.Prefix}}{{$id}}] >= {{$.Rule.MaxDepth}}
+	{{- end -}}
+`
+
+var depthEnter = `
+	{{- $id := $.Rule.Name.Ident -}}
+	{{if $.Grammar.MaxDepth -}}
+		parser.depth++
+	{{end -}}
+	{{if $.Rule.MaxDepth -}}
+		parser.ruleDepth[{{$.Config.Prefix}}{{$id}}]++
+	{{end -}}
+`
+
+var depthExit = `
+	{{- $id := $.Rule.Name.Ident -}}
+	{{if $.Grammar.MaxDepth -}}
+		parser.depth--
+	{{end -}}
+	{{if $.Rule.MaxDepth -}}
+		parser.ruleDepth[{{$.Config.Prefix}}{{$id}}]--
+	{{end -}}
+`
+
+var ruleAccepts = `
+	{{$pre := $.Config.Prefix -}}
+	{{- $id := $.Rule.Name.Ident -}}
+	func {{$pre}}{{$id}}Accepts(parser *{{$pre}}Parser, start int
+		{{- if $.Rule.Params}}, {{$.Rule.Params}}{{end}}) (deltaPos, deltaErr int) {
+		{{- template "stringLabels" $}}
+		{{if $.Config.Trace -}}
+			{{$pre}}Trace.Enter(parser.text, {{$pre}}{{$id}}, start)
+			defer func() { {{$pre}}Trace.Exit(deltaPos, deltaErr) }()
+		{{end -}}
+		{{if not $.Rule.Params -}}
+			if dp, de, ok := {{$pre}}memo(parser, {{$pre}}{{$id}}, start); ok {
+				return dp, de
+			}
+			{{if $.Depths -}}
+				depthFails := parser.depthFails
+			{{end -}}
+		{{end -}}
+		{{if $.Config.Profile -}}
+			{{$pre}}Profile.Enter({{$pre}}{{$id}})
+			defer {{$pre}}Profile.Exit()
+		{{end -}}
+		{{with or $.Rule.WarnSlow $.Grammar.WarnSlow -}}
+			slow := peg.StartSlowTimer({{quote $.Rule.Name.String}}, start, {{.Nanoseconds}})
+			defer func() {
+				if s, ok := slow.Stop(start + {{$pre}}max(deltaPos, deltaErr)); ok {
+					parser.slow = append(parser.slow, s)
+				}
+			}()
+		{{end -}}
+		{{if $.Depth -}}
+			if {{template "depthCond" $}} {
+				// Whether the depth is exceeded depends on the invocation,
+				// not just the position, so neither the failure
+				// nor the results depending on it are memoized.
+				parser.depthFails++
+				parser.lastFail = start
+				return -1, 0
+			}
+			{{template "depthEnter" $}}
+		{{- end -}}
+		{{if (and $.Config.SharedMemo (not $.Rule.Params)) -}}
+			outer := parser.extent
+			parser.extent = start
+		{{end -}}
+		pos, perr := start, -1
+		{{gen (makeAcceptState $.Rule) $.Rule.Expr "" "fail" -}}
+
+		{{if $.Rule.ErrorName -}}
+			perr = start
+		{{end -}}
+		{{if $.Config.Cover -}}
+			{{$pre}}Coverage.Hit({{$pre}}{{$id}})
+		{{end -}}
+		{{template "depthExit" $}}
+		{{- if $.Rule.Params -}}
+			parser.lastFail = perr
+			return pos - start, perr - start
+		{{else -}}
+			return {{$pre}}memoize(parser, {{$pre}}{{$id}}, start, pos, perr
+				{{- if $.Config.SharedMemo}}, outer{{end}}
+				{{- if $.Depths}}, depthFails{{end}})
+		{{end -}}
+	{{if $.Rule.Expr.CanFail -}}
+	fail:
+		{{template "depthExit" $}}
+		{{- if $.Rule.Recovers -}}
+			if end := peg.Recover(parser.text, start, {{template "syncs" $.Rule.RecoverUntil}}, {{template "syncs" $.Rule.RecoverPast}}); end > start {
+				perr = {{$pre}}max(perr, start)
+				parser.recoveries = append(parser.recoveries, {{$pre}}recovery{
+					rule: {{$pre}}{{$id}},
+					start: start,
+					end: end,
+					errPos: perr,
+				})
+				return {{$pre}}memoize(parser, {{$pre}}{{$id}}, start, end, perr
+					{{- if $.Depths}}, depthFails{{end}})
+			}
+		{{end -}}
+		{{if $.Rule.Params -}}
+			parser.lastFail = perr
+			return -1, perr - start
+		{{else -}}
+			return {{$pre}}memoize(parser, {{$pre}}{{$id}}, start, -1, perr
+				{{- if $.Config.SharedMemo}}, outer{{end}}
+				{{- if $.Depths}}, depthFails{{end}})
+		{{end -}}
+	{{end -}}
+	}
+`
+
+var ruleNode = `
+	{{$pre := $.Config.Prefix -}}
+	{{- $id := $.Rule.Name.Ident -}}
+	{{- $name := $.TreeName -}}
+	func {{$pre}}{{$id}}Node(parser *{{$pre}}Parser, start int
+		{{- if $.Rule.Params}}, {{$.Rule.Params}}{{end}}) (int, *peg.Node) {
+		{{- if (and $.Rule.Token (not $.Rule.Params))}}
+			dp, _ := {{$pre}}{{if (or $.Config.SharedMemo $.Depths)}}ensureMemo{{else}}getMemo{{end}}(parser, {{$pre}}{{$id}}, start)
+			if dp < 0 {
+				return -1, nil
+			}
+			key := {{$pre}}key{start: start, rule: {{$pre}}{{$id}}}
+			if parser.node == nil {
+				parser.node = make(map[{{$pre}}key]*peg.Node)
+			}
+			node := parser.node[key]
+			if node == nil {
+				node = &peg.Node{Name: {{quote $name}}, Text: parser.text[start:start+int(dp-1)]}
+				{{if $.Depths -}}
+					if {{$pre}}memoized(parser, {{$pre}}{{$id}}, start) {
+						parser.node[key] = node
+					}
+				{{else -}}
+					parser.node[key] = node
+				{{end -}}
+			}
+			return start + int(dp - 1), node
+		}
+		{{- else}}
+		{{- template "stringLabels" $}}
+		{{if $.Rule.Params -}}
+			pos := start
+			node := &peg.Node{Name: {{quote $name}}}
+		{{else -}}
+			dp, _ := {{$pre}}{{if (or $.Config.SharedMemo $.Depths)}}ensureMemo{{else}}getMemo{{end}}(parser, {{$pre}}{{$id}}, start)
+			if dp < 0 {
+				return -1, nil
+			}
+			key := {{$pre}}key{start: start, rule: {{$pre}}{{$id}}}
+			if parser.node == nil {
+				parser.node = make(map[{{$pre}}key]*peg.Node)
+			}
+			node := parser.node[key]
+			if node != nil {
+				return start + int(dp - 1), node
+			}
+			pos := start
+			node = &peg.Node{Name: {{quote $name}}}
+		{{end -}}
+		{{template "depthEnter" $}}
+		{{- gen (makeNodeState $.Rule) $.Rule.Expr "" "fail" -}}
+
+		{{template "depthExit" $ -}}
+		node.Text = parser.text[start:pos]
+		{{if $.Rule.Token -}}
+			node.Kids = nil
+		{{end -}}
+		{{if (and $.Depths (not $.Rule.Params)) -}}
+			if {{$pre}}memoized(parser, {{$pre}}{{$id}}, start) {
+				parser.node[key] = node
+			}
+		{{else if not $.Rule.Params -}}
+			parser.node[key] = node
+		{{end -}}
+		return pos, node
+	{{if $.Rule.Expr.CanFail -}}
+	fail:
+		{{template "depthExit" $}}
+		{{- if $.Rule.Recovers -}}
+			// The rule recovered, accepting the skipped input.
+			node.Text = parser.text[start:start+int(dp-1)]
+			node.Kids = nil
+			{{if $.Depths -}}
+				if {{$pre}}memoized(parser, {{$pre}}{{$id}}, start) {
+					parser.node[key] = node
+				}
+			{{else -}}
+				parser.node[key] = node
+			{{end -}}
+			return start + int(dp-1), node
+		{{else -}}
+			return -1, nil
+		{{end -}}
+	{{end -}}
+	}
+	{{- end}}
+`
+
+var ruleFail = `
+	{{$pre := $.Config.Prefix -}}
+	{{- $id := $.Rule.Name.Ident -}}
+	func {{$pre}}{{$id}}Fail(parser *{{$pre}}Parser, start, errPos int
+		{{- if $.Rule.Params}}, {{$.Rule.Params}}{{end}}) (int, *peg.Fail) {
+		{{- template "stringLabels" $}}
+		{{if $.Rule.Params -}}
+			pos := start
+			failure := &peg.Fail{
+				Name: {{quote $.TreeName}},
+				Pos: int(start),
+			}
+		{{else -}}
+			pos, failure := {{$pre}}failMemo(parser, {{$pre}}{{$id}}, start, errPos,
+				{{- if $.Rule.ErrorMessage}} peg.ErrorMessage({{quote $.Rule.ErrorName.String}}, parser.text, start)
+				{{- else if $.Rule.ErrorName}} {{quote $.Rule.ErrorName.String}}
+				{{- else}} {{quote $.TreeName}}{{end}})
+			{{if (and (not $.Rule.Expr.CanFail) (not $.Depth)) -}}
+				if failure != nil && pos < 0 {
+					// The rule never fails, so its callers do not check,
+					// and it accepts even where the Fail pass is cut short.
+					pos = start
+					if dp, _ := {{$pre}}{{if (or $.Config.SharedMemo $.Depths)}}ensureMemo{{else}}getMemo{{end}}(parser, {{$pre}}{{$id}}, start); dp > 0 {
+						pos += int(dp - 1)
+					}
+				}
+			{{end -}}
+			if failure != nil {
+				return pos, failure
+			}
+			failure = &peg.Fail{
+				Name: {{quote $.TreeName}},
+				Pos: int(start),
+			}
+			key := {{$pre}}key{start: start, rule: {{$pre}}{{$id}}}
+			if parser.fail == nil {
+				parser.fail = make(map[{{$pre}}key]*peg.Fail)
+			}
+		{{end -}}
+		{{if $.Depth -}}
+			if {{template "depthCond" $}} {
+				failure.Want = peg.MaxDepthExceeded
+				failure.Code = peg.DepthExceeded
+				return -1, failure
+			}
+			{{template "depthEnter" $}}
+		{{- end -}}
+		{{gen (makeFailState $.Rule) $.Rule.Expr "" "fail" -}}
+
+		{{template "depthExit" $}}
+		{{- if $.Rule.ErrorName -}}
+			failure.Kids = nil
+		{{end -}}
+		{{if (and $.Depths (not $.Rule.Params)) -}}
+			if {{$pre}}memoized(parser, {{$pre}}{{$id}}, start) {
+				parser.fail[key] = failure
+			}
+		{{else if not $.Rule.Params -}}
+			parser.fail[key] = failure
+		{{end -}}
+		return pos, failure
+	{{if $.Rule.Expr.CanFail -}}
+	fail:
+		{{template "depthExit" $}}
+		{{- if $.Rule.ErrorMessage -}}
+			failure.Kids = nil
+			failure.Want = peg.ErrorMessage({{quote $.Rule.ErrorName.String}}, parser.text, start)
+			failure.Message = true
+			failure.Code = peg.NamedRule
+		{{else if $.Rule.ErrorName -}}
+			failure.Kids = nil
+			failure.Want = {{quote $.Rule.ErrorName.String}}
+			failure.Code = peg.NamedRule
+		{{end -}}
+		{{if (and $.Depths (not $.Rule.Params)) -}}
+			if {{$pre}}memoized(parser, {{$pre}}{{$id}}, start) {
+				parser.fail[key] = failure
+			}
+		{{else if not $.Rule.Params -}}
+			parser.fail[key] = failure
+		{{end -}}
+		{{if $.Rule.Recovers -}}
+			// The rule recovered, accepting the skipped input,
+			// but its failure is still reported beneath its parent.
+			if dp, _ := {{$pre}}getMemo(parser, {{$pre}}{{$id}}, start); dp > 0 {
+				return start + int(dp-1), failure
+			}
+		{{end -}}
+		return -1, failure
+	{{end -}}
+	}
+`
+
+var ruleAction = `
+	{{$pre := $.Config.Prefix -}}
+	{{- $id := $.Rule.Name.Ident -}}
+	{{- $type := $.Rule.Type -}}
+	func {{$pre}}{{$id}}Action(parser *{{$pre}}Parser, start int
+		{{- if $.Rule.Params}}, {{$.Rule.Params}}{{end}}) (int, *{{$type}}) {
+		{{- template "stringLabels" $}}
+		{{if $.Rule.Labels -}}
+			{{range $l := $.Rule.Labels -}}
+				{{if not (predicate $l.Expr) -}}
+					var label{{$l.N}} {{$l.Type}}
+				{{end -}}
+			{{end}}
+		{{- end -}}
+		{{if not $.Rule.Params -}}
+			dp, _ := {{$pre}}{{if (or $.Config.SharedMemo $.Depths)}}ensureMemo{{else}}getMemo{{end}}(parser, {{$pre}}{{$id}}, start)
+			if dp < 0 {
+				return -1, nil
+			}
+			if n, ok := parser.act{{$id}}[start]; ok {
+				return start + int(dp - 1), &n
+			}
+		{{end -}}
+		var node {{$type}}
+		pos := start
+		{{template "depthEnter" $}}
+		{{- gen (makeActionState $.Rule) $.Rule.Expr "node" "fail" -}}
+
+		{{template "depthExit" $}}
+		{{- if (and $.Depths (not $.Rule.Params)) -}}
+			if {{$pre}}memoized(parser, {{$pre}}{{$id}}, start) {
+				if parser.act{{$id}} == nil {
+					parser.act{{$id}} = make(map[int]{{$type}})
+				}
+				parser.act{{$id}}[start] = node
+			}
+		{{else if not $.Rule.Params -}}
+			if parser.act{{$id}} == nil {
+				parser.act{{$id}} = make(map[int]{{$type}})
+			}
+			parser.act{{$id}}[start] = node
+		{{end -}}
+		return pos,  &node
+	{{if $.Rule.Expr.CanFail -}}
+	fail:
+		{{template "depthExit" $ -}}
+		{{if $.Rule.Recovers -}}
+			// The rule recovered, accepting the skipped input,
+			// and its result is the zero value.
+			var zero {{$type}}
+			return start + int(dp-1), &zero
+		{{else -}}
+			return -1, nil
+		{{end -}}
+	{{end -}}
+	}
+`
+
+var choiceTemplate = `// {{$.Expr.String}}
+{{if $.LiteralCases $.Expr -}}
+	{{template "literalChoice" $}}
+{{- else -}}
+{
+	{{- $ok := id "ok" -}}
+	{{- $nkids := id "nkids" -}}
+	{{- $node0 := id "node" -}}
+	{{- $pos0 := id "pos" -}}
+	{{- $cut := "" -}}
+	{{- $perr0 := "" -}}
+	{{$pos0}} := pos
+	{{if $.NodePass -}}
+		{{$nkids}} := len(node.Kids)
+	{{else if (and $.Node $.ActionPass) -}}
+		var {{$node0}} {{$.Expr.Type}}
+	{{end -}}
+	{{if $.Committing $.Expr -}}
+		{{- $cut = id "cut" -}}
+		{{$cut}} := false
+		{{if (and $.Config.Errors $.AcceptsPass (not $.DryRun)) -}}
+			{{- $perr0 = id "perr" -}}
+			{{- /* The branch's furthest error is its committed failure. */ -}}
+			var {{$perr0}} int
+		{{end -}}
+	{{end -}}
+	{{- range $i, $subExpr := $.Expr.Exprs -}}
+		{{- $fail := id "fail" -}}
+		{{- $committed := (and $perr0 (commits $subExpr) $subExpr.CanFail) -}}
+		{{if $committed -}}
+			{{$perr0}}, perr = perr, -1
+		{{end -}}
+		{{if (and $.ActionPass $subExpr.CanFail (effects $subExpr)) -}}
+			{{dryRun $ $subExpr $fail -}}
+		{{end -}}
+		{{gen ($.WithCut $cut) $subExpr $.Node $fail -}}
+
+		{{if (and $.Config.Cover $.AcceptsPass (not $.DryRun)) -}}
+			{{$.Config.Prefix}}Coverage.Hit({{$.CoverIndex $subExpr}})
+		{{end -}}
+		{{if $subExpr.CanFail -}}
+			{{if $committed -}}
+				perr = {{$.Config.Prefix}}max({{$perr0}}, perr)
+			{{end -}}
+			goto {{$ok}}
+			{{$fail}}:
+				{{if $committed -}}
+					if {{$cut}} && parser.committed == 0 {
+						parser.committed = perr + 1
+					}
+					perr = {{$.Config.Prefix}}max({{$perr0}}, perr)
+				{{end -}}
+				{{if $.NodePass -}}
+					node.Kids = node.Kids[:{{$nkids}}]
+				{{else if (and $.Node $.ActionPass) -}}
+					{{$.Node}} = {{$node0}}
+				{{end -}}
+				pos = {{$pos0}}
+			{{if last $i $.Expr.Exprs -}}
+				goto {{$.Fail}}
+			{{else if (and $cut (commits $subExpr)) -}}
+				if {{$cut}} {
+					goto {{$.Fail}}
+				}
+			{{end -}}
+		{{else -}}
+			{{- /* The branch never fails, so the later branches are never tried. */ -}}
+			{{break -}}
+		{{end -}}
+	{{end -}}
+	{{$ok}}:
+}
+{{end -}}
+`
+
+// longestChoiceTemplate tries each branch, choosing the longest.
+// The accepts and fail passes run every branch,
+// so that all branches contribute to perr and the Fail tree.
+// The node and action passes measure each branch with accepts pass code,
+// and then run only the chosen branch.
+var longestChoiceTemplate = `// {{$.Expr.String}}
+{
+	{{- $pos0 := id "pos" -}}
+	{{- $best := id "best" -}}
+	{{- $win := id "win" -}}
+	{{$pos0}} := pos
+	{{$best}}, {{$win}} := -1, -1
+	{{range $i, $subExpr := $.Expr.Exprs -}}
+		{{- $fail := id "fail" -}}
+		{{if (or $.AcceptsPass $.FailPass) -}}
+			{{gen $ $subExpr "" $fail -}}
+			if pos > {{$best}} {
+				{{$best}}, {{$win}} = pos, {{$i}}
+			}
+		{{else -}}
+			{{measure $ $subExpr $i $best $win $fail -}}
+		{{end -}}
+		{{if $subExpr.CanFail -}}
+			{{$fail}}:
+		{{end -}}
+		pos = {{$pos0}}
+	{{end -}}
+	if {{$win}} < 0 {
+		goto {{$.Fail}}
+	}
+	{{if (or $.AcceptsPass $.FailPass) -}}
+		pos = {{$best}}
+	{{else -}}
+		switch {{$win}} {
+		{{range $i, $subExpr := $.Expr.Exprs -}}
+		case {{$i}}:
+			{{gen $ $subExpr $.Node $.Fail -}}
+		{{end -}}
+		}
+	{{end -}}
+	use({{$win}})
+}
+`
+
+// literalChoice is the choiceTemplate for a choice of only literals.
+// It switches on the next byte, and then tries, in order,
+// only the literals beginning with that byte.
+var literalChoice = `
+	{{- $pre := $.Config.Prefix -}}
+	{{- $ok := id "ok" -}}
+	{{- $cases := $.LiteralCases $.Expr -}}
+	{
+	{{if (and $.Config.SharedMemo $.AcceptsPass) -}}
+		{{$pre}}examine(parser, pos+{{$.MaxLen $cases}})
+	{{end -}}
+	if pos < len(parser.text) {
+		switch parser.text[pos] {
+		{{range $c := $cases -}}
+		case {{$c.Byte}}:
+			{{range $l := $c.Literals -}}
+				{{- $n := len $l.Text -}}
+				if len(parser.text)-pos >= {{$n}} && parser.text[pos:pos+{{$n}}] == {{quote $l.Text}} {
+					{{if (and $.AcceptsPass (not $l.First)) -}}
+						perr = {{$pre}}max(perr, pos)
+					{{else if $.NodePass -}}
+						node.Kids = append(node.Kids, {{$pre}}leaf(parser, pos, pos + {{$n}}))
+					{{else if (and $.ActionPass $.Node) -}}
+						{{$.Node}} = parser.text[pos:pos+{{$n}}]
+					{{end -}}
+					pos += {{$n}}
+					goto {{$ok}}
+				}
+			{{end -}}
+		{{end -}}
+		}
+	}
+	{{if $.AcceptsPass -}}
+		perr = {{$pre}}max(perr, pos)
+	{{end -}}
+	goto {{$.Fail}}
+	{{$ok}}:
+	}
+`
+
+var actionTemplate = `// action
+	{{if $.ActionPass -}}
+		{
+			{{$start := id "start" -}}
+			{{$start}} := pos
+			{{gen $ $.Expr.Expr "" $.Fail -}}
+			{{/* TODO: don't put the func in the scope of the rule. */ -}}
+			{{if $.Node}}{{$.Node}} = {{end}} func(
+				start, end int,
+				{{- if $.Expr.Labels -}}
+					{{range $lexpr := $.Expr.Labels -}}
+						{{$lexpr.Label}} {{$lexpr.Type}},
+					{{- end -}}
+				{{- end -}})
+				{{- $.Expr.Type}} { {{$.Expr.Code}} }(
+					{{$start}}, pos,
+					{{- if $.Expr.Labels -}}
+						{{range $lexpr := $.Expr.Labels -}}
+							{{if predicate $lexpr.Expr}}""{{else}}label{{$lexpr.N}}{{end}},
+						{{- end -}}
+					{{- end -}}
+			)
+		}
+	{{else -}}
+		{{gen $ $.Expr.Expr "" $.Fail -}}
+	{{end -}}
+`
+
+var sequenceTemplate = `// {{$.Expr.String}}
+	{{$node := id "node" -}}
+	{{if (and $.ActionPass $.Node (eq $.Expr.Type "string") (textual $.Expr)) -}}
+		{{template "textValue" $}}
+	{{else -}}
+	{{if (and $.ActionPass $.Node (eq $.Expr.Type "string")) -}}
+		{
+			var {{$node}} string
+	{{else if (and $.ActionPass $.Node $.Expr.IsTuple) -}}
+		{{$.Node}} = {{$.Expr.Type}}{}
+	{{else if (and $.ActionPass $.Node) -}}
+		{{$.Node}} = make({{$.Expr.Type}}, {{len $.Expr.Exprs}})
+	{{end -}}
+
+	{{range $i, $subExpr := $.Expr.Exprs -}}
+		{{if (and $.ActionPass $.Node (eq $.Expr.Type "string")) -}}
+			{{gen $ $subExpr $node $.Fail -}}
+			{{$.Node}}, {{$node}} = {{$.Node}}+{{$node}}, ""
+		{{else if (and $.ActionPass $.Node $.Expr.IsTuple) -}}
+			{{gen $ $subExpr (printf "%s.V%d" $.Node $i) $.Fail -}}
+		{{else if (and $.ActionPass $.Node) -}}
+			{{gen $ $subExpr (printf "%s[%d]" $.Node $i) $.Fail -}}
+		{{else -}}
+			{{gen $ $subExpr "" $.Fail -}}
+		{{end -}}
+	{{end -}}
+
+	{{if (and $.ActionPass $.Node (eq $.Expr.Type "string")) -}}
+		}
+	{{end -}}
+	{{end -}}
+`
+
+// textValue is the action pass of a textual string expression,
+// the value of which is sliced from the input
+// instead of concatenated from the values of its subexpressions.
+var textValue = `{
+	{{- $pos0 := id "pos" -}}
+	{{$pos0}} := pos
+	{{gen $ $.Expr "" $.Fail -}}
+	{{$.Node}} = parser.text[{{$pos0}}:pos]
+}
+`
+
+var labelExprTemplate = `// {{$.Expr.String}}
+	{{$name := $.Expr.Label.String -}}
+	{{- $pos0 := id "pos" -}}
+	{{- $subExpr := $.Expr.Expr -}}
+	{{if predicate $subExpr -}}
+		{{- /*
+			A predicate consumes nothing, so its label is always empty:
+			the zero value of its label variable and its labels entry,
+			which need not be stored.
+		*/ -}}
+		{{gen $ $subExpr "" $.Fail -}}
+		{{if (and $.ActionPass $.Node) -}}
+			{{$.Node}} = ""
+		{{end -}}
+	{{else -}}
+	{
+		{{- $slot := labelSlot $.Rule $.Expr}}
+		{{if ge $slot 0 -}}
+			{{$pos0}} := pos
+		{{end -}}
+		{{if $.ActionPass -}}
+			{{gen $ $subExpr (printf "label%d" $.Expr.N) $.Fail -}}
+			{{if $.Node -}}
+				{{$.Node}} = label{{$.Expr.N}}
+			{{end -}}
+		{{else -}}
+			{{gen $ $subExpr "" $.Fail -}}
+		{{end -}}
+		{{if ge $slot 0 -}}
+			labels[{{$slot}}] = parser.text[{{$pos0}}:pos]
+		{{end -}}
+	}
+	{{end -}}
+`
+
+// wantExprTemplate reports a failure of its subexpression
+// as a single Fail wanting the description,
+// hiding the fails beneath it, as does a named rule.
+var wantExprTemplate = `// {{$.Expr.String}}
+{{if (or $.AcceptsPass $.FailPass) -}}
+{
+	{{- $pre := $.Config.Prefix -}}
+	{{- $pos0 := id "pos" -}}
+	{{- $perr0 := id "perr" -}}
+	{{- $nkids := id "nkids" -}}
+	{{- $fail := id "fail" -}}
+	{{- $ok := id "ok" -}}
+	{{if $.AcceptsPass -}}
+		{{$pos0}}, {{$perr0}} := pos, perr
+	{{else -}}
+		{{if $.Expr.CanFail -}}
+			{{$pos0}} := pos
+		{{end -}}
+		{{$nkids}} := len(failure.Kids)
+	{{end -}}
+	{{gen $ $.Expr.Expr "" $fail -}}
+	{{if $.AcceptsPass -}}
+		perr = {{$pre}}max({{$perr0}}, {{$pos0}})
+	{{else -}}
+		failure.Kids = failure.Kids[:{{$nkids}}]
+	{{end -}}
+	{{if $.Expr.CanFail -}}
+		goto {{$ok}}
+		{{$fail}}:
+			{{if $.AcceptsPass -}}
+				perr = {{$pre}}max({{$perr0}}, {{$pos0}})
+			{{else -}}
+				failure.Kids = failure.Kids[:{{$nkids}}]
+				if {{$pos0}} >= errPos {
+					failure.Kids = append(failure.Kids, &peg.Fail{
+						Pos: int({{$pos0}}),
+						Want: {{quote $.Expr.Want.String}},
+						Code: peg.NamedRule,
+					})
+				}
+			{{end -}}
+			goto {{$.Fail}}
+		{{$ok}}:
+	{{end -}}
+}
+{{else -}}
+	{{gen $ $.Expr.Expr $.Node $.Fail -}}
+{{end -}}
+`
+
+var predExprTemplate = `// {{$.Expr.String}}
+{
+	{{- $pre := $.Config.Prefix -}}
+	{{- $ok := id "ok" -}}
+	{{- $subExpr := $.Expr.Expr -}}
+	{{- $pos0 := id "pos" -}}
+	{{- $nkids := id "nkids" -}}
+	{{- $perr0 := id "perr" -}}
+	{{$pos0}} := pos
+	{{if $.AcceptsPass -}}
+		{{$perr0}} := perr
+		{{if $.Config.CacheSilentFails -}}
+			parser.silent++
+		{{end -}}
+	{{else if $.NodePass -}}
+		{{$nkids}} := len(node.Kids)
+	{{else if $.FailPass -}}
+		{{$nkids}} := len(failure.Kids)
+	{{end -}}
+
+	{{- if $.Expr.Neg -}}
+		{{if (and $.ActionPass (effects $subExpr)) -}}
+			{{dryRun $ $subExpr $ok -}}
+		{{else -}}
+			{{gen $ $subExpr "" $ok -}}
+		{{end -}}
+		pos = {{$pos0}}
+		{{if $.NodePass -}}
+			node.Kids = node.Kids[:{{$nkids}}]
+		{{else if $.AcceptsPass -}}
+			{{if $.Config.CacheSilentFails -}}
+				parser.silent--
+			{{end -}}
+			perr = {{$pre}}max({{$perr0}}, pos)
+		{{else if $.FailPass -}}
+			failure.Kids = failure.Kids[:{{$nkids}}]
+			if pos >= errPos {
+				failure.Kids = append(failure.Kids, &peg.Fail{
+					Pos: int(pos),
+					Want: {{quote $.Expr.String}},
+					Code: peg.PredicateFailed,
+				})
+			}
+		{{end -}}
+		goto {{$.Fail}}
+	{{else -}}
+		{{- $fail := id "fail" -}}
+		{{if (and $.ActionPass (effects $subExpr)) -}}
+			{{dryRun $ $subExpr $fail -}}
+		{{else -}}
+			{{gen $ $subExpr "" $fail -}}
+		{{end -}}
+		goto {{$ok}}
+		{{$fail}}:
+			pos = {{$pos0}}
+			{{if $.AcceptsPass -}}
+				{{if $.Config.CacheSilentFails -}}
+					parser.silent--
+				{{end -}}
+				perr = {{$pre}}max({{$perr0}}, pos)
+			{{else if $.FailPass -}}
+				failure.Kids = failure.Kids[:{{$nkids}}]
+				if pos >= errPos {
+					failure.Kids = append(failure.Kids, &peg.Fail{
+						Pos: int(pos),
+						Want: {{quote $.Expr.String}},
+						Code: peg.PredicateFailed,
+					})
+				}
+			{{end -}}
+			goto {{$.Fail}}
+	{{end -}}
+
+	{{$ok}}:
+	pos = {{$pos0}}
+	{{if $.AcceptsPass -}}
+		{{if $.Config.CacheSilentFails -}}
+			parser.silent--
+		{{end -}}
+		perr = {{$perr0}}
+	{{else if $.NodePass -}}
+		node.Kids = node.Kids[:{{$nkids}}]
+	{{else if $.FailPass -}}
+		failure.Kids = failure.Kids[:{{$nkids}}]
+	{{else if (and $.ActionPass $.Node) -}}
+		{{$.Node}} = ""
+	{{end -}}
+}
+`
+
+var captureExprTemplate = `// {{$.Expr.String}}
+	{{if (and $.ActionPass $.Node) -}}
+		{
+			{{$pos0 := id "pos" -}}
+			{{$pos0}} := pos
+			{{gen $ $.Expr.Expr "" $.Fail -}}
+			{{$.Node}} = parser.text[{{$pos0}}:pos]
+		}
+	{{else -}}
+		{{gen $ $.Expr.Expr "" $.Fail -}}
+	{{end -}}
+`
+
+// diffExprTemplate matches the Expr,
+// then fails if the Sub matches the same span,
+// undoing the effects of matching the Sub as a predicate does.
+// If the Sub matches, the fails of matching the Expr are undone too,
+// so the error is the difference itself, wanting not the Sub,
+// not a failure to extend the match of the Expr.
+var diffExprTemplate = `// {{$.Expr.String}}
+{
+	{{- $pre := $.Config.Prefix -}}
+	{{- $sub := $.Expr.Sub -}}
+	{{- $start := id "start" -}}
+	{{- $end := id "end" -}}
+	{{- $nkids := id "nkids" -}}
+	{{- $nkidsStart := id "nkids" -}}
+	{{- $perr0 := id "perr" -}}
+	{{- $perrStart := id "perr" -}}
+	{{- $ok := id "ok" -}}
+	{{$start}} := pos
+	{{if $.AcceptsPass -}}
+		{{$perrStart}} := perr
+	{{else if $.FailPass -}}
+		{{$nkidsStart}} := len(failure.Kids)
+	{{end -}}
+	{{gen $ $.Expr.Expr $.Node $.Fail -}}
+	{{$end}} := pos
+	pos = {{$start}}
+	{{if $.AcceptsPass -}}
+		{{$perr0}} := perr
+		{{if $.Config.CacheSilentFails -}}
+			parser.silent++
+		{{end -}}
+	{{else if $.NodePass -}}
+		{{$nkids}} := len(node.Kids)
+	{{else if $.FailPass -}}
+		{{$nkids}} := len(failure.Kids)
+	{{end -}}
+	{{if (and $.ActionPass (effects $sub)) -}}
+		{{dryRun $ $sub $ok -}}
+	{{else -}}
+		{{gen $ $sub "" $ok -}}
+	{{end -}}
+	if pos == {{$end}} {
+		pos = {{$start}}
+		{{if $.NodePass -}}
+			node.Kids = node.Kids[:{{$nkids}}]
+		{{else if $.AcceptsPass -}}
+			{{if $.Config.CacheSilentFails -}}
+				parser.silent--
+			{{end -}}
+			perr = {{$pre}}max({{$perrStart}}, pos)
+		{{else if $.FailPass -}}
+			failure.Kids = failure.Kids[:{{$nkidsStart}}]
+			if pos >= errPos {
+				failure.Kids = append(failure.Kids, &peg.Fail{
+					Pos: int(pos),
+					Want: {{quote (printf "not %s" $sub.String)}},
+					Code: peg.PredicateFailed,
+				})
+			}
+		{{end -}}
+		goto {{$.Fail}}
+	}
+	{{$ok}}:
+	pos = {{$end}}
+	{{if $.AcceptsPass -}}
+		{{if $.Config.CacheSilentFails -}}
+			parser.silent--
+		{{end -}}
+		perr = {{$perr0}}
+	{{else if $.NodePass -}}
+		node.Kids = node.Kids[:{{$nkids}}]
+	{{else if $.FailPass -}}
+		failure.Kids = failure.Kids[:{{$nkids}}]
+	{{end -}}
+}
+`
+
+var repExprTemplate = `// {{$.Expr.String}}
+	{{if (and $.ActionPass $.Node (eq $.Expr.Type "string") (textual $.Expr)) -}}
+		{{template "textValue" $}}
+	{{else -}}
+	{{$nkids := id "nkids" -}}
+	{{$pos0 := id "pos" -}}
+	{{$node := id "node" -}}
+	{{- $fail := id "fail" -}}
+	{{- $subExpr := $.Expr.Expr -}}
+	{{if eq $.Expr.Op '+' -}}
+		{{if (and $.ActionPass $.Node) -}}
+			{
+			var {{$node}} {{$subExpr.Type}}
+			{{gen $ $subExpr $node $.Fail -}}
+			{{if (eq $.Expr.Type "string") -}}
+				{{$.Node}} += {{$node}}
+			{{else -}}
+				{{$.Node}} = append({{$.Node}}, {{$node}})
+			{{end -}}
+			}
+		{{else -}}
+			{{gen $ $subExpr "" $.Fail -}}
+		{{end -}}
+	{{end -}}
+	for {
+		{{if $.NodePass -}}
+			{{$nkids}} := len(node.Kids)
+		{{end -}}
+		{{$pos0}} := pos
+		{{if (and $.ActionPass $.Node) -}}
+			var {{$node}} {{$subExpr.Type}}
+			{{if (and $subExpr.CanFail (effects $subExpr)) -}}
+				{{dryRun $ $subExpr $fail -}}
+			{{end -}}
+			{{gen $ $subExpr $node $fail -}}
+			{{if (eq $.Expr.Type "string") -}}
+				{{$.Node}} += {{$node}}
+			{{else -}}
+				{{$.Node}} = append({{$.Node}}, {{$node}})
+			{{end -}}
+		{{else -}}
+			{{if (and $.ActionPass $subExpr.CanFail (effects $subExpr)) -}}
+				{{dryRun $ $subExpr $fail -}}
+			{{end -}}
+			{{gen $ $subExpr "" $fail -}}
+		{{end -}}
+		continue
+		{{$fail}}:
+			{{if $.NodePass -}}
+				node.Kids = node.Kids[:{{$nkids}}]
+			{{end -}}
+			pos = {{$pos0}}
+			break
+	}
+	{{end -}}
+`
+
+var optExprTemplate = `// {{$.Expr.String}}
+	{{$nkids := id "nkids" -}}
+	{{$pos0 := id "pos" -}}
+	{{- $fail := id "fail" -}}
+	{{- $subExpr := $.Expr.Expr -}}
+	{{- if $subExpr.CanFail -}}
+	{
+		{{if $.NodePass -}}
+			{{$nkids}} := len(node.Kids)
+		{{end -}}
+		{{$pos0}} := pos
+		{{if (and $.ActionPass (effects $subExpr)) -}}
+			{{dryRun $ $subExpr $fail -}}
+		{{end -}}
+		{{if (and $.ActionPass $.Node (eq $subExpr.Type "string")) -}}
+			{{gen $ $subExpr $.Node $fail -}}
+		{{else if (and $.ActionPass $.Node $.Expr.OK) -}}
+			{{gen $ $subExpr (printf "%s.Value" $.Node) $fail -}}
+			{{$.Node}}.OK = true
+		{{else if (and $.ActionPass $.Node) -}}
+			{{$.Node}} = new({{$subExpr.Type}})
+			{{gen $ $subExpr (printf "*%s" $.Node) $fail -}}
+		{{else -}}
+			{{gen $ $subExpr "" $fail -}}
+		{{end -}}
+		{{- $ok := id "ok" -}}
+		goto {{$ok}}
+		{{$fail}}:
+			{{if $.NodePass -}}
+				node.Kids = node.Kids[:{{$nkids}}]
+			{{else if (and $.ActionPass $.Node (eq $subExpr.Type "string")) -}}
+				{{$.Node}} = ""
+			{{else if (and $.ActionPass $.Node $.Expr.OK) -}}
+				{{$.Node}} = {{$.Expr.Type}}{}
+			{{else if (and $.ActionPass $.Node) -}}
+				{{$.Node}} = nil
+			{{end -}}
+			pos = {{$pos0}}
+		{{$ok}}:
+	}
+	{{else -}}
+		{{- /* TODO: disallow this case in check */ -}}
+		{{gen $ $subExpr $fail -}}
+	{{- end -}}
+`
+
+var subExprTemplate = `// {{$.Expr.String}}
+	{{if $.NodePass -}}
+	{
+		{{- $pre := $.Config.Prefix -}}
+		{{$nkids := id "nkids" -}}
+		{{$nkids}} := len(node.Kids)
+		{{$pos0 := id "pos0" -}}
+		{{$pos0}} := pos
+		{{gen $ $.Expr.Expr $.Node $.Fail -}}
+		sub := {{$pre}}sub(parser, {{$pos0}}, pos, node.Kids[{{$nkids}}:])
+		node.Kids = append(node.Kids[:{{$nkids}}], sub)
+	}
+	{{else -}}
+		{{gen $ $.Expr.Expr $.Node $.Fail -}}
+	{{end -}}
+`
+
+// TODO: instead, create a function for each predicate
+// with params that are the parser followed by
+// a string for each defined label.
+// Predicate code shouldn't have access to the label.Kids,
+// because it's undefined for the Accepts and Fail pass.
+// NOTE: kids are OK for actions,
+// because actions are only to be called by the Node pass
+// on a successful parse.
+var predCodeTemplate = `// pred code
+	{{if (and $.Config.SharedMemo $.AcceptsPass) -}}
+		{{- /* The code may depend on anything, so the result is not shared. */ -}}
+		{{$.Config.Prefix}}examine(parser, len(parser.text)+1)
+	{{end -}}
+	if ok := func(
+		{{- if $.Expr.Labels -}}
+			{{range $lexpr := $.Expr.Labels -}}
+				{{$lexpr.Label}} string,
+			{{- end -}}
+		{{- end -}}) bool { return {{$.Expr.Code}} }(
+		{{- if $.Expr.Labels -}}
+			{{range $lexpr := $.Expr.Labels -}}
+				{{if predicate $lexpr.Expr}}""{{else}}labels[{{labelSlot $.Rule $lexpr}}]{{end}},
+			{{- end -}}
+		{{- end -}}
+	); {{if not $.Expr.Neg}}!{{end}}ok {
+		{{if $.AcceptsPass -}}
+			{{- $pre := $.Config.Prefix -}}
+			perr = {{$pre}}max(perr, pos)
+		{{else if $.FailPass -}}
+			if pos >= errPos {
+				failure.Kids = append(failure.Kids, &peg.Fail{
+					Pos: int(pos),
+					Want:
+					{{- if $.Expr.Neg}}"!{"{{else}}"&{"{{end}}+
+					{{- quote $.Expr.Code.String}}+"}",
+					Code: peg.PredicateFailed,
+				})
+			}
+		{{end -}}
+		goto {{$.Fail}}
+	}
+	{{if (and $.ActionPass $.Node) -}}
+		{{$.Node}} = ""
+	{{end -}}
+`
+
+// delegateExprTemplate matches the delimited region
+// and calls the delegate parser on its contents.
+// A failure of the delegate parser is translated by peg.DelegateFail
+// into a Fail at the position within the region.
+var delegateExprTemplate = `// {{$.Expr.String}}
+{
+	{{- $pre := $.Config.Prefix -}}
+	{{- $begin := id "begin" -}}
+	{{- $end := id "end" -}}
+	{{- $fail := id "fail" -}}
+	{{$begin}}, {{$end}}, {{$fail}} := peg.DelegateRegion(parser.text, pos, {{quote $.Expr.Open}}, {{quote $.Expr.Close}})
+	{{if (and $.Config.SharedMemo $.AcceptsPass) -}}
+		if {{$fail}} != nil {
+			{{$pre}}examine(parser, len(parser.text)+1)
+		} else {
+			{{$pre}}examine(parser, {{$end}}+{{len $.Expr.Close}})
+		}
+	{{end -}}
+	if {{$fail}} == nil {
+		{{if (and $.ActionPass $.Node) -}}
+			if v, err := ({{$.Expr.Func}})(parser.text[{{$begin}}:{{$end}}]); err != nil {
+				{{$fail}} = peg.DelegateFail({{$begin}}, err)
+			} else {
+				{{$.Node}} = v
+			}
+		{{else -}}
+			if _, err := ({{$.Expr.Func}})(parser.text[{{$begin}}:{{$end}}]); err != nil {
+				{{$fail}} = peg.DelegateFail({{$begin}}, err)
+			}
+		{{end -}}
+	}
+	if {{$fail}} != nil {
+		{{if $.AcceptsPass -}}
+			perr = {{$pre}}max(perr, {{$fail}}.Pos)
+		{{else if $.FailPass -}}
+			if {{$fail}}.Pos >= errPos {
+				failure.Kids = append(failure.Kids, {{$fail}})
+			}
+		{{end -}}
+		goto {{$.Fail}}
+	}
+	{{if $.NodePass -}}
+		node.Kids = append(node.Kids, {{$pre}}leaf(parser, pos, {{$end}}+{{len $.Expr.Close}}))
+	{{end -}}
+	pos = {{$end}}+{{len $.Expr.Close}}
+}
+`
+
+// nativeExprTemplate calls the native matcher function,
+// which returns the end of its match or the Fail of its failure.
+var nativeExprTemplate = `// {{$.Expr.String}}
+{
+	{{- $pre := $.Config.Prefix -}}
+	{{- $end := id "end" -}}
+	{{- $fail := id "fail" -}}
+	{{if (and $.Config.SharedMemo $.AcceptsPass) -}}
+		{{- /* The function may examine any of the text, so the result is not shared. */ -}}
+		{{$pre}}examine(parser, len(parser.text)+1)
+	{{end -}}
+	{{$end}}, {{$fail}} := ({{$.Expr.Func}})(parser.text, pos)
+	if {{$fail}} != nil {
+		{{if $.AcceptsPass -}}
+			perr = {{$pre}}max(perr, {{$fail}}.Pos)
+		{{else if $.FailPass -}}
+			if {{$fail}}.Pos >= errPos {
+				failure.Kids = append(failure.Kids, {{$fail}})
+			}
+		{{end -}}
+		goto {{$.Fail}}
+	}
+	{{if $.NodePass -}}
+		node.Kids = append(node.Kids, {{$pre}}leaf(parser, pos, {{$end}}))
+	{{else if (and $.ActionPass $.Node) -}}
+		{{$.Node}} = parser.text[pos:{{$end}}]
+	{{end -}}
+	pos = {{$end}}
+}
+`
+
+// blockExprTemplate scans for the end of the block with peg.Balanced or peg.Until,
+// which return the end of the block or the Fail of a missing delimiter.
+var blockExprTemplate = `// {{$.Expr.String}}
+{
+	{{- $pre := $.Config.Prefix -}}
+	{{- $end := id "end" -}}
+	{{- $fail := id "fail" -}}
+	{{if eq $.Expr.Kind "balanced" -}}
+		{{$end}}, {{$fail}} := peg.Balanced(parser.text, pos, {{quote $.Expr.Open}}, {{quote $.Expr.Close}}, {{quote $.Expr.Escape}})
+	{{else -}}
+		{{$end}}, {{$fail}} := peg.Until(parser.text, pos, {{quote $.Expr.Close}}, {{quote $.Expr.Escape}})
+	{{end -}}
+	{{if (and $.Config.SharedMemo $.AcceptsPass) -}}
+		if {{$fail}} != nil {
+			{{$pre}}examine(parser, len(parser.text)+1)
+		} else {
+			{{$pre}}examine(parser, {{$end}})
+		}
+	{{end -}}
+	if {{$fail}} != nil {
+		{{if $.AcceptsPass -}}
+			perr = {{$pre}}max(perr, {{$fail}}.Pos)
+		{{else if $.FailPass -}}
+			if {{$fail}}.Pos >= errPos {
+				failure.Kids = append(failure.Kids, {{$fail}})
+			}
+		{{end -}}
+		goto {{$.Fail}}
+	}
+	{{if $.NodePass -}}
+		node.Kids = append(node.Kids, {{$pre}}leaf(parser, pos, {{$end}}))
+	{{else if (and $.ActionPass $.Node) -}}
+		{{$.Node}} = parser.text[pos:{{$end}}]
+	{{end -}}
+	pos = {{$end}}
+}
+`
+
+var identTemplate = `// {{$.Expr.String}}
+	{{$pre := $.Config.Prefix -}}
+	{{- $name := $.Expr.Name.Ident -}}
+	{{if $.Expr.CallArgs -}}
+		{{template "callTemplate" $}}
+	{{else if not $.Expr.CanFail -}}
+		{{/* The rule never fails, so there is no failure to check. */ -}}
+		{{if $.AcceptsPass -}}
+			{{$pre}}accept(parser, {{$pre}}{{$name}}Accepts, &pos, &perr)
+		{{else if $.NodePass -}}
+			{{$pre}}node(parser, {{$pre}}{{$name}}Node, node, &pos)
+		{{else if $.FailPass -}}
+			{{$pre}}fail(parser, {{$pre}}{{$name}}Fail, errPos, failure, &pos)
+		{{else if $.ActionPass -}}
+			{
+				p, {{if $.Node}}n{{else}}_{{end}} := {{$pre}}{{$name}}Action(parser, pos)
+				{{if $.Node -}}
+					{{$.Node}} = *n
+				{{end -}}
+				pos = p
+			}
+		{{end -}}
+	{{else if $.AcceptsPass -}}
+		if !{{$pre}}accept(parser, {{$pre}}{{$name}}Accepts, &pos, &perr) {
+			goto {{$.Fail}}
+		}
+	{{else if $.NodePass -}}
+		if !{{$pre}}node(parser, {{$pre}}{{$name}}Node, node, &pos) {
+			goto {{$.Fail}}
+		}
+	{{else if $.FailPass -}}
+		if !{{$pre}}fail(parser, {{$pre}}{{$name}}Fail, errPos, failure, &pos) {
+			goto {{$.Fail}}
+		}
+	{{else if $.ActionPass -}}
+		if p, n := {{$pre}}{{$name}}Action(parser, pos); n == nil {
+			goto {{$.Fail}}
+		} else {
+			{{if (and $.ActionPass $.Node) -}}
+				{{$.Node}} = *n
+			{{end -}}
+			pos = p
+		}
+	{{end -}}
+`
+
+// callTemplate is the identTemplate for an identifier
+// that passes arguments to the parameters of its rule.
+var callTemplate = `
+	{{- $pre := $.Config.Prefix -}}
+	{{- $name := $.Expr.Name.Ident -}}
+	{{- $args := $.Expr.CallArgs.String -}}
+	{{if $.AcceptsPass -}}
+		{
+			dp, de := {{$pre}}{{$name}}Accepts(parser, pos, {{$args}})
+			perr = {{$pre}}max(perr, pos+de)
+			{{if $.Expr.CanFail -}}
+				if dp < 0 {
+					goto {{$.Fail}}
+				}
+			{{end -}}
+			pos += dp
+		}
+	{{else if not $.Expr.CanFail -}}
+		{{if $.NodePass -}}
+			{
+				p, kid := {{$pre}}{{$name}}Node(parser, pos, {{$args}})
+				node.Kids = append(node.Kids, kid)
+				pos = p
+			}
+		{{else if $.FailPass -}}
+			{
+				p, kid := {{$pre}}{{$name}}Fail(parser, pos, errPos, {{$args}})
+				if kid.Want != "" || len(kid.Kids) > 0 {
+					failure.Kids = append(failure.Kids, kid)
+				}
+				pos = p
+			}
+		{{else if $.ActionPass -}}
+			{
+				p, {{if $.Node}}n{{else}}_{{end}} := {{$pre}}{{$name}}Action(parser, pos, {{$args}})
+				{{if $.Node -}}
+					{{$.Node}} = *n
+				{{end -}}
+				pos = p
+			}
+		{{end -}}
+	{{else if $.NodePass -}}
+		if p, kid := {{$pre}}{{$name}}Node(parser, pos, {{$args}}); kid == nil {
+			goto {{$.Fail}}
+		} else {
+			node.Kids = append(node.Kids, kid)
+			pos = p
+		}
+	{{else if $.FailPass -}}
+		{
+			p, kid := {{$pre}}{{$name}}Fail(parser, pos, errPos, {{$args}})
+			if kid.Want != "" || len(kid.Kids) > 0 {
+				failure.Kids = append(failure.Kids, kid)
+			}
+			if p < 0 {
+				goto {{$.Fail}}
+			}
+			pos = p
+		}
+	{{else if $.ActionPass -}}
+		if p, n := {{$pre}}{{$name}}Action(parser, pos, {{$args}}); n == nil {
+			goto {{$.Fail}}
+		} else {
+			{{if (and $.ActionPass $.Node) -}}
+				{{$.Node}} = *n
+			{{end -}}
+			pos = p
+		}
+	{{end -}}
+`
+
+var literalTemplate = `// {{$.Expr.String}}
+	{{$want := quote $.Expr.Text.String -}}
+	{{- $n := len $.Expr.Text.String -}}
+	{{if (and $.Config.SharedMemo $.AcceptsPass) -}}
+		{{$.Config.Prefix}}examine(parser, pos+{{$n}})
+	{{end -}}
+	{{if eq $n 1 -}}
+		if pos >= len(parser.text) || parser.text[pos] != {{$want}}[0] {
+	{{- else -}}
+		if len(parser.text)-pos < {{$n}} || parser.text[pos:pos+{{$n}}] != {{$want}} {
+	{{- end}}
+		{{if $.AcceptsPass -}}
+			{{- $pre := $.Config.Prefix -}}
+			perr = {{$pre}}max(perr, pos)
+		{{else if $.FailPass -}}
+			if pos >= errPos {
+				failure.Kids = append(failure.Kids, &peg.Fail{
+					Pos: int(pos),
+					Want: {{quote $.Expr.String}},
+					Code: peg.ExpectedLiteral,
+				})
+			}
+		{{end -}}
+		goto {{$.Fail}}
+	}
+	{{$pre := $.Config.Prefix -}}
+	{{if $.NodePass -}}
+		node.Kids = append(node.Kids, {{$pre}}leaf(parser, pos, pos + {{$n}}))
+	{{else if (and $.ActionPass $.Node) -}}
+		{{$.Node}} = parser.text[pos:pos+{{$n}}]
+	{{end -}}
+	{{if eq $n 1 -}}
+		pos++
+	{{- else -}}
+		pos += {{$n}}
+	{{- end}}
+`
+
+// cutTemplate commits the branch of the enclosing choice.
+var cutTemplate = `// {{$.Expr.String}}
+	{{if $.Cut -}}
+		{{$.Cut}} = true
+	{{end -}}
+	{{if (and $.ActionPass $.Node) -}}
+		{{$.Node}} = ""
+	{{end -}}
+`
+
+// emptyTemplate matches the empty string.
+var emptyTemplate = `// {{$.Expr.String}}
+	{{if (and $.ActionPass $.Node) -}}
+		{{$.Node}} = ""
+	{{end -}}
+`
+
+var anyTemplate = `// {{$.Expr.String}}
+	{{$pre := $.Config.Prefix -}}
+	{{- /* \uFFFD is utf8.RuneError */ -}}
+	{{if $.Grammar.InvalidBytes -}}
+		if _, w := {{$pre}}next(parser, pos); w == 0 {
+	{{- else -}}
+		if r, w := {{$pre}}next(parser, pos); w == 0 || r == '\uFFFD' {
+	{{- end}}
+		{{if $.AcceptsPass -}}
+			{{- $pre := $.Config.Prefix -}}
+			perr = {{$pre}}max(perr, pos)
+		{{else if $.FailPass -}}
+			if pos >= errPos {
+				failure.Kids = append(failure.Kids, &peg.Fail{
+					Pos: int(pos),
+					Want: ".",
+					Code: peg.ExpectedAny,
+				})
+			}
+		{{end -}}
+		goto {{$.Fail}}
+	} else {
+		{{$pre := $.Config.Prefix -}}
+		{{if $.NodePass -}}
+			node.Kids = append(node.Kids, {{$pre}}leaf(parser, pos, pos + w))
+		{{else if (and $.ActionPass $.Node) -}}
+			{{$.Node}} = parser.text[pos:pos+w]
+		{{end -}}
+		pos += w
+	}
+`
+
+// charClassCondition emits the if-condition for a character class,
+// assuming that r and w are the rune and its width respectively.
+var charClassCondition = `
+	{{- /* \uFFFD is utf8.RuneError */ -}}
+	{{- if $.Expr.Neg -}}
+		w == 0
+		{{- if not $.Grammar.InvalidBytes}} || r == '\uFFFD'{{end}}
+	{{- else if not $.Expr.Spans -}}
+		true
+	{{- end}}
+	{{- range $i, $span := $.Expr.Spans -}}
+		{{- $first := index $span 0 -}}
+		{{- $second := index $span 1 -}}
+		{{- if $.Expr.Neg -}}
+			{{- " || " -}}
+			{{- if eq $first $second -}}
+				r == {{quoteRune $first}}
+			{{- else -}}
+				(r >= {{quoteRune $first}} && r <= {{quoteRune $second}})
+			{{- end -}}
+		{{- else -}}
+			{{- if gt $i 0}} && {{end -}}
+			{{- if eq $first $second -}}
+				r != {{quoteRune $first}}
+			{{- else -}}
+				(r < {{quoteRune $first}} ||  r > {{quoteRune $second}})
+			{{- end -}}
+		{{- end -}}
+	{{- end -}}
+`
+
+var charClassTemplate = `// {{$.Expr.String}}
+	{{$pre := $.Config.Prefix -}}
+	if r, w := {{$pre}}next(parser, pos);
+		{{- with $a := $.ASCIIClass $.Expr}}
+			r < 0x80 && (uint64({{$a.Lo}})>>uint(r)|uint64({{$a.Hi}})>>uint(r-64))&1 == 0 ||
+			{{- if (or $a.Rest.Neg $a.Rest.Spans)}}
+				r >= 0x80 && ({{template "charClassCondition" ($.WithExpr $a.Rest)}})
+			{{- else}}
+				r >= 0x80
+			{{- end}}
+		{{- else}}
+			{{template "charClassCondition" $}}
+		{{- end}} {
+		{{if $.AcceptsPass -}}
+			{{- $pre := $.Config.Prefix -}}
+			perr = {{$pre}}max(perr, pos)
+		{{else if $.FailPass -}}
+			if pos >= errPos {
+				failure.Kids = append(failure.Kids, &peg.Fail{
+					Pos: int(pos),
+					Want: {{quote $.Expr.String}},
+					Code: {{if $.Expr.NoCase}}peg.ExpectedLiteral{{else}}peg.ExpectedClass{{end}},
+				})
+			}
+		{{end -}}
+		goto {{$.Fail}}
+	} else {
+		{{$pre := $.Config.Prefix -}}
+		{{if $.NodePass -}}
+			{{$pre := $.Config.Prefix -}}
+			node.Kids = append(node.Kids, {{$pre}}leaf(parser, pos, pos + w))
+		{{else if (and $.ActionPass $.Node) -}}
+			{{$.Node}} = parser.text[pos:pos+w]
+		{{end -}}
+		pos += w
+	}
+`
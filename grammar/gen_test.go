@@ -0,0 +1,5578 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd
+
+package grammar
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/peggy/peg"
+	"github.com/eaburns/pretty"
+)
+
+type genTest struct {
+	grammar string
+	cases   []genTestCase
+}
+
+type genTestCase struct {
+	name  string
+	input string
+	pos   int
+	node  *peg.Node
+	fail  *peg.Fail
+}
+
+// TODO: add the bug case.
+var genTests = []genTest{
+	{
+		// "start" is an internal identifier name. There should be no conflict.
+		grammar: `A <- start:'abc' &{ start == "abc" } 'xyz'`,
+		cases: []genTestCase{
+			{
+				name:  "label name conflicts with parser internal variable",
+				input: "abcxyz",
+				pos:   len("abcxyz"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abcxyz",
+					Kids: []*peg.Node{
+						{Text: "abc"},
+						{Text: "xyz"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `A <- L:&'abc' &{L == ""} "abc"`,
+		cases: []genTestCase{
+			{
+				name:  "label pred expr",
+				input: "abc",
+				pos:   len("abc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abc",
+					Kids: []*peg.Node{
+						{Text: "abc"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `A <- L:'abc'* &{L == ""} 'xyz'`,
+		cases: []genTestCase{
+			{
+				name:  "label rep expr none",
+				input: "xyz",
+				pos:   len("xyz"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "xyz",
+					Kids: []*peg.Node{
+						{Text: "xyz"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `A <- L:'abc'* &{L == "abc"} 'xyz'`,
+		cases: []genTestCase{
+			{
+				name:  "label rep expr one",
+				input: "abcxyz",
+				pos:   len("abcxyz"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abcxyz",
+					Kids: []*peg.Node{
+						{Text: "abc"},
+						{Text: "xyz"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `A <- L:'abc'* &{L == "abcabcabc"} 'xyz'`,
+		cases: []genTestCase{
+			{
+				name:  "label rep expr many",
+				input: "abcabcabcxyz",
+				pos:   len("abcabcabcxyz"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abcabcabcxyz",
+					Kids: []*peg.Node{
+						{Text: "abc"},
+						{Text: "abc"},
+						{Text: "abc"},
+						{Text: "xyz"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `A <- L:'abc'? &{L == ""} 'xyz'`,
+		cases: []genTestCase{
+			{
+				name:  "label opt expr empty",
+				input: "xyz",
+				pos:   len("xyz"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "xyz",
+					Kids: []*peg.Node{
+						{Text: "xyz"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `A <- L:'abc'? &{L == "abc"} 'xyz'`,
+		cases: []genTestCase{
+			{
+				name:  "label opt expr non-empty",
+				input: "abcxyz",
+				pos:   len("abcxyz"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abcxyz",
+					Kids: []*peg.Node{
+						{Text: "abc"},
+						{Text: "xyz"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- L:B &{L == `abc`} 'xyz'\nB <- 'abc'",
+		cases: []genTestCase{
+			{
+				name:  "label ident",
+				input: "abcxyz",
+				pos:   len("abcxyz"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abcxyz",
+					Kids: []*peg.Node{
+						{
+							Name: "B",
+							Text: "abc",
+							Kids: []*peg.Node{{Text: "abc"}},
+						},
+						{Text: "xyz"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- L:('123' / 'abc') &{L == `abc`} 'xyz'",
+		cases: []genTestCase{
+			{
+				name:  "label subexpr",
+				input: "abcxyz",
+				pos:   len("abcxyz"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abcxyz",
+					Kids: []*peg.Node{
+						{
+							Text: "abc",
+							Kids: []*peg.Node{{Text: "abc"}},
+						},
+						{Text: "xyz"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- L:&{true} &{L == ``} 'xyz'",
+		cases: []genTestCase{
+			{
+				name:  "label predcode",
+				input: "xyz",
+				pos:   len("xyz"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "xyz",
+					Kids: []*peg.Node{
+						{Text: "xyz"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- L:'abc' &{L == `abc`} 'xyz'",
+		cases: []genTestCase{
+			{
+				name:  "label literal",
+				input: "abcxyz",
+				pos:   len("abcxyz"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abcxyz",
+					Kids: []*peg.Node{
+						{Text: "abc"},
+						{Text: "xyz"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- L:[a-z] &{L == `n`} 'xyz'",
+		cases: []genTestCase{
+			{
+				name:  "label charclass",
+				input: "nxyz",
+				pos:   len("nxyz"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "nxyz",
+					Kids: []*peg.Node{
+						{Text: "n"},
+						{Text: "xyz"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- L:. &{L == `α`} 'xyz'",
+		cases: []genTestCase{
+			{
+				name:  "label any",
+				input: "αxyz",
+				pos:   len("αxyz"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "αxyz",
+					Kids: []*peg.Node{
+						{Text: "α"},
+						{Text: "xyz"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- one:. two:. three:. &{one == `1` && two == `2` && three == `3`}",
+		cases: []genTestCase{
+			{
+				name:  "label multiple",
+				input: "123",
+				pos:   len("123"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "123",
+					Kids: []*peg.Node{
+						{Text: "1"},
+						{Text: "2"},
+						{Text: "3"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- abc:(ab:(a:'a' 'b') 'c') &{abc == `abc` && ab == `ab` && a == `a`}",
+		cases: []genTestCase{
+			{
+				name:  "nested labels",
+				input: "abc",
+				pos:   len("abc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abc",
+					Kids: []*peg.Node{
+						{
+							Text: "abc",
+							Kids: []*peg.Node{
+								{
+									Text: "ab",
+									Kids: []*peg.Node{
+										{Text: "a"},
+										{Text: "b"},
+									},
+								},
+								{Text: "c"},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `A <- L:'abc'* &{L == ""} !.`,
+		cases: []genTestCase{
+			{
+				name:  "predcode with label mismatch",
+				input: "abc",
+				pos:   len("abc"),
+				fail: &peg.Fail{
+					Name: "A",
+					Pos:  0,
+					Kids: []*peg.Fail{
+						{
+							Pos:  len("abc"),
+							Want: `"abc"`,
+							Code: peg.ExpectedLiteral,
+						},
+						{
+							Pos:  len("abc"),
+							Want: `&{L == ""}`,
+							Code: peg.PredicateFailed,
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `A <- "a" ^ "b" / "a" "c" / "d"`,
+		cases: []genTestCase{
+			{
+				name:  "cut branch match",
+				input: "ab",
+				pos:   len("ab"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "ab",
+					Kids: []*peg.Node{
+						{Text: "a"},
+						{Text: "b"},
+					},
+				},
+			},
+			{
+				name:  "cut branch commits",
+				input: "ac",
+				pos:   len("a"),
+				fail: &peg.Fail{
+					Name: "A",
+					Pos:  0,
+					Kids: []*peg.Fail{
+						{
+							Pos:  len("a"),
+							Want: `"b"`,
+							Code: peg.ExpectedLiteral,
+						},
+					},
+				},
+			},
+			{
+				name:  "fail before cut tries next branch",
+				input: "d",
+				pos:   len("d"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "d",
+					Kids: []*peg.Node{
+						{Text: "d"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `A <- L:("x" / ()) &{L == "" || L == "x"} "y"`,
+		cases: []genTestCase{
+			{
+				name:  "empty expression matches the empty string",
+				input: "y",
+				pos:   len("y"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "y",
+					Kids: []*peg.Node{
+						{Text: ""},
+						{Text: "y"},
+					},
+				},
+			},
+			{
+				name:  "branch before the empty expression",
+				input: "xy",
+				pos:   len("xy"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "xy",
+					Kids: []*peg.Node{
+						{
+							Text: "x",
+							Kids: []*peg.Node{
+								{Text: "x"},
+							},
+						},
+						{Text: "y"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `A <- "a" ([0-9]+ "." [0-9]+) !> "a number" ";"`,
+		cases: []genTestCase{
+			{
+				name:  "described expression match",
+				input: "a1.2;",
+				pos:   len("a1.2;"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "a1.2;",
+					Kids: []*peg.Node{
+						{Text: "a"},
+						{
+							Text: "1.2",
+							Kids: []*peg.Node{
+								{Text: "1"},
+								{Text: "."},
+								{Text: "2"},
+							},
+						},
+						{Text: ";"},
+					},
+				},
+			},
+			{
+				name:  "described expression fails",
+				input: "a1.;",
+				pos:   len("a"),
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Pos:  len("a"),
+							Want: "a number",
+							Code: peg.NamedRule,
+						},
+					},
+				},
+			},
+			{
+				name:  "fail after described expression",
+				input: "a1.2x",
+				pos:   len("a1.2"),
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Pos:  len("a1.2"),
+							Want: `";"`,
+							Code: peg.ExpectedLiteral,
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `A <- "<!--" %until("-->") %balanced("(", ")", "\\")`,
+		cases: []genTestCase{
+			{
+				name:  "blocks match",
+				input: `<!-- x -->(a(b)\))`,
+				pos:   len(`<!-- x -->(a(b)\))`),
+				node: &peg.Node{
+					Name: "A",
+					Text: `<!-- x -->(a(b)\))`,
+					Kids: []*peg.Node{
+						{Text: "<!--"},
+						{Text: " x -->"},
+						{Text: `(a(b)\))`},
+					},
+				},
+			},
+			{
+				name:  "until missing close",
+				input: "<!-- x",
+				pos:   len("<!-- x"),
+				fail: &peg.Fail{
+					Name: "A",
+					Pos:  0,
+					Kids: []*peg.Fail{
+						{
+							Pos:  len("<!-- x"),
+							Want: `"-->"`,
+							Code: peg.ExpectedLiteral,
+						},
+					},
+				},
+			},
+			{
+				name:  "balanced missing close",
+				input: "<!---->(a(b)",
+				pos:   len("<!---->(a(b)"),
+				fail: &peg.Fail{
+					Name: "A",
+					Pos:  0,
+					Kids: []*peg.Fail{
+						{
+							Pos:  len("<!---->(a(b)"),
+							Want: `")"`,
+							Code: peg.ExpectedLiteral,
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- &{ true }",
+		cases: []genTestCase{
+			{
+				name:  "predcode match",
+				input: "☺☹",
+				pos:   0,
+				node: &peg.Node{
+					Name: "A",
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- &{ false }",
+		cases: []genTestCase{
+			{
+				name:  "predcode mismatch",
+				input: "☺☹",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Pos:  0,
+					Kids: []*peg.Fail{
+						{
+							Pos:  0,
+							Want: "&{ false }",
+							Code: peg.PredicateFailed,
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- !{ false }",
+		cases: []genTestCase{
+			{
+				name:  "neg predcode match",
+				input: "☺☹",
+				pos:   0,
+				node: &peg.Node{
+					Name: "A",
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- !{ true }",
+		cases: []genTestCase{
+			{
+				name:  "neg predcode mismatch",
+				input: "☺☹",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Pos:  0,
+					Kids: []*peg.Fail{
+						{
+							Pos:  0,
+							Want: "!{ true }",
+							Code: peg.PredicateFailed,
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- 'abc'",
+		cases: []genTestCase{
+			{
+				name:  "literal match",
+				input: "abc",
+				pos:   len("abc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abc",
+					Kids: []*peg.Node{{Text: "abc"}},
+				},
+			},
+			{
+				name:  "literal mismatch",
+				input: "abz",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `"abc"`, Code: peg.ExpectedLiteral},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- 'αβξ'",
+		cases: []genTestCase{
+			{
+				name:  "literal match non-ASCII",
+				input: "αβξ",
+				pos:   len("αβξ"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "αβξ",
+					Kids: []*peg.Node{{Text: "αβξ"}},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- .",
+		cases: []genTestCase{
+			{
+				name:  "any match",
+				input: "abc",
+				pos:   len("a"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "a",
+					Kids: []*peg.Node{{Text: "a"}},
+				},
+			},
+			{
+				name:  "any match non-ASCII",
+				input: "αβξ",
+				pos:   len("α"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "α",
+					Kids: []*peg.Node{{Text: "α"}},
+				},
+			},
+			{
+				name:  "any mismatch",
+				input: "",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `.`, Code: peg.ExpectedAny},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- [abcA-C☹☺α-ξ]",
+		cases: []genTestCase{
+			{
+				name:  "charclass match rune",
+				input: "a",
+				pos:   len("a"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "a",
+					Kids: []*peg.Node{{Text: "a"}},
+				},
+			},
+			{
+				name:  "charclass match range",
+				input: "B",
+				pos:   len("B"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "B",
+					Kids: []*peg.Node{{Text: "B"}},
+				},
+			},
+			{
+				name:  "charclass match non-ASCII rune",
+				input: "☺",
+				pos:   len("☺"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "☺",
+					Kids: []*peg.Node{{Text: "☺"}},
+				},
+			},
+			{
+				name:  "charclass match non-ASCII range",
+				input: "β",
+				pos:   len("β"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "β",
+					Kids: []*peg.Node{{Text: "β"}},
+				},
+			},
+			{
+				name:  "charclass mismatch rune",
+				input: "z",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `[abcA-C☹☺α-ξ]`, Code: peg.ExpectedClass},
+					},
+				},
+			},
+			{
+				name:  "charclass mismatch before range",
+				input: "@", // just before A
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `[abcA-C☹☺α-ξ]`, Code: peg.ExpectedClass},
+					},
+				},
+			},
+			{
+				name:  "charclass mismatch after range",
+				input: "D", // just after C
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `[abcA-C☹☺α-ξ]`, Code: peg.ExpectedClass},
+					},
+				},
+			},
+			{
+				name:  "charclass mismatch non-ASCII rune",
+				input: "·",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `[abcA-C☹☺α-ξ]`, Code: peg.ExpectedClass},
+					},
+				},
+			},
+		},
+	},
+	{
+		// ? and @ are on either side of the boundary
+		// between the words of the ASCII bit mask.
+		grammar: "A <- [?@] [^?@]",
+		cases: []genTestCase{
+			{
+				name:  "charclass mask low word",
+				input: "?x",
+				pos:   len("?x"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "?x",
+					Kids: []*peg.Node{{Text: "?"}, {Text: "x"}},
+				},
+			},
+			{
+				name:  "charclass mask high word",
+				input: "@☺",
+				pos:   len("@☺"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "@☺",
+					Kids: []*peg.Node{{Text: "@"}, {Text: "☺"}},
+				},
+			},
+			{
+				name:  "neg charclass mask mismatch",
+				input: "@?",
+				pos:   1,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Pos: 1, Want: `[^?@]`, Code: peg.ExpectedClass},
+					},
+				},
+			},
+			{
+				name:  "charclass mask mismatch",
+				input: "A",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `[?@]`, Code: peg.ExpectedClass},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- [^abcA-C☹☺α-ξ]",
+		cases: []genTestCase{
+			{
+				name:  "neg charclass match rune",
+				input: "z",
+				pos:   len("z"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "z",
+					Kids: []*peg.Node{{Text: "z"}},
+				},
+			},
+			{
+				name:  "neg charclass match before range",
+				input: "@", // just before A
+				pos:   len("@"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "@",
+					Kids: []*peg.Node{{Text: "@"}},
+				},
+			},
+			{
+				name:  "neg charclass match after range",
+				input: "D", // just after C
+				pos:   len("D"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "D",
+					Kids: []*peg.Node{{Text: "D"}},
+				},
+			},
+			{
+				name:  "neg charclass match non-ASCII rune",
+				input: "·",
+				pos:   len("·"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "·",
+					Kids: []*peg.Node{{Text: "·"}},
+				},
+			},
+			{
+				name:  "neg charclass mismatch rune",
+				input: "a",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `[^abcA-C☹☺α-ξ]`, Code: peg.ExpectedClass},
+					},
+				},
+			},
+			{
+				name:  "neg charclass mismatch begin range",
+				input: "A",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `[^abcA-C☹☺α-ξ]`, Code: peg.ExpectedClass},
+					},
+				},
+			},
+			{
+				name:  "neg charclass mismatch mid range",
+				input: "B",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `[^abcA-C☹☺α-ξ]`, Code: peg.ExpectedClass},
+					},
+				},
+			},
+			{
+				name:  "neg charclass mismatch end range",
+				input: "C",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `[^abcA-C☹☺α-ξ]`, Code: peg.ExpectedClass},
+					},
+				},
+			},
+			{
+				name:  "neg charclass mismatch non-ASCII rune",
+				input: "☺",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `[^abcA-C☹☺α-ξ]`, Code: peg.ExpectedClass},
+					},
+				},
+			},
+			{
+				name:  "neg charclass mismatch begin non-ASCII range",
+				input: "α",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `[^abcA-C☹☺α-ξ]`, Code: peg.ExpectedClass},
+					},
+				},
+			},
+			{
+				name:  "neg charclass mismatch mid non-ASCII range",
+				input: "β",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `[^abcA-C☹☺α-ξ]`, Code: peg.ExpectedClass},
+					},
+				},
+			},
+			{
+				name:  "neg charclass mismatch end non-ASCII range",
+				input: "ξ",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `[^abcA-C☹☺α-ξ]`, Code: peg.ExpectedClass},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- B\nB <- 'abc'",
+		cases: []genTestCase{
+			{
+				name:  "ident match",
+				input: "abc",
+				pos:   len("abc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abc",
+					Kids: []*peg.Node{
+						{
+							Name: "B",
+							Text: "abc",
+							Kids: []*peg.Node{{Text: "abc"}},
+						},
+					},
+				},
+			},
+			{
+				name:  "ident mismatch",
+				input: "abz",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Name: "B",
+							Kids: []*peg.Fail{
+								{Want: `"abc"`, Code: peg.ExpectedLiteral},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- 'abc'*",
+		cases: []genTestCase{
+			{
+				name:  "star match 0",
+				input: "xyz",
+				pos:   0,
+				node: &peg.Node{
+					Name: "A",
+				},
+			},
+			{
+				name:  "star match 0 EOF",
+				input: "xyz",
+				pos:   0,
+				node: &peg.Node{
+					Name: "A",
+				},
+			},
+			{
+				name:  "star match 1",
+				input: "abc",
+				pos:   len("abc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abc",
+					Kids: []*peg.Node{{Text: "abc"}},
+				},
+			},
+			{
+				name:  "star match >1",
+				input: "abcabcabcxyz",
+				pos:   len("abcabcabc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abcabcabc",
+					Kids: []*peg.Node{
+						{Text: "abc"},
+						{Text: "abc"},
+						{Text: "abc"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- .*",
+		cases: []genTestCase{
+			{
+				name:  "star any",
+				input: "abc",
+				pos:   len("abc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abc",
+					Kids: []*peg.Node{
+						{Text: "a"},
+						{Text: "b"},
+						{Text: "c"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- [abc]*",
+		cases: []genTestCase{
+			{
+				name:  "star charclass",
+				input: "abc",
+				pos:   len("abc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abc",
+					Kids: []*peg.Node{
+						{Text: "a"},
+						{Text: "b"},
+						{Text: "c"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- [^abc]*",
+		cases: []genTestCase{
+			{
+				name:  "star neg charclass",
+				input: "XYZ",
+				pos:   len("XYZ"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "XYZ",
+					Kids: []*peg.Node{
+						{Text: "X"},
+						{Text: "Y"},
+						{Text: "Z"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- B*\nB <- 'abc'",
+		cases: []genTestCase{
+			{
+				name:  "star ident",
+				input: "abcabc",
+				pos:   len("abcabc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abcabc",
+					Kids: []*peg.Node{
+						{
+							Name: "B",
+							Text: "abc",
+							Kids: []*peg.Node{{Text: "abc"}},
+						},
+						{
+							Name: "B",
+							Text: "abc",
+							Kids: []*peg.Node{{Text: "abc"}},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- ('a' 'b' 'c')*",
+		cases: []genTestCase{
+			{
+				name:  "star subexpr",
+				input: "abcabc",
+				pos:   len("abcabc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abcabc",
+					Kids: []*peg.Node{
+						{
+							Text: "abc",
+							Kids: []*peg.Node{
+								{Text: "a"},
+								{Text: "b"},
+								{Text: "c"},
+							},
+						},
+						{
+							Text: "abc",
+							Kids: []*peg.Node{
+								{Text: "a"},
+								{Text: "b"},
+								{Text: "c"},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- 'abc'+",
+		cases: []genTestCase{
+			{
+				name:  "plus match 1",
+				input: "abc",
+				pos:   len("abc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abc",
+					Kids: []*peg.Node{{Text: "abc"}},
+				},
+			},
+			{
+				name:  "plus match >1",
+				input: "abcabcabcxyz",
+				pos:   len("abcabcabc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abcabcabc",
+					Kids: []*peg.Node{
+						{Text: "abc"},
+						{Text: "abc"},
+						{Text: "abc"},
+					},
+				},
+			},
+			{
+				name:  "plus mismatch",
+				input: "xyz",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `"abc"`, Code: peg.ExpectedLiteral},
+					},
+				},
+			},
+			{
+				name:  "plus mismatch EOF",
+				input: "",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `"abc"`, Code: peg.ExpectedLiteral},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- .+",
+		cases: []genTestCase{
+			{
+				name:  "plus any match",
+				input: "abc",
+				pos:   len("abc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abc",
+					Kids: []*peg.Node{
+						{Text: "a"},
+						{Text: "b"},
+						{Text: "c"},
+					},
+				},
+			},
+			{
+				name:  "plus any mismatch",
+				input: "",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `.`, Code: peg.ExpectedAny},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- [abc]+",
+		cases: []genTestCase{
+			{
+				name:  "plus charclass match",
+				input: "abc",
+				pos:   len("abc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abc",
+					Kids: []*peg.Node{
+						{Text: "a"},
+						{Text: "b"},
+						{Text: "c"},
+					},
+				},
+			},
+			{
+				name:  "plus charclass mismatch",
+				input: "XYZ",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `[abc]`, Code: peg.ExpectedClass},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- [^abc]+",
+		cases: []genTestCase{
+			{
+				name:  "plus neg charclass match",
+				input: "XYZ",
+				pos:   len("XYZ"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "XYZ",
+					Kids: []*peg.Node{
+						{Text: "X"},
+						{Text: "Y"},
+						{Text: "Z"},
+					},
+				},
+			},
+			{
+				name:  "plus neg charclass mismatch",
+				input: "abc",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `[^abc]`, Code: peg.ExpectedClass},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- B+\nB <- 'abc'",
+		cases: []genTestCase{
+			{
+				name:  "plus ident match",
+				input: "abcabc",
+				pos:   len("abcabc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abcabc",
+					Kids: []*peg.Node{
+						{
+							Name: "B",
+							Text: "abc",
+							Kids: []*peg.Node{{Text: "abc"}},
+						},
+						{
+							Name: "B",
+							Text: "abc",
+							Kids: []*peg.Node{{Text: "abc"}},
+						},
+					},
+				},
+			},
+			{
+				name:  "plus ident mismatch",
+				input: "xyz",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Name: "B",
+							Kids: []*peg.Fail{
+								{Want: `"abc"`, Code: peg.ExpectedLiteral},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- ('a' 'b' 'c')+",
+		cases: []genTestCase{
+			{
+				name:  "plus subexpr match",
+				input: "abcabc",
+				pos:   len("abcabc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abcabc",
+					Kids: []*peg.Node{
+						{
+							Text: "abc",
+							Kids: []*peg.Node{
+								{Text: "a"},
+								{Text: "b"},
+								{Text: "c"},
+							},
+						},
+						{
+							Text: "abc",
+							Kids: []*peg.Node{
+								{Text: "a"},
+								{Text: "b"},
+								{Text: "c"},
+							},
+						},
+					},
+				},
+			},
+			{
+				name:  "plus subexpr mismatch",
+				input: "xyz",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `"a"`, Code: peg.ExpectedLiteral},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- 'abc'?",
+		cases: []genTestCase{
+			{
+				name:  "question match 0",
+				input: "xyz",
+				pos:   0,
+				node: &peg.Node{
+					Name: "A",
+				},
+			},
+			{
+				name:  "question match 0 EOF",
+				input: "xyz",
+				pos:   0,
+				node: &peg.Node{
+					Name: "A",
+				},
+			},
+			{
+				name:  "question match 1",
+				input: "abcabc",
+				pos:   len("abc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abc",
+					Kids: []*peg.Node{{Text: "abc"}},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- .?",
+		cases: []genTestCase{
+			{
+				name:  "question any",
+				input: "a",
+				pos:   len("a"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "a",
+					Kids: []*peg.Node{{Text: "a"}},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- [abc]?",
+		cases: []genTestCase{
+			{
+				name:  "question charclass",
+				input: "a",
+				pos:   len("a"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "a",
+					Kids: []*peg.Node{{Text: "a"}},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- [^abc]?",
+		cases: []genTestCase{
+			{
+				name:  "question neg charclass",
+				input: "X",
+				pos:   len("X"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "X",
+					Kids: []*peg.Node{{Text: "X"}},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- B?\nB <- 'abc'",
+		cases: []genTestCase{
+			{
+				name:  "question ident",
+				input: "abc",
+				pos:   len("abc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abc",
+					Kids: []*peg.Node{
+						{
+							Name: "B",
+							Text: "abc",
+							Kids: []*peg.Node{{Text: "abc"}},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- ('a' 'b' 'c')?",
+		cases: []genTestCase{
+			{
+				name:  "question match subexpr",
+				input: "abc",
+				pos:   len("abc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abc",
+					Kids: []*peg.Node{
+						{
+							Text: "abc",
+							Kids: []*peg.Node{
+								{Text: "a"},
+								{Text: "b"},
+								{Text: "c"},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- &'abc'",
+		cases: []genTestCase{
+			{
+				name:  "pos pred match",
+				input: "abc",
+				pos:   0,
+				node: &peg.Node{
+					Name: "A",
+				},
+			},
+			{
+				name:  "pos pred mismatch",
+				input: "xyz",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `&"abc"`, Code: peg.PredicateFailed},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- &.",
+		cases: []genTestCase{
+			{
+				name:  "pos pred any match",
+				input: "a",
+				pos:   0,
+				node: &peg.Node{
+					Name: "A",
+				},
+			},
+			{
+				name:  "pos pred any mismatch",
+				input: "",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `&.`, Code: peg.PredicateFailed},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- &[abc]",
+		cases: []genTestCase{
+			{
+				name:  "pos pred charclass match",
+				input: "a",
+				pos:   0,
+				node: &peg.Node{
+					Name: "A",
+				},
+			},
+			{
+				name:  "pos pred charclass mismatch",
+				input: "X",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `&[abc]`, Code: peg.PredicateFailed},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- &[^abc]",
+		cases: []genTestCase{
+			{
+				name:  "pos pred neg charclass match",
+				input: "X",
+				pos:   0,
+				node: &peg.Node{
+					Name: "A",
+				},
+			},
+			{
+				name:  "pos pred neg charclass mismatch",
+				input: "a",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `&[^abc]`, Code: peg.PredicateFailed},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- !'abc'",
+		cases: []genTestCase{
+			{
+				name:  "neg pred match",
+				input: "xyz",
+				pos:   0,
+				node: &peg.Node{
+					Name: "A",
+				},
+			},
+			{
+				name:  "neg pred mismatch",
+				input: "abc",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `!"abc"`, Code: peg.PredicateFailed},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- !.",
+		cases: []genTestCase{
+			{
+				name:  "neg pred any match",
+				input: "",
+				pos:   0,
+				node: &peg.Node{
+					Name: "A",
+				},
+			},
+			{
+				name:  "neg pred any mismatch",
+				input: "a",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `!.`, Code: peg.PredicateFailed},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- ![abc]",
+		cases: []genTestCase{
+			{
+				name:  "neg pred charclass match",
+				input: "x",
+				pos:   0,
+				node: &peg.Node{
+					Name: "A",
+				},
+			},
+			{
+				name:  "neg pred charclass mismatch",
+				input: "a",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `![abc]`, Code: peg.PredicateFailed},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- ![^abc]",
+		cases: []genTestCase{
+			{
+				name:  "neg pred neg charclass match",
+				input: "a",
+				pos:   0,
+				node: &peg.Node{
+					Name: "A",
+				},
+			},
+			{
+				name:  "neg pred neg charclass mismatch",
+				input: "x",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `![^abc]`, Code: peg.PredicateFailed},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- !B\nB <- 'abc'",
+		cases: []genTestCase{
+			{
+				name:  "neg pred literal match",
+				input: "xyz",
+				pos:   0,
+				node: &peg.Node{
+					Name: "A",
+				},
+			},
+			{
+				name:  "neg pred neg charclass mismatch",
+				input: "abc",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: "!B", Code: peg.PredicateFailed},
+					},
+				},
+			},
+		},
+	},
+	{
+		// C never fails, so !C always fails.
+		grammar: "A <- B / 'z'\nB <- !C\nC <- 'x'?",
+		cases: []genTestCase{
+			{
+				name:  "neg pred of infallible rule in choice",
+				input: "z",
+				pos:   len("z"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "z",
+					Kids: []*peg.Node{
+						{Text: "z"},
+					},
+				},
+			},
+			{
+				name:  "neg pred of infallible rule in choice fails",
+				input: "y",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Name: "B",
+							Kids: []*peg.Fail{
+								{Want: "!C", Code: peg.PredicateFailed},
+							},
+						},
+						{Want: `"z"`, Code: peg.ExpectedLiteral},
+					},
+				},
+			},
+		},
+	},
+	{
+		// B* never fails, so "a" is never tried.
+		grammar: "A <- B* / 'a'\nB <- 'x'",
+		cases: []genTestCase{
+			{
+				name:  "infallible repetition branch before later branch",
+				input: "xx",
+				pos:   len("xx"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "xx",
+					Kids: []*peg.Node{
+						{Name: "B", Text: "x", Kids: []*peg.Node{{Text: "x"}}},
+						{Name: "B", Text: "x", Kids: []*peg.Node{{Text: "x"}}},
+					},
+				},
+			},
+			{
+				name:  "infallible repetition branch matches empty",
+				input: "a",
+				pos:   0,
+				node:  &peg.Node{Name: "A"},
+			},
+		},
+	},
+	{
+		grammar: "A <- () / 'a'",
+		cases: []genTestCase{
+			{
+				name:  "empty branch before later branch",
+				input: "a",
+				pos:   0,
+				node:  &peg.Node{Name: "A"},
+			},
+		},
+	},
+	{
+		grammar: "A <- 'abc' 'def' 'ghi'",
+		cases: []genTestCase{
+			{
+				name:  "sequence match",
+				input: "abcdefghi",
+				pos:   len("abcdefghi"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abcdefghi",
+					Kids: []*peg.Node{
+						{Text: "abc"},
+						{Text: "def"},
+						{Text: "ghi"},
+					},
+				},
+			},
+			{
+				name:  "sequence mismatch first",
+				input: "XYZdefghi",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `"abc"`, Code: peg.ExpectedLiteral},
+					},
+				},
+			},
+			{
+				name:  "sequence mismatch mid",
+				input: "abcXYZghi",
+				pos:   len("abc"), // error after abc
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Pos:  len("abc"),
+							Want: `"def"`,
+							Code: peg.ExpectedLiteral,
+						},
+					},
+				},
+			},
+			{
+				name:  "sequence mismatch last",
+				input: "abcdefXYZ",
+				pos:   len("abcdef"), // error after abcdef
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Pos:  len("abcdef"),
+							Want: `"ghi"`,
+							Code: peg.ExpectedLiteral,
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- 'abc' / 'def' / 'ghi'",
+		cases: []genTestCase{
+			{
+				name:  "choice match first",
+				input: "abc",
+				pos:   len("abc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abc",
+					Kids: []*peg.Node{{Text: "abc"}},
+				},
+			},
+			{
+				name:  "choice match mid",
+				input: "def",
+				pos:   len("def"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "def",
+					Kids: []*peg.Node{{Text: "def"}},
+				},
+			},
+			{
+				name:  "choice match last",
+				input: "ghi",
+				pos:   len("ghi"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "ghi",
+					Kids: []*peg.Node{{Text: "ghi"}},
+				},
+			},
+			{
+				name:  "choice mismatch",
+				input: "XYZ",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `"abc"`, Code: peg.ExpectedLiteral},
+						{Want: `"def"`, Code: peg.ExpectedLiteral},
+						{Want: `"ghi"`, Code: peg.ExpectedLiteral},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- ('=' / '==' / 'if' / 'in' / 'i' / 'é') '!'",
+		cases: []genTestCase{
+			{
+				name:  "literal choice match first of byte",
+				input: "if!",
+				pos:   len("if!"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "if!",
+					Kids: []*peg.Node{
+						{Text: "if", Kids: []*peg.Node{{Text: "if"}}},
+						{Text: "!"},
+					},
+				},
+			},
+			{
+				name:  "literal choice match later of byte",
+				input: "i!",
+				pos:   len("i!"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "i!",
+					Kids: []*peg.Node{
+						{Text: "i", Kids: []*peg.Node{{Text: "i"}}},
+						{Text: "!"},
+					},
+				},
+			},
+			{
+				name:  "literal choice match non-ASCII",
+				input: "é!",
+				pos:   len("é!"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "é!",
+					Kids: []*peg.Node{
+						{Text: "é", Kids: []*peg.Node{{Text: "é"}}},
+						{Text: "!"},
+					},
+				},
+			},
+			{
+				name:  "literal choice earlier prefix wins",
+				input: "==!",
+				pos:   1,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Pos: 1, Want: `"!"`, Code: peg.ExpectedLiteral},
+					},
+				},
+			},
+			{
+				name:  "literal choice mismatch",
+				input: "x",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `"="`, Code: peg.ExpectedLiteral},
+						{Want: `"=="`, Code: peg.ExpectedLiteral},
+						{Want: `"if"`, Code: peg.ExpectedLiteral},
+						{Want: `"in"`, Code: peg.ExpectedLiteral},
+						{Want: `"i"`, Code: peg.ExpectedLiteral},
+						{Want: `"é"`, Code: peg.ExpectedLiteral},
+					},
+				},
+			},
+			{
+				name:  "literal choice mismatch at end",
+				input: "",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `"="`, Code: peg.ExpectedLiteral},
+						{Want: `"=="`, Code: peg.ExpectedLiteral},
+						{Want: `"if"`, Code: peg.ExpectedLiteral},
+						{Want: `"in"`, Code: peg.ExpectedLiteral},
+						{Want: `"i"`, Code: peg.ExpectedLiteral},
+						{Want: `"é"`, Code: peg.ExpectedLiteral},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- I ';'\nI <- [a-z]+ - K\nK <- 'if' / 'in'",
+		cases: []genTestCase{
+			{
+				name:  "diff match with longer span",
+				input: "ifs;",
+				pos:   len("ifs;"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "ifs;",
+					Kids: []*peg.Node{
+						{
+							Name: "I",
+							Text: "ifs",
+							Kids: []*peg.Node{
+								{Text: "i"},
+								{Text: "f"},
+								{Text: "s"},
+							},
+						},
+						{Text: ";"},
+					},
+				},
+			},
+			{
+				name:  "diff mismatch with same span",
+				input: "if;",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Name: "I",
+							Kids: []*peg.Fail{
+								{Want: "not K", Code: peg.PredicateFailed},
+							},
+						},
+					},
+				},
+			},
+			{
+				name:  "diff mismatch with same span at the end",
+				input: "in",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Name: "I",
+							Kids: []*peg.Fail{
+								{Want: "not K", Code: peg.PredicateFailed},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- ('=' | '==' | [=!] '=' | B) '!'\nB <- '=' '='",
+		cases: []genTestCase{
+			{
+				name:  "longest choice longest wins",
+				input: "==!",
+				pos:   len("==!"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "==!",
+					Kids: []*peg.Node{
+						{Text: "==", Kids: []*peg.Node{{Text: "=="}}},
+						{Text: "!"},
+					},
+				},
+			},
+			{
+				name:  "longest choice shorter wins",
+				input: "=!",
+				pos:   len("=!"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "=!",
+					Kids: []*peg.Node{
+						{Text: "=", Kids: []*peg.Node{{Text: "="}}},
+						{Text: "!"},
+					},
+				},
+			},
+			{
+				name:  "longest choice later branch wins",
+				input: "!=!",
+				pos:   len("!=!"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "!=!",
+					Kids: []*peg.Node{
+						{Text: "!=", Kids: []*peg.Node{{Text: "!"}, {Text: "="}}},
+						{Text: "!"},
+					},
+				},
+			},
+			{
+				name:  "longest choice mismatch",
+				input: "x",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `"="`, Code: peg.ExpectedLiteral},
+						{Want: `"=="`, Code: peg.ExpectedLiteral},
+						{Want: "[=!]", Code: peg.ExpectedClass},
+						{
+							Name: "B",
+							Kids: []*peg.Fail{{Want: `"="`, Code: peg.ExpectedLiteral}},
+						},
+					},
+				},
+			},
+			{
+				name:  "longest choice mismatch after longest",
+				input: "==",
+				pos:   2,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Pos: 2, Want: `"!"`, Code: peg.ExpectedLiteral},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- ('a'+ - 'aa') !.",
+		cases: []genTestCase{
+			{
+				name:  "diff mismatch",
+				input: "aa",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `not "aa"`, Code: peg.PredicateFailed},
+					},
+				},
+			},
+			{
+				name:  "diff match",
+				input: "a",
+				pos:   1,
+				node: &peg.Node{
+					Name: "A",
+					Text: "a",
+					Kids: []*peg.Node{
+						{Text: "a", Kids: []*peg.Node{{Text: "a"}}},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `A <- "\d\d:\d\d" "\P{Ogham}"`,
+		cases: []genTestCase{
+			{
+				name:  "class escapes match",
+				input: "12:34!",
+				pos:   len("12:34!"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "12:34!",
+					Kids: []*peg.Node{
+						{
+							Text: "12:34",
+							Kids: []*peg.Node{
+								{Text: "1"},
+								{Text: "2"},
+								{Text: ":"},
+								{Text: "3"},
+								{Text: "4"},
+							},
+						},
+						{Text: "!", Kids: []*peg.Node{{Text: "!"}}},
+					},
+				},
+			},
+			{
+				name:  "class escape mismatch",
+				input: "12:x4!",
+				pos:   3,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Pos: 3, Want: `"\d"`, Code: peg.ExpectedClass},
+					},
+				},
+			},
+			{
+				name:  "negated class escape mismatch",
+				input: "12:34\u1681",
+				pos:   5,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Pos: 5, Want: `"\P{Ogham}"`, Code: peg.ExpectedClass},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- 'abc' / 'def'?",
+		cases: []genTestCase{
+			{
+				name:  "choice that can't fail empty",
+				input: "XYZ",
+				pos:   0,
+				node: &peg.Node{
+					Name: "A",
+				},
+			},
+			{
+				name:  "choice that can't fail non-empty",
+				input: "def",
+				pos:   len("def"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "def",
+					Kids: []*peg.Node{{Text: "def"}},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- '123' ('abc'/ 'αβξ')",
+		cases: []genTestCase{
+			{
+				name:  "choice after sequence match first",
+				input: "123abc",
+				pos:   len("123abc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "123abc",
+					Kids: []*peg.Node{
+						{Text: "123"},
+						{
+							Text: "abc",
+							Kids: []*peg.Node{{Text: "abc"}},
+						},
+					},
+				},
+			},
+			{
+				name:  "choice after sequence match second",
+				input: "123αβξ",
+				pos:   len("123αβξ"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "123αβξ",
+					Kids: []*peg.Node{
+						{Text: "123"},
+						{
+							Text: "αβξ",
+							Kids: []*peg.Node{{Text: "αβξ"}},
+						},
+					},
+				},
+			},
+			{
+				name:  "choice after sequence mismatch",
+				input: "123XYZ",
+				pos:   len("123"), // error after 123
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Pos:  len("123"),
+							Want: `"abc"`,
+							Code: peg.ExpectedLiteral,
+						},
+						{
+							Pos:  len("123"),
+							Want: `"αβξ"`,
+							Code: peg.ExpectedLiteral,
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- 'a' B 'c' / 'a' B 'd'\nB <- 'B'",
+		cases: []genTestCase{
+			{
+				name:  "rule memo success",
+				input: "aBd",
+				pos:   len("aBd"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "aBd",
+					Kids: []*peg.Node{
+						{Text: "a"},
+						{
+							Name: "B",
+							Text: "B",
+							Kids: []*peg.Node{{Text: "B"}},
+						},
+						{Text: "d"},
+					},
+				},
+			},
+			{
+				name:  "rule memo failure",
+				input: "aAd",
+				pos:   len("a"), // error after a
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Name: "B",
+							Pos:  len("a"),
+							Kids: []*peg.Fail{
+								{
+									Pos:  len("a"),
+									Want: `"B"`,
+									Code: peg.ExpectedLiteral,
+								},
+							},
+						},
+						{
+							Name: "B",
+							Pos:  len("a"),
+							Kids: []*peg.Fail{
+								{
+									Pos:  len("a"),
+									Want: `"B"`,
+									Code: peg.ExpectedLiteral,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- B 'x'\nB <- 'abc' 'def' / .",
+		cases: []genTestCase{
+			{
+				name:  "latest error",
+				input: "abcxyz",
+				pos:   len("abc"), // latest error is after abc
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Name: "B",
+							Pos:  0,
+							Kids: []*peg.Fail{
+								{
+									Pos:  len("abc"),
+									Want: `"def"`,
+									Code: peg.ExpectedLiteral,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		// Don't report the location of fails in silent exprs, & and !.
+		grammar: "A <- !B 'xyz'\nB <- 'abc' 'def'",
+		cases: []genTestCase{
+			{
+				name:  "ignore silent fails",
+				input: "abc",
+				pos:   0, // latest error is just before 'xyz', not after 'abc'
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `"xyz"`, Code: peg.ExpectedLiteral},
+					},
+				},
+			},
+		},
+	},
+	{
+		// If an expr first fails in a silent expr, & or !,
+		// we still report it's fail position if it fails
+		// subsequently in a non-silent context.
+		// Note that this is different from the behavior
+		// of some other PEG parsers, which don't emit errors
+		// if the cached value failed in a silent context.
+		// Config.CacheSilentFails selects that behavior;
+		// see cacheSilentGenTests.
+		grammar: "A <- &B 'f' / B\nB <- 'a' 'b' 'c' 'd' 'e'",
+		cases: []genTestCase{
+			{
+				name:  "no cache silent fails",
+				input: "abce",
+				// The error is the missing 'd' between 'abc' and 'e'.
+				// Some other PEG parsers would report the error at 0,
+				// because the first time 'd' fails, it's silent, that's cached
+				// and the subsequent fail uses the cached,
+				// un-reported error.
+				pos: len("abc"),
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Name: "B",
+							Kids: []*peg.Fail{
+								{
+									Pos:  len("abc"),
+									Want: `"d"`,
+									Code: peg.ExpectedLiteral,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- 'abc' B 'def'\nB 'name' <- C\nC <- D\nD <- '123'",
+		cases: []genTestCase{
+			{
+				name:  "named rule fail",
+				input: "abc124",
+				pos:   len("abc"),
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Name: "B",
+							Pos:  len("abc"),
+							Want: "name",
+							Code: peg.NamedRule,
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- 'x = ' B\nB 'expected a number after %q' <- [0-9]+",
+		cases: []genTestCase{
+			{
+				name:  "named rule error message",
+				input: "x = y",
+				pos:   len("x = "),
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Name:    "B",
+							Pos:     len("x = "),
+							Want:    `expected a number after "="`,
+							Code:    peg.NamedRule,
+							Message: true,
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- 'abc' B 'def'\nB 'name' <- '1' '2' '3' / .",
+		cases: []genTestCase{
+			{
+				name: "ignore errors under successful named rules",
+				// B fails after 12, backtracks and succeeds after the 1.
+				// We should not report the error after abc12, but after abc1.
+				input: "abc12x",
+				pos:   len("abc1"),
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Pos:  len("abc1"),
+							Want: `"def"`,
+							Code: peg.ExpectedLiteral,
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `
+			A <- List<B> List<C>
+			B <- "b"
+			C <- "c"
+			List<x> <- x*`,
+		cases: []genTestCase{
+			{
+				name:  "unary template",
+				input: "bbbccc",
+				pos:   len("bbbccc"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "bbbccc",
+					Kids: []*peg.Node{
+						{
+							Name: "List<B>",
+							Text: "bbb",
+							Kids: []*peg.Node{
+								{
+									Name: "B",
+									Text: "b",
+									Kids: []*peg.Node{{Text: "b"}},
+								},
+								{
+									Name: "B",
+									Text: "b",
+									Kids: []*peg.Node{{Text: "b"}},
+								},
+								{
+									Name: "B",
+									Text: "b",
+									Kids: []*peg.Node{{Text: "b"}},
+								},
+							},
+						},
+						{
+							Name: "List<C>",
+							Text: "ccc",
+							Kids: []*peg.Node{
+								{
+									Name: "C",
+									Text: "c",
+									Kids: []*peg.Node{{Text: "c"}},
+								},
+								{
+									Name: "C",
+									Text: "c",
+									Kids: []*peg.Node{{Text: "c"}},
+								},
+								{
+									Name: "C",
+									Text: "c",
+									Kids: []*peg.Node{{Text: "c"}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `
+			A <- Three<X, Y, Z>
+			X <- "x"
+			Y <- "y"
+			Z <- "z"
+			Three<x, y, z> <- x y z`,
+		cases: []genTestCase{
+			{
+				name:  "3-ary template",
+				input: "xyz",
+				pos:   len("xyz"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "xyz",
+					Kids: []*peg.Node{
+						{
+							Name: "Three<X, Y, Z>",
+							Text: "xyz",
+							Kids: []*peg.Node{
+								{
+									Name: "X",
+									Text: "x",
+									Kids: []*peg.Node{{Text: "x"}},
+								},
+								{
+									Name: "Y",
+									Text: "y",
+									Kids: []*peg.Node{{Text: "y"}},
+								},
+								{
+									Name: "Z",
+									Text: "z",
+									Kids: []*peg.Node{{Text: "z"}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `
+			A <- Quoted<"'"> List<",">
+			Quoted<'"'> <- '"' (!'"' .)* '"'
+			List<x> <- "[" x "]"`,
+		cases: []genTestCase{
+			{
+				name:  "literal template arguments",
+				input: "'a\"'[,]",
+				pos:   len("'a\"'[,]"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "'a\"'[,]",
+					Kids: []*peg.Node{
+						{
+							Name: `Quoted<"'">`,
+							Text: "'a\"'",
+							Kids: []*peg.Node{
+								{Text: "'"},
+								{Text: "a", Kids: []*peg.Node{{Text: "a"}}},
+								{Text: "\"", Kids: []*peg.Node{{Text: "\""}}},
+								{Text: "'"},
+							},
+						},
+						{
+							Name: `List<",">`,
+							Text: "[,]",
+							Kids: []*peg.Node{
+								{Text: "["},
+								{Text: ","},
+								{Text: "]"},
+							},
+						},
+					},
+				},
+			},
+			{
+				name:  "literal template argument mismatch",
+				input: "'a\"",
+				pos:   3,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Name: `Quoted<"'">`,
+							Kids: []*peg.Fail{
+								{Pos: 3, Want: ".", Code: peg.ExpectedAny},
+								{Pos: 3, Want: `"'"`, Code: peg.ExpectedLiteral},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `
+			A <- "a" B<X>
+			B<X> <- "b" C<X>
+			C<X> <- "c" X C<X>?
+			X <- "x"`,
+		cases: []genTestCase{
+			{
+				name:  "template calls a template",
+				input: "abcxcx",
+				pos:   len("abcxcx"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "abcxcx",
+					Kids: []*peg.Node{
+						{Text: "a"},
+						{
+							Name: "B<X>",
+							Text: "bcxcx",
+							Kids: []*peg.Node{
+								{Text: "b"},
+								{
+									Name: "C<X>",
+									Text: "cxcx",
+									Kids: []*peg.Node{
+										{Text: "c"},
+										{Name: "X", Text: "x", Kids: []*peg.Node{{Text: "x"}}},
+										{
+											Name: "C<X>",
+											Text: "cx",
+											Kids: []*peg.Node{
+												{Text: "c"},
+												{Name: "X", Text: "x", Kids: []*peg.Node{{Text: "x"}}},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `
+			A <- Expr(0) !.
+			Expr @param(depth int) <- &{ depth < 2 } "(" Expr(depth+1) ")" / "x"`,
+		cases: []genTestCase{
+			{
+				name:  "rule parameters match",
+				input: "(x)",
+				pos:   len("(x)"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "(x)",
+					Kids: []*peg.Node{
+						{
+							Name: "Expr",
+							Text: "(x)",
+							Kids: []*peg.Node{
+								{Text: "("},
+								{Name: "Expr", Text: "x", Kids: []*peg.Node{{Text: "x"}}},
+								{Text: ")"},
+							},
+						},
+					},
+				},
+			},
+			{
+				name:  "rule parameters mismatch",
+				input: "(((x)))",
+				pos:   2,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Name: "Expr",
+							Kids: []*peg.Fail{
+								{
+									Name: "Expr",
+									Pos:  1,
+									Kids: []*peg.Fail{
+										{
+											Name: "Expr",
+											Pos:  2,
+											Kids: []*peg.Fail{
+												{Pos: 2, Want: "&{ depth < 2 }", Code: peg.PredicateFailed},
+												{Pos: 2, Want: `"x"`, Code: peg.ExpectedLiteral},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `
+			%maxdepth 3
+			A <- "(" A ")" / "x"`,
+		cases: []genTestCase{
+			{
+				name:  "within maximum depth",
+				input: "((x))",
+				pos:   len("((x))"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "((x))",
+					Kids: []*peg.Node{
+						{Text: "("},
+						{
+							Name: "A",
+							Text: "(x)",
+							Kids: []*peg.Node{
+								{Text: "("},
+								{Name: "A", Text: "x", Kids: []*peg.Node{{Text: "x"}}},
+								{Text: ")"},
+							},
+						},
+						{Text: ")"},
+					},
+				},
+			},
+			{
+				name:  "maximum depth exceeded",
+				input: "(((x)))",
+				pos:   3,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Name: "A",
+							Pos:  1,
+							Kids: []*peg.Fail{
+								{
+									Name: "A",
+									Pos:  2,
+									Kids: []*peg.Fail{
+										{Name: "A", Pos: 3, Want: peg.MaxDepthExceeded, Code: peg.DepthExceeded},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		// W exceeds the maximum depth beneath P2,
+		// but not at the same position directly beneath A.
+		grammar: `
+			%maxdepth 3
+			A <- P1 "!" / W
+			P1 <- P2
+			P2 <- W
+			W <- "w"`,
+		cases: []genTestCase{
+			{
+				name:  "maximum depth exceeded at another depth",
+				input: "w",
+				pos:   len("w"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "w",
+					Kids: []*peg.Node{
+						{Name: "W", Text: "w", Kids: []*peg.Node{{Text: "w"}}},
+					},
+				},
+			},
+			{
+				name:  "maximum depth exceeded at another depth fails",
+				input: "x",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Name: "P1",
+							Kids: []*peg.Fail{
+								{
+									Name: "P2",
+									Kids: []*peg.Fail{
+										{Name: "W", Want: peg.MaxDepthExceeded, Code: peg.DepthExceeded},
+									},
+								},
+							},
+						},
+						{
+							Name: "W",
+							Kids: []*peg.Fail{
+								{Want: `"w"`, Code: peg.ExpectedLiteral},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		// R fails by exceeding the maximum depth beneath Wrap2,
+		// so neither it nor the rules beneath which it fails
+		// are memoized for the shallower R directly beneath A.
+		grammar: `
+			%maxdepth 5
+			A <- Wrap1 / R
+			Wrap1 <- Wrap2
+			Wrap2 <- R "!"
+			R <- "(" R ")" / "x"`,
+		cases: []genTestCase{
+			{
+				name:  "results depending on maximum depth not memoized",
+				input: "((x))",
+				pos:   len("((x))"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "((x))",
+					Kids: []*peg.Node{
+						{
+							Name: "R",
+							Text: "((x))",
+							Kids: []*peg.Node{
+								{Text: "("},
+								{
+									Name: "R",
+									Text: "(x)",
+									Kids: []*peg.Node{
+										{Text: "("},
+										{Name: "R", Text: "x", Kids: []*peg.Node{{Text: "x"}}},
+										{Text: ")"},
+									},
+								},
+								{Text: ")"},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `
+			A <- Num "+" Digits(2)
+			Num @token <- [0-9]+ ("." [0-9]+)?
+			Digits @token @param(n int) <- &{ n > 0 } [0-9] Digits(n-1) / &{ n == 0 }`,
+		cases: []genTestCase{
+			{
+				name:  "token rules",
+				input: "12.5+34",
+				pos:   len("12.5+34"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "12.5+34",
+					Kids: []*peg.Node{
+						{Name: "Num", Text: "12.5"},
+						{Text: "+"},
+						{Name: "Digits", Text: "34"},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `
+			A <- Kw " " B
+			Kw @nocase <- "in" / "x"
+			B <- "b"`,
+		cases: []genTestCase{
+			{
+				name:  "nocase rule",
+				input: "In b",
+				pos:   len("In b"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "In b",
+					Kids: []*peg.Node{
+						{
+							Name: "Kw",
+							Text: "In",
+							Kids: []*peg.Node{
+								{
+									Text: "In",
+									Kids: []*peg.Node{
+										{Text: "I"},
+										{Text: "n"},
+									},
+								},
+							},
+						},
+						{Text: " "},
+						{
+							Name: "B",
+							Text: "b",
+							Kids: []*peg.Node{{Text: "b"}},
+						},
+					},
+				},
+			},
+			{
+				name:  "nocase rule literal mismatch",
+				input: "iy b",
+				pos:   len("i"),
+				fail: &peg.Fail{
+					Name: "A",
+					Pos:  0,
+					Kids: []*peg.Fail{
+						{
+							Name: "Kw",
+							Pos:  0,
+							Kids: []*peg.Fail{
+								{Pos: len("i"), Want: "[Nn]", Code: peg.ExpectedLiteral},
+							},
+						},
+					},
+				},
+			},
+			{
+				name:  "nocase rule does not fold referenced rules",
+				input: "X B",
+				pos:   len("X "),
+				fail: &peg.Fail{
+					Name: "A",
+					Pos:  0,
+					Kids: []*peg.Fail{
+						{
+							Name: "B",
+							Pos:  len("X "),
+							Kids: []*peg.Fail{
+								{Pos: len("X "), Want: `"b"`, Code: peg.ExpectedLiteral},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `
+			A <- "(" A ")" / B
+			B @maxdepth(2) <- "[" B "]" / "x"`,
+		cases: []genTestCase{
+			{
+				name:  "within maximum rule depth",
+				input: "([x])",
+				pos:   len("([x])"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "([x])",
+					Kids: []*peg.Node{
+						{Text: "("},
+						{
+							Name: "A",
+							Text: "[x]",
+							Kids: []*peg.Node{
+								{
+									Name: "B",
+									Text: "[x]",
+									Kids: []*peg.Node{
+										{Text: "["},
+										{Name: "B", Text: "x", Kids: []*peg.Node{{Text: "x"}}},
+										{Text: "]"},
+									},
+								},
+							},
+						},
+						{Text: ")"},
+					},
+				},
+			},
+			{
+				name:  "maximum rule depth exceeded",
+				input: "([[x]])",
+				pos:   3,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{
+							Name: "A",
+							Pos:  1,
+							Kids: []*peg.Fail{
+								{
+									Name: "B",
+									Pos:  1,
+									Kids: []*peg.Fail{
+										{
+											Name: "B",
+											Pos:  2,
+											Kids: []*peg.Fail{
+												{Name: "B", Pos: 3, Want: peg.MaxDepthExceeded, Code: peg.DepthExceeded},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: `A <- . [^a]`,
+		cases: []genTestCase{
+			{
+				name:  "invalid UTF-8 rejected by .",
+				input: "\xff",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{{Want: ".", Code: peg.ExpectedAny}},
+				},
+			},
+			{
+				name:  "invalid UTF-8 rejected by negated class",
+				input: "x\xff",
+				pos:   1,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{{Pos: 1, Want: "[^a]", Code: peg.ExpectedClass}},
+				},
+			},
+		},
+	},
+	{
+		grammar: `
+			%invalidbytes
+			A <- . [^a]`,
+		cases: []genTestCase{
+			{
+				name:  "invalid UTF-8 accepted as bytes",
+				input: "\xff\xfe",
+				pos:   2,
+				node: &peg.Node{
+					Name: "A",
+					Text: "\xff\xfe",
+					Kids: []*peg.Node{{Text: "\xff"}, {Text: "\xfe"}},
+				},
+			},
+			{
+				name:  "valid UTF-8 accepted as runes",
+				input: "αβ",
+				pos:   len("αβ"),
+				node: &peg.Node{
+					Name: "A",
+					Text: "αβ",
+					Kids: []*peg.Node{{Text: "α"}, {Text: "β"}},
+				},
+			},
+			{
+				name:  "negated class still rejects its runes",
+				input: "\xffa",
+				pos:   1,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{{Pos: 1, Want: "[^a]", Code: peg.ExpectedClass}},
+				},
+			},
+		},
+	},
+	{
+		grammar: "%newline crlf\r\nA <- \"a\"\r\n",
+		cases: []genTestCase{
+			{
+				name:  "CRLF grammar",
+				input: "a",
+				pos:   1,
+				node:  &peg.Node{Name: "A", Text: "a", Kids: []*peg.Node{{Text: "a"}}},
+			},
+		},
+	},
+	{
+		grammar: "%newline unicode\nA <- \"a\" \"\\u2028\" \"b\"",
+		cases: []genTestCase{
+			{
+				name:  "unicode line separator",
+				input: "a\u2028c",
+				pos:   4,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{{Pos: 4, Want: `"b"`, Code: peg.ExpectedLiteral}},
+				},
+			},
+		},
+	},
+}
+
+func TestGen(t *testing.T) {
+	for _, layout := range []struct {
+		name string
+		memo MemoLayout
+	}{
+		{name: "row", memo: RowMajor},
+		{name: "column", memo: ColumnMajor},
+		{name: "map", memo: SparseMemo},
+	} {
+		cfg := Config{Prefix: "_", Memo: layout.memo}
+		t.Run(layout.name, func(t *testing.T) { testGen(t, cfg) })
+	}
+}
+
+// TestGenSharedMemo tests a parser using a shared memo cache
+// warmed by parses of other texts with common prefixes.
+func TestGenSharedMemo(t *testing.T) {
+	testGen(t, Config{Prefix: "_", SharedMemo: true})
+}
+
+// TestGenCacheSilentFails tests that, with CacheSilentFails,
+// fails first occurring in silent exprs are never reported.
+func TestGenCacheSilentFails(t *testing.T) {
+	testGenTests(t, Config{Prefix: "_", CacheSilentFails: true}, cacheSilentGenTests)
+}
+
+// TestGenAssert tests that the parsers of the genTests grammars,
+// generated with compile-time assertions, pass go vet.
+func TestGenAssert(t *testing.T) {
+	if testing.Short() {
+		t.Skip("vetting every generated parser is slow")
+	}
+	cfg := Config{Prefix: "_", Assert: true}
+	for _, test := range genTests {
+		test := test
+		t.Run("", func(t *testing.T) {
+			t.Parallel()
+			source := generateTest(cfg, prelude, test.grammar)
+			defer rm(source)
+			if out, err := exec.Command("go", "vet", source).CombinedOutput(); err != nil {
+				t.Errorf("go vet failed: %v\n%s\ngrammar:\n%s", err, out, test.grammar)
+			}
+		})
+	}
+}
+
+// TestGenAssertFails tests that the compile-time assertions
+// fail to compile if a rule constant is wrong.
+func TestGenAssertFails(t *testing.T) {
+	source := generateTest(Config{Prefix: "_", Assert: true}, prelude, "A <- B C\nB <- 'b'\nC <- 'c'")
+	defer rm(source)
+	for _, corrupt := range []string{"_C int = 2", "_N int = 3"} {
+		data, err := ioutil.ReadFile(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), corrupt) {
+			t.Fatalf("generated parser does not contain %q:\n%s", corrupt, data)
+		}
+		bad := strings.Replace(string(data), corrupt, corrupt+" + 1", 1)
+		badSource := strings.TrimSuffix(source, ".go") + "_bad.go"
+		if err := ioutil.WriteFile(badSource, []byte(bad), 0666); err != nil {
+			t.Fatal(err)
+		}
+		out, err := exec.Command("go", "vet", badSource).CombinedOutput()
+		rm(badSource)
+		if err == nil || !strings.Contains(string(out), "out of bounds") {
+			t.Errorf("go vet with %s + 1 succeeded or failed otherwise: %v\n%s", corrupt, err, out)
+		}
+	}
+}
+
+func TestGenCollisions(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		grammar string
+		err     string
+	}{
+		{
+			prefix:  "_",
+			grammar: "A <- N\nN <- 'n'",
+			err:     "test.peggy:5.1,5.9: rule N: the generated identifier _N is declared more than once; rename the rule",
+		},
+		{
+			prefix:  "_",
+			grammar: "Parser <- 'p'",
+			err:     "test.peggy:4.1,4.14: rule Parser: the generated identifier _Parser is declared more than once; rename the rule",
+		},
+		{
+			prefix:  "_",
+			grammar: "A <- AAction\nAAction <- 'a'",
+			err:     "test.peggy:5.1,5.15: rule AAction: the generated identifier _AAction is declared more than once; rename the rule",
+		},
+		{
+			prefix:  "",
+			grammar: "NewParser <- 'p'",
+			err:     "test.peggy:4.1,4.17: rule NewParser: the generated identifier NewParser is declared more than once; rename the rule",
+		},
+		{
+			// Rule names merely beginning with generated names are fine.
+			prefix:  "_",
+			grammar: "Parsers <- Ns\nNs <- 'n'",
+		},
+	}
+	for _, test := range tests {
+		g, err := Parse(strings.NewReader("{\npackage p\n}\n"+test.grammar), "test.peggy")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Check(g); err != nil {
+			t.Fatal(err)
+		}
+		err = Config{Prefix: test.prefix}.Generate(ioutil.Discard, "test.peggy", g)
+		if (err == nil && test.err != "") || (err != nil && err.Error() != test.err) {
+			t.Errorf("Generate(%q) with prefix %q=%v, want %q", test.grammar, test.prefix, err, test.err)
+		}
+	}
+}
+
+var cacheSilentGenTests = []genTest{
+	{
+		grammar: "A <- !B 'xyz'\nB <- 'abc' 'def'",
+		cases: []genTestCase{
+			{
+				name:  "ignore silent fails",
+				input: "abc",
+				pos:   0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: `"xyz"`, Code: peg.ExpectedLiteral},
+					},
+				},
+			},
+		},
+	},
+	{
+		// Compare to "no cache silent fails" in genTests.
+		grammar: "A <- &B 'f' / B\nB <- 'a' 'b' 'c' 'd' 'e'",
+		cases: []genTestCase{
+			{
+				name:  "cache silent fails",
+				input: "abce",
+				// B first fails in &B, so its memoized fail is silent.
+				pos: 0,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Want: "&B", Code: peg.PredicateFailed},
+					},
+				},
+			},
+		},
+	},
+	{
+		grammar: "A <- ('a' - B) 'z' / B\nB <- 'a' 'c' 'd'",
+		cases: []genTestCase{
+			{
+				name:  "cache silent fails in difference",
+				input: "acx",
+				// B first fails in the right of -, so its memoized fail is silent.
+				pos: 1,
+				fail: &peg.Fail{
+					Name: "A",
+					Kids: []*peg.Fail{
+						{Pos: 1, Want: `"z"`, Code: peg.ExpectedLiteral},
+					},
+				},
+			},
+		},
+	},
+}
+
+func testGen(t *testing.T, cfg Config) {
+	testGenTests(t, cfg, genTests)
+}
+
+func testGenTests(t *testing.T, cfg Config, tests []genTest) {
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			t.Parallel()
+			pre := prelude
+			if cfg.SharedMemo {
+				pre = sharedPrelude
+			}
+			source := generateTest(cfg, pre, test.grammar)
+			binary := build(source)
+			defer rm(binary)
+			go rm(source)
+
+			for _, c := range test.cases {
+				test, c := test, c
+				t.Run(c.name, func(t *testing.T) {
+					t.Logf("%q\n", test.grammar)
+					var result struct {
+						Pos  int
+						Perr int
+						Node *peg.Node
+						Fail *peg.Fail
+					}
+					parseGob(binary, c.input, &result)
+					pos := result.Pos
+					if result.Fail != nil {
+						pos = result.Perr
+					}
+					t.Logf("result: %+v\n", result)
+					if pos != c.pos {
+						t.Errorf("parse(%q)=%d, want %d", c.input, pos, c.pos)
+					}
+					var got interface{}
+					if result.Node != nil {
+						got = result.Node
+					} else {
+						got = result.Fail
+					}
+					var want interface{}
+					if c.node != nil {
+						want = c.node
+					} else {
+						want = c.fail
+					}
+					if !reflect.DeepEqual(want, got) {
+						t.Errorf("parse(%q)=\n%s\nwant\n%s",
+							c.input, pretty.String(got), pretty.String(want))
+					}
+				})
+			}
+		})
+	}
+}
+
+// BenchmarkMemoLayout compares the memo table layouts
+// on a grammar with many rules, each tried at every position.
+// The parse time is reported as the parse-ns/op metric,
+// since it is measured by the generated binary.
+func BenchmarkMemoLayout(b *testing.B) {
+	const nrules = 300
+	var grammar, input strings.Builder
+	grammar.WriteString("A <- (")
+	for i := 0; i < nrules; i++ {
+		if i > 0 {
+			grammar.WriteString(" / ")
+		}
+		fmt.Fprintf(&grammar, "R%d", i)
+	}
+	grammar.WriteString(")* !.\n")
+	for i := 0; i < nrules; i++ {
+		fmt.Fprintf(&grammar, "R%d <- \"k%d;\"\n", i, i)
+	}
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&input, "k%d;", (i*7)%nrules)
+	}
+	for _, layout := range []struct {
+		name string
+		memo MemoLayout
+	}{
+		{name: "row", memo: RowMajor},
+		{name: "column", memo: ColumnMajor},
+		{name: "map", memo: SparseMemo},
+	} {
+		b.Run(layout.name, func(b *testing.B) {
+			cfg := Config{Prefix: "_", Memo: layout.memo}
+			source := generateTest(cfg, benchPrelude, grammar.String())
+			binary := build(source)
+			defer rm(source)
+			defer rm(binary)
+			var ns int64
+			parseGob(binary, input.String(), &ns)
+			b.ReportMetric(float64(ns), "parse-ns/op")
+		})
+	}
+}
+
+// BenchmarkLiterals benchmarks a grammar matching mostly literals.
+// The parse time is reported as the parse-ns/op metric,
+// since it is measured by the generated binary.
+func BenchmarkLiterals(b *testing.B) {
+	const grammar = `
+		A <- S* !.
+		S <- "let" _ Id _ "=" _ Num ";" _
+			/ "var" _ Id _ "=" _ Num ";" _
+			/ "return" _ Num ";" _
+		Id <- "x" / "y" / "z"
+		Num <- "0" / "1" / "2"
+		_ <- " "*`
+	var input strings.Builder
+	for i := 0; i < 1000; i++ {
+		switch i % 3 {
+		case 0:
+			input.WriteString("let x = 1; ")
+		case 1:
+			input.WriteString("var y=2;")
+		case 2:
+			input.WriteString("return 0; ")
+		}
+	}
+	source := generateTest(Config{Prefix: "_"}, benchPrelude, grammar)
+	binary := build(source)
+	defer rm(source)
+	defer rm(binary)
+	var ns int64
+	parseGob(binary, input.String(), &ns)
+	b.ReportMetric(float64(ns), "parse-ns/op")
+}
+
+// BenchmarkSharedMemo compares parsing texts with a long common prefix
+// with and without a shared memo cache.
+// The parse time is reported as the parse-ns/op metric,
+// since it is measured by the generated binary.
+func BenchmarkSharedMemo(b *testing.B) {
+	const nrules = 100
+	var grammar, input strings.Builder
+	grammar.WriteString("A <- E* !.\nE <- ")
+	for i := 0; i < nrules; i++ {
+		if i > 0 {
+			grammar.WriteString(" / ")
+		}
+		fmt.Fprintf(&grammar, "R%d", i)
+	}
+	grammar.WriteString("\n")
+	for i := 0; i < nrules; i++ {
+		fmt.Fprintf(&grammar, "R%d <- \"k%d;\"\n", i, i)
+	}
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&input, "k%d;", (i*7)%nrules)
+	}
+	cfg := Config{Prefix: "_", SharedMemo: true}
+	source := generateTest(cfg, sharedBenchPrelude, grammar.String())
+	binary := build(source)
+	defer rm(source)
+	defer rm(binary)
+	var ns [2]int64
+	parseGob(binary, input.String(), &ns)
+	b.Run("unshared", func(b *testing.B) {
+		b.ReportMetric(float64(ns[0]), "parse-ns/op")
+	})
+	b.Run("shared", func(b *testing.B) {
+		b.ReportMetric(float64(ns[1]), "parse-ns/op")
+	})
+}
+
+func TestGenRuleNames(t *testing.T) {
+	const in = `{
+package p
+}
+		A <- B<C> C
+		B<X> <- X
+		C <- "c"`
+	g, err := Parse(strings.NewReader(in), "")
+	if err != nil {
+		t.Fatalf("Parse(%q)=_, %v, want _,nil", in, err)
+	}
+	if err := Check(g); err != nil {
+		t.Fatalf("Check(%q)=%v, want nil", in, err)
+	}
+	var b strings.Builder
+	if err := (Config{Prefix: "_", RuleNames: true}).Generate(&b, "", g); err != nil {
+		t.Fatalf("Generate(_, _, %q)=%v, want nil", in, err)
+	}
+	for _, want := range []string{
+		`var _RuleNames = \[_N\]string{`,
+		`_A:\s+"A",`,
+		`_C:\s+"C",`,
+		`_B__C:\s+"B<C>",`,
+	} {
+		if !regexp.MustCompile(want).MatchString(b.String()) {
+			t.Errorf("Generate(_, _, %q) does not match %q:\n%s", in, want, b.String())
+		}
+	}
+}
+
+func TestGenRuleCode(t *testing.T) {
+	const in = `{
+package p
+}
+%code {
+// isA is A's helper.
+func isA() bool { return true }
+}
+A <- &{ isA() } B<C> B<D>
+%code { var bCount int }
+B<X> <- X
+C <- "c"
+D <- "d"`
+	g, err := Parse(strings.NewReader(in), "")
+	if err != nil {
+		t.Fatalf("Parse(%q)=_, %v, want _,nil", in, err)
+	}
+	if err := Check(g); err != nil {
+		t.Fatalf("Check(%q)=%v, want nil", in, err)
+	}
+	var b strings.Builder
+	if err := Generate(&b, "", g); err != nil {
+		t.Fatalf("Generate(_, _, %q)=%v, want nil", in, err)
+	}
+	src := b.String()
+	// The code immediately precedes the rule's functions,
+	// and a template's code precedes only its first expansion.
+	for _, want := range []string{
+		"\n// isA is A's helper.\nfunc isA() bool { return true }\n\nfunc _AAccepts(",
+		"\nvar bCount int\n\nfunc _B__CAccepts(",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("Generate(_, _, %q) does not contain %q:\n%s", in, want, src)
+		}
+	}
+	if n := strings.Count(src, "var bCount int"); n != 1 {
+		t.Errorf("Generate(_, _, %q) has %d bCount declarations, want 1:\n%s", in, n, src)
+	}
+}
+
+func TestGenReadsLabels(t *testing.T) {
+	const in = `{
+package p
+}
+A <- w:"w" { return string(w) } / x:"a" y:B &{ len(x) > 0 } z:"c" { return string(y + z) }
+B <- x:"b" p:&"c" { return string(x) }`
+	g, err := Parse(strings.NewReader(in), "")
+	if err != nil {
+		t.Fatalf("Parse(%q)=_, %v, want _,nil", in, err)
+	}
+	if err := Check(g); err != nil {
+		t.Fatalf("Check(%q)=%v, want nil", in, err)
+	}
+	var b strings.Builder
+	if err := Generate(&b, "", g); err != nil {
+		t.Fatalf("Generate(_, _, %q)=%v, want nil", in, err)
+	}
+	src := b.String()
+	// Only the labels in scope of a code predicate,
+	// x and y, have entries in the labels array, which are set,
+	// and only rules with such labels declare the labels array.
+	for _, test := range []struct {
+		text string
+		n    int
+	}{
+		{"labels[0] = parser.text", 4},
+		{"labels[1] = parser.text", 4},
+		{"labels[2]", 0},
+		{"var labels [2]string", 4},
+		{"var labels ", 4},
+		{"(labels[0], labels[1])", 4},
+	} {
+		if n := strings.Count(src, test.text); n != test.n {
+			t.Errorf("Generate(_, _, %q) has %d %q, want %d:\n%s", in, n, test.text, test.n, src)
+		}
+	}
+}
+
+func TestGenInfallibleIdent(t *testing.T) {
+	const in = `{
+package p
+}
+A <- Space "a" Space B(1) C
+Space <- " "*
+B @param(n int) <- "b"?
+C @maxdepth(2) <- "c"*`
+	g, err := Parse(strings.NewReader(in), "")
+	if err != nil {
+		t.Fatalf("Parse(%q)=_, %v, want _,nil", in, err)
+	}
+	if err := Check(g); err != nil {
+		t.Fatalf("Check(%q)=%v, want nil", in, err)
+	}
+	var b strings.Builder
+	if err := Generate(&b, "", g); err != nil {
+		t.Fatalf("Generate(_, _, %q)=%v, want nil", in, err)
+	}
+	src := b.String()
+	// Space and B never fail, so their references do not check for failure,
+	// but C can exceed its maximum depth.
+	for _, test := range []struct {
+		text string
+		n    int
+	}{
+		{"_accept(parser, _SpaceAccepts, &pos, &perr)", 2},
+		{"if !_accept(parser, _SpaceAccepts", 0},
+		{"if !_node(parser, _SpaceNode", 0},
+		{"if !_fail(parser, _SpaceFail", 0},
+		{"if p, n := _SpaceAction", 0},
+		{"if p, kid := _BNode", 0},
+		{"if !_accept(parser, _CAccepts", 1},
+		{"if !_node(parser, _CNode", 1},
+		{"if !_fail(parser, _CFail", 1},
+	} {
+		if n := strings.Count(src, test.text); n != test.n {
+			t.Errorf("Generate(_, _, %q) has %d %q, want %d:\n%s", in, n, test.text, test.n, src)
+		}
+	}
+}
+
+func TestGenIncremental(t *testing.T) {
+	const (
+		old = `{
+package p
+}
+A <- B C
+B <- "b"
+C <- "c" D
+D <- "d"`
+		new = `{
+package p
+}
+A <- B C
+B <- "b"
+C <- "c" D
+D <- "d" / "e"`
+	)
+	gen := func(in, prev string) string {
+		g, err := Parse(strings.NewReader(in), "")
+		if err != nil {
+			t.Fatalf("Parse(%q)=_, %v, want _,nil", in, err)
+		}
+		if err := Check(g); err != nil {
+			t.Fatalf("Check(%q)=%v, want nil", in, err)
+		}
+		var b strings.Builder
+		cfg := Config{Prefix: "_", Incremental: true, Previous: prev}
+		if err := cfg.Generate(&b, "", g); err != nil {
+			t.Fatalf("Generate(_, _, %q)=%v, want nil", in, err)
+		}
+		return b.String()
+	}
+	prev := gen(old, "")
+	// Mark each rule's code to see which are copied.
+	for _, rule := range []string{"A", "B", "C", "D"} {
+		f := "func _" + rule + "Accepts("
+		prev = strings.Replace(prev, f, "// copied "+rule+"\n"+f, 1)
+	}
+	src := gen(new, prev)
+	// D changed, and A and C depend on it.
+	for _, rule := range []string{"A", "C", "D"} {
+		if strings.Contains(src, "// copied "+rule+"\n") {
+			t.Errorf("rule %s was copied, want regenerated:\n%s", rule, src)
+		}
+	}
+	if !strings.Contains(src, "// copied B\n") {
+		t.Errorf("rule B was regenerated, want copied:\n%s", src)
+	}
+	if !strings.Contains(src, `"e"`) {
+		t.Errorf("the changed rule D was not regenerated:\n%s", src)
+	}
+	if got := gen(new, ""); got != strings.Replace(src, "// copied B\n", "", 1) {
+		t.Errorf("incremental output differs from full output:\n%s\nwant:\n%s", src, got)
+	}
+}
+
+func TestRuleHash(t *testing.T) {
+	g, err := Parse(strings.NewReader("A <- \"a\""), "")
+	if err != nil {
+		t.Fatalf("Parse(_)=_, %v, want _,nil", err)
+	}
+	if err := Check(g); err != nil {
+		t.Fatalf("Check(_)=%v, want nil", err)
+	}
+	r := g.CheckedRules[0]
+	base := Config{Prefix: "_"}
+	hash := ruleHash(base, g, r)
+	for _, cfg := range []Config{
+		{Prefix: "_", PegParser: true},
+		{Prefix: "_", PegImport: "example.com/peg"},
+		{Prefix: "_", Assert: true},
+		{Prefix: "_", TreeNames: TemplateNames},
+		{Prefix: "_", NoActions: true},
+		{Prefix: "_", NoParseTree: true},
+		{Prefix: "_", AST: true},
+		{Prefix: "_", OptOK: true},
+		{Prefix: "_", Tuples: true},
+		{Prefix: "_", Sandbox: true},
+		{Prefix: "_", Pure: true},
+	} {
+		if ruleHash(cfg, g, r) == hash {
+			t.Errorf("ruleHash(%+v)=ruleHash(%+v), want different", cfg, base)
+		}
+	}
+}
+
+func TestGenPegImport(t *testing.T) {
+	const grammar = `{
+package p
+
+import (
+	"fmt"
+	"github.com/eaburns/peggy/peg"
+	rt "github.com/eaburns/peggy/peg"
+)
+}
+A <- "a"`
+	tests := []struct {
+		path string
+		want []string
+		err  string
+	}{
+		{
+			path: "",
+			want: []string{`"github.com/eaburns/peggy/peg"`, `rt "github.com/eaburns/peggy/peg"`},
+		},
+		{
+			path: "example.com/vendor/peg",
+			want: []string{`"example.com/vendor/peg"`, `rt "example.com/vendor/peg"`},
+		},
+		{
+			path: "example.com/internal/pegrt",
+			want: []string{`peg "example.com/internal/pegrt"`, `rt "example.com/internal/pegrt"`},
+		},
+		{path: "example.com//peg", err: `bad peg import path "example.com//peg"`},
+		{path: "example.com/../peg", err: `bad peg import path "example.com/../peg"`},
+		{path: "example.com/p eg", err: `bad peg import path "example.com/p eg"`},
+	}
+	for _, test := range tests {
+		g, err := Parse(strings.NewReader(grammar), "")
+		if err != nil {
+			t.Fatalf("Parse(_)=_, %v, want _,nil", err)
+		}
+		if err := Check(g); err != nil {
+			t.Fatalf("Check(_)=%v, want nil", err)
+		}
+		var b strings.Builder
+		err = Config{Prefix: "_", PegImport: test.path}.Generate(&b, "", g)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("Generate with PegImport %q=%v, want %s", test.path, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Generate with PegImport %q=%v, want nil", test.path, err)
+			continue
+		}
+		src := b.String()
+		for _, w := range test.want {
+			if !strings.Contains(src, w) {
+				t.Errorf("Generate with PegImport %q does not import %s:\n%s", test.path, w, src)
+			}
+		}
+		if test.path != "" && strings.Contains(src, DefaultPegImport) {
+			t.Errorf("Generate with PegImport %q imports %s:\n%s", test.path, DefaultPegImport, src)
+		}
+	}
+}
+
+func TestGenRuleMetadata(t *testing.T) {
+	const metadataPrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"os"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+var _ *peg.Node
+
+func main() {
+	md := map[string]map[string]string{
+		"A":    _RuleMetadata[_A],
+		"B<C>": _RuleMetadata[_B__C],
+	}
+	if _, ok := _RuleMetadata[_C]; ok {
+		os.Stderr.WriteString("C has metadata\n")
+		os.Exit(1)
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(md); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+	const grammar = `
+		A @category("operator") @deprecated <- B<C> C
+		B<X> @category("template") <- X
+		C @token <- "c"`
+	source := generateTest(Config{Prefix: "_"}, metadataPrelude, grammar)
+	binary := build(source)
+	defer rm(source)
+	defer rm(binary)
+	var got map[string]map[string]string
+	parseGob(binary, "", &got)
+	want := map[string]map[string]string{
+		"A":    {"category": "operator", "deprecated": ""},
+		"B<C>": {"category": "template"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("_RuleMetadata=%v, want %v", got, want)
+	}
+}
+
+func TestGenOptOK(t *testing.T) {
+	const optPrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+var _ *peg.Node
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	p, err := _NewParser(string(data))
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	result := -1
+	if pos, _ := _SumAccepts(p, 0); pos >= 0 {
+		_, v := _SumAction(p, 0)
+		result = *v
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(result); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+	const grammar = `
+		Sum <- x:Num y:("+" n:Num { return int(n) })? !. {
+			if y.OK {
+				return int(x + y.Value)
+			}
+			return int(x)
+		}
+		Num <- d:[0-9]+ {
+			n, _ := strconv.Atoi(d)
+			return int(n)
+		}`
+	g, err := Parse(strings.NewReader(optPrelude+grammar), "")
+	if err != nil {
+		t.Fatalf("Parse(_)=%v, want nil", err)
+	}
+	if err := (Config{Prefix: "_", OptOK: true}).Check(g); err != nil {
+		t.Fatalf("Check(_)=%v, want nil", err)
+	}
+	const wantType = "struct{ Value int; OK bool }"
+	for _, l := range g.Rules[0].Labels {
+		if l.Label.String() == "y" && l.Type() != wantType {
+			t.Errorf("y.Type()=%s, want %s", l.Type(), wantType)
+		}
+	}
+
+	source := generateTest(Config{Prefix: "_", OptOK: true}, optPrelude, grammar)
+	binary := build(source)
+	defer rm(source)
+	defer rm(binary)
+	for _, test := range []struct {
+		input string
+		want  int
+	}{
+		{input: "1", want: 1},
+		{input: "1+23", want: 24},
+		{input: "1+", want: -1},
+	} {
+		var got int
+		parseGob(binary, test.input, &got)
+		if got != test.want {
+			t.Errorf("parse(%q)=%d, want %d", test.input, got, test.want)
+		}
+	}
+}
+
+func TestGenTuples(t *testing.T) {
+	const tuplePrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+var _ *peg.Node
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	p, err := _NewParser(string(data))
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	result := -1
+	if pos, _ := _ProductAccepts(p, 0); pos >= 0 {
+		_, v := _ProductAction(p, 0)
+		result = *v
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(result); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+	const grammar = `
+		Product <- p:("(" Num "," Num ")") !. { return int(p.V1 * p.V3) } / n:Num !. { return int(n) }
+		Num <- d:[0-9]+ {
+			n, _ := strconv.Atoi(d)
+			return int(n)
+		}`
+	g, err := Parse(strings.NewReader(tuplePrelude+grammar), "")
+	if err != nil {
+		t.Fatalf("Parse(_)=%v, want nil", err)
+	}
+	if err := (Config{Prefix: "_", Tuples: true}).Check(g); err != nil {
+		t.Fatalf("Check(_)=%v, want nil", err)
+	}
+	const wantType = "struct{ V0 string; V1 int; V2 string; V3 int; V4 string }"
+	if p := g.Rules[0].Labels[0]; p.Type() != wantType {
+		t.Errorf("p.Type()=%s, want %s", p.Type(), wantType)
+	}
+
+	source := generateTest(Config{Prefix: "_", Tuples: true}, tuplePrelude, grammar)
+	binary := build(source)
+	defer rm(source)
+	defer rm(binary)
+	for _, test := range []struct {
+		input string
+		want  int
+	}{
+		{input: "5", want: 5},
+		{input: "(2,3)", want: 6},
+		{input: "(2,3", want: -1},
+	} {
+		var got int
+		parseGob(binary, test.input, &got)
+		if got != test.want {
+			t.Errorf("parse(%q)=%d, want %d", test.input, got, test.want)
+		}
+	}
+}
+
+func TestGenAST(t *testing.T) {
+	const astPrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+var _ *peg.Node
+
+func (e *_ExprAST) String() string {
+	if e.t != nil {
+		return e.t.String()
+	}
+	return fmt.Sprintf("(%s %s %s)[%d:%d]", e.l, e.op, e.r, e.Begin, e.End)
+}
+
+func (t *_TermAST) String() string {
+	if t.e != nil {
+		return t.e.String()
+	}
+	return fmt.Sprintf("%s[%d:%d]", t.n, t.Begin, t.End)
+}
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	p, err := _NewParser(string(data))
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	var result string
+	if pos, _ := _ExprAccepts(p, 0); pos < 0 {
+		result = "no parse"
+	} else {
+		_, e := _ExprAction(p, 0)
+		result = (*e).String()
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(result); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+	const grammar = `
+		Expr <- l:Term op:("+" / "-") r:Expr / t:Term
+		Term <- n:Num / "(" e:Expr ")"
+		Num <- [0-9]+`
+	source := generateTest(Config{Prefix: "_", AST: true}, astPrelude, grammar)
+	binary := build(source)
+	defer rm(source)
+	defer rm(binary)
+	for _, test := range []struct {
+		input string
+		want  string
+	}{
+		{input: "1", want: "1[0:1]"},
+		{input: "1+23", want: "(1[0:1] + 23[2:4])[0:4]"},
+		{input: "1-(2+3)", want: "(1[0:1] - (2[3:4] + 3[5:6])[3:6])[0:7]"},
+	} {
+		var got string
+		parseGob(binary, test.input, &got)
+		if got != test.want {
+			t.Errorf("parse(%q)=%q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestGenCover(t *testing.T) {
+	const coverPrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+var _ *peg.Node
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	p, err := _NewParser(string(data))
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	if pos, _ := _ExprAccepts(p, 0); pos >= 0 {
+		_ExprAction(p, 0)
+	}
+	var s strings.Builder
+	if err := _Coverage.WriteProfile(&s); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(s.String()); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+	const grammar = `
+Expr <- l:Term op:("+" / "-") r:Expr / t:Term
+Term <- n:Num / "(" e:Expr ")"
+Num <- [0-9]+
+Unused <- "x"`
+	source := generateTest(Config{Prefix: "_", Cover: true}, coverPrelude, grammar)
+	binary := build(source)
+	defer rm(source)
+	defer rm(binary)
+
+	var profiles []string
+	for _, input := range []string{"1+2", "3"} {
+		var profile string
+		parseGob(binary, input, &profile)
+		c, err := peg.ReadProfile(strings.NewReader(profile))
+		if err != nil {
+			t.Fatalf("peg.ReadProfile(%q)=_, %v, want _, nil", profile, err)
+		}
+		if input == "1+2" {
+			// Num accepts at 0 and 2, each counted once,
+			// though the action pass also runs.
+			if c.Points[2].Rule != "Num" || c.Counts[2] != 2 {
+				t.Errorf("parse(%q) Num point %+v count %d, want Num count 2",
+					input, c.Points[2], c.Counts[2])
+			}
+		}
+		f, err := ioutil.TempFile("", "peggy_cover")
+		if err != nil {
+			t.Fatalf("ioutil.TempFile failed: %v", err)
+		}
+		defer rm(f.Name())
+		if _, err := io.WriteString(f, profile); err != nil {
+			t.Fatalf("failed to write profile: %v", err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatalf("failed to close profile: %v", err)
+		}
+		profiles = append(profiles, f.Name())
+	}
+
+	var b strings.Builder
+	if err := CoverReport(&b, profiles); err != nil {
+		t.Fatalf("CoverReport(_, %v)=%v, want nil", profiles, err)
+	}
+	const want = `:44.1,44.14: rule Unused not covered
+:41.26,41.29: branch 2 of rule Expr not covered
+:42.17,42.31: branch 2 of rule Term not covered
+rules: 3/4 (75.0%), branches: 4/6 (66.7%)
+`
+	if b.String() != want {
+		t.Errorf("CoverReport(_, %v) wrote\n%s\nwant\n%s", profiles, b.String(), want)
+	}
+}
+
+func TestGenMatches(t *testing.T) {
+	const matchesPrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+var _ *peg.Node
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(_AMatches(string(data))); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+	const grammar = `
+		A <- B ("," B)*
+		B <- "(" B ")" / C
+		C <- [a-z]+`
+	source := generateTest(Config{Prefix: "_"}, matchesPrelude, grammar)
+	data, err := ioutil.ReadFile(source)
+	if err != nil {
+		t.Fatalf("ReadFile(%q)=_, %v", source, err)
+	}
+	for _, rule := range []string{"B", "C"} {
+		if strings.Contains(string(data), "func _"+rule+"Matches(") {
+			t.Errorf("generated _%sMatches for non-root rule %s", rule, rule)
+		}
+	}
+	binary := build(source)
+	defer rm(source)
+	defer rm(binary)
+	for _, test := range []struct {
+		input string
+		want  bool
+	}{
+		{input: "abc", want: true},
+		{input: "(abc),((x))", want: true},
+		{input: "(abc", want: false},
+		{input: "abc,", want: false},
+		{input: "", want: false},
+	} {
+		var got bool
+		parseGob(binary, test.input, &got)
+		if got != test.want {
+			t.Errorf("_AMatches(%q)=%v, want %v", test.input, got, test.want)
+		}
+	}
+}
+
+// TestGenLazyTables tests that the Node and Fail passes
+// allocate their memo tables when first run
+// after an Accepts pass that did not allocate them,
+// and that the rerun passes hit the tables.
+func TestGenLazyTables(t *testing.T) {
+	const lazyPrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	p, err := _NewParser(string(data))
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	var result struct {
+		Allocated bool
+		Memoized  bool
+		Node      *peg.Node
+		Fail      *peg.Fail
+	}
+	pos, perr := _AAccepts(p, 0)
+	result.Allocated = p.node != nil || p.fail != nil
+	if pos >= 0 {
+		_, result.Node = _ANode(p, 0)
+		_, again := _ANode(p, 0)
+		result.Memoized = again == result.Node
+	} else {
+		_, result.Fail = _AFail(p, 0, perr)
+		_, again := _AFail(p, 0, perr)
+		result.Memoized = again == result.Fail
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(&result); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+	const grammar = `
+		A <- B B / B "!"
+		B <- [a-z]`
+	source := generateTest(Config{Prefix: "_"}, lazyPrelude, grammar)
+	binary := build(source)
+	defer rm(source)
+	defer rm(binary)
+	for _, test := range []struct {
+		input string
+		node  *peg.Node
+		fail  *peg.Fail
+	}{
+		{
+			input: "ab",
+			node: &peg.Node{
+				Name: "A",
+				Text: "ab",
+				Kids: []*peg.Node{
+					{Name: "B", Text: "a", Kids: []*peg.Node{{Text: "a"}}},
+					{Name: "B", Text: "b", Kids: []*peg.Node{{Text: "b"}}},
+				},
+			},
+		},
+		{
+			input: "a?",
+			fail: &peg.Fail{
+				Name: "A",
+				Kids: []*peg.Fail{
+					{
+						Name: "B",
+						Pos:  1,
+						Kids: []*peg.Fail{{Pos: 1, Want: "[a-z]", Code: peg.ExpectedClass}},
+					},
+					{Pos: 1, Want: `"!"`, Code: peg.ExpectedLiteral},
+				},
+			},
+		},
+	} {
+		var result struct {
+			Allocated bool
+			Memoized  bool
+			Node      *peg.Node
+			Fail      *peg.Fail
+		}
+		parseGob(binary, test.input, &result)
+		if result.Allocated {
+			t.Errorf("parse %q: the Accepts pass allocated the node or fail table", test.input)
+		}
+		if !result.Memoized {
+			t.Errorf("parse %q: the rerun pass was not memoized", test.input)
+		}
+		if !reflect.DeepEqual(result.Node, test.node) {
+			t.Errorf("parse %q: node=%s, want %s", test.input, pretty.String(result.Node), pretty.String(test.node))
+		}
+		if !reflect.DeepEqual(result.Fail, test.fail) {
+			t.Errorf("parse %q: fail=%s, want %s", test.input, pretty.String(result.Fail), pretty.String(test.fail))
+		}
+	}
+}
+
+func TestGenParseAt(t *testing.T) {
+	// The input is the start offset and a space,
+	// followed by the text.
+	const parseAtPrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+var _ *peg.Node
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	var start int
+	fmt.Sscanf(string(data), "%d", &start)
+	text := string(data[len(fmt.Sprint(start))+1:])
+	parser, err := _NewParser(text)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	var result struct {
+		End int
+		V   int
+		Err string
+	}
+	result.End, result.V, err = _SumParseAt(parser, start)
+	if err != nil {
+		result.Err = err.Error()
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(&result); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+	const grammar = `
+		Sum <- x:Num "+" y:Sum { return int(x + y) } / Num
+		Num <- n:[0-9]+ { return int(len(n)) }`
+	source := generateTest(Config{Prefix: "_"}, parseAtPrelude, grammar)
+	binary := build(source)
+	defer rm(source)
+	defer rm(binary)
+	for _, test := range []struct {
+		start int
+		text  string
+		end   int
+		v     int
+		err   string
+	}{
+		{start: 0, text: "1+22", end: 4, v: 3},
+		{start: 3, text: "{{ 1+22 }}", end: 7, v: 3},
+		{start: 3, text: "{{ 1+22+333 }}", end: 11, v: 6},
+		{start: 2, text: "x 1 + 2", end: 3, v: 1},
+		{start: 2, text: "{{", end: -1, err: ":1.3: want [0-9]; got EOF"},
+		{start: 1, text: "{x}", end: -1, err: ":1.2: want [0-9]; got 'x}'"},
+	} {
+		var got struct {
+			End int
+			V   int
+			Err string
+		}
+		parseGob(binary, fmt.Sprintf("%d %s", test.start, test.text), &got)
+		if got.End != test.end || got.V != test.v || got.Err != test.err {
+			t.Errorf("_SumParseAt(%q, %d)=%d, %d, %q, want %d, %d, %q",
+				test.text, test.start, got.End, got.V, got.Err,
+				test.end, test.v, test.err)
+		}
+	}
+}
+
+func TestGenExpect(t *testing.T) {
+	const expectPrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+var _ *peg.Node
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	parser, err := _NewParser(string(data))
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	var result struct {
+		End int
+		V   int
+		Err string
+	}
+	result.End, result.V, err = _SumParseAt(parser, 0)
+	if err != nil {
+		result.Err = err.Error()
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(&result); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+	const grammar = `
+		Sum <- x:Num "+" y:Sum { return int(x + y) } / Num
+		Num <- n:[0-9]+ {
+			parser.Expect(len(n) < 3, start, "number too long")
+			parser.Expect(n[0] != '0' || len(n) == 1, end, "leading zero")
+			return int(len(n))
+		} !memo`
+	source := generateTest(Config{Prefix: "_"}, expectPrelude, grammar)
+	binary := build(source)
+	defer rm(source)
+	defer rm(binary)
+	for _, test := range []struct {
+		text string
+		end  int
+		v    int
+		err  string
+	}{
+		{text: "1+22", end: 4, v: 3},
+		{text: "1+222", end: -1, err: ":1.3: number too long"},
+		{text: "1+03+4444", end: -1, err: ":1.5: leading zero"},
+	} {
+		var got struct {
+			End int
+			V   int
+			Err string
+		}
+		parseGob(binary, test.text, &got)
+		if got.End != test.end || got.V != test.v || got.Err != test.err {
+			t.Errorf("_SumParseAt(%q, 0)=%d, %d, %q, want %d, %d, %q",
+				test.text, got.End, got.V, got.Err, test.end, test.v, test.err)
+		}
+	}
+}
+
+func TestGenDiagnostics(t *testing.T) {
+	const diagPrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	parser, err := _NewParser(string(data))
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	var result struct {
+		V     int
+		Diags []string
+		Err   string
+	}
+	_, result.V, err = _SumParseAt(parser, 0)
+	if err != nil {
+		result.Err = err.Error()
+	}
+	for _, d := range _Diagnostics(parser) {
+		result.Diags = append(result.Diags, fmt.Sprintf("%s-%d.%d", d, d.End.Line, d.End.Column))
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(&result); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+var _ *peg.Node
+}
+`
+	const grammar = `
+		Sum <- x:Num "+" y:Sum { return int(x + y) } / Num
+		Num <- n:[0-9]+ {
+			if len(n) > 1 && n[0] == '0' {
+				parser.Diag(start, end, "deprecated octal")
+			}
+			return int(len(n))
+		} !memo`
+	source := generateTest(Config{Prefix: "_"}, diagPrelude, grammar)
+	binary := build(source)
+	defer rm(source)
+	defer rm(binary)
+	for _, test := range []struct {
+		text  string
+		v     int
+		diags []string
+		err   string
+	}{
+		{text: "1+22", v: 3},
+		{text: "01+2+003", v: 6, diags: []string{
+			":1.1: deprecated octal-1.3",
+			":1.6: deprecated octal-1.9",
+		}},
+		{text: "01+", v: 2, diags: []string{":1.1: deprecated octal-1.3"}},
+	} {
+		var got struct {
+			V     int
+			Diags []string
+			Err   string
+		}
+		parseGob(binary, test.text, &got)
+		if got.V != test.v || !reflect.DeepEqual(got.Diags, test.diags) || got.Err != test.err {
+			t.Errorf("parse(%q)=%d, %q, %q, want %d, %q, %q",
+				test.text, got.V, got.Diags, got.Err, test.v, test.diags, test.err)
+		}
+	}
+}
+
+func TestGenParseBounded(t *testing.T) {
+	// The input is the max and a space,
+	// followed by the text.
+	const parseBoundedPrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+var _ *peg.Node
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	var max int
+	fmt.Sscanf(string(data), "%d", &max)
+	text := string(data[len(fmt.Sprint(max))+1:])
+	var result struct {
+		End int
+		Err string
+	}
+	result.End, _, err = _StmtParseBounded(text, max)
+	if err != nil {
+		result.Err = err.Error()
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(&result); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+	const grammar = `
+		Stmt <- Expr ";"
+		Expr <- Num / Ident / Paren
+		Num "number" <- [0-9]+
+		Ident <- [a-z]+
+		Paren <- "(" Expr ")"`
+	source := generateTest(Config{Prefix: "_"}, parseBoundedPrelude, grammar)
+	binary := build(source)
+	defer rm(source)
+	defer rm(binary)
+	for _, test := range []struct {
+		max  int
+		text string
+		end  int
+		err  string
+	}{
+		{max: 1, text: "((x));", end: 6},
+		{max: 10, text: "(", end: -1, err: `:1.2: want "(", [a-z], or number; got EOF`},
+		{max: 10, text: "((", end: -1, err: `:1.3: want "(", [a-z], or number; got EOF`},
+		{max: 3, text: "(", end: -1, err: `:1.2: want Expr; got EOF`},
+		{max: 2, text: "(", end: -1, err: `:1.2: want Paren; got EOF`},
+		{max: 2, text: "x", end: -1, err: `:1.2: want ";" or Ident; got EOF`},
+		{max: 3, text: "x", end: -1, err: `:1.2: want ";" or [a-z]; got EOF`},
+		{max: 1, text: "(", end: -1, err: `:1.2: want Expr; got EOF`},
+		{max: 0, text: "(", end: -1, err: `:1.2: want Expr; got EOF`},
+		{max: 1, text: "((x)", end: -1, err: `:1.5: want Expr; got EOF`},
+	} {
+		var got struct {
+			End int
+			Err string
+		}
+		parseGob(binary, fmt.Sprintf("%d %s", test.max, test.text), &got)
+		if got.End != test.end || got.Err != test.err {
+			t.Errorf("_StmtParseBounded(%q, %d)=%d, _, %q, want %d, _, %q",
+				test.text, test.max, got.End, got.Err, test.end, test.err)
+		}
+	}
+}
+
+func TestGenPegParser(t *testing.T) {
+	// The input is the rule name and a space,
+	// followed by the text.
+	const pegParserPrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	i := strings.Index(string(data), " ")
+	rule, text := string(data[:i]), string(data[i+1:])
+	p, err := _NewParser(text)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	var parser peg.Parser = p
+	var result struct {
+		Node, Value string
+	}
+	if node, err := parser.ParseNode(rule); err != nil {
+		result.Node = err.Error()
+	} else {
+		result.Node = peg.Pretty(node)
+	}
+	if v, err := parser.ParseValue(rule); err != nil {
+		result.Value = err.Error()
+	} else {
+		result.Value = fmt.Sprintf("%T %v", v, v)
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(&result); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+	const grammar = `
+		Sum <- x:Num "+" y:Num { return int(x + y) }
+		Num <- n:[0-9] { return int(n[0] - '0') }
+		Deep @param(depth int) <- "d"
+		List <- Sep<",">
+		Sep<S> <- "x" (S "x")*`
+	source := generateTest(Config{Prefix: "_", PegParser: true}, pegParserPrelude, grammar)
+	binary := build(source)
+	defer rm(source)
+	defer rm(binary)
+	for _, test := range []struct {
+		rule, text  string
+		node, value string
+	}{
+		{rule: "Sum", text: "1+2", node: `Sum{Num{"1"}, "+", Num{"2"},}`, value: "int 3"},
+		{rule: "Num", text: "7+", node: `Num{"7"}`, value: "int 7"},
+		{rule: "Sum", text: "1-", node: `:1.2: want "+"; got '-'`, value: `:1.2: want "+"; got '-'`},
+		{rule: "Sep<\",\">", text: "x,x", node: `Sep<",">{"x", {",", "x",},}`, value: "string x,x"},
+		{rule: "Deep", text: "d", node: "rule Deep has parameters", value: "rule Deep has parameters"},
+		{rule: "Nope", text: "", node: "rule Nope undefined", value: "rule Nope undefined"},
+	} {
+		var got struct {
+			Node, Value string
+		}
+		parseGob(binary, test.rule+" "+test.text, &got)
+		if node := strings.Join(strings.Fields(got.Node), ""); node != strings.Join(strings.Fields(test.node), "") {
+			t.Errorf("ParseNode(%q) on %q=%q, want %q", test.rule, test.text, got.Node, test.node)
+		}
+		if got.Value != test.value {
+			t.Errorf("ParseValue(%q) on %q=%q, want %q", test.rule, test.text, got.Value, test.value)
+		}
+	}
+}
+
+func TestGenRecover(t *testing.T) {
+	const recoverPrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	p, err := _NewParser(string(data))
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	var result struct {
+		Pos       int
+		Node      string
+		Recovered []string
+	}
+	result.Pos, _ = _BlockAccepts(p, 0)
+	if result.Pos >= 0 {
+		_, node := _BlockNode(p, 0)
+		result.Node = peg.Pretty(node)
+		_BlockAction(p, 0)
+	}
+	for _, r := range _Recoveries(p) {
+		loc := peg.Location(p.text, r.Pos)
+		result.Recovered = append(result.Recovered, fmt.Sprintf("%s %d.%d %s %s",
+			r.Name, loc.Line, loc.Column, p.text[r.Pos:r.End], peg.SimpleError(p.text, r.Fail)))
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(&result); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+	const grammar = `
+		Block <- "{" Stmt* "}" !. { return "ok" }
+		Stmt @recoverpast(";") @recoveruntil("}") <- [a-z]+ "=" [0-9]+ ";"`
+	source := generateTest(Config{Prefix: "_"}, recoverPrelude, grammar)
+	binary := build(source)
+	defer rm(source)
+	defer rm(binary)
+	for _, test := range []struct {
+		text      string
+		pos       int
+		node      string
+		recovered []string
+	}{
+		{
+			text: "{a=1;b=2;}",
+			pos:  10,
+			node: `Block{"{", Stmt{"a", "=", "1", ";",}, Stmt{"b", "=", "2", ";",}, "}",}`,
+		},
+		{
+			text: "{a=x;b=2;}",
+			pos:  10,
+			node: `Block{"{", Stmt("a=x;"), Stmt{"b", "=", "2", ";",}, "}",}`,
+			recovered: []string{
+				`Stmt 1.2 a=x; :1.4: want [0-9]; got 'x;b=2;}'`,
+			},
+		},
+		{
+			text: "{a=1;b=}",
+			pos:  8,
+			node: `Block{"{", Stmt{"a", "=", "1", ";",}, Stmt("b="), "}",}`,
+			recovered: []string{
+				`Stmt 1.6 b= :1.8: want [0-9]; got '}'`,
+			},
+		},
+		{
+			text: "{a;b;c=3;}",
+			pos:  10,
+			node: `Block{"{", Stmt("a;"), Stmt("b;"), Stmt{"c", "=", "3", ";",}, "}",}`,
+			recovered: []string{
+				`Stmt 1.2 a; :1.3: want [a-z] or "="; got ';b;c=3;}'`,
+				`Stmt 1.4 b; :1.5: want [a-z] or "="; got ';c=3;}'`,
+			},
+		},
+		{
+			text: "{}}",
+			pos:  -1,
+		},
+	} {
+		var got struct {
+			Pos       int
+			Node      string
+			Recovered []string
+		}
+		parseGob(binary, test.text, &got)
+		if got.Pos != test.pos {
+			t.Errorf("_BlockAccepts(%q)=%d, want %d", test.text, got.Pos, test.pos)
+		}
+		if node := strings.Join(strings.Fields(got.Node), ""); node != strings.Join(strings.Fields(test.node), "") {
+			t.Errorf("_BlockNode(%q)=%s, want %s", test.text, got.Node, test.node)
+		}
+		if !reflect.DeepEqual(got.Recovered, test.recovered) {
+			t.Errorf("_Recoveries(%q)=%q, want %q", test.text, got.Recovered, test.recovered)
+		}
+	}
+}
+
+func TestGenErrorModes(t *testing.T) {
+	const errorModesPrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	p, err := _NewParser(string(data))
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	var result string
+	if _, _, err := _TopParseAt(p, 0); err != nil {
+		result = err.Error()
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(&result); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+	const grammar = `
+		Top <- Stmts / Junk
+		Stmts <- (Stmt ";")* !.
+		Stmt <- "if" ^ " " Cond / [a-z]+ "=" [0-9]+
+		Cond "condition" <- "(" [a-z]+ ")"
+		Junk <- [a-z ]+ "!"`
+	for _, test := range []struct {
+		mode  ErrorMode
+		input string
+		want  string
+	}{
+		{mode: FurthestErrors, input: "if x;", want: `:1.5: want [a-z ] or "!"; got ';'`},
+		{mode: CutErrors, input: "if x;", want: `:1.4: want condition; got 'x;'`},
+		{mode: MergedErrors, input: "if x;", want: `:1.4: want condition; got 'x;'; furthest at 1.5: want [a-z ] or "!"; got ';'`},
+		{mode: CutErrors, input: "if (x);", want: ""},
+		// Without a committed failure, the furthest is reported.
+		{mode: CutErrors, input: "x=;", want: `:1.3: want [0-9]; got ';'`},
+		{mode: MergedErrors, input: "x=;", want: `:1.3: want [0-9]; got ';'`},
+	} {
+		source := generateTest(Config{Prefix: "_", Errors: test.mode}, errorModesPrelude, grammar)
+		binary := build(source)
+		var got string
+		parseGob(binary, test.input, &got)
+		rm(source)
+		rm(binary)
+		if got != test.want {
+			t.Errorf("mode %d: _TopParseAt(%q)=%q, want %q", test.mode, test.input, got, test.want)
+		}
+	}
+}
+
+func TestGenWarnSlow(t *testing.T) {
+	const warnSlowPrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	parser, err := _NewParser(string(data))
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	_FileAccepts(parser, 0)
+	var slow []string
+	for _, s := range _SlowParses(parser) {
+		if s.Duration <= s.Budget {
+			os.Stderr.WriteString(s.String() + " is within budget\n")
+			os.Exit(1)
+		}
+		slow = append(slow, fmt.Sprintf("%s [%d:%d]", s.Rule, s.Start, s.End))
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(slow); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+	const grammar = `
+		%warnslow 1h
+		File <- List !.
+		List <- Elem ("," Elem)*
+		Elem @warnslow(1ns) <- Num / "(" List ")"
+		Num <- [0-9]+`
+	source := generateTest(Config{Prefix: "_"}, warnSlowPrelude, grammar)
+	binary := build(source)
+	defer rm(source)
+	defer rm(binary)
+	for _, test := range []struct {
+		input string
+		want  []string
+	}{
+		{input: "1", want: []string{"Elem [0:1]"}},
+		{input: "1,22", want: []string{"Elem [0:1]", "Elem [2:4]"}},
+		{input: "(1),x", want: []string{"Elem [1:2]", "Elem [0:3]", "Elem [4:4]"}},
+	} {
+		var got []string
+		parseGob(binary, test.input, &got)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("_SlowParses after %q=%q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestGenDelegate(t *testing.T) {
+	const delegatePrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+// digits is a delegate parser of a decimal number.
+func digits(s string) (int, error) {
+	if s == "" {
+		return 0, errors.New("empty number")
+	}
+	for i, r := range s {
+		if r < '0' || r > '9' {
+			return 0, peg.Error{Loc: peg.Location(s, i), Message: "want [0-9]; got " + s[i:]}
+		}
+	}
+	return strconv.Atoi(s)
+}
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	text := string(data)
+	parser, err := _NewParser(text)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	var result struct {
+		V    int
+		Kids []string
+		Err  string
+	}
+	if pos, perr := _SumAccepts(parser, 0); pos < 0 {
+		_, fail := _SumFail(parser, 0, perr)
+		result.Err = peg.SimpleError(text, fail).Error()
+	} else {
+		_, node := _SumNode(parser, 0)
+		for _, kid := range node.Kids {
+			result.Kids = append(result.Kids, kid.Text)
+		}
+		_, v := _SumAction(parser, 0)
+		result.V = *v
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(&result); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+	const grammar = `
+		Sum <- x:Num "+" y:Sum { return int(x + y) } / x:Num !. { return int(x) }
+		Num -> int <- %delegate(digits, "<<", ">>")`
+	source := generateTest(Config{Prefix: "_"}, delegatePrelude, grammar)
+	binary := build(source)
+	defer rm(source)
+	defer rm(binary)
+	for _, test := range []struct {
+		text string
+		v    int
+		kids []string
+		err  string
+	}{
+		{text: "<<12>>", v: 12, kids: []string{"<<12>>"}},
+		{text: "<<1>>+<<22>>", v: 23, kids: []string{"<<1>>", "+", "<<22>>"}},
+		{text: "<<1x>>", err: ":1.4: want [0-9]; got 'x>>'"},
+		{text: "<<1>>+<<>>", err: ":1.9: want empty number; got '>>'"},
+		{text: "<<1>>+<<2", err: ":1.10: want \">>\"; got EOF"},
+		{text: "(12)", err: ":1.1: want \"<<\"; got '(12)'"},
+	} {
+		var got struct {
+			V    int
+			Kids []string
+			Err  string
+		}
+		parseGob(binary, test.text, &got)
+		if got.V != test.v || !reflect.DeepEqual(got.Kids, test.kids) || got.Err != test.err {
+			t.Errorf("parse(%q)=%d, %q, %q, want %d, %q, %q",
+				test.text, got.V, got.Kids, got.Err, test.v, test.kids, test.err)
+		}
+	}
+}
+
+func TestGenNative(t *testing.T) {
+	const nativePrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+// digits is a native matcher of a decimal number.
+func digits(text string, pos int) (int, *peg.Fail) {
+	end := pos
+	for end < len(text) && text[end] >= '0' && text[end] <= '9' {
+		end++
+	}
+	if end == pos {
+		return 0, &peg.Fail{Pos: pos, Want: "digits"}
+	}
+	return end, nil
+}
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	text := string(data)
+	parser, err := _NewParser(text)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	var result struct {
+		V    string
+		Kids []string
+		Err  string
+	}
+	if pos, perr := _ListAccepts(parser, 0); pos < 0 {
+		_, fail := _ListFail(parser, 0, perr)
+		result.Err = peg.SimpleError(text, fail).Error()
+	} else {
+		_, node := _ListNode(parser, 0)
+		for _, kid := range node.Kids {
+			result.Kids = append(result.Kids, kid.Text)
+		}
+		_, v := _ListAction(parser, 0)
+		result.V = *v
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(&result); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+	const grammar = `
+		List <- x:Num "," y:List { return string(x + "+" + y) } / x:Num !. { return string(x) }
+		Num <- %native(digits)`
+	source := generateTest(Config{Prefix: "_"}, nativePrelude, grammar)
+	binary := build(source)
+	defer rm(source)
+	defer rm(binary)
+	for _, test := range []struct {
+		text string
+		v    string
+		kids []string
+		err  string
+	}{
+		{text: "12", v: "12", kids: []string{"12"}},
+		{text: "1,22", v: "1+22", kids: []string{"1", ",", "22"}},
+		{text: "1,", err: ":1.3: want digits; got EOF"},
+		{text: "1x", err: ":1.2: want \",\" or !.; got 'x'"},
+		{text: "x", err: ":1.1: want digits; got 'x'"},
+	} {
+		var got struct {
+			V    string
+			Kids []string
+			Err  string
+		}
+		parseGob(binary, test.text, &got)
+		if got.V != test.v || !reflect.DeepEqual(got.Kids, test.kids) || got.Err != test.err {
+			t.Errorf("parse(%q)=%q, %q, %q, want %q, %q, %q",
+				test.text, got.V, got.Kids, got.Err, test.v, test.kids, test.err)
+		}
+	}
+}
+
+// generateTest generates Go source code for a Peggy
+func generateTest(cfg Config, prelude string, input string) string {
+	f, err := ioutil.TempFile(os.TempDir(), "peggy_test")
+	if err != nil {
+		panic(err.Error())
+	}
+	input = prelude + input
+	g, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		fmt.Printf("%s\n", input)
+		panic(err.Error())
+	}
+	if err := cfg.Check(g); err != nil {
+		fmt.Printf("%s\n", input)
+		panic(err.Error())
+	}
+	// Line comments, since the rules may contain */.
+	rules := "// " + strings.ReplaceAll(String(g.Rules), "\n", "\n// ")
+	if _, err := io.WriteString(f, rules+"\n"); err != nil {
+		panic(err.Error())
+	}
+	if err := cfg.Generate(f, "", g); err != nil {
+		panic(err.Error())
+	}
+	fileName := f.Name()
+	if err := f.Close(); err != nil {
+		panic(err.Error())
+	}
+	goName := fileName + ".go"
+	if err := os.Rename(fileName, goName); err != nil {
+		panic(err.Error())
+	}
+	return goName
+}
+
+// build compiles a Go source and returns the path to the binary.
+func build(source string) string {
+	cmd := exec.Command("go", "build", source)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	if err := cmd.Run(); err != nil {
+		c := cmd.Path + " " + strings.Join(cmd.Args[1:], " ")
+		panic("failed to run [" + c + "]: " + err.Error() +
+			" (source in " + source + ")")
+	}
+	return "./" + filepath.Base(strings.TrimSuffix(source, ".go"))
+}
+
+// rm removes a temporary file of a test,
+// unless $PEGGY_KEEP is set,
+// in which case its path is printed to standard error instead.
+func rm(file string) {
+	if os.Getenv("PEGGY_KEEP") != "" {
+		fmt.Fprintf(os.Stderr, "peggy: kept %s\n", file)
+		return
+	}
+	if err := os.Remove(file); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to remove %s: %s", file, err)
+	}
+}
+
+// parseGob parses an input using the given binary
+// and returns the position of either the parse or error
+// along with whether the parse succeeded.
+func TestGenErrorCode(t *testing.T) {
+	const prelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	text := string(data)
+	parser, err := _NewParser(text)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	var result struct{ Err, Code, Rule string }
+	if pos, perr := _StmtsAccepts(parser, 0); pos < 0 {
+		_, fail := _StmtsFail(parser, 0, perr)
+		err := peg.SimpleError(text, fail)
+		result.Err, result.Code, result.Rule = err.Error(), string(err.Code), err.Rule
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(&result); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+	const grammar = `
+		Stmts <- (Stmt Semi)* !.
+		Stmt <- Name "=" Num
+		Semi <- ";"
+		Name "name" <- [a-z]+
+		Num <- [0-9]`
+	source := generateTest(Config{Prefix: "_"}, prelude, grammar)
+	binary := build(source)
+	defer rm(source)
+	defer rm(binary)
+	for _, test := range []struct {
+		text, code, rule string
+	}{
+		{text: "x=1;"},
+		{text: "x=1", code: "expected-literal", rule: "Semi"},
+		{text: "x=", code: "expected-class", rule: "Num"},
+		{text: "x=1;7", code: "named-rule", rule: "Name"},
+	} {
+		var got struct{ Err, Code, Rule string }
+		parseGob(binary, test.text, &got)
+		if got.Code != test.code || got.Rule != test.rule {
+			t.Errorf("parse(%q)=%q with code %q, rule %q, want code %q, rule %q",
+				test.text, got.Err, got.Code, got.Rule, test.code, test.rule)
+		}
+	}
+}
+
+func TestGenTreeNames(t *testing.T) {
+	const prelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	text := string(data)
+	parser, err := _NewParser(text)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	var result struct {
+		Names []string
+		Rule  string
+	}
+	if pos, perr := _AAccepts(parser, 0); pos < 0 {
+		_, fail := _AFail(parser, 0, perr)
+		result.Rule = peg.SimpleError(text, fail).Rule
+	} else {
+		_, node := _ANode(parser, 0)
+		for _, kid := range node.Kids {
+			if kid.Name != "" {
+				result.Names = append(result.Names, kid.Name)
+			}
+		}
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(&result); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+	const grammar = `
+		A <- nums:List<Num> ";" List<Name> !.
+		List<X> <- "[" X ("," X)* "]"
+		Num <- [0-9]
+		Name <- [a-z]`
+	for _, test := range []struct {
+		naming TreeNaming
+		names  []string
+		rule   string
+	}{
+		{naming: InstanceNames, names: []string{"List<Num>", "List<Name>"}, rule: "List<Name>"},
+		{naming: TemplateNames, names: []string{"List", "List"}, rule: "List"},
+		{naming: LabelNames, names: []string{"nums", "List"}, rule: "List"},
+	} {
+		source := generateTest(Config{Prefix: "_", TreeNames: test.naming}, prelude, grammar)
+		binary := build(source)
+		rm(source)
+		var got struct {
+			Names []string
+			Rule  string
+		}
+		parseGob(binary, "[1,2];[a]", &got)
+		if !reflect.DeepEqual(got.Names, test.names) {
+			t.Errorf("TreeNames %d: node names=%q, want %q", test.naming, got.Names, test.names)
+		}
+		got.Names, got.Rule = nil, ""
+		parseGob(binary, "[1,2];[a", &got)
+		if got.Rule != test.rule {
+			t.Errorf("TreeNames %d: error rule=%q, want %q", test.naming, got.Rule, test.rule)
+		}
+		rm(binary)
+	}
+}
+
+func parseGob(binary, input string, result interface{}) {
+	cmd := exec.Command(binary)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		panic(err.Error())
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		panic(err.Error())
+	}
+	if err := cmd.Start(); err != nil {
+		panic(err.Error())
+	}
+	go func() {
+		if _, err := io.WriteString(stdin, input); err != nil {
+			panic(err.Error())
+		}
+		if err := stdin.Close(); err != nil {
+			panic(err.Error())
+		}
+	}()
+	if err := gob.NewDecoder(stdout).Decode(result); err != nil {
+		panic(err.Error())
+	}
+	if err := cmd.Wait(); err != nil {
+		panic(err.Error())
+	}
+}
+
+var prelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	p, err := _NewParser(string(data))
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	var result struct {
+		Pos int
+		Perr int
+		Node       *peg.Node
+		Fail       *peg.Fail
+	}
+	if result.Pos, result.Perr = _AAccepts(p, 0); result.Pos >= 0 {
+		_, result.Node = _ANode(p, 0)
+	} else {
+		_, result.Fail = _AFail(p, 0, result.Perr)
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(&result); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+
+// sharedPrelude is like prelude,
+// but it parses the input with a shared memo cache
+// after parsing texts with prefixes in common with the input.
+var sharedPrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	text := string(data)
+	cache := peg.NewMemoCache(1 << 20)
+	for _, t := range []string{text, text + "x", text[:len(text)/2], text + text} {
+		p, err := _NewSharedParser(t, cache)
+		if err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(1)
+		}
+		_AAccepts(p, 0)
+	}
+	p, err := _NewSharedParser(text, cache)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	var result struct {
+		Pos int
+		Perr int
+		Node       *peg.Node
+		Fail       *peg.Fail
+	}
+	if result.Pos, result.Perr = _AAccepts(p, 0); result.Pos >= 0 {
+		_, result.Node = _ANode(p, 0)
+	} else {
+		_, result.Fail = _AFail(p, 0, result.Perr)
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(&result); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+
+// sharedBenchPrelude benchmarks parsing the input
+// followed by different suffixes,
+// first each with a new parser,
+// then each with a parser sharing a memo cache.
+var sharedBenchPrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	var texts []string
+	for i := 0; i < 10; i++ {
+		texts = append(texts, string(data)+"k"+strconv.Itoa(i)+";")
+	}
+	var ns [2]int64
+	for i, shared := range []bool{false, true} {
+		r := testing.Benchmark(func(b *testing.B) {
+			cache := peg.NewMemoCache(1 << 20)
+			for i := 0; i < b.N; i++ {
+				var p *_Parser
+				var err error
+				if shared {
+					p, err = _NewSharedParser(texts[i%len(texts)], cache)
+				} else {
+					p, err = _NewParser(texts[i%len(texts)])
+				}
+				if err != nil {
+					panic(err.Error())
+				}
+				if pos, _ := _AAccepts(p, 0); pos < 0 {
+					panic("parse failed")
+				}
+			}
+		})
+		ns[i] = r.NsPerOp()
+	}
+	if err := gob.NewEncoder(os.Stdout).Encode(ns); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
+
+var benchPrelude = `{
+package main
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+var _ *peg.Node
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	r := testing.Benchmark(func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			p, err := _NewParser(string(data))
+			if err != nil {
+				panic(err.Error())
+			}
+			if pos, _ := _AAccepts(p, 0); pos < 0 {
+				panic("parse failed")
+			}
+		}
+	})
+	if err := gob.NewEncoder(os.Stdout).Encode(r.NsPerOp()); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+}
+`
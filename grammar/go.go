@@ -0,0 +1,509 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/scanner"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// ParseGoFile parses go function body statements, returning any syntax errors.
+// The errors contain location information starting from the given Loc.
+func ParseGoFile(loc Loc, code string) error {
+	_, err := parser.ParseFile(token.NewFileSet(), loc.File, code, 0)
+	if err == nil {
+		return nil
+	}
+
+	el, ok := err.(scanner.ErrorList)
+	if !ok {
+		return err
+	}
+	p := el[0].Pos
+	loc.Line += p.Line - 1 // -1 because p.Line is 1-based.
+	if p.Line > 1 {
+		loc.Col = 1
+	}
+	loc.Col += p.Column - 1
+	return Err(loc, el[0].Msg)
+}
+
+// parseGoDecls parses go top-level declarations, returning any syntax errors.
+func parseGoDecls(loc Loc, code string) error {
+	code = "package main\n" + code
+	_, err := parser.ParseFile(token.NewFileSet(), loc.File, code, 0)
+	if err == nil {
+		return nil
+	}
+
+	el, ok := err.(scanner.ErrorList)
+	if !ok {
+		return err
+	}
+	p := el[0].Pos
+	loc.Line += p.Line - 2 // -2 because p.Line is 1-based and the package line.
+	if p.Line > 2 {
+		loc.Col = 1
+	}
+	loc.Col += p.Column - 1
+	return Err(loc, el[0].Msg)
+}
+
+// parseGoBody parses go function body statements, returning any syntax errors.
+// The errors contain location information starting from the given Loc.
+func parseGoBody(loc Loc, code string) (string, error) {
+	fset, file, err := parseGoFunc(loc, code)
+	if err != nil {
+		return "", err
+	}
+	return inferType(loc, fset, file)
+}
+
+// parseGoFunc is like parseGoBody,
+// but it returns the parsed function instead of inferring its type.
+func parseGoFunc(loc Loc, code string) (*token.FileSet, *ast.File, error) {
+	code = "package main; func p() interface{} {\n" + code + "}"
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, loc.File, code, 0)
+	if err == nil {
+		return fset, file, nil
+	}
+
+	el, ok := err.(scanner.ErrorList)
+	if !ok {
+		return nil, nil, err
+	}
+	p := el[0].Pos
+	loc.Line += p.Line - 2 // -2 because p.Line is 1-based and the func line.
+	if p.Line > 2 {
+		loc.Col = 1
+	}
+	loc.Col += p.Column - 1
+	return nil, nil, Err(loc, el[0].Msg)
+}
+
+// inferType infers the type of a function by considering its first return statement.
+// If the returned expression is:
+//   - a type conversion, the type is returned.
+//   - a type assertion, the type is returned.
+//   - a function literal, the type is returned.
+//   - a composite literal, the type is returned.
+//   - an &-composite literal, the type is returned.
+//   - an int literal, int is returned.
+//   - a float literal, float64 is returned.
+//   - a character literal, rune is returned.
+//   - a string literal, string is returned.
+//
+// If the file does not have exactly one top-level funciton, inferType panics.
+// If the function has no return statement, an error is returned.
+// If the return statement does not have exactly one returned value, an error is returned.
+// If the returned value is not an expression in the list above, an error is returned.
+func inferType(loc Loc, fset *token.FileSet, file *ast.File) (string, error) {
+	var funcDecl *ast.FuncDecl
+	for _, decl := range file.Decls {
+		if d, ok := decl.(*ast.FuncDecl); ok {
+			if funcDecl != nil {
+				panic("multiple function declarations")
+			}
+			funcDecl = d
+		}
+	}
+	if funcDecl == nil {
+		panic("no function declarations")
+	}
+
+	var v findReturnVisitor
+	ast.Walk(&v, funcDecl)
+	if v.retStmt == nil {
+		return "", Err(loc, "no return statement")
+	}
+	if len(v.retStmt.Results) != 1 {
+		return "", Err(loc, "must return exactly one value")
+	}
+
+	var typ interface{}
+	switch e := v.retStmt.Results[0].(type) {
+	case *ast.CallExpr:
+		if len(e.Args) != 1 {
+			var s strings.Builder
+			printer.Fprint(&s, fset, e)
+			return "", Err(loc, "cannot infer type from a function call: "+s.String())
+		}
+		typ = e.Fun
+	case *ast.TypeAssertExpr:
+		typ = e.Type
+	case *ast.FuncLit:
+		typ = e.Type
+	case *ast.CompositeLit:
+		typ = e.Type
+	case *ast.BasicLit:
+		switch e.Kind {
+		case token.INT:
+			return "int", nil
+		case token.FLOAT:
+			return "float64", nil
+		case token.CHAR:
+			return "rune", nil
+		case token.STRING:
+			return "string", nil
+		}
+	case *ast.UnaryExpr:
+		lit, ok := e.X.(*ast.CompositeLit)
+		if !ok || e.Op != token.AND {
+			return "", Err(loc, "cannot infer type")
+		}
+		var s strings.Builder
+		printer.Fprint(&s, fset, lit.Type)
+		return "*" + s.String(), nil
+	default:
+		return "", Err(loc, "cannot infer type")
+	}
+	var s strings.Builder
+	printer.Fprint(&s, fset, typ)
+	return s.String(), nil
+}
+
+type findReturnVisitor struct {
+	retStmt *ast.ReturnStmt
+}
+
+func (v *findReturnVisitor) Visit(n ast.Node) ast.Visitor {
+	if r, ok := n.(*ast.ReturnStmt); ok {
+		v.retStmt = r
+		return nil
+	}
+	return v
+}
+
+// parseGoExpr parses a go expression, returning any syntax errors.
+// The errors contain location information starting from the given Loc.
+func parseGoExpr(loc Loc, code string) error {
+	_, err := parser.ParseExprFrom(token.NewFileSet(), loc.File, code, 0)
+	if err == nil {
+		return nil
+	}
+
+	el, ok := err.(scanner.ErrorList)
+	if !ok {
+		return err
+	}
+	p := el[0].Pos
+	loc.Line += p.Line - 1 // -1 because p.Line is 1-based.
+	if p.Line > 1 {
+		loc.Col = 1
+	}
+	loc.Col += p.Column - 1
+	return Err(loc, el[0].Msg)
+}
+
+// parseGoType parses a go type,
+// returning its canonical string representation or any syntax errors.
+func parseGoType(loc Loc, code string) (string, error) {
+	const pre = "(*"
+	fset := token.NewFileSet()
+	expr, err := parser.ParseExprFrom(fset, loc.File, pre+code+")(nil)", 0)
+	if err == nil {
+		call, ok := expr.(*ast.CallExpr)
+		if !ok {
+			return "", Err(loc, "bad type")
+		}
+		typ := call.Fun.(*ast.ParenExpr).X.(*ast.StarExpr).X
+		var s strings.Builder
+		printer.Fprint(&s, fset, typ)
+		return s.String(), nil
+	}
+
+	el, ok := err.(scanner.ErrorList)
+	if !ok {
+		return "", err
+	}
+	p := el[0].Pos
+	loc.Line += p.Line - 1 // -1 because p.Line is 1-based.
+	if p.Line > 1 {
+		loc.Col = 1
+	} else {
+		loc.Col -= len(pre)
+	}
+	loc.Col += p.Column - 1
+	return "", Err(loc, el[0].Msg)
+}
+
+// parseGoArgs parses a go function call argument list,
+// returning the number of arguments or any syntax errors.
+func parseGoArgs(loc Loc, code string) (int, error) {
+	const pre = "_("
+	expr, err := parser.ParseExprFrom(token.NewFileSet(), loc.File, pre+code+")", 0)
+	if err == nil {
+		call, ok := expr.(*ast.CallExpr)
+		if !ok {
+			return 0, Err(loc, "bad argument list")
+		}
+		return len(call.Args), nil
+	}
+
+	el, ok := err.(scanner.ErrorList)
+	if !ok {
+		return 0, err
+	}
+	p := el[0].Pos
+	loc.Line += p.Line - 1 // -1 because p.Line is 1-based.
+	if p.Line > 1 {
+		loc.Col = 1
+	} else {
+		loc.Col -= len(pre)
+	}
+	loc.Col += p.Column - 1
+	return 0, Err(loc, el[0].Msg)
+}
+
+// parseGoStrings parses a comma-separated list of Go string literals,
+// returning their values or any errors.
+func parseGoStrings(loc Loc, code string) ([]string, error) {
+	if _, err := parseGoArgs(loc, code); err != nil {
+		return nil, err
+	}
+	call, _ := parser.ParseExpr("_(" + code + ")")
+	var strs []string
+	for _, arg := range call.(*ast.CallExpr).Args {
+		lit, ok := arg.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return nil, Err(loc, "want string literals")
+		}
+		s, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return nil, Err(loc, "want string literals")
+		}
+		strs = append(strs, s)
+	}
+	return strs, nil
+}
+
+// parseDelegateArgs parses the argument list of a %delegate expression:
+// a Go expression of the delegate parser function,
+// followed by Go string literals of the open and close markers.
+// It returns the function expression and the markers or any errors.
+func parseDelegateArgs(loc Loc, code string) (fun, open, close string, err error) {
+	const pre = "_("
+	fset := token.NewFileSet()
+	expr, err := parser.ParseExprFrom(fset, loc.File, pre+code+")", 0)
+	if err == nil {
+		call, ok := expr.(*ast.CallExpr)
+		if !ok || len(call.Args) != 3 {
+			return "", "", "", Err(loc, "%%delegate wants a function, open marker, and close marker")
+		}
+		var s strings.Builder
+		printer.Fprint(&s, fset, call.Args[0])
+		var markers [2]string
+		for i, arg := range call.Args[1:] {
+			lit, ok := arg.(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return "", "", "", Err(loc, "%%delegate markers must be string literals")
+			}
+			if markers[i], err = strconv.Unquote(lit.Value); err != nil {
+				return "", "", "", Err(loc, "%s", err)
+			}
+			if markers[i] == "" {
+				return "", "", "", Err(loc, "%%delegate markers must not be empty")
+			}
+		}
+		return s.String(), markers[0], markers[1], nil
+	}
+
+	el, ok := err.(scanner.ErrorList)
+	if !ok {
+		return "", "", "", err
+	}
+	p := el[0].Pos
+	loc.Line += p.Line - 1 // -1 because p.Line is 1-based.
+	if p.Line > 1 {
+		loc.Col = 1
+	} else {
+		loc.Col -= len(pre)
+	}
+	loc.Col += p.Column - 1
+	return "", "", "", Err(loc, el[0].Msg)
+}
+
+// parseBlockArgs parses the argument list of a %balanced or %until expression,
+// named by kind: Go string or rune literals of the open delimiter,
+// for %balanced, the close delimiter, and an optional escape.
+// It returns the delimiters and escape, which is empty if there is none,
+// or any errors.
+func parseBlockArgs(loc Loc, kind, code string) (open, close, escape string, err error) {
+	if _, err := parseGoArgs(loc, code); err != nil {
+		return "", "", "", err
+	}
+	want := 2
+	if kind == "balanced" {
+		want = 3
+	}
+	call, _ := parser.ParseExpr("_(" + code + ")")
+	args := call.(*ast.CallExpr).Args
+	if len(args) != want && len(args) != want-1 {
+		if kind == "balanced" {
+			return "", "", "", Err(loc, "%%balanced wants open and close delimiters and an optional escape")
+		}
+		return "", "", "", Err(loc, "%%until wants a close delimiter and an optional escape")
+	}
+	// strs is the open delimiter, close delimiter, and escape;
+	// %until has no open delimiter.
+	strs := make([]string, 3-want, 3)
+	for _, arg := range args {
+		lit, ok := arg.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING && lit.Kind != token.CHAR {
+			return "", "", "", Err(loc, "%%%s delimiters must be string literals", kind)
+		}
+		s, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return "", "", "", Err(loc, "%s", err)
+		}
+		if s == "" {
+			return "", "", "", Err(loc, "%%%s delimiters must not be empty", kind)
+		}
+		strs = append(strs, s)
+	}
+	for len(strs) < 3 {
+		strs = append(strs, "")
+	}
+	return strs[0], strs[1], strs[2], nil
+}
+
+// parseGoParams parses a go function parameter list,
+// returning the parameter names or any syntax errors.
+func parseGoParams(loc Loc, code string) ([]string, error) {
+	const pre = "func("
+	expr, err := parser.ParseExprFrom(token.NewFileSet(), loc.File, pre+code+"){}", 0)
+	if err == nil {
+		lit, ok := expr.(*ast.FuncLit)
+		if !ok {
+			return nil, Err(loc, "bad parameter list")
+		}
+		var names []string
+		for _, field := range lit.Type.Params.List {
+			if len(field.Names) == 0 {
+				return nil, Err(loc, "parameters must be named")
+			}
+			for _, n := range field.Names {
+				names = append(names, n.Name)
+			}
+		}
+		return names, nil
+	}
+
+	el, ok := err.(scanner.ErrorList)
+	if !ok {
+		return nil, err
+	}
+	p := el[0].Pos
+	loc.Line += p.Line - 1 // -1 because p.Line is 1-based.
+	if p.Line > 1 {
+		loc.Col = 1
+	} else {
+		loc.Col -= len(pre)
+	}
+	loc.Col += p.Column - 1
+	return nil, Err(loc, el[0].Msg)
+}
+
+// goIdents returns the set of identifiers in go code.
+func goIdents(code string) map[string]bool {
+	idents := make(map[string]bool)
+	var s scanner.Scanner
+	fset := token.NewFileSet()
+	s.Init(fset.AddFile("", fset.Base(), len(code)), []byte(code), nil, 0)
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return idents
+		}
+		if tok == token.IDENT {
+			idents[lit] = true
+		}
+	}
+}
+
+// goFreeIdents returns the identifiers referred to by go code
+// that it does not itself declare, in order of their first reference.
+// If body is true, the code is function body statements;
+// otherwise it is an expression.
+// Selected fields, struct literal keys, and statement labels
+// are not references.
+// If the code does not parse, goFreeIdents returns nil.
+func goFreeIdents(code string, body bool) []string {
+	if body {
+		code = "func(){" + code + "\n}"
+	}
+	expr, err := parser.ParseExpr(code)
+	if err != nil {
+		return nil
+	}
+	declared := make(map[string]bool)
+	declare := func(ids ...ast.Expr) {
+		for _, id := range ids {
+			if id, ok := id.(*ast.Ident); ok {
+				declared[id.Name] = true
+			}
+		}
+	}
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE {
+				declare(n.Lhs...)
+			}
+		case *ast.RangeStmt:
+			if n.Tok == token.DEFINE {
+				declare(n.Key, n.Value)
+			}
+		case *ast.ValueSpec:
+			for _, id := range n.Names {
+				declare(id)
+			}
+		case *ast.TypeSpec:
+			declare(n.Name)
+		case *ast.Field:
+			for _, id := range n.Names {
+				declare(id)
+			}
+		}
+		return true
+	})
+	var free []string
+	seen := make(map[string]bool)
+	var visit func(ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			ast.Inspect(n.X, visit)
+			return false
+		case *ast.KeyValueExpr:
+			if _, ok := n.Key.(*ast.Ident); !ok {
+				ast.Inspect(n.Key, visit)
+			}
+			ast.Inspect(n.Value, visit)
+			return false
+		case *ast.LabeledStmt:
+			ast.Inspect(n.Stmt, visit)
+			return false
+		case *ast.BranchStmt:
+			return false
+		case *ast.Ident:
+			if n.Name != "_" && !declared[n.Name] && !seen[n.Name] {
+				seen[n.Name] = true
+				free = append(free, n.Name)
+			}
+		}
+		return true
+	}
+	ast.Inspect(expr, visit)
+	return free
+}
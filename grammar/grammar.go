@@ -0,0 +1,1154 @@
+// Code generated by goyacc -o grammar.go -p peggy grammar.y. DO NOT EDIT.
+
+//line grammar.y:8
+package grammar
+
+import __yyfmt__ "fmt"
+
+//line grammar.y:8
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+//line grammar.y:17
+type peggySymType struct {
+	yys        int
+	text       text
+	cclass     *CharClass
+	loc        Loc
+	expr       Expr
+	action     *Action
+	rule       Rule
+	rules      []Rule
+	texts      []Text
+	name       Name
+	annot      Annotation
+	annots     []Annotation
+	directive  Directive
+	directives []Directive
+	grammar    Grammar
+}
+
+const _ERROR = 57346
+const _IDENT = 57347
+const _STRING = 57348
+const _CODE = 57349
+const _ARROW = 57350
+const _ANNOT = 57351
+const _ARGS = 57352
+const _DIRECTIVE = 57353
+const _NUMBER = 57354
+const _TYPE = 57355
+const _RULECODE = 57356
+const _DELEGATE = 57357
+const _NATIVE = 57358
+const _BLOCK = 57359
+const _WANT = 57360
+const _CHARCLASS = 57361
+
+var peggyToknames = [...]string{
+	"$end",
+	"error",
+	"$unk",
+	"_ERROR",
+	"_IDENT",
+	"_STRING",
+	"_CODE",
+	"_ARROW",
+	"_ANNOT",
+	"_ARGS",
+	"_DIRECTIVE",
+	"_NUMBER",
+	"_TYPE",
+	"_RULECODE",
+	"_DELEGATE",
+	"_NATIVE",
+	"_BLOCK",
+	"_WANT",
+	"_CHARCLASS",
+	"'.'",
+	"'*'",
+	"'+'",
+	"'?'",
+	"':'",
+	"'/'",
+	"'!'",
+	"'&'",
+	"'('",
+	"')'",
+	"'^'",
+	"'<'",
+	"'>'",
+	"','",
+	"'$'",
+	"'='",
+	"'-'",
+	"'|'",
+	"'\\n'",
+}
+
+var peggyStatenames = [...]string{}
+
+const peggyEofCode = 1
+const peggyErrCode = 2
+const peggyInitialStackSize = 16
+
+//line grammar.y:379
+
+// Parse parses a Peggy input file, and returns the Grammar,
+// using a default Config, with no build tags.
+func Parse(in io.RuneScanner, fileName string) (*Grammar, error) {
+	return Config{}.Parse(in, fileName)
+}
+
+// Parse parses a Peggy input file, and returns the Grammar,
+// enabling the %if regions of the Config's Tags.
+func (c Config) Parse(in io.RuneScanner, fileName string) (*Grammar, error) {
+	tags := make(map[string]bool)
+	for _, t := range c.Tags {
+		if t = strings.TrimSpace(t); t != "" {
+			tags[t] = true
+		}
+	}
+	src := readRunes(in)
+	x := &lexer{
+		in:     src.reader(),
+		file:   fileName,
+		line:   1,
+		consts: make(map[string]string),
+		tags:   tags,
+		params: paramRules(src, fileName, tags),
+	}
+	peggyParse(x)
+	if x.err != nil {
+		return nil, x.err
+	}
+	return &x.result, nil
+}
+
+//line yacctab:1
+var peggyExca = [...]int8{
+	-1, 1,
+	1, -1,
+	-2, 0,
+	-1, 116,
+	29, 75,
+	-2, 0,
+}
+
+const peggyPrivate = 57344
+
+const peggyLast = 183
+
+var peggyAct = [...]int8{
+	2, 60, 63, 59, 57, 4, 111, 44, 35, 38,
+	82, 89, 19, 54, 55, 30, 106, 56, 30, 62,
+	76, 127, 83, 100, 88, 86, 93, 94, 95, 73,
+	74, 75, 47, 77, 70, 17, 76, 112, 49, 126,
+	65, 64, 69, 117, 71, 73, 74, 75, 66, 77,
+	70, 78, 53, 79, 85, 28, 65, 64, 69, 24,
+	71, 87, 82, 81, 66, 90, 91, 92, 11, 17,
+	96, 52, 41, 80, 83, 4, 40, 99, 15, 13,
+	72, 103, 33, 104, 105, 42, 107, 31, 16, 108,
+	109, 7, 98, 110, 113, 115, 101, 102, 114, 16,
+	32, 16, 18, 97, 51, 50, 8, 36, 16, 119,
+	120, 122, 124, 17, 116, 62, 76, 125, 123, 14,
+	48, 118, 15, 29, 87, 73, 74, 75, 121, 77,
+	70, 17, 76, 45, 46, 1, 65, 64, 69, 22,
+	71, 73, 74, 75, 66, 77, 70, 12, 39, 43,
+	3, 6, 65, 64, 69, 9, 71, 10, 84, 68,
+	66, 67, 20, 21, 37, 41, 27, 34, 25, 40,
+	23, 25, 61, 58, 26, 5, 0, 26, 0, 0,
+	0, 0, 20,
+}
+
+var peggyPact = [...]int16{
+	-33, -32768, 99, -32768, -33, -32768, -33, 108, -32768, -32768,
+	-32768, -33, -33, -32768, 165, -33, 117, -16, 108, -32768,
+	64, -32768, 162, -27, -32768, -32768, -32768, 64, 156, -32768,
+	128, -33, -32768, -32768, -32768, 114, -32768, -33, 97, -32768,
+	-32768, 94, 63, -19, -32768, -32768, -32768, -32768, -32768, 110,
+	-33, -32768, -33, 65, -32768, 128, -15, -32768, 18, 110,
+	-32768, 6, -13, -32768, -33, -33, -33, 5, -32768, -33,
+	-32768, -32768, 93, 82, 67, 13, -32768, -32768, 110, 110,
+	-33, -32768, -33, -33, -10, -33, -32768, -32768, -33, -33,
+	30, 30, 126, -32768, -32768, -32768, 14, -32768, -32768, -32768,
+	-32768, -15, -15, 110, 110, 110, 123, 110, 112, 126,
+	-32768, -32768, -32768, -32768, -32768, -32768, 37, -32768, -15, -32768,
+	-32768, -32768, 110, -32768, -32768, -8, -32768, -32768,
+}
+
+var peggyPgo = [...]uint8{
+	0, 175, 17, 4, 173, 3, 1, 172, 2, 161,
+	159, 158, 6, 151, 9, 7, 149, 79, 68, 80,
+	148, 55, 147, 91, 139, 59, 135, 0, 150,
+}
+
+var peggyR1 = [...]int8{
+	0, 26, 1, 1, 23, 23, 22, 22, 22, 24,
+	24, 25, 25, 25, 13, 18, 18, 18, 17, 17,
+	17, 17, 17, 14, 21, 21, 20, 20, 19, 19,
+	16, 16, 15, 15, 2, 2, 2, 3, 3, 4,
+	4, 5, 5, 6, 6, 7, 7, 8, 8, 8,
+	8, 9, 9, 9, 9, 10, 10, 10, 10, 10,
+	10, 10, 10, 10, 10, 10, 10, 10, 10, 12,
+	11, 11, 28, 28, 27, 27,
+}
+
+var peggyR2 = [...]int8{
+	0, 2, 5, 3, 3, 0, 2, 1, 4, 2,
+	1, 1, 1, 1, 1, 3, 1, 0, 3, 5,
+	6, 6, 7, 1, 2, 0, 1, 2, 4, 1,
+	1, 3, 1, 1, 4, 4, 1, 2, 1, 4,
+	1, 2, 1, 4, 1, 4, 1, 3, 3, 3,
+	1, 2, 2, 2, 1, 5, 3, 3, 3, 1,
+	1, 1, 2, 2, 2, 2, 1, 1, 4, 1,
+	1, 3, 2, 1, 1, 0,
+}
+
+var peggyChk = [...]int16{
+	-32768, -26, -27, -28, 38, -1, -13, -23, 7, -28,
+	-28, -18, -22, -17, 11, 14, -19, 5, -23, -27,
+	-28, -28, -24, 5, -25, 6, 12, -28, -21, 6,
+	31, -18, -17, -25, 5, 35, -17, 8, -14, -20,
+	13, 9, -21, -16, -15, 5, 6, -27, 6, -27,
+	8, 10, 8, -14, 32, 33, -2, -3, -4, -5,
+	-6, -7, 5, -8, 27, 26, 34, -9, -10, 28,
+	20, 30, -19, 15, 16, 17, 6, 19, -27, -27,
+	8, -15, 25, 37, -11, 36, 7, -6, 18, 24,
+	-27, -27, -27, 21, 22, 23, -27, 10, 10, 10,
+	10, -2, -2, -27, -27, -27, 26, -27, -27, -27,
+	-8, -12, 7, -8, -12, -8, -2, 29, -2, -3,
+	-3, 5, -5, 6, -8, -27, 2, 29,
+}
+
+var peggyDef = [...]int8{
+	75, -2, 5, 74, 73, 1, 0, 17, 14, 72,
+	5, 75, 0, 16, 7, 0, 25, 29, 17, 3,
+	74, 4, 6, 11, 10, 12, 13, 0, 0, 25,
+	0, 75, 15, 9, 11, 0, 18, 75, 0, 24,
+	23, 26, 0, 0, 30, 32, 33, 2, 8, 0,
+	75, 27, 75, 0, 28, 0, 19, 36, 38, 40,
+	42, 44, 29, 46, 75, 75, 75, 50, 54, 75,
+	59, 60, 61, 0, 0, 0, 66, 67, 0, 0,
+	75, 31, 75, 75, 37, 75, 70, 41, 75, 75,
+	0, 0, 0, 51, 52, 53, 0, 62, 63, 64,
+	65, 20, 21, 0, 0, 0, 0, 0, 0, 0,
+	47, 57, 69, 48, 58, 49, -2, 56, 22, 34,
+	35, 71, 39, 43, 45, 0, 68, 55,
+}
+
+var peggyTok1 = [...]int8{
+	1, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	38, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 26, 3, 3, 34, 3, 27, 3,
+	28, 29, 21, 22, 33, 36, 20, 25, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 24, 3,
+	31, 35, 32, 23, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 30, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 37,
+}
+
+var peggyTok2 = [...]int8{
+	2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
+	12, 13, 14, 15, 16, 17, 18, 19,
+}
+
+var peggyTok3 = [...]int8{
+	0,
+}
+
+var peggyErrorMessages = [...]struct {
+	state int
+	token int
+	msg   string
+}{}
+
+//line yaccpar:1
+
+/*	parser for yacc output	*/
+
+var (
+	peggyDebug        = 0
+	peggyErrorVerbose = false
+)
+
+type peggyLexer interface {
+	Lex(lval *peggySymType) int
+	Error(s string)
+}
+
+type peggyParser interface {
+	Parse(peggyLexer) int
+	Lookahead() int
+}
+
+type peggyParserImpl struct {
+	lval  peggySymType
+	stack [peggyInitialStackSize]peggySymType
+	char  int
+}
+
+func (p *peggyParserImpl) Lookahead() int {
+	return p.char
+}
+
+func peggyNewParser() peggyParser {
+	return &peggyParserImpl{}
+}
+
+const peggyFlag = -32768
+
+func peggyTokname(c int) string {
+	if c >= 1 && c-1 < len(peggyToknames) {
+		if peggyToknames[c-1] != "" {
+			return peggyToknames[c-1]
+		}
+	}
+	return __yyfmt__.Sprintf("tok-%v", c)
+}
+
+func peggyStatname(s int) string {
+	if s >= 0 && s < len(peggyStatenames) {
+		if peggyStatenames[s] != "" {
+			return peggyStatenames[s]
+		}
+	}
+	return __yyfmt__.Sprintf("state-%v", s)
+}
+
+func peggyErrorMessage(state, lookAhead int) string {
+	const TOKSTART = 4
+
+	if !peggyErrorVerbose {
+		return "syntax error"
+	}
+
+	for _, e := range peggyErrorMessages {
+		if e.state == state && e.token == lookAhead {
+			return "syntax error: " + e.msg
+		}
+	}
+
+	res := "syntax error: unexpected " + peggyTokname(lookAhead)
+
+	// To match Bison, suggest at most four expected tokens.
+	expected := make([]int, 0, 4)
+
+	// Look for shiftable tokens.
+	base := int(peggyPact[state])
+	for tok := TOKSTART; tok-1 < len(peggyToknames); tok++ {
+		if n := base + tok; n >= 0 && n < peggyLast && int(peggyChk[int(peggyAct[n])]) == tok {
+			if len(expected) == cap(expected) {
+				return res
+			}
+			expected = append(expected, tok)
+		}
+	}
+
+	if peggyDef[state] == -2 {
+		i := 0
+		for peggyExca[i] != -1 || int(peggyExca[i+1]) != state {
+			i += 2
+		}
+
+		// Look for tokens that we accept or reduce.
+		for i += 2; peggyExca[i] >= 0; i += 2 {
+			tok := int(peggyExca[i])
+			if tok < TOKSTART || peggyExca[i+1] == 0 {
+				continue
+			}
+			if len(expected) == cap(expected) {
+				return res
+			}
+			expected = append(expected, tok)
+		}
+
+		// If the default action is to accept or reduce, give up.
+		if peggyExca[i+1] != 0 {
+			return res
+		}
+	}
+
+	for i, tok := range expected {
+		if i == 0 {
+			res += ", expecting "
+		} else {
+			res += " or "
+		}
+		res += peggyTokname(tok)
+	}
+	return res
+}
+
+func peggylex1(lex peggyLexer, lval *peggySymType) (char, token int) {
+	token = 0
+	char = lex.Lex(lval)
+	if char <= 0 {
+		token = int(peggyTok1[0])
+		goto out
+	}
+	if char < len(peggyTok1) {
+		token = int(peggyTok1[char])
+		goto out
+	}
+	if char >= peggyPrivate {
+		if char < peggyPrivate+len(peggyTok2) {
+			token = int(peggyTok2[char-peggyPrivate])
+			goto out
+		}
+	}
+	for i := 0; i < len(peggyTok3); i += 2 {
+		token = int(peggyTok3[i+0])
+		if token == char {
+			token = int(peggyTok3[i+1])
+			goto out
+		}
+	}
+
+out:
+	if token == 0 {
+		token = int(peggyTok2[1]) /* unknown char */
+	}
+	if peggyDebug >= 3 {
+		__yyfmt__.Printf("lex %s(%d)\n", peggyTokname(token), uint(char))
+	}
+	return char, token
+}
+
+func peggyParse(peggylex peggyLexer) int {
+	return peggyNewParser().Parse(peggylex)
+}
+
+func (peggyrcvr *peggyParserImpl) Parse(peggylex peggyLexer) int {
+	var peggyn int
+	var peggyVAL peggySymType
+	var peggyDollar []peggySymType
+	_ = peggyDollar // silence set and not used
+	peggyS := peggyrcvr.stack[:]
+
+	Nerrs := 0   /* number of errors */
+	Errflag := 0 /* error recovery flag */
+	peggystate := 0
+	peggyrcvr.char = -1
+	peggytoken := -1 // peggyrcvr.char translated into internal numbering
+	defer func() {
+		// Make sure we report no lookahead when not parsing.
+		peggystate = -1
+		peggyrcvr.char = -1
+		peggytoken = -1
+	}()
+	peggyp := -1
+	goto peggystack
+
+ret0:
+	return 0
+
+ret1:
+	return 1
+
+peggystack:
+	/* put a state and value onto the stack */
+	if peggyDebug >= 4 {
+		__yyfmt__.Printf("char %v in %v\n", peggyTokname(peggytoken), peggyStatname(peggystate))
+	}
+
+	peggyp++
+	if peggyp >= len(peggyS) {
+		nyys := make([]peggySymType, len(peggyS)*2)
+		copy(nyys, peggyS)
+		peggyS = nyys
+	}
+	peggyS[peggyp] = peggyVAL
+	peggyS[peggyp].yys = peggystate
+
+peggynewstate:
+	peggyn = int(peggyPact[peggystate])
+	if peggyn <= peggyFlag {
+		goto peggydefault /* simple state */
+	}
+	if peggyrcvr.char < 0 {
+		peggyrcvr.char, peggytoken = peggylex1(peggylex, &peggyrcvr.lval)
+	}
+	peggyn += peggytoken
+	if peggyn < 0 || peggyn >= peggyLast {
+		goto peggydefault
+	}
+	peggyn = int(peggyAct[peggyn])
+	if int(peggyChk[peggyn]) == peggytoken { /* valid shift */
+		peggyrcvr.char = -1
+		peggytoken = -1
+		peggyVAL = peggyrcvr.lval
+		peggystate = peggyn
+		if Errflag > 0 {
+			Errflag--
+		}
+		goto peggystack
+	}
+
+peggydefault:
+	/* default state action */
+	peggyn = int(peggyDef[peggystate])
+	if peggyn == -2 {
+		if peggyrcvr.char < 0 {
+			peggyrcvr.char, peggytoken = peggylex1(peggylex, &peggyrcvr.lval)
+		}
+
+		/* look through exception table */
+		xi := 0
+		for {
+			if peggyExca[xi+0] == -1 && int(peggyExca[xi+1]) == peggystate {
+				break
+			}
+			xi += 2
+		}
+		for xi += 2; ; xi += 2 {
+			peggyn = int(peggyExca[xi+0])
+			if peggyn < 0 || peggyn == peggytoken {
+				break
+			}
+		}
+		peggyn = int(peggyExca[xi+1])
+		if peggyn < 0 {
+			goto ret0
+		}
+	}
+	if peggyn == 0 {
+		/* error ... attempt to resume parsing */
+		switch Errflag {
+		case 0: /* brand new error */
+			peggylex.Error(peggyErrorMessage(peggystate, peggytoken))
+			Nerrs++
+			if peggyDebug >= 1 {
+				__yyfmt__.Printf("%s", peggyStatname(peggystate))
+				__yyfmt__.Printf(" saw %s\n", peggyTokname(peggytoken))
+			}
+			fallthrough
+
+		case 1, 2: /* incompletely recovered error ... try again */
+			Errflag = 3
+
+			/* find a state where "error" is a legal shift action */
+			for peggyp >= 0 {
+				peggyn = int(peggyPact[peggyS[peggyp].yys]) + peggyErrCode
+				if peggyn >= 0 && peggyn < peggyLast {
+					peggystate = int(peggyAct[peggyn]) /* simulate a shift of "error" */
+					if int(peggyChk[peggystate]) == peggyErrCode {
+						goto peggystack
+					}
+				}
+
+				/* the current p has no shift on "error", pop stack */
+				if peggyDebug >= 2 {
+					__yyfmt__.Printf("error recovery pops state %d\n", peggyS[peggyp].yys)
+				}
+				peggyp--
+			}
+			/* there is no state on the stack with an error shift ... abort */
+			goto ret1
+
+		case 3: /* no shift yet; clobber input char */
+			if peggyDebug >= 2 {
+				__yyfmt__.Printf("error recovery discards %s\n", peggyTokname(peggytoken))
+			}
+			if peggytoken == peggyEofCode {
+				goto ret1
+			}
+			peggyrcvr.char = -1
+			peggytoken = -1
+			goto peggynewstate /* try again in the same state */
+		}
+	}
+
+	/* reduction by production peggyn */
+	if peggyDebug >= 2 {
+		__yyfmt__.Printf("reduce %v in:\n\t%v\n", peggyn, peggyStatname(peggystate))
+	}
+
+	peggynt := peggyn
+	peggypt := peggyp
+	_ = peggypt // guard against "declared and not used"
+
+	peggyp -= int(peggyR2[peggyn])
+	// peggyp is now the index of $0. Perform the default action. Iff the
+	// reduced production is ε, $1 is possibly out of range.
+	if peggyp+1 >= len(peggyS) {
+		nyys := make([]peggySymType, len(peggyS)*2)
+		copy(nyys, peggyS)
+		peggyS = nyys
+	}
+	peggyVAL = peggyS[peggyp+1]
+
+	/* consult goto table to find next state */
+	peggyn = int(peggyR1[peggyn])
+	peggyg := int(peggyPgo[peggyn])
+	peggyj := peggyg + peggyS[peggyp].yys + 1
+
+	if peggyj >= peggyLast {
+		peggystate = int(peggyAct[peggyg])
+	} else {
+		peggystate = int(peggyAct[peggyj])
+		if int(peggyChk[peggystate]) != -peggyn {
+			peggystate = int(peggyAct[peggyg])
+		}
+	}
+	// dummy call; replaced with literal code
+	switch peggynt {
+
+	case 1:
+		peggyDollar = peggyS[peggypt-2 : peggypt+1]
+//line grammar.y:57
+		{
+			peggylex.(*lexer).result = peggyDollar[2].grammar
+		}
+	case 2:
+		peggyDollar = peggyS[peggypt-5 : peggypt+1]
+//line grammar.y:61
+		{
+			peggyVAL.grammar = Grammar{Prelude: peggyDollar[1].text, Rules: peggyDollar[4].rules}
+			if err := peggyVAL.grammar.direct(peggyDollar[3].directives); err != nil {
+				peggylex.(*lexer).err = err
+			}
+		}
+	case 3:
+		peggyDollar = peggyS[peggypt-3 : peggypt+1]
+//line grammar.y:68
+		{
+			peggyVAL.grammar = Grammar{Rules: peggyDollar[2].rules}
+			if err := peggyVAL.grammar.direct(peggyDollar[1].directives); err != nil {
+				peggylex.(*lexer).err = err
+			}
+		}
+	case 4:
+		peggyDollar = peggyS[peggypt-3 : peggypt+1]
+//line grammar.y:76
+		{
+			peggyVAL.directives = append(peggyDollar[1].directives, peggyDollar[2].directive)
+		}
+	case 5:
+		peggyDollar = peggyS[peggypt-0 : peggypt+1]
+//line grammar.y:77
+		{
+			peggyVAL.directives = nil
+		}
+	case 6:
+		peggyDollar = peggyS[peggypt-2 : peggypt+1]
+//line grammar.y:80
+		{
+			peggyVAL.directive = Directive{Name: peggyDollar[1].text, Args: peggyDollar[2].texts}
+		}
+	case 7:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:81
+		{
+			peggyVAL.directive = Directive{Name: peggyDollar[1].text}
+		}
+	case 8:
+		peggyDollar = peggyS[peggypt-4 : peggypt+1]
+//line grammar.y:83
+		{
+			peggyVAL.directive = Directive{Name: peggyDollar[1].text, Args: []Text{peggyDollar[2].text}, Value: peggyDollar[4].text}
+			peggylex.(*lexer).plain(peggyDollar[4].text)
+			if peggyDollar[1].text.String() == "const" {
+				// Define the constant now, since it may be used
+				// in literals and character classes lexed after it.
+				peggylex.(*lexer).consts[peggyDollar[2].text.String()] = peggyDollar[4].text.String()
+			}
+		}
+	case 9:
+		peggyDollar = peggyS[peggypt-2 : peggypt+1]
+//line grammar.y:97
+		{
+			peggyVAL.texts = append(peggyDollar[1].texts, peggyDollar[2].text)
+		}
+	case 10:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:98
+		{
+			peggyVAL.texts = []Text{peggyDollar[1].text}
+		}
+	case 11:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:101
+		{
+			peggyVAL.text = peggyDollar[1].text
+		}
+	case 12:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:102
+		{
+			peggylex.(*lexer).plain(peggyDollar[1].text)
+			peggyVAL.text = peggyDollar[1].text
+		}
+	case 13:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:103
+		{
+			peggyVAL.text = peggyDollar[1].text
+		}
+	case 14:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:107
+		{
+			loc := peggyDollar[1].text.Begin()
+			loc.Col++ // skip the open {.
+			err := ParseGoFile(loc, peggyDollar[1].text.String())
+			if err != nil {
+				peggylex.(*lexer).err = err
+			}
+			peggyVAL.text = peggyDollar[1].text
+		}
+	case 15:
+		peggyDollar = peggyS[peggypt-3 : peggypt+1]
+//line grammar.y:118
+		{
+			peggyVAL.rules = append(peggyDollar[1].rules, peggyDollar[3].rule)
+		}
+	case 16:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:119
+		{
+			peggyVAL.rules = []Rule{peggyDollar[1].rule}
+		}
+	case 17:
+		peggyDollar = peggyS[peggypt-0 : peggypt+1]
+//line grammar.y:123
+		{
+			peggyVAL.rules = nil
+		}
+	case 18:
+		peggyDollar = peggyS[peggypt-3 : peggypt+1]
+//line grammar.y:127
+		{
+			loc := peggyDollar[1].text.Begin()
+			loc.Col++ // skip the open {.
+			if err := parseGoDecls(loc, peggyDollar[1].text.String()); err != nil {
+				peggylex.(*lexer).err = err
+			}
+			peggyVAL.rule = peggyDollar[3].rule
+			peggyVAL.rule.Code = append([]Text{peggyDollar[1].text}, peggyDollar[3].rule.Code...)
+		}
+	case 19:
+		peggyDollar = peggyS[peggypt-5 : peggypt+1]
+//line grammar.y:136
+		{
+			peggyVAL.rule = Rule{Name: peggyDollar[1].name, Expr: peggyDollar[5].expr}
+			if err := peggyVAL.rule.annotate(peggyDollar[2].annots); err != nil {
+				peggylex.(*lexer).err = err
+			} else if x := peggylex.(*lexer); x.err == nil {
+				// The Expr may be incomplete after an error.
+				x.err = peggyVAL.rule.typeActions()
+			}
+		}
+	case 20:
+		peggyDollar = peggyS[peggypt-6 : peggypt+1]
+//line grammar.y:145
+		{
+			peggyVAL.rule = Rule{Name: peggyDollar[1].name, ResultType: peggyDollar[3].text, Expr: peggyDollar[6].expr}
+			if err := peggyVAL.rule.annotate(peggyDollar[2].annots); err != nil {
+				peggylex.(*lexer).err = err
+			} else if x := peggylex.(*lexer); x.err == nil {
+				// The Expr may be incomplete after an error.
+				x.err = peggyVAL.rule.typeActions()
+			}
+		}
+	case 21:
+		peggyDollar = peggyS[peggypt-6 : peggypt+1]
+//line grammar.y:154
+		{
+			peggyVAL.rule = Rule{Name: peggyDollar[1].name, ErrorName: peggyDollar[2].text, Expr: peggyDollar[6].expr}
+			peggylex.(*lexer).plain(peggyDollar[2].text)
+			if err := peggyVAL.rule.annotate(peggyDollar[3].annots); err != nil {
+				peggylex.(*lexer).err = err
+			} else if x := peggylex.(*lexer); x.err == nil {
+				// The Expr may be incomplete after an error.
+				x.err = peggyVAL.rule.typeActions()
+			}
+		}
+	case 22:
+		peggyDollar = peggyS[peggypt-7 : peggypt+1]
+//line grammar.y:164
+		{
+			peggyVAL.rule = Rule{Name: peggyDollar[1].name, ErrorName: peggyDollar[2].text, ResultType: peggyDollar[4].text, Expr: peggyDollar[7].expr}
+			peggylex.(*lexer).plain(peggyDollar[2].text)
+			if err := peggyVAL.rule.annotate(peggyDollar[3].annots); err != nil {
+				peggylex.(*lexer).err = err
+			} else if x := peggylex.(*lexer); x.err == nil {
+				// The Expr may be incomplete after an error.
+				x.err = peggyVAL.rule.typeActions()
+			}
+		}
+	case 23:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:177
+		{
+			typ, err := parseGoType(peggyDollar[1].text.Begin(), peggyDollar[1].text.String())
+			if err != nil {
+				peggylex.(*lexer).err = err
+			}
+			peggyVAL.text = text{str: typ, begin: peggyDollar[1].text.Begin(), end: peggyDollar[1].text.End()}
+		}
+	case 24:
+		peggyDollar = peggyS[peggypt-2 : peggypt+1]
+//line grammar.y:186
+		{
+			peggyVAL.annots = append(peggyDollar[1].annots, peggyDollar[2].annot)
+		}
+	case 25:
+		peggyDollar = peggyS[peggypt-0 : peggypt+1]
+//line grammar.y:187
+		{
+			peggyVAL.annots = nil
+		}
+	case 26:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:190
+		{
+			peggyVAL.annot = Annotation{Name: peggyDollar[1].text}
+		}
+	case 27:
+		peggyDollar = peggyS[peggypt-2 : peggypt+1]
+//line grammar.y:191
+		{
+			peggyVAL.annot = Annotation{Name: peggyDollar[1].text, Args: peggyDollar[2].text}
+		}
+	case 28:
+		peggyDollar = peggyS[peggypt-4 : peggypt+1]
+//line grammar.y:194
+		{
+			peggyVAL.name = Name{Name: peggyDollar[1].text, Args: peggyDollar[3].texts}
+		}
+	case 29:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:195
+		{
+			peggyVAL.name = Name{Name: peggyDollar[1].text}
+		}
+	case 30:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:198
+		{
+			peggyVAL.texts = []Text{peggyDollar[1].text}
+		}
+	case 31:
+		peggyDollar = peggyS[peggypt-3 : peggypt+1]
+//line grammar.y:199
+		{
+			peggyVAL.texts = append(peggyDollar[1].texts, peggyDollar[3].text)
+		}
+	case 32:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:204
+		{
+			peggyVAL.text = peggyDollar[1].text
+		}
+	case 33:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:206
+		{
+			peggylex.(*lexer).plain(peggyDollar[1].text)
+			peggyVAL.text = text{str: strconv.Quote(peggyDollar[1].text.String()), begin: peggyDollar[1].text.Begin(), end: peggyDollar[1].text.End()}
+		}
+	case 34:
+		peggyDollar = peggyS[peggypt-4 : peggypt+1]
+//line grammar.y:213
+		{
+			if _, ok := peggyDollar[1].expr.(*LongestChoice); ok {
+				peggylex.(*lexer).err = Err(peggyDollar[2].loc, "mixed / and | choice without parentheses")
+			}
+			e, ok := peggyDollar[1].expr.(*Choice)
+			if !ok {
+				e = &Choice{Exprs: []Expr{peggyDollar[1].expr}}
+			}
+			e.Exprs = append(e.Exprs, peggyDollar[4].expr)
+			peggyVAL.expr = e
+		}
+	case 35:
+		peggyDollar = peggyS[peggypt-4 : peggypt+1]
+//line grammar.y:225
+		{
+			if _, ok := peggyDollar[1].expr.(*Choice); ok {
+				peggylex.(*lexer).err = Err(peggyDollar[2].loc, "mixed / and | choice without parentheses")
+			}
+			e, ok := peggyDollar[1].expr.(*LongestChoice)
+			if !ok {
+				e = &LongestChoice{Exprs: []Expr{peggyDollar[1].expr}}
+			}
+			e.Exprs = append(e.Exprs, peggyDollar[4].expr)
+			peggyVAL.expr = e
+		}
+	case 36:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:236
+		{
+			peggyVAL.expr = peggyDollar[1].expr
+		}
+	case 37:
+		peggyDollar = peggyS[peggypt-2 : peggypt+1]
+//line grammar.y:240
+		{
+			peggyDollar[2].action.Expr = peggyDollar[1].expr
+			peggyVAL.expr = peggyDollar[2].action
+		}
+	case 38:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:244
+		{
+			peggyVAL.expr = peggyDollar[1].expr
+		}
+	case 39:
+		peggyDollar = peggyS[peggypt-4 : peggypt+1]
+//line grammar.y:247
+		{
+			peggyVAL.expr = &DiffExpr{Expr: peggyDollar[1].expr, Sub: peggyDollar[4].expr, Loc: peggyDollar[2].loc}
+		}
+	case 40:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:248
+		{
+			peggyVAL.expr = peggyDollar[1].expr
+		}
+	case 41:
+		peggyDollar = peggyS[peggypt-2 : peggypt+1]
+//line grammar.y:252
+		{
+			e, ok := peggyDollar[1].expr.(*Sequence)
+			if !ok {
+				e = &Sequence{Exprs: []Expr{peggyDollar[1].expr}}
+			}
+			e.Exprs = append(e.Exprs, peggyDollar[2].expr)
+			peggyVAL.expr = e
+		}
+	case 42:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:260
+		{
+			peggyVAL.expr = peggyDollar[1].expr
+		}
+	case 43:
+		peggyDollar = peggyS[peggypt-4 : peggypt+1]
+//line grammar.y:264
+		{
+			peggylex.(*lexer).plain(peggyDollar[4].text)
+			peggyVAL.expr = &WantExpr{Expr: peggyDollar[1].expr, Want: peggyDollar[4].text, Loc: peggyDollar[2].text.Begin()}
+		}
+	case 44:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:268
+		{
+			peggyVAL.expr = peggyDollar[1].expr
+		}
+	case 45:
+		peggyDollar = peggyS[peggypt-4 : peggypt+1]
+//line grammar.y:271
+		{
+			peggyVAL.expr = &LabelExpr{Label: peggyDollar[1].text, Expr: peggyDollar[4].expr}
+		}
+	case 46:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:272
+		{
+			peggyVAL.expr = peggyDollar[1].expr
+		}
+	case 47:
+		peggyDollar = peggyS[peggypt-3 : peggypt+1]
+//line grammar.y:275
+		{
+			peggyVAL.expr = &PredExpr{Expr: peggyDollar[3].expr, Loc: peggyDollar[1].loc}
+		}
+	case 48:
+		peggyDollar = peggyS[peggypt-3 : peggypt+1]
+//line grammar.y:276
+		{
+			peggyVAL.expr = &PredExpr{Neg: true, Expr: peggyDollar[3].expr, Loc: peggyDollar[1].loc}
+		}
+	case 49:
+		peggyDollar = peggyS[peggypt-3 : peggypt+1]
+//line grammar.y:277
+		{
+			peggyVAL.expr = &CaptureExpr{Expr: peggyDollar[3].expr, Loc: peggyDollar[1].loc}
+		}
+	case 50:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:278
+		{
+			peggyVAL.expr = peggyDollar[1].expr
+		}
+	case 51:
+		peggyDollar = peggyS[peggypt-2 : peggypt+1]
+//line grammar.y:281
+		{
+			peggyVAL.expr = &RepExpr{Op: '*', Expr: peggyDollar[1].expr, Loc: peggyDollar[2].loc}
+		}
+	case 52:
+		peggyDollar = peggyS[peggypt-2 : peggypt+1]
+//line grammar.y:282
+		{
+			peggyVAL.expr = &RepExpr{Op: '+', Expr: peggyDollar[1].expr, Loc: peggyDollar[2].loc}
+		}
+	case 53:
+		peggyDollar = peggyS[peggypt-2 : peggypt+1]
+//line grammar.y:283
+		{
+			peggyVAL.expr = &OptExpr{Expr: peggyDollar[1].expr, Loc: peggyDollar[2].loc}
+		}
+	case 54:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:284
+		{
+			peggyVAL.expr = peggyDollar[1].expr
+		}
+	case 55:
+		peggyDollar = peggyS[peggypt-5 : peggypt+1]
+//line grammar.y:287
+		{
+			peggyVAL.expr = &SubExpr{Expr: peggyDollar[3].expr, Open: peggyDollar[1].loc, Close: peggyDollar[5].loc}
+		}
+	case 56:
+		peggyDollar = peggyS[peggypt-3 : peggypt+1]
+//line grammar.y:288
+		{
+			peggyVAL.expr = &Empty{Open: peggyDollar[1].loc, Close: peggyDollar[3].loc}
+		}
+	case 57:
+		peggyDollar = peggyS[peggypt-3 : peggypt+1]
+//line grammar.y:289
+		{
+			peggyVAL.expr = &PredCode{Code: peggyDollar[3].text, Loc: peggyDollar[1].loc}
+		}
+	case 58:
+		peggyDollar = peggyS[peggypt-3 : peggypt+1]
+//line grammar.y:290
+		{
+			peggyVAL.expr = &PredCode{Neg: true, Code: peggyDollar[3].text, Loc: peggyDollar[1].loc}
+		}
+	case 59:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:291
+		{
+			peggyVAL.expr = &Any{Loc: peggyDollar[1].loc}
+		}
+	case 60:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:292
+		{
+			peggyVAL.expr = &Cut{Loc: peggyDollar[1].loc}
+		}
+	case 61:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:293
+		{
+			peggyVAL.expr = &Ident{Name: peggyDollar[1].name}
+		}
+	case 62:
+		peggyDollar = peggyS[peggypt-2 : peggypt+1]
+//line grammar.y:295
+		{
+			loc := peggyDollar[2].text.Begin()
+			loc.Col++ // skip the open (.
+			if _, err := parseGoArgs(loc, peggyDollar[2].text.String()); err != nil {
+				peggylex.(*lexer).err = err
+			}
+			peggyVAL.expr = &Ident{Name: peggyDollar[1].name, CallArgs: peggyDollar[2].text}
+		}
+	case 63:
+		peggyDollar = peggyS[peggypt-2 : peggypt+1]
+//line grammar.y:304
+		{
+			loc := peggyDollar[2].text.Begin()
+			loc.Col++ // skip the open (.
+			fun, open, close, err := parseDelegateArgs(loc, peggyDollar[2].text.String())
+			if err != nil {
+				peggylex.(*lexer).err = err
+			}
+			peggyVAL.expr = &DelegateExpr{Func: fun, Open: open, Close: close, Args: peggyDollar[2].text, Loc: peggyDollar[1].text.Begin()}
+		}
+	case 64:
+		peggyDollar = peggyS[peggypt-2 : peggypt+1]
+//line grammar.y:314
+		{
+			loc := peggyDollar[2].text.Begin()
+			loc.Col++ // skip the open (.
+			if n, err := parseGoArgs(loc, peggyDollar[2].text.String()); err != nil {
+				peggylex.(*lexer).err = err
+			} else if n != 1 {
+				peggylex.(*lexer).err = Err(peggyDollar[2].text, "%%native wants a function")
+			}
+			peggyVAL.expr = &NativeExpr{Func: strings.TrimSpace(peggyDollar[2].text.String()), Args: peggyDollar[2].text, Loc: peggyDollar[1].text.Begin()}
+		}
+	case 65:
+		peggyDollar = peggyS[peggypt-2 : peggypt+1]
+//line grammar.y:325
+		{
+			loc := peggyDollar[2].text.Begin()
+			loc.Col++ // skip the open (.
+			open, close, escape, err := parseBlockArgs(loc, peggyDollar[1].text.String(), peggyDollar[2].text.String())
+			if err != nil {
+				peggylex.(*lexer).err = err
+			}
+			peggyVAL.expr = &BlockExpr{Kind: peggyDollar[1].text.String(), Open: open, Close: close, Escape: escape, Args: peggyDollar[2].text, Loc: peggyDollar[1].text.Begin()}
+		}
+	case 66:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:334
+		{
+			peggyVAL.expr = peggylex.(*lexer).literal(peggyDollar[1].text)
+		}
+	case 67:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:335
+		{
+			peggyVAL.expr = peggyDollar[1].cclass
+		}
+	case 68:
+		peggyDollar = peggyS[peggypt-4 : peggypt+1]
+//line grammar.y:336
+		{
+			peggylex.Error("unexpected end of file")
+		}
+	case 69:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:340
+		{
+			loc := peggyDollar[1].text.Begin()
+			loc.Col++ // skip the open {.
+			err := parseGoExpr(loc, peggyDollar[1].text.String())
+			if err != nil {
+				peggylex.(*lexer).err = err
+			}
+			peggyVAL.text = peggyDollar[1].text
+		}
+	case 70:
+		peggyDollar = peggyS[peggypt-1 : peggypt+1]
+//line grammar.y:352
+		{
+			loc := peggyDollar[1].text.Begin()
+			loc.Col++ // skip the open {.
+			peggyVAL.action = &Action{Code: peggyDollar[1].text}
+			if fset, file, err := parseGoFunc(loc, peggyDollar[1].text.String()); err != nil {
+				peggylex.(*lexer).err = err
+			} else {
+				peggyVAL.action.ReturnType, peggyVAL.action.typeErr = inferType(loc, fset, file)
+			}
+		}
+	case 71:
+		peggyDollar = peggyS[peggypt-3 : peggypt+1]
+//line grammar.y:363
+		{
+			if peggyDollar[3].text.String() != "memo" {
+				peggylex.(*lexer).err = Err(peggyDollar[3].text, "unknown action annotation: !"+peggyDollar[3].text.String())
+			}
+			peggyDollar[1].action.NoMemo = true
+			peggyVAL.action = peggyDollar[1].action
+		}
+	}
+	goto peggystack /* stack new state and value */
+}
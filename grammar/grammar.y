@@ -0,0 +1,410 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+%{
+package grammar
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+%}
+
+%union{
+	text text
+	cclass *CharClass
+	loc Loc
+	expr Expr
+	action *Action
+	rule Rule
+	rules []Rule
+	texts []Text
+	name Name
+	annot Annotation
+	annots []Annotation
+	directive Directive
+	directives []Directive
+	grammar Grammar
+}
+
+%type <grammar> Grammar
+%type <expr> Expr, ActExpr, DiffExpr, SeqExpr, WantExpr, LabelExpr, PredExpr, RepExpr, Operand
+%type <action> GoAction
+%type <text> GoPred Prelude ResultType Arg
+%type <texts> Args
+%type <rule> Rule
+%type <rules> Rules
+%type <name> Name
+%type <annot> Annot
+%type <annots> Annots
+%type <directive> Directive
+%type <directives> Directives
+%type <texts> DirectiveArgs
+%type <text> DirectiveArg
+
+%token _ERROR
+%token <text> _IDENT _STRING _CODE _ARROW _ANNOT _ARGS _DIRECTIVE _NUMBER _TYPE _RULECODE _DELEGATE _NATIVE _BLOCK _WANT
+%token <cclass> _CHARCLASS
+%token <loc> '.', '*', '+', '?', ':', '/', '!', '&', '(', ')', '^', '<', '>', ',', '$', '=', '-', '|'
+
+%%
+
+Top:
+	Nl Grammar { peggylex.(*lexer).result = $2 }
+
+Grammar:
+	Prelude NewLine Directives Rules Nl
+	{
+		$$ = Grammar{ Prelude: $1, Rules: $4 }
+		if err := $$.direct($3); err != nil {
+			peggylex.(*lexer).err = err
+		}
+	}
+|	Directives Rules Nl
+	{
+		$$ = Grammar{ Rules: $2 }
+		if err := $$.direct($1); err != nil {
+			peggylex.(*lexer).err = err
+		}
+	}
+
+Directives:
+	Directives Directive NewLine { $$ = append($1, $2) }
+|	{ $$ = nil }
+
+Directive:
+	_DIRECTIVE DirectiveArgs { $$ = Directive{ Name: $1, Args: $2 } }
+|	_DIRECTIVE { $$ = Directive{ Name: $1 } }
+|	_DIRECTIVE _IDENT '=' _STRING
+	{
+		$$ = Directive{ Name: $1, Args: []Text{ $2 }, Value: $4 }
+		peggylex.(*lexer).plain($4)
+		if $1.String() == "const" {
+			// Define the constant now, since it may be used
+			// in literals and character classes lexed after it.
+			peggylex.(*lexer).consts[$2.String()] = $4.String()
+		}
+	}
+
+// DirectiveArgs is non-empty,
+// so that _IDENT '=' is not a shift/reduce conflict
+// with an empty DirectiveArgs followed by an _IDENT.
+DirectiveArgs:
+	DirectiveArgs DirectiveArg { $$ = append($1, $2) }
+|	DirectiveArg { $$ = []Text{ $1 } }
+
+DirectiveArg:
+	_IDENT { $$ = $1 }
+|	_STRING { peggylex.(*lexer).plain($1); $$ = $1 }
+|	_NUMBER { $$ = $1 }
+
+Prelude:
+	_CODE
+	{
+		loc := $1.Begin()
+		loc.Col++ // skip the open {.
+		err := ParseGoFile(loc, $1.String())
+		if err != nil {
+			peggylex.(*lexer).err = err
+		}
+		$$ = $1
+	}
+
+Rules:
+	Rules NewLine Rule { $$ = append($1, $3) }
+|	Rule { $$ = []Rule{ $1 } }
+// The following production adds a shift/reduce conflict:
+// 	reduce the empty string or shift into a Rule?
+// Yacc always prefers shift in the case of both, which is the desired behavior.
+|	{ $$ = nil }
+
+Rule:
+	_RULECODE NewLine Rule
+	{
+		loc := $1.Begin()
+		loc.Col++ // skip the open {.
+		if err := parseGoDecls(loc, $1.String()); err != nil {
+			peggylex.(*lexer).err = err
+		}
+		$$ = $3
+		$$.Code = append([]Text{ $1 }, $3.Code...)
+	}
+|	Name Annots _ARROW Nl Expr {
+		$$ = Rule{ Name: $1, Expr: $5 }
+		if err := $$.annotate($2); err != nil {
+			peggylex.(*lexer).err = err
+		} else if x := peggylex.(*lexer); x.err == nil {
+			// The Expr may be incomplete after an error.
+			x.err = $$.typeActions()
+		}
+	}
+|	Name Annots ResultType _ARROW Nl Expr {
+		$$ = Rule{ Name: $1, ResultType: $3, Expr: $6 }
+		if err := $$.annotate($2); err != nil {
+			peggylex.(*lexer).err = err
+		} else if x := peggylex.(*lexer); x.err == nil {
+			// The Expr may be incomplete after an error.
+			x.err = $$.typeActions()
+		}
+	}
+|	Name _STRING Annots _ARROW Nl Expr {
+		$$ = Rule{ Name: $1, ErrorName: $2, Expr: $6 }
+		peggylex.(*lexer).plain($2)
+		if err := $$.annotate($3); err != nil {
+			peggylex.(*lexer).err = err
+		} else if x := peggylex.(*lexer); x.err == nil {
+			// The Expr may be incomplete after an error.
+			x.err = $$.typeActions()
+		}
+	}
+|	Name _STRING Annots ResultType _ARROW Nl Expr {
+		$$ = Rule{ Name: $1, ErrorName: $2, ResultType: $4, Expr: $7 }
+		peggylex.(*lexer).plain($2)
+		if err := $$.annotate($3); err != nil {
+			peggylex.(*lexer).err = err
+		} else if x := peggylex.(*lexer); x.err == nil {
+			// The Expr may be incomplete after an error.
+			x.err = $$.typeActions()
+		}
+	}
+
+ResultType:
+	_TYPE
+	{
+		typ, err := parseGoType($1.Begin(), $1.String())
+		if err != nil {
+			peggylex.(*lexer).err = err
+		}
+		$$ = text{ str: typ, begin: $1.Begin(), end: $1.End() }
+	}
+
+Annots:
+	Annots Annot { $$ = append($1, $2) }
+|	{ $$ = nil }
+
+Annot:
+	_ANNOT { $$ = Annotation{ Name: $1 } }
+|	_ANNOT _ARGS { $$ = Annotation{ Name: $1, Args: $2 } }
+
+Name:
+	_IDENT '<' Args '>' { $$ = Name{ Name: $1, Args: $3 } }
+|	_IDENT { $$ = Name{ Name: $1 } }
+
+Args:
+	Arg { $$ = []Text{$1} }
+|	Args ',' Arg { $$ = append($1, $3) }
+
+// Arg is a template parameter or argument.
+// String literals are Go-quoted, distinguishing them from identifiers.
+Arg:
+	_IDENT { $$ = $1 }
+|	_STRING
+	{
+		peggylex.(*lexer).plain($1)
+		$$ = text{ str: strconv.Quote($1.String()), begin: $1.Begin(), end: $1.End() }
+	}
+
+Expr:
+	Expr '/' Nl ActExpr
+	{
+		if _, ok := $1.(*LongestChoice); ok {
+			peggylex.(*lexer).err = Err($2, "mixed / and | choice without parentheses")
+		}
+		e, ok := $1.(*Choice)
+		if !ok {
+			e = &Choice{ Exprs: []Expr{$1} }
+		}
+		e.Exprs = append(e.Exprs, $4)
+		$$ = e
+	}
+|	Expr '|' Nl ActExpr
+	{
+		if _, ok := $1.(*Choice); ok {
+			peggylex.(*lexer).err = Err($2, "mixed / and | choice without parentheses")
+		}
+		e, ok := $1.(*LongestChoice)
+		if !ok {
+			e = &LongestChoice{ Exprs: []Expr{$1} }
+		}
+		e.Exprs = append(e.Exprs, $4)
+		$$ = e
+	}
+|	ActExpr { $$ = $1 }
+
+ActExpr:
+	DiffExpr GoAction
+	{
+		$2.Expr = $1
+		$$ = $2
+	}
+|	DiffExpr { $$ = $1 }
+
+DiffExpr:
+	DiffExpr '-' Nl SeqExpr { $$ = &DiffExpr{ Expr: $1, Sub: $4, Loc: $2 } }
+|	SeqExpr { $$ = $1 }
+
+SeqExpr:
+	SeqExpr WantExpr
+	{
+		e, ok := $1.(*Sequence)
+		if !ok {
+			e = &Sequence{ Exprs: []Expr{$1} }
+		}
+		e.Exprs = append(e.Exprs, $2)
+		$$ = e
+	}
+|	WantExpr { $$ = $1 }
+
+WantExpr:
+	LabelExpr _WANT Nl _STRING
+	{
+		peggylex.(*lexer).plain($4)
+		$$ = &WantExpr{ Expr: $1, Want: $4, Loc: $2.Begin() }
+	}
+|	LabelExpr { $$ = $1 }
+
+LabelExpr:
+	_IDENT ':' Nl PredExpr { $$ = &LabelExpr{ Label: $1, Expr: $4 } }
+|	PredExpr { $$ = $1 }
+
+PredExpr:
+	'&' Nl PredExpr { $$ = &PredExpr{ Expr: $3, Loc: $1 } }
+|	'!' Nl PredExpr { $$ = &PredExpr{ Neg: true, Expr: $3, Loc: $1 } }
+|	'$' Nl PredExpr { $$ = &CaptureExpr{ Expr: $3, Loc: $1 } }
+|	RepExpr { $$ = $1 }
+
+RepExpr:
+	RepExpr '*' { $$ = &RepExpr{ Op: '*', Expr: $1, Loc: $2 } }
+|	RepExpr '+' { $$ = &RepExpr{ Op: '+', Expr: $1, Loc: $2 } }
+|	RepExpr '?' { $$ = &OptExpr{ Expr: $1, Loc: $2 } }
+|	Operand { $$ = $1 }
+
+Operand:
+	'(' Nl Expr Nl ')' { $$ = &SubExpr{ Expr: $3, Open: $1, Close: $5 } }
+|	'(' Nl ')' { $$ = &Empty{ Open: $1, Close: $3 } }
+|	'&' Nl  GoPred { $$ = &PredCode{ Code: $3, Loc: $1 } }
+|	'!' Nl GoPred { $$ = &PredCode{ Neg: true, Code: $3, Loc: $1 } }
+|	'.' { $$ = &Any{ Loc: $1 } }
+|	'^' { $$ = &Cut{ Loc: $1 } }
+|	Name { $$ = &Ident{ Name: $1 } }
+|	Name _ARGS
+	{
+		loc := $2.Begin()
+		loc.Col++ // skip the open (.
+		if _, err := parseGoArgs(loc, $2.String()); err != nil {
+			peggylex.(*lexer).err = err
+		}
+		$$ = &Ident{ Name: $1, CallArgs: $2 }
+	}
+|	_DELEGATE _ARGS
+	{
+		loc := $2.Begin()
+		loc.Col++ // skip the open (.
+		fun, open, close, err := parseDelegateArgs(loc, $2.String())
+		if err != nil {
+			peggylex.(*lexer).err = err
+		}
+		$$ = &DelegateExpr{ Func: fun, Open: open, Close: close, Args: $2, Loc: $1.Begin() }
+	}
+|	_NATIVE _ARGS
+	{
+		loc := $2.Begin()
+		loc.Col++ // skip the open (.
+		if n, err := parseGoArgs(loc, $2.String()); err != nil {
+			peggylex.(*lexer).err = err
+		} else if n != 1 {
+			peggylex.(*lexer).err = Err($2, "%%native wants a function")
+		}
+		$$ = &NativeExpr{ Func: strings.TrimSpace($2.String()), Args: $2, Loc: $1.Begin() }
+	}
+|	_BLOCK _ARGS
+	{
+		loc := $2.Begin()
+		loc.Col++ // skip the open (.
+		open, close, escape, err := parseBlockArgs(loc, $1.String(), $2.String())
+		if err != nil {
+			peggylex.(*lexer).err = err
+		}
+		$$ = &BlockExpr{ Kind: $1.String(), Open: open, Close: close, Escape: escape, Args: $2, Loc: $1.Begin() }
+	}
+|	_STRING { $$ = peggylex.(*lexer).literal($1) }
+|	_CHARCLASS { $$ =$1 }
+|	'(' Nl Expr error { peggylex.Error("unexpected end of file") }
+
+GoPred:
+	_CODE
+	{
+		loc := $1.Begin()
+		loc.Col++ // skip the open {.
+		err := parseGoExpr(loc, $1.String())
+		if err != nil {
+			peggylex.(*lexer).err = err
+		}
+		$$ = $1
+	}
+
+GoAction:
+	_CODE
+	{
+		loc := $1.Begin()
+		loc.Col++ // skip the open {.
+		$$ = &Action{ Code: $1 }
+		if fset, file, err := parseGoFunc(loc, $1.String()); err != nil {
+			peggylex.(*lexer).err = err
+		} else {
+			$$.ReturnType, $$.typeErr = inferType(loc, fset, file)
+		}
+	}
+|	GoAction '!' _IDENT
+	{
+		if $3.String() != "memo" {
+			peggylex.(*lexer).err = Err($3, "unknown action annotation: !"+$3.String())
+		}
+		$1.NoMemo = true
+		$$ = $1
+	}
+
+NewLine:
+	'\n' NewLine
+|	'\n'
+
+Nl:
+	NewLine
+|
+
+%%
+
+// Parse parses a Peggy input file, and returns the Grammar,
+// using a default Config, with no build tags.
+func Parse(in io.RuneScanner, fileName string) (*Grammar, error) {
+	return Config{}.Parse(in, fileName)
+}
+
+// Parse parses a Peggy input file, and returns the Grammar,
+// enabling the %if regions of the Config's Tags.
+func (c Config) Parse(in io.RuneScanner, fileName string) (*Grammar, error) {
+	tags := make(map[string]bool)
+	for _, t := range c.Tags {
+		if t = strings.TrimSpace(t); t != "" {
+			tags[t] = true
+		}
+	}
+	src := readRunes(in)
+	x := &lexer{
+		in:     src.reader(),
+		file:   fileName,
+		line:   1,
+		consts: make(map[string]string),
+		tags:   tags,
+		params: paramRules(src, fileName, tags),
+	}
+	peggyParse(x)
+	if x.err != nil {
+		return nil, x.err
+	}
+	return &x.result, nil
+}
@@ -0,0 +1,668 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"unicode"
+)
+
+// ImportGrammar parses a grammar in another PEG dialect,
+// either "pigeon" or "pegjs", returning the equivalent Peggy Grammar.
+//
+// The other dialects' actions and code predicates
+// cannot be translated, since their code returns different values
+// (and for pegjs, is JavaScript).
+// They are dropped from the Grammar, and each is returned in dropped.
+// A pigeon initializer is Go code, so it becomes the Grammar's prelude;
+// a pegjs initializer is dropped, and the prelude is just a package clause.
+// Case-insensitive literals and character classes, marked by an i suffix,
+// are translated to case-sensitive character classes.
+func ImportGrammar(in io.Reader, file, dialect string) (g *Grammar, dropped Errors, err error) {
+	if dialect != "pigeon" && dialect != "pegjs" {
+		return nil, Errors{}, errors.New("bad import dialect " + dialect + ": want pigeon or pegjs")
+	}
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, Errors{}, err
+	}
+	im := &importer{
+		dialect: dialect,
+		file:    file,
+		src:     []rune(string(data)),
+		line:    1,
+		col:     1,
+	}
+	if g, err = im.grammar(); err != nil {
+		return nil, Errors{}, err
+	}
+	return g, im.dropped, nil
+}
+
+// WriteImported writes the Peggy source of an imported grammar.
+func WriteImported(w io.Writer, g *Grammar) error {
+	if _, err := io.WriteString(w, "{"+g.Prelude.String()+"}\n"); err != nil {
+		return err
+	}
+	for i := range g.Rules {
+		if _, err := io.WriteString(w, g.Rules[i].String()+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const importEOF = -1
+
+type importer struct {
+	dialect string
+	file    string
+	src     []rune
+	pos     int
+	// line and col are the Loc of src[pos].
+	line, col int
+	dropped   Errors
+}
+
+// importState is the position of the importer,
+// saved to backtrack after lookahead.
+type importState struct{ pos, line, col int }
+
+func (im *importer) save() importState { return importState{im.pos, im.line, im.col} }
+
+func (im *importer) restore(s importState) { im.pos, im.line, im.col = s.pos, s.line, s.col }
+
+func (im *importer) loc() Loc { return Loc{File: im.file, Line: im.line, Col: im.col} }
+
+func (im *importer) peek() rune { return im.peekN(0) }
+
+func (im *importer) peekN(n int) rune {
+	if im.pos+n >= len(im.src) {
+		return importEOF
+	}
+	return im.src[im.pos+n]
+}
+
+func (im *importer) next() rune {
+	r := im.peek()
+	if r == importEOF {
+		return r
+	}
+	im.pos++
+	if r == '\n' {
+		im.line++
+		im.col = 1
+	} else {
+		im.col++
+	}
+	return r
+}
+
+func (im *importer) errorf(format string, args ...interface{}) error {
+	return Err(im.loc(), format, args...)
+}
+
+// space skips whitespace and // and /* */ comments.
+func (im *importer) space() {
+	for {
+		switch r := im.peek(); {
+		case unicode.IsSpace(r):
+			im.next()
+		case r == '/' && im.peekN(1) == '/':
+			for im.peek() != '\n' && im.peek() != importEOF {
+				im.next()
+			}
+		case r == '/' && im.peekN(1) == '*':
+			im.next()
+			im.next()
+			for im.peek() != importEOF && !(im.peek() == '*' && im.peekN(1) == '/') {
+				im.next()
+			}
+			im.next()
+			im.next()
+		default:
+			return
+		}
+	}
+}
+
+func (im *importer) grammar() (*Grammar, error) {
+	g := &Grammar{Prelude: text{str: "\npackage main\n"}}
+	im.space()
+	if im.peek() == '{' {
+		code, err := im.code()
+		if err != nil {
+			return nil, err
+		}
+		if im.dialect == "pigeon" {
+			g.Prelude = code
+		} else {
+			im.dropped.add(code, "dropped initializer")
+		}
+		im.space()
+	}
+	for im.peek() != importEOF {
+		r, err := im.rule()
+		if err != nil {
+			return nil, err
+		}
+		g.Rules = append(g.Rules, r)
+		im.space()
+	}
+	return g, nil
+}
+
+func (im *importer) rule() (Rule, error) {
+	if !isIdentStart(im.peek()) {
+		return Rule{}, im.errorf("expected rule name")
+	}
+	r := Rule{Name: Name{Name: im.ident()}}
+	im.space()
+	if q := im.peek(); q == '"' || q == '\'' || q == '`' {
+		s, err := im.string()
+		if err != nil {
+			return Rule{}, err
+		}
+		r.ErrorName = s
+		im.space()
+	}
+	if !im.ruleOp() {
+		return Rule{}, im.errorf("expected = after rule name")
+	}
+	im.space()
+	var err error
+	if r.Expr, err = im.choice(); err != nil {
+		return Rule{}, err
+	}
+	im.space()
+	if im.peek() == ';' {
+		im.next()
+	}
+	return r, nil
+}
+
+// ruleOp consumes and returns whether the next token
+// separates a rule name from its expression.
+func (im *importer) ruleOp() bool {
+	switch r := im.peek(); {
+	case r == '=':
+		im.next()
+		return true
+	case im.dialect == "pigeon" && (r == '←' || r == '⟵'):
+		im.next()
+		return true
+	case im.dialect == "pigeon" && r == '<' && im.peekN(1) == '-':
+		im.next()
+		im.next()
+		return true
+	}
+	return false
+}
+
+// ruleStart returns whether the next tokens begin a new rule,
+// without consuming them.
+func (im *importer) ruleStart() bool {
+	s := im.save()
+	defer im.restore(s)
+	if !isIdentStart(im.peek()) {
+		return false
+	}
+	im.ident()
+	im.space()
+	if q := im.peek(); q == '"' || q == '\'' || q == '`' {
+		if _, err := im.string(); err != nil {
+			return false
+		}
+		im.space()
+	}
+	return im.ruleOp()
+}
+
+func (im *importer) choice() (Expr, error) {
+	var exprs []Expr
+	for {
+		e, err := im.action()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+		im.space()
+		if im.peek() != '/' {
+			break
+		}
+		im.next()
+		im.space()
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return &Choice{Exprs: exprs}, nil
+}
+
+func (im *importer) action() (Expr, error) {
+	e, err := im.sequence()
+	if err != nil {
+		return nil, err
+	}
+	im.space()
+	if im.peek() == '{' {
+		code, err := im.code()
+		if err != nil {
+			return nil, err
+		}
+		im.dropped.add(code, "dropped action")
+	}
+	return e, nil
+}
+
+func (im *importer) sequence() (Expr, error) {
+	begin := im.loc()
+	var n int
+	var exprs []Expr
+	for ; ; n++ {
+		im.space()
+		if !im.exprStart() || im.ruleStart() {
+			break
+		}
+		e, dropped, err := im.labeled()
+		if err != nil {
+			return nil, err
+		}
+		if !dropped {
+			exprs = append(exprs, e)
+		}
+	}
+	switch {
+	case n == 0:
+		return nil, im.errorf("expected expression")
+	case len(exprs) == 0:
+		// Every element was dropped, so match the empty string.
+		return &Literal{Text: text{begin: begin, end: begin}}, nil
+	case len(exprs) == 1:
+		return exprs[0], nil
+	}
+	return &Sequence{Exprs: exprs}, nil
+}
+
+// exprStart returns whether the next rune begins a sequence element.
+func (im *importer) exprStart() bool {
+	switch r := im.peek(); {
+	case isIdentStart(r):
+		return true
+	case r == '"' || r == '\'' || r == '[' || r == '.' || r == '(':
+		return true
+	case r == '&' || r == '!' || r == '$':
+		return true
+	case im.dialect == "pigeon" && (r == '`' || r == '#'):
+		return true
+	case im.dialect == "pegjs" && r == '@':
+		return true
+	}
+	return false
+}
+
+// labeled returns the next sequence element,
+// and whether it was dropped.
+func (im *importer) labeled() (Expr, bool, error) {
+	if im.dialect == "pegjs" && im.peek() == '@' {
+		im.dropped.add(im.loc(), "dropped @ pluck operator")
+		im.next()
+		im.space()
+	}
+	if isIdentStart(im.peek()) {
+		s := im.save()
+		label := im.ident()
+		im.space()
+		if im.peek() == ':' {
+			im.next()
+			im.space()
+			e, dropped, err := im.prefixed()
+			if err != nil || dropped {
+				return nil, dropped, err
+			}
+			return &LabelExpr{Label: label, Expr: e}, false, nil
+		}
+		im.restore(s)
+	}
+	return im.prefixed()
+}
+
+func (im *importer) prefixed() (Expr, bool, error) {
+	loc := im.loc()
+	switch r := im.peek(); {
+	case r == '$':
+		im.next()
+		im.space()
+		e, err := im.suffixed()
+		if err != nil {
+			return nil, false, err
+		}
+		return &CaptureExpr{Expr: e, Loc: loc}, false, nil
+	case r == '&' || r == '!':
+		im.next()
+		im.space()
+		if im.peek() == '{' {
+			code, err := im.code()
+			if err != nil {
+				return nil, false, err
+			}
+			im.dropped.add(text{begin: loc, end: code.End()}, "dropped code predicate")
+			return nil, true, nil
+		}
+		e, err := im.suffixed()
+		if err != nil {
+			return nil, false, err
+		}
+		return &PredExpr{Expr: e, Neg: r == '!', Loc: loc}, false, nil
+	case r == '#':
+		im.next()
+		im.space()
+		if im.peek() != '{' {
+			return nil, false, im.errorf("expected { after #")
+		}
+		code, err := im.code()
+		if err != nil {
+			return nil, false, err
+		}
+		im.dropped.add(text{begin: loc, end: code.End()}, "dropped state code block")
+		return nil, true, nil
+	}
+	e, err := im.suffixed()
+	return e, false, err
+}
+
+func (im *importer) suffixed() (Expr, error) {
+	e, err := im.primary()
+	if err != nil {
+		return nil, err
+	}
+	s := im.save()
+	im.space()
+	loc := im.loc()
+	switch im.peek() {
+	case '*', '+':
+		return &RepExpr{Op: im.next(), Expr: e, Loc: loc}, nil
+	case '?':
+		im.next()
+		return &OptExpr{Expr: e, Loc: loc}, nil
+	case '|':
+		if im.dialect == "pegjs" {
+			return nil, im.errorf("cannot translate | repetition")
+		}
+	}
+	im.restore(s)
+	return e, nil
+}
+
+func (im *importer) primary() (Expr, error) {
+	loc := im.loc()
+	switch r := im.peek(); {
+	case isIdentStart(r):
+		return &Ident{Name: Name{Name: im.ident()}}, nil
+	case r == '"' || r == '\'' || r == '`':
+		s, err := im.string()
+		if err != nil {
+			return nil, err
+		}
+		if im.caseFlag() {
+			return foldLiteral(s), nil
+		}
+		return &Literal{Text: s}, nil
+	case r == '[':
+		return im.charClass()
+	case r == '.':
+		im.next()
+		return &Any{Loc: loc}, nil
+	case r == '(':
+		im.next()
+		im.space()
+		e, err := im.choice()
+		if err != nil {
+			return nil, err
+		}
+		im.space()
+		if im.peek() != ')' {
+			return nil, im.errorf("expected )")
+		}
+		close := im.loc()
+		im.next()
+		return &SubExpr{Expr: e, Open: loc, Close: close}, nil
+	case r == importEOF:
+		return nil, im.errorf("unexpected end of file")
+	}
+	return nil, im.errorf("unexpected %q", im.peek())
+}
+
+// caseFlag consumes and returns whether the next rune
+// is an i marking a literal or character class as case-insensitive.
+func (im *importer) caseFlag() bool {
+	if im.peek() != 'i' || isIdentPart(im.peekN(1)) {
+		return false
+	}
+	im.next()
+	return true
+}
+
+func isIdentStart(r rune) bool { return unicode.IsLetter(r) || r == '_' }
+
+func isIdentPart(r rune) bool { return isIdentStart(r) || unicode.IsDigit(r) }
+
+func (im *importer) ident() Text {
+	begin := im.loc()
+	var s []rune
+	for isIdentPart(im.peek()) {
+		s = append(s, im.next())
+	}
+	return text{str: string(s), begin: begin, end: im.loc()}
+}
+
+// string returns the unescaped contents of a quoted string.
+func (im *importer) string() (Text, error) {
+	begin := im.loc()
+	q := im.next()
+	var s []rune
+	for {
+		r := im.next()
+		switch {
+		case r == importEOF || r == '\n' && q != '`':
+			return nil, Err(begin, "unclosed string")
+		case r == q:
+			return text{str: string(s), begin: begin, end: im.loc()}, nil
+		case r == '\\' && q != '`':
+			e, err := im.escape()
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, e)
+		default:
+			s = append(s, r)
+		}
+	}
+}
+
+// escape returns the rune of the escape sequence following a \.
+func (im *importer) escape() (rune, error) {
+	loc := im.loc()
+	switch r := im.next(); r {
+	case 'a':
+		return '\a', nil
+	case 'b':
+		return '\b', nil
+	case 'f':
+		return '\f', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 't':
+		return '\t', nil
+	case 'v':
+		return '\v', nil
+	case '0':
+		return 0, nil
+	case 'x':
+		return im.hex(loc, 2)
+	case 'u':
+		return im.hex(loc, 4)
+	case 'U':
+		return im.hex(loc, 8)
+	case importEOF:
+		return 0, Err(loc, "unclosed escape")
+	default:
+		return r, nil
+	}
+}
+
+func (im *importer) hex(loc Loc, n int) (rune, error) {
+	var s []rune
+	for i := 0; i < n; i++ {
+		s = append(s, im.next())
+	}
+	v, err := strconv.ParseUint(string(s), 16, 32)
+	if err != nil {
+		return 0, Err(loc, "bad escape")
+	}
+	return rune(v), nil
+}
+
+func (im *importer) charClass() (Expr, error) {
+	e := &CharClass{Open: im.loc()}
+	im.next()
+	if im.peek() == '^' {
+		im.next()
+		e.Neg = true
+	}
+	for im.peek() != ']' {
+		lo, err := im.classRune()
+		if err != nil {
+			return nil, err
+		}
+		hi := lo
+		if im.peek() == '-' && im.peekN(1) != ']' {
+			im.next()
+			if hi, err = im.classRune(); err != nil {
+				return nil, err
+			}
+			if hi < lo {
+				return nil, Err(e.Open, "bad span")
+			}
+		}
+		e.Spans = append(e.Spans, [2]rune{lo, hi})
+	}
+	e.Close = im.loc()
+	im.next()
+	if im.caseFlag() {
+		foldCharClass(e)
+	}
+	return e, nil
+}
+
+func (im *importer) classRune() (rune, error) {
+	loc := im.loc()
+	switch r := im.next(); r {
+	case importEOF, '\n':
+		return 0, Err(loc, "unclosed [")
+	case '\\':
+		if p := im.peek(); p == 'p' || p == 'P' {
+			return 0, Err(loc, "cannot translate Unicode class \\%c", p)
+		}
+		return im.escape()
+	default:
+		return r, nil
+	}
+}
+
+// code returns the code between { and }, including nested braces,
+// skipping braces within strings and comments.
+func (im *importer) code() (Text, error) {
+	begin := im.loc()
+	im.next()
+	start := im.pos
+	for depth := 1; ; {
+		switch r := im.next(); {
+		case r == importEOF:
+			return nil, Err(begin, "unclosed {")
+		case r == '{':
+			depth++
+		case r == '}':
+			if depth--; depth == 0 {
+				str := string(im.src[start : im.pos-1])
+				return text{str: str, begin: begin, end: im.loc()}, nil
+			}
+		case r == '"' || r == '\'' || r == '`':
+			for c := im.next(); c != r && c != importEOF; c = im.next() {
+				if c == '\\' && r != '`' {
+					im.next()
+				}
+			}
+		case r == '/' && im.peek() == '/':
+			for im.peek() != '\n' && im.peek() != importEOF {
+				im.next()
+			}
+		case r == '/' && im.peek() == '*':
+			im.next()
+			for im.peek() != importEOF && !(im.peek() == '*' && im.peekN(1) == '/') {
+				im.next()
+			}
+			im.next()
+			im.next()
+		}
+	}
+}
+
+// foldLiteral returns a case-sensitive expression
+// matching a case-insensitive literal:
+// a sequence of literals for runes without case
+// and character classes for runes with case.
+func foldLiteral(lit Text) Expr {
+	loc := lit.Begin()
+	var exprs []Expr
+	var runes []rune
+	flush := func() {
+		if len(runes) > 0 {
+			exprs = append(exprs, &Literal{Text: text{str: string(runes), begin: loc, end: loc}})
+			runes = nil
+		}
+	}
+	for _, r := range lit.String() {
+		up, low := unicode.ToUpper(r), unicode.ToLower(r)
+		if up == low {
+			runes = append(runes, r)
+			continue
+		}
+		flush()
+		exprs = append(exprs, &CharClass{
+			Spans:  [][2]rune{{up, up}, {low, low}},
+			Open:   loc,
+			Close:  loc,
+			NoCase: true,
+		})
+	}
+	flush()
+	switch len(exprs) {
+	case 0:
+		return &Literal{Text: lit}
+	case 1:
+		return exprs[0]
+	}
+	return &SubExpr{Expr: &Sequence{Exprs: exprs}, Open: loc, Close: lit.End()}
+}
+
+// foldCharClass adds the other case of each span of lower or upper case letters.
+func foldCharClass(e *CharClass) {
+	for _, sp := range e.Spans {
+		switch {
+		case unicode.IsLower(sp[0]) && unicode.IsLower(sp[1]):
+			e.Spans = append(e.Spans, [2]rune{unicode.ToUpper(sp[0]), unicode.ToUpper(sp[1])})
+		case unicode.IsUpper(sp[0]) && unicode.IsUpper(sp[1]):
+			e.Spans = append(e.Spans, [2]rune{unicode.ToLower(sp[0]), unicode.ToLower(sp[1])})
+		}
+	}
+}
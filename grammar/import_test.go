@@ -0,0 +1,167 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect string
+		in      string
+		want    string
+		dropped []string
+		err     string
+	}{
+		{
+			name:    "pigeon",
+			dialect: "pigeon",
+			in: `{
+package p
+}
+
+// Comment.
+Start ← e:Expr EOF {
+	return e, nil // }
+}
+Expr "expression" = l:Term rest:( _ op:( '+' / "-" ) _ Term )* {
+	return eval(l, rest), nil
+}
+Term <- Num / '(' _ Expr _ ')'
+Num = $[0-9]+ &{ return len(c.text) < 10, nil } #{ return nil }
+_ "whitespace" <- [ \n\t\r]*
+/* multi
+line */
+EOF = !.`,
+			want: `{
+package p
+}
+Start <- e:Expr EOF
+Expr "expression" <- l:Term rest:(_ op:("+"/"-") _ Term)*
+Term <- Num/"(" _ Expr _ ")"
+Num <- $[0-9]+
+_ "whitespace" <- [ \n\t\r]*
+EOF <- !.
+`,
+			dropped: []string{
+				"test.file:6.20,8.2: dropped action",
+				"test.file:9.64,11.2: dropped action",
+				"test.file:13.15,13.48: dropped code predicate",
+				"test.file:13.49,13.64: dropped state code block",
+			},
+		},
+		{
+			name:    "pegjs",
+			dialect: "pegjs",
+			in: `{ var x = "}"; }
+start = a:"a" b:b+ { return [a,b]; } ;
+b "bee" = 'xA\'' / @c:c
+c = "c" { return '}'; }
+  / &{ return true; }`,
+			want: `{
+package main
+}
+start <- a:"a" b:b+
+b "bee" <- "xA'"/c:c
+c <- "c"/""
+`,
+			dropped: []string{
+				"test.file:1.1,1.17: dropped initializer",
+				"test.file:2.20,2.37: dropped action",
+				"test.file:3.20: dropped @ pluck operator",
+				"test.file:4.9,4.24: dropped action",
+				"test.file:5.5,5.22: dropped code predicate",
+			},
+		},
+		{
+			name:    "case-insensitive",
+			dialect: "pegjs",
+			in:      `A = "a-b"i "1"i [a-cX_]i`,
+			want: `{
+package main
+}
+A <- ([Aa] "-" [Bb]) "1" [a-cX_A-Cx]
+`,
+		},
+		{
+			name:    "pigeon <- is not pegjs",
+			dialect: "pegjs",
+			in:      `A <- "a"`,
+			err:     "^test.file:1.3: expected = after rule name",
+		},
+		{
+			name:    "unclosed action",
+			dialect: "pigeon",
+			in:      `A = "a" { return`,
+			err:     "^test.file:1.9: unclosed {",
+		},
+		{
+			name:    "missing expression",
+			dialect: "pigeon",
+			in:      `A = / "a"`,
+			err:     "^test.file:1.5: expected expression",
+		},
+		{
+			name:    "Unicode class",
+			dialect: "pigeon",
+			in:      `A = [\pL]`,
+			err:     `^test.file:1.6: cannot translate Unicode class \\p`,
+		},
+		{
+			name:    "bad dialect",
+			dialect: "yacc",
+			in:      `A = "a"`,
+			err:     "^bad import dialect yacc: want pigeon or pegjs",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			g, dropped, err := ImportGrammar(strings.NewReader(test.in), "test.file", test.dialect)
+			if test.err != "" {
+				if err == nil || !regexp.MustCompile(test.err).MatchString(err.Error()) {
+					t.Fatalf("ImportGrammar(%q, _, %s)=_, _, %v, want matching %q",
+						test.in, test.dialect, err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ImportGrammar(%q, _, %s)=_, _, %v, want nil", test.in, test.dialect, err)
+			}
+			var b strings.Builder
+			if err := WriteImported(&b, g); err != nil {
+				t.Fatalf("WriteImported(_, _)=%v, want nil", err)
+			}
+			if b.String() != test.want {
+				t.Errorf("ImportGrammar(%q, _, %s) wrote\n%s\nwant\n%s",
+					test.in, test.dialect, b.String(), test.want)
+			}
+			var got []string
+			for _, e := range dropped.Errs {
+				got = append(got, e.Error())
+			}
+			if strings.Join(got, "\n") != strings.Join(test.dropped, "\n") {
+				t.Errorf("ImportGrammar(%q, _, %s) dropped\n%s\nwant\n%s",
+					test.in, test.dialect, strings.Join(got, "\n"), strings.Join(test.dropped, "\n"))
+			}
+
+			// The imported grammar is a valid Peggy grammar.
+			pg, err := Parse(strings.NewReader(b.String()), "imported")
+			if err != nil {
+				t.Fatalf("Parse(%q)=_, %v, want _,nil", b.String(), err)
+			}
+			if err := Check(pg); err != nil {
+				t.Errorf("Check(%q)=%v, want nil", b.String(), err)
+			}
+		})
+	}
+}
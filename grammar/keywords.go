@@ -0,0 +1,129 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Keywords returns the literal terminals of the grammar's rules,
+// grouped by the names of the metadata annotations
+// of the rules that contain them, such as @keyword or @operator.
+// The literals of rules without a metadata annotation
+// are grouped under the empty string.
+// The literals of each group are sorted and distinct.
+// The literals between the escapes of a string literal
+// with class escapes, such as "\d\d", are not terminals,
+// so they are omitted.
+//
+// Keywords reads the rules as parsed, including the templates;
+// it must be called before Check,
+// which rewrites the literals of @nocase rules.
+func Keywords(gr *Grammar) map[string][]string {
+	sets := make(map[string]map[string]bool)
+	for i := range gr.Rules {
+		r := &gr.Rules[i]
+		groups := []string{""}
+		if len(r.Metadata) > 0 {
+			groups = groups[:0]
+			for name := range r.Metadata {
+				groups = append(groups, name)
+			}
+		}
+		escaped := make(map[*Literal]bool)
+		r.Expr.Walk(func(e Expr) bool {
+			switch e := e.(type) {
+			case *SubExpr:
+				if e.Source == "" {
+					break
+				}
+				e.Expr.Walk(func(e Expr) bool {
+					if l, ok := e.(*Literal); ok {
+						escaped[l] = true
+					}
+					return true
+				})
+			case *Literal:
+				lit := e.Text.String()
+				if escaped[e] || lit == "" {
+					break
+				}
+				for _, g := range groups {
+					if sets[g] == nil {
+						sets[g] = make(map[string]bool)
+					}
+					sets[g][lit] = true
+				}
+			}
+			return true
+		})
+	}
+	keywords := make(map[string][]string, len(sets))
+	for g, set := range sets {
+		var lits []string
+		for lit := range set {
+			lits = append(lits, lit)
+		}
+		sort.Strings(lits)
+		keywords[g] = lits
+	}
+	return keywords
+}
+
+// WriteKeywords writes the keyword table as JSON,
+// an object mapping each group to its literals,
+// or as a Go file of the package declaring
+// a variable <prefix>Keywords of type map[string][]string.
+func WriteKeywords(w io.Writer, form, pkg, prefix string, keywords map[string][]string) error {
+	var data []byte
+	switch form {
+	case "json":
+		var err error
+		if data, err = json.MarshalIndent(keywords, "", "\t"); err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	case "go":
+		var groups []string
+		for g := range keywords {
+			groups = append(groups, g)
+		}
+		sort.Strings(groups)
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "// Code generated by peggy keywords. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+		fmt.Fprintf(&b, "// %sKeywords are the literal terminals of the grammar,\n", prefix)
+		b.WriteString("// grouped by the metadata annotations of their rules,\n")
+		b.WriteString("// or the empty string for rules without one.\n")
+		fmt.Fprintf(&b, "var %sKeywords = map[string][]string{\n", prefix)
+		for _, g := range groups {
+			fmt.Fprintf(&b, "%s: {", strconv.Quote(g))
+			for i, lit := range keywords[g] {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				b.WriteString(strconv.Quote(lit))
+			}
+			b.WriteString("},\n")
+		}
+		b.WriteString("}\n")
+		var err error
+		if data, err = format.Source(b.Bytes()); err != nil {
+			return err
+		}
+	default:
+		return errors.New("bad format " + form + ": want go or json")
+	}
+	_, err := w.Write(data)
+	return err
+}
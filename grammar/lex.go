@@ -0,0 +1,1385 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:generate goyacc -o grammar.go -p "peggy" grammar.y
+
+const eof = -1
+
+// constRef is returned by nextUnesc for a \{Name} constant reference.
+// The value of the constant is in the lexer's constValue field.
+const constRef = -2
+
+// classRef is returned by nextUnesc for a character class escape,
+// such as \d or \p{Greek}.
+// The class is in the lexer's classValue field.
+const classRef = -3
+
+type text struct {
+	str        string
+	begin, end Loc
+}
+
+func (t text) PrettyPrint() string {
+	return fmt.Sprintf(`Text{%d:%d-%d:%d: "%s"}`,
+		t.begin.Line, t.begin.Col,
+		t.end.Line, t.end.Col,
+		t.str)
+}
+
+func (t text) String() string { return t.str }
+func (t text) Begin() Loc     { return t.begin }
+func (t text) End() Loc       { return t.end }
+
+type lexer struct {
+	in                                io.RuneScanner
+	file                              string
+	n, line, lineStart, prevLineStart int
+	eof                               bool
+
+	// cr is whether the most-recently read rune is \r.
+	// crlf is whether the most-recently read rune is the \n of a \r\n.
+	// A \r\n is a single line terminator, as is a lone \r or \n.
+	cr, crlf bool
+
+	// args is whether the most-recently scanned token
+	// was immediately followed by a (.
+	// If so, the next token is the _ARGS of that token.
+	args bool
+
+	// params are the names of the rules declared with parameters.
+	// Only an identifier naming one of them
+	// is followed by the _ARGS of a call;
+	// otherwise, a ( immediately following it begins a subexpression.
+	// If params is nil, every identifier followed by ( has _ARGS.
+	params map[string]bool
+
+	// arrow is the <- that ended the most-recently scanned _TYPE.
+	// If non-nil, the next token is the _ARROW.
+	arrow *text
+
+	// consts are the values of the constants
+	// defined by %const directives so far.
+	// constValue is the value of the most-recently read constant reference.
+	consts     map[string]string
+	constValue string
+
+	// classValue is the most-recently read character class escape.
+	// classLits are the desugared string literals containing
+	// character class escapes, keyed by their begin location.
+	classValue *classEsc
+	classLits  map[Loc]*SubExpr
+
+	// tags are the tags enabling %if regions.
+	// conds are the enclosing %if regions
+	// of the current line, innermost last.
+	tags  map[string]bool
+	conds []cond
+
+	// midLine is whether a token was scanned
+	// since the beginning of the current line.
+	midLine bool
+
+	// ahead are tokens scanned ahead of the parser
+	// to decide whether a newline ends a rule.
+	ahead []lexeme
+
+	// directive is whether the most-recently returned token
+	// was on a directive line.
+	directive bool
+
+	// prevBegin is the beginning of the most-recently scanned token.
+	// prevEnd is the end of the most-recently scanned token.
+	// These are used for error reporting.
+	prevBegin, prevEnd Loc
+
+	// partial are the runes read of the token being scanned,
+	// quoted by errors reading the input.
+	partial []rune
+
+	// err is non-nil if there was an error during parsing.
+	err error
+	// result contains the Grammar resulting from a successful parse.
+	result Grammar
+}
+
+// literal returns the expression of a string literal token:
+// a Literal or, if the string has character class escapes,
+// a SubExpr of the sequence of its parts.
+func (x *lexer) literal(t text) Expr {
+	if e, ok := x.classLits[t.begin]; ok {
+		return e
+	}
+	return &Literal{Text: t}
+}
+
+// plain sets the lexer error if a string token that is not a literal
+// has character class escapes.
+func (x *lexer) plain(t text) {
+	if _, ok := x.classLits[t.begin]; ok && x.err == nil {
+		x.err = Err(t, "character class escape outside of a literal")
+	}
+}
+
+// paramRules returns the names of the rules
+// declared with an @param annotation in the source.
+// The rules may be declared after their calls,
+// so they are found by scanning the source ahead of parsing it,
+// with every identifier followed by ( taking _ARGS.
+// The scan ends at the first error, which is left for the parse to report.
+func paramRules(src *runeSource, file string, tags map[string]bool) map[string]bool {
+	x := &lexer{
+		in:     src.reader(),
+		file:   file,
+		line:   1,
+		consts: make(map[string]string),
+		tags:   tags,
+	}
+	var toks []lexeme
+	for {
+		t := x.scan()
+		if t.tok <= 0 || x.err != nil {
+			break
+		}
+		toks = append(toks, t)
+		// Constants are defined by the parser,
+		// but they are needed to scan the literals that refer to them.
+		if n := len(toks); n >= 4 &&
+			toks[n-4].tok == _DIRECTIVE && toks[n-4].lval.text.str == "const" &&
+			toks[n-3].tok == _IDENT && toks[n-2].tok == '=' && t.tok == _STRING {
+			x.consts[toks[n-3].lval.text.str] = t.lval.text.str
+		}
+	}
+	params := make(map[string]bool)
+	for i, t := range toks {
+		if t.tok != _IDENT || i > 0 && toks[i-1].tok != '\n' {
+			continue
+		}
+		// Like endsRule, a rule header is an identifier
+		// followed by <- on the same line.
+		var param bool
+	header:
+		for _, h := range toks[i+1:] {
+			switch h.tok {
+			case _ARROW:
+				if param {
+					params[t.lval.text.str] = true
+				}
+				break header
+			case _ANNOT:
+				param = param || h.lval.text.str == "param"
+			case '<', '>', ',', _IDENT, _STRING, _ARGS, _TYPE:
+				continue
+			default:
+				break header
+			}
+		}
+	}
+	return params
+}
+
+// A runeSource is the runes of an input,
+// read up front so that it can be scanned more than once.
+type runeSource struct {
+	runes []rune
+	// err is the error that ended reading the input,
+	// or io.EOF if it was read to the end.
+	err error
+}
+
+// readRunes returns the runes of the input.
+func readRunes(in io.RuneScanner) *runeSource {
+	var src runeSource
+	for {
+		r, _, err := in.ReadRune()
+		if err != nil {
+			src.err = err
+			return &src
+		}
+		src.runes = append(src.runes, r)
+	}
+}
+
+// reader returns an io.RuneScanner reading the runes of the source,
+// and then returning the error that ended reading the input.
+func (src *runeSource) reader() io.RuneScanner {
+	return &runeReader{src: src}
+}
+
+type runeReader struct {
+	src *runeSource
+	i   int
+}
+
+func (r *runeReader) ReadRune() (rune, int, error) {
+	if r.i >= len(r.src.runes) {
+		return 0, 0, r.src.err
+	}
+	c := r.src.runes[r.i]
+	r.i++
+	return c, utf8.RuneLen(c), nil
+}
+
+func (r *runeReader) UnreadRune() error {
+	if r.i == 0 {
+		return errors.New("no rune to unread")
+	}
+	r.i--
+	return nil
+}
+
+// Begin returns the begin location of the last returned token.
+func (x *lexer) Begin() Loc { return x.prevBegin }
+
+// End returns the end location of the last returned token.
+func (x *lexer) End() Loc { return x.prevEnd }
+
+func (x *lexer) loc() Loc {
+	return Loc{
+		File: x.file,
+		Line: x.line,
+		Col:  x.n - x.lineStart + 1,
+	}
+}
+
+func (x *lexer) next() (rune, error) {
+	if x.eof {
+		return eof, nil
+	}
+	r, _, err := x.in.ReadRune()
+	if err == io.EOF {
+		x.eof = true
+		return eof, nil
+	}
+	if err != nil {
+		return 0, x.readError(err)
+	}
+	x.partial = append(x.partial, r)
+	x.n++
+	x.crlf = r == '\n' && x.cr
+	switch {
+	case x.crlf:
+		x.lineStart = x.n
+	case r == '\n' || r == '\r':
+		x.prevLineStart = x.lineStart
+		x.lineStart = x.n
+		x.line++
+	}
+	x.cr = r == '\r'
+	return r, nil
+}
+
+// maxPartial is the maximum number of runes
+// of a partial token quoted by a read error.
+const maxPartial = 20
+
+// readError returns an Error wrapping an error reading the input,
+// located from the beginning of the token being scanned
+// to the end of the last rune read,
+// and quoting the end of the token read so far.
+func (x *lexer) readError(err error) error {
+	e := Error{
+		Located: text{begin: x.prevBegin, end: x.loc()},
+		Msg:     "read error: " + err.Error(),
+		cause:   err,
+	}
+	if p := x.partial; len(p) > 0 {
+		var dots string
+		if len(p) > maxPartial {
+			p, dots = p[len(p)-maxPartial:], "..."
+		}
+		e.Msg = fmt.Sprintf("read error after %s%q: %s", dots, string(p), err)
+	}
+	return e
+}
+
+func (x *lexer) back() error {
+	if x.eof {
+		return nil
+	}
+	switch {
+	case x.crlf:
+		x.lineStart = x.n - 1
+		x.crlf = false
+		x.cr = true
+	case x.lineStart == x.n:
+		x.lineStart = x.prevLineStart
+		x.line--
+	}
+	x.n--
+	if len(x.partial) > 0 {
+		x.partial = x.partial[:len(x.partial)-1]
+	}
+	return x.in.UnreadRune()
+}
+
+// peek returns whether the next rune is r, without consuming it.
+func (x *lexer) peek(r rune) (bool, error) {
+	next, err := x.next()
+	if err != nil {
+		return false, err
+	}
+	if next == eof {
+		return false, nil
+	}
+	return next == r, x.back()
+}
+
+// skipLF consumes the \n of a \r\n line terminator,
+// assuming that the \r was just read.
+func (x *lexer) skipLF() error {
+	lf, err := x.peek('\n')
+	if err != nil || !lf {
+		return err
+	}
+	_, err = x.next()
+	return err
+}
+
+func (x *lexer) Error(s string) {
+	if x.err != nil {
+		return
+	}
+	x.err = Err(x, "%s", s)
+}
+
+// A lexeme is a scanned token, with its value and location.
+type lexeme struct {
+	tok        int
+	lval       peggySymType
+	begin, end Loc
+}
+
+// Lex returns the next token for the parser.
+//
+// Whitespace, including newlines, may appear between any tokens.
+// However, the parser needs a newline to end each rule
+// in order to remain LALR(1):
+// otherwise, the name of the next rule would be the end of a sequence.
+// So Lex returns a newline only where it ends a rule or a directive:
+// where the following line begins a rule header,
+// an identifier followed by <- on the same line,
+// or a directive, or the end of the file.
+// Every other newline is skipped as whitespace.
+func (x *lexer) Lex(lval *peggySymType) int {
+	for {
+		t := x.shift()
+		if t.tok == '\n' && !x.directive && !x.endsRule() {
+			continue
+		}
+		x.directive = t.tok == _DIRECTIVE || x.directive && t.tok != '\n'
+		*lval = t.lval
+		x.prevBegin, x.prevEnd = t.begin, t.end
+		return t.tok
+	}
+}
+
+// shift returns the next token, scanning it if it was not scanned ahead.
+func (x *lexer) shift() lexeme {
+	if len(x.ahead) > 0 {
+		t := x.ahead[0]
+		x.ahead = x.ahead[1:]
+		return t
+	}
+	return x.scan()
+}
+
+// lookahead returns the ith token after the next, scanning ahead as needed.
+func (x *lexer) lookahead(i int) lexeme {
+	for len(x.ahead) <= i {
+		x.ahead = append(x.ahead, x.scan())
+	}
+	return x.ahead[i]
+}
+
+func (x *lexer) scan() lexeme {
+	var t lexeme
+	t.tok = x.lex(&t.lval)
+	t.begin, t.end = x.prevBegin, x.prevEnd
+	return t
+}
+
+// endsRule returns whether a newline just shifted
+// ends a rule or directive.
+func (x *lexer) endsRule() bool {
+	i := 0
+	for x.lookahead(i).tok == '\n' {
+		i++
+	}
+	switch x.lookahead(i).tok {
+	case _DIRECTIVE, _RULECODE:
+		return true
+	case _IDENT:
+	default:
+		return x.lookahead(i).tok <= 0 // end of file
+	}
+	for i++; ; i++ {
+		switch x.lookahead(i).tok {
+		case _ARROW:
+			return true
+		case '<', '>', ',', _IDENT, _STRING, _ANNOT, _ARGS, _TYPE:
+			continue
+		default:
+			return false
+		}
+	}
+}
+
+func (x *lexer) lex(lval *peggySymType) (v int) {
+	defer func() {
+		x.prevEnd = x.loc()
+		x.midLine = v != '\n'
+	}()
+	for {
+		x.prevBegin = x.loc()
+		x.partial = x.partial[:0]
+		lval.text.begin = x.loc()
+		lval.loc = x.loc()
+		if x.arrow != nil {
+			lval.text = *x.arrow
+			x.prevBegin = x.arrow.begin
+			x.arrow = nil
+			return _ARROW
+		}
+		r, err := x.next()
+
+		switch {
+		case err != nil:
+			break
+
+		case x.args:
+			x.args = false
+			if lval.text.str, err = args(x); err != nil {
+				break
+			}
+			lval.text.end = x.loc()
+			return _ARGS
+
+		case r == '#':
+			if err = comment(x); err != nil {
+				break
+			}
+			return '\n'
+
+		case r == '\r':
+			if err = x.skipLF(); err != nil {
+				break
+			}
+			return '\n'
+
+		case unicode.IsLetter(r) || r == '_':
+			if lval.text.str, err = ident(x); err != nil {
+				break
+			}
+			lval.text.str = string([]rune{r}) + lval.text.str
+			lval.text.end = x.loc()
+			if x.params != nil && !x.params[lval.text.str] {
+				return _IDENT
+			}
+			if x.args, err = x.peek('('); err != nil {
+				break
+			}
+			return _IDENT
+
+		case r == '@':
+			if r, err = x.next(); err != nil {
+				break
+			}
+			if !unicode.IsLetter(r) && r != '_' {
+				err = errors.New("expected annotation name after @")
+				break
+			}
+			if lval.text.str, err = ident(x); err != nil {
+				break
+			}
+			lval.text.str = string([]rune{r}) + lval.text.str
+			lval.text.end = x.loc()
+			if x.args, err = x.peek('('); err != nil {
+				break
+			}
+			return _ANNOT
+
+		case r == '%':
+			if r, err = x.next(); err != nil {
+				break
+			}
+			if !unicode.IsLetter(r) && r != '_' {
+				err = errors.New("expected directive name after %")
+				break
+			}
+			if lval.text.str, err = ident(x); err != nil {
+				break
+			}
+			lval.text.str = string([]rune{r}) + lval.text.str
+			lval.text.end = x.loc()
+			if s := lval.text.str; s == "if" || s == "else" || s == "endif" {
+				if x.midLine {
+					err = errors.New("%" + s + " must be at the start of a line")
+					break
+				}
+				if err = x.conditional(lval.text); err != nil {
+					break
+				}
+				continue
+			}
+			if lval.text.str == "code" {
+				if lval.text, err = ruleCode(x); err != nil {
+					break
+				}
+				return _RULECODE
+			}
+			if lval.text.str == "delegate" {
+				if x.args, err = x.peek('('); err != nil {
+					break
+				}
+				return _DELEGATE
+			}
+			if lval.text.str == "native" {
+				if x.args, err = x.peek('('); err != nil {
+					break
+				}
+				return _NATIVE
+			}
+			if lval.text.str == "balanced" || lval.text.str == "until" {
+				if x.args, err = x.peek('('); err != nil {
+					break
+				}
+				return _BLOCK
+			}
+			return _DIRECTIVE
+
+		case unicode.IsDigit(r):
+			if lval.text.str, err = ident(x); err != nil {
+				break
+			}
+			lval.text.str = string([]rune{r}) + lval.text.str
+			lval.text.end = x.loc()
+			return _NUMBER
+
+		case r == '<':
+			b := x.loc()
+			if r, err = x.next(); err != nil {
+				break
+			}
+			lval.text.str = string([]rune{'<', r})
+			lval.text.end = x.loc()
+			if r != '-' {
+				x.back()
+				x.prevBegin = b
+				return int('<')
+			}
+			return _ARROW
+
+		case r == '-':
+			var arrow bool
+			if arrow, err = x.peek('>'); err != nil || !arrow {
+				if err == nil {
+					return int(r)
+				}
+				break
+			}
+			if _, err = x.next(); err != nil {
+				break
+			}
+			if lval.text, x.arrow, err = resultType(x); err != nil {
+				break
+			}
+			return _TYPE
+
+		case r == '!':
+			var want bool
+			if want, err = x.peek('>'); err != nil || !want {
+				if err == nil {
+					return int(r)
+				}
+				break
+			}
+			if _, err = x.next(); err != nil {
+				break
+			}
+			lval.text.str = "!>"
+			lval.text.end = x.loc()
+			return _WANT
+
+		case r == '{':
+			if lval.text.str, err = code(x); err != nil {
+				break
+			}
+			lval.text.end = x.loc()
+			return _CODE
+
+		case r == '[':
+			if err = x.back(); err != nil {
+				break
+			}
+			if lval.cclass, err = charClass(x); err != nil {
+				x.err = err
+				return _ERROR
+			}
+			return _CHARCLASS
+
+		case r == '\'' || r == '"':
+			var parts []Expr
+			if lval.text.str, parts, err = delimited(x, r); err != nil {
+				break
+			}
+			lval.text.end = x.loc()
+			if parts != nil {
+				if x.classLits == nil {
+					x.classLits = make(map[Loc]*SubExpr)
+				}
+				x.classLits[lval.text.begin] = &SubExpr{
+					Expr:   &Sequence{Exprs: parts},
+					Open:   lval.text.begin,
+					Close:  lval.text.end,
+					Source: string(r) + lval.text.str + string(r),
+				}
+			}
+			return _STRING
+
+		case unicode.IsSpace(r) && r != '\n':
+			continue
+
+		case r == eof && len(x.conds) > 0:
+			x.prevBegin = x.conds[len(x.conds)-1].begin
+			err = errors.New("unclosed %if")
+
+		default:
+			return int(r)
+		}
+		x.prevEnd = x.loc()
+		if e, ok := err.(Error); ok && e.cause != nil {
+			// Read errors are already located.
+			if x.err == nil {
+				x.err = e
+			}
+			return _ERROR
+		}
+		x.Error(err.Error())
+		return _ERROR
+	}
+}
+
+// A cond is an %if region.
+type cond struct {
+	// begin is the location of the %if.
+	begin Loc
+	// els is whether the region has an %else so far.
+	els bool
+}
+
+// conditional handles the %if, %else, or %endif directive d,
+// which must be alone on its line.
+// If the following lines are in a region that is not enabled,
+// they are skipped.
+func (x *lexer) conditional(d text) error {
+	line, err := x.restOfLine()
+	if err != nil {
+		return err
+	}
+	arg := strings.TrimSpace(line)
+	switch d.str {
+	case "if":
+		if arg == "" {
+			return errors.New("%if requires a tag")
+		}
+		tag := strings.TrimPrefix(arg, "!")
+		for _, r := range tag {
+			if !isIdentRune(r) {
+				return errors.New("bad %if tag " + arg)
+			}
+		}
+		x.conds = append(x.conds, cond{begin: d.begin})
+		if x.tags[tag] == strings.HasPrefix(arg, "!") {
+			return x.skip(true)
+		}
+		return nil
+	case "else":
+		if arg != "" {
+			return errors.New("%else takes no arguments")
+		}
+		if len(x.conds) == 0 {
+			return errors.New("%else without %if")
+		}
+		if c := &x.conds[len(x.conds)-1]; c.els {
+			return errors.New("duplicate %else")
+		} else {
+			c.els = true
+		}
+		return x.skip(false)
+	default:
+		if arg != "" {
+			return errors.New("%endif takes no arguments")
+		}
+		if len(x.conds) == 0 {
+			return errors.New("%endif without %if")
+		}
+		x.conds = x.conds[:len(x.conds)-1]
+		return nil
+	}
+}
+
+// skip skips lines through the %endif ending the current %if region,
+// or if else is true, through an %else of the region.
+// Regions nested in the skipped lines are skipped too,
+// each with at most one %else.
+func (x *lexer) skip(els bool) error {
+	// nested are whether each nested region has an %else so far.
+	var nested []bool
+	for !x.eof {
+		begin := x.loc()
+		line, err := x.restOfLine()
+		if err != nil {
+			return err
+		}
+		switch fields := strings.Fields(line); {
+		case len(fields) == 0:
+			continue
+		case fields[0] == "%if":
+			nested = append(nested, false)
+		case fields[0] == "%endif" && len(nested) > 0:
+			nested = nested[:len(nested)-1]
+		case fields[0] == "%endif":
+			x.conds = x.conds[:len(x.conds)-1]
+			return nil
+		case fields[0] == "%else":
+			c := &x.conds[len(x.conds)-1].els
+			if len(nested) > 0 {
+				c = &nested[len(nested)-1]
+			}
+			if *c {
+				x.prevBegin = begin
+				x.prevBegin.Col += utf8.RuneCountInString(line[:strings.Index(line, "%")])
+				return errors.New("duplicate %else")
+			}
+			*c = true
+			if len(nested) == 0 && els {
+				return nil
+			}
+		}
+	}
+	x.prevBegin = x.conds[len(x.conds)-1].begin
+	return errors.New("unclosed %if")
+}
+
+// restOfLine returns the remainder of the current line,
+// consuming its line terminator.
+func (x *lexer) restOfLine() (string, error) {
+	var rs []rune
+	for {
+		r, err := x.next()
+		switch {
+		case err != nil:
+			return "", err
+		case r == eof || r == '\n':
+			return string(rs), nil
+		case r == '\r':
+			return string(rs), x.skipLF()
+		}
+		rs = append(rs, r)
+	}
+}
+
+// delimited returns the string delimited by d.
+// If the string contains character class escapes,
+// the string is instead its source form, without delimiters,
+// and the parts are the Literals and CharClasses it matches in sequence.
+func delimited(x *lexer, d rune) (string, []Expr, error) {
+	var rs, src, lit []rune
+	var parts []Expr
+	begin := x.loc()
+	for {
+		at := x.loc()
+		r, esc, err := x.nextUnesc(d)
+		switch {
+		case err != nil:
+			return "", nil, err
+		case r == constRef:
+			for _, r := range x.constValue {
+				rs = append(rs, r)
+				src = append(src, []rune(sourceRune(r, d))...)
+				lit = append(lit, r)
+			}
+			continue
+		case r == classRef:
+			if len(lit) > 0 {
+				parts = append(parts, &Literal{Text: text{str: string(lit), begin: begin, end: at}})
+				lit = nil
+			}
+			c := x.classValue.CharClass
+			c.Open, c.Close = at, x.loc()
+			c.Source = string(d) + x.classValue.src + string(d)
+			parts = append(parts, &c)
+			src = append(src, []rune(x.classValue.src)...)
+			begin = x.loc()
+			continue
+		case r == eof:
+			return "", nil, errors.New("unclosed " + string([]rune{d}))
+		case r == d && !esc:
+			if parts == nil {
+				return string(rs), nil, nil
+			}
+			if len(lit) > 0 {
+				parts = append(parts, &Literal{Text: text{str: string(lit), begin: begin, end: at}})
+			}
+			return string(src), parts, nil
+		}
+		rs = append(rs, r)
+		src = append(src, []rune(sourceRune(r, d))...)
+		lit = append(lit, r)
+	}
+}
+
+// sourceRune returns the source form of a rune
+// in a string delimited by d.
+func sourceRune(r, d rune) string {
+	switch {
+	case r == d || r == '\\':
+		return string([]rune{'\\', r})
+	case !unicode.IsGraphic(r):
+		return strings.Trim(strconv.QuoteRune(r), "'")
+	}
+	return string(r)
+}
+
+func ident(x *lexer) (string, error) {
+	var rs []rune
+	for {
+		r, err := x.next()
+		if err != nil {
+			return "", err
+		}
+		if !isIdentRune(r) {
+			return string(rs), x.back()
+		}
+		rs = append(rs, r)
+	}
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_'
+}
+
+// resultType scans the Go type following a -> in a rule header,
+// returning the type, without surrounding space,
+// and the <- that ends it.
+// A <- nested in parentheses or brackets does not end the type,
+// so a channel type can be written as (<-chan T).
+func resultType(x *lexer) (text, *text, error) {
+	var t text
+	var rs []rune
+	var n int
+	for {
+		loc := x.loc()
+		r, err := x.next()
+		if err != nil {
+			return text{}, nil, err
+		}
+		switch {
+		case r == eof || r == '\n' || r == '\r':
+			return text{}, nil, errors.New("expected <- after rule result type")
+		case unicode.IsSpace(r):
+			if len(rs) > 0 {
+				rs = append(rs, r)
+			}
+			continue
+		case r == '(' || r == '[' || r == '{':
+			n++
+		case r == ')' || r == ']' || r == '}':
+			n--
+		case r == '<' && n == 0:
+			arrow, err := x.peek('-')
+			if err != nil {
+				return text{}, nil, err
+			}
+			if !arrow {
+				break
+			}
+			if _, err := x.next(); err != nil {
+				return text{}, nil, err
+			}
+			t.str = strings.TrimRightFunc(string(rs), unicode.IsSpace)
+			if t.str == "" {
+				return text{}, nil, errors.New("expected rule result type after ->")
+			}
+			return t, &text{str: "<-", begin: loc, end: x.loc()}, nil
+		}
+		if len(rs) == 0 {
+			t.begin = loc
+		}
+		rs = append(rs, r)
+		t.end = x.loc()
+	}
+}
+
+func code(x *lexer) (string, error) {
+	var rs []rune
+	var n int
+	for {
+		r, err := x.next()
+		if err != nil {
+			return "", err
+		}
+		if r == eof {
+			return "", errors.New("unclosed {")
+		}
+		if r == '{' {
+			n++
+		}
+		if r == '}' {
+			if n == 0 {
+				break
+			}
+			n--
+		}
+		rs = append(rs, r)
+	}
+	return string(rs), nil
+}
+
+// ruleCode lexes the { } code block of a %code directive,
+// beginning just after the directive name,
+// and returns the text between the braces,
+// beginning at the open {.
+func ruleCode(x *lexer) (text, error) {
+	for {
+		r, err := x.next()
+		if err != nil {
+			return text{}, err
+		}
+		if r == '{' {
+			break
+		}
+		if r == '\n' || r == '\r' || r == eof || !unicode.IsSpace(r) {
+			return text{}, errors.New("expected { after %code")
+		}
+	}
+	t := text{begin: x.loc()}
+	t.begin.Col-- // the open {
+	str, err := code(x)
+	if err != nil {
+		return text{}, err
+	}
+	t.str = str
+	t.end = x.loc()
+	return t, nil
+}
+
+// args lexes a parenthesized Go argument or parameter list,
+// beginning just after the open (,
+// and returns the text between the parentheses.
+func args(x *lexer) (string, error) {
+	var rs []rune
+	var n int
+	for {
+		r, err := x.next()
+		if err != nil {
+			return "", err
+		}
+		if r == eof {
+			return "", errors.New("unclosed (")
+		}
+		if r == '(' {
+			n++
+		}
+		if r == ')' {
+			if n == 0 {
+				break
+			}
+			n--
+		}
+		rs = append(rs, r)
+		if r == '"' || r == '\'' || r == '`' {
+			// Skip Go string and rune literals,
+			// which may contain parentheses.
+			if rs, err = goLiteral(x, r, rs); err != nil {
+				return "", err
+			}
+		}
+	}
+	return string(rs), nil
+}
+
+// goLiteral appends to rs the runes of a Go string or rune literal
+// up to and including the closing delimiter d.
+func goLiteral(x *lexer, d rune, rs []rune) ([]rune, error) {
+	for {
+		r, err := x.next()
+		if err != nil {
+			return nil, err
+		}
+		if r == eof || (r == '\n' && d != '`') {
+			return nil, errors.New("unclosed " + string([]rune{d}))
+		}
+		rs = append(rs, r)
+		switch {
+		case r == d:
+			return rs, nil
+		case r == '\\' && d != '`':
+			if r, err = x.next(); err != nil {
+				return nil, err
+			}
+			if r == eof {
+				return nil, errors.New("unclosed " + string([]rune{d}))
+			}
+			rs = append(rs, r)
+		}
+	}
+}
+
+func comment(x *lexer) error {
+	for {
+		r, err := x.next()
+		if err != nil {
+			return err
+		}
+		if r == '\r' {
+			return x.skipLF()
+		}
+		if r == '\n' || r == eof {
+			return nil
+		}
+	}
+}
+
+func charClass(x *lexer) (*CharClass, error) {
+	c := &CharClass{Open: x.loc()}
+	if r, err := x.next(); err != nil {
+		return nil, err
+	} else if r != '[' {
+		panic("impossible, no [")
+	}
+
+	var prev rune
+	var hasPrev, span bool
+
+	// last is the Loc just before last read rune.
+	var last Loc
+
+	// spanLoc is the location of the current span.
+	// (We use type text to borrow that it implements Located.
+	// However we ignore the str field.)
+	var spanLoc text
+loop:
+	for {
+		last = x.loc()
+		if !span && !hasPrev {
+			spanLoc.begin = x.loc()
+		}
+		r, esc, err := x.nextUnesc(']')
+		switch {
+		case err != nil:
+			return nil, err
+
+		case r == eof:
+			c.Close = x.loc()
+			return nil, Err(c, "unclosed [")
+
+		case r == ']' && !esc:
+			c.Close = x.loc()
+			break loop
+
+		case r == constRef:
+			// Each rune of the constant is a member of the class;
+			// none begin or end a span.
+			if span {
+				spanLoc.end = x.loc()
+				return nil, Err(spanLoc, "bad span")
+			}
+			for _, r := range x.constValue {
+				if hasPrev {
+					c.Spans = append(c.Spans, [2]rune{prev, prev})
+				}
+				prev, hasPrev = r, true
+			}
+			spanLoc.begin = x.loc()
+
+		case r == classRef:
+			// The runes of the escape are members of the class;
+			// they neither begin nor end a span.
+			if span {
+				spanLoc.end = x.loc()
+				return nil, Err(spanLoc, "bad span")
+			}
+			if x.classValue.Neg {
+				return nil, Err(text{begin: last, end: x.loc()}, "negated class escape %s in a character class", x.classValue.src)
+			}
+			if hasPrev {
+				c.Spans = append(c.Spans, [2]rune{prev, prev})
+				hasPrev = false
+			}
+			c.Spans = append(c.Spans, x.classValue.Spans...)
+			spanLoc.begin = x.loc()
+
+		case span:
+			spanLoc.end = x.loc()
+			if !hasPrev {
+				return nil, Err(spanLoc, "bad span")
+			}
+			if prev >= r {
+				return nil, Err(spanLoc, "bad span")
+			}
+			c.Spans = append(c.Spans, [2]rune{prev, r})
+			hasPrev, span = false, false
+			spanLoc.begin = spanLoc.end
+
+		case r == '-' && !esc:
+			span = true
+
+		default:
+			if r == '^' && !esc && !c.Neg && len(c.Spans) == 0 && !hasPrev {
+				c.Neg = true
+				continue
+			}
+			if hasPrev {
+				c.Spans = append(c.Spans, [2]rune{prev, prev})
+				spanLoc.begin = last // in case current rune starts a span.
+			}
+			prev, hasPrev = r, true
+		}
+	}
+	if span {
+		spanLoc.end = last // just before closing ]
+		return nil, Err(spanLoc, "bad span")
+	}
+	if hasPrev {
+		c.Spans = append(c.Spans, [2]rune{prev, prev})
+	}
+	if len(c.Spans) == 0 {
+		return nil, Err(c, "bad char class: empty")
+	}
+	return c, nil
+}
+
+var errUnknownEsc = errors.New("unknown escape sequence")
+
+// Like next, but unescapes an escapes a rune according to Go's unescaping rules.
+// The second return value is whether the rune was escaped.
+func (x *lexer) nextUnesc(delim rune) (rune, bool, error) {
+	switch r, err := x.next(); {
+	case err != nil:
+		return 0, false, err
+	case r == delim:
+		return r, false, nil
+	case r == '\\':
+		r, err = x.next()
+		if err != nil {
+			return 0, true, err
+		}
+		switch r {
+		case eof:
+			return eof, true, nil
+		case 'a', 'b', 'f', 'n', 'r', 't', 'v', '\\':
+			switch r {
+			case 'a':
+				r = '\a'
+			case 'b':
+				r = '\b'
+			case 'f':
+				r = '\f'
+			case 'n':
+				r = '\n'
+			case 'r':
+				r = '\r'
+			case 't':
+				r = '\t'
+			case 'v':
+				r = '\v'
+			case '\\':
+				r = '\\'
+			}
+			return r, true, nil
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			v, _ := oct(r)
+			for i := 1; i < 3; i++ {
+				r, err := x.next()
+				if err != nil {
+					return 0, false, err
+				}
+				d, ok := oct(r)
+				if !ok {
+					return 0, false, errUnknownEsc
+				}
+				v = (v << 3) | d
+			}
+			if v > 255 {
+				return 0, false, errors.New("octal escape >255")
+			}
+			return v, true, nil
+		case '{':
+			name, err := ident(x)
+			if err != nil {
+				return 0, false, err
+			}
+			if name == "" {
+				return 0, false, errors.New("expected constant name after \\{")
+			}
+			if r, err = x.next(); err != nil {
+				return 0, false, err
+			}
+			if r != '}' {
+				return 0, false, errors.New("expected } after constant name")
+			}
+			v, ok := x.consts[name]
+			if !ok {
+				return 0, false, errors.New("undefined constant " + name)
+			}
+			x.constValue = v
+			return constRef, true, nil
+		case 'd', 'D', 'w', 'W', 's', 'S', 'p', 'P':
+			c, err := classEscape(x, r)
+			if err != nil {
+				return 0, false, err
+			}
+			x.classValue = c
+			return classRef, true, nil
+		case 'x', 'u', 'U':
+			var n int
+			switch r {
+			case 'x':
+				n = 2
+			case 'u':
+				n = 4
+			case 'U':
+				n = 8
+			}
+			var v int32
+			for i := 0; i < n; i++ {
+				r, err := x.next()
+				if err != nil {
+					return 0, false, err
+				}
+				d, ok := hex(r)
+				if !ok {
+					return 0, false, errUnknownEsc
+				}
+				v = (v << 4) | d
+			}
+			// TODO: surrogate halves are also illegal — whatever that is.
+			if v > 0x10FFFF {
+				return 0, false, errors.New("hex escape >0x10FFFF")
+			}
+			return v, true, nil
+		default:
+			if r == delim {
+				return r, true, nil
+			}
+			// For character classes, allow \- as - and \^ as ^.
+			if delim == ']' && (r == '-' || r == '^') {
+				return r, true, nil
+			}
+			return 0, false, errUnknownEsc
+		}
+	default:
+		return r, false, nil
+	}
+}
+
+// A classEsc is a character class escape.
+type classEsc struct {
+	CharClass
+	// src is the source form of the escape.
+	src string
+}
+
+// classEscape returns the character class escape
+// following a \ and the escape rune r.
+// The escapes \d, \w, and \s are the ASCII digits, word runes, and spaces;
+// \p{Name} and \pN are the runes of a Unicode category or script.
+// The upper-case forms are negated.
+func classEscape(x *lexer, r rune) (*classEsc, error) {
+	c := &classEsc{src: string([]rune{'\\', r})}
+	c.Neg = unicode.IsUpper(r)
+	switch unicode.ToLower(r) {
+	case 'd':
+		c.Spans = [][2]rune{{'0', '9'}}
+	case 'w':
+		c.Spans = [][2]rune{{'0', '9'}, {'A', 'Z'}, {'_', '_'}, {'a', 'z'}}
+	case 's':
+		c.Spans = [][2]rune{{'\t', '\n'}, {'\f', '\r'}, {' ', ' '}}
+	case 'p':
+		r, err := x.next()
+		if err != nil {
+			return nil, err
+		}
+		name := string(r)
+		if r == '{' {
+			if name, err = ident(x); err != nil {
+				return nil, err
+			}
+			if r, err = x.next(); err != nil {
+				return nil, err
+			}
+			if r != '}' {
+				return nil, errors.New("expected } after Unicode class name")
+			}
+			c.src += "{" + name + "}"
+		} else {
+			c.src += name
+		}
+		tab, ok := unicode.Categories[name]
+		if !ok {
+			tab, ok = unicode.Scripts[name]
+		}
+		if !ok {
+			return nil, errors.New("unknown Unicode class " + name)
+		}
+		c.Spans = tableSpans(tab)
+	}
+	return c, nil
+}
+
+// tableSpans returns the rune spans of a unicode.RangeTable.
+func tableSpans(tab *unicode.RangeTable) [][2]rune {
+	var spans [][2]rune
+	add := func(lo, hi, stride rune) {
+		if stride == 1 {
+			spans = append(spans, [2]rune{lo, hi})
+			return
+		}
+		for r := lo; r <= hi; r += stride {
+			spans = append(spans, [2]rune{r, r})
+		}
+	}
+	for _, r := range tab.R16 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	for _, r := range tab.R32 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	return spans
+}
+
+func oct(r rune) (int32, bool) {
+	if '0' <= r && r <= '7' {
+		return int32(r) - '0', true
+	}
+	return 0, false
+}
+
+func hex(r rune) (int32, bool) {
+	if '0' <= r && r <= '9' {
+		return int32(r) - '0', true
+	}
+	if 'a' <= r && r <= 'f' {
+		return int32(r) - 'a' + 10, true
+	}
+	if 'A' <= r && r <= 'F' {
+		return int32(r) - 'A' + 10, true
+	}
+	return 0, false
+}
@@ -0,0 +1,1871 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/pretty"
+)
+
+// A ParserTest is a Peggy input-file parser test
+// with a given input and expected string formats.
+type ParserTest struct {
+	Name  string
+	Input string
+	// FullString is the expected fully parenthesized string.
+	FullString string
+	// String is the expected regular String string.
+	// This is the same as Input, but without
+	// comments and unnecessary whitespace,
+	// except for a single space, " ",
+	// separating sub-exprsessions of a sequence,
+	// and on either side of <-.
+	String string
+	// Prelude is the expected file prelude text.
+	Prelude string
+	// Error is a regexp string that matches an expected parse error.
+	Error string
+}
+
+// ParseTests is a set of tests matching
+// FullString and String outputs with expected outputs for successful parses,
+// and expected parse errors for failed parses.
+// If Input contains a ☹ rune, the io.RuneScanner returns an error on that rune.
+var ParseTests = []ParserTest{
+	{
+		Name:       "empty",
+		Input:      "",
+		FullString: "",
+		String:     "",
+	},
+	{
+		Name:       "only whitespace",
+		Input:      "  \n\n\t    ",
+		FullString: "",
+		String:     "",
+	},
+	{
+		Name:       "simple rule",
+		Input:      "A <- B",
+		FullString: "A <- (B)",
+		String:     "A <- B",
+	},
+	{
+		Name:       "named rule",
+		Input:      `A "name" <- B`,
+		FullString: `A "name" <- (B)`,
+		String:     `A "name" <- B`,
+	},
+	{
+		Name:       "named rule, single quotes",
+		Input:      `A 'name' <- B`,
+		FullString: `A "name" <- (B)`,
+		String:     `A "name" <- B`,
+	},
+	{
+		Name:       "named rule, empty name",
+		Input:      `A "" <- B`,
+		FullString: `A "" <- (B)`,
+		String:     `A "" <- B`,
+	},
+	{
+		Name:       "named rule, escapes",
+		Input:      `A "\t\nabc" <- B`,
+		FullString: `A "\t\nabc" <- (B)`,
+		String:     `A "\t\nabc" <- B`,
+	},
+	{
+		Name: "prelude and simple rule",
+		Input: `{
+package main
+
+import "fmt"
+
+func main() { fmt.Println("Hello, World") }
+}
+A <- B`,
+		FullString: "A <- (B)",
+		String:     "A <- B",
+		Prelude: `
+package main
+
+import "fmt"
+
+func main() { fmt.Println("Hello, World") }
+`,
+	},
+	{
+		Name:       "multiple simple rules",
+		Input:      "A <- B\nC <- D",
+		FullString: "A <- (B)\nC <- (D)",
+		String:     "A <- B\nC <- D",
+	},
+	{
+		Name:       "multiple simple rules",
+		Input:      "A <- B\nC <- D",
+		FullString: "A <- (B)\nC <- (D)",
+		String:     "A <- B\nC <- D",
+	},
+	{
+		Name:       "whitespace",
+		Input:      "\tA <- B\n   \n\n    C <- D\t  ",
+		FullString: "A <- (B)\nC <- (D)",
+		String:     "A <- B\nC <- D",
+	},
+	{
+		Name:       "comments",
+		Input:      "# comment\nA <- B # comment\n# comment",
+		FullString: "A <- (B)",
+		String:     "A <- B",
+	},
+
+	// Operands.
+	{
+		Name:       "& pred code",
+		Input:      "A <- &{pred}",
+		FullString: "A <- (&{pred})",
+		String:     "A <- &{…}",
+	},
+	{
+		Name:       "! pred code",
+		Input:      "A <- !{pred}",
+		FullString: "A <- (!{pred})",
+		String:     "A <- !{…}",
+	},
+	{
+		Name:       "any",
+		Input:      "A <- .",
+		FullString: "A <- (.)",
+		String:     "A <- .",
+	},
+	{
+		Name:       "identifier",
+		Input:      "A <- BCD",
+		FullString: "A <- (BCD)",
+		String:     "A <- BCD",
+	},
+	{
+		Name:       "non-ASCII identifier",
+		Input:      "Â <- _αβξ",
+		FullString: "Â <- (_αβξ)",
+		String:     "Â <- _αβξ",
+	},
+	{
+		Name:       "double-quote string",
+		Input:      `A <- "BCD☺"`,
+		FullString: `A <- ("BCD☺")`,
+		String:     `A <- "BCD☺"`,
+	},
+	{
+		Name:       "single-quote string",
+		Input:      `A <- 'BCD☺'`,
+		FullString: `A <- ("BCD☺")`,
+		String:     `A <- "BCD☺"`,
+	},
+	{
+		Name:       "character class",
+		Input:      `A <- [abc\nxyzαβξ1-9A-Z\-]`,
+		FullString: `A <- ([abc\nxyzαβξ1-9A-Z\-])`,
+		String:     `A <- [abc\nxyzαβξ1-9A-Z\-]`,
+	},
+	{
+		Name:       "^ character class",
+		Input:      `A <- [^^abc\nxyzαβξ]`,
+		FullString: `A <- ([^\^abc\nxyzαβξ])`,
+		String:     `A <- [^\^abc\nxyzαβξ]`,
+	},
+	{
+		Name:       "character class, delimiters",
+		Input:      `A <- [[\]]`,
+		FullString: `A <- ([[\]])`,
+		String:     `A <- [[\]]`,
+	},
+	{
+		// ^ should only negate the class if it's at the beginning
+		Name:       "character class, non-first^",
+		Input:      `A <- [abc^]`,
+		FullString: `A <- ([abc\^])`,
+		String:     `A <- [abc\^]`,
+	},
+	{
+		Name:       "character class, escaping",
+		Input:      `A <- [\a] [\b] [\f] [\n] [\r] [\t] [\v] [\\] [\-] [\]] [\101] [\x41] [\u0041] [\U00000041] [\aa\b] [a\ab] [\^]`,
+		FullString: `A <- ((((((((((((((((([\a]) ([\b])) ([\f])) ([\n])) ([\r])) ([\t])) ([\v])) ([\\])) ([\-])) ([\]])) ([A])) ([A])) ([A])) ([A])) ([\aa\b])) ([a\ab])) ([\^]))`,
+		String:     `A <- [\a] [\b] [\f] [\n] [\r] [\t] [\v] [\\] [\-] [\]] [A] [A] [A] [A] [\aa\b] [a\ab] [\^]`,
+	},
+
+	// Associativity.
+	{
+		Name:       "choice associativity",
+		Input:      "A <- B/C/D",
+		FullString: "A <- (((B)/(C))/(D))",
+		String:     "A <- B/C/D",
+	},
+	{
+		Name:       "sequence associativity",
+		Input:      "A <- B C D",
+		FullString: "A <- (((B) (C)) (D))",
+		String:     "A <- B C D",
+	},
+
+	// Precedence.
+	{
+		Name:       "various precedences",
+		Input:      "A <- x:B*+ C?/(!D y:&E)* {return 0}/F !{p}",
+		FullString: "A <- ((((x:(((B)*)+)) ((C)?))/((((!(D)) (y:(&(E))))*) {return 0}))/((F) (!{p})))",
+		String:     "A <- x:B*+ C?/(!D y:&E)* {…}/F !{…}",
+	},
+	{
+		Name:       "action < choice",
+		Input:      "A <- B { return 0 }/C { return 0 }",
+		FullString: "A <- (((B) { return 0 })/((C) { return 0 }))",
+		String:     "A <- B {…}/C {…}",
+	},
+	{
+		Name:       "sequence < action",
+		Input:      "A <- B C { return 0 }",
+		FullString: "A <- (((B) (C)) { return 0 })",
+		String:     "A <- B C {…}",
+	},
+	{
+		Name:       "label < sequence",
+		Input:      "A <- s:A t:B",
+		FullString: "A <- ((s:(A)) (t:(B)))",
+		String:     "A <- s:A t:B",
+	},
+	{
+		Name:       "pred < label",
+		Input:      "A <- s:!A t:&B",
+		FullString: "A <- ((s:(!(A))) (t:(&(B))))",
+		String:     "A <- s:!A t:&B",
+	},
+	{
+		Name:       "longest choice",
+		Input:      "A <- B | C D\n\t| (E / F)",
+		FullString: "A <- (((B)|((C) (D)))|((E)/(F)))",
+		String:     "A <- B|C D|(E/F)",
+	},
+	{
+		Name:  "mixed / and |",
+		Input: "A <- B / C | D",
+		Error: "^test.file:1.12,1.12: mixed / and | choice without parentheses",
+	},
+	{
+		Name:  "mixed | and /",
+		Input: "A <- B | C / D",
+		Error: "^test.file:1.12,1.12: mixed / and | choice without parentheses",
+	},
+	{
+		Name:       "diff",
+		Input:      "A <- B - C",
+		FullString: "A <- ((B) - (C))",
+		String:     "A <- B - C",
+	},
+	{
+		Name:       "sequence < diff < action < choice",
+		Input:      "A <- B C - D E - F { return 1 } / G\n\t- H",
+		FullString: "A <- ((((((B) (C)) - ((D) (E))) - (F)) { return 1 })/((G) - (H)))",
+		String:     "A <- B C - D E - F {…}/G - H",
+	},
+	{
+		Name:  "diff without operand",
+		Input: "A <- B -",
+		Error: "^test.file:1.9: syntax error",
+	},
+	{
+		Name:       "delegate",
+		Input:      "A <- B %delegate(sql.Parse, \"```sql\", \"```\") C",
+		FullString: "A <- (((B) (%delegate(sql.Parse, \"```sql\", \"```\"))) (C))",
+		String:     "A <- B %delegate(sql.Parse, \"```sql\", \"```\") C",
+	},
+	{
+		Name:  "delegate missing marker",
+		Input: "A <- %delegate(f, \"<\")",
+		Error: "^test.file:1.16: %delegate wants a function, open marker, and close marker",
+	},
+	{
+		Name:  "delegate non-string marker",
+		Input: "A <- %delegate(f, \"<\", x)",
+		Error: "^test.file:1.16: %delegate markers must be string literals",
+	},
+	{
+		Name:  "delegate empty marker",
+		Input: "A <- %delegate(f, \"\", \">\")",
+		Error: "^test.file:1.16: %delegate markers must not be empty",
+	},
+	{
+		Name:  "delegate bad function",
+		Input: "A <- %delegate(f+, \"<\", \">\")",
+		Error: "^test.file:1.18: expected operand",
+	},
+	{
+		Name:  "delegate without arguments",
+		Input: "A <- %delegate B",
+		Error: "^test.file:1.16,1.17: syntax error",
+	},
+	{
+		Name:       "native",
+		Input:      "A <- B %native(json.ScanString) C",
+		FullString: "A <- (((B) (%native(json.ScanString))) (C))",
+		String:     "A <- B %native(json.ScanString) C",
+	},
+	{
+		Name:  "native without function",
+		Input: "A <- %native()",
+		Error: "^test.file:1.13,1.15: %native wants a function",
+	},
+	{
+		Name:  "native with two functions",
+		Input: "A <- %native(f, g)",
+		Error: "^test.file:1.13,1.19: %native wants a function",
+	},
+	{
+		Name:  "native bad function",
+		Input: "A <- %native(f+)",
+		Error: "^test.file:1.16: expected operand",
+	},
+	{
+		Name:       "balanced",
+		Input:      `A <- "x" %balanced('(', ")", "\\") B`,
+		FullString: `A <- ((("x") (%balanced('(', ")", "\\"))) (B))`,
+		String:     `A <- "x" %balanced('(', ")", "\\") B`,
+	},
+	{
+		Name:       "until",
+		Input:      `A <- "/*" %until("*/")`,
+		FullString: `A <- (("/*") (%until("*/")))`,
+		String:     `A <- "/*" %until("*/")`,
+	},
+	{
+		Name:  "balanced without close",
+		Input: `A <- %balanced("(")`,
+		Error: "^test.file:1.16: %balanced wants open and close delimiters and an optional escape",
+	},
+	{
+		Name:  "until with too many args",
+		Input: `A <- %until("a", "b", "c")`,
+		Error: "^test.file:1.13: %until wants a close delimiter and an optional escape",
+	},
+	{
+		Name:  "until non-literal",
+		Input: `A <- %until(x)`,
+		Error: "^test.file:1.13: %until delimiters must be string literals",
+	},
+	{
+		Name:  "balanced empty delimiter",
+		Input: `A <- %balanced("", ")")`,
+		Error: "^test.file:1.16: %balanced delimiters must not be empty",
+	},
+	{
+		Name:  "until without args",
+		Input: `A <- %until B`,
+		Error: "^test.file:1.13,1.14: syntax error",
+	},
+	{
+		Name:       "capture < label",
+		Input:      "A <- s:$A t:$B+",
+		FullString: "A <- ((s:($(A))) (t:($((B)+))))",
+		String:     "A <- s:$A t:$B+",
+	},
+	{
+		Name:       "rep < pred",
+		Input:      "A <- !A* &B+ !C?",
+		FullString: "A <- (((!((A)*)) (&((B)+))) (!((C)?)))",
+		String:     "A <- !A* &B+ !C?",
+	},
+	{
+		Name: "operand < rep",
+		Input: `A <- (a/b c)*
+B <- &{pred}*
+C <- !{pred}*
+D <- .*
+E <- Z*
+F <- "cde"*
+G <- [fgh]*`,
+		FullString: `A <- (((a)/((b) (c)))*)
+B <- ((&{pred})*)
+C <- ((!{pred})*)
+D <- ((.)*)
+E <- ((Z)*)
+F <- (("cde")*)
+G <- (([fgh])*)`,
+		String: `A <- (a/b c)*
+B <- &{…}*
+C <- !{…}*
+D <- .*
+E <- Z*
+F <- "cde"*
+G <- [fgh]*`,
+	},
+
+	{
+		Name:       "!memo action",
+		Input:      "A <- B { return 5 }!memo",
+		FullString: "A <- ((B) { return 5 }!memo)",
+		String:     "A <- B {…}!memo",
+	},
+	{
+		Name:  "unknown action annotation",
+		Input: "A <- B { return 5 }!xyz",
+		Error: "^test.file:1.21,1.24: unknown action annotation: !xyz",
+	},
+
+	// Rule parameters
+	{
+		Name:       "rule with parameters",
+		Input:      "A @param(x int, y string) <- A(x+1, \"(\")",
+		FullString: "A @param(x int, y string) <- (A(x+1, \"(\"))",
+		String:     "A @param(x int, y string) <- A(x+1, \"(\")",
+	},
+	{
+		Name:       "named rule with parameters",
+		Input:      "A \"a\" @param(x int) <- B C(f(x), g(y))\nC @param(x, y int) <- D",
+		FullString: "A \"a\" @param(x int) <- ((B) (C(f(x), g(y))))\nC @param(x, y int) <- (D)",
+		String:     "A \"a\" @param(x int) <- B C(f(x), g(y))\nC @param(x, y int) <- D",
+	},
+	{
+		Name:       "call before rule with parameters",
+		Input:      "A <- B(1)\nC <- D\nB @param(x int) <- E",
+		FullString: "A <- (B(1))\nC <- (D)\nB @param(x int) <- (E)",
+		String:     "A <- B(1)\nC <- D\nB @param(x int) <- E",
+	},
+	{
+		Name:       "space before ( is a subexpression",
+		Input:      "A <- B (C)",
+		FullString: "A <- ((B) (C))",
+		String:     "A <- B (C)",
+	},
+	{
+		Name:       "( after rule without parameters is a subexpression",
+		Input:      "A <- B(C / D)\nB <- E",
+		FullString: "A <- ((B) ((C)/(D)))\nB <- (E)",
+		String:     "A <- B (C/D)\nB <- E",
+	},
+	{
+		Name:       "( after undefined rule is a subexpression",
+		Input:      "A <- B(C)",
+		FullString: "A <- ((B) (C))",
+		String:     "A <- B (C)",
+	},
+	{
+		Name:       "( after template parameter is a subexpression",
+		Input:      "A<X> <- X(C)\nB <- A<D>",
+		FullString: "A<X> <- ((X) (C))\nB <- (A<D>)",
+		String:     "A<X> <- X (C)\nB <- A<D>",
+	},
+	{
+		Name:  "@param without parameters",
+		Input: "A @param <- B",
+		Error: "^test.file:1.3,1.9: @param requires a parameter list",
+	},
+	{
+		Name:  "@param redefined",
+		Input: "A @param(x int) @param(y int) <- B",
+		Error: "^test.file:1.17,1.23: @param redefined",
+	},
+	{
+		Name:  "unnamed parameter",
+		Input: "A @param(int) <- B",
+		Error: "^test.file:1.10: parameters must be named",
+	},
+	{
+		Name:  "bad parameters",
+		Input: "A @param(x int,,) <- B",
+		Error: `^test.file:1.16: expected '\)', found ','`,
+	},
+	{
+		Name:  "bad arguments",
+		Input: "A <- B(x+)\nB @param(x int) <- C",
+		Error: `^test.file:1.10: expected operand, found '\)'`,
+	},
+	{
+		Name:  "unclosed arguments",
+		Input: "B @param(x int) <- C\nA <- B(x",
+		Error: `^test.file:2.7,2.9: unclosed \(`,
+	},
+	{
+		Name:       "metadata annotations",
+		Input:      "A @xyz @category(\"operator\") @doc(`a\\b`) <- B",
+		FullString: `A @category("operator") @doc("a\\b") @xyz <- (B)`,
+		String:     `A @category("operator") @doc("a\\b") @xyz <- B`,
+	},
+	{
+		Name:  "metadata annotation with non-string argument",
+		Input: "A @category(operator) <- B",
+		Error: "^test.file:1.12,1.22: @category requires a string literal",
+	},
+	{
+		Name:  "metadata annotation redefined",
+		Input: `A @category("a") @category("b") <- B`,
+		Error: "^test.file:1.18,1.27: @category redefined",
+	},
+	{
+		Name:       "recovery annotations",
+		Input:      "A @recoverpast(\";\", `\n`) @recoveruntil(\"}\") <- B",
+		FullString: `A @recoveruntil("}") @recoverpast(";", "\n") <- (B)`,
+		String:     `A @recoveruntil("}") @recoverpast(";", "\n") <- B`,
+	},
+	{
+		Name:  "recovery annotation with non-string argument",
+		Input: "A @recoverpast(semi) <- B",
+		Error: "^test.file:1.15,1.21: @recoverpast requires string literals",
+	},
+	{
+		Name:  "recovery annotation with empty string argument",
+		Input: `A @recoveruntil("}", "") <- B`,
+		Error: `^test.file:1.16,1.25: @recoveruntil requires non-empty string literals`,
+	},
+	{
+		Name:  "recovery annotation redefined",
+		Input: `A @recoverpast(";") @recoverpast("}") <- B`,
+		Error: "^test.file:1.21,1.33: @recoverpast redefined",
+	},
+
+	// Cuts
+	{
+		Name:       "cut",
+		Input:      `A <- "if" ^ Cond / Expr`,
+		FullString: `A <- (((("if") (^)) (Cond))/(Expr))`,
+		String:     `A <- "if" ^ Cond/Expr`,
+	},
+
+	// Line terminators
+	{
+		Name:       "CRLF line terminators",
+		Input:      "A <- B\r\nC <- D # comment\r\n\r\nE <- F",
+		FullString: "A <- (B)\nC <- (D)\nE <- (F)",
+		String:     "A <- B\nC <- D\nE <- F",
+	},
+	{
+		Name:       "CR line terminators",
+		Input:      "A <- B\rC <- D # comment\r\rE <- F",
+		FullString: "A <- (B)\nC <- (D)\nE <- (F)",
+		String:     "A <- B\nC <- D\nE <- F",
+	},
+	{
+		Name:  "CRLF error location",
+		Input: "A <- B\r\n\r\n<- C",
+		Error: "^test.file:3.1,3.3: syntax error",
+	},
+	{
+		Name:  "CR error location",
+		Input: "A <- B\r\r<- C",
+		Error: "^test.file:3.1,3.3: syntax error",
+	},
+	{
+		Name:       "Unicode line separators are whitespace",
+		Input:      "A <- B\u2028C\u2029\u0085D",
+		FullString: "A <- (((B) (C)) (D))",
+		String:     "A <- B C D",
+	},
+	{
+		Name:  "Unicode line separator error location",
+		Input: "A <- B\u2028<- C",
+		Error: "^test.file:1.8,1.10: syntax error",
+	},
+
+	// Maximum depth
+	{
+		Name:       "%maxdepth directive",
+		Input:      "%maxdepth 100\nA <- B",
+		FullString: "A <- (B)",
+		String:     "A <- B",
+	},
+	{
+		Name: "%maxdepth directive after prelude",
+		Input: `{
+package main
+}
+%maxdepth 100
+
+A <- B`,
+		Prelude: `
+package main
+`,
+		FullString: "A <- (B)",
+		String:     "A <- B",
+	},
+	{
+		Name:       "@maxdepth annotation",
+		Input:      "A @param(x int) @maxdepth( 10 ) <- B",
+		FullString: "A @param(x int) @maxdepth(10) <- (B)",
+		String:     "A @param(x int) @maxdepth(10) <- B",
+	},
+	{
+		Name:  "%maxdepth without depth",
+		Input: "%maxdepth\nA <- B",
+		Error: "^test.file:1.1,1.10: %maxdepth requires a depth",
+	},
+	{
+		Name:  "%maxdepth non-integer depth",
+		Input: "%maxdepth abc\nA <- B",
+		Error: "^test.file:1.11,1.14: %maxdepth depth must be a positive integer, got abc",
+	},
+	{
+		Name:  "%maxdepth zero depth",
+		Input: "%maxdepth 0\nA <- B",
+		Error: "^test.file:1.11,1.12: %maxdepth depth must be a positive integer, got 0",
+	},
+	{
+		Name:  "%maxdepth redefined",
+		Input: "%maxdepth 1\n%maxdepth 2\nA <- B",
+		Error: "^test.file:2.1,2.10: %maxdepth redefined",
+	},
+	{
+		Name:  "@maxdepth without depth",
+		Input: "A @maxdepth <- B",
+		Error: "^test.file:1.3,1.12: @maxdepth requires a depth",
+	},
+	{
+		Name:  "@maxdepth non-integer depth",
+		Input: "A @maxdepth(x) <- B",
+		Error: `^test.file:1.12,1.15: @maxdepth depth must be a positive integer, got x`,
+	},
+	{
+		Name:  "@maxdepth redefined",
+		Input: "A @maxdepth(1) @maxdepth(2) <- B",
+		Error: "^test.file:1.16,1.25: @maxdepth redefined",
+	},
+	{
+		Name:       "%warnslow directive",
+		Input:      "%warnslow 10ms\nA @warnslow( 1.5s ) <- B",
+		FullString: "A @warnslow(1.5s) <- (B)",
+		String:     "A @warnslow(1.5s) <- B",
+	},
+	{
+		Name:  "%warnslow without duration",
+		Input: "%warnslow\nA <- B",
+		Error: "^test.file:1.1,1.10: %warnslow requires a duration",
+	},
+	{
+		Name:  "%warnslow bad duration",
+		Input: "%warnslow 10\nA <- B",
+		Error: "^test.file:1.11,1.13: %warnslow duration must be positive, such as 10ms, got 10",
+	},
+	{
+		Name:  "%warnslow redefined",
+		Input: "%warnslow 1ms\n%warnslow 2ms\nA <- B",
+		Error: "^test.file:2.1,2.10: %warnslow redefined",
+	},
+	{
+		Name:  "@warnslow without duration",
+		Input: "A @warnslow <- B",
+		Error: "^test.file:1.3,1.12: @warnslow requires a duration",
+	},
+	{
+		Name:  "@warnslow negative duration",
+		Input: "A @warnslow(-1ms) <- B",
+		Error: `^test.file:1.12,1.18: @warnslow duration must be positive, such as 10ms, got -1ms`,
+	},
+	{
+		Name:  "@warnslow redefined",
+		Input: "A @warnslow(1ms) @warnslow(2ms) <- B",
+		Error: "^test.file:1.18,1.27: @warnslow redefined",
+	},
+	{
+		Name:       "@token annotation",
+		Input:      "A @token <- B",
+		FullString: "A @token <- (B)",
+		String:     "A @token <- B",
+	},
+	{
+		Name:  "@token with arguments",
+		Input: "A @token(x) <- B",
+		Error: "^test.file:1.9,1.12: @token takes no arguments",
+	},
+	{
+		Name:  "@token redefined",
+		Input: "A @token @token <- B",
+		Error: "^test.file:1.10,1.16: @token redefined",
+	},
+	{
+		Name:       "@nocase annotation",
+		Input:      `A @nocase <- "select" [a-c]`,
+		FullString: `A @nocase <- (("select") ([a-c]))`,
+		String:     `A @nocase <- "select" [a-c]`,
+	},
+	{
+		Name:  "@nocase with arguments",
+		Input: "A @nocase(x) <- B",
+		Error: "^test.file:1.10,1.13: @nocase takes no arguments",
+	},
+	{
+		Name:  "@nocase redefined",
+		Input: "A @nocase @nocase <- B",
+		Error: "^test.file:1.11,1.18: @nocase redefined",
+	},
+	{
+		Name:  "empty expression",
+		Input: "A <- \"x\" / ( )\nB <- (\n) \"y\" ()",
+		FullString: `A <- (("x")/(()))
+B <- (((()) ("y")) (()))`,
+		String: `A <- "x"/()
+B <- () "y" ()`,
+	},
+	{
+		Name:  "empty expression unclosed",
+		Input: "A <- (",
+		Error: "^test.file:1.7: syntax error",
+	},
+	{
+		Name:       "described expressions",
+		Input:      "A <- \"a\" n:[0-9]+ !> \"digits\" (\"x\" \"y\") !>\n\t\"x\\ty\"",
+		FullString: `A <- ((("a") ((n:(([0-9])+)) !> "digits")) ((("x") ("y")) !> "x\ty"))`,
+		String:     `A <- "a" n:[0-9]+ !> "digits" ("x" "y") !> "x\ty"`,
+	},
+	{
+		Name:  "described expression missing description",
+		Input: "A <- \"a\" !>",
+		Error: "^test.file:1.12: syntax error",
+	},
+	{
+		Name:  "! then > is not !>",
+		Input: "A <- \"a\" ! > \"b\"",
+		Error: "^test.file:1.12,1.13: syntax error",
+	},
+	{
+		Name:       "%const in literals",
+		Input:      "%const Q = \"\\\"\"\nA <- \"\\{Q}x\\{Q}\" '\\{Q}'",
+		FullString: `A <- (("\"x\"") ("\""))`,
+		String:     `A <- "\"x\"" "\""`,
+	},
+	{
+		Name:       "%const in character classes",
+		Input:      "%const Ops = \"+-*/\"\nA <- [\\{Ops}0-9] [^a\\{Ops}]",
+		FullString: `A <- (([+\-*/0-9]) ([^a+\-*/]))`,
+		String:     `A <- [+\-*/0-9] [^a+\-*/]`,
+	},
+	{
+		Name:       "%const using a %const",
+		Input:      "%const A = \"a\"\n%const AB = \"\\{A}b\"\nA \"\\{AB}\" <- \"\\{AB}\"",
+		FullString: `A "ab" <- ("ab")`,
+		String:     `A "ab" <- "ab"`,
+	},
+	{
+		Name:  "undefined constant",
+		Input: "A <- \"\\{X}\"",
+		Error: "^test.file:1.6,1.11: undefined constant X",
+	},
+	{
+		Name:  "constant reference without name",
+		Input: "A <- \"\\{}\"",
+		Error: "^test.file:1.6,1.9: expected constant name after \\\\{",
+	},
+	{
+		Name:  "unclosed constant reference",
+		Input: "A <- \"\\{X\"",
+		Error: "^test.file:1.6,1.11: expected } after constant name",
+	},
+	{
+		Name:  "span to a constant",
+		Input: "%const X = \"b\"\nA <- [a-\\{X}]",
+		Error: "^test.file:2.7,2.13: bad span",
+	},
+	{
+		Name:       "class escapes in literals",
+		Input:      `A <- "\d\d:\d\d" '\w+\s' "\D" "\p{Greek}x\PL"`,
+		FullString: `A <- (((("\d\d:\d\d") ('\w+\s')) ("\D")) ("\p{Greek}x\PL"))`,
+		String:     `A <- "\d\d:\d\d" '\w+\s' "\D" "\p{Greek}x\PL"`,
+	},
+	{
+		Name:       "class escapes with escaped runes in literals",
+		Input:      `A <- "\"\\\n\d" '\'\d"'`,
+		FullString: `A <- (("\"\\\n\d") ('\'\d"'))`,
+		String:     `A <- "\"\\\n\d" '\'\d"'`,
+	},
+	{
+		Name:       "class escapes in character classes",
+		Input:      `A <- [\d_] [^\s\d]`,
+		FullString: `A <- (([0-9_]) ([^\t-\n\f-\r 0-9]))`,
+		String:     `A <- [0-9_] [^\t-\n\f-\r 0-9]`,
+	},
+	{
+		Name:  "negated class escape in a character class",
+		Input: `A <- [a\D]`,
+		Error: "^test.file:1.8,1.10: negated class escape \\\\D in a character class",
+	},
+	{
+		Name:  "span to a class escape",
+		Input: `A <- [a-\d]`,
+		Error: "^test.file:1.7,1.11: bad span",
+	},
+	{
+		Name:  "unknown Unicode class",
+		Input: `A <- "\p{Klingon}"`,
+		Error: "^test.file:1.6,1.18: unknown Unicode class Klingon",
+	},
+	{
+		Name:  "class escape in an error name",
+		Input: `A "\d" <- "a"`,
+		Error: "^test.file:1.3,1.7: character class escape outside of a literal",
+	},
+	{
+		Name:  "%const redefined",
+		Input: "%const X = \"a\"\n%const X = \"b\"\nA <- B",
+		Error: "^test.file:2.8,2.9: %const X redefined",
+	},
+	{
+		Name:  "%const without =",
+		Input: "%const X \"a\"\nA <- B",
+		Error: `^test.file:1.1,1.7: %const requires a name = "value"`,
+	},
+	{
+		Name:  "= value on another directive",
+		Input: "%maxdepth x = \"a\"\nA <- B",
+		Error: "^test.file:1.15,1.18: %maxdepth takes no = value",
+	},
+	{
+		Name:       "result type",
+		Input:      "A -> int <- B",
+		FullString: "A -> int <- (B)",
+		String:     "A -> int <- B",
+	},
+	{
+		Name:       "result type canonical form",
+		Input:      `A "a" @token ->  map[ string ]* ast.Expr<-B`,
+		FullString: `A "a" @token -> map[string]*ast.Expr <- (B)`,
+		String:     `A "a" @token -> map[string]*ast.Expr <- B`,
+	},
+	{
+		Name:       "result type with nested <-",
+		Input:      "A -> (<-chan int) <- B",
+		FullString: "A -> (<-chan int) <- (B)",
+		String:     "A -> (<-chan int) <- B",
+	},
+	{
+		Name:  "missing result type",
+		Input: "A -> <- B",
+		Error: "^test.file:1.3,1.8: expected rule result type after ->",
+	},
+	{
+		Name:  "result type without <-",
+		Input: "A -> int\nB <- C",
+		Error: "^test.file:1.3,2.1: expected <- after rule result type",
+	},
+	{
+		Name:  "unclosed result type",
+		Input: "A -> map[int <- B",
+		Error: "^test.file:1.3,1.18: expected <- after rule result type",
+	},
+	{
+		Name:  "bad result type",
+		Input: "A -> int int <- B",
+		Error: "^test.file:1.10: ",
+	},
+	{
+		Name:       "%invalidbytes directive",
+		Input:      "%invalidbytes\nA <- .",
+		FullString: "A <- (.)",
+		String:     "A <- .",
+	},
+	{
+		Name:  "%invalidbytes with arguments",
+		Input: "%invalidbytes yes\nA <- .",
+		Error: "^test.file:1.15,1.18: %invalidbytes takes no arguments",
+	},
+	{
+		Name:       "%newline directive",
+		Input:      "%newline crlf\nA <- B",
+		FullString: "A <- (B)",
+		String:     "A <- B",
+	},
+	{
+		Name:  "%newline bad terminator",
+		Input: "%newline cr\nA <- B",
+		Error: "^test.file:1.10,1.12: %newline requires lf, crlf, js, or unicode, got cr",
+	},
+	{
+		Name:  "%newline redefined",
+		Input: "%newline lf\n%newline crlf\nA <- B",
+		Error: "^test.file:2.1,2.9: %newline redefined",
+	},
+	{
+		Name:  "unknown directive",
+		Input: "%xyz\nA <- B",
+		Error: "^test.file:1.1,1.5: unknown directive %xyz",
+	},
+	{
+		Name:  "directive after rules",
+		Input: "A <- B\n%maxdepth 1",
+		Error: "^test.file:2.1,2.10: syntax error",
+	},
+	{
+		Name: "%code blocks",
+		Input: `%code { func f() {} }
+A <- B
+%code {
+	var x int
+}
+
+%code { var y int }
+C <- D`,
+		FullString: "A <- (B)\nC <- (D)",
+		String:     "A <- B\nC <- D",
+	},
+	{
+		Name:  "%code without {",
+		Input: "%code x\nA <- B",
+		Error: "^test.file:1.1,1.8: expected { after %code",
+	},
+	{
+		Name:  "%code with bad Go",
+		Input: "A <- B\n%code {\n\tvar = 1\n}\nC <- D",
+		Error: "^test.file:3.6: expected",
+	},
+	{
+		Name:  "%code without rule",
+		Input: "A <- B\n%code { var x int }",
+		Error: "syntax error",
+	},
+
+	// Templates
+	{
+		Name:       "1-ary template rule",
+		Input:      `A<x> <- x`,
+		FullString: `A<x> <- (x)`,
+		String:     `A<x> <- x`,
+	},
+	{
+		Name:       "3-ary template rule",
+		Input:      `A<x, y, z> <- x y z`,
+		FullString: `A<x, y, z> <- (((x) (y)) (z))`,
+		String:     `A<x, y, z> <- x y z`,
+	},
+	{
+		Name:       "1-ary template invocation",
+		Input:      `A <- B<x> C`,
+		FullString: `A <- ((B<x>) (C))`,
+		String:     `A <- B<x> C`,
+	},
+	{
+		Name:       "3-ary template invocation",
+		Input:      `A <- B<x, y, z> C`,
+		FullString: `A <- ((B<x, y, z>) (C))`,
+		String:     `A <- B<x, y, z> C`,
+	},
+	{
+		Name:       "literal template parameter",
+		Input:      `Quoted<'"', x> <- '"' (!'"' x)* '"'`,
+		FullString: `Quoted<"\"", x> <- ((("\"") (((!("\"")) (x))*)) ("\""))`,
+		String:     `Quoted<"\"", x> <- "\"" (!"\"" x)* "\""`,
+	},
+	{
+		Name:       "literal template argument",
+		Input:      `A <- Quoted<"'", C> B<'\n'>`,
+		FullString: `A <- ((Quoted<"'", C>) (B<"\n">))`,
+		String:     `A <- Quoted<"'", C> B<"\n">`,
+	},
+
+	// Rune escaping
+	{
+		Name:       `escape \a`,
+		Input:      `A <- "\a"`,
+		FullString: `A <- ("\a")`,
+		String:     `A <- "\a"`,
+	},
+	{
+		Name:       `escape \b`,
+		Input:      `A <- "\b"`,
+		FullString: `A <- ("\b")`,
+		String:     `A <- "\b"`,
+	},
+	{
+		Name:       `escape \f`,
+		Input:      `A <- "\f"`,
+		FullString: `A <- ("\f")`,
+		String:     `A <- "\f"`,
+	},
+	{
+		Name:       `escape \n`,
+		Input:      `A <- "\n"`,
+		FullString: `A <- ("\n")`,
+		String:     `A <- "\n"`,
+	},
+	{
+		Name:       `escape \r`,
+		Input:      `A <- "\r"`,
+		FullString: `A <- ("\r")`,
+		String:     `A <- "\r"`,
+	},
+	{
+		Name:       `escape \t`,
+		Input:      `A <- "\t"`,
+		FullString: `A <- ("\t")`,
+		String:     `A <- "\t"`,
+	},
+	{
+		Name:       `escape \v`,
+		Input:      `A <- "\v"`,
+		FullString: `A <- ("\v")`,
+		String:     `A <- "\v"`,
+	},
+	{
+		Name:       `escape \\`,
+		Input:      `A <- "\\"`,
+		FullString: `A <- ("\\")`,
+		String:     `A <- "\\"`,
+	},
+	{
+		Name:       `escape \"`,
+		Input:      `A <- "\""`,
+		FullString: `A <- ("\"")`,
+		String:     `A <- "\""`,
+	},
+	{
+		Name:       `escape \'`,
+		Input:      `A <- '\''`,
+		FullString: `A <- ("'")`,
+		String:     `A <- "'"`,
+	},
+	{
+		Name:       `escape \000`,
+		Input:      `A <- "\000"`,
+		FullString: `A <- ("\x00")`,
+		String:     `A <- "\x00"`,
+	},
+	{
+		Name:       `escape \101 (A)`,
+		Input:      `A <- "\101"`,
+		FullString: `A <- ("A")`,
+		String:     `A <- "A"`,
+	},
+	{
+		Name:       `escape \101BCD`,
+		Input:      `A <- "\101BCD"`,
+		FullString: `A <- ("ABCD")`,
+		String:     `A <- "ABCD"`,
+	},
+	{
+		Name:       `escape \377 (255)`,
+		Input:      `A <- "\377"`,
+		FullString: `A <- ("ÿ")`, // \xFF
+		String:     `A <- "ÿ"`,
+	},
+	{
+		Name:  `escape \400 (256)`,
+		Input: `A <- "\400"`,
+		Error: "^test.file:1.6,1.11:.*>255",
+	},
+	{
+		Name:  `escape \400 (256)`,
+		Input: `A <- "xyz\400"`,
+		// TODO: report the correct error location.
+		Error: "^test.file:1.6,1.14:.*>255",
+	},
+	{
+		Name:  `escape \4`,
+		Input: `A <- "\4"`,
+		Error: "^test.file:1.6,1.10: unknown escape sequence",
+	},
+	{
+		Name:  `escape \40`,
+		Input: `A <- "\40"`,
+		Error: "^test.file:1.6,1.11: unknown escape sequence",
+	},
+	{
+		Name:       `escape \x00`,
+		Input:      `A <- "\x00"`,
+		FullString: `A <- ("\x00")`,
+		String:     `A <- "\x00"`,
+	},
+	{
+		Name:       `escape \x41 (A)`,
+		Input:      `A <- "\x41"`,
+		FullString: `A <- ("A")`,
+		String:     `A <- "A"`,
+	},
+	{
+		Name:       `escape \x41BCD`,
+		Input:      `A <- "\x41BCD"`,
+		FullString: `A <- ("ABCD")`,
+		String:     `A <- "ABCD"`,
+	},
+	{
+		Name:       `escape \xFF`,
+		Input:      `A <- "\xFF"`,
+		FullString: `A <- ("ÿ")`, // \xFF
+		String:     `A <- "ÿ"`,
+	},
+	{
+		Name:  `escape \xF`,
+		Input: `A <- "\xF"`,
+		Error: "^test.file:1.6,1.11: unknown escape sequence",
+	},
+	{
+		Name:       `escape \u0000`,
+		Input:      `A <- "\u0000"`,
+		FullString: `A <- ("\x00")`,
+		String:     `A <- "\x00"`,
+	},
+	{
+		Name:       `escape \u0041 (A)`,
+		Input:      `A <- "\u0041"`,
+		FullString: `A <- ("A")`,
+		String:     `A <- "A"`,
+	},
+	{
+		Name:       `escape \u0041BCD`,
+		Input:      `A <- "\u0041BCD"`,
+		FullString: `A <- ("ABCD")`,
+		String:     `A <- "ABCD"`,
+	},
+	{
+		Name:       `escape \u263A (☺)`,
+		Input:      `A <- "\u263A"`,
+		FullString: `A <- ("☺")`,
+		String:     `A <- "☺"`,
+	},
+	{
+		Name:       `escape \u263a (☺)`,
+		Input:      `A <- "\u263a"`,
+		FullString: `A <- ("☺")`,
+		String:     `A <- "☺"`,
+	},
+	{
+		Name:  `escape \uF`,
+		Input: `A <- "\xF"`,
+		Error: "^test.file:1.6,1.11: unknown escape sequence",
+	},
+	{
+		Name:  `escape \uFF`,
+		Input: `A <- "\uFF"`,
+		Error: "^test.file:1.6,1.12: unknown escape sequence",
+	},
+	{
+		Name:  `escape \uFFF`,
+		Input: `A <- "\uFFF"`,
+		Error: "^test.file:1.6,1.13: unknown escape sequence",
+	},
+	{
+		Name:       `escape \U00000000`,
+		Input:      `A <- "\U00000000"`,
+		FullString: `A <- ("\x00")`,
+		String:     `A <- "\x00"`,
+	},
+	{
+		Name:       `escape \U00000041 (A)`,
+		Input:      `A <- "\U00000041"`,
+		FullString: `A <- ("A")`,
+		String:     `A <- "A"`,
+	},
+	{
+		Name:       `escape \U00000041BCD`,
+		Input:      `A <- "\U00000041BCD"`,
+		FullString: `A <- ("ABCD")`,
+		String:     `A <- "ABCD"`,
+	},
+	{
+		Name:       `escape \U0000263A (☺)`,
+		Input:      `A <- "\U0000263A"`,
+		FullString: `A <- ("☺")`,
+		String:     `A <- "☺"`,
+	},
+	{
+		Name:       `escape \U0000263a (☺)`,
+		Input:      `A <- "\U0000263a"`,
+		FullString: `A <- ("☺")`,
+		String:     `A <- "☺"`,
+	},
+	{
+		Name:       `escape \U0010FFFF`,
+		Input:      `A <- "\U0010FFFF"`,
+		FullString: `A <- ("\U0010ffff")`,
+		String:     `A <- "\U0010ffff"`,
+	},
+	{
+		Name:  `escape \U00110000`,
+		Input: `A <- "\U00110000"`,
+		Error: "^test.file:1.6,1.17:.*>0x10FFFF",
+	},
+	{
+		Name:  `escape \UF`,
+		Input: `A <- "\UF"`,
+		Error: "^test.file:1.6,1.11: unknown escape sequence",
+	},
+	{
+		Name:  `escape \UFF`,
+		Input: `A <- "\UFF"`,
+		Error: "^test.file:1.6,1.12: unknown escape sequence",
+	},
+	{
+		Name:  `escape \UFFF`,
+		Input: `A <- "\UFFF"`,
+		Error: "^test.file:1.6,1.13: unknown escape sequence",
+	},
+	{
+		Name:  `escape \UFFFF`,
+		Input: `A <- "\UFFFF"`,
+		Error: "^test.file:1.6,1.14: unknown escape sequence",
+	},
+	{
+		Name:  `escape \UFFFFF`,
+		Input: `A <- "\UFFFFF"`,
+		Error: "^test.file:1.6,1.15: unknown escape sequence",
+	},
+	{
+		Name:  `escape \UFFFFFF`,
+		Input: `A <- "\UFFFFFF"`,
+		Error: "^test.file:1.6,1.16: unknown escape sequence",
+	},
+	{
+		Name:  `escape \UFFFFFFF`,
+		Input: `A <- "\UFFFFFFF"`,
+		Error: "^test.file:1.6,1.17: unknown escape sequence",
+	},
+	{
+		Name:       `string with multiple escapes`,
+		Input:      `A <- "x\a\b\f\n\r\t\v\\\"\000\x00\u0000\U00000000☺"`,
+		FullString: `A <- ("x\a\b\f\n\r\t\v\\\"\x00\x00\x00\x00☺")`,
+		String:     `A <- "x\a\b\f\n\r\t\v\\\"\x00\x00\x00\x00☺"`,
+	},
+	{
+		Name:  `unknown escape`,
+		Input: `A <- "\z"`,
+		Error: "^test.file:1.6,1.9: unknown escape sequence",
+	},
+	{
+		Name:  `escape eof`,
+		Input: `A <- "\`,
+		Error: `^test.file:1.6,1.8: unclosed "`,
+	},
+
+	// Whitespace.
+	// Whitespace, including newlines, can appear between any tokens.
+	// A newline only ends a rule if the next line
+	// begins with a rule header or a directive.
+	{
+		Name: `after <-`,
+		Input: `A <-
+		"a"
+
+		B <- #comment
+		"b"
+
+		C "c" <-
+		"c"
+
+		D "d" <- #comment
+		"d"`,
+		FullString: `A <- ("a")
+B <- ("b")
+C "c" <- ("c")
+D "d" <- ("d")`,
+		String: `A <- "a"
+B <- "b"
+C "c" <- "c"
+D "d" <- "d"`,
+	},
+	{
+		Name: `after /`,
+		Input: `A <- B /
+		C / # comment
+		D`,
+		FullString: `A <- (((B)/(C))/(D))`,
+		String:     `A <- B/C/D`,
+	},
+	{
+		Name: `after : label`,
+		Input: `A <- l:
+		B m: #comment
+		C`,
+		FullString: `A <- ((l:(B)) (m:(C)))`,
+		String:     `A <- l:B m:C`,
+	},
+	{
+		Name: `after & predicate`,
+		Input: `A <- &
+		B & #comment
+		C`,
+		FullString: `A <- ((&(B)) (&(C)))`,
+		String:     `A <- &B &C`,
+	},
+	{
+		Name: `after ! predicate`,
+		Input: `A <- !
+		B ! #comment
+		C`,
+		FullString: `A <- ((!(B)) (!(C)))`,
+		String:     `A <- !B !C`,
+	},
+	{
+		Name: `after (`,
+		Input: `A <- (
+		B ( #comment
+		C))`,
+		FullString: `A <- ((B) (C))`,
+		String:     `A <- (B (C))`,
+	},
+	{
+		Name: `before )`,
+		Input: `A <- (B (C
+		) #comment
+		)`,
+		FullString: `A <- ((B) (C))`,
+		String:     `A <- (B (C))`,
+	},
+	{
+		Name: `sequence across lines`,
+		Input: `A <- B
+		C # comment
+
+		D
+		E <- F`,
+		FullString: `A <- (((B) (C)) (D))
+E <- (F)`,
+		String: `A <- B C D
+E <- F`,
+	},
+	{
+		Name: `before /`,
+		Input: `A <- B
+		/ C
+		# comment
+		/ D`,
+		FullString: `A <- (((B)/(C))/(D))`,
+		String:     `A <- B/C/D`,
+	},
+	{
+		Name: `before operators`,
+		Input: `A <- l
+		:B
+		*
+		C
+		+ D
+		?`,
+		FullString: `A <- (((l:((B)*)) ((C)+)) ((D)?))`,
+		String:     `A <- l:B* C+ D?`,
+	},
+	{
+		Name: `before action`,
+		Input: `A <- B
+		{ return 1 }
+		C <- D`,
+		FullString: `A <- ((B) { return 1 })
+C <- (D)`,
+		String: `A <- B {…}
+C <- D`,
+	},
+	{
+		Name: `before template invocation`,
+		Input: `A <- B
+		C<D>
+		C<x> <- x
+		D <- "d"`,
+		FullString: `A <- ((B) (C<D>))
+C<x> <- (x)
+D <- ("d")`,
+		String: `A <- B C<D>
+C<x> <- x
+D <- "d"`,
+	},
+	{
+		Name: `before rule header`,
+		Input: `A <- B
+		C "c" @token -> string <- "c"`,
+		FullString: `A <- (B)
+C "c" @token -> string <- ("c")`,
+		String: `A <- B
+C "c" @token -> string <- "c"`,
+	},
+	{
+		Name: `before directive`,
+		Input: `%maxdepth 10
+
+		%const X = "x"
+		A <- "\{X}"`,
+		FullString: `A <- ("x")`,
+		String:     `A <- "x"`,
+	},
+	{
+		Name: `after & code`,
+		Input: `A <- &
+		{code} & #comment
+		{CODE}`,
+		FullString: `A <- ((&{code}) (&{CODE}))`,
+		String:     `A <- &{…} &{…}`,
+	},
+	{
+		Name: `after ! code`,
+		Input: `A <- !
+		{code} ! #comment
+		{CODE}`,
+		FullString: `A <- ((!{code}) (!{CODE}))`,
+		String:     `A <- !{…} !{…}`,
+	},
+
+	// Systax errors.
+	{
+		Name:  "bad rule name",
+		Input: "\n\t\t&",
+		Error: "^test.file:2.3,2.4:",
+	},
+	{
+		Name:  "missing <-",
+		Input: "\nA B",
+		Error: "^test.file:2.3,2.4:",
+	},
+	{
+		Name:  "bad <-",
+		Input: "\nA <~ C",
+		Error: "^test.file:2.4,2.5:",
+	},
+	{
+		Name:  "missing expr",
+		Input: "\nA <-",
+		Error: "^test.file:2.5:",
+	},
+	{
+		Name:  "unexpected rune",
+		Input: "\nA <- C ☺",
+		Error: "^test.file:2.8,2.9:",
+	},
+	{
+		Name:  "unclosed (",
+		Input: "\nA <- (B",
+		Error: "^test.file:2.8:",
+	},
+	{
+		Name:  "unclosed '",
+		Input: "\nA <- 'B",
+		Error: "^test.file:2.6,2.8: unclosed '",
+	},
+	{
+		Name:  `unclosed "`,
+		Input: "\nA <- \"B",
+		Error: "^test.file:2.6,2.8: unclosed \"",
+	},
+	{
+		Name:  `unclosed {`,
+		Input: "\nA <- B { code",
+		Error: "^test.file:2.8,2.14: unclosed {",
+	},
+	{
+		Name:  `unclosed spans lines`,
+		Input: "\nA <- \"B\n\nC",
+		Error: "^test.file:2.6,4.2: unclosed \"",
+	},
+	{
+		Name:  "unclosed [",
+		Input: "\nA <- [B",
+		Error: "^test.file:2.6,2.8: unclosed [[]",
+	},
+	{
+		Name:  "character class empty",
+		Input: "\nA <- []",
+		Error: "^test.file:2.6,2.8: bad char class: empty",
+	},
+	{
+		Name:  "character class starts with span",
+		Input: "\nA <- [-9]",
+		Error: "^test.file:2.7,2.9: bad span",
+	},
+	{
+		Name:  "character class no span start",
+		Input: "\nA <- [1-3-9]",
+		Error: "^test.file:2.10,2.12: bad span",
+	},
+	{
+		Name:  "character class ends with span",
+		Input: "\nA <- [0-]",
+		Error: "^test.file:2.7,2.9: bad span",
+	},
+	{
+		Name:  "character class inverted span",
+		Input: "\nA <- [9-0]",
+		Error: "^test.file:2.7,2.10: bad span",
+	},
+	{
+		Name:  "character class span after span",
+		Input: "\nA <- [^0-9abcA-Zz-a]",
+		Error: "^test.file:2.17,2.20: bad span",
+	},
+	{
+		Name:  "character class bad span after rune",
+		Input: "\nA <- [^0-9abcZ-A]",
+		Error: "^test.file:2.14,2.17: bad span",
+	},
+
+	// Go syntax errors.
+	{
+		Name:  `bad prelude`,
+		Input: "{ not package line }\nA <- B",
+		Error: "^test.file:1.3",
+	},
+	{
+		Name: `bad multi-line prelude`,
+		Input: `{
+package main
+
+import "fmt"
+
+// Missing open paren.
+func main() { fmt.Println"Hello, World") }
+}
+A <- B`,
+		Error: "^test.file:7.26",
+	},
+	{
+		Name: `bad bool expression`,
+		// = instead of ==.
+		Input: "\nA <- &{ x = z}",
+		Error: "^test.file:2.11",
+	},
+	{
+		Name: `bad multi-line bool expression`,
+		// Missing the closed paren on p(.
+		Input: "\nA <- &{ x == \n p(y, z, h}",
+		Error: "^test.file:3.11",
+	},
+	{
+		Name:  `bad action`,
+		Input: "A <- B { if ( }",
+		Error: "^test.file:1.15",
+	},
+	{
+		Name:  `bad multi-line action`,
+		Input: "\nA <- B {\n	if ( }",
+		Error: "^test.file:3.7",
+	},
+	{
+		Name:  `bad action: invalid nested func def`,
+		Input: "\nA <- B { func f() int { return 1 } }",
+		Error: "^test.file:2.15",
+	},
+	{
+		Name:       `action with nested return`,
+		Input:      "A <- B { if true { return 0 } else { return 1 } }",
+		FullString: "A <- ((B) { if true { return 0 } else { return 1 } })",
+		String:     "A <- B {…}",
+	},
+	{
+		Name:  `missing return`,
+		Input: "A <- B { }",
+		Error: "^test.file:1.9: no return statement",
+	},
+	{
+		Name:  `multi-value return`,
+		Input: "A <- B { return 1, 2, 3 }",
+		Error: "^test.file:1.9: must return exactly one value",
+	},
+	{
+		Name:  `non-conversion multi-ary function return`,
+		Input: "A <- B { return f(a, b, c) }",
+		Error: "^test.file:1.9: cannot infer type",
+	},
+	{
+		Name:  `non-conversion nil-ary function return`,
+		Input: "A <- B { return f() }",
+		Error: "^test.file:1.9: cannot infer type",
+	},
+	{
+		Name:  `non-conversion function return`,
+		Input: "A <- B { return f(a, b, c) }",
+		Error: "^test.file:1.9: cannot infer type",
+	},
+	{
+		Name:       `uninferable action with declared result type`,
+		Input:      `A -> int <- "(" e:A ")" { return e } / B { return f() }`,
+		FullString: `A -> int <- ((((("(") (e:(A))) (")")) { return e })/((B) { return f() }))`,
+		String:     `A -> int <- "(" e:A ")" {…}/B {…}`,
+	},
+	{
+		Name:  `uninferable nested action with declared result type`,
+		Input: "A -> int <- e:(B { return e }) { return 1 }",
+		Error: "^test.file:1.19: cannot infer type",
+	},
+
+	// I/O errors.
+	{
+		Name:  "only I/O error",
+		Input: "☹",
+		Error: `^test.file:1.1: read error: test I/O error$`,
+	},
+	{
+		Name:  "comment I/O error",
+		Input: "#☹",
+		Error: `^test.file:1.1,1.2: read error after "#": test I/O error$`,
+	},
+	{
+		Name:  "ident I/O error",
+		Input: "A☹",
+		Error: `^test.file:1.1,1.2: read error after "A": test I/O error$`,
+	},
+	{
+		Name:  "arrow I/O error",
+		Input: "A <☹",
+		Error: `^test.file:1.3,1.4: read error after "<": test I/O error$`,
+	},
+	{
+		Name:  "code I/O error",
+		Input: "A <- B { ☹",
+		Error: `^test.file:1.8,1.10: read error after "{ ": test I/O error$`,
+	},
+	{
+		Name:  "char class I/O error",
+		Input: "A <- [☹",
+		Error: `^test.file:1.6,1.7: read error after "\[": test I/O error$`,
+	},
+	{
+		Name:  "double-quoted string I/O error",
+		Input: "A <- \"☹",
+		Error: `^test.file:1.6,1.7: read error after "\\"": test I/O error$`,
+	},
+	{
+		Name:  "multi-line string I/O error",
+		Input: "A <- B\nB <- \"ab☹",
+		Error: `^test.file:2.6,2.9: read error after "\\"ab": test I/O error$`,
+	},
+	{
+		Name:  "long token I/O error",
+		Input: "A <- B { return 12345678901234567890 ☹",
+		Error: `^test.file:1.8,1.38: read error after \.\.\."2345678901234567890 ": test I/O error$`,
+	},
+	{
+		Name:  "single-quoted string I/O error",
+		Input: "A <- '☹",
+		Error: `^test.file:1.6,1.7: read error after "'": test I/O error$`,
+	},
+}
+
+func TestParse(t *testing.T) {
+	for _, test := range ParseTests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			t.Parallel()
+			in := testRuneScanner{strings.NewReader(test.Input)}
+			g, err := Parse(in, "test.file")
+
+			if test.Error != "" {
+				if err == nil {
+					t.Log(pretty.String(g.Rules))
+					t.Errorf("Parse(%q) ok, but expected error matching %q",
+						test.Input, test.Error)
+					return
+				}
+				re := regexp.MustCompile(test.Error)
+				if !re.MatchString(err.Error()) {
+					t.Errorf("Parse(%q) err=%q, but expected to match %q",
+						test.Input, err.Error(), test.Error)
+					return
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Parse(%q) failed: %s", test.Input, err)
+				return
+			}
+			var pre string
+			if g.Prelude != nil {
+				pre = g.Prelude.String()
+			}
+			if pre != test.Prelude {
+				t.Errorf("Parse(%q).Prelude=\n%s\nwant:\n%s",
+					test.Input, pre, test.Prelude)
+				return
+			}
+			if s := FullString(g.Rules); s != test.FullString {
+				t.Errorf("Parse(%q)\nfull string:\n%q\nwant:\n%q",
+					test.Input, s, test.FullString)
+				return
+			}
+			if s := String(g.Rules); s != test.String {
+				t.Errorf("Parse(%q)\nstring:\n%q\nwant:\n%q",
+					test.Input, s, test.String)
+				return
+			}
+		})
+	}
+}
+
+func TestPrettyString(t *testing.T) {
+	const in = `A <- x:B (y:"c" { return string(y) })* !(z:"d") { return string(x) }
+B <- "\d\d" / (b:"b" "c")? - c:"d"`
+	g, err := Parse(strings.NewReader(in), "test.file")
+	if err != nil {
+		t.Fatalf("Parse(%q)=_, %v, want _,nil", in, err)
+	}
+	for i, want := range []string{
+		`A <- B ("c")* !("d")`,
+		`B <- "\d\d"/("b" "c")? - "d"`,
+	} {
+		if got := g.Rules[i].PrettyString(); got != want {
+			t.Errorf("Rules[%d].PrettyString()=%q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	const grammar = `A <- B
+%if x
+B <- "x"
+%else
+B <- "not x"
+%endif
+%if !y
+C <- "not y"
+	%if x
+	D <- "x and not y"
+	%endif
+%endif
+`
+	tests := []struct {
+		tags   []string
+		input  string
+		string string
+		err    string
+	}{
+		{
+			tags:   nil,
+			input:  grammar,
+			string: `A <- B` + "\n" + `B <- "not x"` + "\n" + `C <- "not y"`,
+		},
+		{
+			tags:   []string{"x"},
+			input:  grammar,
+			string: `A <- B` + "\n" + `B <- "x"` + "\n" + `C <- "not y"` + "\n" + `D <- "x and not y"`,
+		},
+		{
+			tags:   []string{"x", "y"},
+			input:  grammar,
+			string: `A <- B` + "\n" + `B <- "x"`,
+		},
+		{
+			tags:   []string{"y"},
+			input:  grammar,
+			string: `A <- B` + "\n" + `B <- "not x"`,
+		},
+		{
+			// Disabled regions are not parsed.
+			input:  "A <- B\n%if x\nB <- ((\n%endif\n",
+			string: `A <- B`,
+		},
+		{
+			tags:  []string{"x"},
+			input: "A <- B\n%if x\nB <- ((\n%endif\n",
+			err:   "^test.file:5.1: syntax error",
+		},
+		{
+			input: "A <- B\n%if\n%endif",
+			err:   "^test.file:2.1,3.1: %if requires a tag",
+		},
+		{
+			input: "A <- B\n%if x y\n%endif",
+			err:   "^test.file:2.1,3.1: bad %if tag x y",
+		},
+		{
+			input: "A <- B\n%if x\nB <- C",
+			err:   "^test.file:2.1,3.7: unclosed %if",
+		},
+		{
+			tags:  []string{"x"},
+			input: "A <- B\n%if x\nB <- C",
+			err:   "^test.file:2.1,3.7: unclosed %if",
+		},
+		{
+			input: "A <- B\n%endif\n",
+			err:   "^test.file:2.1,3.1: %endif without %if",
+		},
+		{
+			input: "A <- B\n%else\n",
+			err:   "^test.file:2.1,3.1: %else without %if",
+		},
+		{
+			tags:  []string{"x"},
+			input: "A <- B\n%if x\nB <- C\n%else\nB <- D\n%else\nB <- E\n%endif\n",
+			err:   "^test.file:6.1,7.1: duplicate %else",
+		},
+		{
+			input: "A <- B\n%if x\nB <- C\n%else\nB <- D\n%else\nB <- E\n%endif\n",
+			err:   "^test.file:6.1,7.1: duplicate %else",
+		},
+		{
+			input: "A <- B\n%if x\n%if y\n%else\n  %else\n%endif\n%endif\n",
+			err:   "^test.file:5.3,6.1: duplicate %else",
+		},
+		{
+			input: "A <- B %if x\n%endif\n",
+			err:   "^test.file:1.8,1.11: %if must be at the start of a line",
+		},
+		{
+			tags:  []string{"x"},
+			input: "A <- B\n%if x\nB <- C %else\n%endif\n",
+			err:   "^test.file:3.8,3.13: %else must be at the start of a line",
+		},
+		{
+			tags:  []string{"x"},
+			input: "A <- B\n%if x\nB <- C %endif\n",
+			err:   "^test.file:3.8,3.14: %endif must be at the start of a line",
+		},
+	}
+	for _, test := range tests {
+		g, err := Config{Tags: test.tags}.Parse(strings.NewReader(test.input), "test.file")
+		if test.err != "" {
+			if err == nil || !regexp.MustCompile(test.err).MatchString(err.Error()) {
+				t.Errorf("Tags %q Parse(%q)=_, %v, want error matching %q",
+					test.tags, test.input, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Tags %q Parse(%q)=_, %v, want _,nil", test.tags, test.input, err)
+			continue
+		}
+		if s := String(g.Rules); s != test.string {
+			t.Errorf("Tags %q Parse(%q)=\n%s\nwant\n%s", test.tags, test.input, s, test.string)
+		}
+	}
+}
+
+func TestParseIOErrorUnwrap(t *testing.T) {
+	cause := errors.New("broken pipe")
+	in := errRuneScanner{strings.NewReader("A <- B\nB <- 'b"), cause}
+	_, err := Parse(in, "test.file")
+	if !errors.Is(err, cause) {
+		t.Fatalf("Parse(_)=_, %v, want an error wrapping %v", err, cause)
+	}
+	const want = `test.file:2.6,2.8: read error after "'b": broken pipe`
+	if err.Error() != want {
+		t.Errorf("Parse(_)=_, %q, want %q", err, want)
+	}
+}
+
+// An errRuneScanner returns err instead of io.EOF at the end of its input.
+type errRuneScanner struct {
+	io.RuneScanner
+	err error
+}
+
+func (rs errRuneScanner) ReadRune() (rune, int, error) {
+	r, n, err := rs.RuneScanner.ReadRune()
+	if err == io.EOF {
+		return 0, 0, rs.err
+	}
+	return r, n, err
+}
+
+// testRuneScanner implements io.RuneScanner, wrapping another RuneScanner,
+// however, whenever the original scanner would've returned a ☹ rune,
+// testRuneScanner instead returns an error.
+type testRuneScanner struct {
+	io.RuneScanner
+}
+
+const testIOError = "test I/O error"
+
+func (rs testRuneScanner) ReadRune() (rune, int, error) {
+	r, n, err := rs.RuneScanner.ReadRune()
+	if r == '☹' {
+		return 0, 0, errors.New(testIOError)
+	}
+	return r, n, err
+}
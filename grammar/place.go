@@ -0,0 +1,180 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"bufio"
+	"errors"
+	gobuild "go/build"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// An OutputPackage is the Go package of the directory of an output file,
+// not including the output file itself, which is replaced.
+type OutputPackage struct {
+	// out is the output file.
+	out string
+
+	// Name is the package name, or the empty string if unknown.
+	// It is that of the other Go files of the directory,
+	// or, if there are none, the name conventional for its import path
+	// in the enclosing module.
+	Name string
+
+	// files are the other Go files of the package, not including tests.
+	files []string
+}
+
+// ReadOutputPackage returns the package of the directory of the output file.
+// If the other Go files of the directory belong to more than one package,
+// as in a scratch directory, or if the directory does not exist,
+// the package is unknown: its name is empty, and it has no files,
+// so it accepts any generated source.
+func ReadOutputPackage(out string) (*OutputPackage, error) {
+	dir := filepath.Dir(out)
+	pkg := &OutputPackage{out: out}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return pkg, nil
+	}
+	ctxt := gobuild.Default
+	ctxt.ReadDir = func(dir string) ([]fs.FileInfo, error) {
+		infos, err := ioutil.ReadDir(dir)
+		var others []fs.FileInfo
+		for _, info := range infos {
+			if !sameFile(filepath.Join(dir, info.Name()), out) {
+				others = append(others, info)
+			}
+		}
+		return others, err
+	}
+	p, err := ctxt.ImportDir(dir, 0)
+	var noGo *gobuild.NoGoError
+	var multi *gobuild.MultiplePackageError
+	switch {
+	case errors.As(err, &noGo):
+		pkg.Name = modulePackageName(dir)
+		return pkg, nil
+	case errors.As(err, &multi):
+		return pkg, nil
+	case err != nil:
+		return nil, err
+	}
+	pkg.Name = p.Name
+	for _, f := range p.GoFiles {
+		pkg.files = append(pkg.files, filepath.Join(dir, f))
+	}
+	return pkg, nil
+}
+
+// sameFile returns whether the paths name the same file,
+// which need not exist.
+func sameFile(a, b string) bool {
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	return err == nil && os.SameFile(ai, bi)
+}
+
+// modulePackageName returns the package name conventional
+// for the import path of the directory in its enclosing module,
+// or the empty string if it is not in a module.
+func modulePackageName(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	var elems []string
+	for d := abs; ; d = filepath.Dir(d) {
+		if mod := modulePath(filepath.Join(d, "go.mod")); mod != "" {
+			elems = append(elems, mod)
+			break
+		}
+		if filepath.Dir(d) == d {
+			return ""
+		}
+		elems = append(elems, filepath.Base(d))
+	}
+	for i, j := 0, len(elems)-1; i < j; i, j = i+1, j-1 {
+		elems[i], elems[j] = elems[j], elems[i]
+	}
+	return importName(strings.Join(elems, "/"))
+}
+
+// modulePath returns the module path of the go.mod file,
+// or the empty string if it cannot be read.
+func modulePath(gomod string) string {
+	f, err := os.Open(gomod)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 || fields[0] != "module" {
+			continue
+		}
+		if p, err := strconv.Unquote(fields[1]); err == nil {
+			return p
+		}
+		return fields[1]
+	}
+	return ""
+}
+
+// Check returns an error if the generated source does not fit the package:
+// if its package name differs from that of the other files of the package,
+// or if it declares a top-level identifier also declared
+// by another file of the package.
+// A nil OutputPackage, that of standard output, accepts any source.
+func (pkg *OutputPackage) Check(src []byte) error {
+	if pkg == nil {
+		return nil
+	}
+	var errs Errors
+	gen := parseGoCode(Loc{File: pkg.out, Line: 1, Col: 1}, string(src), 0, &errs)
+	if gen == nil {
+		return errs.ret()
+	}
+	if len(pkg.files) > 0 && gen.Name.Name != pkg.Name {
+		errs.add(gen.ident(gen.Name), "package %s, but the other files of %s are package %s",
+			gen.Name.Name, filepath.Dir(pkg.out), pkg.Name)
+		return errs.ret()
+	}
+	decls := make(map[string]bool)
+	goDecls(gen, false, func(name string, _ text) { decls[name] = true })
+	for _, file := range pkg.files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, file, src, 0)
+		if err != nil {
+			continue // The error is left for the Go compiler.
+		}
+		other := &goFile{File: f, fset: fset, loc: Loc{File: file, Line: 1, Col: 1}}
+		goDecls(other, false, func(name string, loc text) {
+			if name != "_" && name != "init" && decls[name] {
+				errs.add(loc, "%s redeclared; it is declared by the generated parser %s", name, pkg.out)
+			}
+		})
+	}
+	return errs.ret()
+}
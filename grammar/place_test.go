@@ -0,0 +1,184 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestReadOutputPackage(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		out   string
+		want  string
+		sibs  []string
+		err   string
+	}{
+		{
+			name:  "sibling files",
+			files: map[string]string{"a/x.go": "package lang\n", "a/x_test.go": "package lang_test\n"},
+			out:   "a/parser.go",
+			want:  "lang",
+			sibs:  []string{"a/x.go"},
+		},
+		{
+			name: "output file and ignored files are not siblings",
+			files: map[string]string{
+				"a/parser.go": "package old\n",
+				"a/gen.go":    "//go:build ignore\n\npackage main\n",
+			},
+			out:  "a/parser.go",
+			want: "",
+		},
+		{
+			name:  "module root",
+			files: map[string]string{"go.mod": "module example.com/lang\n"},
+			out:   "parser.go",
+			want:  "lang",
+		},
+		{
+			name:  "module subdirectory",
+			files: map[string]string{"go.mod": "module \"example.com/lang/v2\"\n", "a/b/README": ""},
+			out:   "a/b/parser.go",
+			want:  "b",
+		},
+		{
+			name:  "major version module",
+			files: map[string]string{"go.mod": "// The module.\nmodule example.com/lang/v2\n\ngo 1.18\n"},
+			out:   "parser.go",
+			want:  "lang",
+		},
+		{
+			name:  "multiple packages",
+			files: map[string]string{"x.go": "package x\n", "y.go": "package y\n"},
+			out:   "parser.go",
+			want:  "",
+		},
+		{
+			name:  "missing directory",
+			files: map[string]string{"go.mod": "module example.com/lang\n"},
+			out:   "a/b/parser.go",
+			want:  "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeTestFiles(t, test.files)
+			pkg, err := ReadOutputPackage(filepath.Join(dir, test.out))
+			if test.err != "" {
+				if err == nil || !regexp.MustCompile(test.err).MatchString(err.Error()) {
+					t.Fatalf("ReadOutputPackage(%q)=_, %v, want matching %q", test.out, err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadOutputPackage(%q)=_, %v, want nil", test.out, err)
+			}
+			var want []string
+			for _, f := range test.sibs {
+				want = append(want, filepath.Join(dir, f))
+			}
+			if pkg.Name != test.want || !reflect.DeepEqual(pkg.files, want) {
+				t.Errorf("ReadOutputPackage(%q)=%q, %q, want %q, %q",
+					test.out, pkg.Name, pkg.files, test.want, want)
+			}
+		})
+	}
+}
+
+func TestOutputPackageCheck(t *testing.T) {
+	const src = `package lang
+
+import "strings"
+
+type _Parser struct{}
+
+func (p *_Parser) Parse() {}
+
+func _AAccepts() {}
+
+func init() {}
+
+var _ = strings.ToLower
+`
+	tests := []struct {
+		name  string
+		files map[string]string
+		err   string
+	}{
+		{
+			name: "no collisions",
+			files: map[string]string{
+				"lang.go": "package lang\n\nimport \"strings\"\n\nfunc init() {}\n\nvar _ = strings.ToUpper\n\nfunc Parse() {}\n",
+			},
+		},
+		{
+			name:  "package mismatch",
+			files: map[string]string{"lang.go": "package other\n"},
+			err:   "parser.go:1.9,1.13: package lang, but the other files of .* are package other$",
+		},
+		{
+			name:  "no other files",
+			files: map[string]string{"go.mod": "module example.com/other\n"},
+		},
+		{
+			name: "collisions",
+			files: map[string]string{
+				"lang.go": "package lang\n\nfunc _AAccepts() {}\n\nfunc (*_Parser) Parse() {}\n",
+			},
+			err: "lang.go:3.6,3.15: _AAccepts redeclared; it is declared by the generated parser .*parser.go\n" +
+				".*lang.go:5.17,5.22: _Parser.Parse redeclared; it is declared by the generated parser .*parser.go$",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeTestFiles(t, test.files)
+			out := filepath.Join(dir, "parser.go")
+			pkg, err := ReadOutputPackage(out)
+			if err != nil {
+				t.Fatalf("ReadOutputPackage(%q)=_, %v, want nil", out, err)
+			}
+			err = pkg.Check([]byte(src))
+			if test.err == "" {
+				if err != nil {
+					t.Errorf("check(_)=%v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !regexp.MustCompile(test.err).MatchString(err.Error()) {
+				t.Errorf("check(_)=%v, want matching %q", err, test.err)
+			}
+		})
+	}
+
+	var pkg *OutputPackage
+	if err := pkg.Check([]byte("not Go")); err != nil {
+		t.Errorf("(*OutputPackage)(nil).Check(_)=%v, want nil", err)
+	}
+}
+
+// writeTestFiles writes the files, keyed by slash-separated path,
+// to a new temporary directory, returning the directory.
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
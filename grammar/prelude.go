@@ -0,0 +1,250 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// generatedDecls are the top-level identifiers of the generated parser,
+// after the identifier prefix,
+// that do not depend on the rules of the grammar.
+var generatedDecls = []string{
+	"N", "Parser", "NewParser", "NewSharedParser", "SlowParses", "Recoveries",
+	"RuleNames", "RuleMetadata", "Coverage", "Profile", "Trace", "Newline", "grammarID",
+	"Diagnostics",
+	"Committed", "key", "accept", "ensureMemo", "examine", "fail",
+	"failError", "failMemo", "getMemo", "leaf", "max", "memo", "memoize", "memoized", "next",
+	"node", "recovery", "setMemo", "sub",
+}
+
+// generatedRuleDecls are the suffixes, after the identifier prefix
+// and the identifier of a rule, of the top-level identifiers
+// of the generated parser for the rule.
+var generatedRuleDecls = []string{
+	"", "Accepts", "Node", "Fail", "Action",
+	"ParseAt", "ParseBounded", "Matches", "AST",
+}
+
+// generatedPlainDecls are the top-level identifiers of the generated parser
+// without the identifier prefix, including its methods.
+var generatedPlainDecls = []string{
+	"use", "tooBigError", "tooBigError.Error",
+}
+
+// checkPrelude reports conflicting top-level declarations
+// in the prelude and the %code of the rules,
+// which the generated file contains along with the generated parser:
+// a prelude without a package clause,
+// identifiers declared more than once,
+// and identifiers also declared by the generated parser
+// with the identifier prefix, for any other generation options.
+// Without this, the Go compiler reports them against the generated file.
+func checkPrelude(gr *Grammar, rules []*Rule, prefix string, errs *Errors) {
+	generated := make(map[string]*Rule)
+	for _, name := range generatedPlainDecls {
+		generated[name] = nil
+	}
+	for _, name := range generatedDecls {
+		generated[prefix+name] = nil
+	}
+	for _, name := range []string{
+		"ParseNode", "ParseValue", "Checks", "Expect", "ExpectError", "Diag", "Diagnostics",
+	} {
+		generated[prefix+"Parser."+name] = nil
+	}
+	for _, r := range rules {
+		for _, suffix := range generatedRuleDecls {
+			generated[prefix+r.Name.Ident()+suffix] = r
+		}
+	}
+
+	decls := make(map[string]text)
+	declare := func(name string, loc text) {
+		if name == "_" || name == "init" {
+			return
+		}
+		if prev, ok := decls[name]; ok {
+			err := Err(loc, "%s redeclared", name)
+			err = err.note(prev, "%s previously declared here", name)
+			errs.Errs = append(errs.Errs, err)
+			return
+		}
+		decls[name] = loc
+		r, ok := generated[name]
+		switch {
+		case !ok:
+			return
+		case r == nil:
+			errs.add(loc, "%s redeclared; it is declared by the generated parser", name)
+		default:
+			err := Err(loc, "%s redeclared; it is declared by the generated parser", name)
+			err = err.note(r.Name.Name, "for rule %s", r.Name)
+			errs.Errs = append(errs.Errs, err)
+		}
+	}
+
+	if gr.Prelude != nil {
+		loc := gr.Prelude.Begin()
+		loc.Col++ // skip the open {.
+		if file := parseGoCode(loc, gr.Prelude.String(), 0, errs); file != nil {
+			goDecls(file, true, declare)
+		}
+	}
+	for _, r := range rules {
+		for _, code := range r.Code {
+			loc := code.Begin()
+			loc.Col++ // skip the open {.
+			if file := parseGoCode(loc, "package main\n"+code.String(), 1, errs); file != nil {
+				goDecls(file, true, declare)
+			}
+		}
+	}
+}
+
+// parseGoCode parses a Go file beginning at the Loc,
+// after skip added lines before its code,
+// returning the file, or nil after reporting its first syntax error.
+func parseGoCode(loc Loc, code string, skip int, errs *Errors) *goFile {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, loc.File, code, 0)
+	if err == nil {
+		return &goFile{File: file, fset: fset, loc: loc, skip: skip}
+	}
+	el, ok := err.(scanner.ErrorList)
+	if !ok {
+		errs.add(loc, "%s", err)
+		return nil
+	}
+	at := goLoc(loc, el[0].Pos, skip)
+	if strings.HasPrefix(el[0].Msg, "expected 'package'") {
+		errs.add(at, "prelude has no package clause")
+	} else {
+		errs.add(at, "%s", el[0].Msg)
+	}
+	return nil
+}
+
+// A goFile is a parsed Go file of the grammar.
+type goFile struct {
+	*ast.File
+	fset *token.FileSet
+	loc  Loc
+	skip int
+}
+
+// ident returns the text of the identifier, located in the grammar.
+func (f *goFile) ident(id *ast.Ident) text {
+	begin := goLoc(f.loc, f.fset.Position(id.Pos()), f.skip)
+	end := goLoc(f.loc, f.fset.Position(id.End()), f.skip)
+	return text{str: id.Name, begin: begin, end: end}
+}
+
+// goLoc returns the Loc of a position in Go code beginning at the Loc,
+// after skip added lines before the code.
+func goLoc(loc Loc, p token.Position, skip int) Loc {
+	loc.Line += p.Line - 1 - skip // -1 because p.Line is 1-based.
+	if p.Line > 1+skip {
+		loc.Col = 1
+	}
+	loc.Col += p.Column - 1
+	return loc
+}
+
+// goDecls calls declare with each identifier declared at the top level
+// of the file, including its imports if imports is true,
+// and each method as its receiver type and name separated by a dot.
+func goDecls(f *goFile, imports bool, declare func(string, text)) {
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			loc := f.ident(d.Name)
+			if d.Recv == nil || len(d.Recv.List) == 0 {
+				declare(d.Name.Name, loc)
+				continue
+			}
+			if recv := receiverType(d.Recv.List[0].Type); recv != "" {
+				declare(recv+"."+d.Name.Name, loc)
+			}
+		case *ast.GenDecl:
+			for _, s := range d.Specs {
+				switch s := s.(type) {
+				case *ast.ImportSpec:
+					if !imports {
+						continue
+					}
+					if s.Name != nil {
+						if s.Name.Name != "." {
+							declare(s.Name.Name, f.ident(s.Name))
+						}
+						continue
+					}
+					p, err := strconv.Unquote(s.Path.Value)
+					if err != nil {
+						continue
+					}
+					if name := importName(p); name != "" {
+						begin := goLoc(f.loc, f.fset.Position(s.Path.Pos()), f.skip)
+						end := goLoc(f.loc, f.fset.Position(s.Path.End()), f.skip)
+						declare(name, text{str: s.Path.Value, begin: begin, end: end})
+					}
+				case *ast.TypeSpec:
+					declare(s.Name.Name, f.ident(s.Name))
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						declare(n.Name, f.ident(n))
+					}
+				}
+			}
+		}
+	}
+}
+
+// receiverType returns the name of a method's receiver type,
+// or the empty string if it is not a named type.
+func receiverType(e ast.Expr) string {
+	for {
+		switch t := e.(type) {
+		case *ast.StarExpr:
+			e = t.X
+		case *ast.ParenExpr:
+			e = t.X
+		case *ast.IndexExpr:
+			e = t.X
+		case *ast.IndexListExpr:
+			e = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// importName returns the conventional package name of an import path:
+// its last element, skipping a major version element such as v2,
+// up to the first dot, as in gopkg.in/yaml.v2.
+// It returns the empty string if that is not an identifier.
+func importName(p string) string {
+	elems := strings.Split(p, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = elems[len(elems)-2]
+	}
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[:i]
+	}
+	if !token.IsIdentifier(name) {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,507 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+// A reordering is a suggested order of the branches of a choice,
+// trying the most frequently accepted branches first.
+type reordering struct {
+	Rule   *Rule
+	Choice *Choice
+	// Order is the new order of the branches:
+	// Order[i] is the index of the branch tried ith.
+	Order []int
+	// Counts are the acceptances of each branch, in the original order.
+	Counts []uint64
+	// Before and After are the branches attempted
+	// by all accepting parses of the choice,
+	// in the original and the new order.
+	Before, After uint64
+}
+
+// Reorder writes a report of the choices of the checked grammar,
+// parsed from the source of the file,
+// whose branches would be tried fewer times if reordered
+// according to the merged coverage profiles.
+// If apply is true, it writes the source with the choices reordered to w
+// and the report to report; otherwise it writes the report to w.
+func Reorder(w, report io.Writer, file, src string, g *Grammar, profiles []string, apply bool) error {
+	counts, err := readBranchCounts(profiles)
+	if err != nil {
+		return err
+	}
+	rs := reorderChoices(g, counts)
+	if !apply {
+		return writeReorderReport(w, rs, nil)
+	}
+	out, skipped, err := applyReorderings(src, file, rs)
+	if err != nil {
+		return err
+	}
+	if err := writeReorderReport(report, rs, skipped); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, out)
+	return err
+}
+
+// readBranchCounts returns the merged counts of the choice branches
+// of the coverage profiles, keyed by branchKey.
+func readBranchCounts(files []string) (map[string]uint64, error) {
+	counts := make(map[string]uint64)
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		c, err := peg.ReadProfile(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		for i, p := range c.Points {
+			if p.Branch > 0 {
+				counts[branchKey(p.Loc, p.Rule, p.Branch)] += c.Counts[i]
+			}
+		}
+	}
+	return counts, nil
+}
+
+// branchKey returns the key of a choice branch coverage point.
+// The file name is dropped from the location,
+// so profiles match the grammar however its path was spelled.
+func branchKey(loc, rule string, branch int) string {
+	if i := strings.LastIndex(loc, ":"); i >= 0 {
+		loc = loc[i+1:]
+	}
+	return fmt.Sprintf("%s %s %d", loc, rule, branch)
+}
+
+// reorderChoices returns the reorderings of the choices of the grammar
+// that reduce the branches attempted, given the branch counts.
+//
+// Ordered choice accepts the first branch that matches,
+// so only adjacent independent branches are swapped:
+// those that cannot both match the same input,
+// because neither accepts the empty string
+// and their first bytes are disjoint,
+// and that have no side effects.
+// Choices of template expansions are not reordered,
+// since their branches are shared by all expansions.
+func reorderChoices(gr *Grammar, counts map[string]uint64) []reordering {
+	var rs []reordering
+	for _, r := range gr.CheckedRules {
+		if len(r.Name.Args) > 0 {
+			continue
+		}
+		r.Expr.Walk(func(e Expr) bool {
+			c, ok := e.(*Choice)
+			if !ok {
+				return true
+			}
+			ro := reordering{Rule: r, Choice: c, Counts: make([]uint64, len(c.Exprs))}
+			for i, sub := range c.Exprs {
+				ro.Counts[i] = counts[branchKey(coverLoc(sub), r.Name.String(), i+1)]
+			}
+			ro.Order = reorder(c, ro.Counts)
+			for i, j := range ro.Order {
+				ro.Before += ro.Counts[i] * uint64(i+1)
+				ro.After += ro.Counts[j] * uint64(i+1)
+			}
+			if ro.After < ro.Before {
+				rs = append(rs, ro)
+			}
+			return true
+		})
+	}
+	return rs
+}
+
+// reorder returns the order of the branches of the choice
+// moving more frequent branches ahead of the less frequent,
+// but never past a branch on which it is not independent.
+func reorder(c *Choice, counts []uint64) []int {
+	n := len(c.Exprs)
+	order := make([]int, n)
+	first := make([]*byteSet, n)
+	for i, sub := range c.Exprs {
+		order[i] = i
+		if !sub.Epsilon() && !hasEffects(sub) && !hasPredCode(sub) {
+			first[i] = firstBytes(sub, make(map[*Rule]bool))
+		}
+	}
+	independent := func(i, j int) bool {
+		return first[i] != nil && first[j] != nil && !first[i].intersects(first[j])
+	}
+	for i := 1; i < n; i++ {
+		for j := i; j > 0; j-- {
+			a, b := order[j-1], order[j]
+			if counts[b] <= counts[a] || !independent(a, b) {
+				break
+			}
+			order[j-1], order[j] = b, a
+		}
+	}
+	return order
+}
+
+// hasPredCode returns whether the expression has a code predicate,
+// which may depend on the branches tried before it.
+func hasPredCode(expr Expr) bool {
+	var pred bool
+	expr.Walk(func(e Expr) bool {
+		_, pred = e.(*PredCode)
+		return !pred
+	})
+	return pred
+}
+
+// A byteSet is a set of bytes.
+type byteSet [4]uint64
+
+func (s *byteSet) add(lo, hi byte) {
+	for b := int(lo); b <= int(hi); b++ {
+		s[b/64] |= 1 << uint(b%64)
+	}
+}
+
+func (s *byteSet) union(t *byteSet) {
+	for i := range s {
+		s[i] |= t[i]
+	}
+}
+
+func (s *byteSet) intersects(t *byteSet) bool {
+	for i := range s {
+		if s[i]&t[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// firstBytes returns a superset of the first bytes
+// of the non-empty strings matched by the expression.
+// Seen is the rules already on the path,
+// whose first bytes are conservatively all bytes.
+func firstBytes(expr Expr, seen map[*Rule]bool) *byteSet {
+	var s byteSet
+	switch e := expr.(type) {
+	case *Choice:
+		for _, sub := range e.Exprs {
+			s.union(firstBytes(sub, seen))
+		}
+	case *LongestChoice:
+		for _, sub := range e.Exprs {
+			s.union(firstBytes(sub, seen))
+		}
+	case *Sequence:
+		for _, sub := range e.Exprs {
+			s.union(firstBytes(sub, seen))
+			if !sub.Epsilon() {
+				break
+			}
+		}
+	case *Action:
+		return firstBytes(e.Expr, seen)
+	case *LabelExpr:
+		return firstBytes(e.Expr, seen)
+	case *WantExpr:
+		return firstBytes(e.Expr, seen)
+	case *CaptureExpr:
+		return firstBytes(e.Expr, seen)
+	case *DiffExpr:
+		return firstBytes(e.Expr, seen)
+	case *RepExpr:
+		return firstBytes(e.Expr, seen)
+	case *OptExpr:
+		return firstBytes(e.Expr, seen)
+	case *SubExpr:
+		return firstBytes(e.Expr, seen)
+	case *PredExpr, *PredCode, *Cut, *Empty:
+		// Predicates, cuts, and empty expressions consume nothing.
+	case *Ident:
+		r := e.Rule()
+		if r == nil || seen[r] {
+			s.add(0, 0xFF)
+			break
+		}
+		seen[r] = true
+		defer delete(seen, r)
+		return firstBytes(r.Expr, seen)
+	case *Literal:
+		if e.Text.String() == "" {
+			s.add(0, 0xFF)
+			break
+		}
+		b := e.Text.String()[0]
+		s.add(b, b)
+	case *CharClass:
+		if e.Neg {
+			s.add(0, 0xFF)
+			break
+		}
+		for _, sp := range e.Spans {
+			s.add(leadByte(sp[0]), leadByte(sp[1]))
+		}
+	case *BlockExpr:
+		if e.Open == "" {
+			s.add(0, 0xFF)
+			break
+		}
+		s.add(e.Open[0], e.Open[0])
+	default:
+		// Any, %delegate, and %native can begin with any byte.
+		s.add(0, 0xFF)
+	}
+	return &s
+}
+
+// leadByte returns the first byte of the UTF-8 encoding of r.
+// It is monotonic in r, so the lead bytes of a span of runes
+// are the span of the lead bytes of its bounds.
+func leadByte(r rune) byte {
+	switch {
+	case r < 0x80:
+		return byte(r)
+	case r < 0x800:
+		return byte(0xC0 | r>>6)
+	case r < 0x10000:
+		return byte(0xE0 | r>>12)
+	case r <= utf8.MaxRune:
+		return byte(0xF0 | r>>18)
+	default:
+		return 0xFF
+	}
+}
+
+// writeReorderReport writes a line for each reordering,
+// followed by a summary of the branch attempts saved.
+// Skipped are the reorderings that were not applied to the source.
+func writeReorderReport(w io.Writer, rs []reordering, skipped map[*Choice]string) error {
+	var before, after uint64
+	for _, r := range rs {
+		before += r.Before
+		after += r.After
+		var note string
+		if why, ok := skipped[r.Choice]; ok {
+			note = " (not applied: " + why + ")"
+		}
+		_, err := fmt.Fprintf(w, "%s: rule %s: branches %s to %s saves %d of %d branch attempts (%s)%s\n",
+			coverLoc(r.Choice), r.Rule.Name, branchList(identity(len(r.Order))), branchList(r.Order),
+			r.Before-r.After, r.Before, percent(int(r.Before-r.After), int(r.Before)), note)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "choices: %d, saves %d of %d branch attempts (%s)\n",
+		len(rs), before-after, before, percent(int(before-after), int(before)))
+	return err
+}
+
+func identity(n int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	return order
+}
+
+func branchList(order []int) string {
+	var s strings.Builder
+	for i, j := range order {
+		if i > 0 {
+			s.WriteString(",")
+		}
+		fmt.Fprintf(&s, "%d", j+1)
+	}
+	return s.String()
+}
+
+// applyReorderings returns the grammar source
+// with the branches of the reordered choices permuted.
+// The text between the branches, the / and any comments,
+// remains in place.
+// A reordering overlapping one already applied,
+// a choice nested in another reordered choice, is skipped;
+// applying the profile again reorders it.
+// The skipped reorderings are returned with the reason.
+func applyReorderings(src, file string, rs []reordering) (string, map[*Choice]string, error) {
+	toks, err := lexTokens(src, file)
+	if err != nil {
+		return "", nil, err
+	}
+	offs := newSourceOffsets(src)
+	type edit struct {
+		begin, end int
+		text       string
+	}
+	var edits []edit
+	skipped := make(map[*Choice]string)
+	for _, r := range rs {
+		spans, ok := branchSpans(toks, r.Choice, offs)
+		if !ok {
+			skipped[r.Choice] = "branches not found in the source"
+			continue
+		}
+		e := edit{begin: spans[0][0], end: spans[len(spans)-1][1]}
+		overlaps := false
+		for _, f := range edits {
+			if e.begin < f.end && f.begin < e.end {
+				overlaps = true
+			}
+		}
+		if overlaps {
+			skipped[r.Choice] = "overlaps a reordered choice"
+			continue
+		}
+		var s strings.Builder
+		for i, j := range r.Order {
+			if i > 0 {
+				s.WriteString(src[spans[i-1][1]:spans[i][0]])
+			}
+			s.WriteString(src[spans[j][0]:spans[j][1]])
+		}
+		e.text = s.String()
+		edits = append(edits, e)
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].begin < edits[j].begin })
+	var s strings.Builder
+	var prev int
+	for _, e := range edits {
+		s.WriteString(src[prev:e.begin])
+		s.WriteString(e.text)
+		prev = e.end
+	}
+	s.WriteString(src[prev:])
+	return s.String(), skipped, nil
+}
+
+// lexTokens returns the tokens of the grammar source,
+// as returned to the parser.
+func lexTokens(src, file string) ([]lexeme, error) {
+	rs := readRunes(strings.NewReader(src))
+	tags := make(map[string]bool)
+	x := &lexer{
+		in:     rs.reader(),
+		file:   file,
+		line:   1,
+		consts: make(map[string]string),
+		tags:   tags,
+		params: paramRules(rs, file, tags),
+	}
+	var toks []lexeme
+	for {
+		var t lexeme
+		t.tok = x.Lex(&t.lval)
+		if x.err != nil {
+			return nil, x.err
+		}
+		if t.tok <= 0 {
+			return toks, nil
+		}
+		t.begin, t.end = x.Begin(), x.End()
+		toks = append(toks, t)
+	}
+}
+
+// branchSpans returns the byte offsets of the source text
+// of each branch of the choice.
+// Each span begins at the first token of the branch
+// and ends after its last token, before the next top-level /.
+func branchSpans(toks []lexeme, c *Choice, offs sourceOffsets) ([][2]int, bool) {
+	begin := c.Begin()
+	k := -1
+	for i, t := range toks {
+		if t.begin.Line == begin.Line && t.begin.Col == begin.Col {
+			k = i
+			break
+		}
+	}
+	if k < 0 {
+		return nil, false
+	}
+	var spans [][2]int
+	start, depth := toks[k].begin, 0
+	var end Loc
+	for _, t := range toks[k:] {
+		if depth == 0 && (t.tok == ')' || t.tok == '\n') {
+			break
+		}
+		switch t.tok {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '/':
+			if depth == 0 {
+				spans = append(spans, [2]int{offs.offset(start), offs.offset(end)})
+				start = Loc{}
+				continue
+			}
+		}
+		if start == (Loc{}) {
+			start = t.begin
+		}
+		end = t.end
+	}
+	if start == (Loc{}) {
+		return nil, false
+	}
+	spans = append(spans, [2]int{offs.offset(start), offs.offset(end)})
+	if len(spans) != len(c.Exprs) {
+		return nil, false
+	}
+	return spans, true
+}
+
+// sourceOffsets converts Locs of a source to byte offsets.
+type sourceOffsets struct {
+	src   string
+	lines []int
+}
+
+// newSourceOffsets returns the sourceOffsets of the source.
+// Lines are terminated by \n, \r\n, or a lone \r, as by the lexer.
+func newSourceOffsets(src string) sourceOffsets {
+	lines := []int{0}
+	for i := 0; i < len(src); i++ {
+		switch {
+		case src[i] == '\r' && i+1 < len(src) && src[i+1] == '\n':
+			i++
+			lines = append(lines, i+1)
+		case src[i] == '\r' || src[i] == '\n':
+			lines = append(lines, i+1)
+		}
+	}
+	return sourceOffsets{src: src, lines: lines}
+}
+
+// offset returns the byte offset of the Loc,
+// whose Col is a 1-based rune offset into its line.
+func (o sourceOffsets) offset(l Loc) int {
+	if l.Line < 1 || l.Line > len(o.lines) {
+		return len(o.src)
+	}
+	i := o.lines[l.Line-1]
+	for n := 1; n < l.Col && i < len(o.src); n++ {
+		_, w := utf8.DecodeRuneInString(o.src[i:])
+		i += w
+	}
+	return i
+}
@@ -0,0 +1,136 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReorder(t *testing.T) {
+	tests := []struct {
+		name    string
+		grammar string
+		// counts are the acceptances of each branch of the choices,
+		// in the order the choices are walked.
+		counts [][]uint64
+		want   string
+		report string
+	}{
+		{
+			name:    "most frequent first",
+			grammar: `A <- "a" / "b" / "c"`,
+			counts:  [][]uint64{{1, 2, 10}},
+			want:    `A <- "c" / "b" / "a"`,
+			report: `:1.6,1.21: rule A: branches 1,2,3 to 3,2,1 saves 18 of 35 branch attempts (51.4%)
+choices: 1, saves 18 of 35 branch attempts (51.4%)
+`,
+		},
+		{
+			name:    "already ordered",
+			grammar: `A <- "a" / "b" / "c"`,
+			counts:  [][]uint64{{10, 2, 1}},
+			want:    `A <- "a" / "b" / "c"`,
+			report: `choices: 0, saves 0 of 0 branch attempts (100.0%)
+`,
+		},
+		{
+			name:    "stable for equal counts",
+			grammar: `A <- "a" / "b" / "c"`,
+			counts:  [][]uint64{{1, 1, 2}},
+			want:    `A <- "c" / "a" / "b"`,
+		},
+		{
+			name:    "shared first byte",
+			grammar: `A <- "ab" / "a" / [b-z]+`,
+			counts:  [][]uint64{{1, 5, 10}},
+			want:    `A <- [b-z]+ / "ab" / "a"`,
+		},
+		{
+			name:    "epsilon branch",
+			grammar: `A <- "a" / "b"? / "c"`,
+			counts:  [][]uint64{{1, 1, 10}},
+			want:    `A <- "a" / "b"? / "c"`,
+		},
+		{
+			name:    "rules",
+			grammar: "A <- B / C\nB <- [0-9]+\nC <- [a-z] C?",
+			counts:  [][]uint64{{1, 10}},
+			want:    "A <- C / B\nB <- [0-9]+\nC <- [a-z] C?",
+		},
+		{
+			name:    "code predicate",
+			grammar: `A <- &{ true } "a" / "b"`,
+			counts:  [][]uint64{{1, 10}},
+			want:    `A <- &{ true } "a" / "b"`,
+		},
+		{
+			name:    "non-ASCII classes",
+			grammar: `A <- [à-ÿ] / [a-z] / "é"`,
+			counts:  [][]uint64{{1, 5, 10}},
+			want:    `A <- [a-z] / [à-ÿ] / "é"`,
+		},
+		{
+			name:    "comments and subexpressions stay in place",
+			grammar: "A <- (\"a\" / \"x\") \"y\" # first\n\t/ \"b\" { return \"\" } # second\n\t/ \"c\"",
+			counts:  [][]uint64{{1, 5, 10}, {1, 0}},
+			want:    "A <- \"c\" # first\n\t/ \"b\" { return \"\" } # second\n\t/ (\"a\" / \"x\") \"y\"",
+		},
+		{
+			name:    "nested choice",
+			grammar: `A <- "a" / ("b" / "c")`,
+			counts:  [][]uint64{{1, 10}, {1, 9}},
+			want:    `A <- ("b" / "c") / "a"`,
+			report: `:1.6,1.22: rule A: branches 1,2 to 2,1 saves 9 of 21 branch attempts (42.9%)
+:1.13,1.22: rule A: branches 1,2 to 2,1 saves 8 of 19 branch attempts (42.1%) (not applied: overlaps a reordered choice)
+choices: 2, saves 17 of 40 branch attempts (42.5%)
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g, err := Parse(strings.NewReader(test.grammar), "")
+			if err != nil {
+				t.Fatalf("Parse(_)=%v, want nil", err)
+			}
+			if err := Check(g); err != nil {
+				t.Fatalf("Check(_)=%v, want nil", err)
+			}
+			counts := make(map[string]uint64)
+			var choice int
+			for _, r := range g.CheckedRules {
+				r.Expr.Walk(func(e Expr) bool {
+					if c, ok := e.(*Choice); ok {
+						for i, sub := range c.Exprs {
+							counts[branchKey(coverLoc(sub), r.Name.String(), i+1)] = test.counts[choice][i]
+						}
+						choice++
+					}
+					return true
+				})
+			}
+			rs := reorderChoices(g, counts)
+			got, skipped, err := applyReorderings(test.grammar, "", rs)
+			if err != nil {
+				t.Fatalf("applyReorderings(_)=%v, want nil", err)
+			}
+			if got != test.want {
+				t.Errorf("reordered\n%s\nwant\n%s", got, test.want)
+			}
+			if test.report == "" {
+				return
+			}
+			var b strings.Builder
+			if err := writeReorderReport(&b, rs, skipped); err != nil {
+				t.Fatalf("writeReorderReport(_)=%v, want nil", err)
+			}
+			if b.String() != test.report {
+				t.Errorf("report\n%s\nwant\n%s", b.String(), test.report)
+			}
+		})
+	}
+}
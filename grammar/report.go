@@ -0,0 +1,135 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"strings"
+)
+
+// A Report describes a generated parser,
+// for tracking the growth of a grammar
+// and the effect of generation options over time.
+type Report struct {
+	// Grammar is the grammar file name.
+	Grammar string `json:"grammar"`
+
+	// Rules is the number of rules, including expanded templates.
+	// Templates is the number of expanded template instances.
+	Rules     int `json:"rules"`
+	Templates int `json:"templates"`
+
+	// Bytes and Lines are the size of the generated file.
+	Bytes int `json:"bytes"`
+	Lines int `json:"lines"`
+
+	// Passes are the generated passes:
+	// accepts, fail, and, if generated, node and action.
+	Passes []string `json:"passes"`
+
+	// RuleSizes are the sizes of the generated functions of each rule,
+	// in the order of the rules.
+	RuleSizes []ReportRule `json:"ruleSizes"`
+}
+
+// A ReportRule is the size of the generated functions of a rule.
+type ReportRule struct {
+	// Rule is the name of the rule.
+	Rule string `json:"rule"`
+	// Funcs is the number of generated functions of the rule.
+	Funcs int `json:"funcs"`
+	// Bytes and Lines are the size of the functions.
+	Bytes int `json:"bytes"`
+	Lines int `json:"lines"`
+}
+
+// NewReport returns the Report of the parser source
+// generated from the grammar file by Generate with the Config.
+func (c Config) NewReport(file string, gr *Grammar, src []byte) (*Report, error) {
+	rep := &Report{
+		Grammar:   file,
+		Rules:     len(gr.CheckedRules),
+		Bytes:     len(src),
+		Lines:     strings.Count(string(src), "\n"),
+		Passes:    []string{"accepts", "fail"},
+		RuleSizes: []ReportRule{},
+	}
+	if !c.NoParseTree {
+		rep.Passes = append(rep.Passes, "node")
+	}
+	if !c.NoActions {
+		rep.Passes = append(rep.Passes, "action")
+	}
+	rules := make(map[string]int)
+	for i, r := range gr.CheckedRules {
+		if len(r.Name.Args) > 0 {
+			rep.Templates++
+		}
+		rep.RuleSizes = append(rep.RuleSizes, ReportRule{Rule: r.Name.String()})
+		for _, suffix := range generatedRuleDecls {
+			if suffix != "" {
+				rules[c.Prefix+r.Name.Ident()+suffix] = i
+			}
+		}
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range f.Decls {
+		fun, ok := d.(*ast.FuncDecl)
+		if !ok || fun.Recv != nil {
+			continue
+		}
+		i, ok := rules[fun.Name.Name]
+		if !ok {
+			continue
+		}
+		begin, end := fset.Position(fun.Pos()), fset.Position(fun.End())
+		rr := &rep.RuleSizes[i]
+		rr.Funcs++
+		rr.Bytes += end.Offset - begin.Offset
+		rr.Lines += end.Line - begin.Line + 1
+	}
+	return rep, nil
+}
+
+// Write writes the Report in the format, text or json.
+func (rep *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rep)
+	case "text":
+	default:
+		return fmt.Errorf("bad report format %s: want text or json", format)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "grammar: %s\n", rep.Grammar)
+	fmt.Fprintf(&b, "rules: %d (%d expanded templates)\n", rep.Rules, rep.Templates)
+	fmt.Fprintf(&b, "size: %d bytes, %d lines\n", rep.Bytes, rep.Lines)
+	fmt.Fprintf(&b, "passes: %s\n", strings.Join(rep.Passes, ", "))
+	width := len("rule")
+	for _, rr := range rep.RuleSizes {
+		if len(rr.Rule) > width {
+			width = len(rr.Rule)
+		}
+	}
+	fmt.Fprintf(&b, "%-*s %5s %7s %8s\n", width, "rule", "funcs", "lines", "bytes")
+	for _, rr := range rep.RuleSizes {
+		fmt.Fprintf(&b, "%-*s %5d %7d %8d\n", width, rr.Rule, rr.Funcs, rr.Lines, rr.Bytes)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,1591 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+// Grammar is a PEG grammar.
+type Grammar struct {
+	// Prelude is custom code added to the beginning of the generated output.
+	Prelude Text
+
+	// Rules are the rules of the grammar.
+	Rules []Rule
+
+	// MaxDepth, if positive, is the maximum nesting depth
+	// of rule invocations, set by the %maxdepth directive.
+	MaxDepth int
+
+	// WarnSlow, if positive, is the time budget of the Accepts pass
+	// of each rule without its own, set by the %warnslow directive.
+	// Parses of rules exceeding their budget are recorded by the parser.
+	WarnSlow time.Duration
+
+	// InvalidBytes indicates that . and negated character classes
+	// accept each byte of invalid UTF-8 as a single-byte rune,
+	// set by the %invalidbytes directive.
+	InvalidBytes bool
+
+	// Newline, if non-nil, is the argument of the %newline directive:
+	// lf, crlf, js, or unicode.
+	// It specifies the line terminators used to compute error locations.
+	Newline Text
+
+	// Consts are the values of the constants defined by %const directives,
+	// keyed by name.
+	// Constants are expanded in literals and character classes
+	// when the grammar is parsed.
+	Consts map[string]Text
+
+	// CheckedRules are the rules successfully checked by the Check pass.
+	// It contains all non-template rules and all expanded templates.
+	CheckedRules []*Rule
+
+	// LeftRecursion are the left-recursive cycles found by the Check pass,
+	// even if Check returned an error.
+	// Each cycle begins and ends with the same rule.
+	LeftRecursion [][]*Rule
+
+	// Warnings are the likely mistakes found by the Check pass,
+	// in order of their begin location,
+	// such as unused labels and discarded actions with -Wunused.
+	Warnings []Error
+}
+
+// A Rule defines a production in a PEG grammar.
+type Rule struct {
+	Name
+
+	// ErrorName, if non-nil, indicates that this is a named rule.
+	// Errors beneath a named rule are collapsed,
+	// reporting the error position as the start of the rule's parse
+	// with the "want" message set to ErrorName.
+	//
+	// If nil, the rule is unnamed and does not collapse errors.
+	//
+	// If ErrorName has a format verb, %s, %q, or %v,
+	// it is instead an error message, see ErrorMessage.
+	ErrorName Text
+
+	// Params, if non-nil, is the Go parameter list of the rule,
+	// declared with the @param annotation,
+	// not including the parentheses.
+	// The parameters are passed as additional arguments
+	// to each of the rule's generated functions.
+	Params Text
+
+	// MaxDepth, if positive, is the maximum nesting depth
+	// of invocations of this rule, set by the @maxdepth annotation.
+	MaxDepth int
+
+	// WarnSlow, if positive, is the time budget
+	// of the Accepts pass of this rule, set by the @warnslow annotation.
+	// It overrides the Grammar's WarnSlow.
+	WarnSlow time.Duration
+
+	// ResultType, if non-nil, is the declared Go type
+	// of the rule's result in the action pass,
+	// following -> in the rule header.
+	// Its string is the canonical form of the type.
+	ResultType Text
+
+	// Token indicates that the rule's Node pass
+	// produces a single leaf Node for the matched text,
+	// instead of a Node for each subexpression.
+	// It is set by the @token annotation.
+	Token bool
+
+	// NoCase indicates that the literals and character classes
+	// of the rule's expression are case-insensitive.
+	// They are rewritten to case-sensitive expressions by Check.
+	// It is set by the @nocase annotation.
+	NoCase bool
+
+	// RecoverUntil and RecoverPast, if non-nil,
+	// are the sync literals of the @recoveruntil and @recoverpast annotations.
+	// If the rule fails, the parse recovers by skipping the input
+	// from the start of the rule up to the first RecoverUntil literal
+	// or through the first RecoverPast literal,
+	// and the rule accepts the skipped input, if any, instead of failing.
+	RecoverUntil, RecoverPast []string
+
+	// Metadata maps the names of the rule's other annotations
+	// to their values: the unquoted string literal argument,
+	// or the empty string if the annotation has no argument.
+	// It is emitted in the generated RuleMetadata table
+	// for use by other tools.
+	Metadata map[string]string
+
+	// AST, if non-nil, are the labels
+	// that are the fields of the rule's generated AST struct type,
+	// one for each label name, in order of their first appearance.
+	// It is set by the Check pass with Config.AST.
+	AST []*LabelExpr
+
+	// Code are the Go declarations of the %code blocks
+	// immediately preceding the rule,
+	// emitted just before the rule's generated functions.
+	Code []Text
+
+	// Expr is the PEG expression matched by the rule.
+	Expr Expr
+
+	// N is the rule's unique integer within its containing Grammar.
+	// It is a small integer that may be used as an array index.
+	N int
+
+	// typ is the type of the rule in the action pass.
+	// typ is nil before the checkLeft pass add non-nil after.
+	typ *string
+
+	// epsilon indicates whether the rule can match the empty string.
+	epsilon bool
+
+	// effects indicates whether the rule's expression
+	// contains a !memo action, directly or through a referenced rule.
+	effects bool
+
+	// textual indicates whether the rule's value in the action pass
+	// is always the text that it matched.
+	textual bool
+
+	// infallible indicates whether the rule never fails.
+	// It is false, conservatively, until set by Check.
+	infallible bool
+
+	// Labels is the set of all label names in the rule's expression.
+	Labels []*LabelExpr
+}
+
+func (r *Rule) Begin() Loc  { return r.Name.Begin() }
+func (r *Rule) End() Loc    { return r.Expr.End() }
+func (r Rule) Type() string { return *r.typ }
+
+// Epsilon returns whether the rule can match the empty string.
+// It is only valid after Check.
+func (r *Rule) Epsilon() bool { return r.epsilon }
+
+// Effects returns whether the rule's expression contains a !memo action,
+// directly or through a referenced rule.
+// It is only valid after Check.
+func (r *Rule) Effects() bool { return r.effects }
+
+// Recovers returns whether the rule recovers from failures,
+// skipping to the literals of its @recoveruntil or @recoverpast annotations.
+func (r *Rule) Recovers() bool {
+	return len(r.RecoverUntil) > 0 || len(r.RecoverPast) > 0
+}
+
+// ErrorMessage returns whether the rule's ErrorName is an error message:
+// a format with a verb, %s, %q, or %v,
+// filled with the token preceding the rule when it fails,
+// that is reported instead of the wants at the failure.
+func (r *Rule) ErrorMessage() bool {
+	if r.ErrorName == nil {
+		return false
+	}
+	verbs, _ := formatVerbs(r.ErrorName.String())
+	return verbs > 0
+}
+
+// formatVerbs returns the number of verbs %s, %q, and %v in the format,
+// and whether every other % is part of a %%.
+func formatVerbs(format string) (verbs int, ok bool) {
+	ok = true
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		switch {
+		case i == len(format):
+			ok = false
+		case format[i] == 's' || format[i] == 'q' || format[i] == 'v':
+			verbs++
+		case format[i] != '%':
+			ok = false
+		}
+	}
+	return verbs, ok
+}
+
+// An Annotation is an @-annotation in the header of a rule.
+type Annotation struct {
+	// Name is the name of the annotation.
+	// The Begin location of Name includes the @,
+	// but the string does not.
+	Name Text
+
+	// Args, if non-nil, is the text between the parentheses
+	// immediately following the name.
+	// The Begin and End locations of Args includes the ( ) delimiters,
+	// but the string does not.
+	Args Text
+}
+
+// typeActions sets the ReturnType of each Action
+// whose value is the result of a rule with a declared ResultType
+// to the declared type, instead of the type inferred from its Code.
+// It returns the error inferring the type of any other Action.
+func (r *Rule) typeActions() error {
+	if r.ResultType != nil {
+		for _, e := range resultExprs(r.Expr, make(map[Expr]bool)) {
+			if a, ok := e.(*Action); ok {
+				a.ReturnType, a.typeErr = r.ResultType.String(), nil
+			}
+		}
+	}
+	var err error
+	r.Expr.Walk(func(e Expr) bool {
+		if a, ok := e.(*Action); ok && a.typeErr != nil {
+			err = a.typeErr
+		}
+		return err == nil
+	})
+	return err
+}
+
+// annotate applies the annotations to the rule,
+// returning an error for any malformed annotation.
+// Annotations other than @param, @maxdepth, @warnslow, @token, @nocase,
+// @recoveruntil, and @recoverpast are recorded in the rule's Metadata.
+func (r *Rule) annotate(annots []Annotation) error {
+	for _, a := range annots {
+		switch a.Name.String() {
+		case "param":
+			if r.Params != nil {
+				return Err(a.Name, "@param redefined")
+			}
+			if a.Args == nil {
+				return Err(a.Name, "@param requires a parameter list")
+			}
+			loc := a.Args.Begin()
+			loc.Col++ // skip the open (.
+			if _, err := parseGoParams(loc, a.Args.String()); err != nil {
+				return err
+			}
+			r.Params = a.Args
+		case "maxdepth":
+			if r.MaxDepth > 0 {
+				return Err(a.Name, "@maxdepth redefined")
+			}
+			if a.Args == nil {
+				return Err(a.Name, "@maxdepth requires a depth")
+			}
+			n, err := parseDepth(a.Args.String())
+			if err != nil {
+				return Err(a.Args, "@maxdepth %s", err)
+			}
+			r.MaxDepth = n
+		case "warnslow":
+			if r.WarnSlow > 0 {
+				return Err(a.Name, "@warnslow redefined")
+			}
+			if a.Args == nil {
+				return Err(a.Name, "@warnslow requires a duration")
+			}
+			d, err := parseBudget(a.Args.String())
+			if err != nil {
+				return Err(a.Args, "@warnslow %s", err)
+			}
+			r.WarnSlow = d
+		case "token":
+			if r.Token {
+				return Err(a.Name, "@token redefined")
+			}
+			if a.Args != nil {
+				return Err(a.Args, "@token takes no arguments")
+			}
+			r.Token = true
+		case "nocase":
+			if r.NoCase {
+				return Err(a.Name, "@nocase redefined")
+			}
+			if a.Args != nil {
+				return Err(a.Args, "@nocase takes no arguments")
+			}
+			r.NoCase = true
+		case "recoveruntil", "recoverpast":
+			name := a.Name.String()
+			lits := &r.RecoverUntil
+			if name == "recoverpast" {
+				lits = &r.RecoverPast
+			}
+			if *lits != nil {
+				return Err(a.Name, "@%s redefined", name)
+			}
+			if a.Args == nil {
+				return Err(a.Name, "@%s requires string literals", name)
+			}
+			loc := a.Args.Begin()
+			loc.Col++ // skip the open (.
+			if _, err := parseGoArgs(loc, a.Args.String()); err != nil {
+				return err
+			}
+			strs, err := parseGoStrings(loc, a.Args.String())
+			if err != nil || len(strs) == 0 {
+				return Err(a.Args, "@%s requires string literals", name)
+			}
+			for _, s := range strs {
+				if s == "" {
+					return Err(a.Args, "@%s requires non-empty string literals", name)
+				}
+			}
+			*lits = strs
+		default:
+			name := a.Name.String()
+			if _, ok := r.Metadata[name]; ok {
+				return Err(a.Name, "@%s redefined", name)
+			}
+			var v string
+			if a.Args != nil {
+				var err error
+				if v, err = strconv.Unquote(strings.TrimSpace(a.Args.String())); err != nil {
+					return Err(a.Args, "@%s requires a string literal", name)
+				}
+			}
+			if r.Metadata == nil {
+				r.Metadata = make(map[string]string)
+			}
+			r.Metadata[name] = v
+		}
+	}
+	return nil
+}
+
+// A Directive is a %-directive in the header of a grammar.
+type Directive struct {
+	// Name is the name of the directive.
+	// The Begin location of Name includes the %,
+	// but the string does not.
+	Name Text
+
+	// Args are the arguments following the name
+	// on the directive's line.
+	Args []Text
+
+	// Value, if non-nil, is the string following =
+	// in a directive of the form %name arg = "value".
+	Value Text
+}
+
+// direct applies the directives to the grammar,
+// returning an error for any unknown or malformed directive.
+func (g *Grammar) direct(ds []Directive) error {
+	for _, d := range ds {
+		if d.Value != nil && d.Name.String() != "const" {
+			return Err(d.Value, "%%%s takes no = value", d.Name.String())
+		}
+		switch d.Name.String() {
+		case "const":
+			if len(d.Args) != 1 || d.Value == nil {
+				return Err(d.Name, "%%const requires a name = \"value\"")
+			}
+			name := d.Args[0].String()
+			if _, ok := g.Consts[name]; ok {
+				return Err(d.Args[0], "%%const %s redefined", name)
+			}
+			if g.Consts == nil {
+				g.Consts = make(map[string]Text)
+			}
+			g.Consts[name] = d.Value
+		case "maxdepth":
+			if g.MaxDepth > 0 {
+				return Err(d.Name, "%%maxdepth redefined")
+			}
+			if len(d.Args) != 1 {
+				return Err(d.Name, "%%maxdepth requires a depth")
+			}
+			n, err := parseDepth(d.Args[0].String())
+			if err != nil {
+				return Err(d.Args[0], "%%maxdepth %s", err)
+			}
+			g.MaxDepth = n
+		case "warnslow":
+			if g.WarnSlow > 0 {
+				return Err(d.Name, "%%warnslow redefined")
+			}
+			if len(d.Args) != 1 {
+				return Err(d.Name, "%%warnslow requires a duration")
+			}
+			b, err := parseBudget(d.Args[0].String())
+			if err != nil {
+				return Err(d.Args[0], "%%warnslow %s", err)
+			}
+			g.WarnSlow = b
+		case "invalidbytes":
+			if len(d.Args) != 0 {
+				return Err(d.Args[0], "%%invalidbytes takes no arguments")
+			}
+			g.InvalidBytes = true
+		case "newline":
+			if g.Newline != nil {
+				return Err(d.Name, "%%newline redefined")
+			}
+			if len(d.Args) != 1 {
+				return Err(d.Name, "%%newline requires lf, crlf, js, or unicode")
+			}
+			if _, ok := newlines[d.Args[0].String()]; !ok {
+				return Err(d.Args[0], "%%newline requires lf, crlf, js, or unicode, got %s", d.Args[0].String())
+			}
+			g.Newline = d.Args[0]
+		default:
+			return Err(d.Name, "unknown directive %%%s", d.Name.String())
+		}
+	}
+	return nil
+}
+
+// A newline is the line terminators named by an argument of %newline.
+type newline struct {
+	// Const is the name of the constant in package peg.
+	Const string
+	peg.Newline
+}
+
+// newlines maps the arguments of %newline to their line terminators.
+var newlines = map[string]newline{
+	"lf":      {Const: "LF", Newline: peg.LF},
+	"crlf":    {Const: "CRLF", Newline: peg.CRLF},
+	"js":      {Const: "JS", Newline: peg.JS},
+	"unicode": {Const: "Unicode", Newline: peg.Unicode},
+}
+
+// PegNewline returns the line terminators of the %newline directive,
+// used to locate errors in the text parsed by the generated parser,
+// or peg.LF if there is none.
+func (g *Grammar) PegNewline() peg.Newline {
+	return g.pegNewline().Newline
+}
+
+// pegNewline returns the line terminators of the %newline directive,
+// or peg.LF if there is none.
+func (g *Grammar) pegNewline() newline {
+	if g.Newline == nil {
+		return newlines["lf"]
+	}
+	return newlines[g.Newline.String()]
+}
+
+func parseDepth(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("depth must be a positive integer, got %s", s)
+	}
+	return n, nil
+}
+
+func parseBudget(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("duration must be positive, such as 10ms, got %s", s)
+	}
+	return d, nil
+}
+
+// A Name is the name of a rule template.
+type Name struct {
+	// Name is the name of the template.
+	Name Text
+
+	// Args are the arguments or parameters of the template.
+	// Each is either an identifier or a Go-quoted string literal.
+	Args []Text
+}
+
+// literalArg returns the string of a template argument or parameter
+// that is a string literal, and whether it is one.
+func literalArg(a string) (string, bool) {
+	if !strings.HasPrefix(a, `"`) {
+		return "", false
+	}
+	s, err := strconv.Unquote(a)
+	return s, err == nil
+}
+
+func (n Name) Begin() Loc { return n.Name.Begin() }
+func (n Name) End() Loc {
+	if len(n.Args) == 0 {
+		return n.Name.End()
+	}
+	return n.Args[len(n.Args)-1].End()
+}
+
+// Text is a string of text located along with its location in the input.
+type Text interface {
+	Located
+	// String is the text string.
+	String() string
+}
+
+// Loc identifies a location in a file by its line and column numbers.
+type Loc struct {
+	// File is the name of the input file.
+	File string
+	// Line is line number of the location.
+	// The first line of input is line number 1.
+	Line int
+	// Col is the Loc's rune offset into the line.
+	// Col 0 is before the first rune on the line.
+	Col int
+}
+
+// Less returns whether the receiver is earlier in the input than the argument.
+func (l Loc) Less(j Loc) bool {
+	if l.Line == j.Line {
+		return l.Col < j.Col
+	}
+	return l.Line < j.Line
+}
+
+// PrettyPrint implements the pretty.PrettyPrinter interface,
+// returning a simpler, one-line string form of the Loc.
+func (l Loc) PrettyPrint() string { return fmt.Sprintf("Loc{%d, %d}", l.Line, l.Col) }
+
+// Begin returns the Loc.
+func (l Loc) Begin() Loc { return l }
+
+// End returns the Loc.
+func (l Loc) End() Loc { return l }
+
+// Expr is PEG expression that matches a sequence of input runes.
+type Expr interface {
+	Located
+	String() string
+
+	// fullString returns the fully parenthesized string representation.
+	fullString() string
+
+	// Walk calls a function for each expression in the tree.
+	// Walk stops early if the function returns false.
+	Walk(func(Expr) bool) bool
+
+	// substitute returns a clone of the expression
+	// with all occurrences of identifiers that are keys of sub
+	// substituted with the corresponding value.
+	// substitute must not be called after Check,
+	// because it does not update bookkeeping fields
+	// that are set by the Check pass.
+	substitute(sub map[string]string) Expr
+
+	// Type returns the type of the expression in the Action Tree.
+	// This is the Go type associated with the expression.
+	Type() string
+
+	// Epsilon returns whether the expression can match the empty string.
+	// It is only valid after Check.
+	Epsilon() bool
+
+	// CanFail returns whether the node can ever fail to parse.
+	// Nodes like * or ?, for example, can never fail.
+	// Parents of never-fail nodes needn't emit a failure branch,
+	// as it will never be called.
+	CanFail() bool
+
+	// checkLeft checks for left-recursion and sets rule types.
+	checkLeft(rules map[string]*Rule, p path, errs *Errors)
+
+	// check checks for undefined identifiers,
+	// linking defined identifiers to rules;
+	// and checks for type mismatches.
+	check(ctx ctx, valueUsed bool, errs *Errors)
+}
+
+// A Choice is an ordered choice between expressions.
+type Choice struct{ Exprs []Expr }
+
+func (e *Choice) Begin() Loc { return e.Exprs[0].Begin() }
+func (e *Choice) End() Loc   { return e.Exprs[len(e.Exprs)-1].End() }
+
+func (e *Choice) Walk(f func(Expr) bool) bool {
+	if !f(e) {
+		return false
+	}
+	for _, kid := range e.Exprs {
+		if !kid.Walk(f) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Choice) substitute(sub map[string]string) Expr {
+	substitute := *e
+	substitute.Exprs = make([]Expr, len(e.Exprs))
+	for i, kid := range e.Exprs {
+		substitute.Exprs[i] = kid.substitute(sub)
+	}
+	return &substitute
+}
+
+// Type returns the type of a choice expression,
+// which is the type of it's first branch.
+// All other branches must have the same type;
+// this is verified during the Check pass.
+func (e *Choice) Type() string { return e.Exprs[0].Type() }
+
+func (e *Choice) Epsilon() bool {
+	for _, e := range e.Exprs {
+		if e.Epsilon() {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Choice) CanFail() bool {
+	// A choice node can only fail if all of its branches can fail,
+	// or if a branch before a non-failing branch
+	// can fail after a cut.
+	// If there is a non-failing branch, it will always return accept.
+	for _, s := range e.Exprs {
+		if !s.CanFail() {
+			return false
+		}
+		if commits(s) {
+			return true
+		}
+	}
+	return true
+}
+
+// A LongestChoice is an unordered choice between expressions.
+// All branches are tried, and the one matching the longest span is chosen.
+// Of branches matching equally long spans, the first is chosen.
+type LongestChoice struct{ Exprs []Expr }
+
+func (e *LongestChoice) Begin() Loc { return e.Exprs[0].Begin() }
+func (e *LongestChoice) End() Loc   { return e.Exprs[len(e.Exprs)-1].End() }
+
+func (e *LongestChoice) Walk(f func(Expr) bool) bool {
+	if !f(e) {
+		return false
+	}
+	for _, kid := range e.Exprs {
+		if !kid.Walk(f) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *LongestChoice) substitute(sub map[string]string) Expr {
+	substitute := *e
+	substitute.Exprs = make([]Expr, len(e.Exprs))
+	for i, kid := range e.Exprs {
+		substitute.Exprs[i] = kid.substitute(sub)
+	}
+	return &substitute
+}
+
+// Type returns the type of a longest choice expression,
+// which is the type of it's first branch.
+// All other branches must have the same type;
+// this is verified during the Check pass.
+func (e *LongestChoice) Type() string { return e.Exprs[0].Type() }
+
+func (e *LongestChoice) Epsilon() bool { return (*Choice)(e).Epsilon() }
+func (e *LongestChoice) CanFail() bool { return (*Choice)(e).CanFail() }
+
+// An Action is an action expression:
+// a subexpression and code to run if matched.
+type Action struct {
+	Expr Expr
+	// Code is the Go code to execute if the subexpression is matched.
+	// The Begin and End locations of Code includes the { } delimiters,
+	// but the string does not.
+	//
+	// TODO: specify the environment under which the code is run.
+	Code Text
+
+	// ReturnType is the go type of the value returned by the action.
+	ReturnType string
+
+	// typeErr is the error inferring the ReturnType
+	// from the first return statement of the Code.
+	typeErr error
+
+	// NoMemo indicates that the action is annotated with !memo.
+	// A !memo action may have side-effects,
+	// so it is only run if it is part of the successful parse,
+	// and never speculatively in a branch that later fails
+	// or beneath a predicate.
+	NoMemo bool
+
+	// Labels are the labels that are in scope of this action.
+	Labels []*LabelExpr
+}
+
+func (e *Action) Begin() Loc    { return e.Expr.Begin() }
+func (e *Action) End() Loc      { return e.Code.End() }
+func (e *Action) Type() string  { return e.ReturnType }
+func (e *Action) Epsilon() bool { return e.Expr.Epsilon() }
+func (e *Action) CanFail() bool { return e.Expr.CanFail() }
+
+func (e *Action) Walk(f func(Expr) bool) bool {
+	return f(e) && e.Expr.Walk(f)
+}
+
+func (e *Action) substitute(sub map[string]string) Expr {
+	substitute := *e
+	substitute.Expr = e.Expr.substitute(sub)
+	substitute.Labels = nil
+	return &substitute
+}
+
+// A Sequence is a sequence of expressions.
+type Sequence struct {
+	Exprs []Expr
+
+	// Tuples indicates that, if the types of the sub-expressions differ,
+	// the value of the sequence is a tuple of their values,
+	// instead of a type mismatch.
+	// It is set by the Check pass with Config.Tuples.
+	Tuples bool
+}
+
+func (e *Sequence) Begin() Loc { return e.Exprs[0].Begin() }
+func (e *Sequence) End() Loc   { return e.Exprs[len(e.Exprs)-1].End() }
+
+func (e *Sequence) Walk(f func(Expr) bool) bool {
+	if !f(e) {
+		return false
+	}
+	for _, kid := range e.Exprs {
+		if !kid.Walk(f) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Sequence) substitute(sub map[string]string) Expr {
+	substitute := *e
+	substitute.Exprs = make([]Expr, len(e.Exprs))
+	for i, kid := range e.Exprs {
+		substitute.Exprs[i] = kid.substitute(sub)
+	}
+	return &substitute
+}
+
+// Type returns the type of a sequence expression,
+// which is based on the type of its first sub-expression.
+// All other other sub-expressions must have the same type;
+// this is verified during the Check pass.
+//
+// If the first sub-expression is a string,
+// the type of the entire sequence is a string.
+// The value is the concatenation of all sub-expressions.
+//
+// Otherwise, the type is a slice of the first sub-expression type.
+// The value is the slice of all sub-expression values.
+//
+// However, if the sequence is a tuple,
+// the type is a struct with a field for each sub-expression:
+// struct{ V0 T0; V1 T1; ... }, where Ti is the type of the ith sub-expression.
+// The value has the value of each sub-expression in its field.
+func (e *Sequence) Type() string {
+	if e.IsTuple() {
+		var s strings.Builder
+		s.WriteString("struct{ ")
+		for i, sub := range e.Exprs {
+			if i > 0 {
+				s.WriteString("; ")
+			}
+			fmt.Fprintf(&s, "V%d %s", i, sub.Type())
+		}
+		s.WriteString(" }")
+		return s.String()
+	}
+	t := e.Exprs[0].Type()
+	switch t {
+	case "":
+		return ""
+	case "string":
+		return "string"
+	default:
+		return "[]" + t
+	}
+}
+
+// IsTuple returns whether the value of the sequence is a tuple:
+// whether Tuples is true and the types of the sub-expressions differ.
+// If any sub-expression type is not yet known, it is not a tuple.
+func (e *Sequence) IsTuple() bool {
+	if !e.Tuples {
+		return false
+	}
+	t := e.Exprs[0].Type()
+	tuple := false
+	for _, sub := range e.Exprs {
+		switch got := sub.Type(); {
+		case got == "" || t == "":
+			return false
+		case got != t:
+			tuple = true
+		}
+	}
+	return tuple
+}
+
+func (e *Sequence) Epsilon() bool {
+	for _, e := range e.Exprs {
+		if !e.Epsilon() {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Sequence) CanFail() bool {
+	for _, s := range e.Exprs {
+		if s.CanFail() {
+			return true
+		}
+	}
+	return false
+}
+
+// A LabelExpr is a labeled subexpression.
+// The label can be used in actions to refer to the result of the subexperssion.
+type LabelExpr struct {
+	// Label is the text of the label, not including the :.
+	Label Text
+	Expr  Expr
+	// N is a small integer assigned to this label
+	// that is unique within the containing Rule.
+	// It is a small integer that may be used as an array index.
+	N int
+}
+
+func (e *LabelExpr) Begin() Loc    { return e.Label.Begin() }
+func (e *LabelExpr) End() Loc      { return e.Expr.End() }
+func (e *LabelExpr) Type() string  { return e.Expr.Type() }
+func (e *LabelExpr) Epsilon() bool { return e.Expr.Epsilon() }
+func (e *LabelExpr) CanFail() bool { return e.Expr.CanFail() }
+
+func (e *LabelExpr) Walk(f func(Expr) bool) bool {
+	return f(e) && e.Expr.Walk(f)
+}
+
+func (e *LabelExpr) substitute(sub map[string]string) Expr {
+	substitute := *e
+	substitute.Expr = e.Expr.substitute(sub)
+	return &substitute
+}
+
+// A WantExpr is an expression with a description, Expr !> "description".
+// The Fail tree reports a failure of the expression
+// as a single fail at its start wanting the description,
+// hiding the fails beneath it, like a named rule,
+// but without the rule.
+type WantExpr struct {
+	Expr Expr
+	// Want is the description, not including the quotes.
+	Want Text
+	// Loc is the location of the !> operator.
+	Loc Loc
+}
+
+func (e *WantExpr) Begin() Loc    { return e.Expr.Begin() }
+func (e *WantExpr) End() Loc      { return e.Want.End() }
+func (e *WantExpr) Type() string  { return e.Expr.Type() }
+func (e *WantExpr) Epsilon() bool { return e.Expr.Epsilon() }
+func (e *WantExpr) CanFail() bool { return e.Expr.CanFail() }
+
+func (e *WantExpr) Walk(f func(Expr) bool) bool {
+	return f(e) && e.Expr.Walk(f)
+}
+
+func (e *WantExpr) substitute(sub map[string]string) Expr {
+	substitute := *e
+	substitute.Expr = e.Expr.substitute(sub)
+	return &substitute
+}
+
+// A PredExpr is a non-consuming predicate expression:
+// If it succeeds (or fails, in the case of Neg),
+// return success and consume no input.
+// If it fails (or succeeds, in the case of Neg),
+// return failure and consume no input.
+// Predicate expressions allow a powerful form of lookahead.
+type PredExpr struct {
+	Expr Expr
+	// Neg indicates that the result of the predicate is negated.
+	Neg bool
+	// Loc is the location of the operator, & or !.
+	Loc Loc
+}
+
+func (e *PredExpr) Begin() Loc { return e.Loc }
+func (e *PredExpr) End() Loc   { return e.Expr.End() }
+
+// Type returns the type of the predicate expression,
+// which is a string; the value is always the empty string.
+func (e *PredExpr) Type() string { return "string" }
+
+func (e *PredExpr) Epsilon() bool { return true }
+func (e *PredExpr) CanFail() bool { return e.Neg || e.Expr.CanFail() }
+
+func (e *PredExpr) Walk(f func(Expr) bool) bool {
+	return f(e) && e.Expr.Walk(f)
+}
+
+func (e *PredExpr) substitute(sub map[string]string) Expr {
+	substitute := *e
+	substitute.Expr = e.Expr.substitute(sub)
+	return &substitute
+}
+
+// A CaptureExpr is a capture expression:
+// its value is the input text matched by its subexpression.
+type CaptureExpr struct {
+	Expr Expr
+	// Loc is the location of the $ operator.
+	Loc Loc
+}
+
+func (e *CaptureExpr) Begin() Loc { return e.Loc }
+func (e *CaptureExpr) End() Loc   { return e.Expr.End() }
+
+// Type returns the type of the capture expression,
+// which is a string; the value is the text matched by the subexpression.
+func (e *CaptureExpr) Type() string { return "string" }
+
+func (e *CaptureExpr) Epsilon() bool { return e.Expr.Epsilon() }
+func (e *CaptureExpr) CanFail() bool { return e.Expr.CanFail() }
+
+func (e *CaptureExpr) Walk(f func(Expr) bool) bool {
+	return f(e) && e.Expr.Walk(f)
+}
+
+func (e *CaptureExpr) substitute(sub map[string]string) Expr {
+	substitute := *e
+	substitute.Expr = e.Expr.substitute(sub)
+	return &substitute
+}
+
+// A DiffExpr is a difference expression, A - B:
+// it matches A, but not if B matches the same span of input.
+// For example, an identifier that is not a keyword is Ident - Keyword.
+type DiffExpr struct {
+	Expr Expr
+	// Sub is the expression subtracted from Expr.
+	Sub Expr
+	// Loc is the location of the - operator.
+	Loc Loc
+}
+
+func (e *DiffExpr) Begin() Loc    { return e.Expr.Begin() }
+func (e *DiffExpr) End() Loc      { return e.Sub.End() }
+func (e *DiffExpr) Type() string  { return e.Expr.Type() }
+func (e *DiffExpr) Epsilon() bool { return e.Expr.Epsilon() }
+func (e *DiffExpr) CanFail() bool { return true }
+
+func (e *DiffExpr) Walk(f func(Expr) bool) bool {
+	return f(e) && e.Expr.Walk(f) && e.Sub.Walk(f)
+}
+
+func (e *DiffExpr) substitute(sub map[string]string) Expr {
+	substitute := *e
+	substitute.Expr = e.Expr.substitute(sub)
+	substitute.Sub = e.Sub.substitute(sub)
+	return &substitute
+}
+
+// A RepExpr is a repetition expression, sepecifying whether the sub-expression
+// should be matched any number of times (*) or one or more times (+),
+type RepExpr struct {
+	// Op is one of * or +.
+	Op   rune
+	Expr Expr
+	// Loc is the location of the operator, * or  +.
+	Loc Loc
+}
+
+func (e *RepExpr) Begin() Loc { return e.Expr.Begin() }
+func (e *RepExpr) End() Loc   { return e.Loc }
+
+// Type returns the type of the repetition expression,
+// which is based on the type of its sub-expression.
+//
+// If the sub-expression type is string,
+// the repetition expression type is a string.
+// The value is the concatenation of all matches,
+// or the empty string if nothing matches.
+//
+// Otherwise, the type is a slice of the sub-expression type.
+// The value contains an element for each match
+// of the sub-expression.
+func (e *RepExpr) Type() string {
+	switch t := e.Expr.Type(); t {
+	case "":
+		return ""
+	case "string":
+		return t
+	default:
+		return "[]" + t
+	}
+}
+
+func (e *RepExpr) Epsilon() bool { return e.Op == '*' }
+func (e *RepExpr) CanFail() bool { return e.Op == '+' && e.Expr.CanFail() }
+
+func (e *RepExpr) Walk(f func(Expr) bool) bool {
+	return f(e) && e.Expr.Walk(f)
+}
+
+func (e *RepExpr) substitute(sub map[string]string) Expr {
+	substitute := *e
+	substitute.Expr = e.Expr.substitute(sub)
+	return &substitute
+}
+
+// An OptExpr is an optional expression, which may or may not be matched.
+type OptExpr struct {
+	Expr Expr
+	// Loc is the location of the ?.
+	Loc Loc
+
+	// OK indicates that the value of the optional expression,
+	// if its sub-expression type is not string,
+	// is a struct of the sub-expression's value and whether it matched,
+	// instead of a pointer.
+	// It is set by the Check pass with Config.OptOK.
+	OK bool
+}
+
+func (e *OptExpr) Begin() Loc { return e.Expr.Begin() }
+func (e *OptExpr) End() Loc   { return e.Loc }
+
+// Type returns the type of the optional expression,
+// which is based on the type of its sub-expression.
+//
+// If the sub-expression type is string,
+// the optional expression type is a string.
+// The value is the value of the sub-expression if it matched,
+// or the empty string if it did not match.
+//
+// Otherwise, the type is a pointer to the type of the sub-expression.
+// The value is a pointer to the sub-expression's value if it matched,
+// or a nil pointer if it did not match.
+//
+// However, if OK is true, the type is struct{ Value T; OK bool },
+// where T is the type of the sub-expression.
+// The value has the sub-expression's value and OK true if it matched,
+// or is the zero value if it did not match.
+func (e *OptExpr) Type() string {
+	switch t := e.Expr.Type(); {
+	case t == "":
+		return ""
+	case t == "string":
+		return t
+	case e.OK:
+		return "struct{ Value " + t + "; OK bool }"
+	default:
+		return "*" + e.Expr.Type()
+	}
+}
+
+func (e *OptExpr) Epsilon() bool { return true }
+func (e *OptExpr) CanFail() bool { return false }
+
+func (e *OptExpr) Walk(f func(Expr) bool) bool {
+	return f(e) && e.Expr.Walk(f)
+}
+
+func (e *OptExpr) substitute(sub map[string]string) Expr {
+	substitute := *e
+	substitute.Expr = e.Expr.substitute(sub)
+	return &substitute
+}
+
+// An Ident is an identifier referring to the name of anothe rule,
+// indicating to match that rule's expression.
+type Ident struct {
+	Name
+
+	// CallArgs, if non-nil, is the Go argument list
+	// passed to the parameters of the referenced rule,
+	// not including the parentheses.
+	CallArgs Text
+
+	// rule is the rule referred to by this identifier.
+	// It is set during check.
+	rule *Rule
+}
+
+func (e *Ident) Begin() Loc { return e.Name.Begin() }
+
+func (e *Ident) End() Loc {
+	if e.CallArgs != nil {
+		return e.CallArgs.End()
+	}
+	return e.Name.End()
+}
+
+func (e *Ident) Walk(f func(Expr) bool) bool { return f(e) }
+
+// CanFail returns whether the identifier's rule can fail.
+// Before Check, or if the identifier does not refer to a rule,
+// it conservatively returns true.
+func (e *Ident) CanFail() bool { return e.rule == nil || !e.rule.infallible }
+
+// Type returns the type of the identifier expression,
+// which is the type of its corresponding rule.
+func (e *Ident) Type() string {
+	if e.rule == nil {
+		return ""
+	}
+	return e.rule.Type()
+}
+
+// Rule returns the rule to which the identifier refers,
+// or nil if the identifier is undefined.
+// It is only valid after Check.
+func (e *Ident) Rule() *Rule { return e.rule }
+
+func (e *Ident) Epsilon() bool {
+	if e.rule == nil {
+		return false
+	}
+	return e.rule.epsilon
+}
+
+func (e *Ident) substitute(sub map[string]string) Expr {
+	if s, ok := literalArg(sub[e.Name.String()]); ok {
+		return &Literal{Text: text{str: s, begin: e.Begin(), end: e.End()}}
+	}
+	substitute := *e
+	if s, ok := sub[e.Name.String()]; ok {
+		substitute.Name = Name{
+			Name: text{
+				str:   s,
+				begin: e.Name.Begin(),
+				end:   e.Name.End(),
+			},
+		}
+	}
+	substitute.Args = make([]Text, len(e.Args))
+	for i, a := range e.Args {
+		if s, ok := sub[a.String()]; !ok {
+			substitute.Args[i] = e.Args[i]
+		} else {
+			substitute.Args[i] = text{
+				str:   s,
+				begin: a.Begin(),
+				end:   a.End(),
+			}
+		}
+	}
+	return &substitute
+}
+
+// A SubExpr simply wraps an expression.
+// It holds no extra information beyond tracking parentheses.
+// It's purpose is to allow easily re-inserting the parentheses
+// when stringifying an expression, whithout the need
+// to compute precedence inversion for each subexpression.
+type SubExpr struct {
+	Expr
+	// Open is the location of the open parenthesis.
+	// Close is the location of the close parenthesis.
+	Open, Close Loc
+
+	// Source, if non-empty, is the string literal
+	// with character class escapes, such as "\d\d",
+	// that desugars to the SubExpr.
+	// Open and Close are then the locations of its delimiters.
+	Source string
+}
+
+func (e *SubExpr) Begin() Loc    { return e.Open }
+func (e *SubExpr) End() Loc      { return e.Close }
+func (e *SubExpr) Type() string  { return e.Expr.Type() }
+func (e *SubExpr) Epsilon() bool { return e.Expr.Epsilon() }
+func (e *SubExpr) CanFail() bool { return e.Expr.CanFail() }
+
+func (e *SubExpr) Walk(f func(Expr) bool) bool {
+	return f(e) && e.Expr.Walk(f)
+}
+
+func (e *SubExpr) substitute(sub map[string]string) Expr {
+	substitute := *e
+	substitute.Expr = e.Expr.substitute(sub)
+	return &substitute
+}
+
+// A PredCode is a predicate code expression,
+// allowing predication using a Go boolean expression.
+//
+// TODO: Specify the conditions under which the expression is evaluated.
+type PredCode struct {
+	// Code is a Go boolean expression.
+	// The Begin and End locations of Code includes the { } delimiters,
+	// but the string does not.
+	Code Text
+	// Neg indicates that the result of the predicate is negated.
+	Neg bool
+	// Loc is the location of the operator, & or !.
+	Loc Loc
+
+	// Labels are the labels that are in scope of this action.
+	Labels []*LabelExpr
+}
+
+func (e *PredCode) Begin() Loc { return e.Loc }
+func (e *PredCode) End() Loc   { return e.Code.End() }
+
+// Type returns the type of the predicate code expression,
+// which is a string; the value is always the empty string.
+func (e *PredCode) Type() string { return "string" }
+
+func (e *PredCode) Epsilon() bool               { return true }
+func (e *PredCode) CanFail() bool               { return true }
+func (e *PredCode) Walk(f func(Expr) bool) bool { return f(e) }
+
+func (e *PredCode) substitute(sub map[string]string) Expr {
+	substitute := *e
+	substitute.Labels = nil
+	return &substitute
+}
+
+// A Cut is a cut, ^, committing the branch of the choice containing it:
+// if the branch fails after the cut,
+// the choice fails without trying its remaining branches.
+// A cut matches the empty string and never fails.
+type Cut struct {
+	// Loc is the location of the ^ symbol.
+	Loc Loc
+}
+
+func (e *Cut) Begin() Loc                  { return e.Loc }
+func (e *Cut) End() Loc                    { return Loc{Line: e.Loc.Line, Col: e.Loc.Col + 1} }
+func (e *Cut) Type() string                { return "string" }
+func (e *Cut) Epsilon() bool               { return true }
+func (e *Cut) CanFail() bool               { return false }
+func (e *Cut) Walk(f func(Expr) bool) bool { return f(e) }
+
+func (e *Cut) substitute(sub map[string]string) Expr {
+	substitute := *e
+	return &substitute
+}
+
+// An Empty is the empty expression, (),
+// matching the empty string, as in A <- "x" / ().
+// It never fails, and its value is the empty string.
+type Empty struct {
+	// Open and Close are the locations of the ( and ) symbols.
+	Open, Close Loc
+}
+
+func (e *Empty) Begin() Loc                  { return e.Open }
+func (e *Empty) End() Loc                    { return Loc{Line: e.Close.Line, Col: e.Close.Col + 1} }
+func (e *Empty) Type() string                { return "string" }
+func (e *Empty) Epsilon() bool               { return true }
+func (e *Empty) CanFail() bool               { return false }
+func (e *Empty) Walk(f func(Expr) bool) bool { return f(e) }
+
+func (e *Empty) substitute(sub map[string]string) Expr {
+	substitute := *e
+	return &substitute
+}
+
+// commits returns whether the expression, a branch of a choice,
+// has a cut committing the branch:
+// one that is not within a nested choice.
+func commits(e Expr) bool {
+	switch e := e.(type) {
+	case *Cut:
+		return true
+	case *Action:
+		return commits(e.Expr)
+	case *Sequence:
+		for _, sub := range e.Exprs {
+			if commits(sub) {
+				return true
+			}
+		}
+	case *LabelExpr:
+		return commits(e.Expr)
+	case *WantExpr:
+		return commits(e.Expr)
+	case *CaptureExpr:
+		return commits(e.Expr)
+	case *DiffExpr:
+		return commits(e.Expr)
+	case *SubExpr:
+		return commits(e.Expr)
+	}
+	return false
+}
+
+// A DelegateExpr delegates a region of the input to another parser:
+// %delegate(f, "open", "close") matches the open marker,
+// the text up to the first following close marker, and the close marker,
+// and parses the text between the markers by calling f.
+type DelegateExpr struct {
+	// Func is a Go expression of the delegate parser,
+	// a function of type func(string) (T, error).
+	Func string
+	// Open and Close are the markers delimiting the region.
+	Open, Close string
+	// Args is the argument list of %delegate.
+	// The Begin and End locations of Args includes the ( ) delimiters,
+	// but the string does not.
+	Args Text
+	// Loc is the location of %delegate.
+	Loc Loc
+
+	// typ is the declared result type of the rule,
+	// if the value of the expression is the rule's result.
+	typ string
+}
+
+func (e *DelegateExpr) Begin() Loc { return e.Loc }
+func (e *DelegateExpr) End() Loc   { return e.Args.End() }
+
+// Type returns the type of the delegate expression,
+// which is the declared result type of the rule,
+// if its value is the rule's result, and otherwise interface{}.
+// The value is the value returned by the delegate parser.
+func (e *DelegateExpr) Type() string {
+	if e.typ != "" {
+		return e.typ
+	}
+	return "interface{}"
+}
+
+// The open marker is never empty, so a delegate never matches epsilon.
+func (e *DelegateExpr) Epsilon() bool               { return false }
+func (e *DelegateExpr) CanFail() bool               { return true }
+func (e *DelegateExpr) Walk(f func(Expr) bool) bool { return f(e) }
+
+func (e *DelegateExpr) substitute(sub map[string]string) Expr {
+	substitute := *e
+	return &substitute
+}
+
+// A NativeExpr matches the input with a Go function:
+// %native(f) calls f in each pass,
+// so that a hot terminal may be matched by hand-optimized Go code.
+//
+// The function has the type
+//
+//	func(text string, pos int) (end int, fail *peg.Fail)
+//
+// It is called with the entire input text
+// and the byte offset at which to match.
+// If it matches, it returns the byte offset of the end of the match,
+// which must be at least pos and at most len(text), and a nil Fail.
+// Otherwise, it returns a non-nil Fail
+// with the byte offset of the failure in text and what was wanted there.
+// The function must not have side-effects,
+// since it may be called any number of times at a position.
+type NativeExpr struct {
+	// Func is a Go expression of the matcher function.
+	Func string
+	// Args is the argument list of %native.
+	// The Begin and End locations of Args includes the ( ) delimiters,
+	// but the string does not.
+	Args Text
+	// Loc is the location of %native.
+	Loc Loc
+}
+
+func (e *NativeExpr) Begin() Loc { return e.Loc }
+func (e *NativeExpr) End() Loc   { return e.Args.End() }
+
+// Type returns the type of the native expression,
+// which is a string; the value is the text matched by the function.
+func (e *NativeExpr) Type() string { return "string" }
+
+// The function may match the empty string.
+func (e *NativeExpr) Epsilon() bool               { return true }
+func (e *NativeExpr) CanFail() bool               { return true }
+func (e *NativeExpr) Walk(f func(Expr) bool) bool { return f(e) }
+
+func (e *NativeExpr) substitute(sub map[string]string) Expr {
+	substitute := *e
+	return &substitute
+}
+
+// A BlockExpr matches a raw block of the input
+// with a scan of its delimiters, instead of a loop of expressions:
+// %balanced(open, close) matches the open delimiter,
+// the text up to the matching close delimiter,
+// skipping nested pairs of open and close delimiters,
+// and the matching close delimiter;
+// %until(close) matches the text up to and including
+// the first following close delimiter.
+// An optional last argument is an escape:
+// the escape and the character following it are skipped by the scan,
+// so neither is a delimiter.
+//
+// If the open and close delimiters of %balanced are the same,
+// the delimiters do not nest;
+// the block ends at the first unescaped close delimiter.
+type BlockExpr struct {
+	// Kind is either balanced or until.
+	Kind string
+	// Open is the open delimiter of %balanced,
+	// or the empty string for %until.
+	// Close is the close delimiter.
+	// Escape is the escape, or the empty string if there is none.
+	Open, Close, Escape string
+	// Args is the argument list of the expression.
+	// The Begin and End locations of Args includes the ( ) delimiters,
+	// but the string does not.
+	Args Text
+	// Loc is the location of %balanced or %until.
+	Loc Loc
+}
+
+func (e *BlockExpr) Begin() Loc { return e.Loc }
+func (e *BlockExpr) End() Loc   { return e.Args.End() }
+
+// Type returns the type of the block expression,
+// which is a string; the value is the text of the block,
+// including its delimiters.
+func (e *BlockExpr) Type() string { return "string" }
+
+// The close delimiter is never empty, so a block never matches epsilon.
+func (e *BlockExpr) Epsilon() bool               { return false }
+func (e *BlockExpr) CanFail() bool               { return true }
+func (e *BlockExpr) Walk(f func(Expr) bool) bool { return f(e) }
+
+func (e *BlockExpr) substitute(sub map[string]string) Expr {
+	substitute := *e
+	return &substitute
+}
+
+// A Literal matches a literal text string.
+type Literal struct {
+	// Text is the text to match.
+	// The Begin and End locations of Text includes the ' or " delimiters,
+	// but the string does not.
+	Text Text
+}
+
+func (e *Literal) Begin() Loc                  { return e.Text.Begin() }
+func (e *Literal) End() Loc                    { return e.Text.End() }
+func (e *Literal) Type() string                { return "string" }
+func (e *Literal) Epsilon() bool               { return false }
+func (e *Literal) CanFail() bool               { return true }
+func (e *Literal) Walk(f func(Expr) bool) bool { return f(e) }
+
+// A literal is substituted if it is a literal template parameter.
+// Its substitute is either a literal or, for an identifier argument,
+// an identifier.
+func (e *Literal) substitute(sub map[string]string) Expr {
+	a, ok := sub[strconv.Quote(e.Text.String())]
+	if !ok {
+		substitute := *e
+		return &substitute
+	}
+	t := text{str: a, begin: e.Text.Begin(), end: e.Text.End()}
+	if s, ok := literalArg(a); ok {
+		t.str = s
+		return &Literal{Text: t}
+	}
+	return &Ident{Name: Name{Name: t}}
+}
+
+// A CharClass matches a single rune from a set of acceptable
+// (or unacceptable if Neg) runes.
+type CharClass struct {
+	// Spans are rune spans accepted (or rejected) by the character class.
+	// The 0th rune is always ≤ the 1st.
+	// Single rune matches are a span of both the same rune.
+	Spans [][2]rune
+
+	// Neg indicates that the input must not match any in the set.
+	Neg bool
+
+	// Open and Close are the Loc of [ and ] respectively.
+	Open, Close Loc
+
+	// Source, if non-empty, is a string literal
+	// of the character class escape, such as "\d",
+	// from which the CharClass was desugared.
+	// Open and Close are then the Loc of the escape.
+	Source string
+
+	// NoCase indicates that the CharClass matches
+	// a letter of a literal of a @nocase rule in either case,
+	// so its failure is that of the literal.
+	NoCase bool
+}
+
+func (e *CharClass) Begin() Loc                  { return e.Open }
+func (e *CharClass) End() Loc                    { return e.Close }
+func (e *CharClass) Type() string                { return "string" }
+func (e *CharClass) Epsilon() bool               { return false }
+func (e *CharClass) CanFail() bool               { return true }
+func (e *CharClass) Walk(f func(Expr) bool) bool { return f(e) }
+
+func (e *CharClass) substitute(sub map[string]string) Expr {
+	substitute := *e
+	return &substitute
+}
+
+// Any matches any rune.
+type Any struct {
+	// Loc is the location of the . symbol.
+	Loc Loc
+}
+
+func (e *Any) Begin() Loc                  { return e.Loc }
+func (e *Any) End() Loc                    { return Loc{Line: e.Loc.Line, Col: e.Loc.Col + 1} }
+func (e *Any) Type() string                { return "string" }
+func (e *Any) Epsilon() bool               { return false }
+func (e *Any) CanFail() bool               { return true }
+func (e *Any) Walk(f func(Expr) bool) bool { return f(e) }
+
+func (e *Any) substitute(sub map[string]string) Expr {
+	substitute := *e
+	return &substitute
+}
+
+// Merge merges the rules and directives of h,
+// parsed from the file, into g.
+func (g *Grammar) Merge(file string, h *Grammar) error {
+	if h.Prelude != nil {
+		if g.Prelude != nil {
+			return Err(h.Prelude, "prelude redefined; only one grammar file may have a prelude")
+		}
+		g.Prelude = h.Prelude
+	}
+	if h.MaxDepth > 0 {
+		if g.MaxDepth > 0 {
+			return fmt.Errorf("%s: %%maxdepth redefined", file)
+		}
+		g.MaxDepth = h.MaxDepth
+	}
+	if h.WarnSlow > 0 {
+		if g.WarnSlow > 0 {
+			return fmt.Errorf("%s: %%warnslow redefined", file)
+		}
+		g.WarnSlow = h.WarnSlow
+	}
+	if h.Newline != nil {
+		if g.Newline != nil {
+			return Err(h.Newline, "%%newline redefined")
+		}
+		g.Newline = h.Newline
+	}
+	g.InvalidBytes = g.InvalidBytes || h.InvalidBytes
+	g.Rules = append(g.Rules, h.Rules...)
+	return nil
+}
@@ -0,0 +1,812 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SchemaGrammar returns a Peggy Grammar generated from a schema
+// in the format from, either "abnf" or "jsonschema",
+// and the constructs of the schema that could not be translated.
+// The Grammar is built with the New functions.
+func SchemaGrammar(in io.Reader, file, from string) (*Grammar, Errors, error) {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, Errors{}, err
+	}
+	switch from {
+	case "abnf":
+		return abnfGrammar(string(data), file)
+	case "jsonschema":
+		g, err := jsonSchemaGrammar(data)
+		return g, Errors{}, err
+	default:
+		return nil, Errors{}, errors.New("bad schema format " + from + ": want abnf or jsonschema")
+	}
+}
+
+// schemaPrelude is the prelude of generated grammars.
+const schemaPrelude = "\npackage main\n"
+
+// An abnf parses an RFC 5234 ABNF rule list,
+// with the RFC 7405 %s and %i string prefixes.
+type abnf struct {
+	importer
+	// names maps the lower case of each rule name
+	// to its Peggy identifier, from its first definition.
+	names map[string]string
+	// defs are the alternatives of each rule, keyed by identifier.
+	defs  map[string][]Expr
+	order []string
+	// refs are the identifiers referenced by rules.
+	refs    map[string]bool
+	dropped Errors
+}
+
+// abnfGrammar returns the Peggy Grammar of an ABNF rule list.
+//
+// ABNF alternatives are unordered, so they become longest choices,
+// which match the longest alternative, but, unlike ABNF,
+// never backtrack into a shorter alternative
+// if the rest of the input then fails to match.
+// Core rules, such as ALPHA and DIGIT, are added as needed.
+// Prose values, <...>, cannot be translated,
+// so they become !"", which never matches,
+// and each is returned in dropped.
+func abnfGrammar(src, file string) (*Grammar, Errors, error) {
+	p := &abnf{
+		importer: importer{file: file, src: []rune(src), line: 1, col: 1},
+		names:    make(map[string]string),
+		defs:     make(map[string][]Expr),
+		refs:     make(map[string]bool),
+	}
+	if err := p.ruleList(); err != nil {
+		return nil, Errors{}, err
+	}
+	if err := p.addCoreRules(); err != nil {
+		return nil, Errors{}, err
+	}
+	var rules []Rule
+	for _, name := range p.order {
+		expr, err := NewLongestChoice(p.defs[name]...)
+		if err != nil {
+			return nil, Errors{}, err
+		}
+		r, err := NewRule(name, expr)
+		if err != nil {
+			return nil, Errors{}, err
+		}
+		rules = append(rules, r)
+	}
+	g, err := NewGrammar(schemaPrelude, rules...)
+	return g, p.dropped, err
+}
+
+// abnfCoreRules are the core rules of RFC 5234 Appendix B.1.
+var abnfCoreRules = map[string]string{
+	"alpha":  "ALPHA = %x41-5A / %x61-7A\n",
+	"bit":    "BIT = \"0\" / \"1\"\n",
+	"char":   "CHAR = %x01-7F\n",
+	"cr":     "CR = %x0D\n",
+	"crlf":   "CRLF = CR LF\n",
+	"ctl":    "CTL = %x00-1F / %x7F\n",
+	"digit":  "DIGIT = %x30-39\n",
+	"dquote": "DQUOTE = %x22\n",
+	"hexdig": "HEXDIG = DIGIT / \"A\" / \"B\" / \"C\" / \"D\" / \"E\" / \"F\"\n",
+	"htab":   "HTAB = %x09\n",
+	"lf":     "LF = %x0A\n",
+	"lwsp":   "LWSP = *(WSP / CRLF WSP)\n",
+	"octet":  "OCTET = %x00-FF\n",
+	"sp":     "SP = %x20\n",
+	"vchar":  "VCHAR = %x21-7E\n",
+	"wsp":    "WSP = SP / HTAB\n",
+}
+
+// addCoreRules adds the core rules referenced but not defined,
+// and those that they reference, in turn.
+func (p *abnf) addCoreRules() error {
+	for {
+		var missing []string
+		for id := range p.refs {
+			if _, ok := p.defs[id]; !ok {
+				missing = append(missing, id)
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		sort.Strings(missing)
+		for _, id := range missing {
+			core, ok := abnfCoreRules[strings.ToLower(id)]
+			if !ok {
+				return fmt.Errorf("%s: rule %s undefined", p.file, id)
+			}
+			p.importer = importer{file: "<core>", src: []rune(core), line: 1, col: 1}
+			if err := p.ruleList(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (p *abnf) ruleList() error {
+	for {
+		p.blankLines()
+		if p.peek() == importEOF {
+			return nil
+		}
+		if err := p.rule(); err != nil {
+			return err
+		}
+	}
+}
+
+// blankLines skips lines containing only whitespace and comments.
+func (p *abnf) blankLines() {
+	for {
+		s := p.save()
+		p.wsp()
+		switch p.peek() {
+		case '\r', '\n':
+			p.newline()
+		case importEOF:
+			return
+		default:
+			p.restore(s)
+			return
+		}
+	}
+}
+
+// wsp skips spaces, tabs, and a comment on the current line.
+func (p *abnf) wsp() {
+	for {
+		switch p.peek() {
+		case ' ', '\t':
+			p.next()
+		case ';':
+			for p.peek() != '\n' && p.peek() != '\r' && p.peek() != importEOF {
+				p.next()
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *abnf) newline() {
+	if p.peek() == '\r' {
+		p.next()
+	}
+	if p.peek() == '\n' {
+		p.next()
+	}
+}
+
+// cwsp skips whitespace, comments, and line breaks
+// followed by whitespace, which continue the rule.
+func (p *abnf) cwsp() {
+	for {
+		p.wsp()
+		s := p.save()
+		if r := p.peek(); r != '\r' && r != '\n' {
+			return
+		}
+		p.newline()
+		if r := p.peek(); r != ' ' && r != '\t' && r != '\r' && r != '\n' && r != ';' {
+			p.restore(s)
+			return
+		}
+	}
+}
+
+func (p *abnf) rule() error {
+	loc := p.loc()
+	name, err := p.ruleName()
+	if err != nil {
+		return err
+	}
+	id, seen := p.names[strings.ToLower(name)]
+	if !seen {
+		id = strings.Replace(name, "-", "_", -1)
+		p.names[strings.ToLower(name)] = id
+	}
+	p.cwsp()
+	if p.next() != '=' {
+		return p.errorf("expected =")
+	}
+	incremental := p.peek() == '/'
+	if incremental {
+		p.next()
+	}
+	switch {
+	case incremental && p.defs[id] == nil:
+		return Err(loc, "rule %s extended with =/ before it is defined", name)
+	case !incremental && p.defs[id] != nil:
+		return Err(loc, "rule %s redefined", name)
+	}
+	p.cwsp()
+	alts, err := p.alternation()
+	if err != nil {
+		return err
+	}
+	p.cwsp()
+	if r := p.peek(); r != '\r' && r != '\n' && r != importEOF {
+		return p.errorf("unexpected %q", r)
+	}
+	if p.defs[id] == nil {
+		p.order = append(p.order, id)
+	}
+	p.defs[id] = append(p.defs[id], alts...)
+	return nil
+}
+
+func (p *abnf) ruleName() (string, error) {
+	var s []rune
+	if r := p.peek(); r > unicode.MaxASCII || !unicode.IsLetter(r) {
+		return "", p.errorf("expected a rule name")
+	}
+	for r := p.peek(); r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-'); r = p.peek() {
+		s = append(s, p.next())
+	}
+	return string(s), nil
+}
+
+// alternation returns the alternatives.
+func (p *abnf) alternation() ([]Expr, error) {
+	var alts []Expr
+	for {
+		e, err := p.concatenation()
+		if err != nil {
+			return nil, err
+		}
+		alts = append(alts, e)
+		s := p.save()
+		p.cwsp()
+		if p.peek() != '/' {
+			p.restore(s)
+			return alts, nil
+		}
+		p.next()
+		p.cwsp()
+	}
+}
+
+func (p *abnf) concatenation() (Expr, error) {
+	var exprs []Expr
+	for {
+		e, err := p.repetition()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+		s := p.save()
+		p.cwsp()
+		if !p.atElement() {
+			p.restore(s)
+			return NewSequence(exprs...)
+		}
+	}
+}
+
+// atElement returns whether an element, or its repeat, is next.
+func (p *abnf) atElement() bool {
+	r := p.peek()
+	return r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) ||
+		strings.ContainsRune(`*(["%<`, r)
+}
+
+func (p *abnf) repetition() (Expr, error) {
+	min, max := 1, 1
+	if r := p.peek(); r == '*' || unicode.IsDigit(r) {
+		var ok bool
+		n, okMin := p.number()
+		min, max = n, n
+		if p.peek() == '*' {
+			p.next()
+			if !okMin {
+				min = 0
+			}
+			if max, ok = p.number(); !ok {
+				max = -1
+			}
+		}
+		if max >= 0 && max < min {
+			return nil, p.errorf("bad repeat %d*%d", min, max)
+		}
+	}
+	e, err := p.element()
+	if err != nil {
+		return nil, err
+	}
+	return repeat(e, min, max)
+}
+
+// number returns a decimal number and whether there was one.
+func (p *abnf) number() (int, bool) {
+	var s []rune
+	for unicode.IsDigit(p.peek()) {
+		s = append(s, p.next())
+	}
+	n, err := strconv.Atoi(string(s))
+	return n, err == nil
+}
+
+// repeat returns an expression matching e from min to max times,
+// or unbounded if max is negative.
+func repeat(e Expr, min, max int) (Expr, error) {
+	switch {
+	case min == 1 && max == 1:
+		return e, nil
+	case max == 0:
+		return NewLiteral(""), nil
+	case max < 0 && min <= 1:
+		op := '*'
+		if min == 1 {
+			op = '+'
+		}
+		return NewRepExpr(op, e)
+	}
+	var exprs []Expr
+	for i := 0; i < min; i++ {
+		exprs = append(exprs, e)
+	}
+	if max < 0 {
+		plus, err := NewRepExpr('+', e)
+		if err != nil {
+			return nil, err
+		}
+		exprs[len(exprs)-1] = plus
+		return NewSequence(exprs...)
+	}
+	var opt Expr
+	for i := min; i < max; i++ {
+		var err error
+		if opt == nil {
+			opt, err = NewRepExpr('?', e)
+		} else if opt, err = NewSequence(e, opt); err == nil {
+			opt, err = NewRepExpr('?', opt)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opt != nil {
+		exprs = append(exprs, opt)
+	}
+	return NewSequence(exprs...)
+}
+
+func (p *abnf) element() (Expr, error) {
+	switch r := p.peek(); {
+	case r <= unicode.MaxASCII && unicode.IsLetter(r):
+		name, err := p.ruleName()
+		if err != nil {
+			return nil, err
+		}
+		id, ok := p.names[strings.ToLower(name)]
+		if !ok {
+			id = strings.Replace(name, "-", "_", -1)
+			p.names[strings.ToLower(name)] = id
+		}
+		p.refs[id] = true
+		return NewIdent(id)
+	case r == '(' || r == '[':
+		p.next()
+		p.cwsp()
+		alts, err := p.alternation()
+		if err != nil {
+			return nil, err
+		}
+		p.cwsp()
+		close := ')'
+		if r == '[' {
+			close = ']'
+		}
+		if p.next() != close {
+			return nil, p.errorf("expected %c", close)
+		}
+		e, err := NewLongestChoice(alts...)
+		if err != nil || r == '(' {
+			return e, err
+		}
+		return NewRepExpr('?', e)
+	case r == '"':
+		return p.charVal(true)
+	case r == '%':
+		p.next()
+		switch p.peek() {
+		case 's', 'S':
+			p.next()
+			return p.charVal(false)
+		case 'i', 'I':
+			p.next()
+			return p.charVal(true)
+		}
+		return p.numVal()
+	case r == '<':
+		loc := p.loc()
+		for p.peek() != '>' && p.peek() != importEOF {
+			p.next()
+		}
+		if p.next() != '>' {
+			return nil, p.errorf("unterminated prose value")
+		}
+		p.dropped.add(text{begin: loc, end: p.loc()}, "dropped prose value")
+		return NewPredExpr(true, NewLiteral(""))
+	default:
+		return nil, p.errorf("unexpected %q", r)
+	}
+}
+
+// charVal returns the expression of a quoted string,
+// which is case-insensitive if fold.
+func (p *abnf) charVal(fold bool) (Expr, error) {
+	if p.next() != '"' {
+		return nil, p.errorf("expected \"")
+	}
+	var s []rune
+	for p.peek() != '"' {
+		if r := p.peek(); r == importEOF || r == '\n' || r == '\r' {
+			return nil, p.errorf("unterminated string")
+		}
+		s = append(s, p.next())
+	}
+	p.next()
+	if !fold {
+		return NewLiteral(string(s)), nil
+	}
+	e := foldLiteral(NewText(string(s)))
+	if sub, ok := e.(*SubExpr); ok {
+		// Rebuild the folded sequence, located at BuiltLoc.
+		return NewSequence(sub.Expr.(*Sequence).Exprs...)
+	}
+	return e, nil
+}
+
+// numVal returns the expression of a %b, %d, or %x numeric value,
+// following the %.
+// The values are Unicode code points.
+func (p *abnf) numVal() (Expr, error) {
+	base := 0
+	switch p.next() {
+	case 'b', 'B':
+		base = 2
+	case 'd', 'D':
+		base = 10
+	case 'x', 'X':
+		base = 16
+	default:
+		return nil, p.errorf("expected b, d, or x")
+	}
+	r, err := p.numRune(base)
+	if err != nil {
+		return nil, err
+	}
+	switch p.peek() {
+	case '-':
+		p.next()
+		hi, err := p.numRune(base)
+		if err != nil {
+			return nil, err
+		}
+		return NewCharClass(false, [2]rune{r, hi})
+	case '.':
+		s := []rune{r}
+		for p.peek() == '.' {
+			p.next()
+			r, err := p.numRune(base)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, r)
+		}
+		return NewLiteral(string(s)), nil
+	}
+	return NewLiteral(string(r)), nil
+}
+
+func (p *abnf) numRune(base int) (rune, error) {
+	var s []rune
+	for {
+		r := unicode.ToLower(p.peek())
+		if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f') {
+			break
+		}
+		s = append(s, p.next())
+	}
+	n, err := strconv.ParseUint(string(s), base, 21)
+	if err != nil || n > unicode.MaxRune {
+		return 0, p.errorf("bad numeric value %q", string(s))
+	}
+	return rune(n), nil
+}
+
+// A jsonSchema is the subset of a JSON Schema
+// from which jsonSchemaGrammar generates rules.
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 interface{}            `json:"type"`
+	Enum                 []interface{}          `json:"enum"`
+	Const                interface{}            `json:"const"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	AdditionalProperties interface{}            `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	AnyOf                []*jsonSchema          `json:"anyOf"`
+	OneOf                []*jsonSchema          `json:"oneOf"`
+	Definitions          map[string]*jsonSchema `json:"definitions"`
+	Defs                 map[string]*jsonSchema `json:"$defs"`
+	hasConst             bool
+}
+
+func (s *jsonSchema) UnmarshalJSON(data []byte) error {
+	type plain jsonSchema
+	if err := json.Unmarshal(data, (*plain)(s)); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	_, s.hasConst = fields["const"]
+	return nil
+}
+
+// jsonSchemaGrammar returns a Peggy Grammar
+// accepting the JSON documents valid according to a JSON Schema.
+//
+// Only the structure of the schema is translated:
+// types, properties, additionalProperties, items,
+// enum, const, anyOf, oneOf, and local $refs to definitions.
+// Other keywords, such as required, minimum, or pattern, are ignored,
+// so the grammar may accept some invalid documents.
+// The properties of an object are accepted in any order,
+// and may repeat.
+func jsonSchemaGrammar(data []byte) (*Grammar, error) {
+	var root jsonSchema
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	js := &jsonSchemaRules{
+		defs:  make(map[string]*jsonSchema),
+		names: make(map[*jsonSchema]string),
+		used:  map[string]bool{"Document": true},
+	}
+	for _, name := range jsonValueRules {
+		js.used[name] = true
+	}
+	for name, s := range root.Definitions {
+		js.defs["#/definitions/"+name] = s
+	}
+	for name, s := range root.Defs {
+		js.defs["#/$defs/"+name] = s
+	}
+	doc := js.check(NewSequence(
+		js.check(NewIdent("_")),
+		js.rule("Root", &root),
+		js.check(NewIdent("_")),
+		js.check(NewPredExpr(true, NewAny())),
+	))
+	rules := append([]Rule{js.newRule("Document", doc)}, js.rules...)
+	if js.err != nil {
+		return nil, js.err
+	}
+	values, err := Parse(strings.NewReader(jsonValueSource), "<json>")
+	if err != nil {
+		return nil, err
+	}
+	return NewGrammar(schemaPrelude, append(rules, values.Rules...)...)
+}
+
+// jsonSchemaRules are the rules generated from a JSON Schema.
+type jsonSchemaRules struct {
+	// defs are the schemas that may be referenced by $ref.
+	defs map[string]*jsonSchema
+	// names are the rule names of generated schemas.
+	names map[*jsonSchema]string
+	// used are the rule names in use.
+	used  map[string]bool
+	rules []Rule
+	// err is the first error returned by a New function.
+	err error
+}
+
+// check returns the Expr, recording the error, if any.
+func (js *jsonSchemaRules) check(e Expr, err error) Expr {
+	if err != nil && js.err == nil {
+		js.err = err
+	}
+	return e
+}
+
+func (js *jsonSchemaRules) newRule(name string, e Expr) Rule {
+	r, err := NewRule(name, e)
+	js.check(nil, err)
+	return r
+}
+
+// rule returns an identifier referencing the rule
+// generated for the schema, generating it if needed,
+// with a name based on name.
+func (js *jsonSchemaRules) rule(name string, s *jsonSchema) Expr {
+	if n, ok := js.names[s]; ok {
+		return js.check(NewIdent(n))
+	}
+	name = js.unique(name)
+	js.names[s] = name
+	i := len(js.rules)
+	js.rules = append(js.rules, Rule{})
+	js.rules[i] = js.newRule(name, js.expr(name, s))
+	return js.check(NewIdent(name))
+}
+
+// unique returns an unused identifier based on the name.
+func (js *jsonSchemaRules) unique(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if isIdentRune(r) {
+			return r
+		}
+		return '_'
+	}, name)
+	if r, _ := utf8.DecodeRuneInString(name); !unicode.IsLetter(r) && r != '_' {
+		name = "_" + name
+	}
+	base := name
+	for i := 2; js.used[name]; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	js.used[name] = true
+	return name
+}
+
+func (js *jsonSchemaRules) expr(name string, s *jsonSchema) Expr {
+	switch {
+	case s.Ref != "":
+		def, ok := js.defs[s.Ref]
+		if !ok {
+			return js.check(nil, errors.New("unsupported $ref "+s.Ref))
+		}
+		return js.rule(s.Ref[strings.LastIndex(s.Ref, "/")+1:], def)
+	case s.hasConst:
+		return js.literal(s.Const)
+	case s.Enum != nil:
+		var alts []Expr
+		for _, v := range s.Enum {
+			alts = append(alts, js.literal(v))
+		}
+		return js.check(NewLongestChoice(alts...))
+	case s.AnyOf != nil || s.OneOf != nil:
+		var alts []Expr
+		for i, sub := range append(s.AnyOf, s.OneOf...) {
+			alts = append(alts, js.rule(name+"_"+strconv.Itoa(i+1), sub))
+		}
+		return js.check(NewLongestChoice(alts...))
+	}
+	var types []string
+	switch t := s.Type.(type) {
+	case string:
+		types = []string{t}
+	case []interface{}:
+		for _, t := range t {
+			if t, ok := t.(string); ok {
+				types = append(types, t)
+			}
+		}
+	}
+	if len(types) == 0 {
+		return js.check(NewIdent("Value"))
+	}
+	var alts []Expr
+	for _, t := range types {
+		switch t {
+		case "object":
+			alts = append(alts, js.object(name, s))
+		case "array":
+			alts = append(alts, js.array(name, s))
+		case "string", "number", "integer", "boolean", "null":
+			alts = append(alts, js.check(NewIdent(strings.Title(t))))
+		default:
+			return js.check(nil, errors.New("unsupported type "+t))
+		}
+	}
+	return js.check(NewLongestChoice(alts...))
+}
+
+func (js *jsonSchemaRules) object(name string, s *jsonSchema) Expr {
+	var props []string
+	for p := range s.Properties {
+		props = append(props, p)
+	}
+	sort.Strings(props)
+	var members []Expr
+	for _, p := range props {
+		members = append(members, js.check(NewSequence(
+			js.literal(p),
+			js.check(NewIdent("_")),
+			NewLiteral(":"),
+			js.check(NewIdent("_")),
+			js.rule(name+"_"+p, s.Properties[p]),
+		)))
+	}
+	// The schema of additional properties is not translated.
+	if a, ok := s.AdditionalProperties.(bool); !ok || a {
+		members = append(members, js.check(NewIdent("Member")))
+	}
+	if len(members) == 0 {
+		return js.check(NewSequence(NewLiteral("{"), js.check(NewIdent("_")), NewLiteral("}")))
+	}
+	member := js.unique(name + "_member")
+	js.rules = append(js.rules, js.newRule(member, js.check(NewLongestChoice(members...))))
+	return js.list("{", member, "}")
+}
+
+func (js *jsonSchemaRules) array(name string, s *jsonSchema) Expr {
+	if s.Items == nil {
+		return js.list("[", "Value", "]")
+	}
+	return js.list("[", js.rule(name+"_item", s.Items).String(), "]")
+}
+
+// list returns an expression matching a comma-separated list
+// of the rule, delimited by open and close.
+func (js *jsonSchemaRules) list(open, rule, close string) Expr {
+	rest := js.check(NewSequence(
+		NewLiteral(","),
+		js.check(NewIdent("_")),
+		js.check(NewIdent(rule)),
+		js.check(NewIdent("_")),
+	))
+	items := js.check(NewSequence(
+		js.check(NewIdent(rule)),
+		js.check(NewIdent("_")),
+		js.check(NewRepExpr('*', rest)),
+	))
+	return js.check(NewSequence(
+		NewLiteral(open),
+		js.check(NewIdent("_")),
+		js.check(NewRepExpr('?', items)),
+		NewLiteral(close),
+	))
+}
+
+// literal returns a literal matching the JSON encoding of v.
+func (js *jsonSchemaRules) literal(v interface{}) Expr {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return js.check(nil, fmt.Errorf("unsupported enum or const value %v", v))
+	}
+	data, err := json.Marshal(v)
+	js.check(nil, err)
+	return NewLiteral(string(data))
+}
+
+// jsonValueSource is the Peggy source of the rules
+// of JSON values of each type, referenced by the generated rules.
+const jsonValueSource = `Value <- Object/Array/String/Number/Boolean/Null
+Object <- "{" _ (Member _ ("," _ Member _)*)? "}"
+Member <- String _ ":" _ Value
+Array <- "[" _ (Value _ ("," _ Value _)*)? "]"
+String <- "\"" ([^"\\\x00-\x1f]/"\\" (["\\/bfnrt]/"u" [0-9a-fA-F] [0-9a-fA-F] [0-9a-fA-F] [0-9a-fA-F]))* "\""
+Number <- Integer ("." [0-9]+)? ([eE] [+\-]? [0-9]+)?
+Integer <- "-"? ("0"/[1-9] [0-9]*)
+Boolean <- "true"/"false"
+Null <- "null"
+_ <- [ \t\r\n]*
+`
+
+// jsonValueRules are the names of the rules in jsonValueSource.
+var jsonValueRules = []string{"Value", "Object", "Member", "Array", "String", "Number", "Integer", "Boolean", "Null", "_"}
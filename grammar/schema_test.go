@@ -0,0 +1,161 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		in      string
+		want    string
+		dropped []string
+		err     string
+	}{
+		{
+			name: "abnf",
+			from: "abnf",
+			in: `; A comment.
+date = year "-" 2DIGIT "-" 2DIGIT
+year = 4DIGIT
+greeting = "Hi" / %s"Yo" ; comment
+  / <anything polite>
+greeting =/ %x48.65.79
+list = elem *("," elem) [";"]
+elem = 1*3ALPHA / %d48-57 / 2*HEXDIG
+`,
+			want: `{
+package main
+}
+date <- year "-" (DIGIT DIGIT) "-" (DIGIT DIGIT)
+year <- DIGIT DIGIT DIGIT DIGIT
+greeting <- [Hh] [Ii]|"Yo"|!""|"Hey"
+list <- elem ("," elem)* ";"?
+elem <- ALPHA (ALPHA ALPHA?)?|[0-9]|HEXDIG HEXDIG+
+ALPHA <- [A-Z]|[a-z]
+DIGIT <- [0-9]
+HEXDIG <- DIGIT|[Aa]|[Bb]|[Cc]|[Dd]|[Ee]|[Ff]
+`,
+			dropped: []string{
+				"test.file:5.5,5.22: dropped prose value",
+			},
+		},
+		{
+			name: "jsonschema",
+			from: "jsonschema",
+			in: `{
+	"type": "object",
+	"properties": {
+		"kind": {"enum": ["a", "b", 1, null]},
+		"tags": {"type": "array", "items": {"type": "string"}},
+		"point": {"$ref": "#/definitions/point"},
+		"id": {"anyOf": [{"type": "integer"}, {"const": true}]}
+	},
+	"additionalProperties": false,
+	"definitions": {
+		"point": {"type": ["array", "null"], "items": {"type": "number"}}
+	}
+}`,
+			want: `{
+package main
+}
+Document <- _ Root _ !.
+Root <- "{" _ (Root_member _ ("," _ Root_member _)*)? "}"
+Root_id <- Root_id_1|Root_id_2
+Root_id_1 <- Integer
+Root_id_2 <- "true"
+Root_kind <- "\"a\""|"\"b\""|"1"|"null"
+Root_point <- point
+point <- "[" _ (point_item _ ("," _ point_item _)*)? "]"|Null
+point_item <- Number
+Root_tags <- "[" _ (Root_tags_item _ ("," _ Root_tags_item _)*)? "]"
+Root_tags_item <- String
+Root_member <- "\"id\"" _ ":" _ Root_id|"\"kind\"" _ ":" _ Root_kind|"\"point\"" _ ":" _ Root_point|"\"tags\"" _ ":" _ Root_tags
+` + jsonValueSource,
+		},
+		{
+			name: "=/ before =",
+			from: "abnf",
+			in:   "a =/ \"x\"\n",
+			err:  "^test.file:1.1: rule a extended with =/ before it is defined",
+		},
+		{
+			name: "redefined",
+			from: "abnf",
+			in:   "a = \"x\"\nA = \"y\"\n",
+			err:  "^test.file:2.1: rule A redefined",
+		},
+		{
+			name: "undefined",
+			from: "abnf",
+			in:   "a = b\n",
+			err:  "^test.file: rule b undefined",
+		},
+		{
+			name: "unsupported $ref",
+			from: "jsonschema",
+			in:   `{"$ref": "http://example.com/schema"}`,
+			err:  "^unsupported \\$ref http://example.com/schema",
+		},
+		{
+			name: "bad format",
+			from: "yacc",
+			in:   `a = "x"`,
+			err:  "^bad schema format yacc: want abnf or jsonschema",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			g, dropped, err := SchemaGrammar(strings.NewReader(test.in), "test.file", test.from)
+			if test.err != "" {
+				if err == nil || !regexp.MustCompile(test.err).MatchString(err.Error()) {
+					t.Fatalf("SchemaGrammar(%q, _, %s)=_, _, %v, want matching %q",
+						test.in, test.from, err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SchemaGrammar(%q, _, %s)=_, _, %v, want nil", test.in, test.from, err)
+			}
+			var b strings.Builder
+			if err := WriteImported(&b, g); err != nil {
+				t.Fatalf("WriteImported(_, _)=%v, want nil", err)
+			}
+			if b.String() != test.want {
+				t.Errorf("SchemaGrammar(%q, _, %s) wrote\n%s\nwant\n%s",
+					test.in, test.from, b.String(), test.want)
+			}
+			var got []string
+			for _, e := range dropped.Errs {
+				got = append(got, e.Error())
+			}
+			if strings.Join(got, "\n") != strings.Join(test.dropped, "\n") {
+				t.Errorf("SchemaGrammar(%q, _, %s) dropped\n%s\nwant\n%s",
+					test.in, test.from, strings.Join(got, "\n"), strings.Join(test.dropped, "\n"))
+			}
+
+			// The generated grammar is a valid Peggy grammar.
+			pg, err := Parse(strings.NewReader(b.String()), "schema")
+			if err != nil {
+				t.Fatalf("Parse(%q)=_, %v, want _,nil", b.String(), err)
+			}
+			if err := Check(pg); err != nil {
+				t.Errorf("Check(%q)=%v, want nil", b.String(), err)
+			}
+			if err := Check(g); err != nil {
+				t.Errorf("Check(built)=%v, want nil", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"strings"
+)
+
+// A SourceMap associates the functions of a generated parser
+// with the grammar rules from which they were generated,
+// and the code of each expression with the expression's grammar location.
+// Line numbers of the generated code start at 1.
+type SourceMap struct {
+	// Grammar is the grammar file name.
+	Grammar string `json:"grammar"`
+
+	// Funcs are the generated rule functions,
+	// in order of their appearance in the generated code.
+	Funcs []SourceMapFunc `json:"funcs"`
+}
+
+// A SourceMapFunc is a generated rule function.
+type SourceMapFunc struct {
+	// Name is the name of the function.
+	Name string `json:"name"`
+	// Rule is the name of the rule.
+	Rule string `json:"rule"`
+	// Line and EndLine are the first and last lines of the function.
+	Line    int `json:"line"`
+	EndLine int `json:"endLine"`
+	// Loc is the grammar location of the rule.
+	Loc SourceMapLoc `json:"loc"`
+	// Exprs are the expressions whose code is in the function,
+	// in order of their appearance in the generated code.
+	Exprs []SourceMapExpr `json:"exprs"`
+}
+
+// A SourceMapExpr is the generated code of an expression.
+type SourceMapExpr struct {
+	// Line is the first line of the expression's code.
+	// The code extends to the line before the next SourceMapExpr
+	// that is not nested within the expression,
+	// which is one with a Loc that is not within this Loc.
+	Line int `json:"line"`
+	// Loc is the grammar location of the expression.
+	Loc SourceMapLoc `json:"loc"`
+}
+
+// A SourceMapLoc is a range of grammar locations.
+type SourceMapLoc struct {
+	Line    int `json:"line"`
+	Col     int `json:"col"`
+	EndLine int `json:"endLine"`
+	EndCol  int `json:"endCol"`
+}
+
+func sourceMapLoc(l Located) SourceMapLoc {
+	b, e := l.Begin(), l.End()
+	return SourceMapLoc{Line: b.Line, Col: b.Col, EndLine: e.Line, EndCol: e.Col}
+}
+
+// sourceMapPrefix begins the marker comments of GenerateSourceMap.
+// It is not a //word: directive comment,
+// since gofmt moves those to the end of their comment group.
+const sourceMapPrefix = "// peggy-expr "
+
+func sourceMapMarker(e Expr) string {
+	b, end := e.Begin(), e.End()
+	return fmt.Sprintf("%s%d.%d,%d.%d\n", sourceMapPrefix, b.Line, b.Col, end.Line, end.Col)
+}
+
+// GenerateSourceMap generates a parser for the rules, as Generate,
+// and writes its SourceMap to m as JSON.
+func (c Config) GenerateSourceMap(w, m io.Writer, file string, gr *Grammar) error {
+	c.sourceMap = true
+	var marked bytes.Buffer
+	if err := c.Generate(&marked, file, gr); err != nil {
+		io.Copy(w, &marked)
+		return err
+	}
+
+	// Remove the marker lines, recording the expression location
+	// for the line following each.
+	var src strings.Builder
+	exprs := make(map[int][]SourceMapLoc)
+	var line int
+	for _, l := range strings.SplitAfter(marked.String(), "\n") {
+		t := strings.TrimSpace(l)
+		if !strings.HasPrefix(t, sourceMapPrefix) {
+			src.WriteString(l)
+			line++
+			continue
+		}
+		var loc SourceMapLoc
+		if _, err := fmt.Sscanf(strings.TrimPrefix(t, sourceMapPrefix), "%d.%d,%d.%d",
+			&loc.Line, &loc.Col, &loc.EndLine, &loc.EndCol); err != nil {
+			return err
+		}
+		exprs[line+1] = append(exprs[line+1], loc)
+	}
+	if _, err := io.WriteString(w, src.String()); err != nil {
+		return err
+	}
+
+	rules := make(map[string]*Rule)
+	for _, r := range gr.CheckedRules {
+		for _, pass := range []string{"Accepts", "Node", "Fail", "Action", "Matches"} {
+			rules[c.Prefix+r.Name.Ident()+pass] = r
+		}
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src.String(), 0)
+	if err != nil {
+		return err
+	}
+	sm := SourceMap{Grammar: file, Funcs: []SourceMapFunc{}}
+	for _, d := range f.Decls {
+		fun, ok := d.(*ast.FuncDecl)
+		if !ok || fun.Recv != nil || rules[fun.Name.Name] == nil {
+			continue
+		}
+		r := rules[fun.Name.Name]
+		smf := SourceMapFunc{
+			Name:    fun.Name.Name,
+			Rule:    r.Name.String(),
+			Line:    fset.Position(fun.Pos()).Line,
+			EndLine: fset.Position(fun.End()).Line,
+			Loc:     sourceMapLoc(r),
+			Exprs:   []SourceMapExpr{},
+		}
+		for l := smf.Line; l <= smf.EndLine; l++ {
+			for _, loc := range exprs[l] {
+				smf.Exprs = append(smf.Exprs, SourceMapExpr{Line: l, Loc: loc})
+			}
+		}
+		sm.Funcs = append(sm.Funcs, smf)
+	}
+	enc := json.NewEncoder(m)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sm)
+}
@@ -0,0 +1,110 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSourceMap(t *testing.T) {
+	const in = `{
+package p
+}
+A <- b:B C<B> { return string(b) }
+B <- "b"+
+C<X> <- X / "c"`
+	g, err := Parse(strings.NewReader(in), "test.peggy")
+	if err != nil {
+		t.Fatalf("Parse(%q)=_, %v, want _,nil", in, err)
+	}
+	if err := Check(g); err != nil {
+		t.Fatalf("Check(%q)=%v, want nil", in, err)
+	}
+	var src, data strings.Builder
+	cfg := Config{Prefix: "_"}
+	if err := cfg.GenerateSourceMap(&src, &data, "test.peggy", g); err != nil {
+		t.Fatalf("GenerateSourceMap(_, _, _, %q)=%v, want nil", in, err)
+	}
+	var sm SourceMap
+	if err := json.Unmarshal([]byte(data.String()), &sm); err != nil {
+		t.Fatalf("json.Unmarshal(%q)=%v, want nil", data.String(), err)
+	}
+	if sm.Grammar != "test.peggy" {
+		t.Errorf("Grammar=%q, want test.peggy", sm.Grammar)
+	}
+
+	var funcs []string
+	for _, f := range sm.Funcs {
+		funcs = append(funcs, f.Name+" "+f.Rule)
+	}
+	want := []string{
+		"_AMatches A",
+		"_AAccepts A", "_ANode A", "_AFail A", "_AAction A",
+		"_BAccepts B", "_BNode B", "_BFail B", "_BAction B",
+		"_C__BAccepts C<B>", "_C__BNode C<B>", "_C__BFail C<B>", "_C__BAction C<B>",
+	}
+	if !reflect.DeepEqual(funcs, want) {
+		t.Errorf("Funcs=%v, want %v", funcs, want)
+	}
+
+	lines := strings.Split(src.String(), "\n")
+	for _, f := range sm.Funcs {
+		if !strings.HasPrefix(lines[f.Line-1], "func "+f.Name+"(") {
+			t.Errorf("%s Line %d is %q", f.Name, f.Line, lines[f.Line-1])
+		}
+		if lines[f.EndLine-1] != "}" {
+			t.Errorf("%s EndLine %d is %q", f.Name, f.EndLine, lines[f.EndLine-1])
+		}
+	}
+
+	// The first expression of _BAccepts is the "b"+ on line 5.
+	b := sm.Funcs[5]
+	if len(b.Exprs) < 2 {
+		t.Fatalf("%s has %d Exprs, want at least 2", b.Name, len(b.Exprs))
+	}
+	if got, want := b.Loc, (SourceMapLoc{Line: 5, Col: 1, EndLine: 5, EndCol: 9}); got != want {
+		t.Errorf("%s Loc=%+v, want %+v", b.Name, got, want)
+	}
+	if got, want := b.Exprs[0].Loc, (SourceMapLoc{Line: 5, Col: 6, EndLine: 5, EndCol: 9}); got != want {
+		t.Errorf("%s Exprs[0].Loc=%+v, want %+v", b.Name, got, want)
+	}
+	if got := strings.TrimSpace(lines[b.Exprs[0].Line-1]); got != `// "b"+` {
+		t.Errorf("%s Exprs[0].Line %d is %q, want // \"b\"+", b.Name, b.Exprs[0].Line, got)
+	}
+	if got := strings.TrimSpace(lines[b.Exprs[1].Line-1]); got != `// "b"` {
+		t.Errorf("%s Exprs[1].Line %d is %q, want // \"b\"", b.Name, b.Exprs[1].Line, got)
+	}
+}
+
+// TestSourceMapGenerate tests that GenerateSourceMap
+// generates the same code as Generate.
+func TestSourceMapGenerate(t *testing.T) {
+	for _, test := range genTests {
+		in := prelude + test.grammar
+		g, err := Parse(strings.NewReader(in), "")
+		if err != nil {
+			t.Fatalf("Parse(%q)=_, %v, want _,nil", in, err)
+		}
+		if err := Check(g); err != nil {
+			t.Fatalf("Check(%q)=%v, want nil", in, err)
+		}
+		var want, got, data strings.Builder
+		cfg := Config{Prefix: "_"}
+		if err := cfg.Generate(&want, "", g); err != nil {
+			t.Fatalf("Generate(_, _, %q)=%v, want nil", in, err)
+		}
+		if err := cfg.GenerateSourceMap(&got, &data, "", g); err != nil {
+			t.Fatalf("GenerateSourceMap(_, _, _, %q)=%v, want nil", in, err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("GenerateSourceMap(_, _, _, %q) wrote\n%s\nwant\n%s", in, got.String(), want.String())
+		}
+	}
+}
@@ -0,0 +1,479 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// String returns the string representation of the rules.
+// The output contains no comments or whitespace,
+// except for a single space, " ",
+// separating sub-exprsessions of a sequence,
+// and on either side of <-.
+func String(rules []Rule) string {
+	var s string
+	for _, r := range rules {
+		if s != "" {
+			s += "\n"
+		}
+		s += r.String()
+	}
+	return s
+}
+
+// String returns the string representation of a rule.
+// The output contains no comments or whitespace,
+// except for a single space, " ",
+// separating sub-exprsessions of a sequence,
+// and on either side of <-.
+func (r *Rule) String() string {
+	var name string
+	if r.ErrorName != nil {
+		name = " " + strconv.Quote(r.ErrorName.String())
+	}
+	return r.Name.String() + name + r.HeaderString() + " <- " + r.Expr.String()
+}
+
+// PrettyString returns the string representation of a rule,
+// like String, but without its labels or actions.
+func (r *Rule) PrettyString() string {
+	pretty := *r
+	pretty.Expr = unlabeled(r.Expr)
+	return pretty.String()
+}
+
+// unlabeled returns a copy of the expression
+// without its labels or actions.
+func unlabeled(expr Expr) Expr {
+	switch e := expr.(type) {
+	case *Choice:
+		c := *e
+		c.Exprs = unlabeledAll(e.Exprs)
+		return &c
+	case *LongestChoice:
+		c := *e
+		c.Exprs = unlabeledAll(e.Exprs)
+		return &c
+	case *Action:
+		return unlabeled(e.Expr)
+	case *Sequence:
+		c := *e
+		c.Exprs = unlabeledAll(e.Exprs)
+		return &c
+	case *LabelExpr:
+		return unlabeled(e.Expr)
+	case *WantExpr:
+		c := *e
+		c.Expr = unlabeled(e.Expr)
+		return &c
+	case *PredExpr:
+		c := *e
+		c.Expr = unlabeled(e.Expr)
+		return &c
+	case *CaptureExpr:
+		c := *e
+		c.Expr = unlabeled(e.Expr)
+		return &c
+	case *DiffExpr:
+		c := *e
+		c.Expr = unlabeled(e.Expr)
+		c.Sub = unlabeled(e.Sub)
+		return &c
+	case *RepExpr:
+		c := *e
+		c.Expr = unlabeled(e.Expr)
+		return &c
+	case *OptExpr:
+		c := *e
+		c.Expr = unlabeled(e.Expr)
+		return &c
+	case *SubExpr:
+		c := *e
+		c.Expr = unlabeled(e.Expr)
+		return &c
+	default:
+		return expr
+	}
+}
+
+func unlabeledAll(exprs []Expr) []Expr {
+	copies := make([]Expr, len(exprs))
+	for i, e := range exprs {
+		copies[i] = unlabeled(e)
+	}
+	return copies
+}
+
+// quoteList returns the comma-separated Go string literals of the strings.
+func quoteList(strs []string) string {
+	var s string
+	for i, str := range strs {
+		if i > 0 {
+			s += ", "
+		}
+		s += strconv.Quote(str)
+	}
+	return s
+}
+
+// HeaderString returns the string representation
+// of the rule header between the name and the <-:
+// the annotations and result type.
+func (r *Rule) HeaderString() string {
+	var s string
+	if r.Params != nil {
+		s += " @param(" + r.Params.String() + ")"
+	}
+	if r.MaxDepth > 0 {
+		s += " @maxdepth(" + strconv.Itoa(r.MaxDepth) + ")"
+	}
+	if r.WarnSlow > 0 {
+		s += " @warnslow(" + r.WarnSlow.String() + ")"
+	}
+	if r.Token {
+		s += " @token"
+	}
+	if r.NoCase {
+		s += " @nocase"
+	}
+	if r.RecoverUntil != nil {
+		s += " @recoveruntil(" + quoteList(r.RecoverUntil) + ")"
+	}
+	if r.RecoverPast != nil {
+		s += " @recoverpast(" + quoteList(r.RecoverPast) + ")"
+	}
+	var names []string
+	for name := range r.Metadata {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s += " @" + name
+		if v := r.Metadata[name]; v != "" {
+			s += "(" + strconv.Quote(v) + ")"
+		}
+	}
+	if r.ResultType != nil {
+		s += " -> " + r.ResultType.String()
+	}
+	return s
+}
+
+func (n Name) String() string {
+	if len(n.Args) == 0 {
+		return n.Name.String()
+	}
+	s := n.Name.String() + "<"
+	for i, a := range n.Args {
+		if i > 0 {
+			s += ", "
+		}
+		s += a.String()
+	}
+	return s + ">"
+}
+
+// Ident returns a Go identifier for the name.
+// A string literal argument is _ followed by the hex of its bytes.
+func (n Name) Ident() string {
+	if len(n.Args) == 0 {
+		return n.Name.String()
+	}
+	s := n.Name.String() + "__"
+	for i, a := range n.Args {
+		if i > 0 {
+			s += "__"
+		}
+		if lit, ok := literalArg(a.String()); ok {
+			s += fmt.Sprintf("_%x", lit)
+		} else {
+			s += a.String()
+		}
+	}
+	return s
+}
+
+func (e *Choice) String() string {
+	s := e.Exprs[0].String()
+	for _, sub := range e.Exprs[1:] {
+		s += "/" + sub.String()
+	}
+	return s
+}
+
+func (e *LongestChoice) String() string {
+	s := e.Exprs[0].String()
+	for _, sub := range e.Exprs[1:] {
+		s += "|" + sub.String()
+	}
+	return s
+}
+
+func (e *Action) String() string {
+	if e.NoMemo {
+		return e.Expr.String() + " {…}!memo"
+	}
+	return e.Expr.String() + " {…}"
+}
+
+func (e *Sequence) String() string {
+	s := e.Exprs[0].String()
+	for _, sub := range e.Exprs[1:] {
+		s += " " + sub.String()
+	}
+	return s
+}
+
+func (e *LabelExpr) String() string {
+	return e.Label.String() + ":" + e.Expr.String()
+}
+
+func (e *WantExpr) String() string {
+	return e.Expr.String() + " !> " + strconv.Quote(e.Want.String())
+}
+
+func (e *PredExpr) String() string {
+	s := "&"
+	if e.Neg {
+		s = "!"
+	}
+	return s + e.Expr.String()
+}
+
+func (e *DiffExpr) String() string {
+	return e.Expr.String() + " - " + e.Sub.String()
+}
+
+func (e *CaptureExpr) String() string {
+	return "$" + e.Expr.String()
+}
+
+func (e *RepExpr) String() string {
+	return e.Expr.String() + string([]rune{e.Op})
+}
+
+func (e *OptExpr) String() string {
+	return e.Expr.String() + "?"
+}
+
+func (e *SubExpr) String() string {
+	if e.Source != "" {
+		return e.Source
+	}
+	return "(" + e.Expr.String() + ")"
+}
+
+func (e *Ident) String() string {
+	if e.CallArgs != nil {
+		return e.Name.String() + "(" + e.CallArgs.String() + ")"
+	}
+	return e.Name.String()
+}
+
+func (e *Cut) String() string { return "^" }
+
+func (e *Empty) String() string { return "()" }
+
+func (e *PredCode) String() string {
+	s := "&{"
+	if e.Neg {
+		s = "!{"
+	}
+	return s + "…}"
+}
+
+func (e *DelegateExpr) String() string {
+	return "%delegate(" + e.Args.String() + ")"
+}
+
+func (e *NativeExpr) String() string {
+	return "%native(" + e.Args.String() + ")"
+}
+
+func (e *BlockExpr) String() string {
+	return "%" + e.Kind + "(" + e.Args.String() + ")"
+}
+
+func (e *Literal) String() string {
+	s := strconv.QuoteToGraphic(e.Text.String())
+	// Replace some combining characters with their escaped version.
+	for _, sub := range []string{
+		"\u0301",
+		"\u0304",
+		"\u030C",
+		"\u0306",
+		"\u0309",
+		"\u0302",
+		"\u0300",
+		"\u0303",
+	} {
+		q := strconv.QuoteToASCII(sub)
+		s = strings.Replace(s, sub, q[1:len(q)-1], -1)
+	}
+	return s
+}
+
+func (e *CharClass) String() string {
+	if e.Source != "" {
+		return e.Source
+	}
+	s := "["
+	if e.Neg {
+		s += "^"
+	}
+	for _, sp := range e.Spans {
+		if sp[0] == sp[1] {
+			s += charClassEsc(sp[0])
+		} else {
+			s += charClassEsc(sp[0]) + "-" + charClassEsc(sp[1])
+		}
+	}
+	return s + "]"
+}
+
+func charClassEsc(r rune) string {
+	switch r {
+	case '^':
+		return `\^`
+	case '-':
+		return `\-`
+	case ']':
+		return `\]`
+	}
+	s := strconv.QuoteRuneToGraphic(r)
+	return strings.TrimPrefix(strings.TrimSuffix(s, "'"), "'")
+}
+
+func (e *Any) String() string { return "." }
+
+// FullString returns the fully parenthesized string representation of the rules.
+// The output contains no comments or whitespace,
+// except for a single space, " ",
+// separating sub-exprsessions of a sequence,
+// and on either side of <-.
+func FullString(rules []Rule) string {
+	var s string
+	for _, r := range rules {
+		if s != "" {
+			s += "\n"
+		}
+
+		var name string
+		if r.ErrorName != nil {
+			name = " " + strconv.Quote(r.ErrorName.String())
+		}
+		s += fmt.Sprintf("%s%s%s <- %s", r.Name, name, r.HeaderString(), r.Expr.fullString())
+	}
+	return s
+}
+
+func (e *Choice) fullString() string {
+	s := strings.Repeat("(", len(e.Exprs)-1) + e.Exprs[0].fullString()
+	for _, sub := range e.Exprs[1:] {
+		s += "/" + sub.fullString() + ")"
+	}
+	return s
+}
+
+func (e *LongestChoice) fullString() string {
+	s := strings.Repeat("(", len(e.Exprs)-1) + e.Exprs[0].fullString()
+	for _, sub := range e.Exprs[1:] {
+		s += "|" + sub.fullString() + ")"
+	}
+	return s
+}
+
+func (e *Action) fullString() string {
+	if e.NoMemo {
+		return "(" + e.Expr.fullString() + " {" + e.Code.String() + "}!memo)"
+	}
+	return "(" + e.Expr.fullString() + " {" + e.Code.String() + "})"
+}
+
+func (e *Sequence) fullString() string {
+	s := strings.Repeat("(", len(e.Exprs)-1) + e.Exprs[0].fullString()
+	for _, sub := range e.Exprs[1:] {
+		s += " " + sub.fullString() + ")"
+	}
+	return s
+}
+
+func (e *LabelExpr) fullString() string {
+	return fmt.Sprintf("(%s:%s)", e.Label.String(), e.Expr.fullString())
+}
+
+func (e *WantExpr) fullString() string {
+	return fmt.Sprintf("(%s !> %q)", e.Expr.fullString(), e.Want.String())
+}
+
+func (e *PredExpr) fullString() string {
+	if e.Neg {
+		return fmt.Sprintf("(!%s)", e.Expr.fullString())
+	}
+	return fmt.Sprintf("(&%s)", e.Expr.fullString())
+}
+
+func (e *DiffExpr) fullString() string {
+	return fmt.Sprintf("(%s - %s)", e.Expr.fullString(), e.Sub.fullString())
+}
+
+func (e *CaptureExpr) fullString() string {
+	return fmt.Sprintf("($%s)", e.Expr.fullString())
+}
+
+func (e *RepExpr) fullString() string {
+	return fmt.Sprintf("(%s%c)", e.Expr.fullString(), e.Op)
+}
+
+func (e *OptExpr) fullString() string {
+	return "(" + e.Expr.fullString() + "?)"
+}
+
+func (e *Ident) fullString() string { return "(" + e.String() + ")" }
+
+func (e *Cut) fullString() string { return "(" + e.String() + ")" }
+
+func (e *Empty) fullString() string { return "(" + e.String() + ")" }
+
+func (e *PredCode) fullString() string {
+	s := "(&{"
+	if e.Neg {
+		s = "(!{"
+	}
+	return s + e.Code.String() + "})"
+}
+
+func (e *SubExpr) fullString() string {
+	if e.Source != "" {
+		return "(" + e.Source + ")"
+	}
+	return e.Expr.fullString()
+}
+
+func (e *DelegateExpr) fullString() string { return "(" + e.String() + ")" }
+
+func (e *NativeExpr) fullString() string { return "(" + e.String() + ")" }
+
+func (e *BlockExpr) fullString() string { return "(" + e.String() + ")" }
+
+func (e *Literal) fullString() string { return "(" + e.String() + ")" }
+
+func (e *CharClass) fullString() string { return "(" + e.String() + ")" }
+
+func (e *Any) fullString() string { return "(" + e.String() + ")" }
+
+// textString returns the string of the Text, or "" if it is nil.
+func textString(t Text) string {
+	if t == nil {
+		return ""
+	}
+	return t.String()
+}
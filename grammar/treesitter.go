@@ -0,0 +1,228 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode"
+)
+
+// TreeSitter writes a tree-sitter grammar.json for the checked grammar.
+// The conversion is approximate:
+// actions, labels, and captures are ignored,
+// and predicate expressions are dropped,
+// since tree-sitter has no lookahead.
+// An error is returned for each code predicate,
+// which cannot be converted.
+func TreeSitter(w io.Writer, name string, gr *Grammar) error {
+	var errs Errors
+	g := tsGrammar{Name: name, Extras: []*tsNode{}}
+	for _, r := range gr.CheckedRules {
+		n := tsConvert(r.Expr, &errs)
+		if n == nil {
+			n = &tsNode{Type: "BLANK"}
+		}
+		if r.Token {
+			n = &tsNode{Type: "TOKEN", Content: n}
+		}
+		g.Rules = append(g.Rules, tsRule{name: r.Name.Ident(), node: n})
+	}
+	if err := errs.ret(); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
+
+type tsGrammar struct {
+	Name  string  `json:"name"`
+	Rules tsRules `json:"rules"`
+	// Extras is empty, since Peggy grammars match whitespace explicitly,
+	// but tree-sitter's default extras skip it.
+	Extras []*tsNode `json:"extras"`
+}
+
+type tsRule struct {
+	name string
+	node *tsNode
+}
+
+// tsRules are the rules of a tree-sitter grammar.
+// They are marshaled as a JSON object
+// with the keys in order of the rules,
+// since the first rule is the start rule.
+type tsRules []tsRule
+
+func (rs tsRules) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, r := range rs {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(r.name); err != nil {
+			return nil, err
+		}
+		b.WriteByte(':')
+		if err := enc.Encode(r.node); err != nil {
+			return nil, err
+		}
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// A tsNode is a tree-sitter rule expression.
+type tsNode struct {
+	Type    string    `json:"type"`
+	Name    string    `json:"name,omitempty"`
+	Value   string    `json:"value,omitempty"`
+	Members []*tsNode `json:"members,omitempty"`
+	Content *tsNode   `json:"content,omitempty"`
+}
+
+// tsChoice returns the tree-sitter CHOICE of the branches.
+func tsChoice(exprs []Expr, errs *Errors) *tsNode {
+	n := &tsNode{Type: "CHOICE"}
+	for _, sub := range exprs {
+		m := tsConvert(sub, errs)
+		if m == nil {
+			m = &tsNode{Type: "BLANK"}
+		}
+		n.Members = append(n.Members, m)
+	}
+	return n
+}
+
+// tsConvert returns the tree-sitter rule expression for e,
+// or nil if e is dropped from the conversion.
+func tsConvert(e Expr, errs *Errors) *tsNode {
+	switch e := e.(type) {
+	case *Choice:
+		return tsChoice(e.Exprs, errs)
+	case *LongestChoice:
+		// tree-sitter choices are unordered, and its lexer
+		// prefers the longest match of tokens.
+		return tsChoice(e.Exprs, errs)
+	case *Action:
+		return tsConvert(e.Expr, errs)
+	case *Sequence:
+		n := &tsNode{Type: "SEQ"}
+		for _, sub := range e.Exprs {
+			if m := tsConvert(sub, errs); m != nil {
+				n.Members = append(n.Members, m)
+			}
+		}
+		switch len(n.Members) {
+		case 0:
+			return nil
+		case 1:
+			return n.Members[0]
+		}
+		return n
+	case *LabelExpr:
+		return tsConvert(e.Expr, errs)
+	case *WantExpr:
+		return tsConvert(e.Expr, errs)
+	case *PredExpr:
+		tsConvert(e.Expr, errs)
+		return nil
+	case *CaptureExpr:
+		return tsConvert(e.Expr, errs)
+	case *DiffExpr:
+		// tree-sitter has no difference;
+		// keywords are instead excluded by its word extraction.
+		tsConvert(e.Sub, errs)
+		return tsConvert(e.Expr, errs)
+	case *RepExpr:
+		m := tsConvert(e.Expr, errs)
+		if m == nil {
+			return nil
+		}
+		if e.Op == '+' {
+			return &tsNode{Type: "REPEAT1", Content: m}
+		}
+		return &tsNode{Type: "REPEAT", Content: m}
+	case *OptExpr:
+		m := tsConvert(e.Expr, errs)
+		if m == nil {
+			return nil
+		}
+		return &tsNode{Type: "CHOICE", Members: []*tsNode{m, {Type: "BLANK"}}}
+	case *SubExpr:
+		return tsConvert(e.Expr, errs)
+	case *Ident:
+		return &tsNode{Type: "SYMBOL", Name: e.Rule().Name.Ident()}
+	case *PredCode:
+		errs.add(e, "cannot convert code predicate to tree-sitter")
+		return nil
+	case *Cut:
+		// Tree-sitter has no backtracking to cut.
+		return nil
+	case *Empty:
+		return nil
+	case *DelegateExpr:
+		errs.add(e, "cannot convert delegate to tree-sitter")
+		return nil
+	case *NativeExpr:
+		errs.add(e, "cannot convert native matcher to tree-sitter")
+		return nil
+	case *BlockExpr:
+		errs.add(e, "cannot convert block to tree-sitter")
+		return nil
+	case *Literal:
+		if e.Text.String() == "" {
+			return nil
+		}
+		return &tsNode{Type: "STRING", Value: e.Text.String()}
+	case *CharClass:
+		return &tsNode{Type: "PATTERN", Value: tsCharClass(e)}
+	case *Any:
+		return &tsNode{Type: "PATTERN", Value: `[\s\S]`}
+	default:
+		panic(fmt.Sprintf("impossible type: %T", e))
+	}
+}
+
+// tsCharClass returns a regular expression for the character class.
+func tsCharClass(e *CharClass) string {
+	s := "["
+	if e.Neg {
+		s += "^"
+	}
+	for _, sp := range e.Spans {
+		s += tsRuneEsc(sp[0])
+		if sp[0] != sp[1] {
+			s += "-" + tsRuneEsc(sp[1])
+		}
+	}
+	return s + "]"
+}
+
+func tsRuneEsc(r rune) string {
+	switch r {
+	case '\\', '[', ']', '^', '-':
+		return `\` + string(r)
+	case '\n':
+		return `\n`
+	case '\r':
+		return `\r`
+	case '\t':
+		return `\t`
+	}
+	if !unicode.IsGraphic(r) {
+		return fmt.Sprintf(`\x{%x}`, r)
+	}
+	return string(r)
+}
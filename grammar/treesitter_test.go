@@ -0,0 +1,174 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package grammar
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTreeSitter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		// rules is the JSON of the rules object.
+		rules string
+		err   string
+	}{
+		{
+			name: "choice and sequence",
+			in: `A <- "a" B / "<b>" { return "" }
+				B <- "b"`,
+			rules: `{
+				"A": {"type": "CHOICE", "members": [
+					{"type": "SEQ", "members": [
+						{"type": "STRING", "value": "a"},
+						{"type": "SYMBOL", "name": "B"}
+					]},
+					{"type": "STRING", "value": "<b>"}
+				]},
+				"B": {"type": "STRING", "value": "b"}
+			}`,
+		},
+		{
+			name: "repetition and optional",
+			in:   `A <- x:"a"* $"b"+ "c"?`,
+			rules: `{"A": {"type": "SEQ", "members": [
+				{"type": "REPEAT", "content": {"type": "STRING", "value": "a"}},
+				{"type": "REPEAT1", "content": {"type": "STRING", "value": "b"}},
+				{"type": "CHOICE", "members": [
+					{"type": "STRING", "value": "c"},
+					{"type": "BLANK"}
+				]}
+			]}}`,
+		},
+		{
+			name: "character classes",
+			in:   `A <- [a-z_\]] [^\n\-] .`,
+			rules: `{"A": {"type": "SEQ", "members": [
+				{"type": "PATTERN", "value": "[a-z_\\]]"},
+				{"type": "PATTERN", "value": "[^\\n\\-]"},
+				{"type": "PATTERN", "value": "[\\s\\S]"}
+			]}}`,
+		},
+		{
+			name: "predicates dropped",
+			in:   `A <- !"b" "a" / &"c"`,
+			rules: `{"A": {"type": "CHOICE", "members": [
+				{"type": "STRING", "value": "a"},
+				{"type": "BLANK"}
+			]}}`,
+		},
+		{
+			name: "templates and tokens",
+			in: `Z <- L<Y>
+				Y @token <- "y"
+				L<X> <- X ("," X)*`,
+			rules: `{
+				"Z": {"type": "SYMBOL", "name": "L__Y"},
+				"Y": {"type": "TOKEN", "content": {"type": "STRING", "value": "y"}},
+				"L__Y": {"type": "SEQ", "members": [
+					{"type": "SYMBOL", "name": "Y"},
+					{"type": "REPEAT", "content": {"type": "SEQ", "members": [
+						{"type": "STRING", "value": ","},
+						{"type": "SYMBOL", "name": "Y"}
+					]}}
+				]}
+			}`,
+		},
+		{
+			name: "code predicates",
+			in:   `A <- &{ true } "a" / !{ false }`,
+			err:  "^test.file:1.6,1.15: cannot convert code predicate to tree-sitter\ntest.file:1.22,1.32: cannot convert code predicate to tree-sitter$",
+		},
+		{
+			name: "delegates",
+			in:   `A <- %delegate(f, "<", ">")`,
+			err:  "^test.file:1.6,1.28: cannot convert delegate to tree-sitter$",
+		},
+		{
+			name: "natives",
+			in:   `A <- %native(f)`,
+			err:  "^test.file:1.6,1.16: cannot convert native matcher to tree-sitter$",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			in := test.in
+			g, err := Parse(strings.NewReader(in), "test.file")
+			if err != nil {
+				t.Fatalf("Parse(%q, _)=_, %v, want _,nil", in, err)
+			}
+			if err := Check(g); err != nil {
+				t.Fatalf("Check(%q)=%v, want nil", in, err)
+			}
+			var b strings.Builder
+			err = TreeSitter(&b, "test", g)
+			if test.err != "" {
+				if err == nil || !regexp.MustCompile(test.err).MatchString(err.Error()) {
+					t.Fatalf("TreeSitter(_, _, %q)=%v, want matching %q", in, err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TreeSitter(_, _, %q)=%v, want nil", in, err)
+			}
+			var got struct {
+				Name   string
+				Rules  json.RawMessage
+				Extras []interface{}
+			}
+			if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+				t.Fatalf("json.Unmarshal(%q)=%v, want nil", b.String(), err)
+			}
+			if got.Name != "test" || got.Extras == nil || len(got.Extras) != 0 {
+				t.Errorf("TreeSitter(_, _, %q) wrote name %q, extras %v, want test, []",
+					in, got.Name, got.Extras)
+			}
+			var gotRules, wantRules interface{}
+			if err := json.Unmarshal(got.Rules, &gotRules); err != nil {
+				t.Fatalf("json.Unmarshal(%q)=%v, want nil", got.Rules, err)
+			}
+			if err := json.Unmarshal([]byte(test.rules), &wantRules); err != nil {
+				t.Fatalf("json.Unmarshal(%q)=%v, want nil", test.rules, err)
+			}
+			if !reflect.DeepEqual(gotRules, wantRules) {
+				t.Errorf("TreeSitter(_, _, %q) wrote rules\n%s\nwant\n%s", in, got.Rules, test.rules)
+			}
+		})
+	}
+}
+
+// TestTreeSitterRuleOrder tests that the rules are written in order,
+// since tree-sitter's start rule is the first.
+func TestTreeSitterRuleOrder(t *testing.T) {
+	const in = `Z <- Y A
+		Y <- "y"
+		A <- "a"`
+	g, err := Parse(strings.NewReader(in), "test.file")
+	if err != nil {
+		t.Fatalf("Parse(%q, _)=_, %v, want _,nil", in, err)
+	}
+	if err := Check(g); err != nil {
+		t.Fatalf("Check(%q)=%v, want nil", in, err)
+	}
+	var b strings.Builder
+	if err := TreeSitter(&b, "test", g); err != nil {
+		t.Fatalf("TreeSitter(_, _, %q)=%v, want nil", in, err)
+	}
+	z := strings.Index(b.String(), `"Z":`)
+	y := strings.Index(b.String(), `"Y":`)
+	a := strings.Index(b.String(), `"A":`)
+	if z < 0 || y < z || a < y {
+		t.Errorf("TreeSitter(_, _, %q) wrote\n%s\nwant rules in order Z, Y, A", in, b.String())
+	}
+}
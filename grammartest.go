@@ -0,0 +1,267 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+// A grammarTest is a test case embedded in a grammar file.
+// A line of the form
+//
+//	#test Rule "input"
+//
+// requires the rule to match all of the input, and a line of the form
+//
+//	#test !Rule "input"
+//
+// requires the rule to not match all of the input.
+// The input is a Go string literal, either quoted or back-quoted.
+// To the grammar, the line is a comment.
+type grammarTest struct {
+	Loc   grammar.Loc
+	Rule  string
+	Fail  bool
+	Input string
+}
+
+// parseGrammarTests returns the #test cases of the text of a grammar file.
+func parseGrammarTests(file, text string) ([]grammarTest, error) {
+	var tests []grammarTest
+	var errs grammar.Errors
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "#test" && !strings.HasPrefix(line, "#test ") && !strings.HasPrefix(line, "#test\t") {
+			continue
+		}
+		t := grammarTest{Loc: grammar.Loc{File: file, Line: i + 1, Col: 1}}
+		rest := strings.TrimSpace(line[len("#test"):])
+		n := strings.IndexAny(rest, " \t")
+		if n < 0 {
+			errs.Errs = append(errs.Errs, grammar.Err(t.Loc, "malformed #test: want #test [!]Rule \"input\""))
+			continue
+		}
+		t.Rule = rest[:n]
+		if strings.HasPrefix(t.Rule, "!") {
+			t.Rule, t.Fail = t.Rule[1:], true
+		}
+		input, err := strconv.Unquote(strings.TrimSpace(rest[n:]))
+		if t.Rule == "" || err != nil {
+			errs.Errs = append(errs.Errs, grammar.Err(t.Loc, "malformed #test: want #test [!]Rule \"input\""))
+			continue
+		}
+		t.Input = input
+		tests = append(tests, t)
+	}
+	return tests, testErrors(errs)
+}
+
+// testErrors returns the Errors, sorted by location,
+// or nil if there are none.
+func testErrors(errs grammar.Errors) error {
+	if len(errs.Errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(errs.Errs, func(i, j int) bool {
+		return errs.Errs[i].Begin().Less(errs.Errs[j].Begin())
+	})
+	return &errs
+}
+
+// testGrammar runs the #test cases of the grammar files
+// with a cached build of the parser generated with the Config,
+// writing to w a line for each failed case
+// and, if none failed, a line counting the cases.
+// It returns an error if the grammar has an error or any case failed.
+func testGrammar(w io.Writer, files []string, cfg grammar.Config) error {
+	if len(files) == 0 {
+		return errors.New("no grammar files")
+	}
+	var tests []grammarTest
+	var errs grammar.Errors
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		ts, err := parseGrammarTests(file, string(data))
+		if err != nil {
+			errs.Errs = append(errs.Errs, err.(*grammar.Errors).Errs...)
+		}
+		tests = append(tests, ts...)
+	}
+	g, err := parseFiles(cfg, files)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Check(g); err != nil {
+		return err
+	}
+	rules := make(map[string]*grammar.Rule)
+	for _, r := range g.CheckedRules {
+		rules[r.Name.String()] = r
+	}
+	// The harness reads the cases as a JSON array of rule Idents and inputs.
+	type testCase struct{ Rule, Input string }
+	var cases []testCase
+	var idents []string
+	seen := make(map[string]bool)
+	for _, t := range tests {
+		r := rules[t.Rule]
+		switch {
+		case r == nil:
+			errs.Errs = append(errs.Errs, grammar.Err(t.Loc, "rule %s undefined", t.Rule))
+			continue
+		case r.Params != nil:
+			errs.Errs = append(errs.Errs, grammar.Err(t.Loc, "rule %s has parameters, so it cannot be tested", t.Rule))
+			continue
+		}
+		cases = append(cases, testCase{Rule: r.Name.Ident(), Input: t.Input})
+		if !seen[r.Name.Ident()] {
+			seen[r.Name.Ident()] = true
+			idents = append(idents, r.Name.Ident())
+		}
+	}
+	if err := testErrors(errs); err != nil {
+		return err
+	}
+	if len(tests) == 0 {
+		fmt.Fprintf(w, "%s: no #test cases\n", files[0])
+		return nil
+	}
+
+	var src bytes.Buffer
+	if err := cfg.Generate(&src, files[0], g); err != nil {
+		return err
+	}
+	parserSrc, err := mainPackage(src.String())
+	if err != nil {
+		return err
+	}
+	var harnessSrc bytes.Buffer
+	err = template.Must(template.New("test").Parse(testGrammarHarness)).Execute(&harnessSrc, map[string]interface{}{
+		"Prefix":    cfg.Prefix,
+		"Rules":     idents,
+		"PegImport": cfg.PegImportPath(),
+	})
+	if err != nil {
+		return err
+	}
+	binary, err := cachedBinary(parserSrc, harnessSrc.Bytes(), w)
+	if err != nil {
+		return err
+	}
+
+	var in, out bytes.Buffer
+	if err := json.NewEncoder(&in).Encode(cases); err != nil {
+		return err
+	}
+	cmd := exec.Command(binary)
+	cmd.Stdin = &in
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+	var results []struct {
+		Pos   int
+		Error string
+	}
+	if err := json.Unmarshal(out.Bytes(), &results); err != nil {
+		return err
+	}
+	if len(results) != len(tests) {
+		return fmt.Errorf("got %d #test results, want %d", len(results), len(tests))
+	}
+	var failed int
+	for i, t := range tests {
+		r := results[i]
+		switch {
+		case t.Fail && r.Pos == len(t.Input):
+			fmt.Fprintln(w, grammar.Err(t.Loc, "!%s matched %q", t.Rule, t.Input))
+		case t.Fail:
+			continue
+		case r.Pos < 0:
+			fmt.Fprintln(w, grammar.Err(t.Loc, "%s failed at %s", t.Rule, r.Error))
+		case r.Pos < len(t.Input):
+			fmt.Fprintln(w, grammar.Err(t.Loc, "%s matched only %d of %d bytes", t.Rule, r.Pos, len(t.Input)))
+		default:
+			continue
+		}
+		failed++
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d #test cases failed", failed, len(tests))
+	}
+	fmt.Fprintf(w, "%s: %d #test cases passed\n", files[0], len(tests))
+	return nil
+}
+
+var testGrammarHarness = `package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	peg "{{.PegImport}}"
+)
+
+var rules = map[string]struct {
+	accepts func(*{{.Prefix}}Parser, int) (int, int)
+	fail    func(*{{.Prefix}}Parser, int, int) (int, *peg.Fail)
+}{
+{{range .Rules}}	{{printf "%q" .}}: { {{$.Prefix}}{{.}}Accepts, {{$.Prefix}}{{.}}Fail },
+{{end -}}
+}
+
+type result struct {
+	Pos   int
+	Error string
+}
+
+func main() {
+	var tests []struct{ Rule, Input string }
+	if err := json.NewDecoder(os.Stdin).Decode(&tests); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	results := make([]result, len(tests))
+	for i, t := range tests {
+		p, err := {{.Prefix}}NewParser(t.Input)
+		if err != nil {
+			results[i] = result{Pos: -1, Error: err.Error()}
+			continue
+		}
+		r := rules[t.Rule]
+		pos, perr := r.accepts(p, 0)
+		results[i].Pos = pos
+		if pos < 0 {
+			_, fail := r.fail(p, 0, perr)
+			err := peg.SimpleError(t.Input, fail)
+			results[i].Error = fmt.Sprintf("%d.%d: %s", err.Loc.Line, err.Loc.Column, err.Message)
+		}
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(results); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`
@@ -0,0 +1,90 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+func TestParseGrammarTests(t *testing.T) {
+	const text = `A <- "a"+
+#test A "aa"
+	#test !A ` + "`b\\n`" + `
+# test A "not a test"
+#testing A "not a test"
+`
+	tests, err := parseGrammarTests("g.peggy", text)
+	if err != nil {
+		t.Fatalf("parseGrammarTests(…)=_, %v, want nil", err)
+	}
+	want := []grammarTest{
+		{Loc: grammar.Loc{File: "g.peggy", Line: 2, Col: 1}, Rule: "A", Input: "aa"},
+		{Loc: grammar.Loc{File: "g.peggy", Line: 3, Col: 1}, Rule: "A", Fail: true, Input: `b\n`},
+	}
+	if !reflect.DeepEqual(tests, want) {
+		t.Errorf("parseGrammarTests(…)=%+v, want %+v", tests, want)
+	}
+
+	for _, line := range []string{`#test`, `#test A`, `#test A aa`, `#test ! "a"`, `#test A "a`} {
+		_, err := parseGrammarTests("g.peggy", line)
+		if err == nil || !strings.Contains(err.Error(), "g.peggy:1.1: malformed #test") {
+			t.Errorf("parseGrammarTests(%q)=_, %v, want malformed #test", line, err)
+		}
+	}
+}
+
+func TestTestGrammar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peggy_test_grammar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "g.peggy")
+	const src = `{
+package p
+
+import "github.com/eaburns/peggy/peg"
+}
+List <- "[" (Num ("," Num)*)? "]"
+Num <- [0-9]+
+#test List "[1,2]"
+#test List "[1,"
+#test !Num "12"
+#test Num "12"
+#test Undefined "x"
+`
+	if err := ioutil.WriteFile(file, []byte(src), 0666); err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	err = testGrammar(&b, []string{file}, grammar.Config{Prefix: "_"})
+	if err == nil || err.Error() != file+":12.1: rule Undefined undefined" {
+		t.Fatalf("testGrammar(…)=%v, want rule Undefined undefined", err)
+	}
+
+	if err := ioutil.WriteFile(file, []byte(strings.Replace(src, `#test Undefined "x"`, "", 1)), 0666); err != nil {
+		t.Fatal(err)
+	}
+	b.Reset()
+	err = testGrammar(&b, []string{file}, grammar.Config{Prefix: "_"})
+	if err == nil || err.Error() != "2 of 4 #test cases failed" {
+		t.Errorf("testGrammar(…)=%v, want 2 of 4 #test cases failed", err)
+	}
+	want := file + `:9.1: List failed at 1.4: want [0-9]; got EOF
+` + file + `:10.1: !Num matched "12"
+`
+	if b.String() != want {
+		t.Errorf("testGrammar wrote\n%s\nwant\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,314 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+// initFiles are the templates of the files written by peggy init,
+// keyed by their path relative to the project directory.
+// The templates are executed with the Name of the language,
+// the Module path, and the PegImport path.
+var initFiles = []struct {
+	path, template string
+}{
+	{"go.mod", initGoMod},
+	{"{{.Name}}.peggy", initGrammar},
+	{"main.go", initMain},
+	{"{{.Name}}_test.go", initTest},
+	{"testdata/example.txt", initExample},
+	{"testdata/example.golden", initGolden},
+}
+
+// initProject writes a starter parser project for a language to the directory:
+// a grammar, a REPL main.go that generates the parser with go:generate,
+// and a test comparing the syntax trees of testdata files to golden files.
+// The name of the language is the last element of the directory,
+// which must be a Go identifier.
+// If module is empty, the module path is the name,
+// and if pegImport is empty, it is grammar.DefaultPegImport.
+// No existing file is overwritten.
+func initProject(dir, module, pegImport string) error {
+	name := filepath.Base(dir)
+	if !token.IsIdentifier(name) {
+		return fmt.Errorf("bad language name %q, want a Go identifier", name)
+	}
+	if module == "" {
+		module = name
+	}
+	if pegImport == "" {
+		pegImport = grammar.DefaultPegImport
+	}
+	if err := (grammar.Config{PegImport: pegImport}).CheckPegImportPath(); err != nil {
+		return err
+	}
+	data := map[string]string{
+		"Name":      name,
+		"Module":    module,
+		"PegImport": pegImport,
+	}
+	files := make(map[string][]byte)
+	var paths []string
+	for _, f := range initFiles {
+		var path, src bytes.Buffer
+		if err := template.Must(template.New("path").Parse(f.path)).Execute(&path, data); err != nil {
+			return err
+		}
+		if err := template.Must(template.New(f.path).Parse(f.template)).Execute(&src, data); err != nil {
+			return err
+		}
+		p := filepath.Join(dir, filepath.FromSlash(path.String()))
+		if _, err := os.Stat(p); err == nil {
+			return errors.New(p + " already exists")
+		}
+		files[p] = src.Bytes()
+		paths = append(paths, p)
+	}
+	for _, p := range paths {
+		if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(p, files[p], 0666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const initGoMod = `module {{.Module}}
+
+go 1.18
+`
+
+const initGrammar = `{
+package main
+
+import "{{.PegImport}}"
+
+var _ *peg.Node
+}
+
+# File is the root rule: a list of assignments.
+File <- _ Assign* EOF
+
+Assign "assignment" <- Ident "=" _ Expr ";" _
+
+Expr <- Term (AddOp Term)*
+Term <- Factor (MulOp Factor)*
+Factor <- Num / Ident / "(" _ Expr ")" _
+
+AddOp <- [+\-] _
+MulOp <- [*/] _
+
+Num "number" <- [0-9]+ _
+Ident "identifier" <- [a-zA-Z_] [a-zA-Z0-9_]* _
+
+_ <- ([ \t\r\n] / "#" [^\n]*)*
+EOF "end of file" <- !.
+`
+
+const initMain = `// {{.Name}} reads {{.Name}} source from each line of standard input
+// and writes its syntax tree or syntax error.
+package main
+
+//go:generate peggy -o {{.Name}}.go {{.Name}}.peggy
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"{{.PegImport}}"
+)
+
+func main() {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		node, err := parse(scanner.Text())
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Println(peg.Pretty(node))
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// parse returns the syntax tree of the File rule for the text.
+func parse(text string) (*peg.Node, error) {
+	p, err := _NewParser(text)
+	if err != nil {
+		return nil, err
+	}
+	if pos, perr := _FileAccepts(p, 0); pos < 0 {
+		_, fail := _FileFail(p, 0, perr)
+		return nil, peg.SimpleError(text, fail)
+	}
+	_, node := _FileNode(p, 0)
+	return node, nil
+}
+`
+
+const initTest = `package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"{{.PegImport}}"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// TestGolden parses each testdata/*.txt file
+// and compares its syntax tree
+// to that of the corresponding .golden file.
+// Run go test -update to rewrite the golden files.
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		file := file
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			text, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			node, err := parse(string(text))
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden := strings.TrimSuffix(file, ".txt") + ".golden"
+			checkGolden(t, golden, peg.Pretty(node)+"\n")
+		})
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	_, err := parse("x = 1 +;")
+	if err == nil || !strings.Contains(err.Error(), "want") {
+		t.Errorf("parse(\"x = 1 +;\")=_, %v, want a syntax error", err)
+	}
+}
+
+// checkGolden compares got to the contents of the golden file,
+// or, with -update, writes got to the golden file.
+func checkGolden(t *testing.T, golden, got string) {
+	t.Helper()
+	if *update {
+		if err := ioutil.WriteFile(golden, []byte(got), 0666); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("got\n%s\nwant\n%s\n(run go test -update to accept the new tree)", got, want)
+	}
+}
+`
+
+const initExample = `x = (1 + 2) * y;
+`
+
+const initGolden = `File{
+	_(""),
+	Assign{
+		Ident{
+			"x",
+			_{
+				{" "},
+			},
+		},
+		"=",
+		_{
+			{" "},
+		},
+		Expr{
+			Term{
+				Factor{
+					"(",
+					_(""),
+					Expr{
+						Term{
+							Factor{
+								Num{
+									"1",
+									_{
+										{" "},
+									},
+								},
+							},
+						},
+						{
+							AddOp{
+								"+",
+								_{
+									{" "},
+								},
+							},
+							Term{
+								Factor{
+									Num{
+										"2",
+										_(""),
+									},
+								},
+							},
+						},
+					},
+					")",
+					_{
+						{" "},
+					},
+				},
+				{
+					MulOp{
+						"*",
+						_{
+							{" "},
+						},
+					},
+					Factor{
+						Ident{
+							"y",
+							_(""),
+						},
+					},
+				},
+			},
+		},
+		";",
+		_{
+			{"
+"},
+		},
+	},
+	EOF(""),
+}
+`
@@ -0,0 +1,79 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+func TestInit(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "peggy_init")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir(_, _)=_, %v, want nil", err)
+	}
+	defer removeTemp(tmp)
+	dir := filepath.Join(tmp, "mylang")
+	if err := initProject(dir, "example.com/mylang", grammar.DefaultPegImport); err != nil {
+		t.Fatalf("initProject(%q, _, _)=%v, want nil", dir, err)
+	}
+	mod, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil || !strings.HasPrefix(string(mod), "module example.com/mylang\n") {
+		t.Errorf("go.mod=%q, %v, want module example.com/mylang", mod, err)
+	}
+	main, err := ioutil.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil || !strings.Contains(string(main), "//go:generate peggy -o mylang.go mylang.peggy\n") {
+		t.Errorf("main.go=%q, %v, want a go:generate line", main, err)
+	}
+
+	// Generate the parser, as go generate would,
+	// and run the scaffolded test against its golden tree.
+	file := filepath.Join(dir, "mylang.peggy")
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("os.Open(%q)=_, %v, want nil", file, err)
+	}
+	g, err := grammar.Parse(bufio.NewReader(f), file)
+	f.Close()
+	if err != nil {
+		t.Fatalf("Parse(%q)=_, %v, want nil", file, err)
+	}
+	if err := grammar.Check(g); err != nil {
+		t.Fatalf("Check(%q)=%v, want nil", file, err)
+	}
+	out, err := os.Create(filepath.Join(dir, "mylang.go"))
+	if err != nil {
+		t.Fatalf("os.Create(_)=_, %v, want nil", err)
+	}
+	if err := (grammar.Config{Prefix: "_"}).Generate(out, file, g); err != nil {
+		t.Fatalf("Generate(_)=%v, want nil", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("Close()=%v, want nil", err)
+	}
+	cmd := exec.Command("go", "test",
+		filepath.Join(dir, "main.go"),
+		filepath.Join(dir, "mylang.go"),
+		filepath.Join(dir, "mylang_test.go"))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("go test failed: %v\n%s", err, output)
+	}
+
+	if err := initProject(dir, "", grammar.DefaultPegImport); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("initProject(%q, _, _) again=%v, want already exists", dir, err)
+	}
+	if err := initProject(filepath.Join(tmp, "my-lang"), "", grammar.DefaultPegImport); err == nil {
+		t.Errorf("initProject(my-lang, _, _)=nil, want error")
+	}
+}
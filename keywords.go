@@ -0,0 +1,55 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+// preludePackage returns the package name of the grammar's prelude,
+// or main if it has no prelude.
+func preludePackage(gr *grammar.Grammar) (string, error) {
+	if gr.Prelude == nil {
+		return "main", nil
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), "", gr.Prelude.String(), parser.PackageClauseOnly)
+	if err != nil {
+		return "", err
+	}
+	return f.Name.Name, nil
+}
+
+// keywordsMain checks the grammar files, or standard input if none,
+// and writes the keyword table of their rules in the format
+// to the -o file or standard output.
+func keywordsMain(form string, args []string) error {
+	cfg, err := flagConfig()
+	if err != nil {
+		return err
+	}
+	g, err := parseFiles(cfg, args)
+	if err != nil {
+		return err
+	}
+	keywords := grammar.Keywords(g)
+	if err := cfg.Check(g); err != nil {
+		return err
+	}
+	pkg, err := preludePackage(g)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if err := grammar.WriteKeywords(&b, form, pkg, cfg.Prefix, keywords); err != nil {
+		return err
+	}
+	return writeOutput(*out, b.Bytes())
+}
@@ -0,0 +1,112 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+func TestKeywords(t *testing.T) {
+	const src = `{
+package lang
+}
+A <- Kw "(" List<Op> ")" Time !"--"
+Kw @keyword <- "if" / "else" / "if"
+Op @operator @punct <- "+" / "-" / ""
+List<X> <- X ("," X)*
+Time <- "\d\d:\d\d"
+Sel @keyword @nocase <- "select"
+`
+	g, err := grammar.Parse(strings.NewReader(src), "test.peggy")
+	if err != nil {
+		t.Fatalf("Parse(_)=%v, want nil", err)
+	}
+	got := grammar.Keywords(g)
+	want := map[string][]string{
+		"":         {"(", ")", ",", "--"},
+		"keyword":  {"else", "if", "select"},
+		"operator": {"+", "-"},
+		"punct":    {"+", "-"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Keywords(_)=%q, want %q", got, want)
+	}
+	if err := grammar.Check(g); err != nil {
+		t.Fatalf("Check(_)=%v, want nil", err)
+	}
+	pkg, err := preludePackage(g)
+	if pkg != "lang" || err != nil {
+		t.Errorf("preludePackage(_)=%q, %v, want lang, nil", pkg, err)
+	}
+}
+
+func TestWriteKeywords(t *testing.T) {
+	keywords := map[string][]string{
+		"keyword": {"else", "if"},
+		"":        {"(", "\""},
+	}
+	tests := []struct {
+		form string
+		want string
+		err  string
+	}{
+		{
+			form: "json",
+			want: `{
+	"": [
+		"(",
+		"\""
+	],
+	"keyword": [
+		"else",
+		"if"
+	]
+}
+`,
+		},
+		{
+			form: "go",
+			want: `// Code generated by peggy keywords. DO NOT EDIT.
+
+package lang
+
+// _Keywords are the literal terminals of the grammar,
+// grouped by the metadata annotations of their rules,
+// or the empty string for rules without one.
+var _Keywords = map[string][]string{
+	"":        {"(", "\""},
+	"keyword": {"else", "if"},
+}
+`,
+		},
+		{
+			form: "yaml",
+			err:  "bad format yaml: want go or json",
+		},
+	}
+	for _, test := range tests {
+		var b strings.Builder
+		err := grammar.WriteKeywords(&b, test.form, "lang", "_", keywords)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("WriteKeywords(%q)=%v, want %q", test.form, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("WriteKeywords(%q)=%v, want nil", test.form, err)
+			continue
+		}
+		if got := b.String(); got != test.want {
+			t.Errorf("WriteKeywords(%q)=\n%s\nwant\n%s", test.form, got, test.want)
+		}
+	}
+}
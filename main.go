@@ -8,13 +8,21 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
-)
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
 
-//go:generate goyacc -o grammar.go -p "peggy" grammar.y
+	"github.com/eaburns/peggy/grammar"
+)
 
 var (
 	out          = flag.String("o", "", "output file path")
@@ -22,62 +30,724 @@ var (
 	genActions   = flag.Bool("a", true, "generate action parsing")
 	genParseTree = flag.Bool("t", true, "generate parse tree parsing")
 	prettyPrint  = flag.Bool("pretty", false, "don't check or generate, write the grammar without labels or actions")
+	warnUnused   = flag.Bool("Wunused", false, "warn of labels unused by any action or code predicate, and of actions whose values are discarded")
+	pureActions  = flag.Bool("pure", false, "require all actions to be pure, rejecting !memo actions")
+	sandboxCode  = flag.Bool("sandbox", false, "reject action and code predicate code referring to identifiers of the generated parser, such as parser or pos")
+	strictChecks = flag.Bool("strict", false, "reject non-final choice branches that match the empty string and never fail, and predicates and ? of expressions that can match the empty string")
+	genRuleNames = flag.Bool("n", false, "generate a table of rule names indexed by rule constant")
+	genAST       = flag.Bool("ast", false, "generate AST struct types and actions for labeled rules without actions")
+	cover        = flag.Bool("cover", false, "instrument the parser to count rule and choice branch acceptances in <prefix>Coverage")
+	profileRules = flag.Bool("profile", false, "instrument the parser to record the time of the Accepts pass by stacks of rules in <prefix>Profile")
+	traceRules   = flag.Bool("trace", false, "instrument the parser to record the rules entered, accepted, and failed by the Accepts pass in <prefix>Trace")
+	sourceMap    = flag.String("sourcemap", "", "source map output file path, mapping generated functions and lines to grammar locations")
+	report       = flag.String("report", "", "report output file path, describing the rules, passes, and code size of the generated parser, as JSON if the path ends in .json and text otherwise")
+	treeSitter   = flag.Bool("treesitter", false, "don't generate a parser, write an approximate tree-sitter grammar.json")
+	watchGrammar = flag.Bool("w", false, "watch the grammar files, regenerating the -o file when they change")
+	testCases    = flag.Bool("test", false, "with -w, run the #test cases of the grammar files after each regeneration")
+	sharedMemo   = flag.Bool("sharedmemo", false, "generate NewSharedParser, sharing memo table entries across parses of texts with common prefixes")
+	incremental  = flag.Bool("incremental", false, "regenerate only the functions of rules that changed since the previous -incremental output to the -o file")
+	cacheSilent  = flag.Bool("cachesilent", false, "don't report fails of rules first tried inside &, !, or the right of -")
+	memoLayout   = flag.String("memo", "row", "memo table layout: row, column, or map")
+	errorMode    = flag.String("errors", "furthest", "failure reported by the generated parse functions: furthest, cut (the first committed failure after a cut ^), or merged (both)")
+	treeNames    = flag.String("treenames", "instance", "naming of template expansions in Node and Fail trees: instance (List<Expr>), template (List), or label (the label of its invocations)")
+	optOK        = flag.Bool("optok", false, "give optional expressions of non-string types the type struct{ Value T; OK bool } instead of *T")
+	tuples       = flag.Bool("tuples", false, "give sequences of differently typed expressions the type struct{ V0 T0; V1 T1; ... } instead of a type mismatch")
+	pegParser    = flag.Bool("pegparser", false, "generate ParseNode and ParseValue methods of the Parser, implementing peg.Parser")
+	pegImport    = flag.String("pegimport", grammar.DefaultPegImport, "import path of the peg runtime package, replacing imports of "+grammar.DefaultPegImport+" in the prelude")
+	buildTags    = flag.String("tags", "", "comma-separated list of tags enabling %if regions of the grammar")
+	genAssert    = flag.Bool("assert", false, "generate compile-time assertions of the rule constants and action types, for testing the generator")
+	keepTmp      = flag.Bool("keep-tmp", false, "keep the generated temporary files of repl, shrink, explain, profile, trace, and wasm, printing their paths to standard error; also enabled by a non-empty $PEGGY_KEEP")
 )
 
 func main() {
 	flag.Parse()
 	args := flag.Args()
 
-	in := bufio.NewReader(os.Stdin)
-	file := "<stdin>"
-	if len(args) > 0 {
-		f, err := os.Open(args[0])
+	if len(args) > 0 && args[0] == "failview" {
+		// peggy failview [file] pretty-prints a log of peg.Fail trees.
+		var in io.Reader = os.Stdin
+		if len(args) > 1 {
+			f, err := os.Open(args[1])
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			in = f
+		}
+		if err := failview(os.Stdout, in); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "repl" {
+		// peggy repl grammar [rule] parses lines of standard input
+		// with the rule, or the first rule if none is given.
+		if len(args) < 2 || len(args) > 3 {
+			fmt.Println("usage: peggy repl grammar [rule]")
+			os.Exit(1)
+		}
+		var root string
+		if len(args) > 2 {
+			root = args[2]
+		}
+		if err := repl(os.Stdout, os.Stdin, args[1], root); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "shrink" {
+		// peggy shrink grammar [rule] shrinks an input on standard input
+		// that the rule, or the first rule if none is given, rejects
+		// to a minimal input rejected in the same way.
+		if len(args) < 2 || len(args) > 3 {
+			fmt.Println("usage: peggy shrink grammar [rule] < input")
+			os.Exit(1)
+		}
+		var root string
+		if len(args) > 2 {
+			root = args[2]
+		}
+		if err := shrink(os.Stdout, os.Stdin, args[1], root); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "explain" {
+		// peggy explain grammar [rule] explains why the rule,
+		// or the first rule if none is given,
+		// fails to parse an input on standard input.
+		if len(args) < 2 || len(args) > 3 {
+			fmt.Println("usage: peggy explain grammar [rule] < input")
+			os.Exit(1)
+		}
+		var root string
+		if len(args) > 2 {
+			root = args[2]
+		}
+		if err := explain(os.Stdout, os.Stdin, args[1], root); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "cover" {
+		// peggy cover profile... reports the rules and choice branches
+		// not covered by the merged coverage profiles.
+		if len(args) < 2 {
+			fmt.Println("usage: peggy cover profile...")
+			os.Exit(1)
+		}
+		if err := grammar.CoverReport(os.Stdout, args[1:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "init" {
+		// peggy init [-module path] dir writes a starter parser project
+		// for the language named by the last element of dir.
+		fs := flag.NewFlagSet("init", flag.ExitOnError)
+		module := fs.String("module", "", "the module path; the language name if empty")
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			fmt.Println("usage: peggy init [-module path] dir")
+			os.Exit(1)
+		}
+		if err := initProject(fs.Arg(0), *module, *pegImport); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "reorder" {
+		// peggy reorder [-apply] grammar profile... reports the choices
+		// whose branches would be tried fewer times if reordered
+		// by the merged coverage profiles.
+		// With -apply, it writes the reordered grammar
+		// and reports to standard error.
+		fs := flag.NewFlagSet("reorder", flag.ExitOnError)
+		apply := fs.Bool("apply", false, "write the grammar with the choices reordered")
+		fs.Parse(args[1:])
+		if fs.NArg() < 2 {
+			fmt.Println("usage: peggy reorder [-apply] grammar profile...")
+			os.Exit(1)
+		}
+		if err := reorderMain(os.Stdout, os.Stderr, fs.Arg(0), fs.Args()[1:], *apply); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "run" {
+		// peggy run [-root rule] grammar [input...] parses the inputs,
+		// or standard input, with a cached build of the grammar's parser,
+		// writing the parse tree or error of each.
+		fs := flag.NewFlagSet("run", flag.ExitOnError)
+		root := fs.String("root", "", "the root rule; the first rule if empty")
+		fs.Parse(args[1:])
+		if fs.NArg() < 1 {
+			fmt.Println("usage: peggy run [-root rule] grammar [input...]")
+			os.Exit(1)
+		}
+		err := runGrammar(os.Stdout, os.Stdin, fs.Arg(0), *root, fs.Args()[1:])
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			os.Exit(exit.ExitCode())
+		}
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		in = bufio.NewReader(f)
-		file = args[0]
+		return
 	}
 
-	g, err := Parse(in, file)
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	if len(args) > 0 && args[0] == "test" {
+		// peggy test grammar... runs the #test cases of the grammar files
+		// with a cached build of the parser, writing each failed case.
+		if len(args) < 2 {
+			fmt.Println("usage: peggy test grammar...")
+			os.Exit(1)
+		}
+		cfg, err := flagConfig()
+		if err == nil {
+			err = testGrammar(os.Stdout, args[1:], cfg)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	var w io.Writer = os.Stdout
-	if *out != "" {
-		f, err := os.Create(*out)
+	if len(args) > 0 && args[0] == "profile" {
+		// peggy profile [-root rule] [-count n] grammar [input...]
+		// writes the time of parsing the inputs, or standard input,
+		// by stacks of rules in the folded stacks format.
+		fs := flag.NewFlagSet("profile", flag.ExitOnError)
+		root := fs.String("root", "", "the root rule; the first rule if empty")
+		count := fs.Int("count", 1, "the number of times to parse each input")
+		fs.Parse(args[1:])
+		if fs.NArg() < 1 {
+			fmt.Println("usage: peggy profile [-root rule] [-count n] grammar [input...]")
+			os.Exit(1)
+		}
+		if err := profile(os.Stdout, fs.Arg(0), *root, *count, fs.Args()[1:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "trace" {
+		// peggy trace [-root rule] grammar [input] writes the trace
+		// of parsing the input, or standard input, for peggy replay.
+		fs := flag.NewFlagSet("trace", flag.ExitOnError)
+		root := fs.String("root", "", "the root rule; the first rule if empty")
+		fs.Parse(args[1:])
+		if fs.NArg() < 1 || fs.NArg() > 2 {
+			fmt.Println("usage: peggy trace [-root rule] grammar [input] > trace")
+			os.Exit(1)
+		}
+		if err := trace(os.Stdout, fs.Arg(0), *root, fs.Arg(1)); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "replay" {
+		// peggy replay trace steps through a trace,
+		// reading commands from standard input.
+		if len(args) != 2 {
+			fmt.Println("usage: peggy replay trace")
+			os.Exit(1)
+		}
+		if err := replayMain(os.Stdout, os.Stdin, args[1]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "diff" {
+		// peggy diff old new reports the semantic differences
+		// between two grammars.
+		if len(args) != 3 {
+			fmt.Println("usage: peggy diff old.peggy new.peggy")
+			os.Exit(1)
+		}
+		if err := diffMain(os.Stdout, args[1], args[2]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "import" {
+		// peggy import -from dialect [grammar] converts a grammar
+		// in another PEG dialect to a Peggy grammar.
+		fs := flag.NewFlagSet("import", flag.ExitOnError)
+		from := fs.String("from", "pigeon", "dialect of the grammar: pigeon or pegjs")
+		fs.Parse(args[1:])
+		if err := importMain(*from, fs.Args()); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "export" {
+		// peggy export -to dialect [grammar] converts a Peggy grammar
+		// to another grammar dialect.
+		fs := flag.NewFlagSet("export", flag.ExitOnError)
+		to := fs.String("to", "abnf", "dialect of the output: abnf, pegjs, or lpeg")
+		fs.Parse(args[1:])
+		if err := exportMain(*to, fs.Args()); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "schema" {
+		// peggy schema -from format [schema] generates a grammar
+		// from an ABNF or JSON Schema schema.
+		fs := flag.NewFlagSet("schema", flag.ExitOnError)
+		from := fs.String("from", "abnf", "format of the schema: abnf or jsonschema")
+		fs.Parse(args[1:])
+		if err := schemaMain(*from, fs.Args()); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "encode" {
+		// peggy encode -format format [grammar...] writes the checked grammar
+		// encoded as JSON or gob for caches and external analyzers.
+		fs := flag.NewFlagSet("encode", flag.ExitOnError)
+		format := fs.String("format", "json", "format of the output: json or gob")
+		fs.Parse(args[1:])
+		if err := encodeMain(*format, fs.Args()); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "keywords" {
+		// peggy keywords -format format [grammar...] writes a table
+		// of the grammar's literal terminals for editors and highlighters.
+		fs := flag.NewFlagSet("keywords", flag.ExitOnError)
+		format := fs.String("format", "json", "format of the output: json or go")
+		fs.Parse(args[1:])
+		if err := keywordsMain(*format, fs.Args()); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "serve" {
+		// peggy serve [-addr address] serves a web page
+		// for editing a grammar and an input,
+		// showing the parse tree or explained error, and the rule types.
+		fs := flag.NewFlagSet("serve", flag.ExitOnError)
+		addr := fs.String("addr", "localhost:8080", "the loopback address to serve on")
+		fs.Parse(args[1:])
+		cfg, err := flagConfig()
+		if err == nil {
+			err = serve(*addr, cfg)
+		}
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
-		defer func() {
-			if err := f.Close(); err != nil {
-				fmt.Println(err)
-			}
-		}()
-		w = f
+		return
+	}
+
+	if len(args) > 0 && args[0] == "wasm" {
+		// peggy -o file.wasm wasm [-root rule] grammar builds the parser
+		// into a WebAssembly module with a JavaScript module exporting
+		// parse(text), which parses with the rule, or the first rule.
+		fs := flag.NewFlagSet("wasm", flag.ExitOnError)
+		root := fs.String("root", "", "the root rule; the first rule if empty")
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			fmt.Println("usage: peggy -o file.wasm wasm [-root rule] grammar")
+			os.Exit(1)
+		}
+		cfg, err := flagConfig()
+		if err == nil {
+			err = wasm(*out, fs.Arg(0), *root, cfg)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "cabi" {
+		// peggy cabi [-root rule] [-cname name] [grammar...] writes a cgo wrapper
+		// exporting C functions that parse the rule, or the first rule, returning JSON.
+		fs := flag.NewFlagSet("cabi", flag.ExitOnError)
+		root := fs.String("root", "", "the root rule; the first rule if empty")
+		cname := fs.String("cname", "", "prefix of the names of the exported C functions, by default the package name")
+		fs.Parse(args[1:])
+		if err := cabiMain(*root, *cname, fs.Args()); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *watchGrammar {
+		if len(args) == 0 || *out == "" {
+			fmt.Println("-w requires grammar files and an -o output file")
+			os.Exit(exitError)
+		}
+		watch(os.Stdout, args, 500*time.Millisecond, nil)
+		return
+	}
+
+	if code, err := generate(os.Stderr, args); err != nil {
+		fmt.Println(err)
+		os.Exit(code)
 	}
+}
+
+// generate generates the output of the grammar files,
+// or of standard input if there are none, according to the flags,
+// writing the warnings of the grammar to warn.
+// On failure, it returns the exit code of the failure and its error.
+// The output is buffered, and only written if it is complete,
+// so a failure never leaves a truncated -o file or standard output.
+func generate(warn io.Writer, files []string) (int, error) {
+	file := "<stdin>"
+	if len(files) > 0 {
+		file = files[0]
+	}
+	cfg, err := flagConfig()
+	if err != nil {
+		return exitError, err
+	}
+	g, err := parseFiles(cfg, files)
+	if err != nil {
+		if _, ok := err.(*os.PathError); ok {
+			return exitError, err
+		}
+		return exitParse, err
+	}
+
+	var b bytes.Buffer
 	if *prettyPrint {
 		for i := range g.Rules {
-			r := &g.Rules[i]
-			if _, err := io.WriteString(w, r.String()+"\n"); err != nil {
-				fmt.Println(err)
-				os.Exit(1)
-			}
+			b.WriteString(g.Rules[i].PrettyString() + "\n")
 		}
-		os.Exit(0)
+		return exitError, writeOutput(*out, b.Bytes())
 	}
-	if err := Check(g); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	err = cfg.Check(g)
+	for _, w := range g.Warnings {
+		fmt.Fprintln(warn, w)
+	}
+	if err != nil {
+		return exitCheck, err
+	}
+	if *treeSitter {
+		if err := grammar.TreeSitter(&b, treeSitterName(file), g); err != nil {
+			return exitGenerate, err
+		}
+		return exitError, writeOutput(*out, b.Bytes())
 	}
 
-	cfg := Config{Prefix: *prefix}
-	if err := cfg.Generate(w, file, g); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	var pkg *grammar.OutputPackage
+	if *out != "" {
+		if pkg, err = grammar.ReadOutputPackage(*out); err != nil {
+			return exitError, err
+		}
+		if g.Prelude == nil && pkg.Name != "" {
+			// Without a prelude, the output would have no package clause.
+			g.Prelude = grammar.NewText("package " + pkg.Name + "\n")
+		}
+	}
+	var prev []byte
+	if *incremental {
+		if *out == "" {
+			return exitError, errors.New("-incremental requires an -o output file")
+		}
+		if prev, err = ioutil.ReadFile(*out); err != nil && !os.IsNotExist(err) {
+			return exitError, err
+		}
+		cfg.Incremental = true
+		cfg.Previous = string(prev)
+	}
+	var m bytes.Buffer
+	if *sourceMap == "" {
+		err = cfg.Generate(&b, file, g)
+	} else {
+		err = cfg.GenerateSourceMap(&b, &m, file, g)
+	}
+	if err != nil {
+		return exitGenerate, err
+	}
+	if err := pkg.Check(b.Bytes()); err != nil {
+		return exitGenerate, err
+	}
+	if err := writeReport(cfg, file, g, b.Bytes()); err != nil {
+		return exitError, err
+	}
+	// Unchanged -incremental output is not rewritten at all.
+	if !*incremental || !bytes.Equal(b.Bytes(), prev) {
+		if err := writeOutput(*out, b.Bytes()); err != nil {
+			return exitError, err
+		}
+	}
+	if *sourceMap == "" {
+		return 0, nil
+	}
+	return exitError, writeOutput(*sourceMap, m.Bytes())
+}
+
+// The exit codes of the peggy command,
+// distinguishing failures for build scripts.
+// Bad command-line flags exit with 2.
+const (
+	// exitError is a usage, I/O, or other error.
+	exitError = 1
+	// exitParse is a grammar that failed to parse.
+	exitParse = 3
+	// exitCheck is a grammar that failed to check.
+	exitCheck = 4
+	// exitGenerate is a parser that failed to generate.
+	exitGenerate = 5
+)
+
+// parseFiles returns the Grammar of the grammar files, parsed with the Config,
+// or standard input if there are none.
+// The rules of multiple files are merged into one Grammar;
+// only one may have a prelude,
+// and the %const directives of each file apply only to that file.
+func parseFiles(cfg grammar.Config, files []string) (*grammar.Grammar, error) {
+	if len(files) == 0 {
+		return cfg.Parse(bufio.NewReader(os.Stdin), "<stdin>")
+	}
+	var g *grammar.Grammar
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		h, err := cfg.Parse(bufio.NewReader(f), file)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		if g == nil {
+			g = h
+		} else if err := g.Merge(file, h); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// writeOutput writes the data to the file,
+// or standard output if file is the empty string.
+// The file is replaced by renaming a temporary file,
+// so it is never left partially written.
+func writeOutput(file string, data []byte) error {
+	if file == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	// TempFile creates the file readable only by its owner.
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(file); err == nil {
+		mode = fi.Mode()
+	}
+	if err := os.Chmod(f.Name(), mode); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), file); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// flagConfig returns the Config specified by the command-line flags.
+func flagConfig() (grammar.Config, error) {
+	cfg := grammar.Config{
+		Prefix:           *prefix,
+		NoActions:        !*genActions,
+		NoParseTree:      !*genParseTree,
+		Cover:            *cover,
+		SharedMemo:       *sharedMemo,
+		CacheSilentFails: *cacheSilent,
+		Profile:          *profileRules,
+		Trace:            *traceRules,
+		PegImport:        *pegImport,
+		PegParser:        *pegParser,
+		Assert:           *genAssert,
+		RuleNames:        *genRuleNames,
+		Tags:             strings.Split(*buildTags, ","),
+		AST:              *genAST,
+		OptOK:            *optOK,
+		Tuples:           *tuples,
+		WarnUnused:       *warnUnused,
+		Sandbox:          *sandboxCode,
+		Strict:           *strictChecks,
+		Pure:             *pureActions,
+	}
+	switch *memoLayout {
+	case "row":
+		cfg.Memo = grammar.RowMajor
+	case "column":
+		cfg.Memo = grammar.ColumnMajor
+	case "map":
+		cfg.Memo = grammar.SparseMemo
+	default:
+		return grammar.Config{}, errors.New("bad -memo layout " + *memoLayout + ": want row, column, or map")
+	}
+	switch *errorMode {
+	case "furthest":
+		cfg.Errors = grammar.FurthestErrors
+	case "cut":
+		cfg.Errors = grammar.CutErrors
+	case "merged":
+		cfg.Errors = grammar.MergedErrors
+	default:
+		return grammar.Config{}, errors.New("bad -errors mode " + *errorMode + ": want furthest, cut, or merged")
+	}
+	switch *treeNames {
+	case "instance":
+		cfg.TreeNames = grammar.InstanceNames
+	case "template":
+		cfg.TreeNames = grammar.TemplateNames
+	case "label":
+		cfg.TreeNames = grammar.LabelNames
+	default:
+		return grammar.Config{}, errors.New("bad -treenames naming " + *treeNames + ": want instance, template, or label")
+	}
+	return cfg, nil
+}
+
+// importMain imports the grammar file, or standard input if none,
+// writing the Peggy grammar to the -o file or standard output,
+// and a line to standard error for each dropped construct.
+func importMain(from string, args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: peggy import -from dialect [grammar]")
 	}
+	in := bufio.NewReader(os.Stdin)
+	file := "<stdin>"
+	if len(args) > 0 {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = bufio.NewReader(f)
+		file = args[0]
+	}
+	g, dropped, err := grammar.ImportGrammar(in, file, from)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if err := grammar.WriteImported(&b, g); err != nil {
+		return err
+	}
+	if err := writeOutput(*out, b.Bytes()); err != nil {
+		return err
+	}
+	for _, e := range dropped.Errs {
+		fmt.Fprintln(os.Stderr, e)
+	}
+	return nil
+}
+
+// exportMain exports the grammar file, or standard input if none,
+// writing the grammar in the dialect to the -o file or standard output.
+func exportMain(to string, args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: peggy export -to dialect [grammar]")
+	}
+	in := bufio.NewReader(os.Stdin)
+	file := "<stdin>"
+	if len(args) > 0 {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = bufio.NewReader(f)
+		file = args[0]
+	}
+	cfg, err := flagConfig()
+	if err != nil {
+		return err
+	}
+	g, err := cfg.Parse(in, file)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Check(g); err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	dropped, err := grammar.Export(&b, to, g)
+	if err != nil {
+		return err
+	}
+	if err := writeOutput(*out, b.Bytes()); err != nil {
+		return err
+	}
+	for _, e := range dropped.Errs {
+		fmt.Fprintln(os.Stderr, e)
+	}
+	return nil
+}
+
+// treeSitterName returns the tree-sitter grammar name for a grammar file:
+// the base name of the file, without extension,
+// with non-identifier characters replaced by _.
+func treeSitterName(file string) string {
+	if file == "<stdin>" {
+		return "grammar"
+	}
+	base := filepath.Base(file)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return '_'
+	}, base)
 }
@@ -0,0 +1,144 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+func TestParseFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peggy_parse_files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, src string) string {
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, []byte(src), 0666); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	main := write("main.peggy", "{\npackage p\n}\n%maxdepth 10\nA <- B \"a\"\n")
+	exprs := write("exprs.peggy", "%const X = \"x\"\nB <- \"\\{X}\"\n")
+	prelude := write("prelude.peggy", "{\npackage q\n}\nC <- \"c\"\n")
+	depth := write("depth.peggy", "%maxdepth 20\nC <- \"c\"\n")
+	undef := write("undef.peggy", "C <- \"\\{X}\"\n")
+
+	g, err := parseFiles(grammar.Config{}, []string{main, exprs})
+	if err != nil {
+		t.Fatalf("parseFiles(main, exprs)=_, %v, want _, nil", err)
+	}
+	if got, want := grammar.String(g.Rules), "A <- B \"a\"\nB <- \"x\""; got != want {
+		t.Errorf("parseFiles(main, exprs) rules=%q, want %q", got, want)
+	}
+	if g.Prelude == nil || g.MaxDepth != 10 {
+		t.Errorf("parseFiles(main, exprs) lost the prelude or %%maxdepth of main")
+	}
+	if err := grammar.Check(g); err != nil {
+		t.Errorf("Check(parseFiles(main, exprs))=%v, want nil", err)
+	}
+
+	for _, test := range []struct {
+		files []string
+		err   string
+	}{
+		{[]string{main, prelude}, `prelude.peggy:1.1,3.2: prelude redefined`},
+		{[]string{main, depth}, `depth.peggy: %maxdepth redefined`},
+		// Constants apply only within their own file.
+		{[]string{main, exprs, undef}, `undef.peggy:1.6,1.11: undefined constant X`},
+	} {
+		_, err := parseFiles(grammar.Config{}, test.files)
+		if err == nil || !regexp.MustCompile(test.err).MatchString(err.Error()) {
+			t.Errorf("parseFiles(%v)=_, %v, want matching %q", test.files, err, test.err)
+		}
+	}
+}
+
+func TestWriteOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peggy_write_output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "out.go")
+	if err := ioutil.WriteFile(file, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeOutput(file, []byte("new")); err != nil {
+		t.Fatalf("writeOutput(_, new)=%v, want nil", err)
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new" {
+		t.Errorf("after writeOutput, file contains %q, want %q", data, "new")
+	}
+	if fi, err := os.Stat(file); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("after writeOutput, file mode=%v, %v, want 0600", fi.Mode(), err)
+	}
+	// The temporary file is renamed, leaving only the output.
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fis) != 1 {
+		t.Errorf("after writeOutput, directory has %d files, want 1", len(fis))
+	}
+}
+
+func TestGenerateIncremental(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peggy_generate_incremental")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "g.peggy")
+	grammar := "{\npackage p\n}\nA <- x:\"a\"\nB <- \"b\"\n"
+	if err := ioutil.WriteFile(file, []byte(grammar), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// This test cannot be run in parallel.
+	defer func(o, m string, i, w bool) {
+		*out, *sourceMap, *incremental, *warnUnused = o, m, i, w
+	}(*out, *sourceMap, *incremental, *warnUnused)
+	*out = filepath.Join(dir, "g.go")
+	*sourceMap = filepath.Join(dir, "g.json")
+	*incremental = true
+	*warnUnused = true
+
+	var warn strings.Builder
+	if code, err := generate(&warn, []string{file}); err != nil {
+		t.Fatalf("generate(_, %q)=%d, %v, want nil", file, code, err)
+	}
+	if !strings.Contains(warn.String(), "label x is unused") {
+		t.Errorf("generate with -incremental wrote warnings %q, want label x is unused", warn.String())
+	}
+	first, err := ioutil.ReadFile(*sourceMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(*sourceMap); err != nil {
+		t.Fatal(err)
+	}
+	// Regenerating copies the unchanged rules, but writes the full source map.
+	if code, err := generate(&warn, []string{file}); err != nil {
+		t.Fatalf("generate(_, %q)=%d, %v, want nil", file, code, err)
+	}
+	if second, err := ioutil.ReadFile(*sourceMap); err != nil || string(second) != string(first) {
+		t.Errorf("regenerated source map %v\n%s\nwant\n%s", err, second, first)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Balanced returns the byte offset of the end of the block of text at pos
+// of a %balanced expression: the open delimiter,
+// the text up to the matching close delimiter,
+// skipping nested pairs of open and close delimiters,
+// and the matching close delimiter.
+// If escape is non-empty, an escape and the rune following it
+// are skipped, so neither is a delimiter.
+// If open and close are the same, the delimiters do not nest.
+//
+// If the text at pos does not begin with the open delimiter,
+// or the open delimiter has no matching close delimiter,
+// the returned Fail is non-nil, wanting the missing delimiter.
+func Balanced(text string, pos int, open, close, escape string) (end int, fail *Fail) {
+	if !strings.HasPrefix(text[pos:], open) {
+		return 0, &Fail{Pos: pos, Want: strconv.QuoteToGraphic(open), Code: ExpectedLiteral}
+	}
+	depth := 1
+	for i := pos + len(open); i < len(text); {
+		switch {
+		case escape != "" && strings.HasPrefix(text[i:], escape):
+			i += len(escape)
+			if i < len(text) {
+				_, w := utf8.DecodeRuneInString(text[i:])
+				i += w
+			}
+		case strings.HasPrefix(text[i:], close):
+			i += len(close)
+			if depth--; depth == 0 {
+				return i, nil
+			}
+		case strings.HasPrefix(text[i:], open):
+			i += len(open)
+			depth++
+		default:
+			i++
+		}
+	}
+	return 0, &Fail{Pos: len(text), Want: strconv.QuoteToGraphic(close), Code: ExpectedLiteral}
+}
+
+// Until returns the byte offset of the end of the block of text at pos
+// of an %until expression: the text up to and including
+// the first following close delimiter.
+// If escape is non-empty, an escape and the rune following it
+// are skipped, so neither is a delimiter.
+//
+// If there is no following close delimiter,
+// the returned Fail is non-nil, wanting the close delimiter.
+func Until(text string, pos int, close, escape string) (end int, fail *Fail) {
+	if escape == "" {
+		if n := strings.Index(text[pos:], close); n >= 0 {
+			return pos + n + len(close), nil
+		}
+		return 0, &Fail{Pos: len(text), Want: strconv.QuoteToGraphic(close), Code: ExpectedLiteral}
+	}
+	for i := pos; i < len(text); {
+		switch {
+		case strings.HasPrefix(text[i:], escape):
+			i += len(escape)
+			if i < len(text) {
+				_, w := utf8.DecodeRuneInString(text[i:])
+				i += w
+			}
+		case strings.HasPrefix(text[i:], close):
+			return i + len(close), nil
+		default:
+			i++
+		}
+	}
+	return 0, &Fail{Pos: len(text), Want: strconv.QuoteToGraphic(close), Code: ExpectedLiteral}
+}
@@ -0,0 +1,76 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import "testing"
+
+func TestBalanced(t *testing.T) {
+	tests := []struct {
+		text                string
+		pos                 int
+		open, close, escape string
+		end                 int
+		fail                *Fail
+	}{
+		{text: "x(a)y", pos: 1, open: "(", close: ")", end: 4},
+		{text: "(a(b)(c(d))e)f", open: "(", close: ")", end: 13},
+		{text: "()", open: "(", close: ")", end: 2},
+		{text: `(a\)b)`, open: "(", close: ")", escape: `\`, end: 6},
+		{text: `(a\(b)`, open: "(", close: ")", escape: `\`, end: 6},
+		{text: `"a\"b"c"`, open: `"`, close: `"`, escape: `\`, end: 6},
+		{text: "{{a {{b}} c}}d", open: "{{", close: "}}", end: 13},
+		{text: "x(a)", pos: 0, open: "(", close: ")", fail: &Fail{Pos: 0, Want: `"("`, Code: ExpectedLiteral}},
+		{text: "(a(b)", open: "(", close: ")", fail: &Fail{Pos: 5, Want: `")"`, Code: ExpectedLiteral}},
+		{text: `(a\)`, open: "(", close: ")", escape: `\`, fail: &Fail{Pos: 4, Want: `")"`, Code: ExpectedLiteral}},
+		{text: `(a\`, open: "(", close: ")", escape: `\`, fail: &Fail{Pos: 3, Want: `")"`, Code: ExpectedLiteral}},
+	}
+	for _, test := range tests {
+		end, fail := Balanced(test.text, test.pos, test.open, test.close, test.escape)
+		switch {
+		case test.fail == nil && fail != nil:
+			t.Errorf("Balanced(%q, %d, %q, %q, %q)=_, %+v, want nil",
+				test.text, test.pos, test.open, test.close, test.escape, fail)
+		case test.fail != nil && (fail == nil || fail.Pos != test.fail.Pos || fail.Want != test.fail.Want || fail.Code != test.fail.Code):
+			t.Errorf("Balanced(%q, %d, %q, %q, %q)=_, %+v, want %+v",
+				test.text, test.pos, test.open, test.close, test.escape, fail, test.fail)
+		case test.fail == nil && end != test.end:
+			t.Errorf("Balanced(%q, %d, %q, %q, %q)=%d, nil, want %d, nil",
+				test.text, test.pos, test.open, test.close, test.escape, end, test.end)
+		}
+	}
+}
+
+func TestUntil(t *testing.T) {
+	tests := []struct {
+		text          string
+		pos           int
+		close, escape string
+		end           int
+		fail          *Fail
+	}{
+		{text: "/* a */ b", pos: 2, close: "*/", end: 7},
+		{text: "*/", close: "*/", end: 2},
+		{text: `a\"b"c"`, close: `"`, escape: `\`, end: 5},
+		{text: `a\\"b"`, close: `"`, escape: `\`, end: 4},
+		{text: "/* a", pos: 2, close: "*/", fail: &Fail{Pos: 4, Want: `"*/"`, Code: ExpectedLiteral}},
+		{text: `a\"`, close: `"`, escape: `\`, fail: &Fail{Pos: 3, Want: `"\""`, Code: ExpectedLiteral}},
+	}
+	for _, test := range tests {
+		end, fail := Until(test.text, test.pos, test.close, test.escape)
+		switch {
+		case test.fail == nil && fail != nil:
+			t.Errorf("Until(%q, %d, %q, %q)=_, %+v, want nil",
+				test.text, test.pos, test.close, test.escape, fail)
+		case test.fail != nil && (fail == nil || fail.Pos != test.fail.Pos || fail.Want != test.fail.Want || fail.Code != test.fail.Code):
+			t.Errorf("Until(%q, %d, %q, %q)=_, %+v, want %+v",
+				test.text, test.pos, test.close, test.escape, fail, test.fail)
+		case test.fail == nil && end != test.end:
+			t.Errorf("Until(%q, %d, %q, %q)=%d, nil, want %d, nil",
+				test.text, test.pos, test.close, test.escape, end, test.end)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+// Checks records the failed Expect and the diagnostics
+// of the actions of a generated parser,
+// which embeds it, so actions call parser.Expect and parser.Diag.
+// The zero Checks has no failed Expect and no diagnostics.
+type Checks struct {
+	// expectPos is the byte offset, plus 1,
+	// of the first failed Expect, or 0 if none,
+	// and expectMsg is its message.
+	expectPos int
+	expectMsg string
+	diags     []diag
+}
+
+// diag is a diagnostic recorded by Diag.
+type diag struct {
+	start, end int
+	msg        string
+}
+
+// Expect validates a result of an action:
+// if cond is false, the parse fails with the message msg
+// located at the byte offset pos, typically the start or end
+// of the action's match, instead of returning its result.
+// Only the first failure is reported, and it is not cleared,
+// so it is reported by each parse of a match including pos.
+// Actions without !memo may run for matches
+// that are not part of the parse, such as failed choice branches,
+// so Expect is best called by !memo actions.
+// Expect returns cond.
+func (c *Checks) Expect(cond bool, pos int, msg string) bool {
+	if !cond && c.expectPos == 0 {
+		c.expectPos, c.expectMsg = pos+1, msg
+	}
+	return cond
+}
+
+// ExpectError returns the error of the first failed Expect
+// at a byte offset between start and end, inclusive,
+// located in the text by l, or nil if none.
+func (c *Checks) ExpectError(l Locator, text string, start, end int) error {
+	pos := c.expectPos - 1
+	if pos < start || pos > end {
+		return nil
+	}
+	return Error{Loc: l.Location(text, pos), Message: c.expectMsg}
+}
+
+// Diag records a non-fatal diagnostic of an action,
+// such as a warning of deprecated syntax,
+// with the message msg about the text
+// between the byte offsets start and end,
+// typically those of the action's match.
+// The diagnostics do not affect the parse;
+// they are returned by Diagnostics.
+// Actions without !memo may run for matches
+// that are not part of the parse, such as failed choice branches,
+// so Diag is best called by !memo actions.
+func (c *Checks) Diag(start, end int, msg string) {
+	c.diags = append(c.diags, diag{start: start, end: end, msg: msg})
+}
+
+// Diagnostics returns the diagnostics recorded by Diag,
+// in the order that they were recorded, without duplicates,
+// each an Error located in the text by l at the start of its text
+// with End the location of the end of its text.
+func (c *Checks) Diagnostics(l Locator, text string) []Error {
+	if len(c.diags) == 0 {
+		return nil
+	}
+	x := l.NewLineIndex(text)
+	seen := make(map[diag]bool)
+	var errs []Error
+	for _, d := range c.diags {
+		if seen[d] {
+			continue
+		}
+		seen[d] = true
+		errs = append(errs, Error{
+			Loc:     x.Location(d.start),
+			End:     x.Location(d.end),
+			Message: d.msg,
+		})
+	}
+	return errs
+}
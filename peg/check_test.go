@@ -0,0 +1,78 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChecksExpect(t *testing.T) {
+	const text = "ab\rcd"
+	var c Checks
+	if err := c.ExpectError(Locator{}, text, 0, len(text)); err != nil {
+		t.Errorf("ExpectError with no failure=%v, want nil", err)
+	}
+	if !c.Expect(true, 1, "one") {
+		t.Errorf("Expect(true, …)=false, want true")
+	}
+	if c.Expect(false, 4, "four") {
+		t.Errorf("Expect(false, …)=true, want false")
+	}
+	// Only the first failure is recorded.
+	c.Expect(false, 5, "five")
+
+	tests := []struct {
+		l          Locator
+		start, end int
+		want       error
+	}{
+		{start: 0, end: 3, want: nil},
+		{start: 5, end: 5, want: nil},
+		{start: 0, end: 4, want: Error{Loc: Loc{Byte: 4, Rune: 4, Line: 1, Column: 5}, Message: "four"}},
+		{start: 4, end: 5, want: Error{Loc: Loc{Byte: 4, Rune: 4, Line: 1, Column: 5}, Message: "four"}},
+		{
+			l:     Locator{Newline: CRLF},
+			start: 0,
+			end:   5,
+			want:  Error{Loc: Loc{Byte: 4, Rune: 4, Line: 2, Column: 2}, Message: "four"},
+		},
+	}
+	for _, test := range tests {
+		err := c.ExpectError(test.l, text, test.start, test.end)
+		if !reflect.DeepEqual(err, test.want) {
+			t.Errorf("ExpectError(%+v, %q, %d, %d)=%v, want %v",
+				test.l, text, test.start, test.end, err, test.want)
+		}
+	}
+}
+
+func TestChecksDiagnostics(t *testing.T) {
+	const text = "ab\ncd"
+	var c Checks
+	if errs := c.Diagnostics(Locator{}, text); errs != nil {
+		t.Errorf("Diagnostics with none=%v, want nil", errs)
+	}
+	c.Diag(3, 5, "second line")
+	c.Diag(0, 1, "first line")
+	c.Diag(3, 5, "second line")
+	want := []Error{
+		{
+			Loc:     Loc{Byte: 3, Rune: 3, Line: 2, Column: 1},
+			End:     Loc{Byte: 5, Rune: 5, Line: 2, Column: 3},
+			Message: "second line",
+		},
+		{
+			Loc:     Loc{Byte: 0, Rune: 0, Line: 1, Column: 1},
+			End:     Loc{Byte: 1, Rune: 1, Line: 1, Column: 2},
+			Message: "first line",
+		},
+	}
+	if errs := c.Diagnostics(Locator{}, text); !reflect.DeepEqual(errs, want) {
+		t.Errorf("Diagnostics=%+v, want %+v", errs, want)
+	}
+}
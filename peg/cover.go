@@ -0,0 +1,105 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// A Coverage counts the number of times
+// each rule and choice branch of a grammar accepted,
+// for measuring the coverage of the grammar by a corpus of inputs.
+// A parser generated with peggy -cover
+// records its coverage in a Coverage variable, <prefix>Coverage.
+type Coverage struct {
+	// Points are the rules and choice branches of the grammar.
+	Points []CoverPoint
+	// Counts are the number of acceptances of each point,
+	// indexed the same as Points.
+	Counts []uint64
+}
+
+// A CoverPoint is a rule or choice branch of a grammar.
+type CoverPoint struct {
+	// Loc is the grammar location of the rule or branch,
+	// in the form file:line.col,line.col.
+	Loc string
+	// Rule is the name of the rule.
+	Rule string
+	// Branch is 0 for the rule itself,
+	// or the 1-based index of the branch within its choice.
+	Branch int
+}
+
+// Hit increments the count of the ith point.
+// It is safe to call concurrently.
+func (c *Coverage) Hit(i int) {
+	atomic.AddUint64(&c.Counts[i], 1)
+}
+
+// Reset sets all counts to 0.
+func (c *Coverage) Reset() {
+	for i := range c.Counts {
+		atomic.StoreUint64(&c.Counts[i], 0)
+	}
+}
+
+// coverHeader is the first line of a coverage profile.
+const coverHeader = "peggy coverage v1"
+
+// WriteProfile writes the Coverage as a coverage profile,
+// which can be read by ReadProfile
+// or reported by the peggy cover command.
+//
+// The profile is a header line, followed by a line for each point:
+// its location, rule, branch, and count, separated by tabs.
+func (c *Coverage) WriteProfile(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, coverHeader)
+	for i, p := range c.Points {
+		n := atomic.LoadUint64(&c.Counts[i])
+		fmt.Fprintf(bw, "%s\t%s\t%d\t%d\n", p.Loc, p.Rule, p.Branch, n)
+	}
+	return bw.Flush()
+}
+
+// ReadProfile reads a coverage profile written by WriteProfile.
+func ReadProfile(r io.Reader) (*Coverage, error) {
+	var c Coverage
+	s := bufio.NewScanner(r)
+	if !s.Scan() || s.Text() != coverHeader {
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("not a coverage profile: missing %q header", coverHeader)
+	}
+	for line := 2; s.Scan(); line++ {
+		fs := strings.Split(s.Text(), "\t")
+		if len(fs) != 4 {
+			return nil, fmt.Errorf("line %d: got %d fields, want 4", line, len(fs))
+		}
+		branch, err := strconv.Atoi(fs[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad branch: %v", line, err)
+		}
+		n, err := strconv.ParseUint(fs[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad count: %v", line, err)
+		}
+		c.Points = append(c.Points, CoverPoint{Loc: fs[0], Rule: fs[1], Branch: branch})
+		c.Counts = append(c.Counts, n)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/pretty"
+)
+
+func TestCoverageProfileRoundTrip(t *testing.T) {
+	c := &Coverage{
+		Points: []CoverPoint{
+			{Loc: "g.peggy:1.1,1.20", Rule: "A"},
+			{Loc: "g.peggy:2.1,2.9", Rule: "L<B, C>"},
+			{Loc: "g.peggy:1.6,1.9", Rule: "A", Branch: 1},
+			{Loc: "g.peggy:1.12,1.20", Rule: "A", Branch: 2},
+		},
+		Counts: make([]uint64, 4),
+	}
+	c.Hit(0)
+	c.Hit(2)
+	c.Hit(2)
+	var b bytes.Buffer
+	if err := c.WriteProfile(&b); err != nil {
+		t.Fatalf("WriteProfile(_)=%v, want nil", err)
+	}
+	got, err := ReadProfile(&b)
+	if err != nil {
+		t.Fatalf("ReadProfile(_)=_, %v, want _, nil", err)
+	}
+	if !reflect.DeepEqual(got, c) {
+		t.Errorf("ReadProfile(_)=%s, want %s", pretty.String(got), pretty.String(c))
+	}
+
+	c.Reset()
+	if !reflect.DeepEqual(c.Counts, []uint64{0, 0, 0, 0}) {
+		t.Errorf("after Reset, Counts=%v, want all 0", c.Counts)
+	}
+}
+
+func TestReadProfileError(t *testing.T) {
+	tests := []struct {
+		in  string
+		err string
+	}{
+		{in: "", err: "not a coverage profile"},
+		{in: "mode: set\n", err: "not a coverage profile"},
+		{in: "peggy coverage v1\na\tA\t0\n", err: "line 2: got 3 fields, want 4"},
+		{in: "peggy coverage v1\na\tA\tx\t1\n", err: "line 2: bad branch"},
+		{in: "peggy coverage v1\na\tA\t0\t1\na\tA\t1\t-1\n", err: "line 3: bad count"},
+	}
+	for _, test := range tests {
+		_, err := ReadProfile(strings.NewReader(test.in))
+		if err == nil || !regexp.MustCompile(test.err).MatchString(err.Error()) {
+			t.Errorf("ReadProfile(%q)=_, %v, want matching %q", test.in, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// DelegateRegion returns the region of text at pos
+// delimited by the open and close markers of a %delegate expression.
+// begin and end are the byte offsets of the region's contents,
+// between the markers.
+//
+// If the text at pos does not begin with the open marker,
+// or the open marker is never followed by the close marker,
+// the returned Fail is non-nil, wanting the missing marker.
+func DelegateRegion(text string, pos int, open, close string) (begin, end int, fail *Fail) {
+	if !strings.HasPrefix(text[pos:], open) {
+		return 0, 0, &Fail{Pos: pos, Want: strconv.QuoteToGraphic(open), Code: ExpectedLiteral}
+	}
+	begin = pos + len(open)
+	n := strings.Index(text[begin:], close)
+	if n < 0 {
+		return 0, 0, &Fail{Pos: len(text), Want: strconv.QuoteToGraphic(close), Code: ExpectedLiteral}
+	}
+	return begin, begin + n, nil
+}
+
+// DelegateFail returns the Fail of an error
+// returned by the delegate parser of a region beginning at begin.
+//
+// If the error is an Error, as returned by SimpleError
+// from a Peggy-generated delegate parser,
+// the Fail is at its Loc, offset by begin,
+// and wants what its Message wants:
+// the text between "want " and "; got", if the Message is of that form,
+// and otherwise the entire Message, with its Code.
+// If the error is not an Error,
+// the Fail is at begin and wants the error string.
+func DelegateFail(begin int, err error) *Fail {
+	var e Error
+	if p := (*Error)(nil); errors.As(err, &p) {
+		e = *p
+	} else if !errors.As(err, &e) {
+		return &Fail{Pos: begin, Want: err.Error(), Code: OtherFailure}
+	}
+	want := e.Message
+	if i := strings.Index(want, "; got "); strings.HasPrefix(want, "want ") && i >= 0 {
+		want = want[len("want "):i]
+	}
+	code := e.Code
+	if code == "" {
+		code = OtherFailure
+	}
+	return &Fail{Pos: begin + e.Loc.Byte, Want: want, Code: code}
+}
@@ -0,0 +1,60 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestDelegateRegion(t *testing.T) {
+	tests := []struct {
+		text       string
+		pos        int
+		begin, end int
+		fail       *Fail
+	}{
+		{text: "x<<abc>>y", pos: 1, begin: 3, end: 6},
+		{text: "<<>>", pos: 0, begin: 2, end: 2},
+		{text: "<<a>>b>>", pos: 0, begin: 2, end: 3},
+		{text: "x<<abc>>", pos: 0, fail: &Fail{Pos: 0, Want: `"<<"`, Code: ExpectedLiteral}},
+		{text: "<", pos: 0, fail: &Fail{Pos: 0, Want: `"<<"`, Code: ExpectedLiteral}},
+		{text: "<<abc>", pos: 0, fail: &Fail{Pos: 6, Want: `">>"`, Code: ExpectedLiteral}},
+	}
+	for _, test := range tests {
+		begin, end, fail := DelegateRegion(test.text, test.pos, "<<", ">>")
+		switch {
+		case test.fail == nil && fail != nil:
+			t.Errorf("DelegateRegion(%q, %d)=_, _, %+v, want nil", test.text, test.pos, fail)
+		case test.fail != nil && (fail == nil || fail.Pos != test.fail.Pos || fail.Want != test.fail.Want || fail.Code != test.fail.Code):
+			t.Errorf("DelegateRegion(%q, %d)=_, _, %+v, want %+v", test.text, test.pos, fail, test.fail)
+		case test.fail == nil && (begin != test.begin || end != test.end):
+			t.Errorf("DelegateRegion(%q, %d)=%d, %d, nil, want %d, %d, nil",
+				test.text, test.pos, begin, end, test.begin, test.end)
+		}
+	}
+}
+
+func TestDelegateFail(t *testing.T) {
+	perr := Error{Loc: Loc{Byte: 4, Line: 1, Column: 5}, Message: `want "x" or [0-9]; got 'y; got z'`, Code: ExpectedLiteral}
+	tests := []struct {
+		err  error
+		want Fail
+	}{
+		{err: perr, want: Fail{Pos: 14, Want: `"x" or [0-9]`, Code: ExpectedLiteral}},
+		{err: &perr, want: Fail{Pos: 14, Want: `"x" or [0-9]`, Code: ExpectedLiteral}},
+		{err: fmt.Errorf("wrapped: %w", perr), want: Fail{Pos: 14, Want: `"x" or [0-9]`, Code: ExpectedLiteral}},
+		{err: Error{Loc: Loc{Byte: 1}, Message: "bad"}, want: Fail{Pos: 11, Want: "bad", Code: OtherFailure}},
+		{err: errors.New("bad"), want: Fail{Pos: 10, Want: "bad", Code: OtherFailure}},
+	}
+	for _, test := range tests {
+		if got := DelegateFail(10, test.err); got.Pos != test.want.Pos || got.Want != test.want.Want || got.Code != test.want.Code {
+			t.Errorf("DelegateFail(10, %v)=%+v, want %+v", test.err, *got, test.want)
+		}
+	}
+}
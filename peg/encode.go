@@ -0,0 +1,297 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// failVersion is the version of the Fail binary encoding.
+const failVersion = 1
+
+// MarshalBinary implements encoding.BinaryMarshaler,
+// returning a compact binary encoding of the Fail tree.
+//
+// The encoding is:
+//
+//	a version byte,
+//	a uvarint count of strings, followed by
+//		each string as a uvarint length and its bytes,
+//	a uvarint count of Fail nodes, followed by
+//		each Fail node, in post-order, as uvarints:
+//			the string index of its Name,
+//			the string index of its Want, shifted left by one,
+//				with the low bit set if the Want is a Message,
+//			the string index of its Code,
+//			its Pos,
+//			its number of Kids, and
+//			the node index of each of its Kids.
+//
+// The root is the last node.
+//
+// Names, Wants, and Codes are stored once in the string table,
+// and Fail nodes shared by multiple parents are stored once,
+// so the encoding is typically much smaller than the tree.
+func (f *Fail) MarshalBinary() ([]byte, error) {
+	e := failEncoder{
+		strs:  make(map[string]int),
+		nodes: make(map[*Fail]int),
+	}
+	e.add(f)
+	buf := []byte{failVersion}
+	buf = appendUvarint(buf, uint64(len(e.strList)))
+	for _, s := range e.strList {
+		buf = appendUvarint(buf, uint64(len(s)))
+		buf = append(buf, s...)
+	}
+	buf = appendUvarint(buf, uint64(len(e.nodeList)))
+	for _, n := range e.nodeList {
+		if n.Pos < 0 {
+			return nil, fmt.Errorf("negative Fail position %d", n.Pos)
+		}
+		buf = appendUvarint(buf, uint64(e.strs[n.Name]))
+		want := uint64(e.strs[n.Want]) << 1
+		if n.Message {
+			want |= 1
+		}
+		buf = appendUvarint(buf, want)
+		buf = appendUvarint(buf, uint64(e.strs[string(n.Code)]))
+		buf = appendUvarint(buf, uint64(n.Pos))
+		buf = appendUvarint(buf, uint64(len(n.Kids)))
+		for _, k := range n.Kids {
+			buf = appendUvarint(buf, uint64(e.nodes[k]))
+		}
+	}
+	return buf, nil
+}
+
+func appendUvarint(buf []byte, x uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(buf, tmp[:binary.PutUvarint(tmp[:], x)]...)
+}
+
+type failEncoder struct {
+	strs     map[string]int
+	strList  []string
+	nodes    map[*Fail]int
+	nodeList []*Fail
+}
+
+func (e *failEncoder) add(f *Fail) {
+	if _, ok := e.nodes[f]; ok {
+		return
+	}
+	for _, k := range f.Kids {
+		e.add(k)
+	}
+	e.str(f.Name)
+	e.str(f.Want)
+	e.str(string(f.Code))
+	e.nodes[f] = len(e.nodeList)
+	e.nodeList = append(e.nodeList, f)
+}
+
+func (e *failEncoder) str(s string) {
+	if _, ok := e.strs[s]; !ok {
+		e.strs[s] = len(e.strList)
+		e.strList = append(e.strList, s)
+	}
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler,
+// decoding a Fail tree encoded by MarshalBinary into the receiver.
+// Fail nodes that were shared in the encoded tree
+// are also shared in the decoded tree.
+func (f *Fail) UnmarshalBinary(data []byte) error {
+	d := failDecoder{data: data}
+	v := d.byte()
+	if d.err == nil && v != failVersion {
+		return fmt.Errorf("unsupported Fail encoding version %d", v)
+	}
+	strs := make([]string, d.count())
+	for i := range strs {
+		n := d.count()
+		if d.err == nil {
+			strs[i] = string(d.data[:n])
+			d.data = d.data[n:]
+		}
+	}
+	if d.err != nil {
+		return d.err
+	}
+	nodes := make([]*Fail, d.count())
+	if len(nodes) > 0 && len(strs) == 0 {
+		return errors.New("Fail nodes with no strings")
+	}
+	for i := range nodes {
+		// The indices read after an error are 0,
+		// which is in range of the non-empty strs.
+		if d.err != nil {
+			return d.err
+		}
+		n := &Fail{Name: strs[d.index(len(strs))]}
+		want, msg := d.flaggedIndex(len(strs))
+		n.Want, n.Message = strs[want], msg
+		n.Code = ErrorCode(strs[d.index(len(strs))])
+		n.Pos = d.int()
+		if nkids := d.count(); nkids > 0 {
+			n.Kids = make([]*Fail, nkids)
+			for j := range n.Kids {
+				// Kids precede their parents, so the index is less than i.
+				n.Kids[j] = nodes[d.index(i)]
+			}
+		}
+		nodes[i] = n
+	}
+	switch {
+	case d.err != nil:
+		return d.err
+	case len(nodes) == 0:
+		return errors.New("no Fail nodes")
+	case len(d.data) > 0:
+		return errors.New("extra data after Fail nodes")
+	}
+	*f = *nodes[len(nodes)-1]
+	return nil
+}
+
+type failDecoder struct {
+	data []byte
+	err  error
+}
+
+func (d *failDecoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.data) == 0 {
+		d.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b
+}
+
+// count returns the next uvarint, which must be at most len(d.data),
+// since each counted item is encoded with at least one byte.
+func (d *failDecoder) count() int {
+	if d.err != nil {
+		return 0
+	}
+	x, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	d.data = d.data[n:]
+	if x > uint64(len(d.data)) {
+		d.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	return int(x)
+}
+
+// int returns the next uvarint, which must fit in an int.
+func (d *failDecoder) int() int {
+	if d.err != nil {
+		return 0
+	}
+	x, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	d.data = d.data[n:]
+	if int(x) < 0 || uint64(int(x)) != x {
+		d.err = fmt.Errorf("bad position %d", x)
+		return 0
+	}
+	return int(x)
+}
+
+// index returns the next uvarint, which must be less than max.
+func (d *failDecoder) index(max int) int {
+	if d.err != nil {
+		return 0
+	}
+	x, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	d.data = d.data[n:]
+	if x >= uint64(max) {
+		d.err = fmt.Errorf("bad index %d, max %d", x, max)
+		return 0
+	}
+	return int(x)
+}
+
+// flaggedIndex returns the next uvarint shifted right by one,
+// which must be less than max, and whether its low bit is set.
+func (d *failDecoder) flaggedIndex(max int) (int, bool) {
+	if d.err != nil {
+		return 0, false
+	}
+	x, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err = io.ErrUnexpectedEOF
+		return 0, false
+	}
+	d.data = d.data[n:]
+	if x>>1 >= uint64(max) {
+		d.err = fmt.Errorf("bad index %d, max %d", x>>1, max)
+		return 0, false
+	}
+	return int(x >> 1), x&1 == 1
+}
+
+// WriteFail writes the binary encoding of a Fail tree to a Writer
+// as a single record of a log that can be read by ReadFail.
+// Each record is the uvarint length of the encoding, followed by the encoding.
+func WriteFail(w io.Writer, f *Fail) error {
+	data, err := f.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	buf := appendUvarint(nil, uint64(len(data)))
+	_, err = w.Write(append(buf, data...))
+	return err
+}
+
+// ReadFail reads the next record written by WriteFail.
+// It returns io.EOF if there are no more records,
+// and io.ErrUnexpectedEOF if the record is truncated.
+func ReadFail(r interface {
+	io.Reader
+	io.ByteReader
+}) (*Fail, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	// The buffer grows as the record is read,
+	// so a corrupt length fails with io.ErrUnexpectedEOF
+	// instead of allocating the length up front.
+	var data bytes.Buffer
+	if _, err := io.CopyN(&data, r, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	var f Fail
+	if err := f.UnmarshalBinary(data.Bytes()); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/eaburns/pretty"
+)
+
+func TestFailBinaryRoundTrip(t *testing.T) {
+	shared := &Fail{Name: "Num", Pos: 3, Want: "number", Code: NamedRule}
+	tests := []*Fail{
+		{},
+		{Name: "A", Pos: 5, Want: `"a"`, Code: ExpectedLiteral},
+		{Name: "B", Pos: 2, Want: `expected an expression after "="`, Message: true, Code: NamedRule},
+		{
+			Name: "Expr",
+			Kids: []*Fail{
+				{
+					Name: "Sum",
+					Pos:  1,
+					Kids: []*Fail{
+						shared,
+						{Pos: 3, Want: `"+"`, Code: ExpectedLiteral},
+					},
+				},
+				{
+					Name: "Product",
+					Pos:  1,
+					Kids: []*Fail{
+						shared,
+						{Pos: 1000000, Want: "[☺]", Code: ExpectedClass},
+					},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		data, err := test.MarshalBinary()
+		if err != nil {
+			t.Errorf("%s.MarshalBinary()=_, %v, want _,nil", Pretty(test), err)
+			continue
+		}
+		var got Fail
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Errorf("UnmarshalBinary(%s.MarshalBinary())=%v, want nil", Pretty(test), err)
+			continue
+		}
+		if !reflect.DeepEqual(&got, test) {
+			t.Errorf("UnmarshalBinary(%s.MarshalBinary())=\n%s\nwant\n%s",
+				Pretty(test), pretty.String(&got), pretty.String(test))
+		}
+	}
+}
+
+func TestFailBinarySharing(t *testing.T) {
+	shared := &Fail{Name: "Shared", Pos: 1, Kids: []*Fail{{Pos: 1, Want: "."}}}
+	root := &Fail{Name: "Root", Kids: []*Fail{shared, shared}}
+	data, err := root.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary()=_, %v, want _,nil", err)
+	}
+	var got Fail
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary(_)=%v, want nil", err)
+	}
+	if got.Kids[0] != got.Kids[1] {
+		t.Errorf("UnmarshalBinary(_) did not preserve the shared Fail")
+	}
+}
+
+func TestFailBinaryErrors(t *testing.T) {
+	data, err := (&Fail{Name: "A", Kids: []*Fail{{Want: "x"}}}).MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary()=_, %v, want _,nil", err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "bad version", data: append([]byte{0}, data[1:]...)},
+		{name: "unknown version", data: append([]byte{failVersion + 1}, data[1:]...)},
+		{name: "truncated", data: data[:len(data)-1]},
+		{name: "extra data", data: append(append([]byte{}, data...), 0)},
+		{name: "no nodes", data: []byte{failVersion, 0, 0}},
+		{name: "bad string index", data: []byte{failVersion, 1, 0, 1, 1, 0, 0, 0}},
+		{name: "bad kid index", data: []byte{failVersion, 1, 0, 1, 0, 0, 0, 0, 1, 0}},
+		{name: "nodes with no strings", data: []byte{failVersion, 0, 1, 0, 0, 0, 0, 0}},
+		{name: "truncated string", data: []byte{failVersion, 1, 5, 'a'}},
+	}
+	// Every truncation of a larger tree is an error, not a panic.
+	big, err := (&Fail{
+		Name: "A",
+		Kids: []*Fail{
+			{Name: "B", Pos: 300, Want: "b", Code: ExpectedLiteral},
+			{Want: "c", Message: true},
+		},
+	}).MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary()=_, %v, want _,nil", err)
+	}
+	for i := 0; i < len(big); i++ {
+		tests = append(tests, struct {
+			name string
+			data []byte
+		}{name: fmt.Sprintf("truncated to %d", i), data: big[:i]})
+	}
+	for _, test := range tests {
+		var f Fail
+		if err := f.UnmarshalBinary(test.data); err == nil {
+			t.Errorf("%s: UnmarshalBinary(%v)=nil, want error", test.name, test.data)
+		}
+	}
+	if _, err := (&Fail{Pos: -1}).MarshalBinary(); err == nil {
+		t.Errorf("MarshalBinary() with negative Pos=_, nil, want error")
+	}
+}
+
+func TestWriteReadFail(t *testing.T) {
+	fails := []*Fail{
+		{Name: "A", Want: "a"},
+		{Name: "B", Kids: []*Fail{{Pos: 2, Want: "b"}}},
+		{Name: "C", Pos: 7, Want: "c"},
+	}
+	var b bytes.Buffer
+	for _, f := range fails {
+		if err := WriteFail(&b, f); err != nil {
+			t.Fatalf("WriteFail(_, %s)=%v, want nil", Pretty(f), err)
+		}
+	}
+	r := bufio.NewReader(&b)
+	for _, want := range fails {
+		got, err := ReadFail(r)
+		if err != nil {
+			t.Fatalf("ReadFail(_)=_, %v, want _,nil", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ReadFail(_)=%s, want %s", Pretty(got), Pretty(want))
+		}
+	}
+	if f, err := ReadFail(r); err != io.EOF {
+		t.Errorf("ReadFail(_)=%v, %v, want nil, io.EOF", f, err)
+	}
+}
+
+func TestReadFailTruncated(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteFail(&b, &Fail{Name: "A", Want: "a"}); err != nil {
+		t.Fatalf("WriteFail(_, _)=%v, want nil", err)
+	}
+	record := b.Bytes()
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "truncated record", data: record[:len(record)-1]},
+		{name: "oversized length", data: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}},
+		{name: "oversized length with data", data: append([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}, record...)},
+	}
+	for _, test := range tests {
+		r := bufio.NewReader(bytes.NewReader(test.data))
+		if f, err := ReadFail(r); err != io.ErrUnexpectedEOF {
+			t.Errorf("%s: ReadFail(%v)=%v, %v, want nil, io.ErrUnexpectedEOF", test.name, test.data, f, err)
+		}
+	}
+}
@@ -6,30 +6,119 @@
 
 package peg
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
 
 // SimpleError returns an error with a basic error message
 // that describes what was expected at all of the leaf fails
 // with the greatest position in the tree.
 //
+// The text may be a string or a byte slice.
 // The FilePath field of the returned Error is the empty string.
 // The caller can set this field if to prefix the location
 // with the path to an input file.
-func SimpleError(text string, node *Fail) Error {
-	leaves := LeafFails(node)
+func SimpleError[T Text](text T, node *Fail) Error {
+	return simpleError(ErrorOptions{}, text, node)
+}
 
-	var want string
-	for i, l := range leaves {
-		switch {
-		case i == len(leaves)-1 && i == 1:
-			want += " or "
-		case i == len(leaves)-1 && len(want) > 1:
-			want += ", or "
-		case i > 0:
-			want += ", "
+// SimpleError is like the SimpleError function,
+// but the location of the returned Error is computed
+// with lines terminated according to the Newline.
+func (nl Newline) SimpleError(text string, node *Fail) Error {
+	return Locator{Newline: nl}.SimpleError(text, node)
+}
+
+// SimpleError is like the SimpleError function,
+// but the location of the returned Error is computed
+// with the Locator's options.
+func (l Locator) SimpleError(text string, node *Fail) Error {
+	return ErrorOptions{Locator: l}.SimpleError(text, node)
+}
+
+// ErrorOptions are options for the Error returned by SimpleError.
+// When several fails are at the greatest position,
+// by default their Wants are listed in the order of the tree,
+// which depends on the order of the grammar's rules and choices.
+// The options rank and limit them instead,
+// so that messages are stable and concise.
+type ErrorOptions struct {
+	// Locator computes the location of the Error.
+	Locator Locator
+
+	// PreferNamed lists only the Wants of named rules,
+	// such as "int" of Integer "int" <- [0-9]+,
+	// if any of the fails at the greatest position are of named rules.
+	PreferNamed bool
+
+	// Sort lists the Wants in sorted order without duplicates.
+	Sort bool
+
+	// MaxWants, if positive, is the maximum number of Wants listed.
+	// The rest, after ranking, are counted,
+	// as in: want A, B, or 2 others.
+	MaxWants int
+}
+
+// SimpleError is like the SimpleError function,
+// but the Wants are ranked and limited,
+// and the location computed, according to the options.
+func (o ErrorOptions) SimpleError(text string, node *Fail) Error {
+	return simpleError(o, text, node)
+}
+
+// CommittedError is like SimpleError,
+// but describes the leaf fails at the byte offset pos,
+// that of a committed failure,
+// instead of those with the greatest position.
+// If there are none, it returns the error of SimpleError.
+func (o ErrorOptions) CommittedError(text string, node *Fail, pos int) Error {
+	leaves := leafFailsAt(node, pos)
+	if len(leaves) == 0 {
+		return simpleError(o, text, node)
+	}
+	return leafError(o, text, node, leaves)
+}
+
+// MergedError returns the error of CommittedError,
+// but if the leaf fails with the greatest position are after pos,
+// its message also describes them and their location, as in:
+// want ")"; got ']'; furthest at 3.7: want ";"; got 'x'.
+func (o ErrorOptions) MergedError(text string, node *Fail, pos int) Error {
+	err := o.CommittedError(text, node, pos)
+	if far := simpleError(o, text, node); far.Loc.Byte > err.Loc.Byte {
+		err.Message += fmt.Sprintf("; furthest at %d.%d: %s",
+			far.Loc.Line, far.Loc.Column, far.Message)
+	}
+	return err
+}
+
+// simpleError returns the Error of the SimpleError functions and methods
+// for a text of either string or byte slice type.
+func simpleError[T Text](o ErrorOptions, text T, node *Fail) Error {
+	return leafError(o, text, node, LeafFails(node))
+}
+
+// leafError returns the Error describing the leaf fails of the tree,
+// which are all at the same position.
+func leafError[T Text](o ErrorOptions, text T, node *Fail, leaves []*Fail) Error {
+	first := o.first(leaves)
+	code, rule := first.Code, failRule(node, first)
+	if code == "" {
+		// The Fail was not built by a generated parser.
+		code = OtherFailure
+	}
+	if msg, ok := leafMessage(leaves); ok {
+		return Error{
+			Loc:     locate(o.Locator, text, leaves[0].Pos),
+			Message: msg,
+			Code:    code,
+			Rule:    rule,
 		}
-		want += l.Want
 	}
+	want := wantString(o.wants(leaves))
 
 	got := "EOF"
 	pos := leaves[0].Pos
@@ -38,13 +127,155 @@ func SimpleError(text string, node *Fail) Error {
 		if end > len(text) {
 			end = len(text)
 		}
-		got = "'" + text[pos:end] + "'"
+		got = "'" + string(text[pos:end]) + "'"
 	}
 
 	return Error{
-		Loc:     Location(text, pos),
+		Loc:     locate(o.Locator, text, pos),
 		Message: fmt.Sprintf("want %s; got %s", want, got),
+		Code:    code,
+		Rule:    rule,
+	}
+}
+
+// first returns the leaf fail that determines the Code and Rule
+// of the Error describing the leaf fails:
+// the first whose Want is a complete error message, if any,
+// or else the first whose Want is listed, in the order of the tree.
+func (o ErrorOptions) first(leaves []*Fail) *Fail {
+	for _, l := range leaves {
+		if l.Message {
+			return l
+		}
+	}
+	if o.PreferNamed {
+		for _, l := range leaves {
+			if l.Name != "" {
+				return l
+			}
+		}
+	}
+	return leaves[0]
+}
+
+// failRule returns the name of the rule of a leaf fail of the tree:
+// its own Name, if it is a named rule's fail,
+// or else that of the nearest Fail above it with a Name.
+func failRule(node, leaf *Fail) string {
+	if leaf.Name != "" {
+		return leaf.Name
+	}
+	seen := make(map[*Fail]bool)
+	var find func(*Fail, string) (string, bool)
+	find = func(n *Fail, rule string) (string, bool) {
+		if n == leaf {
+			return rule, true
+		}
+		if seen[n] {
+			return "", false
+		}
+		seen[n] = true
+		if n.Name != "" {
+			rule = n.Name
+		}
+		for _, k := range n.Kids {
+			if r, ok := find(k, rule); ok {
+				return r, true
+			}
+		}
+		return "", false
+	}
+	rule, _ := find(node, "")
+	return rule
+}
+
+// leafMessage returns the Want of the first leaf fail
+// whose Want is a complete error message, if any.
+func leafMessage(leaves []*Fail) (string, bool) {
+	for _, l := range leaves {
+		if l.Message {
+			return l.Want, true
+		}
 	}
+	return "", false
+}
+
+// ErrorMessage returns the error message of a named rule
+// whose error name is a format with one verb, %s, %q, or %v,
+// that failed at the byte offset pos of the text.
+// The verb is filled with the token preceding pos:
+// the last run of non-space bytes before pos,
+// or the empty string if there is none.
+//
+// The text may be a string or a byte slice.
+func ErrorMessage[T Text](format string, text T, pos int) string {
+	end := pos
+	for end > 0 && isSpaceByte(text[end-1]) {
+		end--
+	}
+	start := end
+	for start > 0 && !isSpaceByte(text[start-1]) {
+		start--
+	}
+	return fmt.Sprintf(format, string(text[start:end]))
+}
+
+func isSpaceByte(b byte) bool {
+	switch b {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	}
+	return false
+}
+
+// wants returns the Wants of the leaf fails to list,
+// ranked and limited according to the options.
+func (o ErrorOptions) wants(leaves []*Fail) []string {
+	var named bool
+	for _, l := range leaves {
+		named = named || l.Name != ""
+	}
+	var wants []string
+	for _, l := range leaves {
+		if !o.PreferNamed || !named || l.Name != "" {
+			wants = append(wants, l.Want)
+		}
+	}
+	if o.Sort {
+		sort.Strings(wants)
+		uniq := wants[:0]
+		for i, w := range wants {
+			if i == 0 || w != wants[i-1] {
+				uniq = append(uniq, w)
+			}
+		}
+		wants = uniq
+	}
+	if o.MaxWants > 0 && len(wants) > o.MaxWants {
+		n := len(wants) - o.MaxWants
+		wants = append(wants[:o.MaxWants:o.MaxWants], strconv.Itoa(n)+" other")
+		if n > 1 {
+			wants[o.MaxWants] += "s"
+		}
+	}
+	return wants
+}
+
+// wantString returns an English list of the Wants.
+func wantString(wants []string) string {
+	var want string
+	for i, w := range wants {
+		switch {
+		case i == len(wants)-1 && i == 1:
+			want += " or "
+		case i == len(wants)-1 && len(want) > 1:
+			want += ", or "
+		case i > 0:
+			want += ", "
+		}
+		want += w
+	}
+	return want
 }
 
 // Error implements error, prefixing an error message
@@ -54,10 +285,51 @@ type Error struct {
 	FilePath string
 	// Loc is the location of the error.
 	Loc Loc
+	// End, if non-zero, is the location of the end
+	// of the text of the error, which begins at Loc.
+	End Loc
 	// Message is the error message.
 	Message string
+	// Code is the kind of the error,
+	// or the empty string if it is not a syntax error, such as one of Expect.
+	// It is that of the fail of the first Want in the message,
+	// or of the fail whose Want is the message.
+	Code ErrorCode
+	// Rule is the name of the rule of that fail:
+	// the named rule that failed, for the NamedRule code,
+	// or else the innermost rule containing the expression that failed.
+	// It is the empty string if unknown.
+	Rule string
 }
 
+// An ErrorCode is the kind of a syntax error.
+// ErrorCodes are stable, so applications can handle kinds of errors,
+// for example to suggest a missing semicolon,
+// without matching error messages.
+type ErrorCode string
+
+const (
+	// ExpectedLiteral is a failed literal match, such as of "if".
+	ExpectedLiteral ErrorCode = "expected-literal"
+	// ExpectedClass is a failed character class match, such as of [0-9].
+	ExpectedClass ErrorCode = "expected-class"
+	// ExpectedAny is a failed match of any rune, ., at the end of the input.
+	ExpectedAny ErrorCode = "expected-any"
+	// PredicateFailed is a failed predicate, & or !, or code predicate,
+	// or a difference, A - B, failed because B matched.
+	PredicateFailed ErrorCode = "predicate-failed"
+	// NamedRule is a failed rule with an error name,
+	// such as Integer "int" <- [0-9]+,
+	// or a failed expression with a description, such as [0-9]+ !> "int".
+	NamedRule ErrorCode = "named-rule"
+	// DepthExceeded is a rule that was not parsed,
+	// because it would exceed the maximum nesting depth.
+	DepthExceeded ErrorCode = "depth-exceeded"
+	// OtherFailure is any other failure,
+	// such as the error of the delegate parser of a %delegate region.
+	OtherFailure ErrorCode = "other"
+)
+
 func (err Error) Error() string {
 	return fmt.Sprintf("%s:%d.%d: %s",
 		err.FilePath, err.Loc.Line, err.Loc.Column, err.Message)
@@ -92,6 +364,30 @@ func LeafFails(node *Fail) []*Fail {
 	return fails
 }
 
+// leafFailsAt returns all leaf fails in the tree with the Pos.
+func leafFailsAt(node *Fail, pos int) []*Fail {
+	var fails []*Fail
+	seen := make(map[*Fail]bool)
+	var walk func(*Fail)
+	walk = func(n *Fail) {
+		if seen[n] {
+			return
+		}
+		seen[n] = true
+		if len(n.Kids) == 0 {
+			if n.Pos == pos && n.Want != "" {
+				fails = append(fails, n)
+			}
+			return
+		}
+		for _, k := range n.Kids {
+			walk(k)
+		}
+	}
+	walk(node)
+	return fails
+}
+
 // DedupFails removes duplicate fail branches from the tree,
 // keeping only the first occurrence of each.
 // This is useful for example before printing the Fail tree,
@@ -130,3 +130,262 @@ func TestSimpleError_3(t *testing.T) {
 		t.Errorf("err.Error()=%q, want %q", err.Error(), want)
 	}
 }
+
+func TestSimpleErrorBytes(t *testing.T) {
+	text := []byte("123456789\nabcdefghijklmnop")
+	root := &Fail{
+		Kids: []*Fail{
+			&Fail{Pos: 10, Want: "A"},
+			&Fail{Pos: 10, Want: "B"},
+		},
+	}
+	err := SimpleError(text, root)
+	want := ":2.1: want A or B; got 'abcdefghij'"
+	if err.Error() != want {
+		t.Errorf("err.Error()=%q, want %q", err.Error(), want)
+	}
+}
+
+func TestSimpleErrorMessage(t *testing.T) {
+	text := "x = \ny ="
+	root := &Fail{
+		Kids: []*Fail{
+			&Fail{Pos: 8, Want: "[0-9]"},
+			&Fail{Name: "Expr", Pos: 8, Want: `expected an expression after "="`, Message: true},
+		},
+	}
+	err := SimpleError(text, root)
+	want := `:2.4: expected an expression after "="`
+	if err.Error() != want {
+		t.Errorf("err.Error()=%q, want %q", err.Error(), want)
+	}
+}
+
+func TestCommittedError(t *testing.T) {
+	text := "if x;"
+	root := &Fail{
+		Kids: []*Fail{
+			&Fail{Name: "Cond", Pos: 3, Want: "condition"},
+			&Fail{Pos: 4, Want: `"!"`},
+		},
+	}
+	tests := []struct {
+		pos          int
+		commit, merg string
+	}{
+		{
+			pos:    3,
+			commit: ":1.4: want condition; got 'x;'",
+			merg:   `:1.4: want condition; got 'x;'; furthest at 1.5: want "!"; got ';'`,
+		},
+		{
+			pos:    4,
+			commit: `:1.5: want "!"; got ';'`,
+			merg:   `:1.5: want "!"; got ';'`,
+		},
+		{
+			// Without fails at pos, the greatest position is reported.
+			pos:    2,
+			commit: `:1.5: want "!"; got ';'`,
+			merg:   `:1.5: want "!"; got ';'`,
+		},
+	}
+	for _, test := range tests {
+		if err := (ErrorOptions{}).CommittedError(text, root, test.pos); err.Error() != test.commit {
+			t.Errorf("CommittedError(_, _, %d)=%q, want %q", test.pos, err.Error(), test.commit)
+		}
+		if err := (ErrorOptions{}).MergedError(text, root, test.pos); err.Error() != test.merg {
+			t.Errorf("MergedError(_, _, %d)=%q, want %q", test.pos, err.Error(), test.merg)
+		}
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	tests := []struct {
+		format, text string
+		pos          int
+		want         string
+	}{
+		{format: "after %q", text: "x = 1", pos: 4, want: `after "="`},
+		{format: "after %q", text: "x =\t 1", pos: 5, want: `after "="`},
+		{format: "after %s", text: "x = f(1+", pos: 8, want: `after f(1+`},
+		{format: "after %q", text: "x =\r\n1", pos: 5, want: `after "="`},
+		{format: "after %q", text: " \n", pos: 2, want: `after ""`},
+		{format: "after %q", text: "", pos: 0, want: `after ""`},
+		{format: "100%% after %v", text: "a b", pos: 2, want: `100% after a`},
+	}
+	for _, test := range tests {
+		if got := ErrorMessage(test.format, test.text, test.pos); got != test.want {
+			t.Errorf("ErrorMessage(%q, %q, %d)=%q, want %q",
+				test.format, test.text, test.pos, got, test.want)
+		}
+		if got := ErrorMessage(test.format, []byte(test.text), test.pos); got != test.want {
+			t.Errorf("ErrorMessage(%q, []byte(%q), %d)=%q, want %q",
+				test.format, test.text, test.pos, got, test.want)
+		}
+	}
+}
+
+func TestErrorOptions(t *testing.T) {
+	text := "123456789\nabcdefg"
+	root := &Fail{
+		Kids: []*Fail{
+			&Fail{Pos: 10, Want: `"x"`},
+			&Fail{Name: "Num", Pos: 10, Want: "number"},
+			&Fail{Name: "B", Pos: 8, Kids: []*Fail{&Fail{Pos: 10, Want: "[a-z]"}}},
+			&Fail{Name: "Id", Pos: 10, Want: "identifier"},
+			&Fail{Pos: 10, Want: `"x"`},
+		},
+	}
+	tests := []struct {
+		opts ErrorOptions
+		want string
+	}{
+		{
+			opts: ErrorOptions{},
+			want: `:2.1: want "x", number, [a-z], identifier, or "x"; got 'abcdefg'`,
+		},
+		{
+			opts: ErrorOptions{PreferNamed: true},
+			want: `:2.1: want number or identifier; got 'abcdefg'`,
+		},
+		{
+			opts: ErrorOptions{Sort: true},
+			want: `:2.1: want "x", [a-z], identifier, or number; got 'abcdefg'`,
+		},
+		{
+			opts: ErrorOptions{PreferNamed: true, Sort: true},
+			want: `:2.1: want identifier or number; got 'abcdefg'`,
+		},
+		{
+			opts: ErrorOptions{Sort: true, MaxWants: 2},
+			want: `:2.1: want "x", [a-z], or 2 others; got 'abcdefg'`,
+		},
+		{
+			opts: ErrorOptions{Sort: true, MaxWants: 3},
+			want: `:2.1: want "x", [a-z], identifier, or 1 other; got 'abcdefg'`,
+		},
+		{
+			opts: ErrorOptions{Sort: true, MaxWants: 4},
+			want: `:2.1: want "x", [a-z], identifier, or number; got 'abcdefg'`,
+		},
+		{
+			opts: ErrorOptions{Locator: Locator{ByteColumns: true}, PreferNamed: true, MaxWants: 1},
+			want: `:2.1: want number or 1 other; got 'abcdefg'`,
+		},
+	}
+	for _, test := range tests {
+		if got := test.opts.SimpleError(text, root).Error(); got != test.want {
+			t.Errorf("%+v.SimpleError(…)=%q, want %q", test.opts, got, test.want)
+		}
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		name string
+		root *Fail
+		opts ErrorOptions
+		code ErrorCode
+		rule string
+	}{
+		{
+			name: "literal",
+			root: &Fail{Name: "Stmt", Kids: []*Fail{
+				{Pos: 2, Want: `"x"`, Code: ExpectedLiteral},
+				{Name: "Semi", Pos: 3, Kids: []*Fail{{Pos: 3, Want: `";"`, Code: ExpectedLiteral}}},
+			}},
+			code: ExpectedLiteral,
+			rule: "Semi",
+		},
+		{
+			name: "class",
+			root: &Fail{Name: "Num", Kids: []*Fail{{Pos: 3, Want: "[0-9]", Code: ExpectedClass}, {Pos: 3, Want: `"-"`, Code: ExpectedLiteral}}},
+			code: ExpectedClass,
+			rule: "Num",
+		},
+		{
+			name: "any",
+			root: &Fail{Name: "A", Kids: []*Fail{{Pos: 3, Want: ".", Code: ExpectedAny}}},
+			code: ExpectedAny,
+			rule: "A",
+		},
+		{
+			name: "predicate",
+			root: &Fail{Name: "A", Kids: []*Fail{{Name: "B", Pos: 0, Kids: []*Fail{{Pos: 3, Want: `!"x"`, Code: PredicateFailed}}}}},
+			code: PredicateFailed,
+			rule: "B",
+		},
+		{
+			name: "code predicate",
+			root: &Fail{Name: "A", Kids: []*Fail{{Pos: 3, Want: "&{ok}", Code: PredicateFailed}}},
+			code: PredicateFailed,
+			rule: "A",
+		},
+		{
+			name: "named rule",
+			root: &Fail{Name: "A", Kids: []*Fail{{Name: "Int", Pos: 3, Want: "int", Code: NamedRule}}},
+			code: NamedRule,
+			rule: "Int",
+		},
+		{
+			name: "preferred named rule",
+			root: &Fail{Name: "A", Kids: []*Fail{{Pos: 3, Want: `"-"`, Code: ExpectedLiteral}, {Name: "Int", Pos: 3, Want: "int", Code: NamedRule}}},
+			opts: ErrorOptions{PreferNamed: true},
+			code: NamedRule,
+			rule: "Int",
+		},
+		{
+			name: "message",
+			root: &Fail{Name: "A", Kids: []*Fail{{Pos: 3, Want: `"-"`, Code: ExpectedLiteral}, {Name: "Expr", Pos: 3, Want: "no expr", Message: true, Code: NamedRule}}},
+			code: NamedRule,
+			rule: "Expr",
+		},
+		{
+			name: "depth exceeded",
+			root: &Fail{Name: "A", Kids: []*Fail{{Name: "B", Pos: 3, Want: MaxDepthExceeded, Code: DepthExceeded}}},
+			code: DepthExceeded,
+			rule: "B",
+		},
+		{
+			name: "delegate",
+			root: &Fail{Name: "A", Kids: []*Fail{{Pos: 3, Want: "bad JSON", Code: OtherFailure}}},
+			code: OtherFailure,
+			rule: "A",
+		},
+		{
+			name: "no rule",
+			root: &Fail{Kids: []*Fail{{Pos: 3, Want: `"x"`, Code: ExpectedLiteral}}},
+			code: ExpectedLiteral,
+			rule: "",
+		},
+		{
+			name: "no code",
+			root: &Fail{Name: "A", Kids: []*Fail{{Pos: 3, Want: `"x"`}}},
+			code: OtherFailure,
+			rule: "A",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.opts.SimpleError("0123456789", test.root)
+			if err.Code != test.code || err.Rule != test.rule {
+				t.Errorf("SimpleError(_, _)=%q with code %q, rule %q, want code %q, rule %q",
+					err.Error(), err.Code, err.Rule, test.code, test.rule)
+			}
+		})
+	}
+
+	root := &Fail{Name: "If", Kids: []*Fail{
+		{Name: "Cond", Pos: 3, Want: "condition", Code: NamedRule},
+		{Pos: 4, Want: `"!"`, Code: ExpectedLiteral},
+	}}
+	err := (ErrorOptions{}).CommittedError("if x;", root, 3)
+	if err.Code != NamedRule || err.Rule != "Cond" {
+		t.Errorf("CommittedError(_, _, 3) has code %q, rule %q, want %q, \"Cond\"", err.Code, err.Rule, NamedRule)
+	}
+	err = (ErrorOptions{}).MergedError("if x;", root, 3)
+	if err.Code != NamedRule || err.Rule != "Cond" {
+		t.Errorf("MergedError(_, _, 3) has code %q, rule %q, want %q, \"Cond\"", err.Code, err.Rule, NamedRule)
+	}
+}
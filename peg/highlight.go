@@ -0,0 +1,133 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// HighlightOptions are options for HighlightError.
+type HighlightOptions struct {
+	// Newline specifies the line terminators of the text.
+	Newline Newline
+
+	// Context is the number of lines
+	// preceding the line of the failure to print.
+	Context int
+
+	// NoColor disables ANSI color escapes.
+	// The failure is then marked only by the caret line.
+	NoColor bool
+}
+
+const (
+	ansiParsed = "\x1b[32m"   // green
+	ansiFail   = "\x1b[7;31m" // reverse red
+	ansiRest   = "\x1b[2m"    // dim
+	ansiWant   = "\x1b[1m"    // bold
+	ansiReset  = "\x1b[0m"
+)
+
+// HighlightError writes the lines of the text
+// leading up to and containing the furthest failure of the Fail tree,
+// each preceded by its line number.
+// The successfully parsed text is colored green,
+// the rune at the failure is colored reverse red,
+// and the remainder of the failure line is dimmed.
+// The lines are followed by a line with a caret under the failure
+// and the Wants of the fails at the furthest failure position,
+// or the error message of a named rule that failed there.
+func HighlightError(text string, f *Fail, w io.Writer, opts HighlightOptions) error {
+	leaves := LeafFails(f)
+	if len(leaves) == 0 {
+		return nil
+	}
+	pos := leaves[0].Pos
+	if pos > len(text) {
+		pos = len(text)
+	}
+	color := func(code, s string) string {
+		if opts.NoColor || s == "" {
+			return s
+		}
+		return code + s + ansiReset
+	}
+	nl := opts.Newline
+	line := nl.Location(text, pos).Line
+	gutter := len(strconv.Itoa(line))
+
+	// The preceding lines, last first.
+	var context []string
+	start := nl.lineStart(text, pos)
+	for i := 0; i < opts.Context && start > 0; i++ {
+		_, n := utf8.DecodeLastRuneInString(text[:start])
+		end := start - n
+		if nl != LF && end > 0 && text[end] == '\n' && text[end-1] == '\r' {
+			end--
+		}
+		start = nl.lineStart(text, end)
+		context = append(context, text[start:end])
+	}
+	var s strings.Builder
+	for i := len(context) - 1; i >= 0; i-- {
+		fmt.Fprintf(&s, "%*d | %s\n", gutter, line-i-1, color(ansiParsed, context[i]))
+	}
+
+	start = nl.lineStart(text, pos)
+	end := nl.lineEnd(text, pos)
+	// A failure at the end of the line is marked by a space.
+	at, rest := " ", pos
+	if pos < end {
+		_, n := utf8.DecodeRuneInString(text[pos:])
+		at, rest = text[pos:pos+n], pos+n
+	}
+	fmt.Fprintf(&s, "%*d | %s%s%s\n", gutter, line,
+		color(ansiParsed, text[start:pos]),
+		color(ansiFail, at),
+		color(ansiRest, text[rest:end]))
+
+	fmt.Fprintf(&s, "%*s | ", gutter, "")
+	for _, r := range text[start:pos] {
+		if r == '\t' {
+			s.WriteRune('\t')
+		} else {
+			s.WriteRune(' ')
+		}
+	}
+	if msg, ok := leafMessage(leaves); ok {
+		fmt.Fprintf(&s, "^ %s\n", color(ansiWant, msg))
+	} else {
+		fmt.Fprintf(&s, "^ want %s\n", color(ansiWant, wantString(ErrorOptions{}.wants(leaves))))
+	}
+	_, err := io.WriteString(w, s.String())
+	return err
+}
+
+// lineStart returns the byte offset of the start of the line
+// containing the byte offset.
+func (nl Newline) lineStart(text string, byte int) int {
+	i := strings.LastIndexAny(text[:byte], nl.terminators())
+	if i < 0 {
+		return 0
+	}
+	_, n := utf8.DecodeRuneInString(text[i:])
+	return i + n
+}
+
+// lineEnd returns the byte offset of the terminator of the line
+// containing the byte offset, or the length of the text
+// if the line is not terminated.
+func (nl Newline) lineEnd(text string, byte int) int {
+	if i := strings.IndexAny(text[byte:], nl.terminators()); i >= 0 {
+		return byte + i
+	}
+	return len(text)
+}
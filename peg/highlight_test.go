@@ -0,0 +1,92 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlightError(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		fail *Fail
+		opts HighlightOptions
+		want string
+	}{
+		{
+			name: "first line",
+			text: "1+x",
+			fail: &Fail{Kids: []*Fail{{Pos: 2, Want: "[0-9]"}}},
+			opts: HighlightOptions{NoColor: true},
+			want: "1 | 1+x\n" +
+				"  |   ^ want [0-9]\n",
+		},
+		{
+			name: "context lines",
+			text: "a\nb\nc\n\td x\ne",
+			fail: &Fail{Kids: []*Fail{
+				{Pos: 7, Want: `"("`},
+				{Pos: 9, Want: `"x"`},
+				{Pos: 9, Want: `"y"`},
+			}},
+			opts: HighlightOptions{NoColor: true, Context: 2},
+			want: "2 | b\n" +
+				"3 | c\n" +
+				"4 | \td x\n" +
+				"  | \t  ^ want \"x\" or \"y\"\n",
+		},
+		{
+			name: "context at the start of the text",
+			text: "a\r\nb",
+			fail: &Fail{Kids: []*Fail{{Pos: 4, Want: `"c"`}}},
+			opts: HighlightOptions{Newline: CRLF, NoColor: true, Context: 5},
+			want: "1 | a\n" +
+				"2 | b \n" +
+				"  |  ^ want \"c\"\n",
+		},
+		{
+			name: "unicode line separators",
+			text: "a\u2028b\u0085c\u2029d x",
+			fail: &Fail{Kids: []*Fail{{Pos: 13, Want: `"y"`}}},
+			opts: HighlightOptions{Newline: Unicode, NoColor: true, Context: 2},
+			want: "2 | b\n" +
+				"3 | c\n" +
+				"4 | d x\n" +
+				"  |   ^ want \"y\"\n",
+		},
+		{
+			name: "colors",
+			text: "ab\ncd",
+			fail: &Fail{Kids: []*Fail{{Pos: 4, Want: `"x"`}}},
+			opts: HighlightOptions{Context: 1},
+			want: "1 | \x1b[32mab\x1b[0m\n" +
+				"2 | \x1b[32mc\x1b[0m\x1b[7;31md\x1b[0m\n" +
+				"  |  ^ want \x1b[1m\"x\"\x1b[0m\n",
+		},
+		{
+			name: "failure at end of line",
+			text: "ab\ncd",
+			fail: &Fail{Kids: []*Fail{{Pos: 2, Want: `"x"`}}},
+			opts: HighlightOptions{},
+			want: "1 | \x1b[32mab\x1b[0m\x1b[7;31m \x1b[0m\n" +
+				"  |   ^ want \x1b[1m\"x\"\x1b[0m\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var s strings.Builder
+			if err := HighlightError(test.text, test.fail, &s, test.opts); err != nil {
+				t.Fatalf("HighlightError(%q, _, _, %+v)=%v, want nil", test.text, test.opts, err)
+			}
+			if got := s.String(); got != test.want {
+				t.Errorf("HighlightError(%q, _, _, %+v) wrote\n%q\nwant\n%q", test.text, test.opts, got, test.want)
+			}
+		})
+	}
+}
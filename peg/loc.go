@@ -6,7 +6,10 @@
 
 package peg
 
-import "unicode/utf8"
+import (
+	"strings"
+	"unicode/utf8"
+)
 
 // A Loc is a location in the input text.
 type Loc struct {
@@ -16,20 +19,243 @@ type Loc struct {
 	Column int
 }
 
+// A Newline specifies which runes terminate a line
+// when computing the Line and Column of a Loc.
+type Newline int
+
+const (
+	// LF indicates that only \n terminates a line.
+	LF Newline = iota
+
+	// CRLF indicates that \r\n, a lone \r, and a lone \n
+	// each terminate a line.
+	// A \r\n counts as a single line terminator.
+	CRLF
+
+	// JS indicates that, in addition to the terminators of CRLF,
+	// \u2028 (line separator) and \u2029 (paragraph separator)
+	// each terminate a line, as in JavaScript.
+	JS
+
+	// Unicode indicates that, in addition to the terminators of JS,
+	// \u0085 (next line) terminates a line.
+	Unicode
+)
+
+// terminates returns whether the rune terminates a line.
+// The \n of a \r\n is part of the terminator begun by the \r.
+func (nl Newline) terminates(r rune) bool {
+	switch r {
+	case '\n':
+		return true
+	case '\r':
+		return nl != LF
+	case '\u2028', '\u2029':
+		return nl == JS || nl == Unicode
+	case '\u0085':
+		return nl == Unicode
+	}
+	return false
+}
+
+// terminators returns the runes that terminate a line.
+func (nl Newline) terminators() string {
+	switch nl {
+	case CRLF:
+		return "\r\n"
+	case JS:
+		return "\r\n\u2028\u2029"
+	case Unicode:
+		return "\r\n\u0085\u2028\u2029"
+	}
+	return "\n"
+}
+
+// Location returns the Loc at the corresponding byte offset in the text,
+// where only \n terminates a line.
+func Location[T Text](text T, byte int) Loc {
+	return locate(Locator{}, text, byte)
+}
+
+// Location returns the Loc at the corresponding byte offset in the text,
+// where lines are terminated according to the Newline.
+func (nl Newline) Location(text string, byte int) Loc {
+	return Locator{Newline: nl}.Location(text, byte)
+}
+
+// A Locator computes the Locs of byte offsets in a text,
+// with its options for how lines and columns are counted.
+// The zero Locator terminates lines with only \n,
+// and counts each rune, including a tab, as one column,
+// like the Location function.
+type Locator struct {
+	// Newline specifies the line terminators.
+	Newline Newline
+
+	// TabWidth, if greater than 1, is the distance between tab stops.
+	// A tab advances the Column to the next tab stop,
+	// so Columns line up with a text editor
+	// that expands tabs to TabWidth spaces.
+	TabWidth int
+
+	// ByteColumns indicates that the Column counts bytes instead of runes.
+	ByteColumns bool
+}
+
 // Location returns the Loc at the corresponding byte offset in the text.
-func Location(text string, byte int) Loc {
-	var loc Loc
-	loc.Line = 1
-	loc.Column = 1
+func (l Locator) Location(text string, byte int) Loc {
+	return locate(l, text, byte)
+}
+
+// locate returns the Loc at the corresponding byte offset in the text,
+// computed with the Locator's options.
+func locate[T Text](l Locator, text T, byte int) Loc {
+	loc := Loc{Line: 1}
+	start := 0
 	for byte > loc.Byte {
-		r, w := utf8.DecodeRuneInString(text[loc.Byte:])
+		r, w := DecodeRuneInString(text[loc.Byte:])
 		loc.Byte += w
 		loc.Rune++
-		loc.Column++
-		if r == '\n' {
+		if l.Newline.terminates(r) {
+			if r == '\r' && byte > loc.Byte && loc.Byte < len(text) && text[loc.Byte] == '\n' {
+				loc.Byte++
+				loc.Rune++
+			}
 			loc.Line++
-			loc.Column = 1
+			start = loc.Byte
 		}
 	}
+	loc.Column = column(l, text[start:loc.Byte])
 	return loc
 }
+
+// column returns the Column following the text
+// from the beginning of a line.
+func column[T Text](l Locator, line T) int {
+	col := 1
+	for i := 0; i < len(line); {
+		r, w := DecodeRuneInString(line[i:])
+		i += w
+		switch {
+		case r == '\t' && l.TabWidth > 1:
+			col += l.TabWidth - (col-1)%l.TabWidth
+		case l.ByteColumns:
+			col += w
+		default:
+			col++
+		}
+	}
+	return col
+}
+
+// A LineIndex computes the Locs of byte offsets in a text
+// in time logarithmic in the number of lines,
+// instead of linear in the offset, as Location does.
+type LineIndex struct {
+	loc  Locator
+	text string
+	// starts are the byte offsets of the beginning of each line,
+	// and runes are the rune offsets of the same.
+	starts []int
+	runes  []int
+}
+
+// NewLineIndex returns a LineIndex of the text,
+// where only \n terminates a line.
+func NewLineIndex(text string) *LineIndex {
+	return LF.NewLineIndex(text)
+}
+
+// NewLineIndex returns a LineIndex of the text,
+// where lines are terminated according to the Newline.
+func (nl Newline) NewLineIndex(text string) *LineIndex {
+	return Locator{Newline: nl}.NewLineIndex(text)
+}
+
+// NewLineIndex returns a LineIndex of the text,
+// computing Locs with the Locator's options.
+func (l Locator) NewLineIndex(text string) *LineIndex {
+	nl := l.Newline
+	x := &LineIndex{loc: l, text: text, starts: []int{0}, runes: []int{0}}
+	var r int
+	for i := 0; i < len(text); {
+		c, w := utf8.DecodeRuneInString(text[i:])
+		i += w
+		r++
+		if !nl.terminates(c) {
+			continue
+		}
+		if c == '\r' && strings.HasPrefix(text[i:], "\n") {
+			i++
+			r++
+		}
+		x.starts = append(x.starts, i)
+		x.runes = append(x.runes, r)
+	}
+	return x
+}
+
+// Location returns the Loc at the corresponding byte offset in the text.
+// It is the same as the Location method of the Locator
+// of the LineIndex.
+func (x *LineIndex) Location(byte int) Loc {
+	// Find the last line starting at or before byte.
+	lo, hi := 0, len(x.starts)
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		if x.starts[mid] <= byte {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	start := x.starts[lo]
+	if x.loc.Newline != LF && byte > start && byte < len(x.text) &&
+		x.text[byte-1] == '\r' && x.text[byte] == '\n' {
+		// The \n of a \r\n is at the beginning of the next line.
+		return Loc{
+			Byte:   byte,
+			Rune:   x.runes[lo] + utf8.RuneCountInString(x.text[start:byte]),
+			Line:   lo + 2,
+			Column: 1,
+		}
+	}
+	return Loc{
+		Byte:   byte,
+		Rune:   x.runes[lo] + utf8.RuneCountInString(x.text[start:byte]),
+		Line:   lo + 1,
+		Column: column(x.loc, x.text[start:byte]),
+	}
+}
+
+// Locate returns the Loc of the Pos of each Fail in the tree,
+// where only \n terminates a line.
+func Locate(text string, f *Fail) map[*Fail]Loc {
+	return LF.Locate(text, f)
+}
+
+// Locate returns the Loc of the Pos of each Fail in the tree,
+// where lines are terminated according to the Newline.
+func (nl Newline) Locate(text string, f *Fail) map[*Fail]Loc {
+	return Locator{Newline: nl}.Locate(text, f)
+}
+
+// Locate returns the Loc of the Pos of each Fail in the tree,
+// computed with the Locator's options.
+// It indexes the lines of the text once for the whole tree.
+func (l Locator) Locate(text string, f *Fail) map[*Fail]Loc {
+	x := l.NewLineIndex(text)
+	locs := make(map[*Fail]Loc)
+	var walk func(*Fail)
+	walk = func(f *Fail) {
+		if _, ok := locs[f]; ok {
+			return
+		}
+		locs[f] = x.Location(f.Pos)
+		for _, k := range f.Kids {
+			walk(k)
+		}
+	}
+	walk(f)
+	return locs
+}
@@ -9,6 +9,7 @@ package peg
 import (
 	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestLocation(t *testing.T) {
@@ -52,6 +53,14 @@ func TestLocation(t *testing.T) {
 			in:   "☺☺\n☺*☹☹☹",
 			want: Loc{Byte: 3*len("☺") + 1, Rune: 4, Line: 2, Column: 2},
 		},
+		{
+			in:   "ab\r\nc*",
+			want: Loc{Byte: 5, Rune: 5, Line: 2, Column: 2},
+		},
+		{
+			in:   "ab\rc*",
+			want: Loc{Byte: 4, Rune: 4, Line: 1, Column: 5},
+		},
 	}
 	for _, test := range tests {
 		b := strings.Index(test.in, "*")
@@ -62,5 +71,258 @@ func TestLocation(t *testing.T) {
 		if got != test.want {
 			t.Errorf("Location(%q, %d)=%v, want %v", test.in, b, got, test.want)
 		}
+		if got := Location([]byte(test.in), b); got != test.want {
+			t.Errorf("Location([]byte(%q), %d)=%v, want %v", test.in, b, got, test.want)
+		}
+	}
+}
+
+func TestCRLFLocation(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Loc
+	}{
+		{
+			in:   "ab\r\n*",
+			want: Loc{Byte: 4, Rune: 4, Line: 2, Column: 1},
+		},
+		{
+			in:   "ab\r*",
+			want: Loc{Byte: 3, Rune: 3, Line: 2, Column: 1},
+		},
+		{
+			in:   "ab\n*",
+			want: Loc{Byte: 3, Rune: 3, Line: 2, Column: 1},
+		},
+		{
+			in:   "ab\r\n\r\nxyz*",
+			want: Loc{Byte: 9, Rune: 9, Line: 3, Column: 4},
+		},
+		{
+			in:   "ab\n\r\r\n\n*",
+			want: Loc{Byte: 7, Rune: 7, Line: 5, Column: 1},
+		},
+		{
+			in:   "ab*\r\n",
+			want: Loc{Byte: 2, Rune: 2, Line: 1, Column: 3},
+		},
+		{
+			in:   "☺\r\n☺*",
+			want: Loc{Byte: 2*len("☺") + 2, Rune: 4, Line: 2, Column: 2},
+		},
+	}
+	for _, test := range tests {
+		b := strings.Index(test.in, "*")
+		if b < 0 {
+			panic("no *")
+		}
+		got := CRLF.Location(test.in, b)
+		if got != test.want {
+			t.Errorf("CRLF.Location(%q, %d)=%v, want %v", test.in, b, got, test.want)
+		}
+	}
+}
+
+func TestNewlineLocation(t *testing.T) {
+	tests := []struct {
+		in                    string
+		lf, crlf, js, unicode Loc
+	}{
+		{
+			in:      "ab\r*",
+			lf:      Loc{Byte: 3, Rune: 3, Line: 1, Column: 4},
+			crlf:    Loc{Byte: 3, Rune: 3, Line: 2, Column: 1},
+			js:      Loc{Byte: 3, Rune: 3, Line: 2, Column: 1},
+			unicode: Loc{Byte: 3, Rune: 3, Line: 2, Column: 1},
+		},
+		{
+			in:      "ab\r\n*",
+			lf:      Loc{Byte: 4, Rune: 4, Line: 2, Column: 1},
+			crlf:    Loc{Byte: 4, Rune: 4, Line: 2, Column: 1},
+			js:      Loc{Byte: 4, Rune: 4, Line: 2, Column: 1},
+			unicode: Loc{Byte: 4, Rune: 4, Line: 2, Column: 1},
+		},
+		{
+			in:      "ab\u0085*",
+			lf:      Loc{Byte: 4, Rune: 3, Line: 1, Column: 4},
+			crlf:    Loc{Byte: 4, Rune: 3, Line: 1, Column: 4},
+			js:      Loc{Byte: 4, Rune: 3, Line: 1, Column: 4},
+			unicode: Loc{Byte: 4, Rune: 3, Line: 2, Column: 1},
+		},
+		{
+			in:      "ab\u2028*",
+			lf:      Loc{Byte: 5, Rune: 3, Line: 1, Column: 4},
+			crlf:    Loc{Byte: 5, Rune: 3, Line: 1, Column: 4},
+			js:      Loc{Byte: 5, Rune: 3, Line: 2, Column: 1},
+			unicode: Loc{Byte: 5, Rune: 3, Line: 2, Column: 1},
+		},
+		{
+			in:      "ab\u2029c*",
+			lf:      Loc{Byte: 6, Rune: 4, Line: 1, Column: 5},
+			crlf:    Loc{Byte: 6, Rune: 4, Line: 1, Column: 5},
+			js:      Loc{Byte: 6, Rune: 4, Line: 2, Column: 2},
+			unicode: Loc{Byte: 6, Rune: 4, Line: 2, Column: 2},
+		},
+		{
+			in:      "\u2028\u0085\r\n\r*",
+			lf:      Loc{Byte: 8, Rune: 5, Line: 2, Column: 2},
+			crlf:    Loc{Byte: 8, Rune: 5, Line: 3, Column: 1},
+			js:      Loc{Byte: 8, Rune: 5, Line: 4, Column: 1},
+			unicode: Loc{Byte: 8, Rune: 5, Line: 5, Column: 1},
+		},
+	}
+	for _, test := range tests {
+		b := strings.Index(test.in, "*")
+		if b < 0 {
+			panic("no *")
+		}
+		for nl, want := range []Loc{test.lf, test.crlf, test.js, test.unicode} {
+			if got := Newline(nl).Location(test.in, b); got != want {
+				t.Errorf("%d.Location(%q, %d)=%v, want %v", nl, test.in, b, got, want)
+			}
+		}
+	}
+}
+
+func TestLocatorLocation(t *testing.T) {
+	tests := []struct {
+		loc  Locator
+		in   string
+		want Loc
+	}{
+		{
+			loc:  Locator{},
+			in:   "\t\t*",
+			want: Loc{Byte: 2, Rune: 2, Line: 1, Column: 3},
+		},
+		{
+			loc:  Locator{TabWidth: 8},
+			in:   "\t\t*",
+			want: Loc{Byte: 2, Rune: 2, Line: 1, Column: 17},
+		},
+		{
+			loc:  Locator{TabWidth: 4},
+			in:   "ab\tc\t*",
+			want: Loc{Byte: 5, Rune: 5, Line: 1, Column: 9},
+		},
+		{
+			loc:  Locator{TabWidth: 4},
+			in:   "abcd\t*",
+			want: Loc{Byte: 5, Rune: 5, Line: 1, Column: 9},
+		},
+		{
+			loc:  Locator{TabWidth: 4},
+			in:   "\t\n\tx*",
+			want: Loc{Byte: 4, Rune: 4, Line: 2, Column: 6},
+		},
+		{
+			loc:  Locator{ByteColumns: true},
+			in:   "☺☺*",
+			want: Loc{Byte: 2 * len("☺"), Rune: 2, Line: 1, Column: 2*len("☺") + 1},
+		},
+		{
+			loc:  Locator{TabWidth: 4, ByteColumns: true},
+			in:   "☺\t*",
+			want: Loc{Byte: len("☺") + 1, Rune: 2, Line: 1, Column: 5},
+		},
+		{
+			loc:  Locator{Newline: CRLF, TabWidth: 4},
+			in:   "\t\r\t*",
+			want: Loc{Byte: 3, Rune: 3, Line: 2, Column: 5},
+		},
+	}
+	for _, test := range tests {
+		b := strings.Index(test.in, "*")
+		if b < 0 {
+			panic("no *")
+		}
+		got := test.loc.Location(test.in, b)
+		if got != test.want {
+			t.Errorf("%+v.Location(%q, %d)=%v, want %v", test.loc, test.in, b, got, test.want)
+		}
+		if got := test.loc.NewLineIndex(test.in).Location(b); got != test.want {
+			t.Errorf("%+v.NewLineIndex(%q).Location(%d)=%v, want %v", test.loc, test.in, b, got, test.want)
+		}
+	}
+}
+
+func TestLocatorSimpleError(t *testing.T) {
+	const text = "a\n\tbc"
+	f := &Fail{Name: "A", Kids: []*Fail{{Pos: 4, Want: `"x"`}}}
+	err := Locator{TabWidth: 8}.SimpleError(text, f)
+	if got, want := err.Error(), `:2.10: want "x"; got 'c'`; got != want {
+		t.Errorf("Locator{TabWidth: 8}.SimpleError(%q, _)=%q, want %q", text, got, want)
+	}
+}
+
+func TestLineIndex(t *testing.T) {
+	texts := []string{
+		"",
+		"abc",
+		"ab\nabc\nxyz",
+		"\n\n\n",
+		"☺☺\n☺☹☹☹\n",
+		"ab\r\nc\rd\n\r\r\n\n",
+		"\r\n\r\n☺\r\n",
+		"ab\xffc\n\xff",
+		"a\u2028b\u2029c\u0085d\r\ne",
+		"\u0085\r\u2028\n",
+	}
+	for _, nl := range []Newline{LF, CRLF, JS, Unicode} {
+		for _, text := range texts {
+			x := nl.NewLineIndex(text)
+			for b := 0; b <= len(text); b++ {
+				if b < len(text) && !utf8.RuneStart(text[b]) {
+					continue
+				}
+				got := x.Location(b)
+				want := nl.Location(text, b)
+				if got != want {
+					t.Errorf("%d: NewLineIndex(%q).Location(%d)=%v, want %v",
+						nl, text, b, got, want)
+				}
+			}
+		}
+	}
+	texts = append(texts, "\ta\tb\n\t\t☺\tc", "☺\t\r\n\t")
+	for _, l := range []Locator{{TabWidth: 4}, {Newline: CRLF, TabWidth: 8, ByteColumns: true}} {
+		for _, text := range texts {
+			x := l.NewLineIndex(text)
+			for b := 0; b <= len(text); b++ {
+				if b < len(text) && !utf8.RuneStart(text[b]) {
+					continue
+				}
+				if got, want := x.Location(b), l.Location(text, b); got != want {
+					t.Errorf("%+v.NewLineIndex(%q).Location(%d)=%v, want %v",
+						l, text, b, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestLocate(t *testing.T) {
+	const text = "ab\ncd\r\nef"
+	shared := &Fail{Pos: 8, Want: "x"}
+	f := &Fail{
+		Name: "A",
+		Pos:  0,
+		Kids: []*Fail{
+			{Name: "B", Pos: 3, Kids: []*Fail{shared}},
+			{Pos: 4, Want: "y"},
+			shared,
+		},
+	}
+	locs := CRLF.Locate(text, f)
+	if len(locs) != 4 {
+		t.Errorf("len(Locate(…))=%d, want 4", len(locs))
+	}
+	for _, g := range []*Fail{f, f.Kids[0], f.Kids[1], shared} {
+		if got, want := locs[g], CRLF.Location(text, g.Pos); got != want {
+			t.Errorf("Locate(…)[%v]=%v, want %v", g, got, want)
+		}
+	}
+	if got, want := locs[shared], (Loc{Byte: 8, Rune: 8, Line: 3, Column: 2}); got != want {
+		t.Errorf("Locate(…)[shared]=%v, want %v", got, want)
 	}
 }
@@ -0,0 +1,180 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math/bits"
+	"sync"
+)
+
+// A PrefixHash holds a hash of each prefix of a text,
+// keyed by a MemoCache:
+// element i is the hash of the first i bytes.
+type PrefixHash []uint64
+
+// prime is the Mersenne prime 2^61-1, the modulus of the hashes.
+const prime = 1<<61 - 1
+
+// PrefixHash returns the PrefixHash of the text,
+// keyed by the MemoCache, or nil if the MemoCache is nil.
+//
+// The hash is a polynomial of the bytes of the prefix
+// evaluated at a random point chosen by the MemoCache,
+// modulo 2^61-1, so texts of n bytes with different prefixes
+// collide with probability at most n/2^61,
+// and texts colliding in one MemoCache cannot be crafted
+// without knowing its key.
+func (c *MemoCache) PrefixHash(text string) PrefixHash {
+	if c == nil {
+		return nil
+	}
+	h := make(PrefixHash, len(text)+1)
+	for i := 0; i < len(text); i++ {
+		// Add 1 so 0 bytes change the hash.
+		h[i+1] = reduce(mulMod(h[i], c.key) + uint64(text[i]) + 1)
+	}
+	return h
+}
+
+// mulMod returns a*b modulo 2^61-1 for a, b < 2^61-1.
+func mulMod(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	// Since 2^61 = 1 modulo 2^61-1, hi*2^64 + lo = hi*2^3 + lo>>61 + lo&prime.
+	return reduce(hi<<3 | lo>>61 + lo&prime)
+}
+
+// reduce returns x modulo 2^61-1 for x < 2^63.
+func reduce(x uint64) uint64 {
+	x = x&prime + x>>61
+	if x >= prime {
+		x -= prime
+	}
+	return x
+}
+
+// A MemoEntry is a memo table entry
+// for a rule at a start position, shared by a MemoCache.
+type MemoEntry struct {
+	// DeltaPos and DeltaErr are the memo table entries
+	// of the generated parser.
+	DeltaPos, DeltaErr int32
+
+	// Extent is the number of bytes at the beginning of the text,
+	// up to and including the last byte examined by the rule.
+	// The entry is valid for any text with the same first Extent bytes.
+	Extent int
+
+	// Hash is the hash of the first Extent bytes of the text,
+	// element Extent of its PrefixHash.
+	Hash uint64
+}
+
+// A MemoCache is a memo table shared by parsers of different texts
+// that have common prefixes.
+// It is safe for concurrent use by multiple parsers.
+//
+// Parsers generated with peggy -sharedmemo
+// and created by <prefix>NewSharedParser
+// look up each rule at each position in the MemoCache
+// before parsing it, and add the result after.
+// A result is only shared if it was determined by a prefix of the text,
+// so the result of a rule that reached the end of the text
+// or ran a code predicate is not shared.
+//
+// Entries are keyed by grammar, so one MemoCache
+// can be shared by parsers of different grammars.
+// Their prefixes are compared by PrefixHashes keyed by the MemoCache,
+// so the entries of a MemoCache shared by untrusted texts
+// cannot be poisoned by a crafted text with a colliding hash.
+// When the number of entries reaches the capacity,
+// all entries are removed.
+type MemoCache struct {
+	// key is the random point of the PrefixHash polynomials.
+	key uint64
+
+	mu      sync.RWMutex
+	max, n  int
+	entries map[memoKey][]MemoEntry
+}
+
+type memoKey struct {
+	grammar     string
+	rule, start int
+}
+
+// NewMemoCache returns a new, empty MemoCache
+// that holds up to max entries.
+func NewMemoCache(max int) *MemoCache {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("failed to read a random key: " + err.Error())
+	}
+	// Avoid small keys, whose polynomials have few terms
+	// before the modulus wraps them.
+	key := binary.LittleEndian.Uint64(b[:])%(prime-1<<8) + 1<<8
+	return &MemoCache{key: key, max: max, entries: make(map[memoKey][]MemoEntry)}
+}
+
+// Lookup returns the entry for the rule of the grammar
+// at the start position of the text with the PrefixHash,
+// which must be keyed by the MemoCache,
+// and whether there was such an entry.
+// Lookup on a nil MemoCache returns false.
+func (c *MemoCache) Lookup(grammar string, rule, start int, h PrefixHash) (MemoEntry, bool) {
+	if c == nil {
+		return MemoEntry{}, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, e := range c.entries[memoKey{grammar, rule, start}] {
+		if e.Extent < len(h) && h[e.Extent] == e.Hash {
+			return e, true
+		}
+	}
+	return MemoEntry{}, false
+}
+
+// Store adds the entry for the rule of the grammar at the start position.
+// Store on a nil MemoCache does nothing.
+func (c *MemoCache) Store(grammar string, rule, start int, e MemoEntry) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := memoKey{grammar, rule, start}
+	for _, f := range c.entries[k] {
+		if f.Extent == e.Extent && f.Hash == e.Hash {
+			return
+		}
+	}
+	if c.n >= c.max {
+		c.entries = make(map[memoKey][]MemoEntry)
+		c.n = 0
+	}
+	c.entries[k] = append(c.entries[k], e)
+	c.n++
+}
+
+// Invalidate removes all entries.
+// Parsers using the MemoCache remain valid,
+// but they no longer find the removed entries.
+func (c *MemoCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[memoKey][]MemoEntry)
+	c.n = 0
+}
+
+// Len returns the number of entries.
+func (c *MemoCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.n
+}
@@ -0,0 +1,135 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestMemoCache(t *testing.T) {
+	c := NewMemoCache(3)
+	abc := c.PrefixHash("abcdef")
+	e := MemoEntry{DeltaPos: 3, DeltaErr: 4, Extent: 4, Hash: abc[4]}
+	c.Store("g", 1, 2, e)
+
+	tests := []struct {
+		name    string
+		grammar string
+		rule    int
+		start   int
+		text    string
+		ok      bool
+	}{
+		{name: "same text", grammar: "g", rule: 1, start: 2, text: "abcdef", ok: true},
+		{name: "same prefix", grammar: "g", rule: 1, start: 2, text: "abcdxyz", ok: true},
+		{name: "exactly the prefix", grammar: "g", rule: 1, start: 2, text: "abcd", ok: true},
+		{name: "short text", grammar: "g", rule: 1, start: 2, text: "abc", ok: false},
+		{name: "different prefix", grammar: "g", rule: 1, start: 2, text: "abxdef", ok: false},
+		{name: "different rule", grammar: "g", rule: 0, start: 2, text: "abcdef", ok: false},
+		{name: "different start", grammar: "g", rule: 1, start: 1, text: "abcdef", ok: false},
+		{name: "different grammar", grammar: "h", rule: 1, start: 2, text: "abcdef", ok: false},
+	}
+	for _, test := range tests {
+		got, ok := c.Lookup(test.grammar, test.rule, test.start, c.PrefixHash(test.text))
+		if ok != test.ok || ok && got != e {
+			t.Errorf("%s: Lookup(%q, %d, %d, %q)=%+v, %v, want ok=%v",
+				test.name, test.grammar, test.rule, test.start, test.text, got, ok, test.ok)
+		}
+	}
+
+	// Storing a duplicate entry does not add it.
+	c.Store("g", 1, 2, e)
+	if n := c.Len(); n != 1 {
+		t.Errorf("Len()=%d after duplicate Store, want 1", n)
+	}
+
+	// Filling the cache removes all entries.
+	c.Store("g", 2, 2, e)
+	c.Store("g", 3, 2, e)
+	c.Store("g", 4, 2, e)
+	if n := c.Len(); n != 1 {
+		t.Errorf("Len()=%d after exceeding capacity, want 1", n)
+	}
+	if _, ok := c.Lookup("g", 1, 2, abc); ok {
+		t.Errorf("Lookup found an entry removed when exceeding capacity")
+	}
+
+	c.Invalidate()
+	if n := c.Len(); n != 0 {
+		t.Errorf("Len()=%d after Invalidate, want 0", n)
+	}
+	if _, ok := c.Lookup("g", 4, 2, abc); ok {
+		t.Errorf("Lookup found an entry after Invalidate")
+	}
+}
+
+func TestMemoCacheNil(t *testing.T) {
+	var c *MemoCache
+	c.Store("g", 0, 0, MemoEntry{})
+	if _, ok := c.Lookup("g", 0, 0, c.PrefixHash("")); ok {
+		t.Errorf("Lookup on nil MemoCache found an entry")
+	}
+}
+
+func TestMemoCacheConcurrent(t *testing.T) {
+	c := NewMemoCache(100)
+	h := c.PrefixHash("abcdefghij")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.Store("g", i, j%10, MemoEntry{Extent: j % 10, Hash: h[j%10]})
+				c.Lookup("g", i, j%10, h)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if n := c.Len(); n > 100 {
+		t.Errorf("Len()=%d, want at most 100", n)
+	}
+}
+
+func TestPrefixHashKeyed(t *testing.T) {
+	const text = "abcdefghij"
+	c, d := NewMemoCache(1), NewMemoCache(1)
+	hc, hd := c.PrefixHash(text), d.PrefixHash(text)
+	if len(hc) != len(text)+1 {
+		t.Fatalf("len(PrefixHash(%q))=%d, want %d", text, len(hc), len(text)+1)
+	}
+	// The caches have different keys, with overwhelming probability.
+	if hc[len(text)] == hd[len(text)] {
+		t.Errorf("caches with different keys hashed %q the same", text)
+	}
+	// Prefixes differing only in 0 bytes hash differently.
+	z := c.PrefixHash("a\x00\x00")
+	if z[1] == z[2] || z[2] == z[3] {
+		t.Errorf("PrefixHash(\"a\\x00\\x00\")=%v, want distinct prefix hashes", z)
+	}
+	var nilCache *MemoCache
+	if h := nilCache.PrefixHash(text); h != nil {
+		t.Errorf("nil MemoCache PrefixHash(%q)=%v, want nil", text, h)
+	}
+}
+
+func TestMulMod(t *testing.T) {
+	tests := []struct{ a, b, want uint64 }{
+		{0, 5, 0},
+		{1, prime - 1, prime - 1},
+		{prime - 1, prime - 1, 1},
+		{1 << 60, 2, 1},
+		{1 << 60, 4, 2},
+		{123456789, 987654321, 123456789 * 987654321 % prime},
+	}
+	for _, test := range tests {
+		if got := mulMod(test.a, test.b); got != test.want {
+			t.Errorf("mulMod(%d, %d)=%d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
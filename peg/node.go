@@ -0,0 +1,49 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+// Clone returns a deep copy of the tree rooted at n.
+// The copy shares no Nodes or Kids slices with n,
+// so either may be modified without affecting the other.
+// Texts are strings, which are immutable, so they are shared.
+func (n *Node) Clone() *Node {
+	if n == nil {
+		return nil
+	}
+	c := &Node{Name: n.Name, Text: n.Text}
+	if n.Kids != nil {
+		c.Kids = make([]*Node, len(n.Kids))
+		for i, k := range n.Kids {
+			c.Kids[i] = k.Clone()
+		}
+	}
+	return c
+}
+
+// Equal returns whether the trees rooted at n and m
+// have the same structure, Names, and Texts.
+//
+// A Node has no position, so trees parsed
+// from the same text at different offsets of an input are Equal.
+// Unlike reflect.DeepEqual, nil and empty Kids are Equal.
+func (n *Node) Equal(m *Node) bool {
+	if n == nil || m == nil {
+		return n == m
+	}
+	if n == m {
+		return true
+	}
+	if n.Name != m.Name || n.Text != m.Text || len(n.Kids) != len(m.Kids) {
+		return false
+	}
+	for i := range n.Kids {
+		if !n.Kids[i].Equal(m.Kids[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import "testing"
+
+func TestNodeClone(t *testing.T) {
+	c := queryTree.Clone()
+	if !c.Equal(queryTree) {
+		t.Fatalf("queryTree.Clone() is not Equal to queryTree")
+	}
+	c.Kids[1].Kids[1].Name = "Changed"
+	c.Kids[0].Kids = append(c.Kids[0].Kids, &Node{Text: "x"})
+	if queryTree.Kids[1].Kids[1].Name != "Product" || len(queryTree.Kids[0].Kids) != 1 {
+		t.Errorf("modifying the clone modified queryTree")
+	}
+	if (*Node)(nil).Clone() != nil {
+		t.Errorf("nil.Clone()!=nil")
+	}
+}
+
+func TestNodeEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b *Node
+		want bool
+	}{
+		{name: "nil", a: nil, b: nil, want: true},
+		{name: "nil and non-nil", a: nil, b: &Node{}, want: false},
+		{name: "non-nil and nil", a: &Node{}, b: nil, want: false},
+		{name: "same", a: queryTree, b: queryTree, want: true},
+		{name: "clone", a: queryTree, b: queryTree.Clone(), want: true},
+		{
+			name: "nil and empty kids",
+			a:    &Node{Name: "A", Text: "a"},
+			b:    &Node{Name: "A", Text: "a", Kids: []*Node{}},
+			want: true,
+		},
+		{
+			name: "different name",
+			a:    &Node{Name: "A", Text: "a"},
+			b:    &Node{Name: "B", Text: "a"},
+			want: false,
+		},
+		{
+			name: "different text",
+			a:    &Node{Name: "A", Text: "a"},
+			b:    &Node{Name: "A", Text: "b"},
+			want: false,
+		},
+		{
+			name: "different number of kids",
+			a:    &Node{Kids: []*Node{{Text: "a"}}},
+			b:    &Node{Kids: []*Node{{Text: "a"}, {Text: "a"}}},
+			want: false,
+		},
+		{
+			name: "different kid",
+			a:    &Node{Kids: []*Node{{Text: "a"}, {Name: "X", Text: "b"}}},
+			b:    &Node{Kids: []*Node{{Text: "a"}, {Name: "Y", Text: "b"}}},
+			want: false,
+		},
+	}
+	for _, test := range tests {
+		if got := test.a.Equal(test.b); got != test.want {
+			t.Errorf("%s: Equal=%v, want %v", test.name, got, test.want)
+		}
+	}
+}
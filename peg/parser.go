@@ -0,0 +1,38 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+// A Parser parses its text with the rules of a grammar, named at run time.
+// The Parser of a grammar generated with peggy's -pegparser option implements it,
+// so applications can swap grammar versions or dialects behind one interface.
+//
+// Each method parses the rule at the beginning of the text,
+// without requiring it to match the rest of the text.
+// If the rule does not match, the error is a SimpleError.
+// If the Parser cannot parse the rule,
+// because the grammar does not define it, it has parameters,
+// or the pass was not generated, the error is a RuleError.
+type Parser interface {
+	// ParseNode returns the syntax tree of the rule.
+	ParseNode(rule string) (*Node, error)
+
+	// ParseValue returns the result of the rule's actions.
+	ParseValue(rule string) (interface{}, error)
+}
+
+// A RuleError is the error of a Parser method for a rule it cannot parse.
+type RuleError struct {
+	// Rule is the name of the rule.
+	Rule string
+	// Reason is why the rule cannot be parsed,
+	// such as "undefined" or "has parameters".
+	Reason string
+}
+
+func (err RuleError) Error() string {
+	return "rule " + err.Rule + " " + err.Reason
+}
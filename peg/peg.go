@@ -51,11 +51,48 @@ type Fail struct {
 	// 	&… where the text after & is the string representation of a failed predicate subexpression.
 	// 	… the error-name of a rule.
 	// 		For example, "int" in rule: Integer "int" <- [0-9].
+	// 	MaxDepthExceeded indicating that the rule exceeded its maximum nesting depth.
+	// 	… the error message of the delegate parser of a %delegate region.
 	Want string
+
+	// Message indicates that Want is a complete error message:
+	// the error name of a named rule with a format verb,
+	// filled with the token preceding the failed rule, as by ErrorMessage.
+	// SimpleError reports the message instead of listing the Wants.
+	Message bool
+
+	// Code is the kind of the failure of a leaf Fail,
+	// set by the parser that built it.
+	// It is the empty string for Fail nodes with Kids.
+	Code ErrorCode
+}
+
+// MaxDepthExceeded is the Want of a Fail for a rule
+// that was not parsed, because parsing it would exceed
+// the maximum nesting depth set by %maxdepth or @maxdepth.
+const MaxDepthExceeded = "maximum nesting depth exceeded"
+
+// Text is the type of an input text,
+// a string or a byte slice.
+type Text interface {
+	~string | ~[]byte
 }
 
-// DecodeRuneInString is utf8.DecodeRuneInString.
+// DecodeRuneInString is utf8.DecodeRuneInString,
+// for a text of either string or byte slice type.
 // It's here so parsers can just include peg, and not also need unicode/utf8.
-func DecodeRuneInString(s string) (rune, int) {
-	return utf8.DecodeRuneInString(s)
+func DecodeRuneInString[T Text](s T) (rune, int) {
+	if len(s) > 0 && s[0] < utf8.RuneSelf {
+		return rune(s[0]), 1
+	}
+	switch s := interface{}(s).(type) {
+	case string:
+		return utf8.DecodeRuneInString(s)
+	case []byte:
+		return utf8.DecodeRune(s)
+	}
+	// A defined type with an underlying string or byte slice type
+	// is decoded from a copy of its first rune.
+	var buf [utf8.UTFMax]byte
+	return utf8.DecodeRune(buf[:copy(buf[:], s)])
 }
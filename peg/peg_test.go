@@ -0,0 +1,56 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDecodeRuneInString(t *testing.T) {
+	tests := []string{"", "a", "abc", "☺", "☺x", "\xff", "\xe2\x98", "\xe2\x98x", "𝄞"}
+	for _, test := range tests {
+		r, w := utf8.DecodeRuneInString(test)
+		if gotR, gotW := DecodeRuneInString(test); gotR != r || gotW != w {
+			t.Errorf("DecodeRuneInString(%q)=%q, %d, want %q, %d", test, gotR, gotW, r, w)
+		}
+		if gotR, gotW := DecodeRuneInString([]byte(test)); gotR != r || gotW != w {
+			t.Errorf("DecodeRuneInString([]byte(%q))=%q, %d, want %q, %d", test, gotR, gotW, r, w)
+		}
+		if gotR, gotW := DecodeRuneInString(testText(test)); gotR != r || gotW != w {
+			t.Errorf("DecodeRuneInString(testText(%q))=%q, %d, want %q, %d", test, gotR, gotW, r, w)
+		}
+	}
+}
+
+// testText is a defined type with an underlying string type.
+type testText string
+
+var decodeText = strings.Repeat("aαβ☺𝄞", 100)
+
+func BenchmarkDecodeRuneInString(b *testing.B) {
+	b.Run("string", func(b *testing.B) {
+		benchmarkDecodeRuneInString(b, decodeText)
+	})
+	b.Run("bytes", func(b *testing.B) {
+		benchmarkDecodeRuneInString(b, []byte(decodeText))
+	})
+	b.Run("defined", func(b *testing.B) {
+		benchmarkDecodeRuneInString(b, testText(decodeText))
+	})
+}
+
+func benchmarkDecodeRuneInString[T Text](b *testing.B, text T) {
+	b.SetBytes(int64(len(text)))
+	for i := 0; i < b.N; i++ {
+		for pos := 0; pos < len(text); {
+			_, w := DecodeRuneInString(text[pos:])
+			pos += w
+		}
+	}
+}
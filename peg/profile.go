@@ -0,0 +1,128 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A Profile records the time spent parsing each rule of a grammar,
+// keyed by the stack of rules being parsed.
+// A parser generated with peggy -profile
+// records the time of its Accepts pass
+// in a Profile variable, <prefix>Profile.
+//
+// A Profile is not safe for concurrent use,
+// so a profiled parser must not be used by concurrent parses.
+type Profile struct {
+	// Rules are the names of the rules of the grammar,
+	// indexed by rule constant.
+	Rules []string
+
+	root  profileNode
+	stack []profileFrame
+}
+
+// A profileNode is a stack of rules in the profile tree.
+type profileNode struct {
+	rule int
+	// self is the time spent in the rule,
+	// not including the time of the rules it called.
+	self time.Duration
+	kids map[int]*profileNode
+}
+
+// A profileFrame is a rule being parsed.
+type profileFrame struct {
+	node  *profileNode
+	start time.Time
+	// kids is the time spent in the rules called by this one.
+	kids time.Duration
+}
+
+// Enter records the start of parsing a rule,
+// called from the rule on the top of the stack, if any.
+func (p *Profile) Enter(rule int) {
+	parent := &p.root
+	if n := len(p.stack); n > 0 {
+		parent = p.stack[n-1].node
+	}
+	node := parent.kids[rule]
+	if node == nil {
+		node = &profileNode{rule: rule}
+		if parent.kids == nil {
+			parent.kids = make(map[int]*profileNode)
+		}
+		parent.kids[rule] = node
+	}
+	p.stack = append(p.stack, profileFrame{node: node, start: time.Now()})
+}
+
+// Exit records the end of parsing the rule on the top of the stack.
+func (p *Profile) Exit() {
+	n := len(p.stack)
+	f := p.stack[n-1]
+	p.stack = p.stack[:n-1]
+	d := time.Since(f.start)
+	f.node.self += d - f.kids
+	if n > 1 {
+		p.stack[n-2].kids += d
+	}
+}
+
+// Reset discards all recorded times.
+func (p *Profile) Reset() {
+	p.root = profileNode{}
+	p.stack = p.stack[:0]
+}
+
+// WriteFolded writes the Profile in the folded stacks format
+// read by flame graph tools, such as flamegraph.pl and speedscope.
+//
+// Each line is a stack of rule names, outermost first,
+// separated by semicolons, followed by a space
+// and the nanoseconds spent in the innermost rule of the stack,
+// not including the time of the rules that it called.
+func (p *Profile) WriteFolded(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	var stack []string
+	var write func(*profileNode)
+	write = func(n *profileNode) {
+		stack = append(stack, p.ruleName(n.rule))
+		fmt.Fprintf(bw, "%s %d\n", strings.Join(stack, ";"), n.self.Nanoseconds())
+		for _, k := range n.sortedKids() {
+			write(k)
+		}
+		stack = stack[:len(stack)-1]
+	}
+	for _, k := range p.root.sortedKids() {
+		write(k)
+	}
+	return bw.Flush()
+}
+
+func (p *Profile) ruleName(rule int) string {
+	if rule >= 0 && rule < len(p.Rules) {
+		// Spaces and semicolons separate stacks and counts.
+		return strings.NewReplacer(" ", "", ";", ",").Replace(p.Rules[rule])
+	}
+	return fmt.Sprintf("rule%d", rule)
+}
+
+func (n *profileNode) sortedKids() []*profileNode {
+	kids := make([]*profileNode, 0, len(n.kids))
+	for _, k := range n.kids {
+		kids = append(kids, k)
+	}
+	sort.Slice(kids, func(i, j int) bool { return kids[i].rule < kids[j].rule })
+	return kids
+}
@@ -0,0 +1,55 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestProfileWriteFolded(t *testing.T) {
+	p := &Profile{Rules: []string{"A", "B", "L<x, y>"}}
+	p.Enter(0)
+	p.Enter(1)
+	p.Enter(2)
+	p.Exit()
+	p.Exit()
+	p.Enter(2)
+	p.Exit()
+	p.Enter(1)
+	p.Exit()
+	p.Exit()
+
+	var b bytes.Buffer
+	if err := p.WriteFolded(&b); err != nil {
+		t.Fatalf("WriteFolded(_)=%v, want nil", err)
+	}
+	var stacks []string
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n") {
+		i := strings.LastIndex(line, " ")
+		if i < 0 {
+			t.Fatalf("bad folded line %q", line)
+		}
+		if _, err := strconv.ParseInt(line[i+1:], 10, 64); err != nil {
+			t.Errorf("bad folded line %q: %v", line, err)
+		}
+		stacks = append(stacks, line[:i])
+	}
+	want := []string{"A", "A;B", "A;B;L<x,y>", "A;L<x,y>"}
+	if !reflect.DeepEqual(stacks, want) {
+		t.Errorf("WriteFolded stacks=%q, want %q", stacks, want)
+	}
+
+	p.Reset()
+	b.Reset()
+	if err := p.WriteFolded(&b); err != nil || b.Len() != 0 {
+		t.Errorf("after Reset, WriteFolded wrote %q, %v, want \"\", nil", b.String(), err)
+	}
+}
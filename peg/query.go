@@ -0,0 +1,65 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+// Find returns the Nodes reached from n by following a path of rule names.
+//
+// Each element of the path selects, from each Node selected so far,
+// its named children with the element as their Name,
+// or all of its named children if the element is "*".
+// The named children of a Node are its named Kids,
+// along with the named children of its anonymous Kids.
+// Nodes are returned in the order they appear in the tree.
+//
+// For example, Find(n, "Sum", "Product") returns
+// the Product children of the Sum children of n.
+func Find(n *Node, path ...string) []*Node {
+	nodes := []*Node{n}
+	for _, name := range path {
+		var next []*Node
+		for _, n := range nodes {
+			next = appendNamedKids(next, n, name)
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+func appendNamedKids(nodes []*Node, n *Node, name string) []*Node {
+	for _, k := range n.Kids {
+		switch {
+		case k.Name == "":
+			nodes = appendNamedKids(nodes, k, name)
+		case name == "*" || k.Name == name:
+			nodes = append(nodes, k)
+		}
+	}
+	return nodes
+}
+
+// Descendants returns all Nodes beneath n with the given Name,
+// in the order they appear in the tree.
+func (n *Node) Descendants(name string) []*Node {
+	return n.Select(func(k *Node) bool { return k.Name == name })
+}
+
+// Select returns all Nodes beneath n for which the predicate is true,
+// in the order they appear in the tree.
+func (n *Node) Select(pred func(*Node) bool) []*Node {
+	var nodes []*Node
+	var sel func(*Node)
+	sel = func(n *Node) {
+		for _, k := range n.Kids {
+			if pred(k) {
+				nodes = append(nodes, k)
+			}
+			sel(k)
+		}
+	}
+	sel(n)
+	return nodes
+}
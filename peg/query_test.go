@@ -0,0 +1,95 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// queryTree is a parse tree of 1+2*3 for a grammar:
+//
+//	Sum <- Product ( "+" Product )*
+//	Product <- Num ( "*" Num )*
+//	Num <- [0-9]
+var queryTree = &Node{
+	Name: "Sum",
+	Text: "1+2*3",
+	Kids: []*Node{
+		{Name: "Product", Text: "1", Kids: []*Node{{Name: "Num", Text: "1"}}},
+		{
+			Text: "+2*3",
+			Kids: []*Node{
+				{Text: "+"},
+				{
+					Name: "Product",
+					Text: "2*3",
+					Kids: []*Node{
+						{Name: "Num", Text: "2"},
+						{
+							Text: "*3",
+							Kids: []*Node{
+								{Text: "*"},
+								{Name: "Num", Text: "3"},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func texts(nodes []*Node) string {
+	var ss []string
+	for _, n := range nodes {
+		ss = append(ss, n.Name+":"+n.Text)
+	}
+	return strings.Join(ss, " ")
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		path []string
+		want string
+	}{
+		{path: nil, want: "Sum:1+2*3"},
+		{path: []string{"Product"}, want: "Product:1 Product:2*3"},
+		{path: []string{"Product", "Num"}, want: "Num:1 Num:2 Num:3"},
+		{path: []string{"*", "Num"}, want: "Num:1 Num:2 Num:3"},
+		{path: []string{"*"}, want: "Product:1 Product:2*3"},
+		{path: []string{"Num"}, want: ""},
+		{path: []string{"Product", "Num", "Num"}, want: ""},
+	}
+	for _, test := range tests {
+		if got := texts(Find(queryTree, test.path...)); got != test.want {
+			t.Errorf("Find(n, %q...)=%q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestDescendants(t *testing.T) {
+	if got, want := texts(queryTree.Descendants("Num")), "Num:1 Num:2 Num:3"; got != want {
+		t.Errorf("Descendants(\"Num\")=%q, want %q", got, want)
+	}
+	if got := queryTree.Descendants("Sum"); got != nil {
+		t.Errorf("Descendants(\"Sum\")=%q, want none", texts(got))
+	}
+}
+
+func TestSelect(t *testing.T) {
+	got := queryTree.Select(func(n *Node) bool { return n.Name == "" })
+	want := []string{"+2*3", "+", "*3", "*"}
+	var gotText []string
+	for _, n := range got {
+		gotText = append(gotText, n.Text)
+	}
+	if !reflect.DeepEqual(gotText, want) {
+		t.Errorf("Select(anonymous)=%q, want %q", gotText, want)
+	}
+}
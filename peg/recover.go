@@ -0,0 +1,51 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+// A Recovery is a recovery from the failure of a rule
+// annotated with @recoveruntil or @recoverpast,
+// which accepted the skipped input instead of failing.
+type Recovery struct {
+	// Name is the name of the rule.
+	Name string
+
+	// Pos is the byte offset of the start of the failed rule.
+	// End is the byte offset of the end of the skipped input.
+	Pos, End int
+
+	// Fail is the Fail tree of the rule's failure at Pos.
+	Fail *Fail
+}
+
+// Recover returns the byte offset of the end of the input skipped
+// to recover from a failure of a rule at the byte offset pos of the text.
+// The input is skipped up to the first occurrence at or after pos
+// of any literal in until, or through the first occurrence
+// of any literal in past, or, if there is none, to the end of the text.
+// Recover returns pos if no input is skipped,
+// in which case the rule should fail instead.
+//
+// The text may be a string or a byte slice.
+func Recover[T Text](text T, pos int, until, past []string) int {
+	for i := pos; i < len(text); i++ {
+		for _, lit := range until {
+			if hasPrefix(text[i:], lit) {
+				return i
+			}
+		}
+		for _, lit := range past {
+			if hasPrefix(text[i:], lit) {
+				return i + len(lit)
+			}
+		}
+	}
+	return len(text)
+}
+
+func hasPrefix[T Text](text T, prefix string) bool {
+	return len(text) >= len(prefix) && string(text[:len(prefix)]) == prefix
+}
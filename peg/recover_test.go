@@ -0,0 +1,37 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import "testing"
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		text        string
+		pos         int
+		until, past []string
+		want        int
+	}{
+		{text: "x = ; y", pos: 0, past: []string{";"}, want: len("x = ;")},
+		{text: "x = } y", pos: 0, until: []string{"}"}, past: []string{";"}, want: len("x = ")},
+		{text: "x = ; }", pos: 0, until: []string{"}"}, past: []string{";"}, want: len("x = ;")},
+		{text: "a; b = ;", pos: len("a; "), past: []string{";"}, want: len("a; b = ;")},
+		{text: "} x", pos: 0, until: []string{"}"}, want: 0},
+		{text: "x = ", pos: 0, past: []string{";"}, want: len("x = ")},
+		{text: "x end", pos: 0, until: []string{"end"}, want: len("x ")},
+		{text: "", pos: 0, past: []string{";"}, want: 0},
+	}
+	for _, test := range tests {
+		if got := Recover(test.text, test.pos, test.until, test.past); got != test.want {
+			t.Errorf("Recover(%q, %d, %q, %q)=%d, want %d",
+				test.text, test.pos, test.until, test.past, got, test.want)
+		}
+		if got := Recover([]byte(test.text), test.pos, test.until, test.past); got != test.want {
+			t.Errorf("Recover([]byte(%q), %d, %q, %q)=%d, want %d",
+				test.text, test.pos, test.until, test.past, got, test.want)
+		}
+	}
+}
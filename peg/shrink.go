@@ -0,0 +1,53 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+// Shrink returns a shortened copy of the input, with runes deleted,
+// for which fails still returns true.
+// The input must itself satisfy fails.
+//
+// Shrink uses delta debugging:
+// it tries deleting successively smaller chunks of runes,
+// keeping each deletion that preserves the failure,
+// until no single rune can be deleted.
+// The result is minimal in that sense,
+// but it is not necessarily the shortest failing input.
+func Shrink(input string, fails func(string) bool) string {
+	rs := []rune(input)
+	n := 2
+	for len(rs) > 0 {
+		if n > len(rs) {
+			n = len(rs)
+		}
+		chunk := (len(rs) + n - 1) / n
+		deleted := false
+		for start := 0; start < len(rs); start += chunk {
+			end := start + chunk
+			if end > len(rs) {
+				end = len(rs)
+			}
+			rest := make([]rune, 0, len(rs)-(end-start))
+			rest = append(append(rest, rs[:start]...), rs[end:]...)
+			if fails(string(rest)) {
+				rs = rest
+				deleted = true
+				break
+			}
+		}
+		switch {
+		case deleted:
+			if n > 2 {
+				n--
+			}
+		case n < len(rs):
+			n *= 2
+		default:
+			return string(rs)
+		}
+	}
+	return string(rs)
+}
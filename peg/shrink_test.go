@@ -0,0 +1,62 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShrink(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		fails func(string) bool
+		want  string
+	}{
+		{
+			name:  "contains rune",
+			input: "abcdefghij",
+			fails: func(s string) bool { return strings.ContainsRune(s, 'g') },
+			want:  "g",
+		},
+		{
+			name:  "subsequence",
+			input: "(1 + (2 * 3)) - ☺ / 4",
+			fails: func(s string) bool {
+				i := strings.IndexRune(s, '(')
+				return i >= 0 && strings.ContainsRune(s[i:], '☺')
+			},
+			want: "(☺",
+		},
+		{
+			name:  "empty fails",
+			input: "abc",
+			fails: func(string) bool { return true },
+			want:  "",
+		},
+		{
+			name:  "nothing deletable",
+			input: "abc",
+			fails: func(s string) bool { return s == "abc" },
+			want:  "abc",
+		},
+		{
+			name:  "unbalanced parens",
+			input: "((a)(b)(c)",
+			fails: func(s string) bool {
+				return strings.Count(s, "(") > strings.Count(s, ")")
+			},
+			want: "(",
+		},
+	}
+	for _, test := range tests {
+		if got := Shrink(test.input, test.fails); got != test.want {
+			t.Errorf("%s: Shrink(%q, _)=%q, want %q", test.name, test.input, got, test.want)
+		}
+	}
+}
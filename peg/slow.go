@@ -0,0 +1,64 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"fmt"
+	"time"
+)
+
+// A SlowParse is a parse of a rule that took longer than its time budget,
+// set by the %warnslow directive or the @warnslow annotation.
+type SlowParse struct {
+	// Rule is the name of the rule.
+	Rule string
+
+	// Start and End are the byte offsets of the input span
+	// examined by the parse of the rule:
+	// from its start to the end of its match
+	// or its furthest failure, whichever is greater.
+	Start, End int
+
+	// Duration is the time of the parse,
+	// including the time of the rules that it called.
+	Duration time.Duration
+
+	// Budget is the rule's time budget.
+	Budget time.Duration
+}
+
+func (s SlowParse) String() string {
+	return fmt.Sprintf("%s [%d:%d] took %s, budget %s", s.Rule, s.Start, s.End, s.Duration, s.Budget)
+}
+
+// A SlowTimer times the parse of a rule with a time budget.
+// Generated parsers use it so that they need not import time.
+type SlowTimer struct {
+	rule   string
+	start  int
+	budget time.Duration
+	begin  time.Time
+}
+
+// StartSlowTimer returns a SlowTimer for a parse of the rule
+// beginning at the byte offset start, started now.
+func StartSlowTimer(rule string, start int, budget time.Duration) SlowTimer {
+	return SlowTimer{rule: rule, start: start, budget: budget, begin: time.Now()}
+}
+
+// Stop returns the SlowParse of the timed parse, ending at the byte offset end,
+// and whether it took longer than its budget.
+func (t SlowTimer) Stop(end int) (SlowParse, bool) {
+	d := time.Since(t.begin)
+	if d <= t.budget {
+		return SlowParse{}, false
+	}
+	if end < t.start {
+		end = t.start
+	}
+	return SlowParse{Rule: t.rule, Start: t.start, End: end, Duration: d, Budget: t.budget}, true
+}
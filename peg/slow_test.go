@@ -0,0 +1,36 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowTimer(t *testing.T) {
+	if s, ok := StartSlowTimer("A", 1, time.Hour).Stop(5); ok {
+		t.Errorf("Stop(5) within budget=%v, true, want false", s)
+	}
+
+	timer := StartSlowTimer("A", 1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	s, ok := timer.Stop(5)
+	if !ok {
+		t.Fatalf("Stop(5) over budget=_, false, want true")
+	}
+	if s.Rule != "A" || s.Start != 1 || s.End != 5 || s.Budget != time.Nanosecond || s.Duration < time.Millisecond {
+		t.Errorf("Stop(5)=%+v, want A [1:5] over 1ms with budget 1ns", s)
+	}
+	if str := s.String(); !strings.HasPrefix(str, "A [1:5] took ") || !strings.HasSuffix(str, ", budget 1ns") {
+		t.Errorf("String()=%q, want A [1:5] took …, budget 1ns", str)
+	}
+
+	if s, _ := StartSlowTimer("A", 3, -1).Stop(-1); s.Start != 3 || s.End != 3 {
+		t.Errorf("Stop(-1)=%+v, want [3:3]", s)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"bytes"
+	"errors"
+	"unicode/utf16"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF32BE = []byte{0x00, 0x00, 0xFE, 0xFF}
+	bomUTF32LE = []byte{0xFF, 0xFE, 0x00, 0x00}
+)
+
+// Sniff returns the UTF-8 text of input data
+// that may begin with a byte-order mark.
+// A UTF-8 byte-order mark is removed.
+// Data beginning with a UTF-16 byte-order mark
+// is converted from UTF-16 of the marked byte order to UTF-8,
+// with the byte-order mark removed.
+// Data without a byte-order mark is returned unchanged.
+//
+// An error is returned for UTF-32 byte-order marks,
+// and for UTF-16 data of an odd number of bytes.
+// Unpaired UTF-16 surrogates are converted to U+FFFD.
+func Sniff(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return string(data[len(bomUTF8):]), nil
+	case bytes.HasPrefix(data, bomUTF32BE):
+		return "", errors.New("unsupported encoding: UTF-32 big-endian byte-order mark")
+	case bytes.HasPrefix(data, bomUTF32LE):
+		return "", errors.New("unsupported encoding: UTF-32 little-endian byte-order mark")
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return decodeUTF16(data[len(bomUTF16BE):], func(b []byte) uint16 {
+			return uint16(b[0])<<8 | uint16(b[1])
+		})
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return decodeUTF16(data[len(bomUTF16LE):], func(b []byte) uint16 {
+			return uint16(b[1])<<8 | uint16(b[0])
+		})
+	}
+	return string(data), nil
+}
+
+// decodeUTF16 returns the UTF-8 text of UTF-16 data,
+// with code units of byte pairs decoded by unit.
+func decodeUTF16(data []byte, unit func([]byte) uint16) (string, error) {
+	if len(data)%2 != 0 {
+		return "", errors.New("bad UTF-16: odd number of bytes")
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = unit(data[2*i:])
+	}
+	return string(utf16.Decode(units)), nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+		err  string
+	}{
+		{name: "empty", data: nil, want: ""},
+		{name: "no BOM", data: []byte("héllo"), want: "héllo"},
+		{name: "UTF-8 BOM", data: []byte("\xEF\xBB\xBFhéllo"), want: "héllo"},
+		{name: "UTF-8 BOM only", data: []byte("\xEF\xBB\xBF"), want: ""},
+		{
+			name: "UTF-16 big-endian",
+			data: []byte{0xFE, 0xFF, 0x00, 'h', 0x00, 0xE9, 0xD8, 0x3D, 0xDE, 0x00},
+			want: "hé😀",
+		},
+		{
+			name: "UTF-16 little-endian",
+			data: []byte{0xFF, 0xFE, 'h', 0x00, 0xE9, 0x00, 0x3D, 0xD8, 0x00, 0xDE},
+			want: "hé😀",
+		},
+		{
+			name: "UTF-16 unpaired surrogate",
+			data: []byte{0xFE, 0xFF, 0xD8, 0x3D, 0x00, 'h'},
+			want: "�h",
+		},
+		{
+			name: "UTF-16 odd length",
+			data: []byte{0xFE, 0xFF, 0x00, 'h', 0x00},
+			err:  "odd number of bytes",
+		},
+		{
+			name: "UTF-32 big-endian",
+			data: []byte{0x00, 0x00, 0xFE, 0xFF, 0x00, 0x00, 0x00, 'h'},
+			err:  "UTF-32 big-endian",
+		},
+		{
+			name: "UTF-32 little-endian",
+			data: []byte{0xFF, 0xFE, 0x00, 0x00, 'h', 0x00, 0x00, 0x00},
+			err:  "UTF-32 little-endian",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Sniff(test.data)
+			if test.err != "" {
+				if err == nil || !regexp.MustCompile(test.err).MatchString(err.Error()) {
+					t.Errorf("Sniff(%q)=_, %v, want error matching %q", test.data, err, test.err)
+				}
+				return
+			}
+			if err != nil || got != test.want {
+				t.Errorf("Sniff(%q)=%q, %v, want %q, nil", test.data, got, err, test.want)
+			}
+		})
+	}
+}
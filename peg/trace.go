@@ -0,0 +1,218 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A Trace records the rules tried by a parse, in order,
+// so that the parse can be stepped through afterwards,
+// forward and backward, as by peggy replay.
+// A parser generated with peggy -trace
+// records its Accepts pass in a Trace variable, <prefix>Trace.
+//
+// A Trace holds the most recent parse of a text:
+// entering a rule, with no other rules entered,
+// to parse a text other than the Trace's Text
+// discards the recorded events.
+//
+// A Trace is not safe for concurrent use,
+// so a traced parser must not be used by concurrent parses.
+type Trace struct {
+	// Rules are the names of the rules of the grammar,
+	// indexed by rule constant.
+	Rules []string
+
+	// Text is the parsed text.
+	Text string
+
+	// Events are the events of the parse, in order.
+	Events []TraceEvent
+
+	// stack are the indices in Events of the TraceEnter events
+	// of the rules entered and not yet exited.
+	stack []int
+}
+
+// A TraceKind is the kind of a TraceEvent.
+type TraceKind int
+
+const (
+	// TraceEnter is the start of parsing a rule.
+	TraceEnter TraceKind = iota
+	// TraceAccept is a rule accepting.
+	TraceAccept
+	// TraceFail is a rule failing.
+	TraceFail
+)
+
+func (k TraceKind) String() string {
+	switch k {
+	case TraceEnter:
+		return "enter"
+	case TraceAccept:
+		return "accept"
+	case TraceFail:
+		return "fail"
+	}
+	return fmt.Sprintf("TraceKind(%d)", int(k))
+}
+
+// A TraceEvent is an event of a traced parse.
+type TraceEvent struct {
+	Kind TraceKind
+
+	// Rule is the rule constant of the rule.
+	Rule int
+
+	// Pos is a byte offset into the text:
+	// the start of the rule for TraceEnter,
+	// the end of the accepted text for TraceAccept,
+	// and the furthest failure position for TraceFail.
+	Pos int
+}
+
+// Enter records the start of parsing a rule of the text
+// at the start position,
+// called from the rule on the top of the stack, if any.
+func (t *Trace) Enter(text string, rule, start int) {
+	if len(t.stack) == 0 && text != t.Text {
+		t.Text = text
+		t.Events = t.Events[:0]
+	}
+	t.stack = append(t.stack, len(t.Events))
+	t.Events = append(t.Events, TraceEvent{Kind: TraceEnter, Rule: rule, Pos: start})
+}
+
+// Exit records the end of parsing the rule on the top of the stack,
+// given the results of its Accepts function:
+// the number of bytes accepted, or -1 if the rule failed,
+// and the offset of the furthest failure from the start.
+func (t *Trace) Exit(deltaPos, deltaErr int) {
+	n := len(t.stack)
+	enter := t.Events[t.stack[n-1]]
+	t.stack = t.stack[:n-1]
+	ev := TraceEvent{Kind: TraceAccept, Rule: enter.Rule, Pos: enter.Pos + deltaPos}
+	if deltaPos < 0 {
+		ev.Kind = TraceFail
+		ev.Pos = enter.Pos
+		if deltaErr > 0 {
+			ev.Pos += deltaErr
+		}
+	}
+	t.Events = append(t.Events, ev)
+}
+
+// Reset discards the text and all recorded events.
+func (t *Trace) Reset() {
+	t.Text = ""
+	t.Events = t.Events[:0]
+	t.stack = t.stack[:0]
+}
+
+// traceHeader begins a Trace written by Write.
+const traceHeader = "peggy trace 1\n"
+
+// Write writes the Trace in a compact binary format read by ReadTrace.
+//
+// After a header line, the format is a sequence of unsigned varints:
+// the number of rules, each rule name as its length and bytes,
+// the text as its length and bytes, the number of events,
+// and each event as its rule constant times 3 plus its kind,
+// followed by its position as a zig-zag varint
+// of the difference from the position of the previous event.
+func (t *Trace) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(traceHeader)
+	var buf [binary.MaxVarintLen64]byte
+	putUvarint := func(x uint64) {
+		bw.Write(buf[:binary.PutUvarint(buf[:], x)])
+	}
+	putString := func(s string) {
+		putUvarint(uint64(len(s)))
+		bw.WriteString(s)
+	}
+	putUvarint(uint64(len(t.Rules)))
+	for _, r := range t.Rules {
+		putString(r)
+	}
+	putString(t.Text)
+	putUvarint(uint64(len(t.Events)))
+	var pos int
+	for _, ev := range t.Events {
+		putUvarint(uint64(ev.Rule)*3 + uint64(ev.Kind))
+		bw.Write(buf[:binary.PutVarint(buf[:], int64(ev.Pos-pos))])
+		pos = ev.Pos
+	}
+	return bw.Flush()
+}
+
+// ReadTrace returns the Trace written by Write to the reader.
+func ReadTrace(r io.Reader) (*Trace, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(traceHeader))
+	if _, err := io.ReadFull(br, header); err != nil || string(header) != traceHeader {
+		return nil, errors.New("not a peggy trace")
+	}
+	var err error
+	getUvarint := func() uint64 {
+		if err != nil {
+			return 0
+		}
+		var x uint64
+		x, err = binary.ReadUvarint(br)
+		return x
+	}
+	getString := func() string {
+		n := getUvarint()
+		if err != nil {
+			return ""
+		}
+		if n > 1<<32 {
+			err = errors.New("bad trace string length")
+			return ""
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(br, b)
+		return string(b)
+	}
+	var t Trace
+	for n := getUvarint(); err == nil && n > 0; n-- {
+		t.Rules = append(t.Rules, getString())
+	}
+	t.Text = getString()
+	var pos int64
+	for n := getUvarint(); err == nil && n > 0; n-- {
+		x := getUvarint()
+		if err != nil {
+			break
+		}
+		var d int64
+		if d, err = binary.ReadVarint(br); err != nil {
+			break
+		}
+		pos += d
+		ev := TraceEvent{Kind: TraceKind(x % 3), Rule: int(x / 3), Pos: int(pos)}
+		if ev.Rule >= len(t.Rules) || ev.Pos < 0 || ev.Pos > len(t.Text) {
+			err = fmt.Errorf("bad trace event %d", len(t.Events))
+			break
+		}
+		t.Events = append(t.Events, ev)
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package peg
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	tr := &Trace{Rules: []string{"A", "B", "L<x, y>"}}
+	const text = "abcdef"
+	tr.Enter(text, 0, 0)
+	tr.Enter(text, 1, 0)
+	tr.Exit(3, 5)
+	tr.Enter(text, 2, 3)
+	tr.Exit(-1, 2)
+	tr.Enter(text, 2, 3)
+	tr.Exit(-1, -4)
+	tr.Exit(3, 5)
+
+	want := []TraceEvent{
+		{Kind: TraceEnter, Rule: 0, Pos: 0},
+		{Kind: TraceEnter, Rule: 1, Pos: 0},
+		{Kind: TraceAccept, Rule: 1, Pos: 3},
+		{Kind: TraceEnter, Rule: 2, Pos: 3},
+		{Kind: TraceFail, Rule: 2, Pos: 5},
+		{Kind: TraceEnter, Rule: 2, Pos: 3},
+		{Kind: TraceFail, Rule: 2, Pos: 3},
+		{Kind: TraceAccept, Rule: 0, Pos: 3},
+	}
+	if tr.Text != text || !reflect.DeepEqual(tr.Events, want) {
+		t.Fatalf("Trace=%q, %v, want %q, %v", tr.Text, tr.Events, text, want)
+	}
+
+	var b bytes.Buffer
+	if err := tr.Write(&b); err != nil {
+		t.Fatalf("Write(_)=%v, want nil", err)
+	}
+	got, err := ReadTrace(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadTrace(_)=_, %v, want nil", err)
+	}
+	if !reflect.DeepEqual(got.Rules, tr.Rules) || got.Text != tr.Text || !reflect.DeepEqual(got.Events, tr.Events) {
+		t.Errorf("ReadTrace(_)=%q, %q, %v, want %q, %q, %v",
+			got.Rules, got.Text, got.Events, tr.Rules, tr.Text, tr.Events)
+	}
+	for n := 0; n < b.Len(); n++ {
+		if _, err := ReadTrace(bytes.NewReader(b.Bytes()[:n])); err == nil {
+			t.Errorf("ReadTrace(first %d bytes)=_, nil, want error", n)
+		}
+	}
+
+	// A parse of the same text appends; of another text, replaces.
+	tr.Enter(text, 1, 0)
+	tr.Exit(1, 1)
+	if len(tr.Events) != len(want)+2 {
+		t.Errorf("after parsing the same text, %d events, want %d", len(tr.Events), len(want)+2)
+	}
+	tr.Enter("xyz", 1, 0)
+	tr.Exit(1, 1)
+	if tr.Text != "xyz" || len(tr.Events) != 2 {
+		t.Errorf("after parsing another text, Trace=%q with %d events, want \"xyz\" with 2", tr.Text, len(tr.Events))
+	}
+
+	tr.Reset()
+	if tr.Text != "" || len(tr.Events) != 0 {
+		t.Errorf("after Reset, Trace=%q, %v, want \"\", []", tr.Text, tr.Events)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// A profileRun is the gob-encoded standard input of the profile harness:
+// the inputs to parse and the number of times to parse each.
+type profileRun struct {
+	Count  int
+	Inputs []profileInput
+}
+
+// A profileInput is an input parsed by the profile harness.
+type profileInput struct {
+	Name, Text string
+}
+
+// profile generates the parser for a grammar file,
+// instrumented with Config.Profile,
+// along with a harness that parses each input count times
+// with the root rule,
+// and writes to w the time of the Accepts pass by stacks of rules,
+// in the folded stacks format of peg.Profile.WriteFolded.
+// Inputs that fail to parse are reported to standard error.
+// If root is the empty string, the first rule of the grammar is used.
+// If there are no inputs, standard input is parsed.
+func profile(w io.Writer, file, root string, count int, inputs []string) error {
+	if count < 1 {
+		return errors.New("profile count must be positive")
+	}
+	run := profileRun{Count: count}
+	if len(inputs) == 0 {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		run.Inputs = append(run.Inputs, profileInput{Name: "<stdin>", Text: string(data)})
+	}
+	for _, in := range inputs {
+		data, err := ioutil.ReadFile(in)
+		if err != nil {
+			return err
+		}
+		run.Inputs = append(run.Inputs, profileInput{Name: in, Text: string(data)})
+	}
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(run); err != nil {
+		return err
+	}
+	cfg, err := flagConfig()
+	if err != nil {
+		return err
+	}
+	cfg.Profile = true
+	return runHarness(w, &b, file, root, "profile", profileHarness, cfg)
+}
+
+var profileHarness = `package main
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+
+	peg "{{.PegImport}}"
+)
+
+func main() {
+	var run struct {
+		Count  int
+		Inputs []struct{ Name, Text string }
+	}
+	if err := gob.NewDecoder(os.Stdin).Decode(&run); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, in := range run.Inputs {
+		for i := 0; i < run.Count; i++ {
+			p, err := {{.Prefix}}NewParser(in.Text)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", in.Name, err)
+				break
+			}
+			pos, perr := {{.Prefix}}{{.Root}}Accepts(p, 0)
+			if pos < 0 {
+				loc := peg.Location(in.Text, perr)
+				fmt.Fprintf(os.Stderr, "%s:%d.%d: failed to parse\n", in.Name, loc.Line, loc.Column)
+				break
+			}
+		}
+	}
+	if err := {{.Prefix}}Profile.WriteFolded(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`
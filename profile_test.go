@@ -0,0 +1,80 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peggy_profile_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "g.peggy")
+	const grammar = `{
+package calc
+
+import "github.com/eaburns/peggy/peg"
+
+func main() {}
+}
+Top <- List !.
+List <- "[" (Elem ("," Elem)*)? "]"
+Elem <- Num / List
+Num <- [0-9]+
+`
+	if err := ioutil.WriteFile(file, []byte(grammar), 0666); err != nil {
+		t.Fatal(err)
+	}
+	var inputs []string
+	for i, in := range []string{"[1,[2,3]]", "[[[4]]]"} {
+		f := filepath.Join(dir, "in"+string(rune('0'+i)))
+		if err := ioutil.WriteFile(f, []byte(in), 0666); err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, f)
+	}
+
+	var got strings.Builder
+	if err := profile(&got, file, "", 2, inputs); err != nil {
+		t.Fatalf("profile(_, _, \"\", 2, _)=%v, want nil", err)
+	}
+	line := regexp.MustCompile(`^([A-Za-z;]+) [0-9]+$`)
+	stacks := make(map[string]bool)
+	for _, l := range strings.Split(strings.TrimSuffix(got.String(), "\n"), "\n") {
+		m := line.FindStringSubmatch(l)
+		if m == nil {
+			t.Fatalf("bad folded stack line %q", l)
+		}
+		stacks[m[1]] = true
+	}
+	for _, s := range []string{
+		"Top",
+		"Top;List",
+		"Top;List;Elem;Num",
+		"Top;List;Elem;List;Elem;List;Elem;Num",
+	} {
+		if !stacks[s] {
+			t.Errorf("profile missing stack %s:\n%s", s, got.String())
+		}
+	}
+
+	got.Reset()
+	if err := profile(&got, file, "Elem", 1, inputs); err != nil {
+		t.Fatalf("profile(_, _, \"Elem\", 1, _)=%v, want nil", err)
+	}
+	if !strings.HasPrefix(got.String(), "Elem ") {
+		t.Errorf("profile with root Elem wrote %q, want stacks beginning with Elem", got.String())
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+// reorderMain writes a report of the choices of the grammar file
+// whose branches would be tried fewer times if reordered
+// according to the merged coverage profiles.
+// If apply is true, it writes the grammar with the choices reordered to w
+// and the report to report; otherwise it writes the report to w.
+func reorderMain(w, report io.Writer, file string, profiles []string, apply bool) error {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	cfg, err := flagConfig()
+	if err != nil {
+		return err
+	}
+	g, err := cfg.Parse(bytes.NewReader(src), file)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Check(g); err != nil {
+		return err
+	}
+	return grammar.Reorder(w, report, file, string(src), g, profiles, apply)
+}
@@ -0,0 +1,217 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"text/template"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+// repl generates the parser for a grammar file
+// along with a harness that reads lines from in,
+// parsing each with the root rule,
+// and writing to w either the parse tree or the parse error.
+// If root is the empty string, the first rule of the grammar is used.
+func repl(w io.Writer, in io.Reader, file, root string) error {
+	if !*genParseTree {
+		return errors.New("repl requires parse tree generation, -t")
+	}
+	cfg, err := flagConfig()
+	if err != nil {
+		return err
+	}
+	return runHarness(w, in, file, root, "repl", replHarness, cfg)
+}
+
+// runHarness generates the parser for a grammar file with the Config
+// and runs it with go run along with a harness,
+// which is generated by executing the harness template
+// with the Prefix, the root rule Ident, and the PegImport path.
+// The harness reads from in and writes to w and standard error.
+//
+// The harness is built and run in the current directory,
+// which must be in a Go module that can import the peg runtime package,
+// github.com/eaburns/peggy/peg or the -pegimport path.
+// The repl, shrink, explain, trace, and profile subcommands
+// all run their harnesses this way.
+func runHarness(w io.Writer, in io.Reader, file, root, name, harness string, cfg grammar.Config) error {
+	parserSrc, harnessSrc, err := harnessSources(file, root, name, harness, cfg)
+	if err != nil {
+		return err
+	}
+	dir, err := ioutil.TempDir("", "peggy_"+name)
+	if err != nil {
+		return err
+	}
+	defer removeTemp(dir)
+	parserFile := filepath.Join(dir, "parser.go")
+	harnessFile := filepath.Join(dir, name+".go")
+	if err := ioutil.WriteFile(parserFile, parserSrc, 0666); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(harnessFile, harnessSrc, 0666); err != nil {
+		return err
+	}
+	cmd := exec.Command("go", "run", parserFile, harnessFile)
+	cmd.Stdin = in
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// harnessSources returns the source of the parser for a grammar file,
+// generated with the Config and changed to be in package main,
+// and the source of the harness, generated from the harness template.
+func harnessSources(file, root, name, harness string, cfg grammar.Config) (parserSrc, harnessSrc []byte, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	g, err := cfg.Parse(bufio.NewReader(f), file)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.Check(g); err != nil {
+		return nil, nil, err
+	}
+	return checkedHarnessSources(g, file, root, name, harness, cfg)
+}
+
+// checkedHarnessSources is like harnessSources,
+// but for the checked grammar of the file.
+func checkedHarnessSources(g *grammar.Grammar, file, root, name, harness string, cfg grammar.Config) (parserSrc, harnessSrc []byte, err error) {
+	r, err := replRoot(g, root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var src bytes.Buffer
+	if err := cfg.Generate(&src, file, g); err != nil {
+		return nil, nil, err
+	}
+	if parserSrc, err = mainPackage(src.String()); err != nil {
+		return nil, nil, err
+	}
+	var b bytes.Buffer
+	err = template.Must(template.New(name).Parse(harness)).Execute(&b, map[string]string{
+		"Prefix":    cfg.Prefix,
+		"Root":      r.Name.Ident(),
+		"PegImport": cfg.PegImportPath(),
+		"Newline":   strconv.Itoa(int(g.PegNewline())),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return parserSrc, b.Bytes(), nil
+}
+
+// keepTemp returns whether generated temporary files are kept:
+// if the -keep-tmp flag is set or $PEGGY_KEEP is non-empty.
+func keepTemp() bool {
+	return *keepTmp || os.Getenv("PEGGY_KEEP") != ""
+}
+
+// removeTemp removes a temporary file or directory,
+// unless keepTemp is true, in which case
+// its path is printed to standard error instead.
+func removeTemp(path string) {
+	if keepTemp() {
+		fmt.Fprintf(os.Stderr, "peggy: kept %s\n", path)
+		return
+	}
+	os.RemoveAll(path)
+}
+
+// replRoot returns the rule with the given name,
+// or the first rule if the name is empty.
+func replRoot(g *grammar.Grammar, name string) (*grammar.Rule, error) {
+	for _, r := range g.CheckedRules {
+		if name != "" && r.Name.String() != name {
+			continue
+		}
+		if r.Params != nil {
+			return nil, grammar.Err(r, "rule %s has parameters, so it cannot be the root", r.Name)
+		}
+		return r, nil
+	}
+	if name == "" {
+		return nil, errors.New("the grammar has no rules")
+	}
+	return nil, errors.New("rule " + name + " undefined")
+}
+
+// mainPackage returns the Go source code changed to be in package main.
+// Any existing main function is renamed, so as to not conflict with the harness.
+func mainPackage(src string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	file.Name.Name = "main"
+	for _, decl := range file.Decls {
+		if fun, ok := decl.(*ast.FuncDecl); ok && fun.Recv == nil && fun.Name.Name == "main" {
+			fun.Name.Name = "_replHiddenMain"
+		}
+	}
+	var b bytes.Buffer
+	if err := format.Node(&b, fset, file); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+var replHarness = `package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	peg "{{.PegImport}}"
+)
+
+func main() {
+	in := bufio.NewScanner(os.Stdin)
+	for fmt.Print("> "); in.Scan(); fmt.Print("> ") {
+		line := in.Text()
+		p, err := {{.Prefix}}NewParser(line)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		pos, perr := {{.Prefix}}{{.Root}}Accepts(p, 0)
+		if pos < 0 {
+			_, fail := {{.Prefix}}{{.Root}}Fail(p, 0, perr)
+			fmt.Println(peg.SimpleError(line, fail))
+			continue
+		}
+		_, node := {{.Prefix}}{{.Root}}Node(p, 0)
+		fmt.Println(peg.Pretty(node))
+		if pos < len(line) {
+			fmt.Printf("parsed only %d of %d bytes\n", pos, len(line))
+		}
+	}
+	fmt.Println()
+}
+`
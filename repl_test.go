@@ -0,0 +1,100 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRepl(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peggy_repl_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "g.peggy")
+	const grammar = `{
+package calc
+
+import "github.com/eaburns/peggy/peg"
+
+func main() {}
+}
+Pair <- Num "," Num !.
+Num <- [0-9]+
+`
+	if err := ioutil.WriteFile(file, []byte(grammar), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		root, in, want string
+	}{
+		{
+			root: "",
+			in:   "1,2\n1,\n",
+			want: `> Pair{
+	Num{"1"},
+	",",
+	Num{"2"},
+}
+> :1.3: want [0-9]; got EOF
+> 
+`,
+		},
+		{
+			root: "Num",
+			in:   "12,\n",
+			want: `> Num{
+	"1",
+	"2",
+}
+parsed only 2 of 3 bytes
+> 
+`,
+		},
+	}
+	for _, test := range tests {
+		var got strings.Builder
+		if err := repl(&got, strings.NewReader(test.in), file, test.root); err != nil {
+			t.Errorf("repl(_, %q, _, %q)=%v, want nil", test.in, test.root, err)
+			continue
+		}
+		if got.String() != test.want {
+			t.Errorf("repl(_, %q, _, %q) wrote\n%s\nwant\n%s", test.in, test.root, got.String(), test.want)
+		}
+	}
+
+	if err := repl(ioutil.Discard, strings.NewReader(""), file, "Undefined"); err == nil ||
+		err.Error() != "rule Undefined undefined" {
+		t.Errorf("repl(_, _, _, \"Undefined\")=%v, want rule Undefined undefined", err)
+	}
+}
+
+func TestRemoveTemp(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peggy_remove_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Setenv("PEGGY_KEEP", "1")
+	removeTemp(dir)
+	os.Unsetenv("PEGGY_KEEP")
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("with PEGGY_KEEP, removeTemp(%q) removed it: %v", dir, err)
+	}
+
+	removeTemp(dir)
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("removeTemp(%q) did not remove it: %v", dir, err)
+	}
+}
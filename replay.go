@@ -0,0 +1,334 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+// trace generates the parser for a grammar file,
+// instrumented with Config.Trace,
+// along with a harness that parses the input file,
+// or standard input if input is the empty string,
+// with the root rule,
+// and writes to w the trace of the parse, as by peg.Trace.Write.
+// If root is the empty string, the first rule of the grammar is used.
+func trace(w io.Writer, file, root, input string) error {
+	var in io.Reader = os.Stdin
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	cfg, err := flagConfig()
+	if err != nil {
+		return err
+	}
+	cfg.Trace = true
+	return runHarness(w, in, file, root, "trace", traceHarness, cfg)
+}
+
+var traceHarness = `package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+func main() {
+	text, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	p, err := {{.Prefix}}NewParser(string(text))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	{{.Prefix}}{{.Root}}Accepts(p, 0)
+	if err := {{.Prefix}}Trace.Write(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`
+
+// replayMain reads the trace file and replays it,
+// reading commands from in and writing to w.
+func replayMain(w io.Writer, in io.Reader, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	tr, err := peg.ReadTrace(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	return replay(w, in, tr)
+}
+
+// A replayer steps through the events of a trace.
+type replayer struct {
+	trace *peg.Trace
+
+	// cur is the index of the current event.
+	cur int
+
+	// enter is, for each event, the index of the TraceEnter event
+	// of its rule.
+	enter []int
+
+	// exit is, for each TraceEnter event, the index of the event
+	// of its rule accepting or failing,
+	// or -1 if the trace ends before the rule does.
+	exit []int
+
+	// parent is, for each TraceEnter event, the index of
+	// the TraceEnter event of the rule that entered its rule,
+	// or -1 for a root rule.
+	parent []int
+}
+
+func newReplayer(tr *peg.Trace) *replayer {
+	n := len(tr.Events)
+	r := &replayer{
+		trace:  tr,
+		enter:  make([]int, n),
+		exit:   make([]int, n),
+		parent: make([]int, n),
+	}
+	var stack []int
+	for i, ev := range tr.Events {
+		r.exit[i] = -1
+		if ev.Kind == peg.TraceEnter {
+			r.parent[i] = -1
+			if len(stack) > 0 {
+				r.parent[i] = stack[len(stack)-1]
+			}
+			r.enter[i] = i
+			stack = append(stack, i)
+			continue
+		}
+		if len(stack) == 0 {
+			// A malformed trace; treat the event as its own rule.
+			r.enter[i], r.parent[i] = i, -1
+			continue
+		}
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		r.enter[i], r.exit[e], r.parent[i] = e, i, r.parent[e]
+	}
+	return r
+}
+
+const replayHelp = `commands:
+	n [count]	step forward count events, 1 by default; also an empty line
+	b [count]	step backward count events, 1 by default
+	g event	go to the event number
+	f	finish: go to the accept or fail of the current rule
+	s	start: go to the enter of the current rule
+	/rule	go to the next enter of the rule
+	?rule	go to the previous enter of the rule
+	h	print this help
+	q	quit
+`
+
+// replay steps through the trace, reading commands from in,
+// one per line, and writing each event stepped to
+// with the input around its position and the stack of active rules.
+func replay(w io.Writer, in io.Reader, tr *peg.Trace) error {
+	if len(tr.Events) == 0 {
+		_, err := fmt.Fprintln(w, "the trace has no events")
+		return err
+	}
+	r := newReplayer(tr)
+	r.show(w)
+	s := bufio.NewScanner(in)
+	for fmt.Fprint(w, "> "); s.Scan(); fmt.Fprint(w, "> ") {
+		line := strings.TrimSpace(s.Text())
+		if line == "q" {
+			return nil
+		}
+		if msg := r.do(line); msg != "" {
+			fmt.Fprintln(w, msg)
+			continue
+		}
+		r.show(w)
+	}
+	fmt.Fprintln(w)
+	return s.Err()
+}
+
+// do performs a command, returning a message to print
+// instead of the current event, or the empty string.
+func (r *replayer) do(cmd string) string {
+	events := r.trace.Events
+	count := func(arg string) (int, string) {
+		if arg == "" {
+			return 1, ""
+		}
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return 0, "bad count " + arg
+		}
+		return n, ""
+	}
+	switch c, arg := cmdArg(cmd); c {
+	case "", "n":
+		n, msg := count(arg)
+		if msg != "" {
+			return msg
+		}
+		if r.cur == len(events)-1 {
+			return "at the last event"
+		}
+		if r.cur += n; r.cur > len(events)-1 {
+			r.cur = len(events) - 1
+		}
+	case "b":
+		n, msg := count(arg)
+		if msg != "" {
+			return msg
+		}
+		if r.cur == 0 {
+			return "at the first event"
+		}
+		if r.cur -= n; r.cur < 0 {
+			r.cur = 0
+		}
+	case "g":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n > len(events) {
+			return fmt.Sprintf("bad event %s: want 1 to %d", arg, len(events))
+		}
+		r.cur = n - 1
+	case "f":
+		e := r.enter[r.cur]
+		if r.exit[e] < 0 {
+			return "the rule does not finish in the trace"
+		}
+		r.cur = r.exit[e]
+	case "s":
+		r.cur = r.enter[r.cur]
+	case "/", "?":
+		step := 1
+		if c == "?" {
+			step = -1
+		}
+		for i := r.cur + step; i >= 0 && i < len(events); i += step {
+			if ev := events[i]; ev.Kind == peg.TraceEnter && r.ruleName(ev.Rule) == arg {
+				r.cur = i
+				return ""
+			}
+		}
+		return "rule " + arg + " not found"
+	case "h":
+		return strings.TrimSuffix(replayHelp, "\n")
+	default:
+		return "unknown command " + cmd + "; h for help"
+	}
+	return ""
+}
+
+// cmdArg splits a command line into the command and its argument.
+// The search commands, / and ?, take the rest of the line.
+func cmdArg(line string) (cmd, arg string) {
+	if strings.HasPrefix(line, "/") || strings.HasPrefix(line, "?") {
+		return line[:1], strings.TrimSpace(line[1:])
+	}
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		return line[:i], strings.TrimSpace(line[i+1:])
+	}
+	return line, ""
+}
+
+// show writes the current event, the stack of active rules,
+// and the line of input containing the event's position,
+// marked by a cursor.
+func (r *replayer) show(w io.Writer) {
+	ev := r.trace.Events[r.cur]
+	text := r.trace.Text
+	start := r.trace.Events[r.enter[r.cur]].Pos
+	line, col, lineText, indent := replayLine(text, ev.Pos)
+	fmt.Fprintf(w, "event %d of %d: %s %s at %d:%d",
+		r.cur+1, len(r.trace.Events), ev.Kind, r.ruleName(ev.Rule), line, col)
+	if ev.Kind == peg.TraceAccept && start <= ev.Pos {
+		fmt.Fprintf(w, ", accepting %s", replayQuote(text[start:ev.Pos]))
+	}
+	fmt.Fprintln(w)
+
+	var stack []string
+	for e := r.enter[r.cur]; e >= 0; e = r.parent[e] {
+		stack = append(stack, r.ruleName(r.trace.Events[e].Rule))
+	}
+	for i, j := 0, len(stack)-1; i < j; i, j = i+1, j-1 {
+		stack[i], stack[j] = stack[j], stack[i]
+	}
+	fmt.Fprintf(w, "stack: %s\n", strings.Join(stack, " > "))
+
+	prefix := strconv.Itoa(line) + " | "
+	fmt.Fprintf(w, "%s%s\n", prefix, lineText)
+	fmt.Fprintf(w, "%s%s^\n", strings.Repeat(" ", len(prefix)), indent)
+}
+
+func (r *replayer) ruleName(rule int) string {
+	if rule >= 0 && rule < len(r.trace.Rules) {
+		return r.trace.Rules[rule]
+	}
+	return fmt.Sprintf("rule%d", rule)
+}
+
+// replayLine returns the 1-based line and column of the byte position,
+// the text of its line, without the newline,
+// and the whitespace indenting a cursor to the position beneath the line.
+func replayLine(text string, pos int) (line, col int, lineText, indent string) {
+	begin := strings.LastIndex(text[:pos], "\n") + 1
+	end := strings.IndexByte(text[pos:], '\n')
+	if end < 0 {
+		end = len(text)
+	} else {
+		end += pos
+	}
+	var b strings.Builder
+	for _, r := range text[begin:pos] {
+		if r == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	line = strings.Count(text[:begin], "\n") + 1
+	col = utf8.RuneCountInString(text[begin:pos]) + 1
+	return line, col, text[begin:end], b.String()
+}
+
+// replayQuote returns the quoted text, shortened if it is long.
+func replayQuote(text string) string {
+	const maxLen = 40
+	if len(text) <= maxLen {
+		return strconv.Quote(text)
+	}
+	n := maxLen
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return strconv.Quote(text[:n]) + "..."
+}
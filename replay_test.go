@@ -0,0 +1,191 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+func TestReplay(t *testing.T) {
+	const (
+		top = iota
+		list
+		num
+	)
+	tr := &peg.Trace{Rules: []string{"Top", "List", "Num"}}
+	const text = "[1,\n\t[x]]"
+	tr.Enter(text, top, 0)
+	tr.Enter(text, list, 0)
+	tr.Enter(text, num, 1)
+	tr.Exit(1, 1)
+	tr.Enter(text, list, 5)
+	tr.Enter(text, num, 6)
+	tr.Exit(-1, 0)
+	tr.Exit(-1, 1)
+	tr.Exit(-1, 5)
+	tr.Exit(-1, 5)
+
+	tests := []struct {
+		name string
+		cmds string
+		want []string
+	}{
+		{
+			name: "step",
+			cmds: "\nn 2\nb\nb 100\nb\n",
+			want: []string{
+				"event 1 of 10: enter Top at 1:1",
+				"event 2 of 10: enter List at 1:1",
+				"event 4 of 10: accept Num at 1:3, accepting \"1\"",
+				"event 3 of 10: enter Num at 1:2",
+				"event 1 of 10: enter Top at 1:1",
+				"at the first event",
+			},
+		},
+		{
+			name: "finish and start",
+			cmds: "g 6\nf\ns\ng 5\nf\ng 100\n",
+			want: []string{
+				"event 1 of 10: enter Top at 1:1",
+				"event 6 of 10: enter Num at 2:3",
+				"event 7 of 10: fail Num at 2:3",
+				"event 6 of 10: enter Num at 2:3",
+				"event 5 of 10: enter List at 2:2",
+				"event 8 of 10: fail List at 2:3",
+				"bad event 100: want 1 to 10",
+			},
+		},
+		{
+			name: "search",
+			cmds: "/Num\n/Num\n/Num\n?List\n?Top\nn 100\nn\n",
+			want: []string{
+				"event 1 of 10: enter Top at 1:1",
+				"event 3 of 10: enter Num at 1:2",
+				"event 6 of 10: enter Num at 2:3",
+				"rule Num not found",
+				"event 5 of 10: enter List at 2:2",
+				"event 1 of 10: enter Top at 1:1",
+				"event 10 of 10: fail Top at 2:2",
+				"at the last event",
+			},
+		},
+		{
+			name: "quit",
+			cmds: "q\nn\n",
+			want: []string{"event 1 of 10: enter Top at 1:1"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := replay(&b, strings.NewReader(test.cmds), tr); err != nil {
+				t.Fatalf("replay(_, %q, _)=%v, want nil", test.cmds, err)
+			}
+			// Each prompt is followed by an event or a message;
+			// keep only its first line.
+			var got []string
+			for _, out := range strings.Split(b.String(), "\n> ") {
+				if line := strings.SplitN(out, "\n", 2)[0]; line != "" {
+					got = append(got, line)
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("replay(_, %q, _) events=\n%s\nwant\n%s\noutput:\n%s", test.cmds,
+					strings.Join(got, "\n"), strings.Join(test.want, "\n"), b.String())
+			}
+		})
+	}
+
+	var b strings.Builder
+	if err := replay(&b, strings.NewReader("g 6\n"), tr); err != nil {
+		t.Fatalf("replay(_, \"g 6\", _)=%v, want nil", err)
+	}
+	const want = "> event 6 of 10: enter Num at 2:3\n" +
+		"stack: Top > List > List > Num\n" +
+		"2 | \t[x]]\n" +
+		"    \t ^\n"
+	if !strings.Contains(b.String(), want) {
+		t.Errorf("replay(_, \"g 6\", _)=\n%s\nwant it to contain\n%s", b.String(), want)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peggy_trace_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "g.peggy")
+	const grammar = `{
+package calc
+
+import "github.com/eaburns/peggy/peg"
+
+func main() {}
+}
+Top <- List !.
+List <- "[" (Elem ("," Elem)*)? "]"
+Elem <- Num / List
+Num <- [0-9]+
+`
+	if err := ioutil.WriteFile(file, []byte(grammar), 0666); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(dir, "in")
+	if err := ioutil.WriteFile(input, []byte("[1,[x]]"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := trace(&b, file, "", input); err != nil {
+		t.Fatalf("trace(_, _, \"\", _)=%v, want nil", err)
+	}
+	tr, err := peg.ReadTrace(&b)
+	if err != nil {
+		t.Fatalf("peg.ReadTrace(_)=_, %v, want nil", err)
+	}
+	if tr.Text != "[1,[x]]" {
+		t.Errorf("trace text=%q, want %q", tr.Text, "[1,[x]]")
+	}
+	var got []string
+	for _, ev := range tr.Events {
+		got = append(got, ev.Kind.String()+" "+tr.Rules[ev.Rule])
+	}
+	want := []string{
+		"enter Top",
+		"enter List",
+		"enter Elem",
+		"enter Num",
+		"accept Num",
+		"accept Elem",
+		"enter Elem",
+		"enter Num",
+		"fail Num",
+		"enter List",
+		"enter Elem",
+		"enter Num",
+		"fail Num",
+		"enter List",
+		"fail List",
+		"fail Elem",
+		"fail List",
+		"fail Elem",
+		"fail List",
+		"fail Top",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("trace events=\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
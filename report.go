@@ -0,0 +1,40 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+// writeReport writes the Report of the generated parser source
+// to the -report file, if any.
+func writeReport(c grammar.Config, file string, gr *grammar.Grammar, src []byte) error {
+	if *report == "" {
+		return nil
+	}
+	rep, err := c.NewReport(file, gr, src)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if err := rep.Write(&b, reportFormat(*report)); err != nil {
+		return err
+	}
+	return writeOutput(*report, b.Bytes())
+}
+
+// reportFormat returns the format of a report file:
+// json if it has the .json extension, and text otherwise.
+func reportFormat(file string) string {
+	if strings.HasSuffix(file, ".json") {
+		return "json"
+	}
+	return "text"
+}
@@ -0,0 +1,111 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+func TestReport(t *testing.T) {
+	const in = `{
+package p
+}
+A <- List<B> List<"c">
+List<X> <- X ("," X)*
+B <- "b"`
+	g, err := grammar.Parse(strings.NewReader(in), "test.peggy")
+	if err != nil {
+		t.Fatalf("Parse(%q)=_, %v, want _,nil", in, err)
+	}
+	if err := grammar.Check(g); err != nil {
+		t.Fatalf("Check(%q)=%v, want nil", in, err)
+	}
+	cfg := grammar.Config{Prefix: "_"}
+	var src bytes.Buffer
+	if err := cfg.Generate(&src, "test.peggy", g); err != nil {
+		t.Fatalf("Generate(_, _, %q)=%v, want nil", in, err)
+	}
+	rep, err := cfg.NewReport("test.peggy", g, src.Bytes())
+	if err != nil {
+		t.Fatalf("NewReport(_)=_, %v, want nil", err)
+	}
+	if rep.Rules != 4 || rep.Templates != 2 {
+		t.Errorf("NewReport(_) has %d rules, %d templates, want 4, 2", rep.Rules, rep.Templates)
+	}
+	if rep.Bytes != src.Len() || rep.Lines != strings.Count(src.String(), "\n") {
+		t.Errorf("NewReport(_) has size %d bytes, %d lines, want %d, %d",
+			rep.Bytes, rep.Lines, src.Len(), strings.Count(src.String(), "\n"))
+	}
+	if want := []string{"accepts", "fail", "node", "action"}; !reflect.DeepEqual(rep.Passes, want) {
+		t.Errorf("NewReport(_) has passes %q, want %q", rep.Passes, want)
+	}
+	var names []string
+	var ruleBytes int
+	for _, rr := range rep.RuleSizes {
+		names = append(names, rr.Rule)
+		if rr.Funcs == 0 || rr.Bytes == 0 || rr.Lines == 0 {
+			t.Errorf("NewReport(_) rule %s has %d funcs, %d bytes, %d lines, want non-zero",
+				rr.Rule, rr.Funcs, rr.Bytes, rr.Lines)
+		}
+		ruleBytes += rr.Bytes
+	}
+	if want := []string{"A", "B", "List<B>", `List<"c">`}; !reflect.DeepEqual(names, want) {
+		t.Errorf("NewReport(_) has rules %q, want %q", names, want)
+	}
+	if ruleBytes > rep.Bytes {
+		t.Errorf("NewReport(_) has %d rule bytes, want at most %d", ruleBytes, rep.Bytes)
+	}
+
+	var text strings.Builder
+	if err := rep.Write(&text, "text"); err != nil {
+		t.Fatalf("Write(_, text)=%v, want nil", err)
+	}
+	for _, want := range []string{
+		"grammar: test.peggy\n",
+		"rules: 4 (2 expanded templates)\n",
+		"passes: accepts, fail, node, action\n",
+		"rule      funcs   lines    bytes\n",
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("Write(_, text)=\n%s\nwant it to contain %q", text.String(), want)
+		}
+	}
+
+	var js bytes.Buffer
+	if err := rep.Write(&js, "json"); err != nil {
+		t.Fatalf("Write(_, json)=%v, want nil", err)
+	}
+	var got grammar.Report
+	if err := json.Unmarshal(js.Bytes(), &got); err != nil {
+		t.Fatalf("json.Unmarshal(%s)=%v, want nil", js.String(), err)
+	}
+	if !reflect.DeepEqual(&got, rep) {
+		t.Errorf("Write(_, json)=%s, want %+v", js.String(), rep)
+	}
+
+	if err := rep.Write(&js, "yaml"); err == nil {
+		t.Errorf("Write(_, yaml)=nil, want error")
+	}
+}
+
+func TestReportFormat(t *testing.T) {
+	for file, want := range map[string]string{
+		"report.json": "json",
+		"report.txt":  "text",
+		"report":      "text",
+	} {
+		if got := reportFormat(file); got != want {
+			t.Errorf("reportFormat(%q)=%q, want %q", file, got, want)
+		}
+	}
+}
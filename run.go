@@ -0,0 +1,294 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// runGrammar parses each input file, or in if there are none,
+// with the root rule of the grammar file,
+// or the first rule if root is the empty string,
+// writing to w the parse tree or the parse error of each.
+//
+// The parser is generated along with a harness
+// and built to a binary by cachedBinary.
+// The binary exits with status 1 if any input fails to parse
+// or is only parsed in part.
+func runGrammar(w io.Writer, in io.Reader, file, root string, inputs []string) error {
+	if !*genParseTree {
+		return errors.New("run requires parse tree generation, -t")
+	}
+	cfg, err := flagConfig()
+	if err != nil {
+		return err
+	}
+	parserSrc, harnessSrc, err := harnessSources(file, root, "run", runGrammarHarness, cfg)
+	if err != nil {
+		return err
+	}
+	binary, err := cachedBinary(parserSrc, harnessSrc, os.Stderr)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(binary, inputs...)
+	cmd.Stdin = in
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// Cached binaries are evicted when a binary is added to the cache,
+// least-recently used first, beyond maxCachedBinaries,
+// or when unused for longer than maxCacheAge.
+const (
+	maxCachedBinaries = 32
+	maxCacheAge       = 7 * 24 * time.Hour
+)
+
+// cachedBinary returns the path of the binary built
+// from the parser and harness sources in package main,
+// building it in the current module if it is not already cached
+// in the user's cache directory,
+// and writing the output of the build to stderr.
+// Unlike runHarness, which builds with go run each time,
+// it is used by subcommands run repeatedly: run, test, and serve.
+//
+// The binary is keyed by the sources, the Go version,
+// the current module's go.mod and go.sum,
+// and the files of every non-standard package the sources import,
+// directly or indirectly, such as the peg runtime package,
+// so editing a dependency, even one replaced by a local directory,
+// rebuilds the binary.
+func cachedBinary(parserSrc, harnessSrc []byte, stderr io.Writer) (string, error) {
+	dir, err := ioutil.TempDir("", "peggy_run")
+	if err != nil {
+		return "", err
+	}
+	defer removeTemp(dir)
+	parserFile := filepath.Join(dir, "parser.go")
+	harnessFile := filepath.Join(dir, "run.go")
+	if err := ioutil.WriteFile(parserFile, parserSrc, 0666); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(harnessFile, harnessSrc, 0666); err != nil {
+		return "", err
+	}
+
+	env, err := exec.Command("go", "env", "GOVERSION", "GOMOD").Output()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write(env)
+	if lines := strings.Split(string(env), "\n"); len(lines) > 1 && lines[1] != "" && lines[1] != os.DevNull {
+		for _, f := range []string{lines[1], strings.TrimSuffix(lines[1], ".mod") + ".sum"} {
+			data, err := ioutil.ReadFile(f)
+			if err != nil && !os.IsNotExist(err) {
+				return "", err
+			}
+			h.Write(data)
+		}
+	}
+	h.Write(parserSrc)
+	h.Write(harnessSrc)
+	if err := hashDeps(h, parserFile, harnessFile); err != nil {
+		return "", err
+	}
+
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	cache = filepath.Join(cache, "peggy", "run")
+	if err := os.MkdirAll(cache, 0777); err != nil {
+		return "", err
+	}
+	binary := filepath.Join(cache, fmt.Sprintf("%x", h.Sum(nil)))
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	if _, err := os.Stat(binary); err == nil {
+		// Mark the binary used, for eviction.
+		now := time.Now()
+		os.Chtimes(binary, now, now)
+		return binary, nil
+	}
+
+	// Build in the temporary directory and move the binary to the cache,
+	// so a concurrent run never sees a partial binary.
+	tmp := filepath.Join(dir, filepath.Base(binary))
+	cmd := exec.Command("go", "build", "-o", tmp, parserFile, harnessFile)
+	cmd.Stdout = stderr
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	if err := moveFile(tmp, binary); err != nil {
+		return "", err
+	}
+	evictBinaries(cache, binary)
+	return binary, nil
+}
+
+// hashDeps writes to h the names and contents of the source files
+// of the non-standard packages imported by the files,
+// directly or indirectly.
+func hashDeps(h io.Writer, files ...string) error {
+	const format = `{{if not .Standard}}{{.Dir}}{{range .GoFiles}} {{.}}{{end}}{{range .CgoFiles}} {{.}}{{end}}` +
+		`{{range .CFiles}} {{.}}{{end}}{{range .HFiles}} {{.}}{{end}}{{range .SFiles}} {{.}}{{end}}` +
+		`{{range .EmbedFiles}} {{.}}{{end}}{{end}}`
+	args := append([]string{"list", "-e", "-deps", "-f", format}, files...)
+	out, err := exec.Command("go", args...).Output()
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		// The package of the files themselves has no directory of its own
+		// and is hashed by the caller.
+		if len(fields) < 2 || fields[0] == filepath.Dir(files[0]) {
+			continue
+		}
+		for _, name := range fields[1:] {
+			data, err := ioutil.ReadFile(filepath.Join(fields[0], name))
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s/%s %d\n", fields[0], name, len(data))
+			h.Write(data)
+		}
+	}
+	return nil
+}
+
+// evictBinaries removes the binaries of the cache directory
+// unused for longer than maxCacheAge, and the least-recently used
+// beyond maxCachedBinaries, except keep.
+// Errors are ignored; a binary failing to be removed
+// is only removed later.
+func evictBinaries(cache, keep string) {
+	infos, err := ioutil.ReadDir(cache)
+	if err != nil {
+		return
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().After(infos[j].ModTime())
+	})
+	var n int
+	for _, info := range infos {
+		path := filepath.Join(cache, info.Name())
+		if info.IsDir() || path == keep {
+			continue
+		}
+		n++
+		if n < maxCachedBinaries && time.Since(info.ModTime()) < maxCacheAge {
+			continue
+		}
+		os.Remove(path)
+	}
+}
+
+// moveFile moves the file src to dst,
+// copying it if they are on different file systems.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(dst), "build")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0755); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), dst)
+}
+
+var runGrammarHarness = `package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	peg "{{.PegImport}}"
+)
+
+func main() {
+	files := os.Args[1:]
+	if len(files) == 0 {
+		files = []string{""}
+	}
+	status := 0
+	for _, file := range files {
+		var data []byte
+		var err error
+		if file == "" {
+			data, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			data, err = ioutil.ReadFile(file)
+		}
+		if err != nil {
+			fmt.Println(err)
+			status = 1
+			continue
+		}
+		if len(files) > 1 {
+			fmt.Println(file + ":")
+		}
+		text := string(data)
+		p, err := {{.Prefix}}NewParser(text)
+		if err != nil {
+			fmt.Println(err)
+			status = 1
+			continue
+		}
+		pos, perr := {{.Prefix}}{{.Root}}Accepts(p, 0)
+		if pos < 0 {
+			_, fail := {{.Prefix}}{{.Root}}Fail(p, 0, perr)
+			err := peg.Newline({{.Newline}}).SimpleError(text, fail)
+			err.FilePath = file
+			fmt.Println(err)
+			status = 1
+			continue
+		}
+		_, node := {{.Prefix}}{{.Root}}Node(p, 0)
+		fmt.Println(peg.Pretty(node))
+		if pos < len(text) {
+			fmt.Printf("parsed only %d of %d bytes\n", pos, len(text))
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
+`
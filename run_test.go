@@ -0,0 +1,243 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the user cache directory is only moved on linux")
+	}
+	dir, err := ioutil.TempDir("", "peggy_run_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// Keep the go build cache while moving the user cache directory.
+	gocache, err := exec.Command("go", "env", "GOCACHE").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOCACHE", strings.TrimSpace(string(gocache)))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	file := filepath.Join(dir, "g.peggy")
+	const grammar = `{
+package calc
+
+import "github.com/eaburns/peggy/peg"
+
+var _ *peg.Node
+}
+List <- "[" (Num ("," Num)*)? "]"
+Num <- [0-9]+
+`
+	if err := ioutil.WriteFile(file, []byte(grammar), 0666); err != nil {
+		t.Fatal(err)
+	}
+	var inputs []string
+	for i, in := range []string{"[1,2]", "[1,", "[3]x"} {
+		f := filepath.Join(dir, "in"+string(rune('0'+i)))
+		if err := ioutil.WriteFile(f, []byte(in), 0666); err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, f)
+	}
+
+	var got strings.Builder
+	err = runGrammar(&got, nil, file, "", inputs)
+	if exit, ok := err.(*exec.ExitError); !ok || exit.ExitCode() != 1 {
+		t.Fatalf("runGrammar(_, _, _, \"\", _)=%v, want exit status 1", err)
+	}
+	want := inputs[0] + `:
+List{
+	"[",
+	{
+		Num{"1"},
+		{
+			",",
+			Num{"2"},
+		},
+	},
+	"]",
+}
+` + inputs[1] + `:
+` + inputs[1] + `:1.4: want [0-9]; got EOF
+` + inputs[2] + `:
+List{
+	"[",
+	{
+		Num{"3"},
+	},
+	"]",
+}
+parsed only 3 of 4 bytes
+`
+	if got.String() != want {
+		t.Errorf("runGrammar wrote\n%s\nwant\n%s", got.String(), want)
+	}
+
+	binaries, err := filepath.Glob(filepath.Join(dir, "cache", "peggy", "run", "*"))
+	if err != nil || len(binaries) != 1 {
+		t.Fatalf("cached binaries %v, %v, want one", binaries, err)
+	}
+	// The binary is cached for each root and reused.
+	stamp, err := os.Stat(binaries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Reset()
+	if err := runGrammar(&got, strings.NewReader("[4]"), file, "Num", nil); err == nil {
+		t.Errorf("runGrammar(_, \"[4]\", _, \"Num\", _)=nil, want exit status 1")
+	}
+	if want := ":1.1: want [0-9]; got '[4]'\n"; got.String() != want {
+		t.Errorf("runGrammar with root Num wrote %q, want %q", got.String(), want)
+	}
+	got.Reset()
+	if err := runGrammar(&got, strings.NewReader("[4]"), file, "", nil); err != nil {
+		t.Errorf("runGrammar(_, \"[4]\", _, \"\", _)=%v, want nil", err)
+	}
+	// A partial parse fails.
+	got.Reset()
+	err = runGrammar(&got, strings.NewReader("[4]x"), file, "", nil)
+	if exit, ok := err.(*exec.ExitError); !ok || exit.ExitCode() != 1 {
+		t.Errorf("runGrammar(_, \"[4]x\", _, \"\", _)=%v, want exit status 1", err)
+	}
+	if want := "parsed only 3 of 4 bytes\n"; !strings.HasSuffix(got.String(), want) {
+		t.Errorf("runGrammar with a partial parse wrote %q, want suffix %q", got.String(), want)
+	}
+	// A rebuilt binary is moved into the cache as a new file.
+	if again, err := os.Stat(binaries[0]); err != nil || !os.SameFile(again, stamp) {
+		t.Errorf("cached binary rebuilt or removed: %v", err)
+	}
+	if binaries, _ := filepath.Glob(filepath.Join(dir, "cache", "peggy", "run", "*")); len(binaries) != 2 {
+		t.Errorf("cached binaries %v, want one for each root", binaries)
+	}
+}
+
+// TestRunNewline tests that runGrammar locates errors
+// with the line terminators of the grammar's %newline.
+func TestRunNewline(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the user cache directory is only moved on linux")
+	}
+	dir, err := ioutil.TempDir("", "peggy_run_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	gocache, err := exec.Command("go", "env", "GOCACHE").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOCACHE", strings.TrimSpace(string(gocache)))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	file := filepath.Join(dir, "g.peggy")
+	const grammar = `{
+package lines
+
+import "github.com/eaburns/peggy/peg"
+
+var _ *peg.Node
+}
+%newline crlf
+Lines <- "a" ("\r" "b")* !.
+`
+	if err := ioutil.WriteFile(file, []byte(grammar), 0666); err != nil {
+		t.Fatal(err)
+	}
+	var got strings.Builder
+	err = runGrammar(&got, strings.NewReader("a\rb\rc"), file, "", nil)
+	if exit, ok := err.(*exec.ExitError); !ok || exit.ExitCode() != 1 {
+		t.Fatalf("runGrammar(_, \"a\\rb\\rc\", _, \"\", _)=%v, want exit status 1", err)
+	}
+	if want := ":3.1: "; !strings.HasPrefix(got.String(), want) {
+		t.Errorf("runGrammar wrote %q, want prefix %q", got.String(), want)
+	}
+}
+
+func TestHashDeps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peggy_hash_deps_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "main.go")
+	const src = `package main
+
+import "github.com/eaburns/peggy/peg"
+
+func main() { _ = peg.Node{} }
+`
+	if err := ioutil.WriteFile(file, []byte(src), 0666); err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := hashDeps(&b, file); err != nil {
+		t.Fatalf("hashDeps(_, %q)=%v, want nil", file, err)
+	}
+	pegDir, err := filepath.Abs("peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	peg, err := ioutil.ReadFile(filepath.Join(pegDir, "peg.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The peg package, but not the standard library, is hashed.
+	if !strings.Contains(b.String(), filepath.Join(pegDir, "peg.go")) || !strings.Contains(b.String(), string(peg)) {
+		t.Errorf("hashDeps did not hash peg/peg.go")
+	}
+	if strings.Contains(b.String(), "package strings") {
+		t.Errorf("hashDeps hashed the standard library")
+	}
+}
+
+func TestEvictBinaries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peggy_evict_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	now := time.Now()
+	var names []string
+	for i := 0; i < maxCachedBinaries+5; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("bin%02d", i))
+		if err := ioutil.WriteFile(name, nil, 0777); err != nil {
+			t.Fatal(err)
+		}
+		// bin00 is the most recently used.
+		used := now.Add(-time.Duration(i) * time.Minute)
+		if i == 1 {
+			used = now.Add(-maxCacheAge - time.Hour)
+		}
+		if err := os.Chtimes(name, used, used); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	keep := names[len(names)-1]
+	evictBinaries(dir, keep)
+	for i, name := range names {
+		_, err := os.Stat(name)
+		// bin01 is too old, and bin00 and bin02 through bin31
+		// are the 31 most recently used binaries besides keep.
+		want := name == keep || i == 0 || i > 1 && i <= maxCachedBinaries-1
+		if got := err == nil; got != want {
+			t.Errorf("%s kept=%v, want %v", filepath.Base(name), got, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2017 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+// schemaMain generates a grammar from the schema file,
+// or standard input if none, in the format from,
+// either "abnf" or "jsonschema",
+// writing the Peggy grammar to the -o file or standard output,
+// and a line to standard error for each dropped construct.
+func schemaMain(from string, args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: peggy schema -from format [schema]")
+	}
+	in := bufio.NewReader(os.Stdin)
+	file := "<stdin>"
+	if len(args) > 0 {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = bufio.NewReader(f)
+		file = args[0]
+	}
+	g, dropped, err := grammar.SchemaGrammar(in, file, from)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if err := grammar.WriteImported(&b, g); err != nil {
+		return err
+	}
+	if err := writeOutput(*out, b.Bytes()); err != nil {
+		return err
+	}
+	for _, e := range dropped.Errs {
+		fmt.Fprintln(os.Stderr, e)
+	}
+	return nil
+}
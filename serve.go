@@ -0,0 +1,390 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eaburns/peggy/grammar"
+	"github.com/eaburns/peggy/peg"
+)
+
+// serveTimeout bounds the time of a parse by a harness,
+// whose actions may not terminate.
+const serveTimeout = 10 * time.Second
+
+// A server is the HTTP handler of peggy serve, a grammar playground.
+// It serves a web page editing a grammar and an input,
+// which posts them to /parse, and shows the result:
+// the types of the rules, and the parse tree of the input,
+// or its error and an explanation of the error.
+//
+// Each grammar is generated and built with a harness,
+// like that of peggy run, in the current directory,
+// which must be in a Go module that can import the peg runtime package.
+// The built harnesses are cached, as by peggy run,
+// so editing only the input does not rebuild.
+//
+// Since posting a grammar runs its code,
+// the server only answers requests to its own loopback address,
+// which rejects pages of other sites and DNS rebinding,
+// carrying its random token, printed at startup.
+// Posts must be JSON, so browsers preflight those from other origins,
+// and must carry the token in the X-Peggy-Token header.
+type server struct {
+	cfg grammar.Config
+	// token is the random token required of requests.
+	token string
+	// hosts are the Host header values of requests
+	// to the loopback address of the server.
+	hosts map[string]bool
+}
+
+// newServer returns a server with a random token
+// answering requests to the loopback address addr.
+func newServer(cfg grammar.Config, addr string) (*server, error) {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	var token [16]byte
+	if _, err := rand.Read(token[:]); err != nil {
+		return nil, err
+	}
+	s := &server{cfg: cfg, token: fmt.Sprintf("%x", token), hosts: make(map[string]bool)}
+	s.hosts[addr] = true
+	for _, host := range []string{"localhost", "127.0.0.1", "::1"} {
+		s.hosts[net.JoinHostPort(host, port)] = true
+	}
+	return s, nil
+}
+
+// serve serves the playground on the address until it fails.
+// The address must be a loopback address.
+func serve(addr string, cfg grammar.Config) error {
+	if !*genParseTree {
+		return errors.New("serve requires parse tree generation, -t")
+	}
+	if !isLoopback(addr) {
+		return errors.New("serve runs the code of posted grammars, so it requires a loopback -addr, such as localhost:8080")
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s, err := newServer(cfg, l.Addr().String())
+	if err != nil {
+		l.Close()
+		return err
+	}
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	fmt.Printf("serving the playground on http://localhost:%s/?token=%s\n", port, s.token)
+	return http.Serve(l, s)
+}
+
+// isLoopback returns whether the host of the address
+// is localhost or a loopback IP address.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// A serveRequest is the body of a post to /parse.
+type serveRequest struct {
+	Grammar string `json:"grammar"`
+	// Root is the root rule, or the first rule if empty.
+	Root  string `json:"root"`
+	Input string `json:"input"`
+}
+
+// A serveResult is the response to a post to /parse.
+type serveResult struct {
+	// Error is an error of the grammar,
+	// or of generating, building, or running its parser.
+	Error    string      `json:"error,omitempty"`
+	Warnings []string    `json:"warnings,omitempty"`
+	Types    []serveType `json:"types,omitempty"`
+
+	// Tree is the peg.Pretty parse tree of the input.
+	Tree string `json:"tree,omitempty"`
+	// Note notes a parse that did not consume the entire input.
+	Note string `json:"note,omitempty"`
+
+	// ParseError is the error of a failed parse of the input,
+	// and Explain is the explanation of the failure, as by peggy explain.
+	ParseError string `json:"parseError,omitempty"`
+	Explain    string `json:"explain,omitempty"`
+}
+
+// A serveType is the result type of a rule.
+type serveType struct {
+	Rule string `json:"rule"`
+	Type string `json:"type"`
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.hosts[r.Host] {
+		http.Error(w, "bad host "+r.Host, http.StatusForbidden)
+		return
+	}
+	switch r.URL.Path {
+	case "/":
+		if !s.validToken(r.URL.Query().Get("token")) {
+			http.Error(w, "missing or bad token; use the URL printed by peggy serve", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, serveHTML)
+	case "/parse":
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" && !s.hosts[strings.TrimPrefix(origin, "http://")] {
+			http.Error(w, "bad origin "+origin, http.StatusForbidden)
+			return
+		}
+		if t, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || t != "application/json" {
+			http.Error(w, "want Content-Type application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+		if !s.validToken(r.Header.Get("X-Peggy-Token")) {
+			http.Error(w, "missing or bad X-Peggy-Token", http.StatusForbidden)
+			return
+		}
+		var req serveRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.parse(req))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// validToken returns whether the token is the server's token.
+func (s *server) validToken(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// parse returns the result of parsing the input of the request.
+func (s *server) parse(req serveRequest) serveResult {
+	var res serveResult
+	const file = "grammar"
+	g, err := s.cfg.Parse(strings.NewReader(req.Grammar), file)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if g.Prelude == nil {
+		// The generated parser refers to the peg package.
+		g.Prelude = grammar.NewText("package main\n\nimport \"" + s.cfg.PegImportPath() + "\"\n")
+	}
+	err = s.cfg.Check(g)
+	for _, w := range g.Warnings {
+		res.Warnings = append(res.Warnings, w.Error())
+	}
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	for _, r := range g.CheckedRules {
+		res.Types = append(res.Types, serveType{Rule: r.Name.String(), Type: r.Type()})
+	}
+	parserSrc, harnessSrc, err := checkedHarnessSources(g, file, req.Root, "serve", serveHarness, s.cfg)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	var buildOut bytes.Buffer
+	binary, err := cachedBinary(parserSrc, harnessSrc, &buildOut)
+	if err != nil {
+		res.Error = err.Error()
+		if buildOut.Len() > 0 {
+			res.Error = strings.TrimSpace(buildOut.String())
+		}
+		return res
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), serveTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, binary)
+	cmd.Stdin = strings.NewReader(req.Input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			res.Error = "the parse timed out after " + serveTimeout.String()
+		} else {
+			res.Error = strings.TrimSpace(err.Error() + "\n" + stderr.String())
+		}
+		return res
+	}
+	status, out, _ := bytes.Cut(out, []byte("\n"))
+	if bytes.HasPrefix(status, []byte("ok ")) {
+		res.Tree = string(out)
+		if n, _ := strconv.Atoi(string(status[len("ok "):])); n < len(req.Input) {
+			res.Note = fmt.Sprintf("parsed only %d of %d bytes", n, len(req.Input))
+		}
+		return res
+	}
+	fail, err := peg.ReadFail(bufio.NewReader(bytes.NewReader(out)))
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	const name = "input"
+	perr := g.PegNewline().SimpleError(req.Input, fail)
+	perr.FilePath = name
+	res.ParseError = perr.Error()
+	var explain strings.Builder
+	if err := grammar.ExplainFail(&explain, name, req.Input, g, fail); err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Explain = explain.String()
+	return res
+}
+
+// serveHarness parses standard input with the root rule,
+// and writes a status line, "ok" and the end of the match,
+// followed by the parse tree, or "fail" followed by the encoded Fail tree.
+var serveHarness = `package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	peg "{{.PegImport}}"
+)
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	input := string(data)
+	p, err := {{.Prefix}}NewParser(input)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	pos, perr := {{.Prefix}}{{.Root}}Accepts(p, 0)
+	if pos < 0 {
+		_, fail := {{.Prefix}}{{.Root}}Fail(p, 0, perr)
+		fmt.Println("fail")
+		if err := peg.WriteFail(os.Stdout, fail); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	_, node := {{.Prefix}}{{.Root}}Node(p, 0)
+	fmt.Printf("ok %d\n", pos)
+	fmt.Print(peg.Pretty(node))
+}
+`
+
+var serveHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Peggy playground</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+main { display: grid; grid-template-columns: 1fr 1fr; gap: 1em; }
+textarea, pre { font-family: monospace; font-size: 13px; width: 100%; box-sizing: border-box; }
+textarea { height: 18em; }
+pre { background: #f4f4f4; padding: 0.5em; min-height: 2em; white-space: pre-wrap; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>Peggy playground</h1>
+<main>
+<section>
+<h2>Grammar</h2>
+<textarea id="grammar" spellcheck="false">Sum &lt;- x:Num "+" y:Num { return int(x + y) }
+Num "number" &lt;- n:[0-9]+ { return int(len(n)) }</textarea>
+<p><label>Root rule <input id="root" placeholder="the first rule"></label></p>
+<h2>Input</h2>
+<textarea id="input" spellcheck="false">12+345</textarea>
+</section>
+<section>
+<h2>Result <small id="status"></small></h2>
+<pre id="error" class="error" hidden></pre>
+<pre id="tree"></pre>
+<h2>Types</h2>
+<pre id="types"></pre>
+</section>
+</main>
+<script>
+const $ = (id) => document.getElementById(id);
+const token = new URLSearchParams(location.search).get("token");
+let timer, seq = 0;
+
+async function update() {
+	const n = ++seq;
+	$("status").textContent = "…";
+	const resp = await fetch("/parse", {
+		method: "POST",
+		headers: { "Content-Type": "application/json", "X-Peggy-Token": token },
+		body: JSON.stringify({ grammar: $("grammar").value, root: $("root").value, input: $("input").value }),
+	});
+	const res = await resp.json();
+	if (n !== seq) {
+		return;
+	}
+	$("status").textContent = "";
+	const errs = [res.error, ...(res.warnings || [])].filter((e) => e);
+	$("error").hidden = errs.length === 0;
+	$("error").textContent = errs.join("\n");
+	if (res.error) {
+		return;
+	}
+	$("tree").textContent = res.tree ? res.tree + (res.note ? "\n" + res.note : "") : res.explain;
+	$("tree").className = res.tree ? "" : "error";
+	$("types").textContent = (res.types || []).map((t) => t.rule + " " + t.type).join("\n");
+}
+
+for (const id of ["grammar", "root", "input"]) {
+	$(id).addEventListener("input", () => {
+		clearTimeout(timer);
+		timer = setTimeout(update, 300);
+	});
+}
+update();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,218 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+func TestServe(t *testing.T) {
+	ts, s := newTestServer(t)
+	defer ts.Close()
+
+	const src = `
+Pair <- x:Num "," y:Num { return [2]int{x, y} }
+Num <- n:[0-9]+ { return int(len(n)) }
+`
+	types := []serveType{{Rule: "Pair", Type: "[2]int"}, {Rule: "Num", Type: "int"}}
+	tests := []struct {
+		req  serveRequest
+		want serveResult
+	}{
+		{
+			req: serveRequest{Grammar: src, Input: "1,23"},
+			want: serveResult{
+				Types: types,
+				Tree: `Pair{
+	Num{"1"},
+	",",
+	Num{"2", "3",},
+}`,
+			},
+		},
+		{
+			req: serveRequest{Grammar: src, Root: "Num", Input: "12,"},
+			want: serveResult{
+				Types: types,
+				Tree:  `Num{"1", "2",}`,
+				Note:  "parsed only 2 of 3 bytes",
+			},
+		},
+		{
+			req: serveRequest{Grammar: src, Input: "1,"},
+			want: serveResult{
+				Types:      types,
+				ParseError: "input:1.3: want [0-9]; got EOF",
+			},
+		},
+		{
+			req:  serveRequest{Grammar: "A <- ", Input: ""},
+			want: serveResult{Error: "grammar:1.6: syntax error"},
+		},
+		{
+			req:  serveRequest{Grammar: "A <- B", Input: ""},
+			want: serveResult{Error: "grammar:1.6,1.7: rule B undefined"},
+		},
+		{
+			req:  serveRequest{Grammar: src, Root: "Nope", Input: ""},
+			want: serveResult{Types: types, Error: "rule Nope undefined"},
+		},
+		{
+			req:  serveRequest{Grammar: `A <- "a" { return string(undefined) }`, Input: "a"},
+			want: serveResult{Types: []serveType{{Rule: "A", Type: "string"}}, Error: "# command-line-arguments\n"},
+		},
+	}
+	for _, test := range tests {
+		body, err := json.Marshal(test.req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := post(ts.URL+"/parse", s.token, string(body), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got serveResult
+		err = json.NewDecoder(resp.Body).Decode(&got)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The explanation and build errors are long,
+		// and tested elsewhere, so check only their beginnings.
+		if test.want.ParseError != "" && !strings.HasPrefix(got.Explain, test.want.ParseError) {
+			t.Errorf("%+v: explanation %q does not begin with %q", test.req, got.Explain, test.want.ParseError)
+		}
+		got.Explain = ""
+		if strings.HasPrefix(got.Error, test.want.Error) {
+			got.Error = test.want.Error
+		}
+		got.Tree = strings.Join(strings.Fields(got.Tree), "")
+		test.want.Tree = strings.Join(strings.Fields(test.want.Tree), "")
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%+v=%+v, want %+v", test.req, got, test.want)
+		}
+	}
+	resp, err := http.Get(ts.URL + "/?token=" + s.token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || !strings.Contains(string(page), "Peggy playground") {
+		t.Errorf("GET / failed: %v\n%s", err, page)
+	}
+	if resp, err := http.Get(ts.URL + "/parse"); err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET /parse=%v, %v, want status %d", resp, err, http.StatusMethodNotAllowed)
+	}
+}
+
+// TestServeRejects tests that the server rejects requests
+// that could come from other sites.
+func TestServeRejects(t *testing.T) {
+	ts, s := newTestServer(t)
+	defer ts.Close()
+	_, port, err := net.SplitHostPort(ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const body = `{"grammar": "A <- 'a'", "input": "a"}`
+	tests := []struct {
+		name    string
+		token   string
+		headers map[string]string
+		status  int
+	}{
+		{name: "ok", token: s.token, status: http.StatusOK},
+		{
+			name:    "ok localhost",
+			token:   s.token,
+			headers: map[string]string{"Host": "localhost:" + port, "Origin": "http://localhost:" + port},
+			status:  http.StatusOK,
+		},
+		{name: "no token", status: http.StatusForbidden},
+		{name: "bad token", token: s.token + "0", status: http.StatusForbidden},
+		{
+			name:    "text/plain",
+			token:   s.token,
+			headers: map[string]string{"Content-Type": "text/plain"},
+			status:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:    "other origin",
+			token:   s.token,
+			headers: map[string]string{"Origin": "http://evil.example"},
+			status:  http.StatusForbidden,
+		},
+		{
+			name:    "rebound host",
+			token:   s.token,
+			headers: map[string]string{"Host": "evil.example:" + port},
+			status:  http.StatusForbidden,
+		},
+	}
+	for _, test := range tests {
+		resp, err := post(ts.URL+"/parse", test.token, body, test.headers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("%s: status %d, want %d", test.name, resp.StatusCode, test.status)
+		}
+	}
+	if resp, err := http.Get(ts.URL + "/"); err != nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("GET / without token=%v, %v, want status %d", resp, err, http.StatusForbidden)
+	}
+
+	for _, addr := range []string{":8080", "0.0.0.0:8080", "example.com:8080", "192.168.0.1:8080"} {
+		if err := serve(addr, grammar.Config{Prefix: "_"}); err == nil || !strings.Contains(err.Error(), "loopback") {
+			t.Errorf("serve(%q, _)=%v, want a loopback error", addr, err)
+		}
+	}
+}
+
+// newTestServer returns a started test server of a server with prefix _.
+func newTestServer(t *testing.T) (*httptest.Server, *server) {
+	ts := httptest.NewUnstartedServer(nil)
+	s, err := newServer(grammar.Config{Prefix: "_"}, ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.Config.Handler = s
+	ts.Start()
+	return ts, s
+}
+
+// post posts the JSON body to the URL with the token
+// and the headers, which override the defaults.
+func post(url, token, body string, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("X-Peggy-Token", token)
+	}
+	for k, v := range headers {
+		if k == "Host" {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	return http.DefaultClient.Do(req)
+}
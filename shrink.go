@@ -0,0 +1,102 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"errors"
+	"io"
+)
+
+// shrink generates the parser for a grammar file
+// along with a harness that reads an input from in
+// that the root rule fails to parse,
+// and writes to w a minimal input, as computed by peg.Shrink,
+// that fails to parse with the same set of wanted leaf fails
+// at the same rune (or end of input).
+// The parse error of the minimal input is written to standard error.
+// If root is the empty string, the first rule of the grammar is used.
+func shrink(w io.Writer, in io.Reader, file, root string) error {
+	if !*genParseTree {
+		return errors.New("shrink requires parse tree generation, -t")
+	}
+	cfg, err := flagConfig()
+	if err != nil {
+		return err
+	}
+	return runHarness(w, in, file, root, "shrink", shrinkHarness, cfg)
+}
+
+var shrinkHarness = `package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	peg "{{.PegImport}}"
+)
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	input := string(data)
+	want, ok := _shrinkFailure(input)
+	if !ok {
+		fmt.Fprintln(os.Stderr, "the input does not fail to parse")
+		os.Exit(1)
+	}
+	min := peg.Shrink(input, func(s string) bool {
+		got, ok := _shrinkFailure(s)
+		return ok && got == want
+	})
+	os.Stdout.WriteString(min)
+	p, err := {{.Prefix}}NewParser(min)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	_, perr := {{.Prefix}}{{.Root}}Accepts(p, 0)
+	_, fail := {{.Prefix}}{{.Root}}Fail(p, 0, perr)
+	fmt.Fprintf(os.Stderr, "shrunk %d bytes to %d bytes: %s\n",
+		len(input), len(min), peg.SimpleError(min, fail))
+}
+
+// _shrinkFailure returns a string identifying the failure
+// of the parse of the text — its sorted leaf fail Wants
+// and the rune at their position — and whether the parse failed.
+func _shrinkFailure(text string) (string, bool) {
+	p, err := {{.Prefix}}NewParser(text)
+	if err != nil {
+		return "", false
+	}
+	pos, perr := {{.Prefix}}{{.Root}}Accepts(p, 0)
+	if pos >= 0 {
+		return "", false
+	}
+	_, fail := {{.Prefix}}{{.Root}}Fail(p, 0, perr)
+	var wants []string
+	seen := make(map[string]bool)
+	got := "EOF"
+	for _, l := range peg.LeafFails(fail) {
+		if !seen[l.Want] {
+			seen[l.Want] = true
+			wants = append(wants, l.Want)
+		}
+		if l.Pos < len(text) {
+			r, _ := peg.DecodeRuneInString(text[l.Pos:])
+			got = string(r)
+		}
+	}
+	sort.Strings(wants)
+	return strings.Join(wants, "\x00") + "\x00" + got, true
+}
+`
@@ -0,0 +1,63 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShrink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peggy_shrink_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "g.peggy")
+	const grammar = `{
+package calc
+
+import "github.com/eaburns/peggy/peg"
+
+func main() {}
+}
+Top <- List !.
+List <- "[" (Elem ("," Elem)*)? "]"
+Elem <- Num / List
+Num <- [0-9]+
+`
+	if err := ioutil.WriteFile(file, []byte(grammar), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		root, in, want string
+	}{
+		// The failure is at the second "," of "45,,6",
+		// wanting a Num or List element.
+		{root: "", in: "[[1,2],[3,[45,,6]],7]", want: "[5,,"},
+		{root: "", in: "[1]x", want: "[]x"},
+		{root: "Elem", in: "[1,x]", want: "x"},
+	}
+	for _, test := range tests {
+		var got strings.Builder
+		if err := shrink(&got, strings.NewReader(test.in), file, test.root); err != nil {
+			t.Errorf("shrink(_, %q, _, %q)=%v, want nil", test.in, test.root, err)
+			continue
+		}
+		if got.String() != test.want {
+			t.Errorf("shrink(_, %q, _, %q) wrote %q, want %q", test.in, test.root, got.String(), test.want)
+		}
+	}
+
+	if err := shrink(ioutil.Discard, strings.NewReader("[1]"), file, ""); err == nil {
+		t.Errorf("shrink(_, \"[1]\", _, \"\")=nil, want error")
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+// wasm builds the parser for a grammar file with the Config
+// into a WebAssembly module, the out file, which must end in .wasm,
+// along with a JavaScript module next to it, of the same name ending in .js,
+// exporting the function parse(text), which parses the text with the root rule,
+// or the first rule if root is the empty string.
+// The JavaScript module imports wasm_exec.js, the support file of the Go installation,
+// which is copied into the same directory.
+//
+// The parse function returns {end, value},
+// the byte offset of the end of the match and the result of the rule's actions
+// encoded as JSON by encoding/json and decoded by JavaScript,
+// or {end: -1, error}, the error, as returned by the parse function
+// of the C ABI wrapper.
+//
+// The module is built with GOOS=js GOARCH=wasm go build
+// in the current directory, which must be in a Go module
+// that can import the peg runtime package,
+// github.com/eaburns/peggy/peg or the -pegimport path.
+func wasm(out, file, root string, cfg grammar.Config) error {
+	if !*genActions {
+		return errors.New("wasm requires action generation, -a")
+	}
+	if !strings.HasSuffix(out, ".wasm") {
+		return errors.New("wasm requires an -o output file ending in .wasm")
+	}
+	parserSrc, harnessSrc, err := harnessSources(file, root, "wasm", wasmHarness, cfg)
+	if err != nil {
+		return err
+	}
+	support, err := wasmExecJS()
+	if err != nil {
+		return err
+	}
+	dir, err := ioutil.TempDir("", "peggy_wasm")
+	if err != nil {
+		return err
+	}
+	defer removeTemp(dir)
+	parserFile := filepath.Join(dir, "parser.go")
+	harnessFile := filepath.Join(dir, "wasm.go")
+	if err := ioutil.WriteFile(parserFile, parserSrc, 0666); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(harnessFile, harnessSrc, 0666); err != nil {
+		return err
+	}
+	binary := filepath.Join(dir, "parser.wasm")
+	cmd := exec.Command("go", "build", "-o", binary, parserFile, harnessFile)
+	cmd.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+	module, err := ioutil.ReadFile(binary)
+	if err != nil {
+		return err
+	}
+	var shim bytes.Buffer
+	err = template.Must(template.New("shim").Parse(wasmShim)).Execute(&shim, map[string]string{
+		"Wasm": filepath.Base(out),
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeOutput(out, module); err != nil {
+		return err
+	}
+	if err := writeOutput(strings.TrimSuffix(out, ".wasm")+".js", shim.Bytes()); err != nil {
+		return err
+	}
+	return writeOutput(filepath.Join(filepath.Dir(out), "wasm_exec.js"), support)
+}
+
+// wasmExecJS returns the contents of wasm_exec.js
+// of the Go installation.
+func wasmExecJS() ([]byte, error) {
+	goroot, err := exec.Command("go", "env", "GOROOT").Output()
+	if err != nil {
+		return nil, err
+	}
+	// Go 1.24 moved the file from misc/wasm to lib/wasm.
+	for _, dir := range []string{"lib", "misc"} {
+		path := filepath.Join(strings.TrimSpace(string(goroot)), dir, "wasm", "wasm_exec.js")
+		if data, err := ioutil.ReadFile(path); err == nil {
+			return data, nil
+		}
+	}
+	return nil, errors.New("wasm_exec.js not found in the Go installation")
+}
+
+// wasmHarness sets the global variable named by its argument
+// to a function returning the JSON result of parsing a text,
+// then waits for calls.
+var wasmHarness = `package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"syscall/js"
+
+	peg "{{.PegImport}}"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		os.Stderr.WriteString("usage: parser.wasm global\n")
+		os.Exit(1)
+	}
+	js.Global().Set(os.Args[1], js.FuncOf(func(_ js.Value, args []js.Value) interface{} {
+		return string({{.Prefix}}jsonParse(args[0].String()))
+	}))
+	select {}
+}
+` + jsonParseFuncs
+
+var wasmShim = `// Code generated by peggy wasm. DO NOT EDIT.
+
+import "./wasm_exec.js";
+
+const go = new Go();
+const global = "__peggy_parse_" + Math.random().toString(36).slice(2);
+go.argv = ["parser.wasm", global];
+
+const url = new URL({{printf "%q" .Wasm}}, import.meta.url);
+let result;
+if (url.protocol === "file:") {
+	const { readFile } = await import("node:fs/promises");
+	result = await WebAssembly.instantiate(await readFile(url), go.importObject);
+} else {
+	result = await WebAssembly.instantiateStreaming(fetch(url), go.importObject);
+}
+go.run(result.instance);
+const parseJSON = globalThis[global];
+delete globalThis[global];
+
+// parse parses the text, returning {end, value} on success,
+// where end is the byte offset of the end of the match
+// and value is the result of the rule's actions,
+// or {end: -1, error} on failure.
+export function parse(text) {
+	return JSON.parse(parseJSON(text));
+}
+`
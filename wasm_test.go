@@ -0,0 +1,87 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/eaburns/peggy/grammar"
+)
+
+func TestWasm(t *testing.T) {
+	if testing.Short() {
+		t.Skip("building a WebAssembly module is slow")
+	}
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("no node to run the module")
+	}
+	dir, err := ioutil.TempDir("", "peggy_wasm_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "calc.peggy")
+	const src = `{
+package calc
+
+import "github.com/eaburns/peggy/peg"
+}
+Sum <- x:Num "+" y:Num { return []int{x, y} }
+Num "number" <- n:[0-9] {
+	if n == "0" {
+		panic("zero")
+	}
+	return int(n[0] - '0')
+}
+`
+	if err := ioutil.WriteFile(file, []byte(src), 0666); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "web", "calc.wasm")
+	if err := os.Mkdir(filepath.Dir(out), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := wasm(out, file, "", grammar.Config{Prefix: "_"}); err != nil {
+		t.Fatalf("wasm(%q, %q, \"\", _)=%v, want nil", out, file, err)
+	}
+	for _, name := range []string{"calc.wasm", "calc.js", "wasm_exec.js"} {
+		if _, err := os.Stat(filepath.Join(dir, "web", name)); err != nil {
+			t.Errorf("wasm did not write %s: %v", name, err)
+		}
+	}
+
+	script := filepath.Join(dir, "web", "test.mjs")
+	const js = `import { parse } from "./calc.js";
+for (const text of ["1+2", "1+2+3", "1-", "0+1"]) {
+	console.log(JSON.stringify(parse(text)));
+}
+`
+	if err := ioutil.WriteFile(script, []byte(js), 0666); err != nil {
+		t.Fatal(err)
+	}
+	got, err := exec.Command("node", script).CombinedOutput()
+	if err != nil {
+		t.Fatalf("node failed: %v\n%s", err, got)
+	}
+	const want = `{"end":3,"value":[1,2]}
+{"end":3,"value":[1,2]}
+{"end":-1,"error":{"message":"want \"+\"; got '-'","loc":{"byte":1,"line":1,"column":2},"code":"expected-literal","rule":"Sum"}}
+{"end":-1,"error":{"message":"panic: zero"}}
+`
+	if string(got) != want {
+		t.Errorf("parse wrote\n%s\nwant\n%s", got, want)
+	}
+
+	if err := wasm(filepath.Join(dir, "calc.js"), file, "", grammar.Config{Prefix: "_"}); err == nil ||
+		err.Error() != "wasm requires an -o output file ending in .wasm" {
+		t.Errorf("wasm(\"calc.js\", _, _, _)=%v, want an -o error", err)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// watch polls the grammar files every interval,
+// regenerating the -o file as the peggy command does
+// each time any of them changes,
+// and writing to w the warnings and errors of each regeneration
+// or a line noting that the -o file was written.
+// With the -test flag, the #test cases of the grammar files
+// are run after each regeneration, writing their failures to w.
+// The -o file is only written if the grammar has no errors.
+// watch returns when done is closed; if done is nil, it never returns.
+func watch(w io.Writer, files []string, interval time.Duration, done <-chan struct{}) {
+	type stamp struct {
+		modTime time.Time
+		size    int64
+	}
+	stamps := make([]stamp, len(files))
+	var lastErr string
+	for {
+		var changed bool
+		var statErr error
+		for i, file := range files {
+			fi, err := os.Stat(file)
+			if err != nil {
+				statErr = err
+				break
+			}
+			if s := stamps[i]; !fi.ModTime().Equal(s.modTime) || fi.Size() != s.size {
+				stamps[i], changed = stamp{fi.ModTime(), fi.Size()}, true
+			}
+		}
+		switch {
+		case statErr != nil:
+			if statErr.Error() != lastErr {
+				fmt.Fprintln(w, statErr)
+				lastErr = statErr.Error()
+			}
+		case changed:
+			lastErr = ""
+			regenerate(w, files)
+		}
+		select {
+		case <-done:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// regenerate regenerates the -o file of the grammar files,
+// and runs their #test cases if the -test flag is set,
+// writing the diagnostics to w.
+func regenerate(w io.Writer, files []string) {
+	if _, err := generate(w, files); err != nil {
+		fmt.Fprintln(w, err)
+		return
+	}
+	fmt.Fprintf(w, "%s: wrote %s\n", files[0], *out)
+	if !*testCases {
+		return
+	}
+	cfg, err := flagConfig()
+	if err == nil {
+		err = testGrammar(w, files, cfg)
+	}
+	if err != nil {
+		fmt.Fprintln(w, err)
+	}
+}
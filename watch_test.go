@@ -0,0 +1,148 @@
+// Copyright 2018 The Peggy Authors
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file or at
+// https://developers.google.com/open-source/licenses/bsd.
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peggy_watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "g.peggy")
+	prelude := filepath.Join(dir, "prelude.peggy")
+	outFile := filepath.Join(dir, "g.go")
+	write := func(file, src string) {
+		if err := ioutil.WriteFile(file, []byte(src), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(file, `A <- "a"`)
+	write(prelude, "{\npackage p\n}\n")
+
+	// This test cannot be run in parallel.
+	defer func(o string) { *out = o }(*out)
+	*out = outFile
+	lines, stop := startWatch(t, []string{file, prelude})
+	defer stop()
+
+	wrote := file + ": wrote " + outFile
+	if line := lines(); line != wrote {
+		t.Fatalf("got %q, want %q", line, wrote)
+	}
+	src, err := ioutil.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(src), "func _AAccepts(") {
+		t.Errorf("generated parser does not contain _AAccepts:\n%s", src)
+	}
+
+	write(file, `A <- "a" Undefined`)
+	if line := lines(); !strings.HasSuffix(line, "rule Undefined undefined") {
+		t.Errorf("got %q, want rule Undefined undefined error", line)
+	}
+	if src2, err := ioutil.ReadFile(outFile); err != nil || string(src2) != string(src) {
+		t.Errorf("output changed after a grammar error")
+	}
+
+	write(file, `A <- "a" B
+B <- "b"`)
+	if line := lines(); line != wrote {
+		t.Fatalf("got %q, want %q", line, wrote)
+	}
+
+	// The prelude file is watched too.
+	write(prelude, "{\npackage q\n}\n")
+	if line := lines(); line != wrote {
+		t.Fatalf("got %q, want %q", line, wrote)
+	}
+	if src, err := ioutil.ReadFile(outFile); err != nil || !strings.Contains(string(src), "package q") {
+		t.Errorf("output not regenerated with the changed prelude: %v\n%s", err, src)
+	}
+}
+
+func TestWatchTest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peggy_watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "g.peggy")
+	outFile := filepath.Join(dir, "g.go")
+	write := func(grammar string) {
+		src := "{\npackage p\n\nimport \"github.com/eaburns/peggy/peg\"\n}\n" + grammar
+		if err := ioutil.WriteFile(file, []byte(src), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`A <- "a"+
+#test A "aa"
+#test !A "b"
+`)
+
+	// This test cannot be run in parallel.
+	defer func(o string, t bool) { *out, *testCases = o, t }(*out, *testCases)
+	*out, *testCases = outFile, true
+	lines, stop := startWatch(t, []string{file})
+	defer stop()
+
+	for _, want := range []string{
+		file + ": wrote " + outFile,
+		file + ": 2 #test cases passed",
+	} {
+		if line := lines(); line != want {
+			t.Fatalf("got %q, want %q", line, want)
+		}
+	}
+
+	write(`A <- "a"+
+#test A "ab"
+#test !A "a"
+`)
+	for _, want := range []string{
+		file + ": wrote " + outFile,
+		file + ":7.1: A matched only 1 of 2 bytes",
+		file + ":8.1: !A matched \"a\"",
+		"2 of 2 #test cases failed",
+	} {
+		if line := lines(); line != want {
+			t.Fatalf("got %q, want %q", line, want)
+		}
+	}
+}
+
+// startWatch starts watching the files,
+// returning a function returning the next line written by watch
+// and a function stopping the watch.
+func startWatch(t *testing.T, files []string) (func() string, func()) {
+	r, w := io.Pipe()
+	done := make(chan struct{})
+	go watch(w, files, 10*time.Millisecond, done)
+	lines := bufio.NewScanner(r)
+	next := func() string {
+		if !lines.Scan() {
+			t.Fatalf("watch output ended: %v", lines.Err())
+		}
+		return lines.Text()
+	}
+	stop := func() {
+		close(done)
+		r.Close()
+	}
+	return next, stop
+}